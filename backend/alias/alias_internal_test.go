@@ -11,6 +11,7 @@ import (
 	"github.com/ncw/rclone/fs"
 	"github.com/ncw/rclone/fs/config"
 	"github.com/stretchr/testify/require"
+	"golang.org/x/net/context"
 )
 
 var (
@@ -28,6 +29,7 @@ func prepare(t *testing.T, root string) {
 }
 
 func TestNewFS(t *testing.T) {
+	ctx := context.Background()
 	type testEntry struct {
 		remote string
 		size   int64
@@ -71,7 +73,7 @@ func TestNewFS(t *testing.T) {
 		prepare(t, remoteRoot)
 		f, err := fs.NewFs(fmt.Sprintf("%s:%s", remoteName, test.fsRoot))
 		require.NoError(t, err, what)
-		gotEntries, err := f.List(test.fsList)
+		gotEntries, err := f.List(ctx, test.fsList)
 		require.NoError(t, err, what)
 
 		sort.Sort(gotEntries)
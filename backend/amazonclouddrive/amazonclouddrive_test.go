@@ -70,5 +70,6 @@ func TestFsIsFile(t *testing.T)            { fstests.TestFsIsFile(t) }
 func TestFsIsFileNotFound(t *testing.T)    { fstests.TestFsIsFileNotFound(t) }
 func TestObjectRemove(t *testing.T)        { fstests.TestObjectRemove(t) }
 func TestFsPutStream(t *testing.T)         { fstests.TestFsPutStream(t) }
+func TestFsPutZeroLength(t *testing.T)     { fstests.TestFsPutZeroLength(t) }
 func TestObjectPurge(t *testing.T)         { fstests.TestObjectPurge(t) }
 func TestFinalise(t *testing.T)            { fstests.TestFinalise(t) }
@@ -10,10 +10,12 @@ import (
 	"encoding/base64"
 	"encoding/binary"
 	"encoding/hex"
+	"encoding/xml"
 	"fmt"
 	gohash "hash"
 	"io"
 	"net/http"
+	"net/url"
 	"path"
 	"regexp"
 	"strconv"
@@ -31,7 +33,9 @@ import (
 	"github.com/ncw/rclone/fs/hash"
 	"github.com/ncw/rclone/fs/walk"
 	"github.com/ncw/rclone/lib/pacer"
+	"github.com/ncw/rclone/lib/rest"
 	"github.com/pkg/errors"
+	"golang.org/x/net/context"
 )
 
 const (
@@ -43,16 +47,32 @@ const (
 	modTimeKey         = "mtime"
 	timeFormatIn       = time.RFC3339
 	timeFormatOut      = "2006-01-02T15:04:05.000000000Z07:00"
-	maxTotalParts      = 50000   // in multipart upload
-	maxUncommittedSize = 9 << 30 // can't upload bigger than this
+	maxTotalParts      = 50000     // in multipart upload
+	maxUncommittedSize = 9 << 30   // can't upload bigger than this
+	sasExpiry          = time.Hour // how long the SAS URLs we make for ourselves last
+)
+
+var pacerOptions = pacer.AddFlags("azureblob", pacer.Options{
+	MinSleep:      minSleep,
+	MaxSleep:      maxSleep,
+	DecayConstant: decayConstant,
+})
+
+// Access tiers for block blobs
+const (
+	accessTierHot     = "Hot"
+	accessTierCool    = "Cool"
+	accessTierArchive = "Archive"
 )
 
 // Globals
 var (
-	maxChunkSize    = fs.SizeSuffix(100 * 1024 * 1024)
-	chunkSize       = fs.SizeSuffix(4 * 1024 * 1024)
-	uploadCutoff    = fs.SizeSuffix(256 * 1024 * 1024)
-	maxUploadCutoff = fs.SizeSuffix(256 * 1024 * 1024)
+	maxChunkSize     = fs.SizeSuffix(100 * 1024 * 1024)
+	chunkSize        = fs.SizeSuffix(4 * 1024 * 1024)
+	uploadCutoff     = fs.SizeSuffix(256 * 1024 * 1024)
+	maxUploadCutoff  = fs.SizeSuffix(256 * 1024 * 1024)
+	accessTier       = flags.StringP("azureblob-access-tier", "", "", "Access tier of blob, supports hot, cool and archive tiers.")
+	noCheckContainer = flags.BoolP("azureblob-no-check-container", "", false, "Don't check the container exists or try to create it.")
 )
 
 // Register with Fs
@@ -63,13 +83,55 @@ func init() {
 		NewFs:       NewFs,
 		Options: []fs.Option{{
 			Name: "account",
-			Help: "Storage Account Name",
+			Help: "Storage Account Name (leave blank to use SAS URL)",
 		}, {
 			Name: "key",
-			Help: "Storage Account Key",
+			Help: "Storage Account Key (leave blank to use SAS URL)",
+		}, {
+			Name: "sas_url",
+			Help: "Account level SAS URL - leave blank to use account/key",
 		}, {
 			Name: "endpoint",
 			Help: "Endpoint for the service - leave blank normally.",
+		}, {
+			Name: "access_tier",
+			Help: "Access tier for uploaded blobs - leave blank to use the account default.",
+			Examples: []fs.OptionExample{{
+				Value: "",
+				Help:  "Use the account default",
+			}, {
+				Value: "hot",
+				Help:  "Hot - optimized for frequent access",
+			}, {
+				Value: "cool",
+				Help:  "Cool - optimized for infrequent access",
+			}, {
+				Value: "archive",
+				Help:  "Archive - offline, must be rehydrated before reading",
+			}},
+		}, {
+			Name: "public_access",
+			Help: "Public access level of new containers.",
+			Examples: []fs.OptionExample{{
+				Value: "",
+				Help:  "Private - only the account owner can read [default if left blank].",
+			}, {
+				Value: "blob",
+				Help:  "Anyone can read blobs but not list the container.",
+			}, {
+				Value: "container",
+				Help:  "Anyone can read blobs and list the container.",
+			}},
+		}, {
+			Name: "no_check_container",
+			Help: "Don't check the container exists or try to create it.",
+			Examples: []fs.OptionExample{{
+				Value: "false",
+				Help:  "Check the container exists and create it if not [default if left blank].",
+			}, {
+				Value: "true",
+				Help:  "Assume the container exists - use if the credentials can't create or check containers.",
+			}},
 		},
 		},
 	})
@@ -85,7 +147,11 @@ type Fs struct {
 	account          string       // account name
 	key              []byte       // auth key
 	endpoint         string       // name of the starting api endpoint
+	sasToken         url.Values   // SAS token if using SAS auth or nil
+	accessTier       string       // access tier to set on uploads or ""
 	bc               *storage.BlobStorageClient
+	publicAccess     storage.ContainerAccessType
+	srv              *rest.Client // for the calls the SDK doesn't support
 	cc               *storage.Container
 	container        string                // the container we are working on
 	containerOKMu    sync.Mutex            // mutex to protect container OK
@@ -105,6 +171,7 @@ type Object struct {
 	size     int64             // Size of the object
 	mimeType string            // Content-Type of the object
 	meta     map[string]string // blob metadata
+	tier     string            // access tier of the blob if known
 }
 
 // ------------------------------------------------------------
@@ -165,6 +232,9 @@ var retryErrorCodes = []int{
 func (f *Fs) shouldRetry(err error) (bool, error) {
 	// FIXME interpret special errors - more to do here
 	if storageErr, ok := err.(storage.AzureStorageServiceError); ok {
+		if isImmutableError(storageErr) {
+			return false, fserrors.NoRetryError(errors.Wrap(err, "blob is protected by an immutability policy or legal hold"))
+		}
 		statusCode := storageErr.StatusCode
 		for _, e := range retryErrorCodes {
 			if statusCode == e {
@@ -175,6 +245,47 @@ func (f *Fs) shouldRetry(err error) (bool, error) {
 	return fserrors.ShouldRetry(err), err
 }
 
+// isImmutableError returns true if the error was caused by trying to
+// modify or delete a blob under an immutability policy or legal hold
+func isImmutableError(storageErr storage.AzureStorageServiceError) bool {
+	return storageErr.StatusCode == http.StatusConflict && strings.HasPrefix(storageErr.Code, "BlobImmutable")
+}
+
+// errorHandler parses a non 2xx error response from the calls made
+// directly with f.srv into an AzureStorageServiceError
+func errorHandler(resp *http.Response) error {
+	storageErr := storage.AzureStorageServiceError{
+		StatusCode: resp.StatusCode,
+		RequestID:  resp.Header.Get("x-ms-request-id"),
+		Date:       resp.Header.Get("Date"),
+		APIVersion: resp.Header.Get("x-ms-version"),
+	}
+	body, err := rest.ReadBody(resp)
+	if err != nil {
+		return errors.Wrap(err, "error reading error out of body")
+	}
+	if len(body) == 0 {
+		storageErr.Message = resp.Status
+		return storageErr
+	}
+	err = xml.Unmarshal(body, &storageErr)
+	if err != nil {
+		storageErr.Message = fmt.Sprintf("failed to decode error %q: %v", body, err)
+	}
+	return storageErr
+}
+
+// parseAccessTier checks the access tier is valid and returns it
+// normalised to the form the API uses
+func parseAccessTier(tier string) (string, error) {
+	for _, validTier := range []string{accessTierHot, accessTierCool, accessTierArchive} {
+		if strings.EqualFold(tier, validTier) {
+			return validTier, nil
+		}
+	}
+	return "", errors.Errorf("azure: access tier %q not supported - must be one of hot, cool or archive", tier)
+}
+
 // NewFs contstructs an Fs from the path, container:path
 func NewFs(name, root string) (fs.Fs, error) {
 	if uploadCutoff > maxUploadCutoff {
@@ -187,45 +298,94 @@ func NewFs(name, root string) (fs.Fs, error) {
 	if err != nil {
 		return nil, err
 	}
-	account := config.FileGet(name, "account")
-	if account == "" {
+	var (
+		account  = config.FileGet(name, "account")
+		key      = config.FileGet(name, "key")
+		sasURL   = config.FileGet(name, "sas_url")
+		endpoint = config.FileGet(name, "endpoint", storage.DefaultBaseURL)
+		keyBytes []byte
+		sasToken url.Values
+		client   storage.Client
+	)
+	switch {
+	case sasURL != "":
+		u, err := url.Parse(sasURL)
+		if err != nil {
+			return nil, errors.Wrap(err, "failed to parse SAS URL")
+		}
+		if strings.Trim(u.Path, "/") != "" {
+			return nil, errors.New("SAS URL must be an account level SAS URL without a container")
+		}
+		hostParts := strings.SplitN(u.Host, ".", 2)
+		if len(hostParts) != 2 {
+			return nil, errors.Errorf("SAS URL host %q must be the account followed by the endpoint, eg account.blob.core.windows.net", u.Host)
+		}
+		account, endpoint = hostParts[0], hostParts[1]
+		client, err = storage.NewAccountSASClientFromEndpointToken(u.Scheme+"://"+u.Host, u.RawQuery)
+		if err != nil {
+			return nil, errors.Wrap(err, "failed to make azure storage client from SAS URL")
+		}
+		sasToken = u.Query()
+	case account == "":
 		return nil, errors.New("account not found")
-	}
-	key := config.FileGet(name, "key")
-	if key == "" {
+	case key == "":
 		return nil, errors.New("key not found")
+	default:
+		keyBytes, err = base64.StdEncoding.DecodeString(key)
+		if err != nil {
+			return nil, errors.Errorf("malformed storage account key: %v", err)
+		}
+		client, err = storage.NewClient(account, key, endpoint, apiVersion, true)
+		if err != nil {
+			return nil, errors.Wrap(err, "failed to make azure storage client")
+		}
 	}
-	keyBytes, err := base64.StdEncoding.DecodeString(key)
-	if err != nil {
-		return nil, errors.Errorf("malformed storage account key: %v", err)
+	tier := config.FileGet(name, "access_tier")
+	if *accessTier != "" {
+		tier = *accessTier
 	}
-
-	endpoint := config.FileGet(name, "endpoint", storage.DefaultBaseURL)
-
-	client, err := storage.NewClient(account, key, endpoint, apiVersion, true)
-	if err != nil {
-		return nil, errors.Wrap(err, "failed to make azure storage client")
+	if tier != "" {
+		tier, err = parseAccessTier(tier)
+		if err != nil {
+			return nil, err
+		}
 	}
-	client.HTTPClient = fshttp.NewClient(fs.Config)
+	publicAccess := storage.ContainerAccessType(strings.ToLower(config.FileGet(name, "public_access")))
+	switch publicAccess {
+	case storage.ContainerAccessTypePrivate, storage.ContainerAccessTypeBlob, storage.ContainerAccessTypeContainer:
+	default:
+		return nil, errors.Errorf("azure: public access %q not supported - must be blob or container", publicAccess)
+	}
+	client.HTTPClient = fshttp.NewClient(fs.ConfigForRemote(name))
 	bc := client.GetBlobService()
 
 	f := &Fs{
-		name:        name,
-		container:   container,
-		root:        directory,
-		account:     account,
-		key:         keyBytes,
-		endpoint:    endpoint,
-		bc:          &bc,
-		cc:          bc.GetContainerReference(container),
-		pacer:       pacer.New().SetMinSleep(minSleep).SetMaxSleep(maxSleep).SetDecayConstant(decayConstant),
-		uploadToken: pacer.NewTokenDispenser(fs.Config.Transfers),
+		name:         name,
+		container:    container,
+		root:         directory,
+		account:      account,
+		key:          keyBytes,
+		endpoint:     endpoint,
+		sasToken:     sasToken,
+		accessTier:   tier,
+		publicAccess: publicAccess,
+		bc:           &bc,
+		srv:          rest.NewClient(fshttp.NewClient(fs.ConfigForRemote(name))).SetErrorHandler(errorHandler),
+		cc:           bc.GetContainerReference(container),
+		pacer:        pacer.New().SetOptions(pacerOptions),
+		uploadToken:  pacer.NewTokenDispenser(fs.Config.Transfers),
 	}
 	f.features = (&fs.Features{
 		ReadMimeType:  true,
 		WriteMimeType: true,
 		BucketBased:   true,
+		SetTier:       true,
+		GetTier:       true,
 	}).Fill(f)
+	if *noCheckContainer || config.FileGetBool(name, "no_check_container") {
+		// Assume the container exists so Mkdir doesn't touch it
+		f.containerOK = true
+	}
 	if f.root != "" {
 		f.root += "/"
 		// Check to see if the (container,directory) is actually an existing file
@@ -237,7 +397,7 @@ func NewFs(name, root string) (fs.Fs, error) {
 		} else {
 			f.root += "/"
 		}
-		_, err := f.NewObject(remote)
+		_, err := f.NewObject(context.TODO(), remote)
 		if err != nil {
 			if err == fs.ErrorObjectNotFound {
 				// File doesn't exist so return old f
@@ -276,7 +436,7 @@ func (f *Fs) newObjectWithInfo(remote string, info *storage.Blob) (fs.Object, er
 
 // NewObject finds the Object at remote.  If it can't be found
 // it returns the error fs.ErrorObjectNotFound.
-func (f *Fs) NewObject(remote string) (fs.Object, error) {
+func (f *Fs) NewObject(ctx context.Context, remote string) (fs.Object, error) {
 	return f.newObjectWithInfo(remote, nil)
 }
 
@@ -453,7 +613,7 @@ func (f *Fs) listContainers(dir string) (entries fs.DirEntries, err error) {
 //
 // This should return ErrDirNotFound if the directory isn't
 // found.
-func (f *Fs) List(dir string) (entries fs.DirEntries, err error) {
+func (f *Fs) List(ctx context.Context, dir string) (entries fs.DirEntries, err error) {
 	if f.container == "" {
 		return f.listContainers(dir)
 	}
@@ -476,7 +636,7 @@ func (f *Fs) List(dir string) (entries fs.DirEntries, err error) {
 //
 // Don't implement this unless you have a more efficient way
 // of listing recursively that doing a directory traversal.
-func (f *Fs) ListR(dir string, callback fs.ListRCallback) (err error) {
+func (f *Fs) ListR(ctx context.Context, dir string, callback fs.ListRCallback) (err error) {
 	if f.container == "" {
 		return fs.ErrorListBucketRequired
 	}
@@ -524,24 +684,24 @@ func (f *Fs) listContainersToFn(fn listContainerFn) error {
 // Copy the reader in to the new object which is returned
 //
 // The new object may have been created if an error is returned
-func (f *Fs) Put(in io.Reader, src fs.ObjectInfo, options ...fs.OpenOption) (fs.Object, error) {
+func (f *Fs) Put(ctx context.Context, in io.Reader, src fs.ObjectInfo, options ...fs.OpenOption) (fs.Object, error) {
 	// Temporary Object under construction
 	fs := &Object{
 		fs:     f,
 		remote: src.Remote(),
 	}
-	return fs, fs.Update(in, src, options...)
+	return fs, fs.Update(ctx, in, src, options...)
 }
 
 // Mkdir creates the container if it doesn't exist
-func (f *Fs) Mkdir(dir string) error {
+func (f *Fs) Mkdir(ctx context.Context, dir string) error {
 	f.containerOKMu.Lock()
 	defer f.containerOKMu.Unlock()
 	if f.containerOK {
 		return nil
 	}
 	options := storage.CreateContainerOptions{
-		Access: storage.ContainerAccessTypePrivate,
+		Access: f.publicAccess,
 	}
 	err := f.pacer.Call(func() (bool, error) {
 		err := f.cc.Create(&options)
@@ -612,7 +772,7 @@ func (f *Fs) deleteContainer() error {
 // Rmdir deletes the container if the fs is at the root
 //
 // Returns an error if it isn't empty
-func (f *Fs) Rmdir(dir string) error {
+func (f *Fs) Rmdir(ctx context.Context, dir string) error {
 	err := f.isEmpty(dir)
 	if err != nil {
 		return err
@@ -634,7 +794,7 @@ func (f *Fs) Hashes() hash.Set {
 }
 
 // Purge deletes all the files and directories including the old versions.
-func (f *Fs) Purge() error {
+func (f *Fs) Purge(ctx context.Context) error {
 	dir := "" // forward compat!
 	if f.root != "" || dir != "" {
 		// Delegate to caller if not root container
@@ -652,8 +812,8 @@ func (f *Fs) Purge() error {
 // Will only be called if src.Fs().Name() == f.Name()
 //
 // If it isn't possible then return fs.ErrorCantCopy
-func (f *Fs) Copy(src fs.Object, remote string) (fs.Object, error) {
-	err := f.Mkdir("")
+func (f *Fs) Copy(ctx context.Context, src fs.Object, remote string) (fs.Object, error) {
+	err := f.Mkdir(ctx, "")
 	if err != nil {
 		return nil, err
 	}
@@ -673,7 +833,7 @@ func (f *Fs) Copy(src fs.Object, remote string) (fs.Object, error) {
 	if err != nil {
 		return nil, err
 	}
-	return f.NewObject(remote)
+	return f.NewObject(ctx, remote)
 }
 
 // ------------------------------------------------------------
@@ -817,7 +977,7 @@ func (o *Object) ModTime() (result time.Time) {
 }
 
 // SetModTime sets the modification time of the local fs object
-func (o *Object) SetModTime(modTime time.Time) error {
+func (o *Object) SetModTime(ctx context.Context, modTime time.Time) error {
 	blob := o.getBlobWithModTime(modTime)
 	options := storage.SetBlobMetadataOptions{}
 	err := o.fs.pacer.Call(func() (bool, error) {
@@ -831,6 +991,134 @@ func (o *Object) SetModTime(modTime time.Time) error {
 	return nil
 }
 
+// signedURL returns the URL of the blob and the query parameters
+// needed to authorize requests which the SDK doesn't support
+func (o *Object) signedURL() (rootURL string, params url.Values, err error) {
+	blob := o.getBlobReference()
+	if o.fs.sasToken != nil {
+		params = url.Values{}
+		for k, v := range o.fs.sasToken {
+			params[k] = v
+		}
+		return blob.GetURL(), params, nil
+	}
+	sasURL, err := blob.GetSASURI(storage.BlobSASOptions{
+		BlobServiceSASPermissions: storage.BlobServiceSASPermissions{
+			Read:  true,
+			Write: true,
+		},
+		SASOptions: storage.SASOptions{
+			Expiry:   time.Now().Add(sasExpiry),
+			UseHTTPS: true,
+		},
+	})
+	if err != nil {
+		return "", nil, errors.Wrap(err, "failed to make SAS URL")
+	}
+	u, err := url.Parse(sasURL)
+	if err != nil {
+		return "", nil, errors.Wrap(err, "failed to parse SAS URL")
+	}
+	params = u.Query()
+	u.RawQuery = ""
+	return u.String(), params, nil
+}
+
+// readTier reads the access tier and the archive status of the blob
+//
+// The archive status is only set while the blob is being rehydrated
+// out of the archive tier.
+func (o *Object) readTier() (tier, archiveStatus string, err error) {
+	rootURL, params, err := o.signedURL()
+	if err != nil {
+		return "", "", err
+	}
+	opts := rest.Opts{
+		Method:     "HEAD",
+		RootURL:    rootURL,
+		Parameters: params,
+		NoResponse: true,
+		ExtraHeaders: map[string]string{
+			"x-ms-version": apiVersion,
+		},
+	}
+	var resp *http.Response
+	err = o.fs.pacer.Call(func() (bool, error) {
+		resp, err = o.fs.srv.Call(&opts)
+		return o.fs.shouldRetry(err)
+	})
+	if err != nil {
+		if storageErr, ok := err.(storage.AzureStorageServiceError); ok && storageErr.StatusCode == http.StatusNotFound {
+			return "", "", fs.ErrorObjectNotFound
+		}
+		return "", "", err
+	}
+	return resp.Header.Get("x-ms-access-tier"), resp.Header.Get("x-ms-archive-status"), nil
+}
+
+// GetTier returns the access tier of the blob or "" if it couldn't
+// be read
+func (o *Object) GetTier() string {
+	if o.tier != "" {
+		return o.tier
+	}
+	tier, _, err := o.readTier()
+	if err != nil {
+		fs.Debugf(o, "Failed to read access tier: %v", err)
+		return ""
+	}
+	o.tier = tier
+	return o.tier
+}
+
+// SetTier changes the access tier of the blob
+//
+// Moving a blob out of the archive tier starts rehydrating it, which
+// can take several hours.  It can't be read until that has finished.
+func (o *Object) SetTier(tier string) error {
+	tier, err := parseAccessTier(tier)
+	if err != nil {
+		return err
+	}
+	rootURL, params, err := o.signedURL()
+	if err != nil {
+		return err
+	}
+	params.Set("comp", "tier")
+	opts := rest.Opts{
+		Method:     "PUT",
+		RootURL:    rootURL,
+		Parameters: params,
+		NoResponse: true,
+		ExtraHeaders: map[string]string{
+			"x-ms-version":     apiVersion,
+			"x-ms-access-tier": tier,
+		},
+	}
+	err = o.fs.pacer.Call(func() (bool, error) {
+		_, err = o.fs.srv.Call(&opts)
+		return o.fs.shouldRetry(err)
+	})
+	if err != nil {
+		return errors.Wrapf(err, "failed to set access tier to %q", tier)
+	}
+	o.tier = tier
+	return nil
+}
+
+// archivedError returns an explanatory error for trying to read a
+// blob in the archive tier
+func (o *Object) archivedError() error {
+	_, archiveStatus, err := o.readTier()
+	if err != nil {
+		fs.Debugf(o, "Failed to read archive status: %v", err)
+	}
+	if strings.HasPrefix(archiveStatus, "rehydrate-pending") {
+		return fserrors.NoRetryError(errors.Errorf("blob is being rehydrated out of the archive tier (%s) - try again later", archiveStatus))
+	}
+	return fserrors.NoRetryError(errors.New("blob is in the archive tier - set its access tier to hot or cool to rehydrate it first"))
+}
+
 // Storable returns if this object is storable
 func (o *Object) Storable() bool {
 	return true
@@ -847,7 +1135,7 @@ type openFile struct {
 }
 
 // Open an object for read
-func (o *Object) Open(options ...fs.OpenOption) (in io.ReadCloser, err error) {
+func (o *Object) Open(ctx context.Context, options ...fs.OpenOption) (in io.ReadCloser, err error) {
 	getBlobOptions := storage.GetBlobOptions{}
 	getBlobRangeOptions := storage.GetBlobRangeOptions{
 		GetBlobOptions: &getBlobOptions,
@@ -887,6 +1175,9 @@ func (o *Object) Open(options ...fs.OpenOption) (in io.ReadCloser, err error) {
 		return o.fs.shouldRetry(err)
 	})
 	if err != nil {
+		if storageErr, ok := err.(storage.AzureStorageServiceError); ok && storageErr.Code == "BlobArchived" {
+			return nil, o.archivedError()
+		}
 		return nil, errors.Wrap(err, "failed to open for download")
 	}
 	return in, nil
@@ -1069,8 +1360,8 @@ outer:
 // Update the object with the contents of the io.Reader, modTime and size
 //
 // The new object may have been created if an error is returned
-func (o *Object) Update(in io.Reader, src fs.ObjectInfo, options ...fs.OpenOption) (err error) {
-	err = o.fs.Mkdir("")
+func (o *Object) Update(ctx context.Context, in io.Reader, src fs.ObjectInfo, options ...fs.OpenOption) (err error) {
+	err = o.fs.Mkdir(ctx, "")
 	if err != nil {
 		return err
 	}
@@ -1104,12 +1395,19 @@ func (o *Object) Update(in io.Reader, src fs.ObjectInfo, options ...fs.OpenOptio
 	if err != nil {
 		return err
 	}
+	o.tier = ""
+	if o.fs.accessTier != "" {
+		err = o.SetTier(o.fs.accessTier)
+		if err != nil {
+			return err
+		}
+	}
 	o.clearMetaData()
 	return o.readMetaData()
 }
 
 // Remove an object
-func (o *Object) Remove() error {
+func (o *Object) Remove(ctx context.Context) error {
 	blob := o.getBlobReference()
 	options := storage.DeleteBlobOptions{}
 	return o.fs.pacer.Call(func() (bool, error) {
@@ -1131,4 +1429,6 @@ var (
 	_ fs.ListRer   = &Fs{}
 	_ fs.Object    = &Object{}
 	_ fs.MimeTyper = &Object{}
+	_ fs.SetTierer = &Object{}
+	_ fs.GetTierer = &Object{}
 )
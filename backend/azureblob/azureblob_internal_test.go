@@ -0,0 +1,75 @@
+// +build go1.7
+
+package azureblob
+
+import (
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/Azure/azure-sdk-for-go/storage"
+	"github.com/ncw/rclone/fs/config"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestInternalParseAccessTier(t *testing.T) {
+	for _, test := range []struct {
+		in      string
+		want    string
+		wantErr bool
+	}{
+		{"hot", accessTierHot, false},
+		{"Cool", accessTierCool, false},
+		{"ARCHIVE", accessTierArchive, false},
+		{"", "", true},
+		{"premium", "", true},
+	} {
+		got, err := parseAccessTier(test.in)
+		if test.wantErr {
+			assert.Error(t, err, test.in)
+		} else {
+			assert.NoError(t, err, test.in)
+		}
+		assert.Equal(t, test.want, got, test.in)
+	}
+}
+
+func TestInternalErrorHandler(t *testing.T) {
+	resp := &http.Response{
+		StatusCode: http.StatusConflict,
+		Status:     "409 Conflict",
+		Header:     http.Header{},
+		Body: ioutil.NopCloser(strings.NewReader(`<?xml version="1.0" encoding="utf-8"?>
+<Error><Code>BlobImmutableDueToPolicy</Code><Message>This operation is not permitted as the blob is immutable due to a policy.</Message></Error>`)),
+	}
+	err := errorHandler(resp)
+	storageErr, ok := err.(storage.AzureStorageServiceError)
+	require.True(t, ok)
+	assert.Equal(t, "BlobImmutableDueToPolicy", storageErr.Code)
+	assert.True(t, isImmutableError(storageErr))
+
+	resp = &http.Response{
+		StatusCode: http.StatusNotFound,
+		Status:     "404 Not Found",
+		Header:     http.Header{},
+		Body:       ioutil.NopCloser(strings.NewReader("")),
+	}
+	err = errorHandler(resp)
+	storageErr, ok = err.(storage.AzureStorageServiceError)
+	require.True(t, ok)
+	assert.Equal(t, http.StatusNotFound, storageErr.StatusCode)
+	assert.False(t, isImmutableError(storageErr))
+}
+
+func TestInternalNewFsSASURLNoDot(t *testing.T) {
+	const name = "TestInternalNewFsSASURLNoDot"
+	config.LoadConfig()
+	config.FileSet(name, "type", "azureblob")
+	config.FileSet(name, "sas_url", "http://localhost:10000/?sv=2017-04-17&sig=xyz")
+
+	_, err := NewFs(name, "container")
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "localhost:10000")
+}
@@ -216,6 +216,22 @@ type HideFileRequest struct {
 	Name     string `json:"fileName"` // The name of the file to hide.
 }
 
+// GetDownloadAuthorizationRequest is passed to
+// b2_get_download_authorization
+type GetDownloadAuthorizationRequest struct {
+	BucketID               string `json:"bucketId"`               // The ID of the bucket that you want to grant access to.
+	FileNamePrefix         string `json:"fileNamePrefix"`         // The file name prefix of files the download authorization token will allow access to.
+	ValidDurationInSeconds int64  `json:"validDurationInSeconds"` // The number of seconds before the authorization token will expire.
+}
+
+// GetDownloadAuthorizationResponse is as returned from
+// b2_get_download_authorization
+type GetDownloadAuthorizationResponse struct {
+	BucketID           string `json:"bucketId"`           // The identifier for the bucket.
+	FileNamePrefix     string `json:"fileNamePrefix"`     // The prefix for files the authorization token will allow access to.
+	AuthorizationToken string `json:"authorizationToken"` // The authorization token that can be passed in the Authorization header or as an Authorization parameter to b2_download_file_by_name.
+}
+
 // GetFileInfoRequest is used to return a FileInfo struct with b2_get_file_info
 type GetFileInfoRequest struct {
 	ID string `json:"fileId"` // The ID of the file, as returned by b2_upload_file, b2_list_file_names, or b2_list_file_versions.
@@ -31,6 +31,7 @@ import (
 	"github.com/ncw/rclone/lib/pacer"
 	"github.com/ncw/rclone/lib/rest"
 	"github.com/pkg/errors"
+	"golang.org/x/net/context"
 )
 
 const (
@@ -43,13 +44,21 @@ const (
 	sha1InfoHeader   = headerPrefix + sha1Key
 	testModeHeader   = "X-Bz-Test-Mode"
 	retryAfterHeader = "Retry-After"
+	maxAuthDuration  = 7 * 24 * time.Hour // longest validity of a download authorization token
 	minSleep         = 10 * time.Millisecond
 	maxSleep         = 5 * time.Minute
 	decayConstant    = 1 // bigger for slower decay, exponential
 	maxParts         = 10000
-	maxVersions      = 100 // maximum number of versions we search in --b2-versions mode
+	maxVersions      = 100            // maximum number of versions we search in --b2-versions mode
+	staleUploadAge   = 24 * time.Hour // unfinished large file uploads older than this are cancelled by CleanUp
 )
 
+var pacerOptions = pacer.AddFlags("b2", pacer.Options{
+	MinSleep:      minSleep,
+	MaxSleep:      maxSleep,
+	DecayConstant: decayConstant,
+})
+
 // Globals
 var (
 	minChunkSize       = fs.SizeSuffix(5E6)
@@ -78,6 +87,7 @@ func init() {
 			Help: "Endpoint for the service - leave blank normally.",
 		},
 		},
+		CommandHelp: commandHelp,
 	})
 	flags.VarP(&uploadCutoff, "b2-upload-cutoff", "", "Cutoff for switching to chunked upload")
 	flags.VarP(&chunkSize, "b2-chunk-size", "", "Upload chunk size. Must fit in memory.")
@@ -259,8 +269,8 @@ func NewFs(name, root string) (fs.Fs, error) {
 		account:      account,
 		key:          key,
 		endpoint:     endpoint,
-		srv:          rest.NewClient(fshttp.NewClient(fs.Config)).SetErrorHandler(errorHandler),
-		pacer:        pacer.New().SetMinSleep(minSleep).SetMaxSleep(maxSleep).SetDecayConstant(decayConstant),
+		srv:          rest.NewClient(fshttp.NewClient(fs.ConfigForRemote(name))).SetErrorHandler(errorHandler),
+		pacer:        pacer.New().SetOptions(pacerOptions),
 		bufferTokens: make(chan []byte, fs.Config.Transfers),
 	}
 	f.features = (&fs.Features{
@@ -293,7 +303,7 @@ func NewFs(name, root string) (fs.Fs, error) {
 		} else {
 			f.root += "/"
 		}
-		_, err := f.NewObject(remote)
+		_, err := f.NewObject(context.TODO(), remote)
 		if err != nil {
 			if err == fs.ErrorObjectNotFound {
 				// File doesn't exist so return old f
@@ -424,7 +434,7 @@ func (f *Fs) newObjectWithInfo(remote string, info *api.File) (fs.Object, error)
 
 // NewObject finds the Object at remote.  If it can't be found
 // it returns the error fs.ErrorObjectNotFound.
-func (f *Fs) NewObject(remote string) (fs.Object, error) {
+func (f *Fs) NewObject(ctx context.Context, remote string) (fs.Object, error) {
 	return f.newObjectWithInfo(remote, nil)
 }
 
@@ -595,7 +605,7 @@ func (f *Fs) listBuckets(dir string) (entries fs.DirEntries, err error) {
 //
 // This should return ErrDirNotFound if the directory isn't
 // found.
-func (f *Fs) List(dir string) (entries fs.DirEntries, err error) {
+func (f *Fs) List(ctx context.Context, dir string) (entries fs.DirEntries, err error) {
 	if f.bucket == "" {
 		return f.listBuckets(dir)
 	}
@@ -618,7 +628,7 @@ func (f *Fs) List(dir string) (entries fs.DirEntries, err error) {
 //
 // Don't implement this unless you have a more efficient way
 // of listing recursively that doing a directory traversal.
-func (f *Fs) ListR(dir string, callback fs.ListRCallback) (err error) {
+func (f *Fs) ListR(ctx context.Context, dir string, callback fs.ListRCallback) (err error) {
 	if f.bucket == "" {
 		return fs.ErrorListBucketRequired
 	}
@@ -704,22 +714,22 @@ func (f *Fs) clearBucketID() {
 // Copy the reader in to the new object which is returned
 //
 // The new object may have been created if an error is returned
-func (f *Fs) Put(in io.Reader, src fs.ObjectInfo, options ...fs.OpenOption) (fs.Object, error) {
+func (f *Fs) Put(ctx context.Context, in io.Reader, src fs.ObjectInfo, options ...fs.OpenOption) (fs.Object, error) {
 	// Temporary Object under construction
 	fs := &Object{
 		fs:     f,
 		remote: src.Remote(),
 	}
-	return fs, fs.Update(in, src, options...)
+	return fs, fs.Update(ctx, in, src, options...)
 }
 
 // PutStream uploads to the remote path with the modTime given of indeterminate size
-func (f *Fs) PutStream(in io.Reader, src fs.ObjectInfo, options ...fs.OpenOption) (fs.Object, error) {
-	return f.Put(in, src, options...)
+func (f *Fs) PutStream(ctx context.Context, in io.Reader, src fs.ObjectInfo, options ...fs.OpenOption) (fs.Object, error) {
+	return f.Put(ctx, in, src, options...)
 }
 
 // Mkdir creates the bucket if it doesn't exist
-func (f *Fs) Mkdir(dir string) error {
+func (f *Fs) Mkdir(ctx context.Context, dir string) error {
 	f.bucketOKMu.Lock()
 	defer f.bucketOKMu.Unlock()
 	if f.bucketOK {
@@ -765,7 +775,7 @@ func (f *Fs) Mkdir(dir string) error {
 // Rmdir deletes the bucket if the fs is at the root
 //
 // Returns an error if it isn't empty
-func (f *Fs) Rmdir(dir string) error {
+func (f *Fs) Rmdir(ctx context.Context, dir string) error {
 	f.bucketOKMu.Lock()
 	defer f.bucketOKMu.Unlock()
 	if f.root != "" || dir != "" {
@@ -802,6 +812,90 @@ func (f *Fs) Precision() time.Duration {
 	return time.Millisecond
 }
 
+var commandHelp = []fs.CommandHelp{{
+	Name:  "download-auth",
+	Short: "Get a download authorization token for the remote",
+	Long: `This command gets an authorization token which can be used to
+download the files in the bucket below the path of the remote, or
+below the path passed as an argument, without the account key.
+
+Usage:
+
+    rclone backend download-auth b2:bucket/path
+    rclone backend download-auth b2:bucket path/to/files -o duration=1h
+
+The token should be passed in the Authorization header or as the
+Authorization parameter to b2_download_file_by_name.
+`,
+	Opts: map[string]string{
+		"duration": "How long the token is valid for, eg 1h - default and maximum 1w",
+	},
+}}
+
+// Command the backend to run a named command
+//
+// The command run is name
+// args may be used to read arguments from
+// opts may be used to read optional arguments from
+//
+// The result should be capable of being JSON encoded
+// If it is a string or a []string it will be shown to the user
+// otherwise it will be JSON encoded and shown to the user like that
+func (f *Fs) Command(ctx context.Context, name string, arg []string, opt map[string]string) (out interface{}, err error) {
+	switch name {
+	case "download-auth":
+		dir := ""
+		if len(arg) > 0 {
+			dir = arg[0]
+		}
+		validDuration := maxAuthDuration
+		if duration := opt["duration"]; duration != "" {
+			d, err := fs.ParseDuration(duration)
+			if err != nil {
+				return nil, errors.Wrap(err, "bad duration")
+			}
+			validDuration = d
+		}
+		return f.getDownloadAuthorization(dir, validDuration)
+	default:
+		return nil, fs.ErrorCommandNotFound
+	}
+}
+
+// getDownloadAuthorization gets a token which allows downloads of
+// files below dir for validDuration
+func (f *Fs) getDownloadAuthorization(dir string, validDuration time.Duration) (*api.GetDownloadAuthorizationResponse, error) {
+	if validDuration <= 0 || validDuration > maxAuthDuration {
+		return nil, errors.Errorf("duration must be between 1s and %v", maxAuthDuration)
+	}
+	bucketID, err := f.getBucketID()
+	if err != nil {
+		return nil, err
+	}
+	prefix := f.root
+	if dir != "" {
+		prefix += dir + "/"
+	}
+	opts := rest.Opts{
+		Method: "POST",
+		Path:   "/b2_get_download_authorization",
+	}
+	var request = api.GetDownloadAuthorizationRequest{
+		BucketID:               bucketID,
+		FileNamePrefix:         prefix,
+		ValidDurationInSeconds: int64(validDuration / time.Second),
+	}
+	var response api.GetDownloadAuthorizationResponse
+	err = f.pacer.Call(func() (bool, error) {
+		resp, err := f.srv.CallJSON(&opts, &request, &response)
+		return f.shouldRetry(resp, err)
+	})
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to get download authorization")
+	}
+	return &response, nil
+}
+
 // hide hides a file on the remote
 func (f *Fs) hide(Name string) error {
 	bucketID, err := f.getBucketID()
@@ -848,12 +942,33 @@ func (f *Fs) deleteByID(ID, Name string) error {
 	return nil
 }
 
+// cancelLargeFile cancels the unfinished large file upload with the ID
+// given, discarding any parts uploaded so far
+func (f *Fs) cancelLargeFile(ID, Name string) error {
+	opts := rest.Opts{
+		Method: "POST",
+		Path:   "/b2_cancel_large_file",
+	}
+	var request = api.CancelLargeFileRequest{
+		ID: ID,
+	}
+	var response api.CancelLargeFileResponse
+	err := f.pacer.Call(func() (bool, error) {
+		resp, err := f.srv.CallJSON(&opts, &request, &response)
+		return f.shouldRetry(resp, err)
+	})
+	if err != nil {
+		return errors.Wrapf(err, "failed to cancel large file upload %q", Name)
+	}
+	return nil
+}
+
 // purge deletes all the files and directories
 //
 // if oldOnly is true then it deletes only non current files.
 //
 // Implemented here so we can make sure we delete old versions.
-func (f *Fs) purge(oldOnly bool) error {
+func (f *Fs) purge(ctx context.Context, oldOnly bool) error {
 	var errReturn error
 	var checkErrMutex sync.Mutex
 	var checkErr = func(err error) {
@@ -876,7 +991,11 @@ func (f *Fs) purge(oldOnly bool) error {
 			defer wg.Done()
 			for object := range toBeDeleted {
 				accounting.Stats.Checking(object.Name)
-				checkErr(f.deleteByID(object.ID, object.Name))
+				if object.Action == "start" {
+					checkErr(f.cancelLargeFile(object.ID, object.Name))
+				} else {
+					checkErr(f.deleteByID(object.ID, object.Name))
+				}
 				accounting.Stats.DoneChecking(object.Name)
 			}
 		}()
@@ -885,6 +1004,18 @@ func (f *Fs) purge(oldOnly bool) error {
 	checkErr(f.list("", true, "", 0, true, func(remote string, object *api.File, isDirectory bool) error {
 		if !isDirectory {
 			accounting.Stats.Checking(remote)
+			if object.Action == "start" {
+				// Unfinished large file uploads aren't versions
+				// of the file so don't update last
+				if !oldOnly || time.Since(time.Time(object.UploadTimestamp)) > staleUploadAge {
+					fs.Debugf(remote, "Cancelling unfinished large file upload (id %q)", object.ID)
+					toBeDeleted <- object
+				} else {
+					fs.Debugf(remote, "Not cancelling recent unfinished large file upload (id %q)", object.ID)
+				}
+				accounting.Stats.DoneChecking(remote)
+				return nil
+			}
 			if oldOnly && last != remote {
 				if object.Action == "hide" {
 					fs.Debugf(remote, "Deleting current version (id %q) as it is a hide marker", object.ID)
@@ -905,19 +1036,20 @@ func (f *Fs) purge(oldOnly bool) error {
 	wg.Wait()
 
 	if !oldOnly {
-		checkErr(f.Rmdir(""))
+		checkErr(f.Rmdir(ctx, ""))
 	}
 	return errReturn
 }
 
 // Purge deletes all the files and directories including the old versions.
-func (f *Fs) Purge() error {
-	return f.purge(false)
+func (f *Fs) Purge(ctx context.Context) error {
+	return f.purge(ctx, false)
 }
 
-// CleanUp deletes all the hidden files.
-func (f *Fs) CleanUp() error {
-	return f.purge(true)
+// CleanUp deletes all the hidden files and old versions and cancels
+// any unfinished large file uploads older than a day.
+func (f *Fs) CleanUp(ctx context.Context) error {
+	return f.purge(ctx, true)
 }
 
 // Hashes returns the supported hash sets.
@@ -1086,7 +1218,7 @@ func (o *Object) ModTime() (result time.Time) {
 }
 
 // SetModTime sets the modification time of the local fs object
-func (o *Object) SetModTime(modTime time.Time) error {
+func (o *Object) SetModTime(ctx context.Context, modTime time.Time) error {
 	// Not possible with B2
 	return fs.ErrorCantSetModTime
 }
@@ -1157,7 +1289,7 @@ func (file *openFile) Close() (err error) {
 var _ io.ReadCloser = &openFile{}
 
 // Open an object for read
-func (o *Object) Open(options ...fs.OpenOption) (in io.ReadCloser, err error) {
+func (o *Object) Open(ctx context.Context, options ...fs.OpenOption) (in io.ReadCloser, err error) {
 	opts := rest.Opts{
 		Method:  "GET",
 		RootURL: o.fs.info.DownloadURL,
@@ -1240,11 +1372,11 @@ func urlEncode(in string) string {
 // Update the object with the contents of the io.Reader, modTime and size
 //
 // The new object may have been created if an error is returned
-func (o *Object) Update(in io.Reader, src fs.ObjectInfo, options ...fs.OpenOption) (err error) {
+func (o *Object) Update(ctx context.Context, in io.Reader, src fs.ObjectInfo, options ...fs.OpenOption) (err error) {
 	if *b2Versions {
 		return errNotWithVersions
 	}
-	err = o.fs.Mkdir("")
+	err = o.fs.Mkdir(ctx, "")
 	if err != nil {
 		return err
 	}
@@ -1370,11 +1502,6 @@ func (o *Object) Update(in io.Reader, src fs.ObjectInfo, options ...fs.OpenOptio
 		},
 		ContentLength: &size,
 	}
-	// for go1.8 (see release notes) we must nil the Body if we want a
-	// "Content-Length: 0" header which b2 requires for all files.
-	if size == 0 {
-		opts.Body = nil
-	}
 	var response api.FileInfo
 	// Don't retry, return a retry error instead
 	err = o.fs.pacer.CallNoRetry(func() (bool, error) {
@@ -1394,7 +1521,7 @@ func (o *Object) Update(in io.Reader, src fs.ObjectInfo, options ...fs.OpenOptio
 }
 
 // Remove an object
-func (o *Object) Remove() error {
+func (o *Object) Remove(ctx context.Context) error {
 	if *b2Versions {
 		return errNotWithVersions
 	}
@@ -1409,6 +1536,11 @@ func (o *Object) MimeType() string {
 	return o.mimeType
 }
 
+// ID returns the ID of the Object if known, or "" if not
+func (o *Object) ID() string {
+	return o.id
+}
+
 // Check the interfaces are satisfied
 var (
 	_ fs.Fs          = &Fs{}
@@ -1416,6 +1548,8 @@ var (
 	_ fs.PutStreamer = &Fs{}
 	_ fs.CleanUpper  = &Fs{}
 	_ fs.ListRer     = &Fs{}
+	_ fs.Commander   = &Fs{}
 	_ fs.Object      = &Object{}
+	_ fs.IDer        = &Object{}
 	_ fs.MimeTyper   = &Object{}
 )
@@ -280,19 +280,7 @@ func (up *largeUpload) finish() error {
 
 // cancel aborts the large upload
 func (up *largeUpload) cancel() error {
-	opts := rest.Opts{
-		Method: "POST",
-		Path:   "/b2_cancel_large_file",
-	}
-	var request = api.CancelLargeFileRequest{
-		ID: up.id,
-	}
-	var response api.CancelLargeFileResponse
-	err := up.f.pacer.Call(func() (bool, error) {
-		resp, err := up.f.srv.CallJSON(&opts, &request, &response)
-		return up.f.shouldRetry(resp, err)
-	})
-	return err
+	return up.f.cancelLargeFile(up.id, up.o.remote)
 }
 
 func (up *largeUpload) managedTransferChunk(wg *sync.WaitGroup, errs chan error, part int64, buf []byte) {
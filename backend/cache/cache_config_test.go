@@ -0,0 +1,17 @@
+// +build !plan9,go1.7
+
+package cache_test
+
+import (
+	"github.com/ncw/rclone/fstest/fstests"
+)
+
+// Create the TestCacheMemory: remote which wraps the memory backend
+// so the conformance tests can run without any external config.
+func init() {
+	name := "TestCacheMemory"
+	fstests.ExtraConfig = append(fstests.ExtraConfig, []fstests.ExtraConfigItem{
+		{Name: name, Key: "type", Value: "cache"},
+		{Name: name, Key: "remote", Value: ":memory:rclone-cache-test"},
+	}...)
+}
@@ -12,6 +12,7 @@ import (
 
 	"github.com/ncw/rclone/backend/cache"
 	_ "github.com/ncw/rclone/backend/local"
+	_ "github.com/ncw/rclone/backend/memory"
 	"github.com/ncw/rclone/fs"
 	"github.com/ncw/rclone/fstest/fstests"
 )
@@ -74,5 +75,6 @@ func TestFsIsFile(t *testing.T)            { fstests.TestFsIsFile(t) }
 func TestFsIsFileNotFound(t *testing.T)    { fstests.TestFsIsFileNotFound(t) }
 func TestObjectRemove(t *testing.T)        { fstests.TestObjectRemove(t) }
 func TestFsPutStream(t *testing.T)         { fstests.TestFsPutStream(t) }
+func TestFsPutZeroLength(t *testing.T)     { fstests.TestFsPutZeroLength(t) }
 func TestObjectPurge(t *testing.T)         { fstests.TestObjectPurge(t) }
 func TestFinalise(t *testing.T)            { fstests.TestFinalise(t) }
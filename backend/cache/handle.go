@@ -16,6 +16,7 @@ import (
 	"github.com/ncw/rclone/fs"
 	"github.com/ncw/rclone/fs/operations"
 	"github.com/pkg/errors"
+	"golang.org/x/net/context"
 )
 
 var uploaderMap = make(map[string]*backgroundWriter)
@@ -42,6 +43,7 @@ func initBackgroundUploader(fs *Fs) (*backgroundWriter, error) {
 
 // Handle is managing the read/write/seek operations on an open handle
 type Handle struct {
+	ctx            context.Context
 	cachedObject   *Object
 	cfs            *Fs
 	memory         *Memory
@@ -59,8 +61,9 @@ type Handle struct {
 }
 
 // NewObjectHandle returns a new Handle for an existing Object
-func NewObjectHandle(o *Object, cfs *Fs) *Handle {
+func NewObjectHandle(ctx context.Context, o *Object, cfs *Fs) *Handle {
 	r := &Handle{
+		ctx:           ctx,
 		cachedObject:  o,
 		cfs:           cfs,
 		offset:        0,
@@ -389,7 +392,7 @@ func (w *worker) reader(offset, end int64, closeOpen bool) (io.ReadCloser, error
 	r := w.rc
 	if w.rc == nil {
 		r, err = w.r.cacheFs().openRateLimited(func() (io.ReadCloser, error) {
-			return w.r.cachedObject.Object.Open(&fs.SeekOption{Offset: offset}, &fs.RangeOption{Start: offset, End: end})
+			return w.r.cachedObject.Object.Open(w.r.ctx, &fs.SeekOption{Offset: offset}, &fs.RangeOption{Start: offset, End: end})
 		})
 		if err != nil {
 			return nil, err
@@ -407,7 +410,7 @@ func (w *worker) reader(offset, end int64, closeOpen bool) (io.ReadCloser, error
 
 	_ = w.rc.Close()
 	return w.r.cacheFs().openRateLimited(func() (io.ReadCloser, error) {
-		r, err = w.r.cachedObject.Object.Open(&fs.SeekOption{Offset: offset}, &fs.RangeOption{Start: offset, End: end})
+		r, err = w.r.cachedObject.Object.Open(w.r.ctx, &fs.SeekOption{Offset: offset}, &fs.RangeOption{Start: offset, End: end})
 		if err != nil {
 			return nil, err
 		}
@@ -500,7 +503,7 @@ func (w *worker) download(chunkStart, chunkEnd int64, retry int) {
 	// we seem to be getting only errors so we abort
 	if err != nil {
 		fs.Errorf(w, "object open failed %v: %v", chunkStart, err)
-		err = w.r.cachedObject.refreshFromSource(true)
+		err = w.r.cachedObject.refreshFromSource(w.r.ctx, true)
 		if err != nil {
 			fs.Errorf(w, "%v", err)
 		}
@@ -513,7 +516,7 @@ func (w *worker) download(chunkStart, chunkEnd int64, retry int) {
 	sourceRead, err = io.ReadFull(w.rc, data)
 	if err != nil && err != io.EOF && err != io.ErrUnexpectedEOF {
 		fs.Errorf(w, "failed to read chunk %v: %v", chunkStart, err)
-		err = w.r.cachedObject.refreshFromSource(true)
+		err = w.r.cachedObject.refreshFromSource(w.r.ctx, true)
 		if err != nil {
 			fs.Errorf(w, "%v", err)
 		}
@@ -549,6 +552,30 @@ const (
 	BackgroundUploadError
 )
 
+const (
+	// uploadRetryMinSleep is how long to wait before retrying the
+	// first failed background upload
+	uploadRetryMinSleep = time.Second
+	// uploadRetryMaxSleep is the longest wait between retries of
+	// failed background uploads, eg if the remote is unreachable
+	uploadRetryMaxSleep = 5 * time.Minute
+)
+
+// nextUploadRetrySleep returns how long to wait before the next retry
+// of a failed background upload given the previous wait
+//
+// It doubles the wait each time up to uploadRetryMaxSleep
+func nextUploadRetrySleep(sleep time.Duration) time.Duration {
+	if sleep <= 0 {
+		return uploadRetryMinSleep
+	}
+	sleep *= 2
+	if sleep > uploadRetryMaxSleep {
+		sleep = uploadRetryMaxSleep
+	}
+	return sleep
+}
+
 // BackgroundUploadState is an entity that maps to an existing file which is stored on the temp fs
 type BackgroundUploadState struct {
 	Remote string
@@ -609,8 +636,9 @@ func (b *backgroundWriter) notify(remote string, status int, err error) {
 	}
 }
 
-func (b *backgroundWriter) run() {
+func (b *backgroundWriter) run(ctx context.Context) {
 	state := 0
+	retrySleep := time.Duration(0)
 	for {
 		b.mu.Lock()
 		b.running = true
@@ -639,13 +667,21 @@ func (b *backgroundWriter) run() {
 		remote := b.fs.cleanRootFromPath(absPath)
 		b.notify(remote, BackgroundUploadStarted, nil)
 		fs.Infof(remote, "background upload: started upload")
-		err = operations.MoveFile(b.fs.UnWrap(), b.fs.tempFs, remote, remote)
+		err = operations.MoveFile(ctx, b.fs.UnWrap(), b.fs.tempFs, remote, remote)
 		if err != nil {
 			b.notify(remote, BackgroundUploadError, err)
 			_ = b.fs.cache.rollbackPendingUpload(absPath)
-			fs.Errorf(remote, "background upload: %v", err)
+			// back off so we don't hammer the remote if it is
+			// unreachable - the upload stays queued until then
+			retrySleep = nextUploadRetrySleep(retrySleep)
+			fs.Errorf(remote, "background upload: %v - retrying in %v", err, retrySleep)
+			select {
+			case state = <-b.stateCh:
+			case <-time.After(retrySleep):
+			}
 			continue
 		}
+		retrySleep = 0
 		fs.Infof(remote, "background upload: uploaded entry")
 		err = b.fs.cache.removePendingUpload(absPath)
 		if err != nil && !strings.Contains(err.Error(), "pending upload not found") {
@@ -9,6 +9,7 @@ import (
 
 	"github.com/ncw/rclone/backend/crypt"
 	_ "github.com/ncw/rclone/backend/local"
+	_ "github.com/ncw/rclone/backend/memory"
 	"github.com/ncw/rclone/fs"
 	"github.com/ncw/rclone/fstest/fstests"
 )
@@ -71,5 +72,6 @@ func TestFsIsFile3(t *testing.T)            { fstests.TestFsIsFile(t) }
 func TestFsIsFileNotFound3(t *testing.T)    { fstests.TestFsIsFileNotFound(t) }
 func TestObjectRemove3(t *testing.T)        { fstests.TestObjectRemove(t) }
 func TestFsPutStream3(t *testing.T)         { fstests.TestFsPutStream(t) }
+func TestFsPutZeroLength3(t *testing.T)     { fstests.TestFsPutZeroLength(t) }
 func TestObjectPurge3(t *testing.T)         { fstests.TestObjectPurge(t) }
 func TestFinalise3(t *testing.T)            { fstests.TestFinalise(t) }
@@ -8,7 +8,11 @@ import (
 	"github.com/ncw/rclone/fstest/fstests"
 )
 
-// Create the TestCrypt: remote
+// Create the TestCrypt: remotes
+//
+// TestCryptMemory: wraps the memory backend so the optional features
+// crypt passes through from its base (Copy, Move, ListR...) get tested
+// without any external config.
 func init() {
 	tempdir := filepath.Join(os.TempDir(), "rclone-crypt-test-standard")
 	name := "TestCrypt"
@@ -16,6 +20,7 @@ func init() {
 	name2 := name + "2"
 	tempdir3 := filepath.Join(os.TempDir(), "rclone-crypt-test-obfuscate")
 	name3 := name + "3"
+	nameMemory := name + "Memory"
 	fstests.ExtraConfig = []fstests.ExtraConfigItem{
 		{Name: name, Key: "type", Value: "crypt"},
 		{Name: name, Key: "remote", Value: tempdir},
@@ -29,6 +34,10 @@ func init() {
 		{Name: name3, Key: "remote", Value: tempdir3},
 		{Name: name3, Key: "password", Value: obscure.MustObscure("potato2")},
 		{Name: name3, Key: "filename_encryption", Value: "obfuscate"},
+		{Name: nameMemory, Key: "type", Value: "crypt"},
+		{Name: nameMemory, Key: "remote", Value: ":memory:rclone-crypt-test"},
+		{Name: nameMemory, Key: "password", Value: obscure.MustObscure("potato")},
+		{Name: nameMemory, Key: "filename_encryption", Value: "standard"},
 	}
 	fstests.SkipBadWindowsCharacters[name3+":"] = true
 }
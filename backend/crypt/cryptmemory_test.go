@@ -0,0 +1,77 @@
+// Test Crypt filesystem interface
+//
+// Automatically generated - DO NOT EDIT
+// Regenerate with: make gen_tests
+package crypt_test
+
+import (
+	"testing"
+
+	"github.com/ncw/rclone/backend/crypt"
+	_ "github.com/ncw/rclone/backend/local"
+	_ "github.com/ncw/rclone/backend/memory"
+	"github.com/ncw/rclone/fs"
+	"github.com/ncw/rclone/fstest/fstests"
+)
+
+func TestSetupMemory(t *testing.T) {
+	fstests.NilObject = fs.Object((*crypt.Object)(nil))
+	fstests.RemoteName = "TestCryptMemory:"
+}
+
+// Generic tests for the Fs
+func TestInitMemory(t *testing.T)                { fstests.TestInit(t) }
+func TestFsStringMemory(t *testing.T)            { fstests.TestFsString(t) }
+func TestFsNameMemory(t *testing.T)              { fstests.TestFsName(t) }
+func TestFsRootMemory(t *testing.T)              { fstests.TestFsRoot(t) }
+func TestFsRmdirEmptyMemory(t *testing.T)        { fstests.TestFsRmdirEmpty(t) }
+func TestFsRmdirNotFoundMemory(t *testing.T)     { fstests.TestFsRmdirNotFound(t) }
+func TestFsMkdirMemory(t *testing.T)             { fstests.TestFsMkdir(t) }
+func TestFsMkdirRmdirSubdirMemory(t *testing.T)  { fstests.TestFsMkdirRmdirSubdir(t) }
+func TestFsListEmptyMemory(t *testing.T)         { fstests.TestFsListEmpty(t) }
+func TestFsListDirEmptyMemory(t *testing.T)      { fstests.TestFsListDirEmpty(t) }
+func TestFsListRDirEmptyMemory(t *testing.T)     { fstests.TestFsListRDirEmpty(t) }
+func TestFsNewObjectNotFoundMemory(t *testing.T) { fstests.TestFsNewObjectNotFound(t) }
+func TestFsPutFile1Memory(t *testing.T)          { fstests.TestFsPutFile1(t) }
+func TestFsPutErrorMemory(t *testing.T)          { fstests.TestFsPutError(t) }
+func TestFsPutFile2Memory(t *testing.T)          { fstests.TestFsPutFile2(t) }
+func TestFsUpdateFile1Memory(t *testing.T)       { fstests.TestFsUpdateFile1(t) }
+func TestFsListDirFile2Memory(t *testing.T)      { fstests.TestFsListDirFile2(t) }
+func TestFsListRDirFile2Memory(t *testing.T)     { fstests.TestFsListRDirFile2(t) }
+func TestFsListDirRootMemory(t *testing.T)       { fstests.TestFsListDirRoot(t) }
+func TestFsListRDirRootMemory(t *testing.T)      { fstests.TestFsListRDirRoot(t) }
+func TestFsListSubdirMemory(t *testing.T)        { fstests.TestFsListSubdir(t) }
+func TestFsListRSubdirMemory(t *testing.T)       { fstests.TestFsListRSubdir(t) }
+func TestFsListLevel2Memory(t *testing.T)        { fstests.TestFsListLevel2(t) }
+func TestFsListRLevel2Memory(t *testing.T)       { fstests.TestFsListRLevel2(t) }
+func TestFsListFile1Memory(t *testing.T)         { fstests.TestFsListFile1(t) }
+func TestFsNewObjectMemory(t *testing.T)         { fstests.TestFsNewObject(t) }
+func TestFsListFile1and2Memory(t *testing.T)     { fstests.TestFsListFile1and2(t) }
+func TestFsNewObjectDirMemory(t *testing.T)      { fstests.TestFsNewObjectDir(t) }
+func TestFsCopyMemory(t *testing.T)              { fstests.TestFsCopy(t) }
+func TestFsMoveMemory(t *testing.T)              { fstests.TestFsMove(t) }
+func TestFsDirMoveMemory(t *testing.T)           { fstests.TestFsDirMove(t) }
+func TestFsRmdirFullMemory(t *testing.T)         { fstests.TestFsRmdirFull(t) }
+func TestFsPrecisionMemory(t *testing.T)         { fstests.TestFsPrecision(t) }
+func TestFsDirChangeNotifyMemory(t *testing.T)   { fstests.TestFsDirChangeNotify(t) }
+func TestObjectStringMemory(t *testing.T)        { fstests.TestObjectString(t) }
+func TestObjectFsMemory(t *testing.T)            { fstests.TestObjectFs(t) }
+func TestObjectRemoteMemory(t *testing.T)        { fstests.TestObjectRemote(t) }
+func TestObjectHashesMemory(t *testing.T)        { fstests.TestObjectHashes(t) }
+func TestObjectModTimeMemory(t *testing.T)       { fstests.TestObjectModTime(t) }
+func TestObjectMimeTypeMemory(t *testing.T)      { fstests.TestObjectMimeType(t) }
+func TestObjectSetModTimeMemory(t *testing.T)    { fstests.TestObjectSetModTime(t) }
+func TestObjectSizeMemory(t *testing.T)          { fstests.TestObjectSize(t) }
+func TestObjectOpenMemory(t *testing.T)          { fstests.TestObjectOpen(t) }
+func TestObjectOpenSeekMemory(t *testing.T)      { fstests.TestObjectOpenSeek(t) }
+func TestObjectOpenRangeMemory(t *testing.T)     { fstests.TestObjectOpenRange(t) }
+func TestObjectPartialReadMemory(t *testing.T)   { fstests.TestObjectPartialRead(t) }
+func TestObjectUpdateMemory(t *testing.T)        { fstests.TestObjectUpdate(t) }
+func TestObjectStorableMemory(t *testing.T)      { fstests.TestObjectStorable(t) }
+func TestFsIsFileMemory(t *testing.T)            { fstests.TestFsIsFile(t) }
+func TestFsIsFileNotFoundMemory(t *testing.T)    { fstests.TestFsIsFileNotFound(t) }
+func TestObjectRemoveMemory(t *testing.T)        { fstests.TestObjectRemove(t) }
+func TestFsPutStreamMemory(t *testing.T)         { fstests.TestFsPutStream(t) }
+func TestFsPutZeroLengthMemory(t *testing.T)     { fstests.TestFsPutZeroLength(t) }
+func TestObjectPurgeMemory(t *testing.T)         { fstests.TestObjectPurge(t) }
+func TestFinaliseMemory(t *testing.T)            { fstests.TestFinalise(t) }
@@ -0,0 +1,392 @@
+// Re-encrypt a crypt remote with a new password
+
+package crypt
+
+import (
+	"bufio"
+	"bytes"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+
+	"github.com/ncw/rclone/fs"
+	"github.com/ncw/rclone/fs/config"
+	"github.com/ncw/rclone/fs/config/obscure"
+	"github.com/ncw/rclone/fs/object"
+	"github.com/ncw/rclone/fs/operations"
+	"github.com/ncw/rclone/fs/walk"
+	"github.com/pkg/errors"
+	"golang.org/x/net/context"
+)
+
+// Config keys holding the new passwords while a rekey is in progress
+const (
+	rekeyPasswordKey  = "rekey_password"
+	rekeyPassword2Key = "rekey_password2"
+)
+
+// rekeyTempSuffix is added to the underlying name of a file which is
+// uploaded before being moved over the original
+const rekeyTempSuffix = ".rclone-rekey"
+
+var commandHelp = []fs.CommandHelp{{
+	Name:  "rekey",
+	Short: "Re-encrypt the remote with a new password",
+	Long: `This command reads every file in the crypt remote with the current
+password and writes it back with a new password and salt.  When it
+has finished the new passwords are written to the config file.
+
+Usage:
+
+    rclone backend rekey crypt: -o password=NEW -o password2=NEWSALT
+
+The data is downloaded and uploaded again so this takes as long as a
+full copy of the remote, but it doesn't need any local disk space.
+
+If the command is interrupted or some files fail then run it again,
+without the -o options if you like, and it will carry on where it left
+off.  The new passwords are stored in the config file and the files
+already done are recorded in the rclone cache directory until it
+completes.  Until then the remote is only partly readable with either
+password, so don't use it for anything else.
+
+This must be run on the root of the crypt remote.
+`,
+	Opts: map[string]string{
+		"password":  "The new password - required unless resuming",
+		"password2": "The new password for the salt - optional",
+	},
+}}
+
+// Command the backend to run a named command
+//
+// The command run is name
+// args may be used to read arguments from
+// opts may be used to read optional arguments from
+//
+// The result should be capable of being JSON encoded
+// If it is a string or a []string it will be shown to the user
+// otherwise it will be JSON encoded and shown to the user like that
+func (f *Fs) Command(ctx context.Context, name string, arg []string, opt map[string]string) (out interface{}, err error) {
+	switch name {
+	case "rekey":
+		return f.rekey(ctx, opt["password"], opt["password2"])
+	default:
+		return nil, fs.ErrorCommandNotFound
+	}
+}
+
+// rekeyStats is the result of the rekey command
+type rekeyStats struct {
+	Rekeyed int `json:"rekeyed"` // files re-encrypted
+	Skipped int `json:"skipped"` // files done by a previous run
+	Dirs    int `json:"dirs"`    // directories renamed
+	Errors  int `json:"errors"`  // files or directories which failed
+}
+
+// rekeyState records the underlying names, as they were with the old
+// password, of the files and directories which have been re-encrypted
+// so an interrupted rekey can be resumed.
+type rekeyState struct {
+	path string
+	done map[string]struct{}
+	out  *os.File
+}
+
+// openRekeyState opens the state file for the remote called name
+// reading the names already done.
+func openRekeyState(name string) (s *rekeyState, err error) {
+	dir := filepath.Join(config.CacheDir, "rekey")
+	err = os.MkdirAll(dir, 0700)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to make rekey state directory")
+	}
+	s = &rekeyState{
+		path: filepath.Join(dir, name+".txt"),
+		done: make(map[string]struct{}),
+	}
+	in, err := os.Open(s.path)
+	if err == nil {
+		scanner := bufio.NewScanner(in)
+		for scanner.Scan() {
+			s.done[scanner.Text()] = struct{}{}
+		}
+		err = scanner.Err()
+		_ = in.Close()
+		if err != nil {
+			return nil, errors.Wrap(err, "failed to read rekey state")
+		}
+	} else if !os.IsNotExist(err) {
+		return nil, errors.Wrap(err, "failed to open rekey state")
+	}
+	s.out, err = os.OpenFile(s.path, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0600)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to open rekey state")
+	}
+	return s, nil
+}
+
+// isDone returns whether the underlying name has been re-encrypted
+func (s *rekeyState) isDone(name string) bool {
+	_, ok := s.done[name]
+	return ok
+}
+
+// markDone records that the underlying name has been re-encrypted
+func (s *rekeyState) markDone(name string) error {
+	s.done[name] = struct{}{}
+	_, err := io.WriteString(s.out, name+"\n")
+	return err
+}
+
+// close the state file, removing it if finished is set
+func (s *rekeyState) close(finished bool) error {
+	err := s.out.Close()
+	if finished {
+		err = os.Remove(s.path)
+	}
+	return err
+}
+
+// rekeyPasswords returns the new passwords, storing them in the config
+// file if a rekey isn't already in progress.
+func (f *Fs) rekeyPasswords(password, password2 string) (string, string, error) {
+	pending := config.FileGet(f.name, rekeyPasswordKey)
+	if pending != "" {
+		pendingPassword, err := obscure.Reveal(pending)
+		if err != nil {
+			return "", "", errors.Wrap(err, "failed to decrypt "+rekeyPasswordKey)
+		}
+		pendingPassword2 := config.FileGet(f.name, rekeyPassword2Key)
+		if pendingPassword2 != "" {
+			pendingPassword2, err = obscure.Reveal(pendingPassword2)
+			if err != nil {
+				return "", "", errors.Wrap(err, "failed to decrypt "+rekeyPassword2Key)
+			}
+		}
+		if password != "" && (password != pendingPassword || password2 != pendingPassword2) {
+			return "", "", errors.New("a rekey to different passwords is in progress - run the command again with those or no passwords")
+		}
+		fs.Logf(f, "Resuming rekey")
+		return pendingPassword, pendingPassword2, nil
+	}
+	if password == "" {
+		return "", "", errors.New("the new password must be set with -o password=...")
+	}
+	config.FileSet(f.name, rekeyPasswordKey, obscure.MustObscure(password))
+	if password2 != "" {
+		config.FileSet(f.name, rekeyPassword2Key, obscure.MustObscure(password2))
+	}
+	config.SaveConfig()
+	return password, password2, nil
+}
+
+// rekey re-encrypts all the files in f with the new passwords
+func (f *Fs) rekey(ctx context.Context, password, password2 string) (stats rekeyStats, err error) {
+	if f.root != "" {
+		return stats, errors.New("rekey must be run on the root of the crypt remote")
+	}
+	if fs.Config.DryRun {
+		return stats, errors.New("rekey doesn't support --dry-run")
+	}
+	password, password2, err = f.rekeyPasswords(password, password2)
+	if err != nil {
+		return stats, err
+	}
+	cipher, err := newCipher(f.mode, password, password2, f.dirNameEncrypt)
+	if err != nil {
+		return stats, errors.Wrap(err, "failed to make cipher")
+	}
+	// newF is f as it will be with the new passwords
+	newF := *f
+	newF.cipher = cipher
+
+	state, err := openRekeyState(f.name)
+	if err != nil {
+		return stats, err
+	}
+	finished := false
+	defer func() {
+		if closeErr := state.close(finished); closeErr != nil {
+			fs.Errorf(f, "Failed to close rekey state: %v", closeErr)
+		}
+	}()
+
+	// Read the whole listing first as the files written are in
+	// the same remote
+	var (
+		objects []*Object
+		dirs    []string
+	)
+	err = walk.Walk(ctx, f, "", true, -1, func(dirPath string, entries fs.DirEntries, err error) error {
+		if err != nil {
+			return err
+		}
+		if dirPath != "" && state.isDone(f.cipher.EncryptDirName(dirPath)+"/") {
+			return walk.ErrorSkipDir
+		}
+		for _, entry := range entries {
+			switch x := entry.(type) {
+			case *Object:
+				objects = append(objects, x)
+			case fs.Directory:
+				dirs = append(dirs, x.Remote())
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return stats, errors.Wrap(err, "failed to list remote")
+	}
+
+	for _, o := range objects {
+		if state.isDone(o.Object.Remote()) {
+			// Interrupted before the original was removed
+			if f.cipher.EncryptFileName(o.Remote()) != cipher.EncryptFileName(o.Remote()) {
+				if err = o.Object.Remove(ctx); err != nil {
+					fs.Debugf(o, "Failed to remove original: %v", err)
+				}
+			}
+			stats.Skipped++
+			continue
+		}
+		skipped, err := f.rekeyObject(ctx, &newF, o, state)
+		switch {
+		case err != nil:
+			fs.CountError(err)
+			fs.Errorf(o, "Failed to rekey: %v", err)
+			stats.Errors++
+		case skipped:
+			stats.Skipped++
+		default:
+			fs.Infof(o, "Rekeyed")
+			stats.Rekeyed++
+		}
+	}
+
+	// Make the new directories and remove the old ones, deepest
+	// first, if their names have changed
+	sort.Sort(sort.Reverse(sort.StringSlice(dirs)))
+	for _, dir := range dirs {
+		oldName := f.cipher.EncryptDirName(dir)
+		if oldName == cipher.EncryptDirName(dir) {
+			continue
+		}
+		if !state.isDone(oldName + "/") {
+			err = f.rekeyDir(ctx, &newF, dir, state)
+			if err != nil {
+				fs.CountError(err)
+				fs.Errorf(dir, "Failed to make directory: %v", err)
+				stats.Errors++
+				continue
+			}
+			stats.Dirs++
+		}
+		if err = f.Fs.Rmdir(ctx, oldName); err != nil {
+			fs.Debugf(dir, "Failed to remove old directory: %v", err)
+		}
+	}
+
+	if stats.Errors != 0 {
+		return stats, errors.Errorf("failed to rekey %d files or directories - run the command again to finish", stats.Errors)
+	}
+	config.FileSet(f.name, "password", obscure.MustObscure(password))
+	if password2 != "" {
+		config.FileSet(f.name, "password2", obscure.MustObscure(password2))
+	} else {
+		config.FileDeleteKey(f.name, "password2")
+	}
+	config.FileDeleteKey(f.name, rekeyPasswordKey)
+	config.FileDeleteKey(f.name, rekeyPassword2Key)
+	config.SaveConfig()
+	finished = true
+	fs.Logf(f, "Rekey complete - new passwords saved in the config file")
+	return stats, nil
+}
+
+// rekeyDir makes dir with the new name from newF and records it as
+// done.  The old directory is left for the caller to remove.
+func (f *Fs) rekeyDir(ctx context.Context, newF *Fs, dir string, state *rekeyState) error {
+	err := newF.Mkdir(ctx, dir)
+	if err != nil {
+		return err
+	}
+	return state.markDone(f.cipher.EncryptDirName(dir) + "/")
+}
+
+// isRekeyed returns whether the underlying object o can be read with
+// the cipher of f.
+func (f *Fs) isRekeyed(ctx context.Context, o fs.Object) bool {
+	in, err := f.newObject(o).Open(ctx)
+	if err != nil {
+		return false
+	}
+	var buf [1]byte
+	_, err = io.ReadFull(in, buf[:])
+	_ = in.Close()
+	return err == nil || err == io.EOF
+}
+
+// rekeyObject re-encrypts o with the cipher of newF
+//
+// It returns skipped if o can't be read with the old cipher but can
+// be with the new, which means a previous run rekeyed it.
+func (f *Fs) rekeyObject(ctx context.Context, newF *Fs, o *Object, state *rekeyState) (skipped bool, err error) {
+	oldName := o.Object.Remote()
+	newName := newF.cipher.EncryptFileName(o.Remote())
+	tempName := oldName + rekeyTempSuffix
+	err = func() (err error) {
+		in, err := o.Open(ctx)
+		if err != nil {
+			return err
+		}
+		defer fs.CheckClose(in, &err)
+		// Read the first byte to check the old cipher works
+		var buf [1]byte
+		n, err := io.ReadFull(in, buf[:])
+		if err == io.EOF {
+			err = nil
+		}
+		if err != nil {
+			if newF.isRekeyed(ctx, o.Object) {
+				skipped = true
+				return nil
+			}
+			return errors.Wrap(err, "failed to decrypt")
+		}
+		plaintext := io.MultiReader(bytes.NewReader(buf[:n]), in)
+		if newName != oldName {
+			_, err = newF.Put(ctx, plaintext, o)
+			return err
+		}
+		// The name is the same so upload to a temporary name
+		// and move that over the original
+		encrypted, err := newF.cipher.EncryptData(plaintext)
+		if err != nil {
+			return err
+		}
+		info := object.NewStaticObjectInfo(tempName, o.ModTime(), newF.cipher.EncryptedSize(o.Size()), true, nil, f.Fs)
+		_, err = f.Fs.Put(ctx, encrypted, info)
+		return err
+	}()
+	if err != nil || skipped {
+		return skipped, err
+	}
+	if newName != oldName {
+		err = state.markDone(oldName)
+		if err != nil {
+			return false, err
+		}
+		return false, o.Object.Remove(ctx)
+	}
+	temp, err := f.Fs.NewObject(ctx, tempName)
+	if err != nil {
+		return false, errors.Wrap(err, "failed to find uploaded file")
+	}
+	_, err = operations.Move(ctx, f.Fs, o.Object, oldName, temp)
+	if err != nil {
+		return false, err
+	}
+	return false, state.markDone(oldName)
+}
@@ -0,0 +1,252 @@
+package crypt
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	_ "github.com/ncw/rclone/backend/memory"
+	"github.com/ncw/rclone/fs"
+	"github.com/ncw/rclone/fs/config"
+	"github.com/ncw/rclone/fs/config/obscure"
+	"github.com/ncw/rclone/fs/object"
+	"github.com/ncw/rclone/fs/walk"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/net/context"
+)
+
+// readFiles returns the contents of all the files in f
+func readFiles(t *testing.T, f fs.Fs) map[string]string {
+	ctx := context.Background()
+	files := map[string]string{}
+	err := walk.Walk(ctx, f, "", true, -1, func(dirPath string, entries fs.DirEntries, err error) error {
+		require.NoError(t, err)
+		for _, entry := range entries {
+			o, ok := entry.(fs.Object)
+			if !ok {
+				continue
+			}
+			in, err := o.Open(ctx)
+			require.NoError(t, err)
+			data, err := ioutil.ReadAll(in)
+			require.NoError(t, err)
+			require.NoError(t, in.Close())
+			files[o.Remote()] = string(data)
+		}
+		return nil
+	})
+	require.NoError(t, err)
+	return files
+}
+
+func TestRekey(t *testing.T) {
+	ctx := context.Background()
+	tempDir, err := ioutil.TempDir("", "rclone-rekey-test")
+	require.NoError(t, err)
+	defer func() {
+		require.NoError(t, os.RemoveAll(tempDir))
+	}()
+	oldConfigPath, oldCacheDir := config.ConfigPath, config.CacheDir
+	config.ConfigPath = filepath.Join(tempDir, "rclone.conf")
+	config.CacheDir = filepath.Join(tempDir, "cache")
+	defer func() {
+		config.ConfigPath, config.CacheDir = oldConfigPath, oldCacheDir
+	}()
+	config.LoadConfig()
+
+	want := map[string]string{
+		"one.txt":           "one",
+		"empty.txt":         "",
+		"dir/two.txt":       "two",
+		"dir/sub/three.txt": string(bytes.Repeat([]byte("three"), 100000)),
+	}
+	for _, mode := range []string{"standard", "off", "obfuscate"} {
+		func() {
+			t.Logf("Testing filename_encryption = %s", mode)
+			name := "TestRekey" + mode
+			config.FileSet(name, "type", "crypt")
+			config.FileSet(name, "remote", ":memory:rekey-"+mode)
+			config.FileSet(name, "filename_encryption", mode)
+			config.FileSet(name, "password", obscure.MustObscure("old"))
+
+			f, err := NewFs(name, "")
+			require.NoError(t, err)
+			for remote, contents := range want {
+				src := object.NewStaticObjectInfo(remote, time.Now(), int64(len(contents)), true, nil, nil)
+				_, err = f.Put(ctx, bytes.NewBufferString(contents), src)
+				require.NoError(t, err)
+			}
+			require.NoError(t, f.Mkdir(ctx, "empty"))
+
+			// rekey without a password
+			_, err = f.(fs.Commander).Command(ctx, "rekey", nil, nil)
+			assert.Error(t, err)
+
+			// rekey part of the remote
+			sub, err := NewFs(name, "dir")
+			require.NoError(t, err)
+			_, err = sub.(fs.Commander).Command(ctx, "rekey", nil, map[string]string{"password": "new"})
+			assert.Error(t, err)
+
+			out, err := f.(fs.Commander).Command(ctx, "rekey", nil, map[string]string{"password": "new", "password2": "salt"})
+			require.NoError(t, err)
+			stats := out.(rekeyStats)
+			assert.Equal(t, len(want), stats.Rekeyed)
+			assert.Equal(t, 0, stats.Errors)
+
+			password, err := obscure.Reveal(config.FileGet(name, "password"))
+			require.NoError(t, err)
+			assert.Equal(t, "new", password)
+			password2, err := obscure.Reveal(config.FileGet(name, "password2"))
+			require.NoError(t, err)
+			assert.Equal(t, "salt", password2)
+			assert.Equal(t, "", config.FileGet(name, rekeyPasswordKey))
+
+			f, err = NewFs(name, "")
+			require.NoError(t, err)
+			assert.Equal(t, want, readFiles(t, f))
+			_, err = f.List(ctx, "empty")
+			assert.NoError(t, err)
+
+			// nothing should be left over in the underlying remote
+			underlying := readFiles(t, f.(*Fs).Fs)
+			assert.Equal(t, len(want), len(underlying))
+
+			// the state file should be removed
+			_, err = os.Stat(filepath.Join(config.CacheDir, "rekey", name+".txt"))
+			assert.True(t, os.IsNotExist(err))
+		}()
+	}
+}
+
+func TestRekeyResume(t *testing.T) {
+	ctx := context.Background()
+	tempDir, err := ioutil.TempDir("", "rclone-rekey-test")
+	require.NoError(t, err)
+	defer func() {
+		require.NoError(t, os.RemoveAll(tempDir))
+	}()
+	oldConfigPath, oldCacheDir := config.ConfigPath, config.CacheDir
+	config.ConfigPath = filepath.Join(tempDir, "rclone.conf")
+	config.CacheDir = filepath.Join(tempDir, "cache")
+	defer func() {
+		config.ConfigPath, config.CacheDir = oldConfigPath, oldCacheDir
+	}()
+	config.LoadConfig()
+
+	name := "TestRekeyResume"
+	config.FileSet(name, "type", "crypt")
+	config.FileSet(name, "remote", ":memory:rekey-resume")
+	config.FileSet(name, "filename_encryption", "off")
+	config.FileSet(name, "password", obscure.MustObscure("old"))
+	f, err := NewFs(name, "")
+	require.NoError(t, err)
+	for _, remote := range []string{"a.txt", "b.txt"} {
+		src := object.NewStaticObjectInfo(remote, time.Now(), int64(len(remote)), true, nil, nil)
+		_, err = f.Put(ctx, bytes.NewBufferString(remote), src)
+		require.NoError(t, err)
+	}
+
+	// Simulate an interrupted run which rekeyed a.txt
+	newPassword, _, err := f.(*Fs).rekeyPasswords("new", "")
+	require.NoError(t, err)
+	cipher, err := newCipher(NameEncryptionOff, newPassword, "", true)
+	require.NoError(t, err)
+	newF := *f.(*Fs)
+	newF.cipher = cipher
+	o, err := f.NewObject(ctx, "a.txt")
+	require.NoError(t, err)
+	state, err := openRekeyState(name)
+	require.NoError(t, err)
+	_, err = f.(*Fs).rekeyObject(ctx, &newF, o.(*Object), state)
+	require.NoError(t, err)
+	require.NoError(t, state.close(false))
+
+	// Resume with a different password should fail
+	_, err = f.(fs.Commander).Command(ctx, "rekey", nil, map[string]string{"password": "other"})
+	assert.Error(t, err)
+
+	out, err := f.(fs.Commander).Command(ctx, "rekey", nil, nil)
+	require.NoError(t, err)
+	stats := out.(rekeyStats)
+	assert.Equal(t, 1, stats.Rekeyed)
+	assert.Equal(t, 1, stats.Skipped)
+
+	f, err = NewFs(name, "")
+	require.NoError(t, err)
+	assert.Equal(t, map[string]string{"a.txt": "a.txt", "b.txt": "b.txt"}, readFiles(t, f))
+}
+
+func TestRekeyResumeDirs(t *testing.T) {
+	ctx := context.Background()
+	tempDir, err := ioutil.TempDir("", "rclone-rekey-test")
+	require.NoError(t, err)
+	defer func() {
+		require.NoError(t, os.RemoveAll(tempDir))
+	}()
+	oldConfigPath, oldCacheDir := config.ConfigPath, config.CacheDir
+	config.ConfigPath = filepath.Join(tempDir, "rclone.conf")
+	config.CacheDir = filepath.Join(tempDir, "cache")
+	defer func() {
+		config.ConfigPath, config.CacheDir = oldConfigPath, oldCacheDir
+	}()
+	config.LoadConfig()
+
+	name := "TestRekeyResumeDirs"
+	config.FileSet(name, "type", "crypt")
+	config.FileSet(name, "remote", ":memory:rekey-resume-dirs")
+	config.FileSet(name, "filename_encryption", "standard")
+	config.FileSet(name, "password", obscure.MustObscure("old"))
+	f, err := NewFs(name, "")
+	require.NoError(t, err)
+	want := map[string]string{"dir/a.txt": "a", "dir/sub/b.txt": "b"}
+	for remote, contents := range want {
+		src := object.NewStaticObjectInfo(remote, time.Now(), int64(len(contents)), true, nil, nil)
+		_, err = f.Put(ctx, bytes.NewBufferString(contents), src)
+		require.NoError(t, err)
+	}
+	require.NoError(t, f.Mkdir(ctx, "empty"))
+
+	// Simulate a run interrupted after making the new directories
+	// but before removing the old ones
+	newPassword, _, err := f.(*Fs).rekeyPasswords("new", "")
+	require.NoError(t, err)
+	cipher, err := newCipher(NameEncryptionStandard, newPassword, "", true)
+	require.NoError(t, err)
+	newF := *f.(*Fs)
+	newF.cipher = cipher
+	state, err := openRekeyState(name)
+	require.NoError(t, err)
+	for remote := range want {
+		o, err := f.NewObject(ctx, remote)
+		require.NoError(t, err)
+		_, err = f.(*Fs).rekeyObject(ctx, &newF, o.(*Object), state)
+		require.NoError(t, err)
+	}
+	for _, dir := range []string{"empty", "dir/sub", "dir"} {
+		require.NoError(t, f.(*Fs).rekeyDir(ctx, &newF, dir, state))
+	}
+	require.NoError(t, state.close(false))
+	oldEmpty := f.(*Fs).cipher.EncryptDirName("empty")
+	_, err = f.(*Fs).Fs.List(ctx, oldEmpty)
+	require.NoError(t, err)
+
+	out, err := f.(fs.Commander).Command(ctx, "rekey", nil, nil)
+	require.NoError(t, err)
+	stats := out.(rekeyStats)
+	assert.Equal(t, rekeyStats{}, stats)
+
+	// the old directory should have been removed
+	_, err = f.(*Fs).Fs.List(ctx, oldEmpty)
+	assert.Equal(t, fs.ErrorDirNotFound, err)
+
+	f, err = NewFs(name, "")
+	require.NoError(t, err)
+	assert.Equal(t, want, readFiles(t, f))
+	_, err = f.List(ctx, "empty")
+	assert.NoError(t, err)
+}
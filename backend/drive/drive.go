@@ -29,10 +29,12 @@ import (
 	"github.com/ncw/rclone/fs/fserrors"
 	"github.com/ncw/rclone/fs/fshttp"
 	"github.com/ncw/rclone/fs/hash"
+	"github.com/ncw/rclone/fs/operations"
 	"github.com/ncw/rclone/lib/dircache"
 	"github.com/ncw/rclone/lib/oauthutil"
 	"github.com/ncw/rclone/lib/pacer"
 	"github.com/pkg/errors"
+	"golang.org/x/net/context"
 	"golang.org/x/oauth2"
 	"golang.org/x/oauth2/google"
 	"google.golang.org/api/drive/v3"
@@ -52,6 +54,10 @@ const (
 	defaultScope                = "drive"
 )
 
+var pacerOptions = pacer.AddFlags("drive", pacer.Options{
+	MinSleep: minSleep,
+})
+
 // Globals
 var (
 	// Flags
@@ -168,6 +174,7 @@ func init() {
 			Name: "service_account_file",
 			Help: "Service Account Credentials JSON file path  - leave blank normally.\nNeeded only if you want use SA instead of interactive login.",
 		}},
+		CommandHelp: commandHelp,
 	})
 	flags.VarP(&driveUploadCutoff, "drive-upload-cutoff", "", "Cutoff for switching to chunked upload")
 	flags.VarP(&chunkSize, "drive-chunk-size", "", "Upload chunk size. Must a power of 2 >= 256k.")
@@ -254,8 +261,22 @@ func shouldRetry(err error) (again bool, errOut error) {
 }
 
 // parseParse parses a drive 'url'
-func parseDrivePath(path string) (root string, err error) {
+//
+// If the path starts with {ID} then the ID is returned as the ID of
+// the folder to use as the root and the rest as the path in it.
+func parseDrivePath(path string) (root string, rootID string, err error) {
 	root = strings.Trim(path, "/")
+	if strings.HasPrefix(root, "{") {
+		end := strings.IndexRune(root, '}')
+		if end < 0 {
+			return "", "", errors.Errorf("drive: missing '}' in %q", path)
+		}
+		rootID = root[1:end]
+		if rootID == "" {
+			return "", "", errors.Errorf("drive: empty ID in %q", path)
+		}
+		root = strings.Trim(root[end+1:], "/")
+	}
 	return
 }
 
@@ -444,10 +465,10 @@ func configTeamDrive(name string) error {
 
 // newPacer makes a pacer configured for drive
 func newPacer() *pacer.Pacer {
-	return pacer.New().SetMinSleep(minSleep).SetPacer(pacer.GoogleDrivePacer)
+	return pacer.New().SetOptions(pacerOptions).SetPacer(pacer.GoogleDrivePacer)
 }
 
-func getServiceAccountClient(keyJsonfilePath string) (*http.Client, error) {
+func getServiceAccountClient(name string, keyJsonfilePath string) (*http.Client, error) {
 	data, err := ioutil.ReadFile(os.ExpandEnv(keyJsonfilePath))
 	if err != nil {
 		return nil, errors.Wrap(err, "error opening credentials file")
@@ -459,7 +480,7 @@ func getServiceAccountClient(keyJsonfilePath string) (*http.Client, error) {
 	if *driveImpersonate != "" {
 		conf.Subject = *driveImpersonate
 	}
-	ctxWithSpecialClient := oauthutil.Context(fshttp.NewClient(fs.Config))
+	ctxWithSpecialClient := oauthutil.Context(fshttp.NewClient(fs.ConfigForRemote(name)))
 	return oauth2.NewClient(ctxWithSpecialClient, conf.TokenSource(ctxWithSpecialClient)), nil
 }
 
@@ -469,7 +490,7 @@ func createOAuthClient(name string) (*http.Client, error) {
 
 	serviceAccountPath := config.FileGet(name, "service_account_file")
 	if serviceAccountPath != "" {
-		oAuthClient, err = getServiceAccountClient(serviceAccountPath)
+		oAuthClient, err = getServiceAccountClient(name, serviceAccountPath)
 		if err != nil {
 			return nil, errors.Wrap(err, "failed to create oauth client from service account")
 		}
@@ -497,7 +518,7 @@ func NewFs(name, path string) (fs.Fs, error) {
 		return nil, errors.Wrap(err, "drive: failed when making oauth client")
 	}
 
-	root, err := parseDrivePath(path)
+	root, rootID, err := parseDrivePath(path)
 	if err != nil {
 		return nil, err
 	}
@@ -535,6 +556,18 @@ func NewFs(name, path string) (fs.Fs, error) {
 		f.rootFolderID = rootID
 	}
 
+	// override it again if an ID was given in the path
+	if rootID != "" {
+		info, err := f.getFile(rootID)
+		if err != nil {
+			return nil, errors.Wrapf(err, "drive: failed to read ID %q", rootID)
+		}
+		if info.MimeType != driveFolderType {
+			return nil, errors.Errorf("drive: ID %q is the file %q not a folder - use \"rclone backend copyid\" to copy it", rootID, info.Name)
+		}
+		f.rootFolderID = rootID
+	}
+
 	f.dirCache = dircache.New(root, f.rootFolderID, f)
 
 	// Parse extensions
@@ -561,7 +594,7 @@ func NewFs(name, path string) (fs.Fs, error) {
 			// No root so return old f
 			return f, nil
 		}
-		entries, err := newF.List("")
+		entries, err := newF.List(context.TODO(), "")
 		if err != nil {
 			// unable to list folder so return old f
 			return f, nil
@@ -579,6 +612,15 @@ func NewFs(name, path string) (fs.Fs, error) {
 	return f, nil
 }
 
+// getFile reads the metadata of the file or folder with ID id
+func (f *Fs) getFile(id string) (info *drive.File, err error) {
+	err = f.pacer.Call(func() (bool, error) {
+		info, err = f.svc.Files.Get(id).Fields(googleapi.Field(partialFields)).SupportsTeamDrives(f.isTeamDrive).Do()
+		return shouldRetry(err)
+	})
+	return info, err
+}
+
 // Return an Object from a path
 //
 // If it can't be found it returns the error fs.ErrorObjectNotFound.
@@ -600,7 +642,7 @@ func (f *Fs) newObjectWithInfo(remote string, info *drive.File) (fs.Object, erro
 
 // NewObject finds the Object at remote.  If it can't be found
 // it returns the error fs.ErrorObjectNotFound.
-func (f *Fs) NewObject(remote string) (fs.Object, error) {
+func (f *Fs) NewObject(ctx context.Context, remote string) (fs.Object, error) {
 	return f.newObjectWithInfo(remote, nil)
 }
 
@@ -698,7 +740,7 @@ func (f *Fs) findExportFormat(filepath string, exportMimeTypes []string) (extens
 //
 // This should return ErrDirNotFound if the directory isn't
 // found.
-func (f *Fs) List(dir string) (entries fs.DirEntries, err error) {
+func (f *Fs) List(ctx context.Context, dir string) (entries fs.DirEntries, err error) {
 	err = f.dirCache.FindRoot(false)
 	if err != nil {
 		return nil, err
@@ -798,29 +840,29 @@ func (f *Fs) createFileInfo(remote string, modTime time.Time, size int64) (*Obje
 // Copy the reader in to the new object which is returned
 //
 // The new object may have been created if an error is returned
-func (f *Fs) Put(in io.Reader, src fs.ObjectInfo, options ...fs.OpenOption) (fs.Object, error) {
+func (f *Fs) Put(ctx context.Context, in io.Reader, src fs.ObjectInfo, options ...fs.OpenOption) (fs.Object, error) {
 	exisitingObj, err := f.newObjectWithInfo(src.Remote(), nil)
 	switch err {
 	case nil:
-		return exisitingObj, exisitingObj.Update(in, src, options...)
+		return exisitingObj, exisitingObj.Update(ctx, in, src, options...)
 	case fs.ErrorObjectNotFound:
 		// Not found so create it
-		return f.PutUnchecked(in, src, options...)
+		return f.PutUnchecked(ctx, in, src, options...)
 	default:
 		return nil, err
 	}
 }
 
 // PutStream uploads to the remote path with the modTime given of indeterminate size
-func (f *Fs) PutStream(in io.Reader, src fs.ObjectInfo, options ...fs.OpenOption) (fs.Object, error) {
-	return f.Put(in, src, options...)
+func (f *Fs) PutStream(ctx context.Context, in io.Reader, src fs.ObjectInfo, options ...fs.OpenOption) (fs.Object, error) {
+	return f.Put(ctx, in, src, options...)
 }
 
 // PutUnchecked uploads the object
 //
 // This will create a duplicate if we upload a new file without
 // checking to see if there is one already - use Put() for that.
-func (f *Fs) PutUnchecked(in io.Reader, src fs.ObjectInfo, options ...fs.OpenOption) (fs.Object, error) {
+func (f *Fs) PutUnchecked(ctx context.Context, in io.Reader, src fs.ObjectInfo, options ...fs.OpenOption) (fs.Object, error) {
 	remote := src.Remote()
 	size := src.Size()
 	modTime := src.ModTime()
@@ -854,7 +896,7 @@ func (f *Fs) PutUnchecked(in io.Reader, src fs.ObjectInfo, options ...fs.OpenOpt
 
 // MergeDirs merges the contents of all the directories passed
 // in into the first one and rmdirs the other directories.
-func (f *Fs) MergeDirs(dirs []fs.Directory) error {
+func (f *Fs) MergeDirs(ctx context.Context, dirs []fs.Directory) error {
 	if len(dirs) < 2 {
 		return nil
 	}
@@ -892,7 +934,7 @@ func (f *Fs) MergeDirs(dirs []fs.Directory) error {
 }
 
 // Mkdir creates the container if it doesn't exist
-func (f *Fs) Mkdir(dir string) error {
+func (f *Fs) Mkdir(ctx context.Context, dir string) error {
 	err := f.dirCache.FindRoot(true)
 	if err != nil {
 		return err
@@ -922,7 +964,7 @@ func (f *Fs) rmdir(directoryID string, useTrash bool) error {
 // Rmdir deletes a directory
 //
 // Returns an error if it isn't empty
-func (f *Fs) Rmdir(dir string) error {
+func (f *Fs) Rmdir(ctx context.Context, dir string) error {
 	root := path.Join(f.root, dir)
 	dc := f.dirCache
 	directoryID, err := dc.FindDir(dir, false)
@@ -975,7 +1017,7 @@ func (f *Fs) Precision() time.Duration {
 // Will only be called if src.Fs().Name() == f.Name()
 //
 // If it isn't possible then return fs.ErrorCantCopy
-func (f *Fs) Copy(src fs.Object, remote string) (fs.Object, error) {
+func (f *Fs) Copy(ctx context.Context, src fs.Object, remote string) (fs.Object, error) {
 	srcObj, ok := src.(*Object)
 	if !ok {
 		fs.Debugf(src, "Can't copy - not same remote type")
@@ -1008,7 +1050,7 @@ func (f *Fs) Copy(src fs.Object, remote string) (fs.Object, error) {
 // Optional interface: Only implement this if you have a way of
 // deleting all the files quicker than just running Remove() on the
 // result of List()
-func (f *Fs) Purge() error {
+func (f *Fs) Purge(ctx context.Context) error {
 	if f.root == "" {
 		return errors.New("can't purge root directory")
 	}
@@ -1035,7 +1077,7 @@ func (f *Fs) Purge() error {
 }
 
 // CleanUp empties the trash
-func (f *Fs) CleanUp() error {
+func (f *Fs) CleanUp(ctx context.Context) error {
 	err := f.pacer.Call(func() (bool, error) {
 		err := f.svc.Files.EmptyTrash().Do()
 		return shouldRetry(err)
@@ -1047,6 +1089,65 @@ func (f *Fs) CleanUp() error {
 	return nil
 }
 
+// About gets quota information
+func (f *Fs) About(ctx context.Context) (*fs.Usage, error) {
+	if f.isTeamDrive {
+		// Teamdrives don't appear to have a usage API so just return empty
+		return &fs.Usage{}, nil
+	}
+	var about *drive.About
+	var err error
+	err = f.pacer.Call(func() (bool, error) {
+		about, err = f.svc.About.Get().Fields("storageQuota").Do()
+		return shouldRetry(err)
+	})
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to get Drive storageQuota")
+	}
+	q := about.StorageQuota
+	usage := &fs.Usage{
+		Used:    fs.NewUsageValue(q.UsageInDrive),           // bytes in use
+		Trashed: fs.NewUsageValue(q.UsageInDriveTrash),      // bytes in trash
+		Other:   fs.NewUsageValue(q.Usage - q.UsageInDrive), // other usage eg gmail in drive
+	}
+	if q.Limit > 0 {
+		usage.Total = fs.NewUsageValue(q.Limit)
+		usage.Free = fs.NewUsageValue(q.Limit - q.Usage)
+	}
+	return usage, nil
+}
+
+// PublicLink adds a "readable by anyone with link" permission on the given file or folder.
+func (f *Fs) PublicLink(ctx context.Context, remote string, expire time.Duration) (link string, err error) {
+	id, err := f.dirCache.FindDir(remote, false)
+	if err == nil {
+		fs.Debugf(f, "attempting to share directory '%s'", remote)
+	} else {
+		fs.Debugf(f, "attempting to share single file '%s'", remote)
+		var o fs.Object
+		o, err = f.NewObject(ctx, remote)
+		if err != nil {
+			return "", err
+		}
+		id = o.(*Object).id
+	}
+
+	permission := &drive.Permission{
+		AllowFileDiscovery: false,
+		Role:               "reader",
+		Type:               "anyone",
+	}
+
+	err = f.pacer.Call(func() (bool, error) {
+		_, err = f.svc.Permissions.Create(id, permission).Fields("").SupportsTeamDrives(f.isTeamDrive).Do()
+		return shouldRetry(err)
+	})
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("https://drive.google.com/open?id=%s", id), nil
+}
+
 // Move src to this remote using server side move operations.
 //
 // This is stored with the remote path given
@@ -1056,7 +1157,7 @@ func (f *Fs) CleanUp() error {
 // Will only be called if src.Fs().Name() == f.Name()
 //
 // If it isn't possible then return fs.ErrorCantMove
-func (f *Fs) Move(src fs.Object, remote string) (fs.Object, error) {
+func (f *Fs) Move(ctx context.Context, src fs.Object, remote string) (fs.Object, error) {
 	srcObj, ok := src.(*Object)
 	if !ok {
 		fs.Debugf(src, "Can't move - not same remote type")
@@ -1100,7 +1201,7 @@ func (f *Fs) Move(src fs.Object, remote string) (fs.Object, error) {
 // If it isn't possible then return fs.ErrorCantDirMove
 //
 // If destination exists then return fs.ErrorDirExists
-func (f *Fs) DirMove(src fs.Fs, srcRemote, dstRemote string) error {
+func (f *Fs) DirMove(ctx context.Context, src fs.Fs, srcRemote, dstRemote string) error {
 	srcFs, ok := src.(*Fs)
 	if !ok {
 		fs.Debugf(srcFs, "Can't move directory - not same remote type")
@@ -1275,6 +1376,183 @@ func (f *Fs) dirchangeNotifyRunner(notifyFunc func(string), pollInterval time.Du
 	}
 }
 
+var commandHelp = []fs.CommandHelp{{
+	Name:  "untrash",
+	Short: "Untrash files and directories",
+	Long: `This command untrashes all the files and directories in the directory
+passed in recursively, or in the root of the remote if no directories
+are passed.
+
+Usage:
+
+    rclone backend untrash drive:directory
+    rclone backend untrash drive:directory subdir1 subdir2
+
+This takes an optional argument --dry-run which will list the files
+which would be untrashed but not untrash them.
+
+Result:
+
+    {
+        "Untrashed": 17,
+        "Errors": 0
+    }
+`,
+}, {
+	Name:  "copyid",
+	Short: "Copy files by ID",
+	Long: `This command copies files by ID
+
+Usage:
+
+    rclone backend copyid drive: ID path
+    rclone backend copyid drive: ID1 path1 ID2 path2
+
+It copies the drive file with ID given to the path (an rclone path which
+will be passed internally to rclone copyto). The ID and path pairs can be
+repeated.
+
+The path should end with a / to indicate copy the file as named to
+this directory. If it doesn't end with a / then the last path
+component will be used as the file name.
+
+If the destination is a drive backend then server side copying will be
+attempted if possible.
+
+Use --dry-run to see what would be copied before copying.
+`,
+}}
+
+// untrashResult is the result of the untrash command
+type untrashResult struct {
+	Untrashed int
+	Errors    int
+}
+
+// Command the backend to run a named command
+//
+// The command run is name
+// args may be used to read arguments from
+// opts may be used to read optional arguments from
+//
+// The result should be capable of being JSON encoded
+// If it is a string or a []string it will be shown to the user
+// otherwise it will be JSON encoded and shown to the user like that
+func (f *Fs) Command(ctx context.Context, name string, arg []string, opt map[string]string) (out interface{}, err error) {
+	switch name {
+	case "untrash":
+		dirs := arg
+		if len(dirs) == 0 {
+			dirs = []string{""}
+		}
+		var r untrashResult
+		for _, dir := range dirs {
+			dirID, err := f.dirCache.FindDir(dir, false)
+			if err != nil {
+				return nil, errors.Wrapf(err, "failed to find directory %q", dir)
+			}
+			err = f.untrashDir(dirID, dir, &r)
+			if err != nil {
+				return r, err
+			}
+		}
+		return r, nil
+	case "copyid":
+		if len(arg)%2 != 0 {
+			return nil, errors.New("need an even number of arguments")
+		}
+		for len(arg) > 0 {
+			id, dest := arg[0], arg[1]
+			arg = arg[2:]
+			err = f.copyID(ctx, id, dest)
+			if err != nil {
+				return nil, errors.Wrapf(err, "failed copying %q to %q", id, dest)
+			}
+		}
+		return nil, nil
+	default:
+		return nil, fs.ErrorCommandNotFound
+	}
+}
+
+// copyID copies the file with ID id to dest which is an rclone path
+//
+// If dest ends with / the file is copied into that directory with its
+// own name.
+func (f *Fs) copyID(ctx context.Context, id, dest string) error {
+	info, err := f.getFile(id)
+	if err != nil {
+		return errors.Wrap(err, "couldn't find id")
+	}
+	if info.MimeType == driveFolderType {
+		return errors.Errorf("can't copy folder - use \"%s:{%s}\" instead", f.name, id)
+	}
+	if strings.HasPrefix(info.MimeType, "application/vnd.google-apps.") {
+		return errors.New("can't copy a Google document")
+	}
+	o, err := f.newObjectWithInfo(info.Name, info)
+	if err != nil {
+		return err
+	}
+	destDir, destLeaf := path.Split(dest)
+	if destLeaf == "" {
+		destLeaf = info.Name
+	}
+	if destDir == "" {
+		destDir = "."
+	}
+	dstFs, err := fs.NewFs(destDir)
+	if err != nil {
+		return err
+	}
+	_, err = operations.Copy(ctx, dstFs, nil, destLeaf, o)
+	return err
+}
+
+// untrashDir untrashes all the items in the directory with ID dirID
+// and recurses into any directories found, counting the results in r
+func (f *Fs) untrashDir(dirID string, dir string, r *untrashResult) error {
+	var items []*drive.File
+	_, err := f.list(dirID, "", false, false, true, func(item *drive.File) bool {
+		items = append(items, item)
+		return false
+	})
+	if err != nil {
+		return errors.Wrapf(err, "failed to list %q", dir)
+	}
+	for _, item := range items {
+		remote := path.Join(dir, item.Name)
+		if item.Trashed {
+			if fs.Config.DryRun {
+				fs.Logf(remote, "Not untrashing as --dry-run")
+			} else {
+				info := drive.File{
+					Trashed:         false,
+					ForceSendFields: []string{"Trashed"},
+				}
+				err = f.pacer.Call(func() (bool, error) {
+					_, err := f.svc.Files.Update(item.Id, &info).Fields("").SupportsTeamDrives(f.isTeamDrive).Do()
+					return shouldRetry(err)
+				})
+				if err != nil {
+					fs.Errorf(remote, "Failed to untrash: %v", err)
+					r.Errors++
+					continue
+				}
+				fs.Infof(remote, "Untrashed")
+			}
+			r.Untrashed++
+		}
+		if item.MimeType == driveFolderType {
+			err = f.untrashDir(item.Id, remote, r)
+			if err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
 // DirCacheFlush resets the directory cache - used in testing as an
 // optional interface
 func (f *Fs) DirCacheFlush() {
@@ -1383,7 +1661,7 @@ func (o *Object) ModTime() time.Time {
 }
 
 // SetModTime sets the modification time of the drive fs object
-func (o *Object) SetModTime(modTime time.Time) error {
+func (o *Object) SetModTime(ctx context.Context, modTime time.Time) error {
 	err := o.readMetaData()
 	if err != nil {
 		return err
@@ -1476,7 +1754,7 @@ func (file *openFile) Close() (err error) {
 var _ io.ReadCloser = &openFile{}
 
 // Open an object for read
-func (o *Object) Open(options ...fs.OpenOption) (in io.ReadCloser, err error) {
+func (o *Object) Open(ctx context.Context, options ...fs.OpenOption) (in io.ReadCloser, err error) {
 	req, res, err := o.httpResponse("GET", options)
 	if err != nil {
 		return nil, err
@@ -1501,7 +1779,7 @@ func (o *Object) Open(options ...fs.OpenOption) (in io.ReadCloser, err error) {
 // Copy the reader into the object updating modTime and size
 //
 // The new object may have been created if an error is returned
-func (o *Object) Update(in io.Reader, src fs.ObjectInfo, options ...fs.OpenOption) error {
+func (o *Object) Update(ctx context.Context, in io.Reader, src fs.ObjectInfo, options ...fs.OpenOption) error {
 	size := src.Size()
 	modTime := src.ModTime()
 	if o.isDocument {
@@ -1536,7 +1814,7 @@ func (o *Object) Update(in io.Reader, src fs.ObjectInfo, options ...fs.OpenOptio
 }
 
 // Remove an object
-func (o *Object) Remove() error {
+func (o *Object) Remove(ctx context.Context) error {
 	if o.isDocument {
 		return errors.New("can't delete a google document")
 	}
@@ -1555,6 +1833,11 @@ func (o *Object) Remove() error {
 	return err
 }
 
+// ID returns the ID of the Object if known, or "" if not
+func (o *Object) ID() string {
+	return o.id
+}
+
 // MimeType of an Object if known, "" otherwise
 func (o *Object) MimeType() string {
 	err := o.readMetaData()
@@ -1578,6 +1861,10 @@ var (
 	_ fs.DirChangeNotifier = (*Fs)(nil)
 	_ fs.PutUncheckeder    = (*Fs)(nil)
 	_ fs.MergeDirser       = (*Fs)(nil)
+	_ fs.Abouter           = (*Fs)(nil)
+	_ fs.PublicLinker      = (*Fs)(nil)
+	_ fs.Commander         = (*Fs)(nil)
 	_ fs.Object            = (*Object)(nil)
 	_ fs.MimeTyper         = &Object{}
+	_ fs.IDer              = &Object{}
 )
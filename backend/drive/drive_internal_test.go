@@ -110,3 +110,28 @@ func TestInternalFindExportFormat(t *testing.T) {
 		assert.Equal(t, test.wantMimeType, gotMimeType)
 	}
 }
+
+func TestInternalParseDrivePath(t *testing.T) {
+	for _, test := range []struct {
+		in       string
+		wantRoot string
+		wantID   string
+		wantErr  string
+	}{
+		{"", "", "", ""},
+		{"/path/to/dir/", "path/to/dir", "", ""},
+		{"{1XyfKHCh}", "", "1XyfKHCh", ""},
+		{"/{1XyfKHCh}/path/to/dir/", "path/to/dir", "1XyfKHCh", ""},
+		{"{1XyfKHCh", "", "", `drive: missing '}' in "{1XyfKHCh"`},
+		{"{}/path", "", "", `drive: empty ID in "{}/path"`},
+	} {
+		root, rootID, err := parseDrivePath(test.in)
+		if test.wantErr == "" {
+			assert.NoError(t, err, test.in)
+		} else {
+			assert.EqualError(t, err, test.wantErr, test.in)
+		}
+		assert.Equal(t, test.wantRoot, root, test.in)
+		assert.Equal(t, test.wantID, rootID, test.in)
+	}
+}
@@ -0,0 +1,181 @@
+// Batch deletes using the delete_batch API
+
+// +build go1.7
+
+package dropbox
+
+import (
+	"sync"
+	"time"
+
+	"github.com/dropbox/dropbox-sdk-go-unofficial/dropbox/async"
+	"github.com/dropbox/dropbox-sdk-go-unofficial/dropbox/files"
+	"github.com/ncw/rclone/fs"
+	"github.com/pkg/errors"
+)
+
+// maxBatchSize is the largest batch the delete_batch API accepts
+const maxBatchSize = 1000
+
+// batchRequest is a path waiting in a batch
+type batchRequest struct {
+	path   string
+	result chan error
+}
+
+// batcher gathers requests made at about the same time into batches
+// which are committed together.
+//
+// A batch is committed when it has size requests in or timeout after
+// the first request was added, whichever is sooner.
+type batcher struct {
+	size    int
+	timeout time.Duration
+	commit  func(paths []string) []error // commit a batch, returning an error for each path
+
+	mu      sync.Mutex
+	pending []*batchRequest
+	timer   *time.Timer
+}
+
+// newBatcher makes a batcher which calls commit for each batch
+func newBatcher(size int, timeout time.Duration, commit func(paths []string) []error) *batcher {
+	return &batcher{
+		size:    size,
+		timeout: timeout,
+		commit:  commit,
+	}
+}
+
+// Do adds path to the current batch and waits for the batch to be
+// committed returning the error for path.
+func (b *batcher) Do(path string) error {
+	req := &batchRequest{
+		path:   path,
+		result: make(chan error, 1),
+	}
+	b.mu.Lock()
+	b.pending = append(b.pending, req)
+	if len(b.pending) >= b.size {
+		batch := b.take()
+		b.mu.Unlock()
+		go b.run(batch)
+	} else {
+		if len(b.pending) == 1 {
+			b.timer = time.AfterFunc(b.timeout, b.flush)
+		}
+		b.mu.Unlock()
+	}
+	return <-req.result
+}
+
+// take removes the pending requests - call with the lock held
+func (b *batcher) take() (batch []*batchRequest) {
+	if b.timer != nil {
+		b.timer.Stop()
+		b.timer = nil
+	}
+	batch, b.pending = b.pending, nil
+	return batch
+}
+
+// flush commits the pending requests if there are any
+func (b *batcher) flush() {
+	b.mu.Lock()
+	batch := b.take()
+	b.mu.Unlock()
+	if len(batch) > 0 {
+		b.run(batch)
+	}
+}
+
+// run commits batch and returns the results to the waiting requests
+func (b *batcher) run(batch []*batchRequest) {
+	paths := make([]string, len(batch))
+	for i, req := range batch {
+		paths[i] = req.path
+	}
+	errs := b.commit(paths)
+	for i, req := range batch {
+		req.result <- errs[i]
+	}
+}
+
+// deleteBatch deletes paths with the delete_batch API returning an
+// error for each one
+func (f *Fs) deleteBatch(paths []string) []error {
+	errs := make([]error, len(paths))
+	setAll := func(err error) []error {
+		for i := range errs {
+			errs[i] = err
+		}
+		return errs
+	}
+	args := make([]*files.DeleteArg, len(paths))
+	for i, path := range paths {
+		args[i] = files.NewDeleteArg(path)
+	}
+	fs.Debugf(f, "Deleting batch of %d files", len(paths))
+	var launch *files.DeleteBatchLaunch
+	err := f.pacer.Call(func() (bool, error) {
+		var err error
+		launch, err = f.srv.DeleteBatch(files.NewDeleteBatchArg(args))
+		return shouldRetry(err)
+	})
+	if err != nil {
+		return setAll(errors.Wrap(err, "batch delete failed"))
+	}
+	result := launch.Complete
+	if launch.Tag == files.DeleteBatchLaunchAsyncJobId {
+		result, err = f.waitDeleteBatch(launch.AsyncJobId)
+		if err != nil {
+			return setAll(err)
+		}
+	}
+	if result == nil || len(result.Entries) != len(paths) {
+		return setAll(errors.New("batch delete returned the wrong number of results"))
+	}
+	for i, entry := range result.Entries {
+		if entry.Tag != files.DeleteBatchResultEntrySuccess {
+			reason := entry.Tag
+			if entry.Failure != nil {
+				reason = entry.Failure.Tag
+			}
+			errs[i] = errors.Errorf("batch delete failed: %s", reason)
+		}
+	}
+	return errs
+}
+
+// waitDeleteBatch polls the delete_batch job until it completes
+func (f *Fs) waitDeleteBatch(jobID string) (*files.DeleteBatchResult, error) {
+	sleepTime := 100 * time.Millisecond
+	for {
+		var status *files.DeleteBatchJobStatus
+		err := f.pacer.Call(func() (bool, error) {
+			var err error
+			status, err = f.srv.DeleteBatchCheck(async.NewPollArg(jobID))
+			return shouldRetry(err)
+		})
+		if err != nil {
+			return nil, errors.Wrap(err, "batch delete check failed")
+		}
+		switch status.Tag {
+		case files.DeleteBatchJobStatusComplete:
+			return status.Complete, nil
+		case files.DeleteBatchJobStatusFailed:
+			reason := status.Tag
+			if status.Failed != nil {
+				reason = status.Failed.Tag
+			}
+			return nil, errors.Errorf("batch delete failed: %s", reason)
+		case files.DeleteBatchJobStatusInProgress:
+		default:
+			return nil, errors.Errorf("batch delete returned unknown status %q", status.Tag)
+		}
+		time.Sleep(sleepTime)
+		if sleepTime < 2*time.Second {
+			sleepTime *= 2
+		}
+	}
+}
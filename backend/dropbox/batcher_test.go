@@ -0,0 +1,69 @@
+// +build go1.7
+
+package dropbox
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBatcher(t *testing.T) {
+	var (
+		mu      sync.Mutex
+		batches [][]string
+	)
+	errFail := errors.New("fail")
+	commit := func(paths []string) []error {
+		mu.Lock()
+		batches = append(batches, paths)
+		mu.Unlock()
+		errs := make([]error, len(paths))
+		for i, path := range paths {
+			if path == "/fail" {
+				errs[i] = errFail
+			}
+		}
+		return errs
+	}
+
+	// fill up batches
+	b := newBatcher(3, time.Hour, commit)
+	var wg sync.WaitGroup
+	errs := make([]error, 6)
+	for i := range errs {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			path := fmt.Sprintf("/file%d", i)
+			if i == 4 {
+				path = "/fail"
+			}
+			errs[i] = b.Do(path)
+		}(i)
+	}
+	wg.Wait()
+	assert.Equal(t, 2, len(batches))
+	for _, batch := range batches {
+		assert.Equal(t, 3, len(batch))
+	}
+	for i, err := range errs {
+		if i == 4 {
+			assert.Equal(t, errFail, err)
+		} else {
+			assert.NoError(t, err)
+		}
+	}
+
+	// partial batch sent on the timeout
+	batches = nil
+	b = newBatcher(100, 10*time.Millisecond, commit)
+	start := time.Now()
+	assert.NoError(t, b.Do("/one"))
+	assert.True(t, time.Since(start) >= 10*time.Millisecond)
+	assert.Equal(t, [][]string{{"/one"}}, batches)
+}
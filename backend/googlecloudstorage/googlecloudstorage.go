@@ -13,6 +13,7 @@ FIXME Patch/Delete/Get isn't working with files with spaces in - giving 404 erro
 */
 
 import (
+	"crypto/sha256"
 	"encoding/base64"
 	"encoding/hex"
 	"fmt"
@@ -31,11 +32,13 @@ import (
 	"github.com/ncw/rclone/fs/config"
 	"github.com/ncw/rclone/fs/config/flags"
 	"github.com/ncw/rclone/fs/config/obscure"
+	"github.com/ncw/rclone/fs/fserrors"
 	"github.com/ncw/rclone/fs/fshttp"
 	"github.com/ncw/rclone/fs/hash"
 	"github.com/ncw/rclone/fs/walk"
 	"github.com/ncw/rclone/lib/oauthutil"
 	"github.com/pkg/errors"
+	"golang.org/x/net/context"
 	"golang.org/x/oauth2"
 	"golang.org/x/oauth2/google"
 	"google.golang.org/api/googleapi"
@@ -52,8 +55,9 @@ const (
 )
 
 var (
-	gcsLocation     = flags.StringP("gcs-location", "", "", "Default location for buckets (us|eu|asia|us-central1|us-east1|us-east4|us-west1|asia-east1|asia-noetheast1|asia-southeast1|australia-southeast1|europe-west1|europe-west2).")
-	gcsStorageClass = flags.StringP("gcs-storage-class", "", "", "Default storage class for buckets (MULTI_REGIONAL|REGIONAL|STANDARD|NEARLINE|COLDLINE|DURABLE_REDUCED_AVAILABILITY).")
+	gcsLocation      = flags.StringP("gcs-location", "", "", "Default location for buckets (us|eu|asia|us-central1|us-east1|us-east4|us-west1|asia-east1|asia-noetheast1|asia-southeast1|australia-southeast1|europe-west1|europe-west2).")
+	gcsStorageClass  = flags.StringP("gcs-storage-class", "", "", "Default storage class for buckets (MULTI_REGIONAL|REGIONAL|STANDARD|NEARLINE|COLDLINE|DURABLE_REDUCED_AVAILABILITY).")
+	gcsNoCheckBucket = flags.BoolP("gcs-no-check-bucket", "", false, "Don't check the bucket exists or try to create it.")
 	// Description of how to auth for this app
 	storageConfig = &oauth2.Config{
 		Scopes:       []string{storage.DevstorageFullControlScope},
@@ -200,6 +204,45 @@ func init() {
 				Value: "DURABLE_REDUCED_AVAILABILITY",
 				Help:  "Durable reduced availability storage class",
 			}},
+		}, {
+			Name: "bucket_policy_only",
+			Help: "Set to true if the bucket uses uniform bucket-level access (Bucket Policy Only) so no ACLs are sent.",
+			Examples: []fs.OptionExample{{
+				Value: "false",
+				Help:  "Send object_acl and bucket_acl as normal [default if left blank].",
+			}, {
+				Value: "true",
+				Help:  "Don't send ACLs - access is controlled by bucket-level IAM policies.",
+			}},
+		}, {
+			Name: "no_check_bucket",
+			Help: "Don't check the bucket exists or try to create it.",
+			Examples: []fs.OptionExample{{
+				Value: "false",
+				Help:  "Check the bucket exists and create it if not [default if left blank].",
+			}, {
+				Value: "true",
+				Help:  "Assume the bucket exists - use if the credentials can't create or check buckets.",
+			}},
+		}, {
+			Name: "encryption_key",
+			Help: "Customer-supplied encryption key (CSEK) - base64 encoded AES-256 key - leave blank normally.",
+		}, {
+			Name: "kms_key_name",
+			Help: "Cloud KMS key resource name to encrypt new objects with (CMEK) - leave blank normally.",
+		}, {
+			Name: "object_hold",
+			Help: "Hold to place on new objects - leave blank normally.",
+			Examples: []fs.OptionExample{{
+				Value: "",
+				Help:  "No hold [default if left blank].",
+			}, {
+				Value: "temporary",
+				Help:  "Temporary hold - released manually.",
+			}, {
+				Value: "event-based",
+				Help:  "Event-based hold - starts the bucket retention period when released.",
+			}},
 		}},
 	})
 }
@@ -219,6 +262,11 @@ type Fs struct {
 	bucketACL     string           // used when creating new buckets
 	location      string           // location of new buckets
 	storageClass  string           // storage class of new buckets
+	policyOnly    bool             // set if the bucket uses bucket-level IAM only
+	encryptionKey string           // base64 CSEK or "" if not set
+	keySHA256     string           // base64 SHA256 of the CSEK
+	kmsKeyName    string           // CMEK key to encrypt new objects with
+	objectHold    string           // hold to place on new objects
 }
 
 // Object describes a storage object
@@ -232,6 +280,8 @@ type Object struct {
 	bytes    int64     // Bytes in the object
 	modTime  time.Time // Modified time of the object
 	mimeType string
+	held     bool   // set if the object has a temporary or event-based hold
+	retained string // time the object is retained until or ""
 }
 
 // ------------------------------------------------------------
@@ -277,7 +327,7 @@ func parsePath(path string) (bucket, directory string, err error) {
 	return
 }
 
-func getServiceAccountClient(keyJsonfilePath string) (*http.Client, error) {
+func getServiceAccountClient(name string, keyJsonfilePath string) (*http.Client, error) {
 	data, err := ioutil.ReadFile(os.ExpandEnv(keyJsonfilePath))
 	if err != nil {
 		return nil, errors.Wrap(err, "error opening credentials file")
@@ -286,10 +336,47 @@ func getServiceAccountClient(keyJsonfilePath string) (*http.Client, error) {
 	if err != nil {
 		return nil, errors.Wrap(err, "error processing credentials")
 	}
-	ctxWithSpecialClient := oauthutil.Context(fshttp.NewClient(fs.Config))
+	ctxWithSpecialClient := oauthutil.Context(fshttp.NewClient(fs.ConfigForRemote(name)))
 	return oauth2.NewClient(ctxWithSpecialClient, conf.TokenSource(ctxWithSpecialClient)), nil
 }
 
+// isProtectedError returns true if the error was caused by trying to
+// modify or delete an object which is under a hold or retention
+// policy
+func isProtectedError(err error) bool {
+	gErr, ok := err.(*googleapi.Error)
+	if !ok || gErr.Code != http.StatusForbidden {
+		return false
+	}
+	message := strings.ToLower(gErr.Message)
+	return strings.Contains(message, "hold") || strings.Contains(message, "retention")
+}
+
+// parseEncryptionKey checks the customer-supplied encryption key and
+// returns the base64 encoded SHA256 of it
+func parseEncryptionKey(key string) (keySHA256 string, err error) {
+	keyBytes, err := base64.StdEncoding.DecodeString(key)
+	if err != nil {
+		return "", errors.Wrap(err, "encryption_key must be base64 encoded")
+	}
+	if len(keyBytes) != 32 {
+		return "", errors.Errorf("encryption_key must be a 256 bit key - was %d bits", 8*len(keyBytes))
+	}
+	sum := sha256.Sum256(keyBytes)
+	return base64.StdEncoding.EncodeToString(sum[:]), nil
+}
+
+// setEncryptionHeaders adds the headers needed to read or write an
+// object encrypted with the customer-supplied encryption key if set
+func (f *Fs) setEncryptionHeaders(header http.Header) {
+	if f.encryptionKey == "" {
+		return
+	}
+	header.Set("x-goog-encryption-algorithm", "AES256")
+	header.Set("x-goog-encryption-key", f.encryptionKey)
+	header.Set("x-goog-encryption-key-sha256", f.keySHA256)
+}
+
 // NewFs contstructs an Fs from the path, bucket:path
 func NewFs(name, root string) (fs.Fs, error) {
 	var oAuthClient *http.Client
@@ -297,7 +384,7 @@ func NewFs(name, root string) (fs.Fs, error) {
 
 	serviceAccountPath := config.FileGet(name, "service_account_file")
 	if serviceAccountPath != "" {
-		oAuthClient, err = getServiceAccountClient(serviceAccountPath)
+		oAuthClient, err = getServiceAccountClient(name, serviceAccountPath)
 		if err != nil {
 			log.Fatalf("Failed configuring Google Cloud Storage Service Account: %v", err)
 		}
@@ -322,6 +409,24 @@ func NewFs(name, root string) (fs.Fs, error) {
 		bucketACL:     config.FileGet(name, "bucket_acl"),
 		location:      config.FileGet(name, "location"),
 		storageClass:  config.FileGet(name, "storage_class"),
+		policyOnly:    config.FileGetBool(name, "bucket_policy_only"),
+		encryptionKey: config.FileGet(name, "encryption_key"),
+		kmsKeyName:    config.FileGet(name, "kms_key_name"),
+		objectHold:    config.FileGet(name, "object_hold"),
+	}
+	if f.encryptionKey != "" {
+		if f.kmsKeyName != "" {
+			return nil, errors.New("can't use both encryption_key and kms_key_name")
+		}
+		f.keySHA256, err = parseEncryptionKey(f.encryptionKey)
+		if err != nil {
+			return nil, err
+		}
+	}
+	switch f.objectHold {
+	case "", "temporary", "event-based":
+	default:
+		return nil, errors.Errorf("object_hold must be temporary or event-based - was %q", f.objectHold)
 	}
 	f.features = (&fs.Features{
 		ReadMimeType:  true,
@@ -340,6 +445,10 @@ func NewFs(name, root string) (fs.Fs, error) {
 	if *gcsStorageClass != "" {
 		f.storageClass = *gcsStorageClass
 	}
+	if *gcsNoCheckBucket || config.FileGetBool(name, "no_check_bucket") {
+		// Assume the bucket exists so Mkdir doesn't touch it
+		f.bucketOK = true
+	}
 
 	// Create a new authorized Drive client.
 	f.client = oAuthClient
@@ -351,7 +460,9 @@ func NewFs(name, root string) (fs.Fs, error) {
 	if f.root != "" {
 		f.root += "/"
 		// Check to see if the object exists
-		_, err = f.svc.Objects.Get(bucket, directory).Do()
+		getObject := f.svc.Objects.Get(bucket, directory)
+		f.setEncryptionHeaders(getObject.Header())
+		_, err = getObject.Do()
 		if err == nil {
 			f.root = path.Dir(directory)
 			if f.root == "." {
@@ -387,7 +498,7 @@ func (f *Fs) newObjectWithInfo(remote string, info *storage.Object) (fs.Object,
 
 // NewObject finds the Object at remote.  If it can't be found
 // it returns the error fs.ErrorObjectNotFound.
-func (f *Fs) NewObject(remote string) (fs.Object, error) {
+func (f *Fs) NewObject(ctx context.Context, remote string) (fs.Object, error) {
 	return f.newObjectWithInfo(remote, nil)
 }
 
@@ -517,7 +628,7 @@ func (f *Fs) listBuckets(dir string) (entries fs.DirEntries, err error) {
 //
 // This should return ErrDirNotFound if the directory isn't
 // found.
-func (f *Fs) List(dir string) (entries fs.DirEntries, err error) {
+func (f *Fs) List(ctx context.Context, dir string) (entries fs.DirEntries, err error) {
 	if f.bucket == "" {
 		return f.listBuckets(dir)
 	}
@@ -540,7 +651,7 @@ func (f *Fs) List(dir string) (entries fs.DirEntries, err error) {
 //
 // Don't implement this unless you have a more efficient way
 // of listing recursively that doing a directory traversal.
-func (f *Fs) ListR(dir string, callback fs.ListRCallback) (err error) {
+func (f *Fs) ListR(ctx context.Context, dir string, callback fs.ListRCallback) (err error) {
 	if f.bucket == "" {
 		return fs.ErrorListBucketRequired
 	}
@@ -563,22 +674,22 @@ func (f *Fs) ListR(dir string, callback fs.ListRCallback) (err error) {
 // Copy the reader in to the new object which is returned
 //
 // The new object may have been created if an error is returned
-func (f *Fs) Put(in io.Reader, src fs.ObjectInfo, options ...fs.OpenOption) (fs.Object, error) {
+func (f *Fs) Put(ctx context.Context, in io.Reader, src fs.ObjectInfo, options ...fs.OpenOption) (fs.Object, error) {
 	// Temporary Object under construction
 	o := &Object{
 		fs:     f,
 		remote: src.Remote(),
 	}
-	return o, o.Update(in, src, options...)
+	return o, o.Update(ctx, in, src, options...)
 }
 
 // PutStream uploads to the remote path with the modTime given of indeterminate size
-func (f *Fs) PutStream(in io.Reader, src fs.ObjectInfo, options ...fs.OpenOption) (fs.Object, error) {
-	return f.Put(in, src, options...)
+func (f *Fs) PutStream(ctx context.Context, in io.Reader, src fs.ObjectInfo, options ...fs.OpenOption) (fs.Object, error) {
+	return f.Put(ctx, in, src, options...)
 }
 
 // Mkdir creates the bucket if it doesn't exist
-func (f *Fs) Mkdir(dir string) error {
+func (f *Fs) Mkdir(ctx context.Context, dir string) error {
 	f.bucketOKMu.Lock()
 	defer f.bucketOKMu.Unlock()
 	if f.bucketOK {
@@ -606,7 +717,16 @@ func (f *Fs) Mkdir(dir string) error {
 		Location:     f.location,
 		StorageClass: f.storageClass,
 	}
-	_, err = f.svc.Buckets.Insert(f.projectNumber, &bucket).PredefinedAcl(f.bucketACL).Do()
+	if f.kmsKeyName != "" {
+		bucket.Encryption = &storage.BucketEncryption{
+			DefaultKmsKeyName: f.kmsKeyName,
+		}
+	}
+	insertBucket := f.svc.Buckets.Insert(f.projectNumber, &bucket)
+	if !f.policyOnly {
+		insertBucket.PredefinedAcl(f.bucketACL)
+	}
+	_, err = insertBucket.Do()
 	if err == nil {
 		f.bucketOK = true
 	}
@@ -617,7 +737,7 @@ func (f *Fs) Mkdir(dir string) error {
 //
 // Returns an error if it isn't empty: Error 409: The bucket you tried
 // to delete was not empty.
-func (f *Fs) Rmdir(dir string) error {
+func (f *Fs) Rmdir(ctx context.Context, dir string) error {
 	f.bucketOKMu.Lock()
 	defer f.bucketOKMu.Unlock()
 	if f.root != "" || dir != "" {
@@ -644,8 +764,8 @@ func (f *Fs) Precision() time.Duration {
 // Will only be called if src.Fs().Name() == f.Name()
 //
 // If it isn't possible then return fs.ErrorCantCopy
-func (f *Fs) Copy(src fs.Object, remote string) (fs.Object, error) {
-	err := f.Mkdir("")
+func (f *Fs) Copy(ctx context.Context, src fs.Object, remote string) (fs.Object, error) {
+	err := f.Mkdir(ctx, "")
 	if err != nil {
 		return nil, err
 	}
@@ -654,6 +774,11 @@ func (f *Fs) Copy(src fs.Object, remote string) (fs.Object, error) {
 		fs.Debugf(src, "Can't copy - not same remote type")
 		return nil, fs.ErrorCantCopy
 	}
+	if f.encryptionKey != "" || srcObj.fs.encryptionKey != "" {
+		// objects.copy doesn't support customer-supplied encryption keys
+		fs.Debugf(src, "Can't copy - using customer-supplied encryption keys")
+		return nil, fs.ErrorCantCopy
+	}
 
 	// Temporary Object under construction
 	dstObj := &Object{
@@ -717,6 +842,8 @@ func (o *Object) setMetaData(info *storage.Object) {
 	o.url = info.MediaLink
 	o.bytes = int64(info.Size)
 	o.mimeType = info.ContentType
+	o.held = info.TemporaryHold || info.EventBasedHold
+	o.retained = info.RetentionExpirationTime
 
 	// Read md5sum
 	md5sumData, err := base64.StdEncoding.DecodeString(info.Md5Hash)
@@ -753,7 +880,9 @@ func (o *Object) readMetaData() (err error) {
 	if !o.modTime.IsZero() {
 		return nil
 	}
-	object, err := o.fs.svc.Objects.Get(o.fs.bucket, o.fs.root+o.remote).Do()
+	getObject := o.fs.svc.Objects.Get(o.fs.bucket, o.fs.root+o.remote)
+	o.fs.setEncryptionHeaders(getObject.Header())
+	object, err := getObject.Do()
 	if err != nil {
 		if gErr, ok := err.(*googleapi.Error); ok {
 			if gErr.Code == http.StatusNotFound {
@@ -787,7 +916,7 @@ func metadataFromModTime(modTime time.Time) map[string]string {
 }
 
 // SetModTime sets the modification time of the local fs object
-func (o *Object) SetModTime(modTime time.Time) error {
+func (o *Object) SetModTime(ctx context.Context, modTime time.Time) error {
 	// This only adds metadata so will perserve other metadata
 	object := storage.Object{
 		Bucket:   o.fs.bucket,
@@ -796,6 +925,9 @@ func (o *Object) SetModTime(modTime time.Time) error {
 	}
 	newObject, err := o.fs.svc.Objects.Patch(o.fs.bucket, o.fs.root+o.remote, &object).Do()
 	if err != nil {
+		if isProtectedError(err) {
+			return fserrors.NoRetryError(err)
+		}
 		return err
 	}
 	o.setMetaData(newObject)
@@ -808,12 +940,13 @@ func (o *Object) Storable() bool {
 }
 
 // Open an object for read
-func (o *Object) Open(options ...fs.OpenOption) (in io.ReadCloser, err error) {
+func (o *Object) Open(ctx context.Context, options ...fs.OpenOption) (in io.ReadCloser, err error) {
 	req, err := http.NewRequest("GET", o.url, nil)
 	if err != nil {
 		return nil, err
 	}
 	fs.OpenOptionAddHTTPHeaders(req.Header, options)
+	o.fs.setEncryptionHeaders(req.Header)
 	res, err := o.fs.client.Do(req)
 	if err != nil {
 		return nil, err
@@ -829,8 +962,8 @@ func (o *Object) Open(options ...fs.OpenOption) (in io.ReadCloser, err error) {
 // Update the object with the contents of the io.Reader, modTime and size
 //
 // The new object may have been created if an error is returned
-func (o *Object) Update(in io.Reader, src fs.ObjectInfo, options ...fs.OpenOption) error {
-	err := o.fs.Mkdir("")
+func (o *Object) Update(ctx context.Context, in io.Reader, src fs.ObjectInfo, options ...fs.OpenOption) error {
+	err := o.fs.Mkdir(ctx, "")
 	if err != nil {
 		return err
 	}
@@ -843,8 +976,25 @@ func (o *Object) Update(in io.Reader, src fs.ObjectInfo, options ...fs.OpenOptio
 		Updated:     modTime.Format(timeFormatOut), // Doesn't get set
 		Metadata:    metadataFromModTime(modTime),
 	}
-	newObject, err := o.fs.svc.Objects.Insert(o.fs.bucket, &object).Media(in, googleapi.ContentType("")).Name(object.Name).PredefinedAcl(o.fs.objectACL).Do()
+	switch o.fs.objectHold {
+	case "temporary":
+		object.TemporaryHold = true
+	case "event-based":
+		object.EventBasedHold = true
+	}
+	insertObject := o.fs.svc.Objects.Insert(o.fs.bucket, &object).Media(in, googleapi.ContentType("")).Name(object.Name)
+	if !o.fs.policyOnly {
+		insertObject.PredefinedAcl(o.fs.objectACL)
+	}
+	if o.fs.kmsKeyName != "" {
+		insertObject.KmsKeyName(o.fs.kmsKeyName)
+	}
+	o.fs.setEncryptionHeaders(insertObject.Header())
+	newObject, err := insertObject.Do()
 	if err != nil {
+		if isProtectedError(err) {
+			return fserrors.NoRetryError(err)
+		}
 		return err
 	}
 	// Set the metadata for the new object while we have it
@@ -853,8 +1003,18 @@ func (o *Object) Update(in io.Reader, src fs.ObjectInfo, options ...fs.OpenOptio
 }
 
 // Remove an object
-func (o *Object) Remove() error {
-	return o.fs.svc.Objects.Delete(o.fs.bucket, o.fs.root+o.remote).Do()
+func (o *Object) Remove(ctx context.Context) error {
+	err := o.fs.svc.Objects.Delete(o.fs.bucket, o.fs.root+o.remote).Do()
+	if err != nil && isProtectedError(err) {
+		switch {
+		case o.held:
+			err = errors.Wrap(err, "object is under a hold")
+		case o.retained != "":
+			err = errors.Wrapf(err, "object is retained until %s", o.retained)
+		}
+		return fserrors.NoRetryError(err)
+	}
+	return err
 }
 
 // MimeType of an Object if known, "" otherwise
@@ -0,0 +1,38 @@
+package googlecloudstorage
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/api/googleapi"
+)
+
+func TestInternalParseEncryptionKey(t *testing.T) {
+	// 32 zero bytes
+	keySHA256, err := parseEncryptionKey("AAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAA=")
+	assert.NoError(t, err)
+	assert.Equal(t, "Zmh6rfhivXdsj8GLjp+OIAiXFIVu4jOzkCpZHQ1fKSU=", keySHA256)
+
+	_, err = parseEncryptionKey("not base64!")
+	assert.Error(t, err)
+
+	// 16 zero bytes
+	_, err = parseEncryptionKey("AAAAAAAAAAAAAAAAAAAAAA==")
+	assert.EqualError(t, err, "encryption_key must be a 256 bit key - was 128 bits")
+}
+
+func TestInternalIsProtectedError(t *testing.T) {
+	for _, test := range []struct {
+		err  error
+		want bool
+	}{
+		{nil, false},
+		{&googleapi.Error{Code: http.StatusForbidden, Message: "Object 'b/o' is under active Temporary hold and cannot be deleted, overwritten or archived until hold is removed."}, true},
+		{&googleapi.Error{Code: http.StatusForbidden, Message: "Object 'b/o' is subject to bucket's retention policy and cannot be deleted, overwritten or archived until 2018-05-01T00:00:00Z"}, true},
+		{&googleapi.Error{Code: http.StatusForbidden, Message: "Access denied."}, false},
+		{&googleapi.Error{Code: http.StatusNotFound, Message: "No such object"}, false},
+	} {
+		assert.Equal(t, test.want, isProtectedError(test.err), test.err)
+	}
+}
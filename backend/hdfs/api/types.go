@@ -0,0 +1,85 @@
+// Package api has type definitions for the WebHDFS REST API
+//
+// See https://hadoop.apache.org/docs/stable/hadoop-project-dist/hadoop-hdfs/WebHDFS.html
+package api
+
+import (
+	"fmt"
+	"time"
+)
+
+// Types of FileStatus
+const (
+	TypeFile      = "FILE"
+	TypeDirectory = "DIRECTORY"
+	TypeSymlink   = "SYMLINK"
+)
+
+// Time represents a time in milliseconds since the epoch as used by
+// WebHDFS
+type Time int64
+
+// Time converts the Time into a time.Time
+func (t Time) Time() time.Time {
+	return time.Unix(0, int64(t)*int64(time.Millisecond))
+}
+
+// NewTime makes a Time from a time.Time
+func NewTime(t time.Time) Time {
+	return Time(t.UnixNano() / int64(time.Millisecond))
+}
+
+// FileStatus describes a file or directory
+type FileStatus struct {
+	AccessTime       Time   `json:"accessTime"`
+	BlockSize        int64  `json:"blockSize"`
+	Group            string `json:"group"`
+	Length           int64  `json:"length"`
+	ModificationTime Time   `json:"modificationTime"`
+	Owner            string `json:"owner"`
+	PathSuffix       string `json:"pathSuffix"`
+	Permission       string `json:"permission"`
+	Replication      int    `json:"replication"`
+	Type             string `json:"type"`
+}
+
+// FileStatusResponse is returned by GETFILESTATUS
+type FileStatusResponse struct {
+	FileStatus FileStatus `json:"FileStatus"`
+}
+
+// ListStatusResponse is returned by LISTSTATUS
+type ListStatusResponse struct {
+	FileStatuses struct {
+		FileStatus []FileStatus `json:"FileStatus"`
+	} `json:"FileStatuses"`
+}
+
+// BooleanResponse is returned by MKDIRS, RENAME and DELETE
+type BooleanResponse struct {
+	Boolean bool `json:"boolean"`
+}
+
+// RemoteException is the body of an error returned by WebHDFS
+type RemoteException struct {
+	Exception     string `json:"exception"`
+	JavaClassName string `json:"javaClassName"`
+	Message       string `json:"message"`
+}
+
+// Error is returned from WebHDFS when things go wrong
+type Error struct {
+	StatusCode      int             `json:"-"`
+	RemoteException RemoteException `json:"RemoteException"`
+}
+
+// Error returns a string for the error and statistifes the error interface
+func (e *Error) Error() string {
+	if e.RemoteException.Exception == "" {
+		return fmt.Sprintf("webhdfs error: HTTP error %d", e.StatusCode)
+	}
+	return fmt.Sprintf("webhdfs error: %s: %s (%d)", e.RemoteException.Exception, e.RemoteException.Message, e.StatusCode)
+}
+
+// Check Error statisfies the error interface
+var _ error = (*Error)(nil)
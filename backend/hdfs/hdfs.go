@@ -0,0 +1,727 @@
+// Package hdfs provides an interface to the Hadoop Distributed File
+// System using the WebHDFS REST API.
+package hdfs
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"path"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/ncw/rclone/backend/hdfs/api"
+	"github.com/ncw/rclone/fs"
+	"github.com/ncw/rclone/fs/config"
+	"github.com/ncw/rclone/fs/config/obscure"
+	"github.com/ncw/rclone/fs/fserrors"
+	"github.com/ncw/rclone/fs/fshttp"
+	"github.com/ncw/rclone/fs/hash"
+	"github.com/ncw/rclone/lib/pacer"
+	"github.com/ncw/rclone/lib/rest"
+	"github.com/pkg/errors"
+	"golang.org/x/net/context"
+)
+
+const (
+	minSleep      = 10 * time.Millisecond
+	maxSleep      = 2 * time.Second
+	decayConstant = 2 // bigger for slower decay, exponential
+	apiPath       = "/webhdfs/v1"
+)
+
+var pacerOptions = pacer.AddFlags("hdfs", pacer.Options{
+	MinSleep:      minSleep,
+	MaxSleep:      maxSleep,
+	DecayConstant: decayConstant,
+})
+
+// Register with Fs
+func init() {
+	fs.Register(&fs.RegInfo{
+		Name:        "hdfs",
+		Description: "Hadoop distributed file system (WebHDFS)",
+		NewFs:       NewFs,
+		Options: []fs.Option{{
+			Name: "namenode",
+			Help: "URL of the namenode's WebHDFS endpoint",
+			Examples: []fs.OptionExample{{
+				Value: "http://namenode:9870",
+				Help:  "Hadoop 3 default port",
+			}, {
+				Value: "http://namenode:50070",
+				Help:  "Hadoop 2 default port",
+			}},
+		}, {
+			Name:     "username",
+			Help:     "Hadoop user name for simple authentication - leave blank to use the delegation token.",
+			Optional: true,
+		}, {
+			Name:       "delegation_token",
+			Help:       "Delegation token for clusters secured with Kerberos - leave blank for simple authentication.",
+			Optional:   true,
+			IsPassword: true,
+		}},
+	})
+}
+
+// Fs represents a remote hdfs
+type Fs struct {
+	name             string       // name of this remote
+	root             string       // the path we are working on
+	features         *fs.Features // optional features
+	srv              *rest.Client // the connection to the namenode
+	noRedirectClient *http.Client // client which doesn't follow redirects for upload
+	endpointURL      string       // URL of the WebHDFS API
+	pacer            *pacer.Pacer // pacer for API calls
+	user             string       // user name for simple auth
+	delegation       string       // delegation token for secured clusters
+}
+
+// Object describes an hdfs object
+type Object struct {
+	fs      *Fs       // what this object is part of
+	remote  string    // The remote path
+	size    int64     // size of the object
+	modTime time.Time // modification time of the object
+}
+
+// ------------------------------------------------------------
+
+// Name of the remote (as passed into NewFs)
+func (f *Fs) Name() string {
+	return f.name
+}
+
+// Root of the remote (as passed into NewFs)
+func (f *Fs) Root() string {
+	return f.root
+}
+
+// String converts this Fs to a string
+func (f *Fs) String() string {
+	return fmt.Sprintf("hdfs root '%s'", f.root)
+}
+
+// Features returns the optional features of this Fs
+func (f *Fs) Features() *fs.Features {
+	return f.features
+}
+
+// parsePath parses an hdfs 'url'
+func parsePath(path string) (root string) {
+	root = strings.Trim(path, "/")
+	return
+}
+
+// retryErrorCodes is a slice of error codes that we will retry
+var retryErrorCodes = []int{
+	429, // Too Many Requests.
+	500, // Internal Server Error
+	502, // Bad Gateway
+	503, // Service Unavailable
+	504, // Gateway Timeout
+	509, // Bandwidth Limit Exceeded
+}
+
+// shouldRetry returns a boolean as to whether this resp and err
+// deserve to be retried.  It returns the err as a convenience
+func shouldRetry(resp *http.Response, err error) (bool, error) {
+	if apiErr, ok := err.(*api.Error); ok {
+		// The namenode is busy or failing over
+		switch apiErr.RemoteException.Exception {
+		case "RetriableException", "StandbyException":
+			return true, err
+		}
+	}
+	return fserrors.ShouldRetry(err) || fserrors.ShouldRetryHTTP(resp, retryErrorCodes), pacer.RetryAfterHTTP(resp, err)
+}
+
+// errorHandler parses a non 2xx error response into an error
+func errorHandler(resp *http.Response) error {
+	// Decode error response
+	errResponse := new(api.Error)
+	err := rest.DecodeJSON(resp, &errResponse)
+	if err != nil {
+		fs.Debugf(nil, "Couldn't decode error response: %v", err)
+	}
+	errResponse.StatusCode = resp.StatusCode
+	return errResponse
+}
+
+// isNotFound returns true if err means the path doesn't exist
+func isNotFound(err error) bool {
+	apiErr, ok := err.(*api.Error)
+	return ok && (apiErr.StatusCode == http.StatusNotFound || apiErr.RemoteException.Exception == "FileNotFoundException")
+}
+
+// NewFs constructs an Fs from the path, container:path
+func NewFs(name, root string) (fs.Fs, error) {
+	endpoint := strings.TrimRight(config.FileGet(name, "namenode"), "/")
+	if endpoint == "" {
+		return nil, errors.New("hdfs: namenode not set in config")
+	}
+	if _, err := url.Parse(endpoint); err != nil {
+		return nil, errors.Wrap(err, "hdfs: couldn't parse namenode URL")
+	}
+	delegation := config.FileGet(name, "delegation_token")
+	if delegation != "" {
+		var err error
+		delegation, err = obscure.Reveal(delegation)
+		if err != nil {
+			return nil, errors.Wrap(err, "couldn't decrypt delegation token")
+		}
+	}
+
+	client := fshttp.NewClient(fs.ConfigForRemote(name))
+	noRedirectClient := *client
+	noRedirectClient.CheckRedirect = func(req *http.Request, via []*http.Request) error {
+		return http.ErrUseLastResponse
+	}
+
+	f := &Fs{
+		name:             name,
+		root:             parsePath(root),
+		srv:              rest.NewClient(client).SetRoot(endpoint + apiPath),
+		noRedirectClient: &noRedirectClient,
+		endpointURL:      endpoint + apiPath,
+		pacer:            pacer.New().SetOptions(pacerOptions),
+		user:             config.FileGet(name, "username"),
+		delegation:       delegation,
+	}
+	f.features = (&fs.Features{
+		CanHaveEmptyDirectories: true,
+	}).Fill(f)
+	f.srv.SetErrorHandler(errorHandler)
+
+	if f.root != "" {
+		// Check to see if the root actually an existing file
+		info, err := f.getFileStatus("")
+		if err == nil && info.Type == api.TypeFile {
+			newRoot := path.Dir(f.root)
+			if newRoot == "." {
+				newRoot = ""
+			}
+			f.root = newRoot
+			// return an error with an fs which points to the parent
+			return f, fs.ErrorIsFile
+		}
+	}
+	return f, nil
+}
+
+// filePath returns an escaped absolute path (f.root, remote)
+func (f *Fs) filePath(remote string) string {
+	return rest.URLPathEscape("/" + path.Join(f.root, remote))
+}
+
+// absPath returns an unescaped absolute path (f.root, remote)
+func (f *Fs) absPath(remote string) string {
+	return "/" + path.Join(f.root, remote)
+}
+
+// params makes the query parameters for op including the authentication
+func (f *Fs) params(op string) url.Values {
+	params := url.Values{}
+	params.Set("op", op)
+	if f.delegation != "" {
+		params.Set("delegation", f.delegation)
+	} else if f.user != "" {
+		params.Set("user.name", f.user)
+	}
+	return params
+}
+
+// getFileStatus reads the status of remote
+func (f *Fs) getFileStatus(remote string) (info *api.FileStatus, err error) {
+	opts := rest.Opts{
+		Method:     "GET",
+		Path:       f.filePath(remote),
+		Parameters: f.params("GETFILESTATUS"),
+	}
+	var result api.FileStatusResponse
+	var resp *http.Response
+	err = f.pacer.Call(func() (bool, error) {
+		resp, err = f.srv.CallJSON(&opts, nil, &result)
+		return shouldRetry(resp, err)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &result.FileStatus, nil
+}
+
+// Return an Object from a path
+//
+// If it can't be found it returns the error fs.ErrorObjectNotFound.
+func (f *Fs) newObjectWithInfo(remote string, info *api.FileStatus) (fs.Object, error) {
+	o := &Object{
+		fs:     f,
+		remote: remote,
+	}
+	var err error
+	if info != nil {
+		err = o.setMetaData(info)
+	} else {
+		err = o.readMetaData()
+	}
+	if err != nil {
+		return nil, err
+	}
+	return o, nil
+}
+
+// NewObject finds the Object at remote.  If it can't be found
+// it returns the error fs.ErrorObjectNotFound.
+func (f *Fs) NewObject(ctx context.Context, remote string) (fs.Object, error) {
+	return f.newObjectWithInfo(remote, nil)
+}
+
+// List the objects and directories in dir into entries.  The
+// entries can be returned in any order but should be for a
+// complete directory.
+//
+// dir should be "" to list the root, and should not have
+// trailing slashes.
+//
+// This should return ErrDirNotFound if the directory isn't
+// found.
+func (f *Fs) List(ctx context.Context, dir string) (entries fs.DirEntries, err error) {
+	opts := rest.Opts{
+		Method:     "GET",
+		Path:       f.filePath(dir),
+		Parameters: f.params("LISTSTATUS"),
+	}
+	var result api.ListStatusResponse
+	var resp *http.Response
+	err = f.pacer.Call(func() (bool, error) {
+		resp, err = f.srv.CallJSON(&opts, nil, &result)
+		return shouldRetry(resp, err)
+	})
+	if isNotFound(err) {
+		return nil, fs.ErrorDirNotFound
+	}
+	if err != nil {
+		return nil, errors.Wrap(err, "couldn't list files")
+	}
+	for i := range result.FileStatuses.FileStatus {
+		item := &result.FileStatuses.FileStatus[i]
+		if item.PathSuffix == "" {
+			// LISTSTATUS on a file returns the file itself
+			return nil, fs.ErrorDirNotFound
+		}
+		remote := path.Join(dir, item.PathSuffix)
+		switch item.Type {
+		case api.TypeDirectory:
+			d := fs.NewDir(remote, item.ModificationTime.Time())
+			entries = append(entries, d)
+		case api.TypeFile:
+			o, err := f.newObjectWithInfo(remote, item)
+			if err != nil {
+				return nil, err
+			}
+			entries = append(entries, o)
+		default:
+			fs.Debugf(f, "Skipping %q of unknown type %q", remote, item.Type)
+		}
+	}
+	return entries, nil
+}
+
+// Put the object
+//
+// Copy the reader in to the new object which is returned
+//
+// The new object may have been created if an error is returned
+func (f *Fs) Put(ctx context.Context, in io.Reader, src fs.ObjectInfo, options ...fs.OpenOption) (fs.Object, error) {
+	o := &Object{
+		fs:     f,
+		remote: src.Remote(),
+	}
+	return o, o.Update(ctx, in, src, options...)
+}
+
+// PutStream uploads to the remote path with the modTime given of indeterminate size
+func (f *Fs) PutStream(ctx context.Context, in io.Reader, src fs.ObjectInfo, options ...fs.OpenOption) (fs.Object, error) {
+	return f.Put(ctx, in, src, options...)
+}
+
+// callBoolean calls an operation which returns a boolean result,
+// returning an error if the result is false
+func (f *Fs) callBoolean(opts *rest.Opts) (err error) {
+	var result api.BooleanResponse
+	var resp *http.Response
+	err = f.pacer.Call(func() (bool, error) {
+		resp, err = f.srv.CallJSON(opts, nil, &result)
+		return shouldRetry(resp, err)
+	})
+	if err != nil {
+		return err
+	}
+	if !result.Boolean {
+		return errors.Errorf("%s failed", opts.Parameters.Get("op"))
+	}
+	return nil
+}
+
+// Mkdir creates the directory and any parents if it doesn't exist
+func (f *Fs) Mkdir(ctx context.Context, dir string) error {
+	opts := rest.Opts{
+		Method:     "PUT",
+		Path:       f.filePath(dir),
+		Parameters: f.params("MKDIRS"),
+	}
+	err := f.callBoolean(&opts)
+	if err != nil {
+		return errors.Wrap(err, "mkdir failed")
+	}
+	return nil
+}
+
+// purgeCheck removes the directory dir, if check is set then it
+// refuses to do so if it has anything in
+func (f *Fs) purgeCheck(dir string, check bool) error {
+	info, err := f.getFileStatus(dir)
+	if isNotFound(err) {
+		return fs.ErrorDirNotFound
+	}
+	if err != nil {
+		return errors.Wrap(err, "rmdir failed")
+	}
+	if info.Type != api.TypeDirectory {
+		return fs.ErrorIsFile
+	}
+	opts := rest.Opts{
+		Method:     "DELETE",
+		Path:       f.filePath(dir),
+		Parameters: f.params("DELETE"),
+	}
+	// HDFS refuses to delete a non empty directory unless recursive is set
+	opts.Parameters.Set("recursive", strconv.FormatBool(!check))
+	err = f.callBoolean(&opts)
+	if apiErr, ok := err.(*api.Error); ok && apiErr.RemoteException.Exception == "PathIsNotEmptyDirectoryException" {
+		return fs.ErrorDirectoryNotEmpty
+	}
+	if err != nil {
+		return errors.Wrap(err, "rmdir failed")
+	}
+	return nil
+}
+
+// Rmdir deletes the root folder
+//
+// Returns an error if it isn't empty
+func (f *Fs) Rmdir(ctx context.Context, dir string) error {
+	return f.purgeCheck(dir, true)
+}
+
+// Purge deletes all the files and the container
+//
+// Optional interface: Only implement this if you have a way of
+// deleting all the files quicker than just running Remove() on the
+// result of List()
+func (f *Fs) Purge(ctx context.Context) error {
+	return f.purgeCheck("", false)
+}
+
+// Precision return the precision of this Fs
+func (f *Fs) Precision() time.Duration {
+	return time.Millisecond
+}
+
+// rename renames srcPath (escaped) to the absolute dstPath
+func (f *Fs) rename(srcPath, dstPath string) error {
+	opts := rest.Opts{
+		Method:     "PUT",
+		Path:       srcPath,
+		Parameters: f.params("RENAME"),
+	}
+	opts.Parameters.Set("destination", dstPath)
+	return f.callBoolean(&opts)
+}
+
+// mkParentDir makes the parent of remote if necessary
+func (f *Fs) mkParentDir(ctx context.Context, remote string) error {
+	parent := path.Dir(remote)
+	if parent == "." {
+		parent = ""
+	}
+	return f.Mkdir(ctx, parent)
+}
+
+// Move src to this remote using server side move operations.
+//
+// This is stored with the remote path given
+//
+// It returns the destination Object and a possible error
+//
+// Will only be called if src.Fs().Name() == f.Name()
+//
+// If it isn't possible then return fs.ErrorCantMove
+func (f *Fs) Move(ctx context.Context, src fs.Object, remote string) (fs.Object, error) {
+	srcObj, ok := src.(*Object)
+	if !ok {
+		fs.Debugf(src, "Can't move - not same remote type")
+		return nil, fs.ErrorCantMove
+	}
+	err := f.mkParentDir(ctx, remote)
+	if err != nil {
+		return nil, err
+	}
+	// RENAME won't overwrite so remove any existing object first
+	dstObj, err := f.NewObject(ctx, remote)
+	if err == nil {
+		err = dstObj.Remove(ctx)
+		if err != nil {
+			return nil, err
+		}
+	}
+	err = f.rename(srcObj.fs.filePath(srcObj.remote), f.absPath(remote))
+	if err != nil {
+		return nil, errors.Wrap(err, "move failed")
+	}
+	return f.NewObject(ctx, remote)
+}
+
+// DirMove moves src, srcRemote to this remote at dstRemote
+// using server side move operations.
+//
+// Will only be called if src.Fs().Name() == f.Name()
+//
+// If it isn't possible then return fs.ErrorCantDirMove
+//
+// If destination exists then return fs.ErrorDirExists
+func (f *Fs) DirMove(ctx context.Context, src fs.Fs, srcRemote, dstRemote string) error {
+	srcFs, ok := src.(*Fs)
+	if !ok {
+		fs.Debugf(srcFs, "Can't move directory - not same remote type")
+		return fs.ErrorCantDirMove
+	}
+	// Check if destination exists
+	_, err := f.getFileStatus(dstRemote)
+	if err == nil {
+		return fs.ErrorDirExists
+	} else if !isNotFound(err) {
+		return err
+	}
+	err = f.mkParentDir(ctx, dstRemote)
+	if err != nil {
+		return err
+	}
+	err = f.rename(srcFs.filePath(srcRemote), f.absPath(dstRemote))
+	if err != nil {
+		return errors.Wrap(err, "dirmove failed")
+	}
+	return nil
+}
+
+// Hashes returns the supported hash sets.
+func (f *Fs) Hashes() hash.Set {
+	return hash.Set(hash.None)
+}
+
+// ------------------------------------------------------------
+
+// Fs returns the parent Fs
+func (o *Object) Fs() fs.Info {
+	return o.fs
+}
+
+// Return a string version
+func (o *Object) String() string {
+	if o == nil {
+		return "<nil>"
+	}
+	return o.remote
+}
+
+// Remote returns the remote path
+func (o *Object) Remote() string {
+	return o.remote
+}
+
+// Hash is unsupported on hdfs
+func (o *Object) Hash(t hash.Type) (string, error) {
+	return "", hash.ErrUnsupported
+}
+
+// Size returns the size of an object in bytes
+func (o *Object) Size() int64 {
+	return o.size
+}
+
+// setMetaData sets the metadata from info
+func (o *Object) setMetaData(info *api.FileStatus) error {
+	if info.Type != api.TypeFile {
+		return fs.ErrorNotAFile
+	}
+	o.size = info.Length
+	o.modTime = info.ModificationTime.Time()
+	return nil
+}
+
+// readMetaData gets the metadata
+func (o *Object) readMetaData() error {
+	info, err := o.fs.getFileStatus(o.remote)
+	if isNotFound(err) {
+		return fs.ErrorObjectNotFound
+	}
+	if err != nil {
+		return err
+	}
+	return o.setMetaData(info)
+}
+
+// ModTime returns the modification time of the object
+func (o *Object) ModTime() time.Time {
+	return o.modTime
+}
+
+// SetModTime sets the modification time of the object
+func (o *Object) SetModTime(ctx context.Context, modTime time.Time) error {
+	opts := rest.Opts{
+		Method:     "PUT",
+		Path:       o.fs.filePath(o.remote),
+		Parameters: o.fs.params("SETTIMES"),
+		NoResponse: true,
+	}
+	opts.Parameters.Set("modificationtime", strconv.FormatInt(int64(api.NewTime(modTime)), 10))
+	err := o.fs.pacer.Call(func() (bool, error) {
+		resp, err := o.fs.srv.Call(&opts)
+		return shouldRetry(resp, err)
+	})
+	if err != nil {
+		return errors.Wrap(err, "failed to set modification time")
+	}
+	o.modTime = modTime
+	return nil
+}
+
+// Storable returns a boolean showing whether this object storable
+func (o *Object) Storable() bool {
+	return true
+}
+
+// Open an object for read
+func (o *Object) Open(ctx context.Context, options ...fs.OpenOption) (in io.ReadCloser, err error) {
+	opts := rest.Opts{
+		Method:     "GET",
+		Path:       o.fs.filePath(o.remote),
+		Parameters: o.fs.params("OPEN"),
+	}
+	for _, option := range options {
+		switch x := option.(type) {
+		case *fs.SeekOption:
+			opts.Parameters.Set("offset", strconv.FormatInt(x.Offset, 10))
+		case *fs.RangeOption:
+			offset, limit := x.Decode(o.size)
+			opts.Parameters.Set("offset", strconv.FormatInt(offset, 10))
+			if limit >= 0 {
+				opts.Parameters.Set("length", strconv.FormatInt(limit, 10))
+			}
+		default:
+			if option.Mandatory() {
+				fs.Logf(o, "Unsupported mandatory option: %v", option)
+			}
+		}
+	}
+	var resp *http.Response
+	err = o.fs.pacer.Call(func() (bool, error) {
+		resp, err = o.fs.srv.Call(&opts)
+		return shouldRetry(resp, err)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return resp.Body, nil
+}
+
+// createLocation asks the namenode which datanode to write remote to
+//
+// The namenode replies with a redirect which must not be followed
+// automatically as the data is sent to the datanode in a second
+// request.
+func (o *Object) createLocation() (location string, err error) {
+	params := o.fs.params("CREATE")
+	params.Set("overwrite", "true")
+	URL := o.fs.endpointURL + o.fs.filePath(o.remote) + "?" + params.Encode()
+	err = o.fs.pacer.Call(func() (bool, error) {
+		req, err := http.NewRequest("PUT", URL, nil)
+		if err != nil {
+			return false, err
+		}
+		resp, err := o.fs.noRedirectClient.Do(req)
+		if err != nil {
+			return shouldRetry(resp, err)
+		}
+		if resp.StatusCode != http.StatusTemporaryRedirect {
+			err = errorHandler(resp)
+			return shouldRetry(resp, err)
+		}
+		location = resp.Header.Get("Location")
+		return false, resp.Body.Close()
+	})
+	if err != nil {
+		return "", err
+	}
+	if location == "" {
+		return "", errors.New("no Location returned for upload")
+	}
+	return location, nil
+}
+
+// Update the object with the contents of the io.Reader, modTime and size
+//
+// If existing is set then it updates the object rather than creating a new one
+//
+// The new object may have been created if an error is returned
+func (o *Object) Update(ctx context.Context, in io.Reader, src fs.ObjectInfo, options ...fs.OpenOption) (err error) {
+	location, err := o.createLocation()
+	if err != nil {
+		return errors.Wrap(err, "failed to start upload")
+	}
+	opts := rest.Opts{
+		Method:      "PUT",
+		RootURL:     location,
+		Body:        in,
+		ContentType: "application/octet-stream",
+		NoResponse:  true,
+	}
+	if size := src.Size(); size >= 0 {
+		opts.ContentLength = &size
+	}
+	err = o.fs.pacer.CallNoRetry(func() (bool, error) {
+		resp, err := o.fs.srv.Call(&opts)
+		return shouldRetry(resp, err)
+	})
+	if err != nil {
+		return errors.Wrap(err, "failed to upload")
+	}
+	err = o.SetModTime(ctx, src.ModTime())
+	if err != nil {
+		return err
+	}
+	return o.readMetaData()
+}
+
+// Remove an object
+func (o *Object) Remove(ctx context.Context) error {
+	opts := rest.Opts{
+		Method:     "DELETE",
+		Path:       o.fs.filePath(o.remote),
+		Parameters: o.fs.params("DELETE"),
+	}
+	return o.fs.callBoolean(&opts)
+}
+
+// Check the interfaces are satisfied
+var (
+	_ fs.Fs          = (*Fs)(nil)
+	_ fs.Purger      = (*Fs)(nil)
+	_ fs.Mover       = (*Fs)(nil)
+	_ fs.DirMover    = (*Fs)(nil)
+	_ fs.PutStreamer = (*Fs)(nil)
+	_ fs.Object      = (*Object)(nil)
+)
@@ -0,0 +1,48 @@
+package hdfs
+
+import (
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ncw/rclone/backend/hdfs/api"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTime(t *testing.T) {
+	when := time.Date(2018, 8, 1, 12, 30, 45, 123000000, time.UTC)
+	hdfsTime := api.NewTime(when)
+	assert.Equal(t, api.Time(1533126645123), hdfsTime)
+	assert.True(t, when.Equal(hdfsTime.Time()))
+}
+
+func TestErrorHandler(t *testing.T) {
+	resp := &http.Response{
+		StatusCode: http.StatusNotFound,
+		Body: ioutil.NopCloser(strings.NewReader(`{"RemoteException":{
+  "exception"    : "FileNotFoundException",
+  "javaClassName": "java.io.FileNotFoundException",
+  "message"      : "File does not exist: /foo/a.patch"
+}}`)),
+	}
+	err := errorHandler(resp)
+	require.Error(t, err)
+	assert.True(t, isNotFound(err))
+	assert.Equal(t, "webhdfs error: FileNotFoundException: File does not exist: /foo/a.patch (404)", err.Error())
+
+	resp = &http.Response{
+		StatusCode: http.StatusForbidden,
+		Body: ioutil.NopCloser(strings.NewReader(`{"RemoteException":{
+  "exception"    : "StandbyException",
+  "javaClassName": "org.apache.hadoop.ipc.StandbyException",
+  "message"      : "Operation category READ is not supported in state standby"
+}}`)),
+	}
+	err = errorHandler(resp)
+	assert.False(t, isNotFound(err))
+	retry, _ := shouldRetry(resp, err)
+	assert.True(t, retry)
+}
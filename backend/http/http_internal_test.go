@@ -20,6 +20,7 @@ import (
 	"github.com/ncw/rclone/lib/rest"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
+	"golang.org/x/net/context"
 )
 
 var (
@@ -60,7 +61,8 @@ func prepare(t *testing.T) (fs.Fs, func()) {
 }
 
 func testListRoot(t *testing.T, f fs.Fs) {
-	entries, err := f.List("")
+	ctx := context.Background()
+	entries, err := f.List(ctx, "")
 	require.NoError(t, err)
 
 	sort.Sort(entries)
@@ -99,10 +101,11 @@ func TestListRoot(t *testing.T) {
 }
 
 func TestListSubDir(t *testing.T) {
+	ctx := context.Background()
 	f, tidy := prepare(t)
 	defer tidy()
 
-	entries, err := f.List("three")
+	entries, err := f.List(ctx, "three")
 	require.NoError(t, err)
 
 	sort.Sort(entries)
@@ -117,10 +120,11 @@ func TestListSubDir(t *testing.T) {
 }
 
 func TestNewObject(t *testing.T) {
+	ctx := context.Background()
 	f, tidy := prepare(t)
 	defer tidy()
 
-	o, err := f.NewObject("four/under four.txt")
+	o, err := f.NewObject(ctx, "four/under four.txt")
 	require.NoError(t, err)
 
 	assert.Equal(t, "four/under four.txt", o.Remote())
@@ -141,21 +145,22 @@ func TestNewObject(t *testing.T) {
 }
 
 func TestOpen(t *testing.T) {
+	ctx := context.Background()
 	f, tidy := prepare(t)
 	defer tidy()
 
-	o, err := f.NewObject("four/under four.txt")
+	o, err := f.NewObject(ctx, "four/under four.txt")
 	require.NoError(t, err)
 
 	// Test normal read
-	fd, err := o.Open()
+	fd, err := o.Open(ctx)
 	require.NoError(t, err)
 	data, err := ioutil.ReadAll(fd)
 	require.NoError(t, fd.Close())
 	assert.Equal(t, "beetroot\n", string(data))
 
 	// Test with range request
-	fd, err = o.Open(&fs.RangeOption{Start: 1, End: 5})
+	fd, err = o.Open(ctx, &fs.RangeOption{Start: 1, End: 5})
 	require.NoError(t, err)
 	data, err = ioutil.ReadAll(fd)
 	require.NoError(t, fd.Close())
@@ -163,10 +168,11 @@ func TestOpen(t *testing.T) {
 }
 
 func TestMimeType(t *testing.T) {
+	ctx := context.Background()
 	f, tidy := prepare(t)
 	defer tidy()
 
-	o, err := f.NewObject("four/under four.txt")
+	o, err := f.NewObject(ctx, "four/under four.txt")
 	require.NoError(t, err)
 
 	do, ok := o.(fs.MimeTyper)
@@ -185,13 +191,14 @@ func TestIsAFileRoot(t *testing.T) {
 }
 
 func TestIsAFileSubDir(t *testing.T) {
+	ctx := context.Background()
 	tidy := prepareServer(t)
 	defer tidy()
 
 	f, err := NewFs(remoteName, "three/underthree.txt")
 	assert.Equal(t, err, fs.ErrorIsFile)
 
-	entries, err := f.List("")
+	entries, err := f.List(ctx, "")
 	require.NoError(t, err)
 
 	sort.Sort(entries)
@@ -0,0 +1,1129 @@
+// Package jottacloud provides an interface to the Jottacloud storage
+// system.
+package jottacloud
+
+import (
+	"bytes"
+	"crypto/md5"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"log"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/ncw/rclone/backend/jottacloud/api"
+	"github.com/ncw/rclone/fs"
+	"github.com/ncw/rclone/fs/config"
+	"github.com/ncw/rclone/fs/config/flags"
+	"github.com/ncw/rclone/fs/fserrors"
+	"github.com/ncw/rclone/fs/fshttp"
+	"github.com/ncw/rclone/fs/hash"
+	"github.com/ncw/rclone/fs/walk"
+	"github.com/ncw/rclone/lib/oauthutil"
+	"github.com/ncw/rclone/lib/pacer"
+	"github.com/ncw/rclone/lib/rest"
+	"github.com/pkg/errors"
+	"golang.org/x/net/context"
+	"golang.org/x/oauth2"
+)
+
+const (
+	minSleep          = 10 * time.Millisecond
+	maxSleep          = 2 * time.Second
+	decayConstant     = 2 // bigger for slower decay, exponential
+	defaultDevice     = "Jotta"
+	defaultMountpoint = "Archive"
+	rootURL           = "https://jfs.jottacloud.com/jfs/"
+	apiURL            = "https://api.jottacloud.com/"
+	defaultTokenURL   = "https://id.jottacloud.com/auth/realms/jottacloud/protocol/openid-connect/token"
+	loginTokenURL     = "https://www.jottacloud.com/web/secure"
+	rcloneClientID    = "jottacli"
+	configTokenURL    = "token_url"
+)
+
+var pacerOptions = pacer.AddFlags("jottacloud", pacer.Options{
+	MinSleep:      minSleep,
+	MaxSleep:      maxSleep,
+	DecayConstant: decayConstant,
+})
+
+// Globals
+var (
+	// Description of how to auth for this app
+	oauthConfig = &oauth2.Config{
+		Endpoint: oauth2.Endpoint{
+			TokenURL: defaultTokenURL,
+		},
+		ClientID:    rcloneClientID,
+		Scopes:      []string{"openid", "offline_access"},
+		RedirectURL: oauthutil.RedirectLocalhostURL,
+	}
+	md5MemoryLimit = fs.SizeSuffix(10 * 1024 * 1024)
+	hardDelete     = flags.BoolP("jottacloud-hard-delete", "", false, "Delete files permanently rather than putting them into the trash.")
+)
+
+// Register with Fs
+func init() {
+	fs.Register(&fs.RegInfo{
+		Name:        "jottacloud",
+		Description: "JottaCloud",
+		NewFs:       NewFs,
+		Config: func(name string) {
+			err := doConfig(name)
+			if err != nil {
+				log.Fatalf("Failed to configure Jottacloud: %v", err)
+			}
+		},
+		Options: []fs.Option{{
+			Name:     "device",
+			Help:     "The device to use - leave blank normally to choose from a list.",
+			Optional: true,
+		}, {
+			Name:     "mountpoint",
+			Help:     "The mountpoint to use - leave blank normally to choose from a list.",
+			Optional: true,
+		}},
+	})
+	flags.VarP(&md5MemoryLimit, "jottacloud-md5-memory-limit", "", "Files bigger than this will be cached on disk to calculate the MD5 if required.")
+}
+
+// Fs represents a remote jottacloud
+type Fs struct {
+	name         string           // name of this remote
+	root         string           // the path we are working on
+	user         string           // the internal username of the account
+	device       string           // the device being used
+	mountpoint   string           // the mountpoint being used
+	features     *fs.Features     // optional features
+	srv          *rest.Client     // the connection to the jfs server
+	apiSrv       *rest.Client     // the connection to the api server
+	pacer        *pacer.Pacer     // pacer for API calls
+	tokenRenewer *oauthutil.Renew // renew the token on expiry
+}
+
+// Object describes a jottacloud object
+//
+// Will definitely have info but maybe not meta
+type Object struct {
+	fs          *Fs       // what this object is part of
+	remote      string    // The remote path
+	hasMetaData bool      // whether info below has been set
+	size        int64     // size of the object
+	modTime     time.Time // modification time of the object
+	md5         string    // MD5 of the object
+	mimeType    string    // Content-Type of the object
+}
+
+// ------------------------------------------------------------
+
+// Name of the remote (as passed into NewFs)
+func (f *Fs) Name() string {
+	return f.name
+}
+
+// Root of the remote (as passed into NewFs)
+func (f *Fs) Root() string {
+	return f.root
+}
+
+// String converts this Fs to a string
+func (f *Fs) String() string {
+	return fmt.Sprintf("jottacloud root '%s'", f.root)
+}
+
+// Features returns the optional features of this Fs
+func (f *Fs) Features() *fs.Features {
+	return f.features
+}
+
+// parsePath parses a jottacloud 'url'
+func parsePath(path string) (root string) {
+	root = strings.Trim(path, "/")
+	return
+}
+
+// retryErrorCodes is a slice of error codes that we will retry
+var retryErrorCodes = []int{
+	429, // Too Many Requests.
+	500, // Internal Server Error
+	502, // Bad Gateway
+	503, // Service Unavailable
+	504, // Gateway Timeout
+	509, // Bandwidth Limit Exceeded
+}
+
+// shouldRetry returns a boolean as to whether this resp and err
+// deserve to be retried.  It returns the err as a convenience
+func shouldRetry(resp *http.Response, err error) (bool, error) {
+	return fserrors.ShouldRetry(err) || fserrors.ShouldRetryHTTP(resp, retryErrorCodes), pacer.RetryAfterHTTP(resp, err)
+}
+
+// errorHandler parses a non 2xx error response into an error
+func errorHandler(resp *http.Response) error {
+	// Decode error response
+	errResponse := new(api.Error)
+	err := rest.DecodeXML(resp, &errResponse)
+	if err != nil {
+		fs.Debugf(nil, "Couldn't decode error response: %v", err)
+	}
+	if errResponse.StatusCode == 0 {
+		errResponse.StatusCode = resp.StatusCode
+	}
+	if errResponse.Message == "" {
+		errResponse.Message = resp.Status
+	}
+	return errResponse
+}
+
+// isNotFound returns true if err is a 404 from the server
+func isNotFound(err error) bool {
+	apiErr, ok := err.(*api.Error)
+	return ok && apiErr.StatusCode == http.StatusNotFound
+}
+
+// getOAuthConfig returns the oauth config using the token URL
+// discovered when the login token was exchanged
+func getOAuthConfig(name string) *oauth2.Config {
+	oauthConfigCopy := *oauthConfig
+	oauthConfigCopy.Endpoint.TokenURL = config.FileGet(name, configTokenURL, defaultTokenURL)
+	return &oauthConfigCopy
+}
+
+// doTokenAuth exchanges the personal login token for an oauth token
+//
+// It returns the token and the token URL to use to refresh it
+func doTokenAuth(name string, srv *rest.Client, loginTokenBase64 string) (token *oauth2.Token, tokenURL string, err error) {
+	loginTokenBytes, err := base64.RawURLEncoding.DecodeString(strings.TrimRight(strings.TrimSpace(loginTokenBase64), "="))
+	if err != nil {
+		return nil, "", errors.Wrap(err, "failed to decode login token")
+	}
+	var loginToken api.LoginToken
+	err = json.Unmarshal(loginTokenBytes, &loginToken)
+	if err != nil {
+		return nil, "", errors.Wrap(err, "failed to parse login token")
+	}
+
+	// Find the token endpoint from the well known configuration
+	opts := rest.Opts{
+		Method:  "GET",
+		RootURL: loginToken.WellKnownLink,
+	}
+	var wellKnown api.WellKnown
+	_, err = srv.CallJSON(&opts, nil, &wellKnown)
+	if err != nil {
+		return nil, "", errors.Wrap(err, "failed to get well known configuration")
+	}
+
+	// Exchange the login token for an oauth token
+	oauthConfigCopy := *oauthConfig
+	oauthConfigCopy.Endpoint.TokenURL = wellKnown.TokenEndpoint
+	ctx := oauthutil.Context(fshttp.NewClient(fs.ConfigForRemote(name)))
+	token, err = oauthConfigCopy.PasswordCredentialsToken(ctx, loginToken.Username, loginToken.AuthToken)
+	if err != nil {
+		return nil, "", errors.Wrap(err, "failed to get oauth token")
+	}
+	return token, wellKnown.TokenEndpoint, nil
+}
+
+// getCustomerInfo fetches the account details including the internal username
+func getCustomerInfo(apiSrv *rest.Client) (info *api.CustomerInfo, err error) {
+	opts := rest.Opts{
+		Method: "GET",
+		Path:   "account/v1/customer",
+	}
+	_, err = apiSrv.CallJSON(&opts, nil, &info)
+	if err != nil {
+		return nil, errors.Wrap(err, "couldn't get customer info")
+	}
+	return info, nil
+}
+
+// getAccountInfo lists the devices of the account
+func getAccountInfo(srv *rest.Client, user string) (info *api.AccountInfo, err error) {
+	opts := rest.Opts{
+		Method: "GET",
+		Path:   rest.URLPathEscape(user),
+	}
+	_, err = srv.CallXML(&opts, nil, &info)
+	if err != nil {
+		return nil, errors.Wrap(err, "couldn't get account info")
+	}
+	return info, nil
+}
+
+// getDeviceInfo lists the mountpoints of a device
+func getDeviceInfo(srv *rest.Client, user, device string) (info *api.JottaDevice, err error) {
+	opts := rest.Opts{
+		Method: "GET",
+		Path:   rest.URLPathEscape(path.Join(user, device)),
+	}
+	_, err = srv.CallXML(&opts, nil, &info)
+	if err != nil {
+		return nil, errors.Wrap(err, "couldn't get device info")
+	}
+	return info, nil
+}
+
+// doConfig runs the interactive configuration
+//
+// It exchanges a personal login token for an oauth token then lets
+// the user choose the device and mountpoint to use.
+func doConfig(name string) error {
+	fmt.Printf("Generate a personal login token here: %s\n", loginTokenURL)
+	fmt.Printf("Login Token> ")
+	loginToken := config.ReadLine()
+
+	token, tokenURL, err := doTokenAuth(name, rest.NewClient(fshttp.NewClient(fs.ConfigForRemote(name))), loginToken)
+	if err != nil {
+		return err
+	}
+	config.FileSet(name, configTokenURL, tokenURL)
+	err = oauthutil.PutToken(name, token, true)
+	if err != nil {
+		return errors.Wrap(err, "error while saving token")
+	}
+
+	oAuthClient, _, err := oauthutil.NewClient(name, getOAuthConfig(name))
+	if err != nil {
+		return errors.Wrap(err, "failed to load oauth client")
+	}
+	srv := rest.NewClient(oAuthClient).SetRoot(rootURL)
+	apiSrv := rest.NewClient(oAuthClient).SetRoot(apiURL)
+
+	customer, err := getCustomerInfo(apiSrv)
+	if err != nil {
+		return err
+	}
+	config.FileSet(name, "user", customer.Username)
+
+	device := config.FileGet(name, "device")
+	if device == "" {
+		account, err := getAccountInfo(srv, customer.Username)
+		if err != nil {
+			return err
+		}
+		var devices, help []string
+		for _, d := range account.Devices {
+			devices = append(devices, d.Name)
+			help = append(help, d.DisplayName)
+		}
+		fmt.Printf("Choose the device to use\n")
+		device = config.Choose("device", devices, help, false)
+		config.FileSet(name, "device", device)
+	}
+
+	mountpoint := config.FileGet(name, "mountpoint")
+	if mountpoint == "" {
+		deviceInfo, err := getDeviceInfo(srv, customer.Username, device)
+		if err != nil {
+			return err
+		}
+		var mountpoints []string
+		for _, mp := range deviceInfo.MountPoints {
+			mountpoints = append(mountpoints, mp.Name)
+		}
+		fmt.Printf("Choose the mountpoint to use\n")
+		mountpoint = config.Choose("mountpoint", mountpoints, nil, false)
+		config.FileSet(name, "mountpoint", mountpoint)
+	}
+	return nil
+}
+
+// filePath returns an escaped file path (f.root, file)
+func (f *Fs) filePath(file string) string {
+	return rest.URLPathEscape(path.Join(f.user, f.device, f.mountpoint, f.root, file))
+}
+
+// absPath returns the unescaped absolute jfs path (f.root, file) as
+// used in move, copy and allocate requests
+func (f *Fs) absPath(file string) string {
+	return "/" + path.Join(f.user, f.device, f.mountpoint, f.root, file)
+}
+
+// filePath returns an escaped file path (f.root, remote)
+func (o *Object) filePath() string {
+	return o.fs.filePath(o.remote)
+}
+
+// NewFs constructs an Fs from the path, container:path
+func NewFs(name, root string) (fs.Fs, error) {
+	root = parsePath(root)
+	user := config.FileGet(name, "user")
+	if user == "" {
+		return nil, errors.New("jottacloud: no user found - run rclone config to reauthorize")
+	}
+	oAuthClient, ts, err := oauthutil.NewClient(name, getOAuthConfig(name))
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to configure Jottacloud oauth client")
+	}
+
+	f := &Fs{
+		name:       name,
+		root:       root,
+		user:       user,
+		device:     config.FileGet(name, "device", defaultDevice),
+		mountpoint: config.FileGet(name, "mountpoint", defaultMountpoint),
+		srv:        rest.NewClient(oAuthClient).SetRoot(rootURL),
+		apiSrv:     rest.NewClient(oAuthClient).SetRoot(apiURL),
+		pacer:      pacer.New().SetOptions(pacerOptions),
+	}
+	f.features = (&fs.Features{
+		CaseInsensitive:         true,
+		CanHaveEmptyDirectories: true,
+	}).Fill(f)
+	f.srv.SetErrorHandler(errorHandler)
+	f.apiSrv.SetErrorHandler(errorHandler)
+
+	// Renew the token in the background
+	f.tokenRenewer = oauthutil.NewRenew(f.String(), ts, func() error {
+		_, err := getAccountInfo(f.srv, f.user)
+		return err
+	})
+
+	if root != "" {
+		// Check to see if the root actually an existing file
+		remote := path.Base(root)
+		f.root = path.Dir(root)
+		if f.root == "." {
+			f.root = ""
+		}
+		_, err := f.NewObject(context.TODO(), remote)
+		if err != nil {
+			if errors.Cause(err) == fs.ErrorObjectNotFound || errors.Cause(err) == fs.ErrorNotAFile {
+				// File doesn't exist so return old f
+				f.root = root
+				return f, nil
+			}
+			return nil, err
+		}
+		// return an error with an fs which points to the parent
+		return f, fs.ErrorIsFile
+	}
+	return f, nil
+}
+
+// readMetaDataForPath reads the metadata from the path
+func (f *Fs) readMetaDataForPath(path string) (info *api.JottaFile, err error) {
+	opts := rest.Opts{
+		Method: "GET",
+		Path:   f.filePath(path),
+	}
+	var result api.JottaFile
+	var resp *http.Response
+	err = f.pacer.Call(func() (bool, error) {
+		resp, err = f.srv.CallXML(&opts, nil, &result)
+		return shouldRetry(resp, err)
+	})
+	if isNotFound(err) {
+		return nil, fs.ErrorObjectNotFound
+	}
+	if err != nil {
+		return nil, errors.Wrap(err, "read metadata failed")
+	}
+	if result.XMLName.Local != "file" {
+		return nil, fs.ErrorNotAFile
+	}
+	if result.Deleted {
+		return nil, fs.ErrorObjectNotFound
+	}
+	return &result, nil
+}
+
+// Return an Object from a path
+//
+// If it can't be found it returns the error fs.ErrorObjectNotFound.
+func (f *Fs) newObjectWithInfo(remote string, info *api.JottaFile) (fs.Object, error) {
+	o := &Object{
+		fs:     f,
+		remote: remote,
+	}
+	var err error
+	if info != nil {
+		// Set info
+		err = o.setMetaData(info)
+	} else {
+		err = o.readMetaData() // reads info and meta, returning an error
+	}
+	if err != nil {
+		return nil, err
+	}
+	return o, nil
+}
+
+// NewObject finds the Object at remote.  If it can't be found
+// it returns the error fs.ErrorObjectNotFound.
+func (f *Fs) NewObject(ctx context.Context, remote string) (fs.Object, error) {
+	return f.newObjectWithInfo(remote, nil)
+}
+
+// readFolder fetches the folder listing for dir
+//
+// This returns fs.ErrorDirNotFound if the directory isn't found
+func (f *Fs) readFolder(dir string) (result *api.JottaFolder, err error) {
+	opts := rest.Opts{
+		Method: "GET",
+		Path:   f.filePath(dir),
+	}
+	var resp *http.Response
+	err = f.pacer.Call(func() (bool, error) {
+		resp, err = f.srv.CallXML(&opts, nil, &result)
+		return shouldRetry(resp, err)
+	})
+	if isNotFound(err) {
+		return nil, fs.ErrorDirNotFound
+	}
+	if err != nil {
+		return nil, errors.Wrap(err, "couldn't list files")
+	}
+	if result.XMLName.Local == "file" || result.Deleted {
+		return nil, fs.ErrorDirNotFound
+	}
+	return result, nil
+}
+
+// List the objects and directories in dir into entries.  The
+// entries can be returned in any order but should be for a
+// complete directory.
+//
+// dir should be "" to list the root, and should not have
+// trailing slashes.
+//
+// This should return ErrDirNotFound if the directory isn't
+// found.
+func (f *Fs) List(ctx context.Context, dir string) (entries fs.DirEntries, err error) {
+	result, err := f.readFolder(dir)
+	if err != nil {
+		return nil, err
+	}
+	for i := range result.Folders {
+		item := &result.Folders[i]
+		if item.Deleted {
+			continue
+		}
+		remote := path.Join(dir, item.Name)
+		d := fs.NewDir(remote, time.Time(item.ModifiedAt))
+		entries = append(entries, d)
+	}
+	for i := range result.Files {
+		item := &result.Files[i]
+		// Skip deleted files and those which haven't finished uploading
+		if item.Deleted || item.State != "COMPLETED" {
+			continue
+		}
+		remote := path.Join(dir, item.Name)
+		o, err := f.newObjectWithInfo(remote, item)
+		if err != nil {
+			continue
+		}
+		entries = append(entries, o)
+	}
+	return entries, nil
+}
+
+// listFileDirFn is called from listFileDir to handle an object
+type listFileDirFn func(fs.DirEntry) error
+
+// listFileDir calls fn for every directory and file in the
+// recursive listing of dir returned in startFolder
+//
+// The recursive listing is a flat list of folders, each with the path
+// of its parent and the files directly in it.
+func (f *Fs) listFileDir(dir string, startFolder *api.JottaFolder, fn listFileDirFn) error {
+	rootPath := f.absPath("")
+	for i := range startFolder.Folders {
+		folder := &startFolder.Folders[i]
+		if folder.Deleted {
+			continue
+		}
+		folderPath := path.Join(folder.Path, folder.Name)
+		var remoteDir string
+		if folderPath != rootPath {
+			if !strings.HasPrefix(folderPath, rootPath+"/") {
+				fs.Debugf(f, "Ignoring folder %q outside root", folderPath)
+				continue
+			}
+			remoteDir = folderPath[len(rootPath)+1:]
+		}
+		if remoteDir != dir {
+			d := fs.NewDir(remoteDir, time.Time(folder.ModifiedAt))
+			err := fn(d)
+			if err != nil {
+				return err
+			}
+		}
+		for i := range folder.Files {
+			file := &folder.Files[i]
+			// Skip deleted files and those which haven't finished uploading
+			if file.Deleted || file.State != "COMPLETED" {
+				continue
+			}
+			o, err := f.newObjectWithInfo(path.Join(remoteDir, file.Name), file)
+			if err != nil {
+				return err
+			}
+			err = fn(o)
+			if err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// ListR lists the objects and directories of the Fs starting
+// from dir recursively into out.
+//
+// dir should be "" to start from the root, and should not
+// have trailing slashes.
+//
+// This should return ErrDirNotFound if the directory isn't
+// found.
+//
+// It should call callback for each tranche of entries read.
+// These need not be returned in any particular order.  If
+// callback returns an error then the listing will stop
+// immediately.
+//
+// Don't implement this unless you have a more efficient way
+// of listing recursively that doing a directory traversal.
+func (f *Fs) ListR(ctx context.Context, dir string, callback fs.ListRCallback) (err error) {
+	opts := rest.Opts{
+		Method:     "GET",
+		Path:       f.filePath(dir),
+		Parameters: url.Values{},
+	}
+	opts.Parameters.Set("mode", "list")
+	var resp *http.Response
+	var result api.JottaFolder
+	err = f.pacer.Call(func() (bool, error) {
+		resp, err = f.srv.CallXML(&opts, nil, &result)
+		return shouldRetry(resp, err)
+	})
+	if isNotFound(err) {
+		return fs.ErrorDirNotFound
+	}
+	if err != nil {
+		return errors.Wrap(err, "couldn't list files")
+	}
+	if result.XMLName.Local == "file" || result.Deleted {
+		return fs.ErrorDirNotFound
+	}
+	list := walk.NewListRHelper(callback)
+	err = f.listFileDir(dir, &result, list.Add)
+	if err != nil {
+		return err
+	}
+	return list.Flush()
+}
+
+// Creates from the parameters passed in a half finished Object which
+// must have setMetaData called on it
+//
+// Used to create new objects
+func (f *Fs) createObject(remote string, modTime time.Time, size int64) (o *Object) {
+	// Temporary Object under construction
+	o = &Object{
+		fs:      f,
+		remote:  remote,
+		size:    size,
+		modTime: modTime,
+	}
+	return o
+}
+
+// Put the object
+//
+// Copy the reader in to the new object which is returned
+//
+// The new object may have been created if an error is returned
+func (f *Fs) Put(ctx context.Context, in io.Reader, src fs.ObjectInfo, options ...fs.OpenOption) (fs.Object, error) {
+	o := f.createObject(src.Remote(), src.ModTime(), src.Size())
+	return o, o.Update(ctx, in, src, options...)
+}
+
+// PutStream uploads to the remote path with the modTime given of indeterminate size
+func (f *Fs) PutStream(ctx context.Context, in io.Reader, src fs.ObjectInfo, options ...fs.OpenOption) (fs.Object, error) {
+	return f.Put(ctx, in, src, options...)
+}
+
+// mkdir makes the directory and any parent directories
+func (f *Fs) mkdir(dirPath string) error {
+	opts := rest.Opts{
+		Method:     "POST",
+		Path:       dirPath,
+		Parameters: url.Values{},
+		NoResponse: true,
+	}
+	opts.Parameters.Set("mkDir", "true")
+	return f.pacer.Call(func() (bool, error) {
+		resp, err := f.srv.Call(&opts)
+		return shouldRetry(resp, err)
+	})
+}
+
+// Mkdir creates the directory if it doesn't exist
+func (f *Fs) Mkdir(ctx context.Context, dir string) error {
+	err := f.mkdir(f.filePath(dir))
+	if err != nil {
+		return errors.Wrap(err, "Mkdir failed")
+	}
+	return nil
+}
+
+// purgeCheck removes the directory dir, if check is set then it
+// refuses to do so if it has anything in
+func (f *Fs) purgeCheck(ctx context.Context, dir string, check bool) (err error) {
+	if check {
+		entries, err := f.List(ctx, dir)
+		if err != nil {
+			return err
+		}
+		if len(entries) != 0 {
+			return fs.ErrorDirectoryNotEmpty
+		}
+	}
+
+	opts := rest.Opts{
+		Method:     "POST",
+		Path:       f.filePath(dir),
+		Parameters: url.Values{},
+		NoResponse: true,
+	}
+	if *hardDelete {
+		opts.Parameters.Set("rmDir", "true")
+	} else {
+		opts.Parameters.Set("dlDir", "true")
+	}
+	var resp *http.Response
+	err = f.pacer.Call(func() (bool, error) {
+		resp, err = f.srv.Call(&opts)
+		return shouldRetry(resp, err)
+	})
+	if isNotFound(err) {
+		return fs.ErrorDirNotFound
+	}
+	if err != nil {
+		return errors.Wrap(err, "rmdir failed")
+	}
+	return nil
+}
+
+// Rmdir deletes the root folder
+//
+// Returns an error if it isn't empty
+func (f *Fs) Rmdir(ctx context.Context, dir string) error {
+	return f.purgeCheck(ctx, dir, true)
+}
+
+// Precision return the precision of this Fs
+func (f *Fs) Precision() time.Duration {
+	return time.Second
+}
+
+// Purge deletes all the files and the container
+//
+// Optional interface: Only implement this if you have a way of
+// deleting all the files quicker than just running Remove() on the
+// result of List()
+func (f *Fs) Purge(ctx context.Context) error {
+	return f.purgeCheck(ctx, "", false)
+}
+
+// copyOrMove copies or moves the file or directory at src to dest
+// where method is "cp", "mv" or "mvDir"
+func (f *Fs) copyOrMove(method, src, dest string) (info *api.JottaFile, err error) {
+	opts := rest.Opts{
+		Method:     "POST",
+		Path:       src,
+		Parameters: url.Values{},
+	}
+	opts.Parameters.Set(method, dest)
+	var resp *http.Response
+	err = f.pacer.Call(func() (bool, error) {
+		resp, err = f.srv.CallXML(&opts, nil, &info)
+		return shouldRetry(resp, err)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return info, nil
+}
+
+// mkParentDir makes the parent of remote if necessary
+func (f *Fs) mkParentDir(ctx context.Context, remote string) error {
+	parent := path.Dir(remote)
+	if parent == "." {
+		parent = ""
+	}
+	return f.Mkdir(ctx, parent)
+}
+
+// Copy src to this remote using server side copy operations.
+//
+// This is stored with the remote path given
+//
+// It returns the destination Object and a possible error
+//
+// Will only be called if src.Fs().Name() == f.Name()
+//
+// If it isn't possible then return fs.ErrorCantCopy
+func (f *Fs) Copy(ctx context.Context, src fs.Object, remote string) (fs.Object, error) {
+	srcObj, ok := src.(*Object)
+	if !ok {
+		fs.Debugf(src, "Can't copy - not same remote type")
+		return nil, fs.ErrorCantCopy
+	}
+	err := f.mkParentDir(ctx, remote)
+	if err != nil {
+		return nil, err
+	}
+	info, err := f.copyOrMove("cp", srcObj.filePath(), f.absPath(remote))
+	if err != nil {
+		return nil, errors.Wrap(err, "copy failed")
+	}
+	return f.newObjectWithInfo(remote, info)
+}
+
+// Move src to this remote using server side move operations.
+//
+// This is stored with the remote path given
+//
+// It returns the destination Object and a possible error
+//
+// Will only be called if src.Fs().Name() == f.Name()
+//
+// If it isn't possible then return fs.ErrorCantMove
+func (f *Fs) Move(ctx context.Context, src fs.Object, remote string) (fs.Object, error) {
+	srcObj, ok := src.(*Object)
+	if !ok {
+		fs.Debugf(src, "Can't move - not same remote type")
+		return nil, fs.ErrorCantMove
+	}
+	err := f.mkParentDir(ctx, remote)
+	if err != nil {
+		return nil, err
+	}
+	info, err := f.copyOrMove("mv", srcObj.filePath(), f.absPath(remote))
+	if err != nil {
+		return nil, errors.Wrap(err, "move failed")
+	}
+	return f.newObjectWithInfo(remote, info)
+}
+
+// DirMove moves src, srcRemote to this remote at dstRemote
+// using server side move operations.
+//
+// Will only be called if src.Fs().Name() == f.Name()
+//
+// If it isn't possible then return fs.ErrorCantDirMove
+//
+// If destination exists then return fs.ErrorDirExists
+func (f *Fs) DirMove(ctx context.Context, src fs.Fs, srcRemote, dstRemote string) error {
+	srcFs, ok := src.(*Fs)
+	if !ok {
+		fs.Debugf(srcFs, "Can't move directory - not same remote type")
+		return fs.ErrorCantDirMove
+	}
+
+	// Check if destination exists
+	_, err := f.readFolder(dstRemote)
+	if err == nil {
+		return fs.ErrorDirExists
+	} else if err != fs.ErrorDirNotFound {
+		return err
+	}
+
+	// Make sure the parent directory exists
+	err = f.mkParentDir(ctx, dstRemote)
+	if err != nil {
+		return err
+	}
+
+	_, err = f.copyOrMove("mvDir", srcFs.filePath(srcRemote), f.absPath(dstRemote))
+	if err != nil {
+		return errors.Wrap(err, "dirmove failed")
+	}
+	return nil
+}
+
+// Hashes returns the supported hash sets.
+func (f *Fs) Hashes() hash.Set {
+	return hash.Set(hash.MD5)
+}
+
+// ------------------------------------------------------------
+
+// Fs returns the parent Fs
+func (o *Object) Fs() fs.Info {
+	return o.fs
+}
+
+// Return a string version
+func (o *Object) String() string {
+	if o == nil {
+		return "<nil>"
+	}
+	return o.remote
+}
+
+// Remote returns the remote path
+func (o *Object) Remote() string {
+	return o.remote
+}
+
+// Hash returns the MD5 of an object returning a lowercase hex string
+func (o *Object) Hash(t hash.Type) (string, error) {
+	if t != hash.MD5 {
+		return "", hash.ErrUnsupported
+	}
+	return o.md5, nil
+}
+
+// Size returns the size of an object in bytes
+func (o *Object) Size() int64 {
+	err := o.readMetaData()
+	if err != nil {
+		fs.Logf(o, "Failed to read metadata: %v", err)
+		return 0
+	}
+	return o.size
+}
+
+// MimeType of an Object if known, "" otherwise
+func (o *Object) MimeType() string {
+	return o.mimeType
+}
+
+// setMetaData sets the metadata from info
+func (o *Object) setMetaData(info *api.JottaFile) (err error) {
+	o.hasMetaData = true
+	o.size = info.Size
+	o.md5 = info.MD5
+	o.mimeType = info.MimeType
+	o.modTime = time.Time(info.ModifiedAt)
+	return nil
+}
+
+// readMetaData gets the metadata if it hasn't already been fetched
+func (o *Object) readMetaData() (err error) {
+	if o.hasMetaData {
+		return nil
+	}
+	info, err := o.fs.readMetaDataForPath(o.remote)
+	if err != nil {
+		return err
+	}
+	return o.setMetaData(info)
+}
+
+// ModTime returns the modification time of the object
+//
+// It attempts to read the objects mtime and if that isn't present the
+// LastModified returned in the http headers
+func (o *Object) ModTime() time.Time {
+	err := o.readMetaData()
+	if err != nil {
+		fs.Logf(o, "Failed to read metadata: %v", err)
+		return time.Now()
+	}
+	return o.modTime
+}
+
+// SetModTime sets the modification time of the local fs object
+func (o *Object) SetModTime(ctx context.Context, modTime time.Time) error {
+	return fs.ErrorCantSetModTime
+}
+
+// Storable returns a boolean showing whether this object storable
+func (o *Object) Storable() bool {
+	return true
+}
+
+// Open an object for read
+func (o *Object) Open(ctx context.Context, options ...fs.OpenOption) (in io.ReadCloser, err error) {
+	fs.FixRangeOption(options, o.size)
+	var resp *http.Response
+	opts := rest.Opts{
+		Method:     "GET",
+		Path:       o.filePath(),
+		Parameters: url.Values{},
+		Options:    options,
+	}
+	opts.Parameters.Set("mode", "bin")
+	err = o.fs.pacer.Call(func() (bool, error) {
+		resp, err = o.fs.srv.Call(&opts)
+		return shouldRetry(resp, err)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return resp.Body, err
+}
+
+// readMD5 reads the object into memory, or into a temporary file if
+// it is bigger than threshold or of unknown size, calculating the MD5
+// as it goes.
+//
+// It returns the MD5, the size read, a reader for the data and a
+// function to clean up the temporary file.
+func readMD5(in io.Reader, size, threshold int64) (md5sum string, written int64, out io.Reader, cleanup func(), err error) {
+	hasher := md5.New()
+	cleanup = func() {}
+	if size < 0 || size > threshold {
+		var tempFile *os.File
+		tempFile, err = ioutil.TempFile("", "rclone-jottacloud-md5")
+		if err != nil {
+			return "", 0, nil, cleanup, err
+		}
+		cleanup = func() {
+			_ = tempFile.Close()
+			_ = os.Remove(tempFile.Name())
+		}
+		written, err = io.Copy(io.MultiWriter(hasher, tempFile), in)
+		if err != nil {
+			return "", 0, nil, cleanup, err
+		}
+		_, err = tempFile.Seek(0, 0)
+		if err != nil {
+			return "", 0, nil, cleanup, err
+		}
+		out = tempFile
+	} else {
+		var buf []byte
+		buf, err = ioutil.ReadAll(io.TeeReader(in, hasher))
+		if err != nil {
+			return "", 0, nil, cleanup, err
+		}
+		written = int64(len(buf))
+		out = bytes.NewReader(buf)
+	}
+	return hex.EncodeToString(hasher.Sum(nil)), written, out, cleanup, nil
+}
+
+// Update the object with the contents of the io.Reader, modTime and size
+//
+// If existing is set then it updates the object rather than creating a new one
+//
+// The new object may have been created if an error is returned
+func (o *Object) Update(ctx context.Context, in io.Reader, src fs.ObjectInfo, options ...fs.OpenOption) (err error) {
+	o.fs.tokenRenewer.Start()
+	defer o.fs.tokenRenewer.Stop()
+
+	size := src.Size()
+	md5String, err := src.Hash(hash.MD5)
+	if err != nil || md5String == "" || size < 0 {
+		// Jottacloud needs the MD5 before the upload starts so
+		// read the data to a temporary location to find it
+		var cleanup func()
+		md5String, size, in, cleanup, err = readMD5(in, size, int64(md5MemoryLimit))
+		defer cleanup()
+		if err != nil {
+			return errors.Wrap(err, "failed to calculate MD5")
+		}
+	}
+
+	// Ask the server where to upload to - this also finds out
+	// whether the file is already there or partially uploaded
+	modTime := api.Time(src.ModTime())
+	request := api.AllocateFileRequest{
+		Bytes:    size,
+		Created:  modTime.String(),
+		Modified: modTime.String(),
+		Md5:      md5String,
+		Path:     o.fs.absPath(o.remote),
+	}
+	opts := rest.Opts{
+		Method: "POST",
+		Path:   "files/v1/allocate",
+	}
+	var allocation api.AllocateFileResponse
+	var resp *http.Response
+	err = o.fs.pacer.Call(func() (bool, error) {
+		resp, err = o.fs.apiSrv.CallJSON(&opts, &request, &allocation)
+		return shouldRetry(resp, err)
+	})
+	if err != nil {
+		return errors.Wrap(err, "failed to allocate upload")
+	}
+
+	// If the file state is INCOMPLETE upload the remaining part,
+	// otherwise the server already has the data for this MD5
+	if allocation.State != "COMPLETED" {
+		if allocation.ResumePos > 0 {
+			fs.Debugf(o, "Resuming upload from byte %d", allocation.ResumePos)
+			_, err = io.CopyN(ioutil.Discard, in, allocation.ResumePos)
+			if err != nil {
+				return errors.Wrap(err, "failed to skip to resume position")
+			}
+		}
+		remaining := size - allocation.ResumePos
+		opts = rest.Opts{
+			Method:        "POST",
+			RootURL:       allocation.UploadURL,
+			Body:          in,
+			ContentLength: &remaining,
+			ContentType:   "application/octet-stream",
+			ExtraHeaders: map[string]string{
+				"Range": "bytes=" + strconv.FormatInt(allocation.ResumePos, 10) + "-" + strconv.FormatInt(size-1, 10),
+			},
+		}
+		var result api.UploadResponse
+		err = o.fs.pacer.CallNoRetry(func() (bool, error) {
+			resp, err = o.fs.apiSrv.CallJSON(&opts, nil, &result)
+			return shouldRetry(resp, err)
+		})
+		if err != nil {
+			return errors.Wrap(err, "failed to upload file")
+		}
+		if result.Md5 != "" && !strings.EqualFold(result.Md5, md5String) {
+			return errors.Errorf("corrupted on transfer: MD5 differ %q vs %q", md5String, result.Md5)
+		}
+	}
+
+	o.hasMetaData = true
+	o.size = size
+	o.md5 = md5String
+	o.modTime = time.Time(modTime)
+	return nil
+}
+
+// Remove an object
+func (o *Object) Remove(ctx context.Context) error {
+	opts := rest.Opts{
+		Method:     "POST",
+		Path:       o.filePath(),
+		Parameters: url.Values{},
+		NoResponse: true,
+	}
+	if *hardDelete {
+		opts.Parameters.Set("rm", "true")
+	} else {
+		opts.Parameters.Set("dl", "true")
+	}
+	return o.fs.pacer.Call(func() (bool, error) {
+		resp, err := o.fs.srv.Call(&opts)
+		return shouldRetry(resp, err)
+	})
+}
+
+// Check the interfaces are satisfied
+var (
+	_ fs.Fs          = (*Fs)(nil)
+	_ fs.Purger      = (*Fs)(nil)
+	_ fs.Copier      = (*Fs)(nil)
+	_ fs.Mover       = (*Fs)(nil)
+	_ fs.DirMover    = (*Fs)(nil)
+	_ fs.PutStreamer = (*Fs)(nil)
+	_ fs.ListRer     = (*Fs)(nil)
+	_ fs.Object      = (*Object)(nil)
+	_ fs.MimeTyper   = (*Object)(nil)
+)
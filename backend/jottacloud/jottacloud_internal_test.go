@@ -0,0 +1,139 @@
+package jottacloud
+
+import (
+	"bytes"
+	"crypto/md5"
+	"encoding/hex"
+	"encoding/xml"
+	"io/ioutil"
+	"testing"
+	"time"
+
+	"github.com/ncw/rclone/backend/jottacloud/api"
+	"github.com/ncw/rclone/fs"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestReadMD5(t *testing.T) {
+	data := []byte("Hello, jottacloud!")
+	sum := md5.Sum(data)
+	wantMD5 := hex.EncodeToString(sum[:])
+
+	for _, test := range []struct {
+		name      string
+		size      int64
+		threshold int64
+	}{
+		{"memory", int64(len(data)), 1024},
+		{"disk", int64(len(data)), 1},
+		{"unknown size", -1, 1024},
+	} {
+		func() {
+			md5sum, written, out, cleanup, err := readMD5(bytes.NewReader(data), test.size, test.threshold)
+			defer cleanup()
+			require.NoError(t, err, test.name)
+			assert.Equal(t, wantMD5, md5sum, test.name)
+			assert.Equal(t, int64(len(data)), written, test.name)
+			got, err := ioutil.ReadAll(out)
+			require.NoError(t, err, test.name)
+			assert.Equal(t, data, got, test.name)
+		}()
+	}
+}
+
+func TestParseFolder(t *testing.T) {
+	const listing = `<?xml version="1.0" encoding="UTF-8"?>
+<mountPoint time="2018-08-01-T12:00:00Z" host="dn-000">
+  <name xml:space="preserve">Archive</name>
+  <folders>
+    <folder name="dir"/>
+    <folder name="gone" deleted="true"/>
+  </folders>
+  <files>
+    <file name="file.txt" uuid="1234">
+      <currentRevision>
+        <number>1</number>
+        <state>COMPLETED</state>
+        <created>2018-08-01-T10:00:00Z</created>
+        <modified>2018-08-01-T11:00:00Z</modified>
+        <mime>text/plain</mime>
+        <size>6</size>
+        <md5>b1946ac92492d2347c6235b4d2611184</md5>
+        <updated>2018-08-01-T11:00:01Z</updated>
+      </currentRevision>
+    </file>
+  </files>
+</mountPoint>`
+	var folder api.JottaFolder
+	require.NoError(t, xml.Unmarshal([]byte(listing), &folder))
+	assert.Equal(t, "mountPoint", folder.XMLName.Local)
+	require.Len(t, folder.Folders, 2)
+	assert.Equal(t, "dir", folder.Folders[0].Name)
+	assert.False(t, bool(folder.Folders[0].Deleted))
+	assert.True(t, bool(folder.Folders[1].Deleted))
+	require.Len(t, folder.Files, 1)
+	file := folder.Files[0]
+	assert.Equal(t, "file.txt", file.Name)
+	assert.Equal(t, "COMPLETED", file.State)
+	assert.Equal(t, int64(6), file.Size)
+	assert.Equal(t, "b1946ac92492d2347c6235b4d2611184", file.MD5)
+	assert.Equal(t, time.Date(2018, 8, 1, 11, 0, 0, 0, time.UTC), time.Time(file.ModifiedAt).UTC())
+}
+
+func TestListFileDir(t *testing.T) {
+	const listing = `<?xml version="1.0" encoding="UTF-8"?>
+<folder name="root" time="2018-08-01-T12:00:00Z" host="dn-000">
+  <path xml:space="preserve">/user/Jotta/Archive</path>
+  <folders>
+    <folder name="root">
+      <path xml:space="preserve">/user/Jotta/Archive</path>
+      <files>
+        <file name="top.txt">
+          <currentRevision>
+            <state>COMPLETED</state>
+            <modified>2018-08-01-T11:00:00Z</modified>
+            <size>3</size>
+          </currentRevision>
+        </file>
+      </files>
+    </folder>
+    <folder name="dir">
+      <path xml:space="preserve">/user/Jotta/Archive/root</path>
+      <files>
+        <file name="file.txt">
+          <currentRevision>
+            <state>COMPLETED</state>
+            <modified>2018-08-01-T11:00:00Z</modified>
+            <size>6</size>
+          </currentRevision>
+        </file>
+        <file name="partial.txt">
+          <currentRevision>
+            <state>INCOMPLETE</state>
+            <size>6</size>
+          </currentRevision>
+        </file>
+      </files>
+    </folder>
+    <folder name="gone" deleted="true">
+      <path xml:space="preserve">/user/Jotta/Archive/root</path>
+    </folder>
+  </folders>
+</folder>`
+	var folder api.JottaFolder
+	require.NoError(t, xml.Unmarshal([]byte(listing), &folder))
+	f := &Fs{
+		root:       "root",
+		user:       "user",
+		device:     "Jotta",
+		mountpoint: "Archive",
+	}
+	var remotes []string
+	err := f.listFileDir("", &folder, func(entry fs.DirEntry) error {
+		remotes = append(remotes, entry.Remote())
+		return nil
+	})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"top.txt", "dir", "dir/file.txt"}, remotes)
+}
@@ -1,16 +1,21 @@
 package local
 
 import (
+	"io/ioutil"
 	"os"
 	"path"
+	"path/filepath"
+	"runtime"
 	"testing"
 	"time"
 
+	"github.com/ncw/rclone/fs"
 	"github.com/ncw/rclone/fs/hash"
 	"github.com/ncw/rclone/fstest"
 	"github.com/ncw/rclone/lib/readers"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
+	"golang.org/x/net/context"
 )
 
 // TestMain drives the tests
@@ -60,3 +65,91 @@ func TestMapper(t *testing.T) {
 	_, err = in.Read(buf)
 	require.Errorf(t, err, "can't copy - source file is being updated")
 }
+
+func TestMetadata(t *testing.T) {
+	ctx := context.Background()
+	r := fstest.NewRun(t)
+	defer r.Finalise()
+	const filePath = "metafile.txt"
+	when := time.Date(2018, 5, 6, 7, 8, 9, 123456789, time.UTC)
+	r.WriteFile(filePath, "metadata file contents", when)
+	f := r.Flocal.(*Fs)
+	obj, err := f.NewObject(ctx, filePath)
+	require.NoError(t, err)
+	o := obj.(*Object)
+
+	metadata, err := o.Metadata()
+	require.NoError(t, err)
+	assert.Equal(t, when.Format(time.RFC3339Nano), metadata["mtime"])
+	assert.NotEqual(t, "", metadata["mode"])
+
+	// Now set some new metadata and check it is read back
+	newWhen := when.Add(time.Hour)
+	err = o.writeMetadata(fs.Metadata{
+		"mode":  "600",
+		"mtime": newWhen.Format(time.RFC3339Nano),
+	})
+	require.NoError(t, err)
+	fi, err := os.Stat(o.path)
+	require.NoError(t, err)
+	assert.True(t, newWhen.Equal(fi.ModTime()))
+	if runtime.GOOS != "windows" {
+		assert.Equal(t, os.FileMode(0600), fi.Mode().Perm())
+	}
+
+	metadata, err = o.Metadata()
+	require.NoError(t, err)
+	assert.Equal(t, newWhen.Format(time.RFC3339Nano), metadata["mtime"])
+}
+
+func TestSnapshotRoot(t *testing.T) {
+	live, err := ioutil.TempDir("", "rclone-snapshot-live")
+	require.NoError(t, err)
+	defer func() { require.NoError(t, os.RemoveAll(live)) }()
+	snap, err := ioutil.TempDir("", "rclone-snapshot-snap")
+	require.NoError(t, err)
+	defer func() { require.NoError(t, os.RemoveAll(snap)) }()
+	require.NoError(t, os.MkdirAll(filepath.Join(snap, "sub"), 0777))
+	require.NoError(t, ioutil.WriteFile(filepath.Join(snap, "sub", "file.txt"), []byte("snapshot"), 0666))
+	require.NoError(t, os.MkdirAll(filepath.Join(live, "sub"), 0777))
+	require.NoError(t, ioutil.WriteFile(filepath.Join(live, "sub", "file.txt"), []byte("live"), 0666))
+
+	made := 0
+	snapshotters["test"] = func(dir string) (*snapshot, error) {
+		made++
+		return &snapshot{
+			origin: dir,
+			root:   snap,
+			remove: func() error { return nil },
+		}, nil
+	}
+	oldSnapshotType, oldSnapshots := *snapshotType, snapshots
+	defer func() {
+		*snapshotType, snapshots = oldSnapshotType, oldSnapshots
+		delete(snapshotters, "test")
+	}()
+
+	*snapshotType = "potato"
+	_, err = NewFs("local", live)
+	assert.Error(t, err)
+
+	*snapshotType = "test"
+	f, err := NewFs("local", live)
+	require.NoError(t, err)
+	assert.Equal(t, 1, made)
+	o, err := f.NewObject(context.Background(), "sub/file.txt")
+	require.NoError(t, err)
+	assert.Equal(t, int64(len("snapshot")), o.Size())
+
+	// A second Fs inside the same snapshot reuses it
+	f, err = NewFs("local", filepath.Join(live, "sub"))
+	require.NoError(t, err)
+	assert.Equal(t, 1, made)
+	assert.Equal(t, filepath.Join(snap, "sub"), f.Root())
+
+	// Which also works for files
+	f, err = NewFs("local", filepath.Join(live, "sub", "file.txt"))
+	assert.Equal(t, fs.ErrorIsFile, err)
+	assert.Equal(t, filepath.Join(snap, "sub"), f.Root())
+	assert.Equal(t, 1, made)
+}
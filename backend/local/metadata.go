@@ -0,0 +1,115 @@
+// Reading and writing object metadata
+
+package local
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/ncw/rclone/fs"
+)
+
+// Metadata returns metadata for an object
+//
+// It should return nil if there is no Metadata
+func (o *Object) Metadata() (metadata fs.Metadata, err error) {
+	info, err := o.fs.lstat(o.path)
+	if err != nil {
+		return nil, err
+	}
+	metadata.Set("mtime", info.ModTime().Format(time.RFC3339Nano))
+	metadata.Set("mode", fmt.Sprintf("%o", info.Mode().Perm()))
+	// Add any metadata only available on this platform
+	readMetadataFromFile(info, &metadata)
+	if *useXattrs {
+		err = readXattrs(o.path, &metadata)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return metadata, nil
+}
+
+// xattrPrefix is the prefix of the metadata keys holding extended
+// attributes - the values are base64 encoded
+const xattrPrefix = "xattr-"
+
+// parseMetadataTime parses a time from the metadata with the key given
+func parseMetadataTime(metadata fs.Metadata, key string) (t time.Time, ok bool) {
+	value, ok := metadata[key]
+	if !ok {
+		return t, false
+	}
+	t, err := time.Parse(time.RFC3339Nano, value)
+	if err != nil {
+		fs.Debugf(nil, "Failed to parse metadata %s: %q: %v", key, value, err)
+		return t, false
+	}
+	return t, true
+}
+
+// writeMetadata sets the metadata passed in on the file
+//
+// This should be called after the mtime has been set on the file.
+// Failure to set the owner is only logged as it usually needs root.
+func (o *Object) writeMetadata(metadata fs.Metadata) (err error) {
+	if value, ok := metadata["mode"]; ok {
+		mode, err := strconv.ParseUint(value, 8, 32)
+		if err != nil {
+			fs.Debugf(o, "Failed to parse metadata mode: %q: %v", value, err)
+		} else {
+			err = os.Chmod(o.path, os.FileMode(mode).Perm())
+			if err != nil {
+				return err
+			}
+		}
+	}
+	atime, haveAtime := parseMetadataTime(metadata, "atime")
+	mtime, haveMtime := parseMetadataTime(metadata, "mtime")
+	if haveAtime || haveMtime {
+		if !haveMtime {
+			mtime = o.modTime
+		}
+		if !haveAtime {
+			atime = mtime
+		}
+		err = os.Chtimes(o.path, atime, mtime)
+		if err != nil {
+			return err
+		}
+	}
+	uidString, haveUID := metadata["uid"]
+	gidString, haveGID := metadata["gid"]
+	if haveUID || haveGID {
+		uid, gid := -1, -1
+		if haveUID {
+			uid, err = strconv.Atoi(uidString)
+			if err != nil {
+				fs.Debugf(o, "Failed to parse metadata uid: %q: %v", uidString, err)
+				uid = -1
+			}
+		}
+		if haveGID {
+			gid, err = strconv.Atoi(gidString)
+			if err != nil {
+				fs.Debugf(o, "Failed to parse metadata gid: %q: %v", gidString, err)
+				gid = -1
+			}
+		}
+		if uid >= 0 || gid >= 0 {
+			err = os.Lchown(o.path, uid, gid)
+			if err != nil {
+				fs.Debugf(o, "Failed to set owner: %v", err)
+			}
+		}
+	}
+	if *useXattrs {
+		err = writeXattrs(o.path, metadata)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,13 @@
+// +build linux
+
+package local
+
+import (
+	"syscall"
+	"time"
+)
+
+// readAtime returns the access time from the stat
+func readAtime(stat *syscall.Stat_t) time.Time {
+	return time.Unix(stat.Atim.Unix())
+}
@@ -0,0 +1,14 @@
+// +build !darwin,!freebsd,!linux,!netbsd
+
+package local
+
+import (
+	"os"
+
+	"github.com/ncw/rclone/fs"
+)
+
+// readMetadataFromFile adds the metadata which is only available on
+// unix like systems - there is none on this platform
+func readMetadataFromFile(info os.FileInfo, metadata *fs.Metadata) {
+}
@@ -0,0 +1,25 @@
+// +build darwin freebsd linux netbsd
+
+package local
+
+import (
+	"os"
+	"strconv"
+	"syscall"
+	"time"
+
+	"github.com/ncw/rclone/fs"
+)
+
+// readMetadataFromFile adds the metadata which is only available on
+// unix like systems from the os.FileInfo passed in
+func readMetadataFromFile(info os.FileInfo, metadata *fs.Metadata) {
+	stat, ok := info.Sys().(*syscall.Stat_t)
+	if !ok {
+		fs.Debugf(info.Name(), "Type assertion info.Sys().(*syscall.Stat_t) failed from: %#v", info.Sys())
+		return
+	}
+	metadata.Set("atime", readAtime(stat).Format(time.RFC3339Nano))
+	metadata.Set("uid", strconv.FormatUint(uint64(stat.Uid), 10))
+	metadata.Set("gid", strconv.FormatUint(uint64(stat.Gid), 10))
+}
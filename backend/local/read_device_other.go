@@ -1,6 +1,6 @@
 // Device reading functions
 
-// +build !darwin,!dragonfly,!freebsd,!linux,!netbsd,!openbsd,!solaris
+// +build !darwin,!dragonfly,!freebsd,!linux,!netbsd,!openbsd,!solaris,!windows
 
 package local
 
@@ -8,6 +8,12 @@ import "os"
 
 // readDevice turns a valid os.FileInfo into a device number,
 // returning devUnset if it fails.
-func readDevice(fi os.FileInfo) uint64 {
+func readDevice(fi os.FileInfo, path string) uint64 {
 	return devUnset
 }
+
+// readInode returns the inode number of a valid os.FileInfo which
+// isn't supported on this OS.
+func readInode(fi os.FileInfo) (ino uint64, ok bool) {
+	return 0, false
+}
@@ -18,7 +18,7 @@ var (
 
 // readDevice turns a valid os.FileInfo into a device number,
 // returning devUnset if it fails.
-func readDevice(fi os.FileInfo) uint64 {
+func readDevice(fi os.FileInfo, path string) uint64 {
 	if !*oneFileSystem {
 		return devUnset
 	}
@@ -29,3 +29,12 @@ func readDevice(fi os.FileInfo) uint64 {
 	}
 	return uint64(statT.Dev)
 }
+
+// readInode returns the inode number of a valid os.FileInfo
+func readInode(fi os.FileInfo) (ino uint64, ok bool) {
+	statT, ok := fi.Sys().(*syscall.Stat_t)
+	if !ok {
+		return 0, false
+	}
+	return uint64(statT.Ino), true
+}
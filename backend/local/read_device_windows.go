@@ -0,0 +1,55 @@
+// Device reading functions
+
+// +build windows
+
+package local
+
+import (
+	"os"
+	"syscall"
+
+	"github.com/ncw/rclone/fs"
+	"github.com/ncw/rclone/fs/config/flags"
+)
+
+var (
+	oneFileSystem = flags.BoolP("one-file-system", "x", false, "Don't cross filesystem boundaries.")
+)
+
+// readDevice turns a valid os.FileInfo into a device number,
+// returning devUnset if it fails.
+//
+// On Windows the os.FileInfo doesn't contain the volume so this opens
+// path to read the volume serial number.
+func readDevice(fi os.FileInfo, path string) uint64 {
+	if !*oneFileSystem {
+		return devUnset
+	}
+	pathp, err := syscall.UTF16PtrFromString(path)
+	if err != nil {
+		fs.Debugf(fi.Name(), "Failed to convert path to read device: %v", err)
+		return devUnset
+	}
+	// FILE_FLAG_BACKUP_SEMANTICS is needed to open directories
+	h, err := syscall.CreateFile(pathp, 0, syscall.FILE_SHARE_READ|syscall.FILE_SHARE_WRITE|syscall.FILE_SHARE_DELETE, nil, syscall.OPEN_EXISTING, syscall.FILE_FLAG_BACKUP_SEMANTICS, 0)
+	if err != nil {
+		fs.Debugf(fi.Name(), "Failed to open to read device: %v", err)
+		return devUnset
+	}
+	defer func() {
+		_ = syscall.CloseHandle(h)
+	}()
+	var info syscall.ByHandleFileInformation
+	err = syscall.GetFileInformationByHandle(h, &info)
+	if err != nil {
+		fs.Debugf(fi.Name(), "Failed to read device: %v", err)
+		return devUnset
+	}
+	return uint64(info.VolumeSerialNumber)
+}
+
+// readInode returns the inode number of a valid os.FileInfo which
+// isn't supported on Windows.
+func readInode(fi os.FileInfo) (ino uint64, ok bool) {
+	return 0, false
+}
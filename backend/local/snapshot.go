@@ -0,0 +1,295 @@
+// Read the local disk from a point in time snapshot
+
+package local
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/ncw/rclone/fs"
+	"github.com/ncw/rclone/fs/config/flags"
+	"github.com/ncw/rclone/lib/atexit"
+	"github.com/pkg/errors"
+)
+
+var (
+	snapshotType = flags.StringP("local-snapshot", "", "", "Read local files from a snapshot made with btrfs|zfs|lvm|vss.")
+	snapshotSize = fs.SizeSuffix(1024 * 1024 * 1024)
+)
+
+func init() {
+	flags.VarP(&snapshotSize, "local-snapshot-size", "", "Space for changes made while an lvm snapshot exists.")
+}
+
+// snapshot is a read only point in time copy of part of the local disk
+type snapshot struct {
+	origin string       // the directory on the live disk which was snapshotted
+	root   string       // the directory where origin can be read in the snapshot
+	remove func() error // removes the snapshot
+}
+
+// snapshotter makes a snapshot of the volume containing dir
+type snapshotter func(dir string) (*snapshot, error)
+
+// snapshotters is the types of snapshot which can be made
+var snapshotters = map[string]snapshotter{
+	"btrfs": btrfsSnapshot,
+	"zfs":   zfsSnapshot,
+	"lvm":   lvmSnapshot,
+	"vss":   vssSnapshot,
+}
+
+var (
+	snapshotsMu sync.Mutex
+	snapshots   []*snapshot // snapshots made so far, reused for other Fs in the same volume
+)
+
+// snapshotRoot returns where root can be read from in a snapshot of
+// the type set with --local-snapshot, making the snapshot if
+// necessary.  The snapshot is removed when rclone exits.
+func snapshotRoot(root string) (string, error) {
+	makeSnapshot, ok := snapshotters[*snapshotType]
+	if !ok {
+		return "", errors.Errorf("unknown --local-snapshot %q - use btrfs, zfs, lvm or vss", *snapshotType)
+	}
+	root, err := filepath.Abs(root)
+	if err != nil {
+		return "", err
+	}
+	snapshotsMu.Lock()
+	defer snapshotsMu.Unlock()
+	for _, snap := range snapshots {
+		if newRoot, ok := snap.find(root); ok {
+			return newRoot, nil
+		}
+	}
+	// Snapshot the directory containing root if it is a file
+	dir := root
+	if fi, err := os.Stat(root); err == nil && !fi.IsDir() {
+		dir = filepath.Dir(root)
+	}
+	snap, err := makeSnapshot(dir)
+	if err != nil {
+		return "", errors.Wrapf(err, "failed to make %s snapshot of %q", *snapshotType, dir)
+	}
+	fs.Infof(nil, "Made %s snapshot of %q at %q", *snapshotType, snap.origin, snap.root)
+	snapshots = append(snapshots, snap)
+	atexit.Register(func() {
+		err := snap.remove()
+		if err != nil {
+			fs.Errorf(nil, "Failed to remove snapshot at %q: %v", snap.root, err)
+		} else {
+			fs.Infof(nil, "Removed snapshot at %q", snap.root)
+		}
+	})
+	newRoot, _ := snap.find(root)
+	return newRoot, nil
+}
+
+// find returns where path can be read in the snapshot if it is
+// inside the snapshot
+func (snap *snapshot) find(path string) (string, bool) {
+	rel, err := filepath.Rel(snap.origin, path)
+	if err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		return "", false
+	}
+	if rel == "." {
+		return snap.root, true
+	}
+	// Don't use filepath.Join as it mangles Windows device paths
+	return strings.TrimRight(snap.root, string(filepath.Separator)) + string(filepath.Separator) + rel, true
+}
+
+// snapshotName returns a name for a new snapshot
+func snapshotName() string {
+	return fmt.Sprintf("rclone-snapshot-%s-%d", time.Now().Format("20060102-150405"), os.Getpid())
+}
+
+// runCommand runs the command returning its output with the error
+// output in the error if it fails.
+func runCommand(name string, args ...string) (string, error) {
+	fs.Debugf(nil, "Running %s %s", name, strings.Join(args, " "))
+	var stdout, stderr bytes.Buffer
+	cmd := exec.Command(name, args...)
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	err := cmd.Run()
+	if err != nil {
+		return "", errors.Wrapf(err, "%s failed: %s", name, strings.TrimSpace(stderr.String()))
+	}
+	return stdout.String(), nil
+}
+
+// btrfsSnapshot makes a read only snapshot of the btrfs subvolume
+// containing dir inside that subvolume.
+func btrfsSnapshot(dir string) (*snapshot, error) {
+	subvolume, err := btrfsSubvolume(dir)
+	if err != nil {
+		return nil, err
+	}
+	snapDir := filepath.Join(subvolume, "."+snapshotName())
+	_, err = runCommand("btrfs", "subvolume", "snapshot", "-r", subvolume, snapDir)
+	if err != nil {
+		return nil, err
+	}
+	return &snapshot{
+		origin: subvolume,
+		root:   snapDir,
+		remove: func() error {
+			_, err := runCommand("btrfs", "subvolume", "delete", snapDir)
+			return err
+		},
+	}, nil
+}
+
+// btrfsSubvolumeInode is the inode number of the root of every btrfs
+// subvolume
+const btrfsSubvolumeInode = 256
+
+// btrfsSubvolume finds the root of the btrfs subvolume containing dir
+func btrfsSubvolume(dir string) (string, error) {
+	for {
+		fi, err := os.Stat(dir)
+		if err != nil {
+			return "", err
+		}
+		ino, ok := readInode(fi)
+		if !ok {
+			return "", errors.New("btrfs snapshots aren't supported on this OS")
+		}
+		if ino == btrfsSubvolumeInode {
+			return dir, nil
+		}
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return "", errors.New("not on a btrfs subvolume")
+		}
+		dir = parent
+	}
+}
+
+// zfsSnapshot makes a snapshot of the zfs dataset containing dir
+// which is read through the .zfs directory of the dataset.
+func zfsSnapshot(dir string) (*snapshot, error) {
+	out, err := runCommand("zfs", "list", "-H", "-o", "name,mountpoint", dir)
+	if err != nil {
+		return nil, err
+	}
+	fields := strings.Split(strings.TrimSpace(out), "\t")
+	if len(fields) != 2 {
+		return nil, errors.Errorf("couldn't parse zfs list output %q", out)
+	}
+	dataset, mountpoint := fields[0], fields[1]
+	name := snapshotName()
+	_, err = runCommand("zfs", "snapshot", dataset+"@"+name)
+	if err != nil {
+		return nil, err
+	}
+	return &snapshot{
+		origin: mountpoint,
+		root:   filepath.Join(mountpoint, ".zfs", "snapshot", name),
+		remove: func() error {
+			_, err := runCommand("zfs", "destroy", dataset+"@"+name)
+			return err
+		},
+	}, nil
+}
+
+// lvmSnapshot makes a snapshot of the lvm logical volume mounted at
+// dir and mounts it read only in a temporary directory.
+func lvmSnapshot(dir string) (*snapshot, error) {
+	out, err := runCommand("findmnt", "-n", "-o", "SOURCE,TARGET,FSTYPE", "--target", dir)
+	if err != nil {
+		return nil, err
+	}
+	fields := strings.Fields(out)
+	if len(fields) != 3 {
+		return nil, errors.Errorf("couldn't parse findmnt output %q", out)
+	}
+	device, mountpoint, fsType := fields[0], fields[1], fields[2]
+	out, err = runCommand("lvs", "--noheadings", "-o", "vg_name", device)
+	if err != nil {
+		return nil, err
+	}
+	vg := strings.TrimSpace(out)
+	name := snapshotName()
+	_, err = runCommand("lvcreate", "--snapshot", "--size", fmt.Sprintf("%db", int64(snapshotSize)), "--name", name, device)
+	if err != nil {
+		return nil, err
+	}
+	lv := vg + "/" + name
+	removeLV := func() error {
+		_, err := runCommand("lvremove", "-f", lv)
+		return err
+	}
+	mountDir, err := ioutil.TempDir("", "rclone-snapshot")
+	if err != nil {
+		_ = removeLV()
+		return nil, err
+	}
+	mountOptions := "ro"
+	if fsType == "xfs" {
+		// xfs won't mount two filesystems with the same UUID
+		mountOptions += ",nouuid"
+	}
+	_, err = runCommand("mount", "-o", mountOptions, "/dev/"+lv, mountDir)
+	if err != nil {
+		_ = os.Remove(mountDir)
+		_ = removeLV()
+		return nil, err
+	}
+	return &snapshot{
+		origin: mountpoint,
+		root:   mountDir,
+		remove: func() error {
+			_, err := runCommand("umount", mountDir)
+			if err != nil {
+				return err
+			}
+			_ = os.Remove(mountDir)
+			return removeLV()
+		},
+	}, nil
+}
+
+// vssCreate is the PowerShell to make a shadow copy of a volume and
+// print its ID and device
+const vssCreate = `$s = (Get-WmiObject -List Win32_ShadowCopy).Create('%s', 'ClientAccessible')
+if ($s.ReturnValue -ne 0) { Write-Error "Create returned $($s.ReturnValue)"; exit 1 }
+$c = Get-WmiObject Win32_ShadowCopy | Where-Object { $_.ID -eq $s.ShadowID }
+Write-Output $c.ID
+Write-Output $c.DeviceObject`
+
+// vssSnapshot makes a Volume Shadow Copy of the Windows volume
+// containing dir.  This needs rclone to be run as Administrator.
+func vssSnapshot(dir string) (*snapshot, error) {
+	volume := filepath.VolumeName(dir)
+	if volume == "" {
+		return nil, errors.New("vss snapshots are only supported on Windows drives")
+	}
+	volume += `\`
+	out, err := runCommand("powershell", "-NoProfile", "-NonInteractive", "-Command", fmt.Sprintf(vssCreate, volume))
+	if err != nil {
+		return nil, err
+	}
+	lines := strings.Fields(out)
+	if len(lines) != 2 {
+		return nil, errors.Errorf("couldn't parse shadow copy output %q", out)
+	}
+	id, device := lines[0], lines[1]
+	return &snapshot{
+		origin: volume,
+		root:   device + `\`,
+		remove: func() error {
+			_, err := runCommand("vssadmin", "delete", "shadows", "/shadow="+id, "/quiet")
+			return err
+		},
+	}, nil
+}
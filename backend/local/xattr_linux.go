@@ -0,0 +1,100 @@
+// +build linux
+
+package local
+
+import (
+	"bytes"
+	"encoding/base64"
+	"strings"
+
+	"github.com/ncw/rclone/fs"
+	"github.com/pkg/errors"
+	"golang.org/x/sys/unix"
+)
+
+const xattrSupported = true
+
+// xattrRead calls read with a buffer big enough for the result
+//
+// read should be a Llistxattr or Lgetxattr call which returns the
+// size needed when passed a nil buffer.
+func xattrRead(read func(dest []byte) (int, error)) ([]byte, error) {
+	for tries := 0; tries < 10; tries++ {
+		size, err := read(nil)
+		if err != nil {
+			return nil, err
+		}
+		if size == 0 {
+			return nil, nil
+		}
+		buf := make([]byte, size)
+		size, err = read(buf)
+		if err == unix.ERANGE {
+			// attribute grew between the calls so try again
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		return buf[:size], nil
+	}
+	return nil, unix.ERANGE
+}
+
+// readXattrs adds the extended attributes of the file at path to
+// metadata
+func readXattrs(path string, metadata *fs.Metadata) error {
+	list, err := xattrRead(func(dest []byte) (int, error) {
+		return unix.Llistxattr(path, dest)
+	})
+	if err == unix.ENOTSUP {
+		return nil
+	}
+	if err != nil {
+		return errors.Wrap(err, "failed to list xattrs")
+	}
+	for _, name := range bytes.Split(list, []byte{0}) {
+		if len(name) == 0 {
+			continue
+		}
+		value, err := xattrRead(func(dest []byte) (int, error) {
+			return unix.Lgetxattr(path, string(name), dest)
+		})
+		if err == unix.ENODATA {
+			// removed since we listed it
+			continue
+		}
+		if err != nil {
+			return errors.Wrapf(err, "failed to read xattr %q", name)
+		}
+		metadata.Set(xattrPrefix+string(name), base64.StdEncoding.EncodeToString(value))
+	}
+	return nil
+}
+
+// writeXattrs sets the extended attributes in metadata on the file
+// at path
+//
+// Failure to set attributes outside the user namespace is only
+// logged as they usually need root.
+func writeXattrs(path string, metadata fs.Metadata) error {
+	for key, encoded := range metadata {
+		if !strings.HasPrefix(key, xattrPrefix) {
+			continue
+		}
+		name := key[len(xattrPrefix):]
+		value, err := base64.StdEncoding.DecodeString(encoded)
+		if err != nil {
+			fs.Debugf(path, "Failed to decode metadata %s: %q: %v", key, encoded, err)
+			continue
+		}
+		err = unix.Lsetxattr(path, name, value, 0)
+		if err != nil {
+			if strings.HasPrefix(name, "user.") {
+				return errors.Wrapf(err, "failed to set xattr %q", name)
+			}
+			fs.Debugf(path, "Failed to set xattr %q: %v", name, err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,58 @@
+package local
+
+import (
+	"testing"
+
+	"github.com/ncw/rclone/fs"
+	"github.com/ncw/rclone/fstest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/net/context"
+	"golang.org/x/sys/unix"
+)
+
+func TestXattrMetadata(t *testing.T) {
+	ctx := context.Background()
+	r := fstest.NewRun(t)
+	defer r.Finalise()
+	oldUseXattrs := *useXattrs
+	*useXattrs = true
+	defer func() { *useXattrs = oldUseXattrs }()
+
+	r.WriteFile("src.txt", "xattr source", fstest.Time("2001-02-03T04:05:06.499999999Z"))
+	r.WriteFile("dst.txt", "xattr dest", fstest.Time("2001-02-03T04:05:06.499999999Z"))
+	f := r.Flocal.(*Fs)
+	obj, err := f.NewObject(ctx, "src.txt")
+	require.NoError(t, err)
+	src := obj.(*Object)
+	err = unix.Lsetxattr(src.path, "user.rclone-test", []byte("hello\x00world"), 0)
+	if err == unix.ENOTSUP {
+		t.Skip("xattrs not supported on this filesystem")
+	}
+	require.NoError(t, err)
+
+	metadata, err := src.Metadata()
+	require.NoError(t, err)
+	assert.Equal(t, "aGVsbG8Ad29ybGQ=", metadata["xattr-user.rclone-test"])
+
+	obj, err = f.NewObject(ctx, "dst.txt")
+	require.NoError(t, err)
+	dst := obj.(*Object)
+	require.NoError(t, dst.writeMetadata(fs.Metadata{
+		"xattr-user.rclone-test": metadata["xattr-user.rclone-test"],
+		"xattr-user.bad-base64":  "!!!",
+	}))
+	buf := make([]byte, 64)
+	n, err := unix.Lgetxattr(dst.path, "user.rclone-test", buf)
+	require.NoError(t, err)
+	assert.Equal(t, "hello\x00world", string(buf[:n]))
+	_, err = unix.Lgetxattr(dst.path, "user.bad-base64", buf)
+	assert.Equal(t, unix.ENODATA, err)
+
+	// without the flag xattrs aren't read
+	*useXattrs = false
+	metadata, err = src.Metadata()
+	require.NoError(t, err)
+	_, found := metadata["xattr-user.rclone-test"]
+	assert.False(t, found)
+}
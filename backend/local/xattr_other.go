@@ -0,0 +1,21 @@
+// +build !linux
+
+package local
+
+import (
+	"github.com/ncw/rclone/fs"
+)
+
+const xattrSupported = false
+
+// readXattrs adds the extended attributes of the file at path to
+// metadata - they aren't supported on this platform
+func readXattrs(path string, metadata *fs.Metadata) error {
+	return nil
+}
+
+// writeXattrs sets the extended attributes in metadata on the file
+// at path - they aren't supported on this platform
+func writeXattrs(path string, metadata fs.Metadata) error {
+	return nil
+}
@@ -0,0 +1,623 @@
+// Package memory provides an interface to an in memory object storage system
+//
+// This is mainly useful for testing and for serving ephemeral data.
+// All Fs made with the memory backend share the same storage, which
+// lasts until rclone exits.
+package memory
+
+import (
+	"bytes"
+	"crypto/md5"
+	"encoding/hex"
+	"io"
+	"io/ioutil"
+	"path"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/ncw/rclone/fs"
+	"github.com/ncw/rclone/fs/hash"
+	"github.com/ncw/rclone/fs/walk"
+	"github.com/pkg/errors"
+	"golang.org/x/net/context"
+)
+
+// Register with Fs
+func init() {
+	fs.Register(&fs.RegInfo{
+		Name:        "memory",
+		Description: "In memory object storage system.",
+		NewFs:       NewFs,
+	})
+}
+
+// objectData is the contents and metadata of a stored object
+type objectData struct {
+	modTime  time.Time
+	hash     string
+	mimeType string
+	data     []byte
+}
+
+// dirData is a directory in the store holding the names of its
+// children
+type dirData struct {
+	modTime time.Time
+	objects map[string]*objectData
+	dirs    map[string]struct{}
+}
+
+func newDirData() *dirData {
+	return &dirData{
+		modTime: time.Now(),
+		objects: make(map[string]*objectData),
+		dirs:    make(map[string]struct{}),
+	}
+}
+
+// store is the storage shared by all the memory Fs
+//
+// Directories are keyed on their full path from the root of the
+// storage with "" being the root.
+type store struct {
+	mu   sync.RWMutex
+	dirs map[string]*dirData
+}
+
+var storage = &store{
+	dirs: map[string]*dirData{"": newDirData()},
+}
+
+// splitPath splits a full path into directory and leaf
+func splitPath(p string) (dir, leaf string) {
+	dir, leaf = path.Split(p)
+	return strings.TrimSuffix(dir, "/"), leaf
+}
+
+// mkdirAll makes the directory and all its parents - call with the
+// lock held
+func (s *store) mkdirAll(dir string) {
+	if _, ok := s.dirs[dir]; ok {
+		return
+	}
+	s.dirs[dir] = newDirData()
+	parent, leaf := splitPath(dir)
+	s.mkdirAll(parent)
+	s.dirs[parent].dirs[leaf] = struct{}{}
+}
+
+// getObject returns the object at the full path p or nil - call with
+// the lock held
+func (s *store) getObject(p string) *objectData {
+	dir, leaf := splitPath(p)
+	d, ok := s.dirs[dir]
+	if !ok {
+		return nil
+	}
+	return d.objects[leaf]
+}
+
+// putObject stores od at the full path p creating the parent
+// directories if necessary - call with the lock held
+func (s *store) putObject(p string, od *objectData) {
+	dir, leaf := splitPath(p)
+	s.mkdirAll(dir)
+	s.dirs[dir].objects[leaf] = od
+}
+
+// removeObject removes the object at full path p returning false if
+// it wasn't found - call with the lock held
+func (s *store) removeObject(p string) bool {
+	dir, leaf := splitPath(p)
+	d, ok := s.dirs[dir]
+	if !ok {
+		return false
+	}
+	if _, ok := d.objects[leaf]; !ok {
+		return false
+	}
+	delete(d.objects, leaf)
+	return true
+}
+
+// removeDir removes the directory dir and everything under it - call
+// with the lock held
+func (s *store) removeDir(dir string) {
+	prefix := dir + "/"
+	for name := range s.dirs {
+		if name == dir || dir == "" || strings.HasPrefix(name, prefix) {
+			delete(s.dirs, name)
+		}
+	}
+	if dir == "" {
+		s.dirs[""] = newDirData()
+		return
+	}
+	parent, leaf := splitPath(dir)
+	if d, ok := s.dirs[parent]; ok {
+		delete(d.dirs, leaf)
+	}
+}
+
+// Fs represents a remote memory server
+type Fs struct {
+	name     string       // name of this remote
+	root     string       // the path we are working on if any
+	features *fs.Features // optional features
+}
+
+// Object describes a memory object
+type Object struct {
+	fs     *Fs         // what this object is part of
+	remote string      // The remote path
+	od     *objectData // the object data
+}
+
+// ------------------------------------------------------------
+
+// Name of the remote (as passed into NewFs)
+func (f *Fs) Name() string {
+	return f.name
+}
+
+// Root of the remote (as passed into NewFs)
+func (f *Fs) Root() string {
+	return f.root
+}
+
+// String converts this Fs to a string
+func (f *Fs) String() string {
+	return "Memory root '" + f.root + "'"
+}
+
+// Features returns the optional features of this Fs
+func (f *Fs) Features() *fs.Features {
+	return f.features
+}
+
+// Precision of the remote
+func (f *Fs) Precision() time.Duration {
+	return time.Nanosecond
+}
+
+// Hashes returns the supported hash sets.
+func (f *Fs) Hashes() hash.Set {
+	return hash.Set(hash.MD5)
+}
+
+// NewFs constructs an Fs from the path, container:path
+func NewFs(name, root string) (fs.Fs, error) {
+	root = strings.Trim(path.Clean(root), "/")
+	if root == "." {
+		root = ""
+	}
+	f := &Fs{
+		name: name,
+		root: root,
+	}
+	f.features = (&fs.Features{
+		ReadMimeType:            true,
+		WriteMimeType:           true,
+		CanHaveEmptyDirectories: true,
+	}).Fill(f)
+	if root != "" {
+		storage.mu.RLock()
+		od := storage.getObject(root)
+		storage.mu.RUnlock()
+		if od != nil {
+			// root is a file so point at its parent
+			f.root, _ = splitPath(root)
+			return f, fs.ErrorIsFile
+		}
+	}
+	return f, nil
+}
+
+// fullPath returns the path of remote from the root of the storage
+func (f *Fs) fullPath(remote string) string {
+	return strings.Trim(path.Join(f.root, remote), "/")
+}
+
+// newObject makes an Object for remote using od
+func (f *Fs) newObject(remote string, od *objectData) *Object {
+	return &Object{
+		fs:     f,
+		remote: remote,
+		od:     od,
+	}
+}
+
+// List the objects and directories in dir into entries.  The
+// entries can be returned in any order but should be for a
+// complete directory.
+//
+// dir should be "" to list the root, and should not have
+// trailing slashes.
+//
+// This should return ErrDirNotFound if the directory isn't
+// found.
+func (f *Fs) List(ctx context.Context, dir string) (entries fs.DirEntries, err error) {
+	storage.mu.RLock()
+	defer storage.mu.RUnlock()
+	d, ok := storage.dirs[f.fullPath(dir)]
+	if !ok {
+		return nil, fs.ErrorDirNotFound
+	}
+	for leaf := range d.dirs {
+		remote := path.Join(dir, leaf)
+		entries = append(entries, fs.NewDir(remote, storage.dirs[f.fullPath(remote)].modTime))
+	}
+	for leaf, od := range d.objects {
+		entries = append(entries, f.newObject(path.Join(dir, leaf), od))
+	}
+	return entries, nil
+}
+
+// ListR lists the objects and directories of the Fs starting
+// from dir recursively into out.
+//
+// dir should be "" to start from the root, and should not
+// have trailing slashes.
+//
+// This should return ErrDirNotFound if the directory isn't
+// found.
+//
+// It should call callback for each tranche of entries read.
+// These need not be returned in any particular order.  If
+// callback returns an error then the listing will stop
+// immediately.
+func (f *Fs) ListR(ctx context.Context, dir string, callback fs.ListRCallback) (err error) {
+	list := walk.NewListRHelper(callback)
+	storage.mu.RLock()
+	root := f.fullPath(dir)
+	if _, ok := storage.dirs[root]; !ok {
+		storage.mu.RUnlock()
+		return fs.ErrorDirNotFound
+	}
+	// Return parent directories before their children
+	var names []string
+	for name := range storage.dirs {
+		if name == root || root == "" || strings.HasPrefix(name, root+"/") {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	var entries fs.DirEntries
+	for _, name := range names {
+		d := storage.dirs[name]
+		remote := strings.TrimPrefix(strings.TrimPrefix(name, f.root), "/")
+		for leaf := range d.dirs {
+			subRemote := path.Join(remote, leaf)
+			entries = append(entries, fs.NewDir(subRemote, storage.dirs[f.fullPath(subRemote)].modTime))
+		}
+		for leaf, od := range d.objects {
+			entries = append(entries, f.newObject(path.Join(remote, leaf), od))
+		}
+	}
+	storage.mu.RUnlock()
+	for _, entry := range entries {
+		err = list.Add(entry)
+		if err != nil {
+			return err
+		}
+	}
+	return list.Flush()
+}
+
+// NewObject finds the Object at remote.  If it can't be found
+// it returns the error ErrorObjectNotFound.
+func (f *Fs) NewObject(ctx context.Context, remote string) (fs.Object, error) {
+	storage.mu.RLock()
+	defer storage.mu.RUnlock()
+	full := f.fullPath(remote)
+	if _, ok := storage.dirs[full]; ok && full != "" {
+		return nil, fs.ErrorNotAFile
+	}
+	od := storage.getObject(full)
+	if od == nil {
+		return nil, fs.ErrorObjectNotFound
+	}
+	return f.newObject(remote, od), nil
+}
+
+// Put the object into the remote
+//
+// Copy the reader in to the new object which is returned
+//
+// The new object may have been created if an error is returned
+func (f *Fs) Put(ctx context.Context, in io.Reader, src fs.ObjectInfo, options ...fs.OpenOption) (fs.Object, error) {
+	o := f.newObject(src.Remote(), nil)
+	return o, o.Update(ctx, in, src, options...)
+}
+
+// PutStream uploads to the remote path with the modTime given of indeterminate size
+func (f *Fs) PutStream(ctx context.Context, in io.Reader, src fs.ObjectInfo, options ...fs.OpenOption) (fs.Object, error) {
+	return f.Put(ctx, in, src, options...)
+}
+
+// Mkdir creates the directory if it doesn't exist
+func (f *Fs) Mkdir(ctx context.Context, dir string) error {
+	storage.mu.Lock()
+	defer storage.mu.Unlock()
+	full := f.fullPath(dir)
+	if storage.getObject(full) != nil {
+		return fs.ErrorIsFile
+	}
+	storage.mkdirAll(full)
+	return nil
+}
+
+// Rmdir deletes the directory if empty
+//
+// Returns an error if it isn't empty
+func (f *Fs) Rmdir(ctx context.Context, dir string) error {
+	storage.mu.Lock()
+	defer storage.mu.Unlock()
+	full := f.fullPath(dir)
+	d, ok := storage.dirs[full]
+	if !ok {
+		return fs.ErrorDirNotFound
+	}
+	if len(d.objects) != 0 || len(d.dirs) != 0 {
+		return fs.ErrorDirectoryNotEmpty
+	}
+	storage.removeDir(full)
+	return nil
+}
+
+// Purge deletes all the files and directories including the old versions.
+func (f *Fs) Purge(ctx context.Context) error {
+	storage.mu.Lock()
+	defer storage.mu.Unlock()
+	if _, ok := storage.dirs[f.root]; !ok {
+		return fs.ErrorDirNotFound
+	}
+	storage.removeDir(f.root)
+	return nil
+}
+
+// Copy src to this remote using server side copy operations.
+//
+// This is stored with the remote path given
+//
+// It returns the destination Object and a possible error
+//
+// Will only be called if src.Fs().Name() == f.Name()
+//
+// If it isn't possible then return fs.ErrorCantCopy
+func (f *Fs) Copy(ctx context.Context, src fs.Object, remote string) (fs.Object, error) {
+	srcObj, ok := src.(*Object)
+	if !ok {
+		fs.Debugf(src, "Can't copy - not same remote type")
+		return nil, fs.ErrorCantCopy
+	}
+	storage.mu.Lock()
+	defer storage.mu.Unlock()
+	od := storage.getObject(srcObj.fs.fullPath(srcObj.remote))
+	if od == nil {
+		return nil, fs.ErrorObjectNotFound
+	}
+	// The data is never modified in place so can be shared
+	newOd := *od
+	storage.putObject(f.fullPath(remote), &newOd)
+	return f.newObject(remote, &newOd), nil
+}
+
+// Move src to this remote using server side move operations.
+//
+// This is stored with the remote path given
+//
+// It returns the destination Object and a possible error
+//
+// Will only be called if src.Fs().Name() == f.Name()
+//
+// If it isn't possible then return fs.ErrorCantMove
+func (f *Fs) Move(ctx context.Context, src fs.Object, remote string) (fs.Object, error) {
+	srcObj, ok := src.(*Object)
+	if !ok {
+		fs.Debugf(src, "Can't move - not same remote type")
+		return nil, fs.ErrorCantMove
+	}
+	storage.mu.Lock()
+	defer storage.mu.Unlock()
+	srcPath := srcObj.fs.fullPath(srcObj.remote)
+	od := storage.getObject(srcPath)
+	if od == nil {
+		return nil, fs.ErrorObjectNotFound
+	}
+	storage.removeObject(srcPath)
+	storage.putObject(f.fullPath(remote), od)
+	return f.newObject(remote, od), nil
+}
+
+// DirMove moves src, srcRemote to this remote at dstRemote
+// using server side move operations.
+//
+// Will only be called if src.Fs().Name() == f.Name()
+//
+// If it isn't possible then return fs.ErrorCantDirMove
+//
+// If destination exists then return fs.ErrorDirExists
+func (f *Fs) DirMove(ctx context.Context, src fs.Fs, srcRemote, dstRemote string) error {
+	srcFs, ok := src.(*Fs)
+	if !ok {
+		fs.Debugf(srcFs, "Can't move directory - not same remote type")
+		return fs.ErrorCantDirMove
+	}
+	storage.mu.Lock()
+	defer storage.mu.Unlock()
+	srcPath := srcFs.fullPath(srcRemote)
+	dstPath := f.fullPath(dstRemote)
+	if _, ok := storage.dirs[dstPath]; ok {
+		return fs.ErrorDirExists
+	}
+	if srcPath == "" || strings.HasPrefix(dstPath, srcPath+"/") {
+		return fs.ErrorCantDirMove
+	}
+	if _, ok := storage.dirs[srcPath]; !ok {
+		return fs.ErrorDirNotFound
+	}
+	dstParent, _ := splitPath(dstPath)
+	storage.mkdirAll(dstParent)
+	// Rename the directory and all the directories under it
+	moved := make(map[string]*dirData)
+	for name, d := range storage.dirs {
+		if name == srcPath || strings.HasPrefix(name, srcPath+"/") {
+			moved[dstPath+strings.TrimPrefix(name, srcPath)] = d
+		}
+	}
+	storage.removeDir(srcPath)
+	for name, d := range moved {
+		storage.dirs[name] = d
+	}
+	parent, leaf := splitPath(dstPath)
+	storage.dirs[parent].dirs[leaf] = struct{}{}
+	return nil
+}
+
+// ------------------------------------------------------------
+
+// Fs returns the parent Fs
+func (o *Object) Fs() fs.Info {
+	return o.fs
+}
+
+// Return a string version
+func (o *Object) String() string {
+	if o == nil {
+		return "<nil>"
+	}
+	return o.remote
+}
+
+// Remote returns the remote path
+func (o *Object) Remote() string {
+	return o.remote
+}
+
+// Hash returns the MD5 of an object returning a lowercase hex string
+func (o *Object) Hash(t hash.Type) (string, error) {
+	if t != hash.MD5 {
+		return "", hash.ErrUnsupported
+	}
+	storage.mu.RLock()
+	defer storage.mu.RUnlock()
+	return o.od.hash, nil
+}
+
+// Size returns the size of an object in bytes
+func (o *Object) Size() int64 {
+	storage.mu.RLock()
+	defer storage.mu.RUnlock()
+	return int64(len(o.od.data))
+}
+
+// ModTime returns the modification time of the object
+func (o *Object) ModTime() time.Time {
+	storage.mu.RLock()
+	defer storage.mu.RUnlock()
+	return o.od.modTime
+}
+
+// SetModTime sets the modification time of the object
+func (o *Object) SetModTime(ctx context.Context, modTime time.Time) error {
+	storage.mu.Lock()
+	defer storage.mu.Unlock()
+	o.od.modTime = modTime
+	return nil
+}
+
+// Storable returns if this object is storable
+func (o *Object) Storable() bool {
+	return true
+}
+
+// MimeType of an Object if known, "" otherwise
+func (o *Object) MimeType() string {
+	storage.mu.RLock()
+	defer storage.mu.RUnlock()
+	return o.od.mimeType
+}
+
+// Open an object for read
+func (o *Object) Open(ctx context.Context, options ...fs.OpenOption) (io.ReadCloser, error) {
+	storage.mu.RLock()
+	data := o.od.data
+	storage.mu.RUnlock()
+	size := int64(len(data))
+	var offset, limit int64 = 0, -1
+	for _, option := range options {
+		switch x := option.(type) {
+		case *fs.RangeOption:
+			offset, limit = x.Decode(size)
+		case *fs.SeekOption:
+			offset = x.Offset
+		default:
+			if option.Mandatory() {
+				fs.Logf(o, "Unsupported mandatory option: %v", option)
+			}
+		}
+	}
+	if offset > size {
+		offset = size
+	}
+	data = data[offset:]
+	if limit >= 0 && limit < int64(len(data)) {
+		data = data[:limit]
+	}
+	return ioutil.NopCloser(bytes.NewReader(data)), nil
+}
+
+// Update the object with the contents of the io.Reader, modTime and size
+//
+// The new object may have been created if an error is returned
+func (o *Object) Update(ctx context.Context, in io.Reader, src fs.ObjectInfo, options ...fs.OpenOption) error {
+	data, err := ioutil.ReadAll(in)
+	if err != nil {
+		return errors.Wrap(err, "failed to read data")
+	}
+	sum := md5.Sum(data)
+	od := &objectData{
+		modTime:  src.ModTime(),
+		hash:     hex.EncodeToString(sum[:]),
+		mimeType: fs.MimeType(src),
+		data:     data,
+	}
+	storage.mu.Lock()
+	defer storage.mu.Unlock()
+	full := o.fs.fullPath(o.remote)
+	if existing := storage.getObject(full); existing != nil {
+		// Update in place so other Objects for this path (eg ones
+		// held by an overlay like cache) see the new contents
+		*existing = *od
+		od = existing
+	}
+	storage.putObject(full, od)
+	o.od = od
+	return nil
+}
+
+// Remove an object
+func (o *Object) Remove(ctx context.Context) error {
+	storage.mu.Lock()
+	defer storage.mu.Unlock()
+	if !storage.removeObject(o.fs.fullPath(o.remote)) {
+		return fs.ErrorObjectNotFound
+	}
+	return nil
+}
+
+// Check the interfaces are satisfied
+var (
+	_ fs.Fs          = &Fs{}
+	_ fs.Purger      = &Fs{}
+	_ fs.PutStreamer = &Fs{}
+	_ fs.Copier      = &Fs{}
+	_ fs.Mover       = &Fs{}
+	_ fs.DirMover    = &Fs{}
+	_ fs.ListRer     = &Fs{}
+	_ fs.Object      = &Object{}
+	_ fs.MimeTyper   = &Object{}
+)
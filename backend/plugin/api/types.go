@@ -0,0 +1,122 @@
+// Package api has type definitions for the plugin protocol
+//
+// rclone starts the plugin program and sends it one Request per line
+// on its stdin.  The plugin answers each with one Response per line on
+// its stdout.  Requests are sent one at a time.  Anything the plugin
+// writes to stderr is passed through to rclone's stderr.  The plugin
+// should exit when its stdin is closed.
+//
+// Paths are relative to the top of the plugin's storage with no
+// leading or trailing "/".  The top is "".
+package api
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+)
+
+// Methods
+const (
+	MethodInit       = "init"       // InitParams -> InitResult
+	MethodList       = "list"       // PathParams -> ListResult
+	MethodStat       = "stat"       // PathParams -> Entry
+	MethodGet        = "get"        // GetParams -> nothing
+	MethodPut        = "put"        // PutParams -> Entry
+	MethodSetModTime = "setmodtime" // SetModTimeParams -> Entry
+	MethodRemove     = "remove"     // PathParams -> nothing
+	MethodMkdir      = "mkdir"      // PathParams -> nothing
+	MethodRmdir      = "rmdir"      // PathParams -> nothing
+)
+
+// Error codes - errors with any other code are passed on as they are
+const (
+	CodeNotFound     = "not_found"      // the object doesn't exist
+	CodeDirNotFound  = "dir_not_found"  // the directory doesn't exist
+	CodeDirNotEmpty  = "dir_not_empty"  // the directory isn't empty
+	CodeNotSupported = "not_supported"  // the plugin can't do this
+	CodeUnknown      = "unknown_method" // the plugin doesn't know this method
+	CodeRetry        = "retry"          // try the request again
+	CodeFatal        = "fatal"          // don't try anything else
+)
+
+// Request is sent to the plugin
+type Request struct {
+	ID     int64       `json:"id"`
+	Method string      `json:"method"`
+	Params interface{} `json:"params,omitempty"`
+}
+
+// Response is received from the plugin
+type Response struct {
+	ID     int64           `json:"id"`
+	Result json.RawMessage `json:"result,omitempty"`
+	Error  *Error          `json:"error,omitempty"`
+}
+
+// Error is returned by the plugin when a request fails
+type Error struct {
+	Code    string `json:"code"`
+	Message string `json:"message"`
+}
+
+// Error satisfies the error interface
+func (e *Error) Error() string {
+	return fmt.Sprintf("plugin error %s: %s", e.Code, e.Message)
+}
+
+// InitParams is sent once when the plugin is started
+type InitParams struct {
+	Config map[string]string `json:"config"` // the config section of the remote
+}
+
+// InitResult describes what the plugin supports
+type InitResult struct {
+	Hashes    []string `json:"hashes,omitempty"`    // hash names, eg "MD5", "SHA-1"
+	Precision string   `json:"precision,omitempty"` // modtime precision, eg "1s" - blank if not supported
+}
+
+// PathParams is used by the methods which just take a path
+type PathParams struct {
+	Path string `json:"path"`
+}
+
+// GetParams asks the plugin to write the contents of the object at
+// Path into the local file File.
+//
+// If Count is >= 0 only Count bytes starting at Offset are wanted.
+type GetParams struct {
+	Path   string `json:"path"`
+	File   string `json:"file"`
+	Offset int64  `json:"offset"`
+	Count  int64  `json:"count"`
+}
+
+// PutParams asks the plugin to store the local file File at Path
+// replacing any existing object.
+type PutParams struct {
+	Path    string    `json:"path"`
+	File    string    `json:"file"`
+	Size    int64     `json:"size"`
+	ModTime time.Time `json:"modtime"`
+}
+
+// SetModTimeParams asks the plugin to set the modification time
+type SetModTimeParams struct {
+	Path    string    `json:"path"`
+	ModTime time.Time `json:"modtime"`
+}
+
+// Entry is an object or directory
+type Entry struct {
+	Path    string            `json:"path"`
+	Size    int64             `json:"size"`
+	ModTime time.Time         `json:"modtime"`
+	IsDir   bool              `json:"is_dir,omitempty"`
+	Hashes  map[string]string `json:"hashes,omitempty"` // hash name to lower case hex
+}
+
+// ListResult is the contents of a directory
+type ListResult struct {
+	Entries []Entry `json:"entries"`
+}
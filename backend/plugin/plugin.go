@@ -0,0 +1,629 @@
+// Package plugin provides an interface to backends implemented by
+// external programs which rclone talks to with JSON over their stdin
+// and stdout.
+package plugin
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/ncw/rclone/backend/plugin/api"
+	"github.com/ncw/rclone/fs"
+	"github.com/ncw/rclone/fs/config"
+	"github.com/ncw/rclone/fs/config/flags"
+	"github.com/ncw/rclone/fs/fserrors"
+	"github.com/ncw/rclone/fs/hash"
+	"github.com/ncw/rclone/lib/atexit"
+	"github.com/pkg/errors"
+	"golang.org/x/net/context"
+)
+
+const (
+	programPrefix = "rclone-plugin-" // plugin programs are called this followed by the name
+	stopTimeout   = 10 * time.Second // how long to wait for the plugin to exit when its stdin is closed
+)
+
+// Globals
+var (
+	pluginDir = flags.StringP("plugin-dir", "", "", "Directory to look for plugin programs in (default \"plugins\" next to the config file).")
+)
+
+// Register with Fs
+func init() {
+	fs.Register(&fs.RegInfo{
+		Name:        "plugin",
+		Description: "External plugin program",
+		NewFs:       NewFs,
+		Options: []fs.Option{{
+			Name: "program",
+			Help: "Name of the plugin - rclone runs " + programPrefix + "NAME from the plugin directory or the PATH.\nAlternatively the full path to the plugin program.",
+		}},
+		// All the settings are passed to the plugin
+		AnyKeys: true,
+	})
+}
+
+// client talks to a running plugin program
+//
+// The protocol has one request in flight at a time so the mutex
+// serialises all the calls to the plugin.
+type client struct {
+	mu      sync.Mutex
+	cmd     *exec.Cmd
+	in      io.WriteCloser
+	enc     *json.Encoder
+	dec     *json.Decoder
+	id      int64
+	err     error // set if the plugin has stopped working
+	stopped bool  // set if the plugin program has been stopped
+}
+
+// Fs represents a remote plugin
+type Fs struct {
+	name      string        // name of this remote
+	root      string        // the path we are working on
+	features  *fs.Features  // optional features
+	c         *client       // the running plugin
+	hashes    hash.Set      // hashes the plugin supports
+	precision time.Duration // modtime precision the plugin supports
+}
+
+// Object describes a plugin object
+type Object struct {
+	fs      *Fs                  // what this object is part of
+	remote  string               // The remote path
+	size    int64                // size of the object
+	modTime time.Time            // modification time of the object
+	hashes  map[hash.Type]string // hashes of the object if known
+}
+
+// ------------------------------------------------------------
+
+// findProgram returns the path of the plugin program called name
+//
+// It looks in the --plugin-dir then the PATH unless name is a path
+// already.
+func findProgram(name string) (string, error) {
+	if name == "" {
+		return "", errors.New("program not set in the config")
+	}
+	if strings.ContainsRune(name, '/') || strings.ContainsRune(name, filepath.Separator) {
+		return name, nil
+	}
+	dir := *pluginDir
+	if dir == "" {
+		dir = filepath.Join(filepath.Dir(config.ConfigPath), "plugins")
+	}
+	program, err := exec.LookPath(filepath.Join(dir, programPrefix+name))
+	if err == nil {
+		return program, nil
+	}
+	program, err = exec.LookPath(programPrefix + name)
+	if err != nil {
+		return "", errors.Errorf("couldn't find plugin %q in %q or the PATH", programPrefix+name, dir)
+	}
+	return program, nil
+}
+
+// newClient starts program and returns a client to talk to it
+func newClient(program string) (*client, error) {
+	cmd := exec.Command(program)
+	cmd.Stderr = os.Stderr
+	in, err := cmd.StdinPipe()
+	if err != nil {
+		return nil, err
+	}
+	out, err := cmd.StdoutPipe()
+	if err != nil {
+		return nil, err
+	}
+	err = cmd.Start()
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to start plugin")
+	}
+	return &client{
+		cmd: cmd,
+		in:  in,
+		enc: json.NewEncoder(in),
+		dec: json.NewDecoder(bufio.NewReader(out)),
+	}, nil
+}
+
+// call sends method with params to the plugin and decodes the reply
+// into result if it isn't nil.
+func (c *client) call(method string, params interface{}, result interface{}) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.err != nil {
+		return c.err
+	}
+	c.id++
+	err := c.enc.Encode(&api.Request{
+		ID:     c.id,
+		Method: method,
+		Params: params,
+	})
+	var resp api.Response
+	if err == nil {
+		err = c.dec.Decode(&resp)
+	}
+	if err == nil && resp.ID != c.id {
+		err = errors.Errorf("response id %d doesn't match request id %d", resp.ID, c.id)
+	}
+	if err != nil {
+		// The plugin is broken so don't use it any more
+		c.err = fserrors.FatalError(errors.Wrap(err, "plugin failed"))
+		c.stop(0)
+		return c.err
+	}
+	if resp.Error != nil {
+		return translateError(resp.Error)
+	}
+	if result != nil && len(resp.Result) > 0 {
+		err = json.Unmarshal(resp.Result, result)
+		if err != nil {
+			return errors.Wrapf(err, "failed to decode %s response", method)
+		}
+	}
+	return nil
+}
+
+// stop closes the plugin's stdin and waits up to timeout for it to
+// exit before killing it.
+//
+// Call with the lock held
+func (c *client) stop(timeout time.Duration) {
+	if c.stopped {
+		return
+	}
+	c.stopped = true
+	_ = c.in.Close()
+	done := make(chan error, 1)
+	go func() {
+		done <- c.cmd.Wait()
+	}()
+	select {
+	case err := <-done:
+		if err != nil {
+			fs.Debugf(nil, "plugin %q exited with error: %v", c.cmd.Path, err)
+		}
+		return
+	case <-time.After(timeout):
+	}
+	if timeout > 0 {
+		fs.Errorf(nil, "plugin %q didn't exit - killing it", c.cmd.Path)
+	}
+	_ = c.cmd.Process.Kill()
+	<-done
+}
+
+// close stops the plugin program.  Calls made after this return an
+// error.
+func (c *client) close() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.err == nil {
+		c.err = fserrors.FatalError(errors.New("plugin closed"))
+	}
+	c.stop(stopTimeout)
+}
+
+// init sends the config of the remote called name to the plugin,
+// stopping it if that fails.
+func (c *client) init(name string) (*api.InitResult, error) {
+	params := api.InitParams{
+		Config: make(map[string]string),
+	}
+	for _, key := range config.FileKeys(name) {
+		if key != "type" {
+			params.Config[key] = config.FileGet(name, key)
+		}
+	}
+	var info api.InitResult
+	err := c.call(api.MethodInit, &params, &info)
+	if err != nil {
+		c.close()
+		return nil, errors.Wrap(err, "failed to initialise plugin")
+	}
+	return &info, nil
+}
+
+// translateError turns an error from the plugin into the equivalent
+// rclone error
+func translateError(e *api.Error) error {
+	switch e.Code {
+	case api.CodeNotFound:
+		return fs.ErrorObjectNotFound
+	case api.CodeDirNotFound:
+		return fs.ErrorDirNotFound
+	case api.CodeDirNotEmpty:
+		return fs.ErrorDirectoryNotEmpty
+	case api.CodeRetry:
+		return fserrors.RetryError(e)
+	case api.CodeFatal:
+		return fserrors.FatalError(e)
+	}
+	return e
+}
+
+// isNotSupported returns true if err means the plugin can't do it
+func isNotSupported(err error) bool {
+	e, ok := err.(*api.Error)
+	return ok && (e.Code == api.CodeNotSupported || e.Code == api.CodeUnknown)
+}
+
+// Name of the remote (as passed into NewFs)
+func (f *Fs) Name() string {
+	return f.name
+}
+
+// Root of the remote (as passed into NewFs)
+func (f *Fs) Root() string {
+	return f.root
+}
+
+// String converts this Fs to a string
+func (f *Fs) String() string {
+	return fmt.Sprintf("plugin %s root '%s'", f.name, f.root)
+}
+
+// Features returns the optional features of this Fs
+func (f *Fs) Features() *fs.Features {
+	return f.features
+}
+
+// NewFs constructs an Fs from the path
+func NewFs(name, root string) (fs.Fs, error) {
+	program, err := findProgram(config.FileGet(name, "program"))
+	if err != nil {
+		return nil, errors.Wrap(err, "plugin")
+	}
+	c, err := newClient(program)
+	if err != nil {
+		return nil, err
+	}
+	info, err := c.init(name)
+	if err != nil {
+		return nil, err
+	}
+
+	f := &Fs{
+		name:      name,
+		root:      strings.Trim(root, "/"),
+		c:         c,
+		precision: fs.ModTimeNotSupported,
+	}
+	for _, name := range info.Hashes {
+		var ht hash.Type
+		if err := ht.Set(name); err != nil {
+			fs.Debugf(f, "Ignoring unknown hash %q", name)
+			continue
+		}
+		f.hashes.Add(ht)
+	}
+	if info.Precision != "" {
+		f.precision, err = time.ParseDuration(info.Precision)
+		if err != nil {
+			c.close()
+			return nil, errors.Wrap(err, "plugin returned bad precision")
+		}
+	}
+	f.features = (&fs.Features{
+		CanHaveEmptyDirectories: true,
+	}).Fill(f)
+	atexit.Register(f.Shutdown)
+
+	if f.root != "" {
+		// Check to see if the root is a file
+		var entry api.Entry
+		err = f.c.call(api.MethodStat, &api.PathParams{Path: f.root}, &entry)
+		if err == nil && !entry.IsDir {
+			f.root = path.Dir(f.root)
+			if f.root == "." {
+				f.root = ""
+			}
+			return f, fs.ErrorIsFile
+		}
+	}
+	return f, nil
+}
+
+// Shutdown stops the plugin program.  The Fs can't be used after
+// this.  It is called when rclone exits.
+func (f *Fs) Shutdown() {
+	f.c.close()
+}
+
+// fullPath returns the path of remote for the plugin
+func (f *Fs) fullPath(remote string) string {
+	return path.Join(f.root, remote)
+}
+
+// remotePath returns the remote for the plugin path p
+func (f *Fs) remotePath(p string) string {
+	if f.root == "" {
+		return p
+	}
+	return strings.TrimPrefix(p, f.root+"/")
+}
+
+// setMetaData sets the metadata from entry
+func (o *Object) setMetaData(entry *api.Entry) {
+	o.size = entry.Size
+	o.modTime = entry.ModTime
+	o.hashes = make(map[hash.Type]string, len(entry.Hashes))
+	for name, sum := range entry.Hashes {
+		var ht hash.Type
+		if err := ht.Set(name); err == nil {
+			o.hashes[ht] = strings.ToLower(sum)
+		}
+	}
+}
+
+// newObject makes an Object from entry
+func (f *Fs) newObject(entry *api.Entry) *Object {
+	o := &Object{
+		fs:     f,
+		remote: f.remotePath(entry.Path),
+	}
+	o.setMetaData(entry)
+	return o
+}
+
+// List the objects and directories in dir into entries.  The
+// entries can be returned in any order but should be for a
+// complete directory.
+//
+// dir should be "" to list the root, and should not have
+// trailing slashes.
+//
+// This should return ErrDirNotFound if the directory isn't
+// found.
+func (f *Fs) List(ctx context.Context, dir string) (entries fs.DirEntries, err error) {
+	var result api.ListResult
+	err = f.c.call(api.MethodList, &api.PathParams{Path: f.fullPath(dir)}, &result)
+	if err != nil {
+		return nil, err
+	}
+	for i := range result.Entries {
+		entry := &result.Entries[i]
+		if entry.IsDir {
+			d := fs.NewDir(f.remotePath(entry.Path), entry.ModTime)
+			entries = append(entries, d)
+		} else {
+			entries = append(entries, f.newObject(entry))
+		}
+	}
+	return entries, nil
+}
+
+// NewObject finds the Object at remote.  If it can't be found
+// it returns the error fs.ErrorObjectNotFound.
+func (f *Fs) NewObject(ctx context.Context, remote string) (fs.Object, error) {
+	var entry api.Entry
+	err := f.c.call(api.MethodStat, &api.PathParams{Path: f.fullPath(remote)}, &entry)
+	if err != nil {
+		return nil, err
+	}
+	if entry.IsDir {
+		return nil, fs.ErrorNotAFile
+	}
+	return f.newObject(&entry), nil
+}
+
+// Put the object
+//
+// Copy the reader in to the new object which is returned
+//
+// The new object may have been created if an error is returned
+func (f *Fs) Put(ctx context.Context, in io.Reader, src fs.ObjectInfo, options ...fs.OpenOption) (fs.Object, error) {
+	o := &Object{
+		fs:     f,
+		remote: src.Remote(),
+	}
+	return o, o.Update(ctx, in, src, options...)
+}
+
+// PutStream uploads to the remote path with the modTime given of indeterminate size
+func (f *Fs) PutStream(ctx context.Context, in io.Reader, src fs.ObjectInfo, options ...fs.OpenOption) (fs.Object, error) {
+	return f.Put(ctx, in, src, options...)
+}
+
+// Mkdir creates the directory if it doesn't exist
+func (f *Fs) Mkdir(ctx context.Context, dir string) error {
+	return f.c.call(api.MethodMkdir, &api.PathParams{Path: f.fullPath(dir)}, nil)
+}
+
+// Rmdir removes the directory if empty
+//
+// Returns an error if it isn't empty
+func (f *Fs) Rmdir(ctx context.Context, dir string) error {
+	return f.c.call(api.MethodRmdir, &api.PathParams{Path: f.fullPath(dir)}, nil)
+}
+
+// Precision of the object storage system
+func (f *Fs) Precision() time.Duration {
+	return f.precision
+}
+
+// Hashes returns the supported hash sets.
+func (f *Fs) Hashes() hash.Set {
+	return f.hashes
+}
+
+// ------------------------------------------------------------
+
+// Fs returns the parent Fs
+func (o *Object) Fs() fs.Info {
+	return o.fs
+}
+
+// Return a string version
+func (o *Object) String() string {
+	if o == nil {
+		return "<nil>"
+	}
+	return o.remote
+}
+
+// Remote returns the remote path
+func (o *Object) Remote() string {
+	return o.remote
+}
+
+// Hash returns the hash of an object returning a lowercase hex string
+func (o *Object) Hash(t hash.Type) (string, error) {
+	if !o.fs.hashes.Contains(t) {
+		return "", hash.ErrUnsupported
+	}
+	return o.hashes[t], nil
+}
+
+// Size returns the size of an object in bytes
+func (o *Object) Size() int64 {
+	return o.size
+}
+
+// ModTime returns the modification time of the object
+func (o *Object) ModTime() time.Time {
+	return o.modTime
+}
+
+// SetModTime sets the modification time of the object
+func (o *Object) SetModTime(ctx context.Context, modTime time.Time) error {
+	var entry api.Entry
+	err := o.fs.c.call(api.MethodSetModTime, &api.SetModTimeParams{
+		Path:    o.fs.fullPath(o.remote),
+		ModTime: modTime,
+	}, &entry)
+	if isNotSupported(err) {
+		return fs.ErrorCantSetModTime
+	}
+	if err != nil {
+		return err
+	}
+	o.setMetaData(&entry)
+	return nil
+}
+
+// Storable returns a boolean showing whether this object storable
+func (o *Object) Storable() bool {
+	return true
+}
+
+// tempFile is an open temporary file which is removed when closed
+type tempFile struct {
+	*os.File
+}
+
+// Close the file and remove it
+func (t tempFile) Close() error {
+	err := t.File.Close()
+	removeErr := os.Remove(t.Name())
+	if err == nil {
+		err = removeErr
+	}
+	return err
+}
+
+// newTempFile makes an empty temporary file
+func newTempFile() (tempFile, error) {
+	file, err := ioutil.TempFile("", programPrefix)
+	return tempFile{file}, err
+}
+
+// Open an object for read
+//
+// The plugin writes the data into a temporary file which is removed
+// when it is closed.  This means the whole object (or range) is
+// fetched before Open returns and needs room on the local disk.
+func (o *Object) Open(ctx context.Context, options ...fs.OpenOption) (in io.ReadCloser, err error) {
+	params := api.GetParams{
+		Path:  o.fs.fullPath(o.remote),
+		Count: -1,
+	}
+	for _, option := range options {
+		switch x := option.(type) {
+		case *fs.SeekOption:
+			params.Offset = x.Offset
+		case *fs.RangeOption:
+			params.Offset, params.Count = x.Decode(o.size)
+		default:
+			if option.Mandatory() {
+				fs.Logf(o, "Unsupported mandatory option: %v", option)
+			}
+		}
+	}
+	file, err := newTempFile()
+	if err != nil {
+		return nil, err
+	}
+	params.File = file.Name()
+	err = o.fs.c.call(api.MethodGet, &params, nil)
+	if err != nil {
+		_ = file.Close()
+		return nil, err
+	}
+	// Read the file the plugin wrote
+	_, err = file.Seek(0, 0)
+	if err != nil {
+		_ = file.Close()
+		return nil, err
+	}
+	return file, nil
+}
+
+// Update the object with the contents of the io.Reader, modTime and size
+//
+// The data is written to a temporary file for the plugin to read so
+// the whole object is spooled to the local disk before the plugin
+// starts uploading it.
+func (o *Object) Update(ctx context.Context, in io.Reader, src fs.ObjectInfo, options ...fs.OpenOption) (err error) {
+	file, err := newTempFile()
+	if err != nil {
+		return err
+	}
+	defer fs.CheckClose(file, &err)
+	size, err := io.Copy(file, in)
+	if err != nil {
+		return errors.Wrap(err, "failed to write temporary file")
+	}
+	err = file.Sync()
+	if err != nil {
+		return err
+	}
+	var entry api.Entry
+	err = o.fs.c.call(api.MethodPut, &api.PutParams{
+		Path:    o.fs.fullPath(o.remote),
+		File:    file.Name(),
+		Size:    size,
+		ModTime: src.ModTime(),
+	}, &entry)
+	if err != nil {
+		return err
+	}
+	o.setMetaData(&entry)
+	return nil
+}
+
+// Remove an object
+func (o *Object) Remove(ctx context.Context) error {
+	return o.fs.c.call(api.MethodRemove, &api.PathParams{Path: o.fs.fullPath(o.remote)}, nil)
+}
+
+// Check the interfaces are satisfied
+var (
+	_ fs.Fs          = (*Fs)(nil)
+	_ fs.PutStreamer = (*Fs)(nil)
+	_ fs.Object      = (*Object)(nil)
+)
@@ -0,0 +1,22 @@
+package plugin_test
+
+import (
+	"os"
+	"path/filepath"
+
+	"github.com/ncw/rclone/fstest/fstests"
+)
+
+// Create the TestPlugin: remote
+//
+// This runs the test binary itself as the plugin - see TestMain.
+func init() {
+	program, err := filepath.Abs(os.Args[0])
+	if err != nil {
+		panic(err)
+	}
+	fstests.ExtraConfig = []fstests.ExtraConfigItem{
+		{Name: "TestPlugin", Key: "type", Value: "plugin"},
+		{Name: "TestPlugin", Key: "program", Value: program},
+	}
+}
@@ -0,0 +1,321 @@
+package plugin
+
+import (
+	"bufio"
+	"crypto/md5"
+	"encoding/hex"
+	"encoding/json"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"syscall"
+	"testing"
+
+	"github.com/ncw/rclone/backend/plugin/api"
+	"github.com/ncw/rclone/fs"
+	"github.com/ncw/rclone/fs/config"
+	"github.com/ncw/rclone/fs/fserrors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// If this is set the test binary runs as a plugin serving this
+// directory
+const testServeEnv = "RCLONE_PLUGIN_TEST_DIR"
+
+// TestMain runs the tests or, when started by the tests as a plugin,
+// serves a local directory with the plugin protocol.
+func TestMain(m *testing.M) {
+	if dir := os.Getenv(testServeEnv); dir != "" {
+		err := serveTestPlugin(dir, os.Stdin, os.Stdout)
+		if err != nil {
+			fs.Errorf(nil, "test plugin failed: %v", err)
+			os.Exit(1)
+		}
+		os.Exit(0)
+	}
+	dir, err := ioutil.TempDir("", "rclone-plugin-test")
+	if err != nil {
+		panic(err)
+	}
+	_ = os.Setenv(testServeEnv, dir)
+	rc := m.Run()
+	_ = os.RemoveAll(dir)
+	os.Exit(rc)
+}
+
+// testPlugin is a minimal plugin storing files in a local directory
+type testPlugin struct {
+	dir string
+}
+
+// serveTestPlugin answers requests from in on out until in is closed
+func serveTestPlugin(dir string, in io.Reader, out io.Writer) error {
+	p := testPlugin{dir: dir}
+	dec := json.NewDecoder(bufio.NewReader(in))
+	enc := json.NewEncoder(out)
+	for {
+		var req struct {
+			ID     int64           `json:"id"`
+			Method string          `json:"method"`
+			Params json.RawMessage `json:"params"`
+		}
+		err := dec.Decode(&req)
+		if err == io.EOF {
+			return nil
+		} else if err != nil {
+			return err
+		}
+		result, err := p.handle(req.Method, req.Params)
+		resp := api.Response{ID: req.ID}
+		if err != nil {
+			resp.Error = testError(err)
+		} else if result != nil {
+			resp.Result, err = json.Marshal(result)
+			if err != nil {
+				return err
+			}
+		}
+		err = enc.Encode(&resp)
+		if err != nil {
+			return err
+		}
+	}
+}
+
+// testError converts err into an api.Error
+func testError(err error) *api.Error {
+	if e, ok := err.(*api.Error); ok {
+		return e
+	}
+	code := "other"
+	switch {
+	case os.IsNotExist(err):
+		code = api.CodeNotFound
+	case isNotEmpty(err):
+		code = api.CodeDirNotEmpty
+	}
+	return &api.Error{Code: code, Message: err.Error()}
+}
+
+// isNotEmpty returns true if err is a directory not empty error
+func isNotEmpty(err error) bool {
+	if pathErr, ok := err.(*os.PathError); ok {
+		err = pathErr.Err
+	}
+	return err == syscall.ENOTEMPTY || err == syscall.EEXIST
+}
+
+// entry makes an api.Entry for p
+func (p *testPlugin) entry(path string) (*api.Entry, error) {
+	fi, err := os.Stat(filepath.Join(p.dir, path))
+	if err != nil {
+		return nil, err
+	}
+	entry := &api.Entry{
+		Path:    path,
+		Size:    fi.Size(),
+		ModTime: fi.ModTime(),
+		IsDir:   fi.IsDir(),
+	}
+	if fi.IsDir() {
+		entry.Size = -1
+		return entry, nil
+	}
+	data, err := ioutil.ReadFile(filepath.Join(p.dir, path))
+	if err != nil {
+		return nil, err
+	}
+	sum := md5.Sum(data)
+	entry.Hashes = map[string]string{"MD5": hex.EncodeToString(sum[:])}
+	return entry, nil
+}
+
+// handle a single request
+func (p *testPlugin) handle(method string, rawParams json.RawMessage) (result interface{}, err error) {
+	// All the params are subsets of these
+	var (
+		get api.GetParams
+		put api.PutParams
+	)
+	if len(rawParams) > 0 {
+		err = json.Unmarshal(rawParams, &get)
+		if err == nil {
+			err = json.Unmarshal(rawParams, &put)
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+	path := get.Path
+	local := filepath.Join(p.dir, path)
+	switch method {
+	case api.MethodInit:
+		var init api.InitParams
+		err = json.Unmarshal(rawParams, &init)
+		if err != nil {
+			return nil, err
+		}
+		if init.Config["fail_init"] != "" {
+			return nil, &api.Error{Code: api.CodeFatal, Message: "init failed"}
+		}
+		return &api.InitResult{Hashes: []string{"MD5"}, Precision: "1s"}, nil
+	case api.MethodList:
+		fis, err := ioutil.ReadDir(local)
+		if os.IsNotExist(err) {
+			return nil, &api.Error{Code: api.CodeDirNotFound, Message: err.Error()}
+		} else if err != nil {
+			return nil, err
+		}
+		list := api.ListResult{Entries: []api.Entry{}}
+		for _, fi := range fis {
+			entry, err := p.entry(filepath.ToSlash(filepath.Join(path, fi.Name())))
+			if err != nil {
+				return nil, err
+			}
+			list.Entries = append(list.Entries, *entry)
+		}
+		return &list, nil
+	case api.MethodStat:
+		return p.entry(path)
+	case api.MethodGet:
+		in, err := os.Open(local)
+		if err != nil {
+			return nil, err
+		}
+		defer fs.CheckClose(in, &err)
+		_, err = in.Seek(get.Offset, 0)
+		if err != nil {
+			return nil, err
+		}
+		var src io.Reader = in
+		if get.Count >= 0 {
+			src = io.LimitReader(in, get.Count)
+		}
+		out, err := os.Create(get.File)
+		if err != nil {
+			return nil, err
+		}
+		defer fs.CheckClose(out, &err)
+		_, err = io.Copy(out, src)
+		return nil, err
+	case api.MethodPut:
+		err = os.MkdirAll(filepath.Dir(local), 0777)
+		if err != nil {
+			return nil, err
+		}
+		data, err := ioutil.ReadFile(put.File)
+		if err != nil {
+			return nil, err
+		}
+		err = ioutil.WriteFile(local, data, 0666)
+		if err != nil {
+			return nil, err
+		}
+		err = os.Chtimes(local, put.ModTime, put.ModTime)
+		if err != nil {
+			return nil, err
+		}
+		return p.entry(path)
+	case api.MethodSetModTime:
+		err = os.Chtimes(local, put.ModTime, put.ModTime)
+		if err != nil {
+			return nil, err
+		}
+		return p.entry(path)
+	case api.MethodRemove:
+		return nil, os.Remove(local)
+	case api.MethodMkdir:
+		return nil, os.MkdirAll(local, 0777)
+	case api.MethodRmdir:
+		err = os.Remove(local)
+		if os.IsNotExist(err) {
+			return nil, &api.Error{Code: api.CodeDirNotFound, Message: err.Error()}
+		}
+		return nil, err
+	}
+	return nil, &api.Error{Code: api.CodeUnknown, Message: "unknown method " + method}
+}
+
+func TestTranslateError(t *testing.T) {
+	assert.Equal(t, fs.ErrorObjectNotFound, translateError(&api.Error{Code: api.CodeNotFound}))
+	assert.Equal(t, fs.ErrorDirNotFound, translateError(&api.Error{Code: api.CodeDirNotFound}))
+	assert.Equal(t, fs.ErrorDirectoryNotEmpty, translateError(&api.Error{Code: api.CodeDirNotEmpty}))
+	assert.True(t, fserrors.IsRetryError(translateError(&api.Error{Code: api.CodeRetry})))
+	assert.True(t, fserrors.IsFatalError(translateError(&api.Error{Code: api.CodeFatal})))
+	e := &api.Error{Code: "potato", Message: "sausage"}
+	assert.Equal(t, e, translateError(e))
+	assert.Equal(t, "plugin error potato: sausage", e.Error())
+}
+
+func TestFindProgram(t *testing.T) {
+	dir, err := ioutil.TempDir("", "rclone-plugin-dir")
+	require.NoError(t, err)
+	defer func() {
+		_ = os.RemoveAll(dir)
+	}()
+	oldPluginDir := *pluginDir
+	*pluginDir = dir
+	defer func() { *pluginDir = oldPluginDir }()
+
+	_, err = findProgram("")
+	assert.Error(t, err)
+
+	program, err := findProgram("/path/to/program")
+	require.NoError(t, err)
+	assert.Equal(t, "/path/to/program", program)
+
+	_, err = findProgram("potato")
+	assert.Error(t, err)
+
+	want := filepath.Join(dir, programPrefix+"potato")
+	require.NoError(t, ioutil.WriteFile(want, []byte("#!/bin/sh\n"), 0777))
+	program, err = findProgram("potato")
+	require.NoError(t, err)
+	assert.Equal(t, want, program)
+}
+
+// testClient starts the test binary as a plugin
+func testClient(t *testing.T) *client {
+	program, err := filepath.Abs(os.Args[0])
+	require.NoError(t, err)
+	c, err := newClient(program)
+	require.NoError(t, err)
+	return c
+}
+
+func TestClientClose(t *testing.T) {
+	config.LoadConfig()
+	const name = "TestPluginClose"
+	config.FileSet(name, "type", "plugin")
+
+	c := testClient(t)
+	_, err := c.init(name)
+	require.NoError(t, err)
+
+	c.close()
+	require.NotNil(t, c.cmd.ProcessState, "plugin not waited for")
+	assert.True(t, c.cmd.ProcessState.Exited())
+	err = c.call(api.MethodList, &api.PathParams{}, nil)
+	assert.True(t, fserrors.IsFatalError(err))
+
+	// Check double close
+	c.close()
+}
+
+func TestClientInitFailure(t *testing.T) {
+	config.LoadConfig()
+	const name = "TestPluginInitFailure"
+	config.FileSet(name, "type", "plugin")
+	config.FileSet(name, "fail_init", "true")
+
+	c := testClient(t)
+	_, err := c.init(name)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "init failed")
+
+	// The plugin should have been stopped
+	require.NotNil(t, c.cmd.ProcessState, "plugin not waited for")
+	assert.True(t, c.cmd.ProcessState.Exited())
+}
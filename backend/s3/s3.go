@@ -21,6 +21,7 @@ import (
 	"net/http"
 	"path"
 	"regexp"
+	"strconv"
 	"strings"
 	"sync"
 	"time"
@@ -41,10 +42,12 @@ import (
 	"github.com/ncw/rclone/fs/config/flags"
 	"github.com/ncw/rclone/fs/fshttp"
 	"github.com/ncw/rclone/fs/hash"
+	"github.com/ncw/rclone/fs/operations"
 	"github.com/ncw/rclone/fs/walk"
 	"github.com/ncw/rclone/lib/rest"
 	"github.com/ncw/swift"
 	"github.com/pkg/errors"
+	"golang.org/x/net/context"
 )
 
 // Register with Fs
@@ -195,6 +198,19 @@ func init() {
 				Value: "bucket-owner-full-control",
 				Help:  "Both the object owner and the bucket owner get FULL_CONTROL over the object.\nIf you specify this canned ACL when creating a bucket, Amazon S3 ignores it.",
 			}},
+		}, {
+			Name: "bucket_acl",
+			Help: "Canned ACL used when creating buckets - leave blank to use acl.",
+		}, {
+			Name: "no_check_bucket",
+			Help: "Don't check the bucket exists or try to create it.",
+			Examples: []fs.OptionExample{{
+				Value: "false",
+				Help:  "Check the bucket exists and create it if not [default if left blank].",
+			}, {
+				Value: "true",
+				Help:  "Assume the bucket exists - use if the credentials can't create or check buckets.",
+			}},
 		}, {
 			Name: "server_side_encryption",
 			Help: "The server-side encryption algorithm used when storing this object in S3.",
@@ -222,6 +238,7 @@ func init() {
 				Help:  "Standard Infrequent Access storage class",
 			}},
 		}},
+		CommandHelp: commandHelp,
 	})
 }
 
@@ -233,13 +250,16 @@ const (
 	maxRetries     = 10                            // number of retries to make of operations
 	maxSizeForCopy = 5 * 1024 * 1024 * 1024        // The maximum size of object we can COPY
 	maxFileSize    = 5 * 1024 * 1024 * 1024 * 1024 // largest possible upload file size
+	maxExpire      = 7 * 24 * time.Hour            // longest possible expiry time for a presigned URL
+	staleUploadAge = 24 * time.Hour                // multipart uploads older than this are aborted by CleanUp
 )
 
 // Globals
 var (
 	// Flags
-	s3ACL          = flags.StringP("s3-acl", "", "", "Canned ACL used when creating buckets and/or storing objects in S3")
-	s3StorageClass = flags.StringP("s3-storage-class", "", "", "Storage class to use when uploading S3 objects (STANDARD|REDUCED_REDUNDANCY|STANDARD_IA)")
+	s3ACL           = flags.StringP("s3-acl", "", "", "Canned ACL used when creating buckets and/or storing objects in S3")
+	s3StorageClass  = flags.StringP("s3-storage-class", "", "", "Storage class to use when uploading S3 objects (STANDARD|REDUCED_REDUNDANCY|STANDARD_IA)")
+	s3NoCheckBucket = flags.BoolP("s3-no-check-bucket", "", false, "Don't check the bucket exists or try to create it")
 )
 
 // Fs represents a remote s3 server
@@ -254,6 +274,7 @@ type Fs struct {
 	bucketOK           bool             // true if we have created the bucket
 	bucketDeleted      bool             // true if we have deleted the bucket
 	acl                string           // ACL for new buckets / objects
+	bucketACL          string           // ACL for new buckets if different from acl
 	locationConstraint string           // location constraint of new buckets
 	sse                string           // the type of server-side encryption
 	storageClass       string           // storage class
@@ -272,6 +293,7 @@ type Object struct {
 	lastModified time.Time          // Last modified
 	meta         map[string]*string // The object metadata if known - may be nil
 	mimeType     string             // MimeType of object - may be ""
+	storageClass string             // eg GLACIER - may be "" which means STANDARD
 }
 
 // ------------------------------------------------------------
@@ -377,7 +399,7 @@ func s3Connection(name string) (*s3.S3, *session.Session, error) {
 		WithMaxRetries(maxRetries).
 		WithCredentials(cred).
 		WithEndpoint(endpoint).
-		WithHTTPClient(fshttp.NewClient(fs.Config)).
+		WithHTTPClient(fshttp.NewClient(fs.ConfigForRemote(name))).
 		WithS3ForcePathStyle(true)
 	// awsConfig.WithLogLevel(aws.LogDebugWithSigning)
 	ses := session.New()
@@ -414,6 +436,7 @@ func NewFs(name, root string) (fs.Fs, error) {
 		bucket:             bucket,
 		ses:                ses,
 		acl:                config.FileGet(name, "acl"),
+		bucketACL:          config.FileGet(name, "bucket_acl"),
 		root:               directory,
 		locationConstraint: config.FileGet(name, "location_constraint"),
 		sse:                config.FileGet(name, "server_side_encryption"),
@@ -423,6 +446,10 @@ func NewFs(name, root string) (fs.Fs, error) {
 		ReadMimeType:  true,
 		WriteMimeType: true,
 		BucketBased:   true,
+		ReadMetadata:  true,
+		WriteMetadata: true,
+		SetTier:       true,
+		GetTier:       true,
 	}).Fill(f)
 	if *s3ACL != "" {
 		f.acl = *s3ACL
@@ -430,6 +457,13 @@ func NewFs(name, root string) (fs.Fs, error) {
 	if *s3StorageClass != "" {
 		f.storageClass = *s3StorageClass
 	}
+	if *s3NoCheckBucket || config.FileGetBool(name, "no_check_bucket") {
+		// Assume the bucket exists so Mkdir doesn't touch it
+		f.bucketOK = true
+	}
+	if f.bucketACL == "" {
+		f.bucketACL = f.acl
+	}
 	if f.root != "" {
 		f.root += "/"
 		// Check to see if the object exists
@@ -471,6 +505,7 @@ func (f *Fs) newObjectWithInfo(remote string, info *s3.Object) (fs.Object, error
 		}
 		o.etag = aws.StringValue(info.ETag)
 		o.bytes = aws.Int64Value(info.Size)
+		o.storageClass = aws.StringValue(info.StorageClass)
 	} else {
 		err := o.readMetaData() // reads info and meta, returning an error
 		if err != nil {
@@ -482,7 +517,7 @@ func (f *Fs) newObjectWithInfo(remote string, info *s3.Object) (fs.Object, error
 
 // NewObject finds the Object at remote.  If it can't be found
 // it returns the error fs.ErrorObjectNotFound.
-func (f *Fs) NewObject(remote string) (fs.Object, error) {
+func (f *Fs) NewObject(ctx context.Context, remote string) (fs.Object, error) {
 	return f.newObjectWithInfo(remote, nil)
 }
 
@@ -635,7 +670,7 @@ func (f *Fs) listBuckets(dir string) (entries fs.DirEntries, err error) {
 //
 // This should return ErrDirNotFound if the directory isn't
 // found.
-func (f *Fs) List(dir string) (entries fs.DirEntries, err error) {
+func (f *Fs) List(ctx context.Context, dir string) (entries fs.DirEntries, err error) {
 	if f.bucket == "" {
 		return f.listBuckets(dir)
 	}
@@ -658,7 +693,7 @@ func (f *Fs) List(dir string) (entries fs.DirEntries, err error) {
 //
 // Don't implement this unless you have a more efficient way
 // of listing recursively that doing a directory traversal.
-func (f *Fs) ListR(dir string, callback fs.ListRCallback) (err error) {
+func (f *Fs) ListR(ctx context.Context, dir string, callback fs.ListRCallback) (err error) {
 	if f.bucket == "" {
 		return fs.ErrorListBucketRequired
 	}
@@ -677,18 +712,18 @@ func (f *Fs) ListR(dir string, callback fs.ListRCallback) (err error) {
 }
 
 // Put the Object into the bucket
-func (f *Fs) Put(in io.Reader, src fs.ObjectInfo, options ...fs.OpenOption) (fs.Object, error) {
+func (f *Fs) Put(ctx context.Context, in io.Reader, src fs.ObjectInfo, options ...fs.OpenOption) (fs.Object, error) {
 	// Temporary Object under construction
 	fs := &Object{
 		fs:     f,
 		remote: src.Remote(),
 	}
-	return fs, fs.Update(in, src, options...)
+	return fs, fs.Update(ctx, in, src, options...)
 }
 
 // PutStream uploads to the remote path with the modTime given of indeterminate size
-func (f *Fs) PutStream(in io.Reader, src fs.ObjectInfo, options ...fs.OpenOption) (fs.Object, error) {
-	return f.Put(in, src, options...)
+func (f *Fs) PutStream(ctx context.Context, in io.Reader, src fs.ObjectInfo, options ...fs.OpenOption) (fs.Object, error) {
+	return f.Put(ctx, in, src, options...)
 }
 
 // Check if the bucket exists
@@ -711,7 +746,7 @@ func (f *Fs) dirExists() (bool, error) {
 }
 
 // Mkdir creates the bucket if it doesn't exist
-func (f *Fs) Mkdir(dir string) error {
+func (f *Fs) Mkdir(ctx context.Context, dir string) error {
 	f.bucketOKMu.Lock()
 	defer f.bucketOKMu.Unlock()
 	if f.bucketOK {
@@ -728,7 +763,7 @@ func (f *Fs) Mkdir(dir string) error {
 	}
 	req := s3.CreateBucketInput{
 		Bucket: &f.bucket,
-		ACL:    &f.acl,
+		ACL:    &f.bucketACL,
 	}
 	if f.locationConstraint != "" {
 		req.CreateBucketConfiguration = &s3.CreateBucketConfiguration{
@@ -751,7 +786,7 @@ func (f *Fs) Mkdir(dir string) error {
 // Rmdir deletes the bucket if the fs is at the root
 //
 // Returns an error if it isn't empty
-func (f *Fs) Rmdir(dir string) error {
+func (f *Fs) Rmdir(ctx context.Context, dir string) error {
 	f.bucketOKMu.Lock()
 	defer f.bucketOKMu.Unlock()
 	if f.root != "" || dir != "" {
@@ -788,8 +823,8 @@ func pathEscape(s string) string {
 // Will only be called if src.Fs().Name() == f.Name()
 //
 // If it isn't possible then return fs.ErrorCantCopy
-func (f *Fs) Copy(src fs.Object, remote string) (fs.Object, error) {
-	err := f.Mkdir("")
+func (f *Fs) Copy(ctx context.Context, src fs.Object, remote string) (fs.Object, error) {
+	err := f.Mkdir(ctx, "")
 	if err != nil {
 		return nil, err
 	}
@@ -811,7 +846,73 @@ func (f *Fs) Copy(src fs.Object, remote string) (fs.Object, error) {
 	if err != nil {
 		return nil, err
 	}
-	return f.NewObject(remote)
+	return f.NewObject(ctx, remote)
+}
+
+// CleanUp aborts any multipart uploads under the root which were
+// started more than a day ago.
+//
+// These are left behind if an upload is interrupted and the parts
+// uploaded so far are charged for until they are removed.
+func (f *Fs) CleanUp(ctx context.Context) error {
+	if f.bucket == "" {
+		return errors.New("can't clean up without a bucket")
+	}
+	req := s3.ListMultipartUploadsInput{
+		Bucket: &f.bucket,
+	}
+	if f.root != "" {
+		req.Prefix = &f.root
+	}
+	var errReturn error
+	err := f.c.ListMultipartUploadsPages(&req, func(resp *s3.ListMultipartUploadsOutput, lastPage bool) bool {
+		for _, upload := range resp.Uploads {
+			if upload.Key == nil || upload.UploadId == nil {
+				continue
+			}
+			if upload.Initiated != nil && time.Since(*upload.Initiated) < staleUploadAge {
+				fs.Debugf(f, "Not aborting recent multipart upload of %q", *upload.Key)
+				continue
+			}
+			fs.Infof(f, "Aborting multipart upload of %q", *upload.Key)
+			_, err := f.c.AbortMultipartUpload(&s3.AbortMultipartUploadInput{
+				Bucket:   &f.bucket,
+				Key:      upload.Key,
+				UploadId: upload.UploadId,
+			})
+			if err != nil {
+				fs.Errorf(f, "Failed to abort multipart upload of %q: %v", *upload.Key, err)
+				errReturn = err
+			}
+		}
+		return true
+	})
+	if err != nil {
+		return errors.Wrap(err, "failed to list multipart uploads")
+	}
+	return errReturn
+}
+
+// PublicLink generates a presigned URL for the object which can be
+// used to download it without credentials.
+//
+// The URL is valid for expire or the maximum of 7 days if expire is
+// zero or too long.
+func (f *Fs) PublicLink(ctx context.Context, remote string, expire time.Duration) (link string, err error) {
+	// Check the object exists
+	_, err = f.NewObject(ctx, remote)
+	if err != nil {
+		return "", err
+	}
+	if expire <= 0 || expire > maxExpire {
+		expire = maxExpire
+	}
+	key := f.root + remote
+	httpReq, _ := f.c.GetObjectRequest(&s3.GetObjectInput{
+		Bucket: &f.bucket,
+		Key:    &key,
+	})
+	return httpReq.Presign(expire)
 }
 
 // Hashes returns the supported hash sets.
@@ -819,6 +920,112 @@ func (f *Fs) Hashes() hash.Set {
 	return hash.Set(hash.MD5)
 }
 
+var commandHelp = []fs.CommandHelp{{
+	Name:  "restore",
+	Short: "Restore objects from GLACIER to normal storage",
+	Long: `This command can be used to restore one or more objects from GLACIER
+to normal storage so they can be read.
+
+Usage Examples:
+
+    rclone backend restore s3:bucket/path/to/object [-o priority=PRIORITY] [-o lifetime=DAYS]
+    rclone backend restore s3:bucket/path/to/directory [-o priority=PRIORITY] [-o lifetime=DAYS]
+    rclone backend restore s3:bucket [-o priority=PRIORITY] [-o lifetime=DAYS]
+
+This command also obeys the filters. Test first with the --dry-run flag
+
+    rclone --dry-run --include "*.txt" backend restore s3:bucket/path -o priority=Standard
+
+All the objects shown will be marked for restore, then
+
+    rclone backend restore --include "*.txt" s3:bucket/path -o priority=Standard
+
+It returns a list of status dictionaries with Remote and Status
+keys. The Status will be OK if it was successful or an error message
+if not.
+`,
+	Opts: map[string]string{
+		"priority":    "Priority of restore: Standard|Expedited|Bulk",
+		"lifetime":    "Lifetime of the active copy in days",
+		"description": "The optional description for the job.",
+	},
+}}
+
+// restoreStatus is the result of restoring a single object
+type restoreStatus struct {
+	Status string
+	Remote string
+}
+
+// Command the backend to run a named command
+//
+// The command run is name
+// args may be used to read arguments from
+// opts may be used to read optional arguments from
+//
+// The result should be capable of being JSON encoded
+// If it is a string or a []string it will be shown to the user
+// otherwise it will be JSON encoded and shown to the user like that
+func (f *Fs) Command(ctx context.Context, name string, arg []string, opt map[string]string) (out interface{}, err error) {
+	switch name {
+	case "restore":
+		return f.restore(ctx, opt)
+	default:
+		return nil, fs.ErrorCommandNotFound
+	}
+}
+
+// restore requests a temporary copy of every GLACIER object under the
+// root, honouring the filters
+func (f *Fs) restore(ctx context.Context, opt map[string]string) (out interface{}, err error) {
+	if f.bucket == "" {
+		return nil, errors.New("can't restore without a bucket")
+	}
+	req := s3.RestoreRequest{}
+	if lifetime := opt["lifetime"]; lifetime != "" {
+		days, err := strconv.ParseInt(lifetime, 10, 64)
+		if err != nil {
+			return nil, errors.Wrap(err, "bad lifetime")
+		}
+		req.Days = &days
+	}
+	if priority := opt["priority"]; priority != "" {
+		req.GlacierJobParameters = &s3.GlacierJobParameters{
+			Tier: &priority,
+		}
+	}
+	if description := opt["description"]; description != "" {
+		req.Description = &description
+	}
+	var status []restoreStatus
+	err = operations.ListFn(ctx, f, func(obj fs.Object) {
+		o, ok := obj.(*Object)
+		if !ok || o.storageClass != s3.ObjectStorageClassGlacier {
+			return
+		}
+		st := restoreStatus{Remote: o.remote, Status: "OK"}
+		if fs.Config.DryRun {
+			fs.Logf(o, "Not restoring as --dry-run")
+			st.Status = "Not restored as --dry-run"
+		} else {
+			key := f.root + o.remote
+			_, err := f.c.RestoreObject(&s3.RestoreObjectInput{
+				Bucket:         &f.bucket,
+				Key:            &key,
+				RestoreRequest: &req,
+			})
+			if err != nil {
+				st.Status = err.Error()
+			}
+		}
+		status = append(status, st)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return status, nil
+}
+
 // ------------------------------------------------------------
 
 // Fs returns the parent Fs
@@ -909,6 +1116,7 @@ func (o *Object) readMetaData() (err error) {
 		o.lastModified = *resp.LastModified
 	}
 	o.mimeType = aws.StringValue(resp.ContentType)
+	o.storageClass = aws.StringValue(resp.StorageClass)
 	return nil
 }
 
@@ -937,7 +1145,7 @@ func (o *Object) ModTime() time.Time {
 }
 
 // SetModTime sets the modification time of the local fs object
-func (o *Object) SetModTime(modTime time.Time) error {
+func (o *Object) SetModTime(ctx context.Context, modTime time.Time) error {
 	err := o.readMetaData()
 	if err != nil {
 		return err
@@ -969,13 +1177,54 @@ func (o *Object) SetModTime(modTime time.Time) error {
 	return err
 }
 
+// SetTier changes the storage class of the object by copying it to
+// itself with the new storage class
+func (o *Object) SetTier(tier string) error {
+	err := o.readMetaData()
+	if err != nil {
+		return err
+	}
+	tier = strings.ToUpper(tier)
+	if o.bytes >= maxSizeForCopy {
+		return errors.Errorf("can't change the storage class of objects bigger than %v", fs.SizeSuffix(maxSizeForCopy))
+	}
+	key := o.fs.root + o.remote
+	sourceKey := o.fs.bucket + "/" + key
+	directive := s3.MetadataDirectiveCopy // keep the existing metadata
+	req := s3.CopyObjectInput{
+		Bucket:            &o.fs.bucket,
+		ACL:               &o.fs.acl,
+		Key:               &key,
+		CopySource:        aws.String(pathEscape(sourceKey)),
+		MetadataDirective: &directive,
+		StorageClass:      &tier,
+	}
+	if o.fs.sse != "" {
+		req.ServerSideEncryption = &o.fs.sse
+	}
+	_, err = o.fs.c.CopyObject(&req)
+	if err != nil {
+		return errors.Wrapf(err, "failed to set storage class to %q", tier)
+	}
+	o.storageClass = tier
+	return nil
+}
+
+// GetTier returns the storage class of the object
+func (o *Object) GetTier() string {
+	if o.storageClass == "" {
+		return s3.StorageClassStandard
+	}
+	return o.storageClass
+}
+
 // Storable raturns a boolean indicating if this object is storable
 func (o *Object) Storable() bool {
 	return true
 }
 
 // Open an object for read
-func (o *Object) Open(options ...fs.OpenOption) (in io.ReadCloser, err error) {
+func (o *Object) Open(ctx context.Context, options ...fs.OpenOption) (in io.ReadCloser, err error) {
 	key := o.fs.root + o.remote
 	req := s3.GetObjectInput{
 		Bucket: &o.fs.bucket,
@@ -1005,8 +1254,8 @@ func (o *Object) Open(options ...fs.OpenOption) (in io.ReadCloser, err error) {
 }
 
 // Update the Object from in with modTime and size
-func (o *Object) Update(in io.Reader, src fs.ObjectInfo, options ...fs.OpenOption) error {
-	err := o.fs.Mkdir("")
+func (o *Object) Update(ctx context.Context, in io.Reader, src fs.ObjectInfo, options ...fs.OpenOption) error {
+	err := o.fs.Mkdir(ctx, "")
 	if err != nil {
 		return err
 	}
@@ -1052,6 +1301,23 @@ func (o *Object) Update(in io.Reader, src fs.ObjectInfo, options ...fs.OpenOptio
 	// Guess the content type
 	mimeType := fs.MimeType(src)
 
+	// Set the metadata from the source if required
+	srcMetadata, err := fs.GetMetadataOptions(src, options)
+	if err != nil {
+		return errors.Wrap(err, "failed to read metadata from source object")
+	}
+	for k, v := range srcMetadata {
+		switch k {
+		case "mtime", "md5chksum":
+			// mtime is stored from the modTime above and
+			// md5chksum is reserved for the MD5 of the object
+		case "content-type":
+			mimeType = v
+		default:
+			metadata[k] = aws.String(v)
+		}
+	}
+
 	key := o.fs.root + o.remote
 	req := s3manager.UploadInput{
 		Bucket:      &o.fs.bucket,
@@ -1080,7 +1346,7 @@ func (o *Object) Update(in io.Reader, src fs.ObjectInfo, options ...fs.OpenOptio
 }
 
 // Remove an object
-func (o *Object) Remove() error {
+func (o *Object) Remove(ctx context.Context) error {
 	key := o.fs.root + o.remote
 	req := s3.DeleteObjectInput{
 		Bucket: &o.fs.bucket,
@@ -1090,6 +1356,29 @@ func (o *Object) Remove() error {
 	return err
 }
 
+// Metadata returns metadata for an object
+//
+// This returns the user metadata with lower case keys along with the
+// mtime and content-type of the object.  The md5chksum rclone stores
+// for multipart uploads isn't returned.
+func (o *Object) Metadata() (metadata fs.Metadata, err error) {
+	err = o.readMetaData()
+	if err != nil {
+		return nil, err
+	}
+	for k, v := range o.meta {
+		if v == nil || k == metaMtime || k == metaMD5Hash {
+			continue
+		}
+		metadata.Set(strings.ToLower(k), *v)
+	}
+	metadata.Set("mtime", o.ModTime().Format(time.RFC3339Nano))
+	if o.mimeType != "" {
+		metadata.Set("content-type", o.mimeType)
+	}
+	return metadata, nil
+}
+
 // MimeType of an Object if known, "" otherwise
 func (o *Object) MimeType() string {
 	err := o.readMetaData()
@@ -1102,10 +1391,16 @@ func (o *Object) MimeType() string {
 
 // Check the interfaces are satisfied
 var (
-	_ fs.Fs          = &Fs{}
-	_ fs.Copier      = &Fs{}
-	_ fs.PutStreamer = &Fs{}
-	_ fs.ListRer     = &Fs{}
-	_ fs.Object      = &Object{}
-	_ fs.MimeTyper   = &Object{}
+	_ fs.Fs           = &Fs{}
+	_ fs.Copier       = &Fs{}
+	_ fs.PutStreamer  = &Fs{}
+	_ fs.ListRer      = &Fs{}
+	_ fs.CleanUpper   = &Fs{}
+	_ fs.PublicLinker = &Fs{}
+	_ fs.Commander    = &Fs{}
+	_ fs.Object       = &Object{}
+	_ fs.MimeTyper    = &Object{}
+	_ fs.Metadataer   = &Object{}
+	_ fs.SetTierer    = &Object{}
+	_ fs.GetTierer    = &Object{}
 )
@@ -0,0 +1,59 @@
+// Package api has type definitions for the Sia renterd API
+//
+// See https://api.sia.tech/renterd
+package api
+
+import (
+	"fmt"
+	"time"
+)
+
+// ObjectMetadata describes an object or, if Name ends in "/", a
+// directory
+type ObjectMetadata struct {
+	ETag     string            `json:"eTag"`
+	Health   float64           `json:"health"`
+	ModTime  time.Time         `json:"modTime"`
+	Name     string            `json:"name"` // full path starting with "/"
+	Size     int64             `json:"size"`
+	MimeType string            `json:"mimeType"`
+	Metadata map[string]string `json:"metadata,omitempty"` // user metadata
+}
+
+// ObjectResponse is returned when reading a single object
+type ObjectResponse struct {
+	Object ObjectMetadata `json:"object"`
+}
+
+// ListResponse is returned when listing a directory
+type ListResponse struct {
+	HasMore bool             `json:"hasMore"`
+	Entries []ObjectMetadata `json:"entries"`
+}
+
+// Rename modes
+const (
+	RenameSingle = "single" // rename a single object
+	RenameMulti  = "multi"  // rename all objects with a prefix
+)
+
+// RenameRequest renames an object or all the objects under a prefix
+type RenameRequest struct {
+	Bucket string `json:"bucket"`
+	From   string `json:"from"`
+	To     string `json:"to"`
+	Mode   string `json:"mode"`
+	Force  bool   `json:"force"`
+}
+
+// Error is returned by renterd as a plain text body along with a
+// non 2xx status
+type Error struct {
+	StatusCode int
+	Message    string
+}
+
+// Error satisfies the error interface
+func (e *Error) Error() string {
+	return fmt.Sprintf("%s (%d)", e.Message, e.StatusCode)
+}
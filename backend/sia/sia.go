@@ -0,0 +1,718 @@
+// Package sia provides an interface to the Sia decentralized storage
+// network using the API of a renterd node.
+package sia
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"path"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/ncw/rclone/backend/sia/api"
+	"github.com/ncw/rclone/fs"
+	"github.com/ncw/rclone/fs/config"
+	"github.com/ncw/rclone/fs/config/obscure"
+	"github.com/ncw/rclone/fs/fserrors"
+	"github.com/ncw/rclone/fs/fshttp"
+	"github.com/ncw/rclone/fs/hash"
+	"github.com/ncw/rclone/lib/pacer"
+	"github.com/ncw/rclone/lib/rest"
+	"github.com/ncw/swift"
+	"github.com/pkg/errors"
+	"golang.org/x/net/context"
+)
+
+const (
+	minSleep      = 10 * time.Millisecond
+	maxSleep      = 2 * time.Second
+	decayConstant = 2 // bigger for slower decay, exponential
+	defaultURL    = "http://127.0.0.1:9980"
+	defaultBucket = "default"
+	listChunk     = 1000    // number of entries to read per listing call
+	metaMtime     = "Mtime" // the meta key to store mtime in - eg X-Sia-Meta-Mtime
+	metaPrefix    = "X-Sia-Meta-"
+)
+
+var pacerOptions = pacer.AddFlags("sia", pacer.Options{
+	MinSleep:      minSleep,
+	MaxSleep:      maxSleep,
+	DecayConstant: decayConstant,
+})
+
+// Register with Fs
+func init() {
+	fs.Register(&fs.RegInfo{
+		Name:        "sia",
+		Description: "Sia Decentralized Cloud (renterd)",
+		NewFs:       NewFs,
+		Options: []fs.Option{{
+			Name:     "api_url",
+			Help:     "URL of the renterd API - leave blank to use the default (" + defaultURL + ")",
+			Optional: true,
+			Examples: []fs.OptionExample{{
+				Value: defaultURL,
+				Help:  "renterd running on this machine",
+			}},
+		}, {
+			Name:       "api_password",
+			Help:       "renterd API password, as set with RENTERD_API_PASSWORD or in renterd.yml.",
+			Optional:   true,
+			IsPassword: true,
+		}, {
+			Name:     "bucket",
+			Help:     "Bucket to store objects in - leave blank to use the default (" + defaultBucket + ")",
+			Optional: true,
+		}},
+	})
+}
+
+// Fs represents a remote sia
+type Fs struct {
+	name     string       // name of this remote
+	root     string       // the path we are working on
+	features *fs.Features // optional features
+	srv      *rest.Client // the connection to renterd
+	pacer    *pacer.Pacer // pacer for API calls
+	bucket   string       // the bucket the objects are in
+}
+
+// Object describes a sia object
+type Object struct {
+	fs           *Fs               // what this object is part of
+	remote       string            // The remote path
+	hasMetaData  bool              // whether info below has been set
+	size         int64             // size of the object
+	lastModified time.Time         // time the object was uploaded
+	mimeType     string            // content type of the object
+	meta         map[string]string // user metadata
+}
+
+// ------------------------------------------------------------
+
+// Name of the remote (as passed into NewFs)
+func (f *Fs) Name() string {
+	return f.name
+}
+
+// Root of the remote (as passed into NewFs)
+func (f *Fs) Root() string {
+	return f.root
+}
+
+// String converts this Fs to a string
+func (f *Fs) String() string {
+	return fmt.Sprintf("sia bucket %s root '%s'", f.bucket, f.root)
+}
+
+// Features returns the optional features of this Fs
+func (f *Fs) Features() *fs.Features {
+	return f.features
+}
+
+// parsePath parses a sia 'url'
+func parsePath(path string) (root string) {
+	root = strings.Trim(path, "/")
+	return
+}
+
+// retryErrorCodes is a slice of error codes that we will retry
+var retryErrorCodes = []int{
+	429, // Too Many Requests.
+	500, // Internal Server Error
+	502, // Bad Gateway
+	503, // Service Unavailable
+	504, // Gateway Timeout
+	509, // Bandwidth Limit Exceeded
+}
+
+// shouldRetry returns a boolean as to whether this resp and err
+// deserve to be retried.  It returns the err as a convenience
+func shouldRetry(resp *http.Response, err error) (bool, error) {
+	return fserrors.ShouldRetry(err) || fserrors.ShouldRetryHTTP(resp, retryErrorCodes), pacer.RetryAfterHTTP(resp, err)
+}
+
+// errorHandler parses a non 2xx error response into an error
+//
+// renterd returns errors as plain text
+func errorHandler(resp *http.Response) error {
+	body, err := rest.ReadBody(resp)
+	if err != nil {
+		fs.Debugf(nil, "Couldn't read error response: %v", err)
+	}
+	errResponse := &api.Error{
+		StatusCode: resp.StatusCode,
+		Message:    strings.TrimSpace(string(body)),
+	}
+	if errResponse.Message == "" {
+		errResponse.Message = resp.Status
+	}
+	return errResponse
+}
+
+// isNotFound returns true if err means the object doesn't exist
+func isNotFound(err error) bool {
+	apiErr, ok := err.(*api.Error)
+	return ok && apiErr.StatusCode == http.StatusNotFound
+}
+
+// NewFs constructs an Fs from the path, container:path
+func NewFs(name, root string) (fs.Fs, error) {
+	apiURL := strings.TrimRight(config.FileGet(name, "api_url", defaultURL), "/")
+	if apiURL == "" {
+		apiURL = defaultURL
+	}
+	if _, err := url.Parse(apiURL); err != nil {
+		return nil, errors.Wrap(err, "sia: couldn't parse api_url")
+	}
+	password := config.FileGet(name, "api_password")
+	if password != "" {
+		var err error
+		password, err = obscure.Reveal(password)
+		if err != nil {
+			return nil, errors.Wrap(err, "couldn't decrypt API password")
+		}
+	}
+	bucket := config.FileGet(name, "bucket", defaultBucket)
+	if bucket == "" {
+		bucket = defaultBucket
+	}
+
+	f := &Fs{
+		name:   name,
+		root:   parsePath(root),
+		srv:    rest.NewClient(fshttp.NewClient(fs.ConfigForRemote(name))).SetRoot(apiURL),
+		pacer:  pacer.New().SetOptions(pacerOptions),
+		bucket: bucket,
+	}
+	f.features = (&fs.Features{
+		ReadMimeType:  true,
+		WriteMimeType: true,
+	}).Fill(f)
+	f.srv.SetErrorHandler(errorHandler)
+	if password != "" {
+		// renterd uses basic auth with an empty user name
+		f.srv.SetUserPass("", password)
+	}
+
+	if f.root != "" {
+		// Check to see if the root actually an existing file
+		_, err := f.getObject("")
+		if err == nil {
+			newRoot := path.Dir(f.root)
+			if newRoot == "." {
+				newRoot = ""
+			}
+			f.root = newRoot
+			// return an error with an fs which points to the parent
+			return f, fs.ErrorIsFile
+		}
+	}
+	return f, nil
+}
+
+// absPath returns the unescaped object name for remote starting
+// with a "/"
+func (f *Fs) absPath(remote string) string {
+	return "/" + path.Join(f.root, remote)
+}
+
+// dirPath returns the unescaped prefix of the directory dir
+// starting and ending with a "/"
+func (f *Fs) dirPath(dir string) string {
+	dirPath := f.absPath(dir)
+	if dirPath != "/" {
+		dirPath += "/"
+	}
+	return dirPath
+}
+
+// busPath returns the path of the bus API for the object name given
+func busPath(name string) string {
+	return "/api/bus/objects" + rest.URLPathEscape(name)
+}
+
+// workerPath returns the path of the worker API for the object name given
+func workerPath(name string) string {
+	return "/api/worker/objects" + rest.URLPathEscape(name)
+}
+
+// params makes the query parameters needed for every call
+func (f *Fs) params() url.Values {
+	params := url.Values{}
+	params.Set("bucket", f.bucket)
+	return params
+}
+
+// getObject reads the metadata of remote
+func (f *Fs) getObject(remote string) (info *api.ObjectMetadata, err error) {
+	opts := rest.Opts{
+		Method:     "GET",
+		Path:       busPath(f.absPath(remote)),
+		Parameters: f.params(),
+	}
+	opts.Parameters.Set("onlymetadata", "true")
+	var result api.ObjectResponse
+	var resp *http.Response
+	err = f.pacer.Call(func() (bool, error) {
+		resp, err = f.srv.CallJSON(&opts, nil, &result)
+		return shouldRetry(resp, err)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &result.Object, nil
+}
+
+// listFn is called from listAll to handle an object
+type listFn func(remote string, isDir bool, info *api.ObjectMetadata) error
+
+// listAll lists the contents of the directory dir calling fn for
+// each entry
+//
+// It returns found as false if nothing was found
+func (f *Fs) listAll(dir string, fn listFn) (found bool, err error) {
+	prefix := f.dirPath(dir)
+	opts := rest.Opts{
+		Method:     "GET",
+		Path:       busPath(prefix),
+		Parameters: f.params(),
+	}
+	opts.Parameters.Set("limit", strconv.Itoa(listChunk))
+	offset := 0
+	for {
+		opts.Parameters.Set("offset", strconv.Itoa(offset))
+		var result api.ListResponse
+		var resp *http.Response
+		err = f.pacer.Call(func() (bool, error) {
+			resp, err = f.srv.CallJSON(&opts, nil, &result)
+			return shouldRetry(resp, err)
+		})
+		if err != nil {
+			return found, errors.Wrap(err, "couldn't list files")
+		}
+		for i := range result.Entries {
+			item := &result.Entries[i]
+			if !strings.HasPrefix(item.Name, prefix) {
+				fs.Debugf(f, "Ignoring %q not in directory %q", item.Name, prefix)
+				continue
+			}
+			found = true
+			isDir := strings.HasSuffix(item.Name, "/")
+			leaf := strings.TrimSuffix(item.Name[len(prefix):], "/")
+			if leaf == "" {
+				continue
+			}
+			err = fn(path.Join(dir, leaf), isDir, item)
+			if err != nil {
+				return found, err
+			}
+		}
+		if !result.HasMore || len(result.Entries) == 0 {
+			break
+		}
+		offset += len(result.Entries)
+	}
+	return found, nil
+}
+
+// Return an Object from a path
+//
+// If it can't be found it returns the error fs.ErrorObjectNotFound.
+func (f *Fs) newObjectWithInfo(remote string, info *api.ObjectMetadata) (fs.Object, error) {
+	o := &Object{
+		fs:     f,
+		remote: remote,
+	}
+	if info != nil {
+		// Set info but not meta as listings don't include it
+		o.size = info.Size
+		o.lastModified = info.ModTime
+		o.mimeType = info.MimeType
+	} else {
+		err := o.readMetaData() // reads info and meta, returning an error
+		if err != nil {
+			return nil, err
+		}
+	}
+	return o, nil
+}
+
+// NewObject finds the Object at remote.  If it can't be found
+// it returns the error fs.ErrorObjectNotFound.
+func (f *Fs) NewObject(ctx context.Context, remote string) (fs.Object, error) {
+	return f.newObjectWithInfo(remote, nil)
+}
+
+// List the objects and directories in dir into entries.  The
+// entries can be returned in any order but should be for a
+// complete directory.
+//
+// dir should be "" to list the root, and should not have
+// trailing slashes.
+//
+// This should return ErrDirNotFound if the directory isn't
+// found.
+func (f *Fs) List(ctx context.Context, dir string) (entries fs.DirEntries, err error) {
+	found, err := f.listAll(dir, func(remote string, isDir bool, info *api.ObjectMetadata) error {
+		if isDir {
+			d := fs.NewDir(remote, info.ModTime)
+			entries = append(entries, d)
+			return nil
+		}
+		o, err := f.newObjectWithInfo(remote, info)
+		if err != nil {
+			return err
+		}
+		entries = append(entries, o)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	// Directories only exist if they have objects in
+	if !found && f.dirPath(dir) != "/" {
+		return nil, fs.ErrorDirNotFound
+	}
+	return entries, nil
+}
+
+// Put the object
+//
+// # Copy the reader in to the new object which is returned
+//
+// The new object may have been created if an error is returned
+func (f *Fs) Put(ctx context.Context, in io.Reader, src fs.ObjectInfo, options ...fs.OpenOption) (fs.Object, error) {
+	o := &Object{
+		fs:     f,
+		remote: src.Remote(),
+	}
+	return o, o.Update(ctx, in, src, options...)
+}
+
+// PutStream uploads to the remote path with the modTime given of indeterminate size
+func (f *Fs) PutStream(ctx context.Context, in io.Reader, src fs.ObjectInfo, options ...fs.OpenOption) (fs.Object, error) {
+	return f.Put(ctx, in, src, options...)
+}
+
+// Mkdir creates the directory if it doesn't exist
+//
+// Directories are implied by the objects in them so this does nothing
+func (f *Fs) Mkdir(ctx context.Context, dir string) error {
+	return nil
+}
+
+// Rmdir deletes the directory
+//
+// Returns an error if it isn't empty
+func (f *Fs) Rmdir(ctx context.Context, dir string) error {
+	found, err := f.listAll(dir, func(remote string, isDir bool, info *api.ObjectMetadata) error {
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	if found {
+		return fs.ErrorDirectoryNotEmpty
+	}
+	return nil
+}
+
+// Purge deletes all the files and directories
+//
+// Optional interface: Only implement this if you have a way of
+// deleting all the files quicker than just running Remove() on the
+// result of List()
+func (f *Fs) Purge(ctx context.Context) error {
+	opts := rest.Opts{
+		Method:     "DELETE",
+		Path:       workerPath(f.dirPath("")),
+		Parameters: f.params(),
+		NoResponse: true,
+	}
+	// batch deletes every object with the prefix
+	opts.Parameters.Set("batch", "true")
+	err := f.pacer.Call(func() (bool, error) {
+		resp, err := f.srv.Call(&opts)
+		return shouldRetry(resp, err)
+	})
+	if err != nil && !isNotFound(err) {
+		return errors.Wrap(err, "purge failed")
+	}
+	return nil
+}
+
+// Precision return the precision of this Fs
+func (f *Fs) Precision() time.Duration {
+	return time.Nanosecond
+}
+
+// rename renames the object or prefix from to to
+func (f *Fs) rename(from, to, mode string) error {
+	opts := rest.Opts{
+		Method:     "POST",
+		Path:       "/api/bus/objects/rename",
+		NoResponse: true,
+	}
+	request := api.RenameRequest{
+		Bucket: f.bucket,
+		From:   from,
+		To:     to,
+		Mode:   mode,
+		Force:  mode == api.RenameSingle,
+	}
+	return f.pacer.Call(func() (bool, error) {
+		resp, err := f.srv.CallJSON(&opts, &request, nil)
+		return shouldRetry(resp, err)
+	})
+}
+
+// Move src to this remote using server side move operations.
+//
+// # This is stored with the remote path given
+//
+// # It returns the destination Object and a possible error
+//
+// Will only be called if src.Fs().Name() == f.Name()
+//
+// If it isn't possible then return fs.ErrorCantMove
+func (f *Fs) Move(ctx context.Context, src fs.Object, remote string) (fs.Object, error) {
+	srcObj, ok := src.(*Object)
+	if !ok {
+		fs.Debugf(src, "Can't move - not same remote type")
+		return nil, fs.ErrorCantMove
+	}
+	if srcObj.fs.bucket != f.bucket {
+		fs.Debugf(src, "Can't move - not same bucket")
+		return nil, fs.ErrorCantMove
+	}
+	err := f.rename(srcObj.fs.absPath(srcObj.remote), f.absPath(remote), api.RenameSingle)
+	if err != nil {
+		return nil, errors.Wrap(err, "move failed")
+	}
+	return f.NewObject(ctx, remote)
+}
+
+// DirMove moves src, srcRemote to this remote at dstRemote
+// using server side move operations.
+//
+// Will only be called if src.Fs().Name() == f.Name()
+//
+// If it isn't possible then return fs.ErrorCantDirMove
+//
+// If destination exists then return fs.ErrorDirExists
+func (f *Fs) DirMove(ctx context.Context, src fs.Fs, srcRemote, dstRemote string) error {
+	srcFs, ok := src.(*Fs)
+	if !ok {
+		fs.Debugf(srcFs, "Can't move directory - not same remote type")
+		return fs.ErrorCantDirMove
+	}
+	if srcFs.bucket != f.bucket {
+		fs.Debugf(srcFs, "Can't move directory - not same bucket")
+		return fs.ErrorCantDirMove
+	}
+	srcPath := srcFs.dirPath(srcRemote)
+	dstPath := f.dirPath(dstRemote)
+	if srcPath == "/" || dstPath == "/" {
+		fs.Debugf(srcFs, "Can't move directory - can't move the root of the bucket")
+		return fs.ErrorCantDirMove
+	}
+	// Check if destination exists
+	found, err := f.listAll(dstRemote, func(remote string, isDir bool, info *api.ObjectMetadata) error {
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	if found {
+		return fs.ErrorDirExists
+	}
+	err = f.rename(srcPath, dstPath, api.RenameMulti)
+	if err != nil {
+		return errors.Wrap(err, "dirmove failed")
+	}
+	return nil
+}
+
+// Hashes returns the supported hash sets.
+func (f *Fs) Hashes() hash.Set {
+	return hash.Set(hash.None)
+}
+
+// ------------------------------------------------------------
+
+// Fs returns the parent Fs
+func (o *Object) Fs() fs.Info {
+	return o.fs
+}
+
+// Return a string version
+func (o *Object) String() string {
+	if o == nil {
+		return "<nil>"
+	}
+	return o.remote
+}
+
+// Remote returns the remote path
+func (o *Object) Remote() string {
+	return o.remote
+}
+
+// Hash is unsupported on sia
+func (o *Object) Hash(t hash.Type) (string, error) {
+	return "", hash.ErrUnsupported
+}
+
+// Size returns the size of an object in bytes
+func (o *Object) Size() int64 {
+	return o.size
+}
+
+// readMetaData gets the metadata if it hasn't already been fetched
+func (o *Object) readMetaData() error {
+	if o.hasMetaData {
+		return nil
+	}
+	info, err := o.fs.getObject(o.remote)
+	if isNotFound(err) {
+		return fs.ErrorObjectNotFound
+	}
+	if err != nil {
+		return err
+	}
+	if strings.HasSuffix(info.Name, "/") {
+		return fs.ErrorNotAFile
+	}
+	o.size = info.Size
+	o.lastModified = info.ModTime
+	o.mimeType = info.MimeType
+	o.meta = info.Metadata
+	o.hasMetaData = true
+	return nil
+}
+
+// ModTime returns the modification time of the object
+//
+// It attempts to read the objects mtime and if that isn't present the
+// time the object was uploaded
+func (o *Object) ModTime() time.Time {
+	err := o.readMetaData()
+	if err != nil {
+		fs.Logf(o, "Failed to read metadata: %v", err)
+		return time.Now()
+	}
+	// read mtime out of metadata if available
+	for k, v := range o.meta {
+		if strings.EqualFold(k, metaMtime) {
+			modTime, err := swift.FloatStringToTime(v)
+			if err != nil {
+				fs.Logf(o, "Failed to read mtime from object: %v", err)
+				break
+			}
+			return modTime
+		}
+	}
+	return o.lastModified
+}
+
+// SetModTime sets the modification time of the object
+//
+// renterd can't change the metadata of an existing object
+func (o *Object) SetModTime(ctx context.Context, modTime time.Time) error {
+	return fs.ErrorCantSetModTime
+}
+
+// Storable returns a boolean showing whether this object storable
+func (o *Object) Storable() bool {
+	return true
+}
+
+// MimeType of an Object if known, "" otherwise
+func (o *Object) MimeType() string {
+	err := o.readMetaData()
+	if err != nil {
+		fs.Logf(o, "Failed to read metadata: %v", err)
+		return ""
+	}
+	return o.mimeType
+}
+
+// Open an object for read
+func (o *Object) Open(ctx context.Context, options ...fs.OpenOption) (in io.ReadCloser, err error) {
+	opts := rest.Opts{
+		Method:     "GET",
+		Path:       workerPath(o.fs.absPath(o.remote)),
+		Parameters: o.fs.params(),
+		Options:    options,
+	}
+	var resp *http.Response
+	err = o.fs.pacer.Call(func() (bool, error) {
+		resp, err = o.fs.srv.Call(&opts)
+		return shouldRetry(resp, err)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return resp.Body, nil
+}
+
+// Update the object with the contents of the io.Reader, modTime and size
+//
+// # If existing is set then it updates the object rather than creating a new one
+//
+// The new object may have been created if an error is returned
+func (o *Object) Update(ctx context.Context, in io.Reader, src fs.ObjectInfo, options ...fs.OpenOption) (err error) {
+	opts := rest.Opts{
+		Method:      "PUT",
+		Path:        workerPath(o.fs.absPath(o.remote)),
+		Parameters:  o.fs.params(),
+		Body:        in,
+		ContentType: fs.MimeType(src),
+		NoResponse:  true,
+		ExtraHeaders: map[string]string{
+			metaPrefix + metaMtime: swift.TimeToFloatString(src.ModTime()),
+		},
+	}
+	if size := src.Size(); size >= 0 {
+		opts.ContentLength = &size
+	}
+	err = o.fs.pacer.CallNoRetry(func() (bool, error) {
+		resp, err := o.fs.srv.Call(&opts)
+		return shouldRetry(resp, err)
+	})
+	if err != nil {
+		return errors.Wrap(err, "failed to upload")
+	}
+	// Read the metadata from the newly created object
+	o.hasMetaData = false
+	return o.readMetaData()
+}
+
+// Remove an object
+func (o *Object) Remove(ctx context.Context) error {
+	opts := rest.Opts{
+		Method:     "DELETE",
+		Path:       workerPath(o.fs.absPath(o.remote)),
+		Parameters: o.fs.params(),
+		NoResponse: true,
+	}
+	return o.fs.pacer.Call(func() (bool, error) {
+		resp, err := o.fs.srv.Call(&opts)
+		return shouldRetry(resp, err)
+	})
+}
+
+// Check the interfaces are satisfied
+var (
+	_ fs.Fs          = (*Fs)(nil)
+	_ fs.Purger      = (*Fs)(nil)
+	_ fs.Mover       = (*Fs)(nil)
+	_ fs.DirMover    = (*Fs)(nil)
+	_ fs.PutStreamer = (*Fs)(nil)
+	_ fs.Object      = (*Object)(nil)
+	_ fs.MimeTyper   = (*Object)(nil)
+)
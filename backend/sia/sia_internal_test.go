@@ -0,0 +1,74 @@
+package sia
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/ncw/rclone/fs"
+	"github.com/ncw/rclone/lib/pacer"
+	"github.com/ncw/rclone/lib/rest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/net/context"
+)
+
+// newTestFs makes an Fs talking to a renterd API served by handler
+func newTestFs(t *testing.T, root string, handler http.HandlerFunc) (*Fs, func()) {
+	ts := httptest.NewServer(handler)
+	f := &Fs{
+		name:   "sia",
+		root:   root,
+		srv:    rest.NewClient(http.DefaultClient).SetRoot(ts.URL),
+		pacer:  pacer.New().SetMinSleep(minSleep).SetMaxSleep(maxSleep).SetDecayConstant(decayConstant).SetRetries(1),
+		bucket: defaultBucket,
+	}
+	f.features = (&fs.Features{}).Fill(f)
+	f.srv.SetErrorHandler(errorHandler)
+	return f, ts.Close
+}
+
+func TestList(t *testing.T) {
+	ctx := context.Background()
+	f, cleanup := newTestFs(t, "root", func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, defaultBucket, r.URL.Query().Get("bucket"))
+		switch r.URL.Path {
+		case "/api/bus/objects/root/dir/":
+			fmt.Fprint(w, `{"hasMore":false,"entries":[
+				{"name":"/root/dir/file.txt","size":5,"modTime":"2018-08-01T12:00:00Z"},
+				{"name":"/root/dir/sub/","size":10,"modTime":"2018-08-01T13:00:00Z"}
+			]}`)
+		case "/api/bus/objects/root/empty/":
+			fmt.Fprint(w, `{"hasMore":false,"entries":[]}`)
+		default:
+			http.Error(w, "object not found", http.StatusNotFound)
+		}
+	})
+	defer cleanup()
+
+	entries, err := f.List(ctx, "dir")
+	require.NoError(t, err)
+	require.Len(t, entries, 2)
+	o, ok := entries[0].(*Object)
+	require.True(t, ok)
+	assert.Equal(t, "dir/file.txt", o.Remote())
+	assert.Equal(t, int64(5), o.Size())
+	d, ok := entries[1].(*fs.Dir)
+	require.True(t, ok)
+	assert.Equal(t, "dir/sub", d.Remote())
+
+	_, err = f.List(ctx, "empty")
+	assert.Equal(t, fs.ErrorDirNotFound, err)
+}
+
+func TestNewObjectNotFound(t *testing.T) {
+	ctx := context.Background()
+	f, cleanup := newTestFs(t, "", func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "object not found\n", http.StatusNotFound)
+	})
+	defer cleanup()
+
+	_, err := f.NewObject(ctx, "missing")
+	assert.Equal(t, fs.ErrorObjectNotFound, err)
+}
@@ -0,0 +1,891 @@
+// Package smb provides an interface to SMB/CIFS file shares
+
+// +build go1.8
+
+package smb
+
+import (
+	"io"
+	"net"
+	"os"
+	"os/user"
+	"path"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/hirochachacha/go-smb2"
+	"github.com/ncw/rclone/fs"
+	"github.com/ncw/rclone/fs/config"
+	"github.com/ncw/rclone/fs/config/obscure"
+	"github.com/ncw/rclone/fs/fshttp"
+	"github.com/ncw/rclone/fs/hash"
+	"github.com/ncw/rclone/lib/readers"
+	"github.com/pkg/errors"
+	"golang.org/x/net/context"
+	"golang.org/x/time/rate"
+)
+
+const (
+	connectionsPerSecond = 10 // don't make more than this many SMB connections/s
+	defaultPort          = "445"
+	defaultDomain        = "WORKGROUP"
+)
+
+var (
+	currentUser = readCurrentUser()
+)
+
+// Register with Fs
+func init() {
+	fs.Register(&fs.RegInfo{
+		Name:        "smb",
+		Description: "SMB / CIFS",
+		NewFs:       NewFs,
+		Options: []fs.Option{{
+			Name:     "host",
+			Help:     "SMB server hostname to connect to",
+			Optional: false,
+			Examples: []fs.OptionExample{{
+				Value: "example.com",
+				Help:  "Connect to example.com",
+			}},
+		}, {
+			Name:     "user",
+			Help:     "SMB username, leave blank for current username, " + currentUser,
+			Optional: true,
+		}, {
+			Name:     "port",
+			Help:     "SMB port, leave blank to use default (" + defaultPort + ")",
+			Optional: true,
+		}, {
+			Name:       "pass",
+			Help:       "SMB password.",
+			Optional:   true,
+			IsPassword: true,
+		}, {
+			Name:     "domain",
+			Help:     "Domain name for NTLM authentication, leave blank to use default (" + defaultDomain + ")",
+			Optional: true,
+		}},
+	})
+}
+
+// Fs represents a remote SMB server
+type Fs struct {
+	name      string
+	root      string
+	features  *fs.Features // optional features
+	host      string
+	port      string
+	url       string
+	initiator *smb2.NTLMInitiator
+	poolMu    sync.Mutex
+	pool      []*conn
+	connLimit *rate.Limiter // for limiting number of connections per second
+}
+
+// Object describes a file on an SMB share
+type Object struct {
+	fs      *Fs
+	remote  string
+	size    int64       // size of the object
+	modTime time.Time   // modification time of the object
+	mode    os.FileMode // mode bits from the file
+}
+
+// readCurrentUser finds the current user name or "" if not found
+func readCurrentUser() (userName string) {
+	usr, err := user.Current()
+	if err == nil {
+		return usr.Username
+	}
+	// Fall back to reading $USER then $LOGNAME
+	userName = os.Getenv("USER")
+	if userName != "" {
+		return userName
+	}
+	return os.Getenv("LOGNAME")
+}
+
+// split returns the share and the path within the share for a path
+// relative to the root of the server
+func split(absPath string) (share, sharePath string) {
+	absPath = strings.Trim(absPath, "/")
+	if absPath == "" || absPath == "." {
+		return "", ""
+	}
+	i := strings.IndexRune(absPath, '/')
+	if i < 0 {
+		return absPath, ""
+	}
+	return absPath[:i], absPath[i+1:]
+}
+
+// conn encapsulates an SMB session and the shares mounted on it
+type conn struct {
+	tcpConn net.Conn
+	session *smb2.Session
+	shares  map[string]*smb2.Share
+}
+
+// getShare returns the share called name, mounting it if necessary
+func (c *conn) getShare(name string) (*smb2.Share, error) {
+	if share, ok := c.shares[name]; ok {
+		return share, nil
+	}
+	share, err := c.session.Mount(name)
+	if err != nil {
+		return nil, errors.Wrapf(err, "couldn't mount share %q", name)
+	}
+	c.shares[name] = share
+	return share, nil
+}
+
+// Closes the connection
+func (c *conn) close() error {
+	for _, share := range c.shares {
+		_ = share.Umount()
+	}
+	logoffErr := c.session.Logoff()
+	tcpErr := c.tcpConn.Close()
+	if logoffErr != nil {
+		return logoffErr
+	}
+	return tcpErr
+}
+
+// Open a new connection to the SMB server.
+func (f *Fs) smbConnection() (c *conn, err error) {
+	// Rate limit rate of new connections
+	err = f.connLimit.Wait(context.Background())
+	if err != nil {
+		return nil, errors.Wrap(err, "limiter failed in connect")
+	}
+	dialer := fshttp.NewDialer(fs.Config)
+	tcpConn, err := dialer.Dial("tcp", net.JoinHostPort(f.host, f.port))
+	if err != nil {
+		return nil, errors.Wrap(err, "couldn't connect SMB")
+	}
+	d := &smb2.Dialer{
+		Initiator: f.initiator,
+	}
+	session, err := d.Dial(tcpConn)
+	if err != nil {
+		_ = tcpConn.Close()
+		return nil, errors.Wrap(err, "couldn't initialise SMB session")
+	}
+	return &conn{
+		tcpConn: tcpConn,
+		session: session,
+		shares:  make(map[string]*smb2.Share),
+	}, nil
+}
+
+// Get an SMB connection from the pool, or open a new one
+func (f *Fs) getConnection() (c *conn, err error) {
+	f.poolMu.Lock()
+	if len(f.pool) > 0 {
+		c = f.pool[0]
+		f.pool = f.pool[1:]
+	}
+	f.poolMu.Unlock()
+	if c != nil {
+		return c, nil
+	}
+	return f.smbConnection()
+}
+
+// Return an SMB connection to the pool
+//
+// It nils the pointed to connection out so it can't be reused
+//
+// if err is not nil and isn't an error about a file then the
+// connection is closed rather than returned to the pool
+func (f *Fs) putConnection(pc **conn, err error) {
+	c := *pc
+	*pc = nil
+	if err != nil {
+		// work out if this is an expected error
+		isRegularError := false
+		switch errors.Cause(err).(type) {
+		case *os.PathError, *os.LinkError:
+			isRegularError = true
+		}
+		if !isRegularError {
+			fs.Debugf(f, "Closing connection after error: %v", err)
+			_ = c.close()
+			return
+		}
+	}
+	f.poolMu.Lock()
+	f.pool = append(f.pool, c)
+	f.poolMu.Unlock()
+}
+
+// NewFs constructs an Fs from the path, share/path
+func NewFs(name, root string) (fs.Fs, error) {
+	user := config.FileGet(name, "user")
+	host := config.FileGet(name, "host")
+	port := config.FileGet(name, "port")
+	pass := config.FileGet(name, "pass")
+	domain := config.FileGet(name, "domain")
+	if host == "" {
+		return nil, errors.New("host not found in config")
+	}
+	if user == "" {
+		user = currentUser
+	}
+	if port == "" {
+		port = defaultPort
+	}
+	if domain == "" {
+		domain = defaultDomain
+	}
+	if pass != "" {
+		var err error
+		pass, err = obscure.Reveal(pass)
+		if err != nil {
+			return nil, errors.Wrap(err, "couldn't decrypt password")
+		}
+	}
+
+	root = strings.Trim(root, "/")
+	f := &Fs{
+		name: name,
+		root: root,
+		host: host,
+		port: port,
+		url:  "smb://" + user + "@" + host + ":" + port + "/" + root,
+		initiator: &smb2.NTLMInitiator{
+			User:     user,
+			Password: pass,
+			Domain:   domain,
+		},
+		connLimit: rate.NewLimiter(rate.Limit(connectionsPerSecond), 1),
+	}
+	f.features = (&fs.Features{
+		CanHaveEmptyDirectories: true,
+	}).Fill(f)
+	// Make a connection and pool it to return errors early
+	c, err := f.getConnection()
+	if err != nil {
+		return nil, errors.Wrap(err, "NewFs")
+	}
+	f.putConnection(&c, nil)
+	if share, sharePath := split(root); share != "" && sharePath != "" {
+		// Check to see if the root actually an existing file
+		remote := path.Base(root)
+		f.root = path.Dir(root)
+		_, err := f.NewObject(context.TODO(), remote)
+		if err != nil {
+			if err == fs.ErrorObjectNotFound || errors.Cause(err) == fs.ErrorNotAFile {
+				// File doesn't exist so return old f
+				f.root = root
+				return f, nil
+			}
+			return nil, err
+		}
+		// return an error with an fs which points to the parent
+		return f, fs.ErrorIsFile
+	}
+	return f, nil
+}
+
+// Name returns the configured name of the file system
+func (f *Fs) Name() string {
+	return f.name
+}
+
+// Root returns the root for the filesystem
+func (f *Fs) Root() string {
+	return f.root
+}
+
+// String returns the URL for the filesystem
+func (f *Fs) String() string {
+	return f.url
+}
+
+// Features returns the optional features of this Fs
+func (f *Fs) Features() *fs.Features {
+	return f.features
+}
+
+// Precision returns the precision of the modification times
+//
+// SMB stores times in 100ns units
+func (f *Fs) Precision() time.Duration {
+	return 100 * time.Nanosecond
+}
+
+// Hashes returns the supported hash types of the filesystem
+func (f *Fs) Hashes() hash.Set {
+	return hash.Set(hash.None)
+}
+
+// NewObject finds the Object at remote.  If it can't be found
+// it returns the error fs.ErrorObjectNotFound.
+func (f *Fs) NewObject(ctx context.Context, remote string) (fs.Object, error) {
+	o := &Object{
+		fs:     f,
+		remote: remote,
+	}
+	err := o.stat()
+	if err != nil {
+		return nil, err
+	}
+	return o, nil
+}
+
+// stat returns info about absPath which is share/path
+func (f *Fs) stat(absPath string) (info os.FileInfo, err error) {
+	shareName, sharePath := split(absPath)
+	c, err := f.getConnection()
+	if err != nil {
+		return nil, errors.Wrap(err, "stat")
+	}
+	share, err := c.getShare(shareName)
+	if err == nil {
+		info, err = share.Stat(sharePath)
+	}
+	f.putConnection(&c, err)
+	return info, err
+}
+
+// dirExists returns true,nil if the directory exists, false, nil if
+// it doesn't or false, err
+func (f *Fs) dirExists(absPath string) (bool, error) {
+	info, err := f.stat(absPath)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return false, nil
+		}
+		return false, errors.Wrap(err, "dirExists stat failed")
+	}
+	if !info.IsDir() {
+		return false, fs.ErrorIsFile
+	}
+	return true, nil
+}
+
+// listShares lists the shares on the server as directories
+func (f *Fs) listShares() (entries fs.DirEntries, err error) {
+	c, err := f.getConnection()
+	if err != nil {
+		return nil, errors.Wrap(err, "List")
+	}
+	names, err := c.session.ListSharenames()
+	f.putConnection(&c, err)
+	if err != nil {
+		return nil, errors.Wrap(err, "error listing shares")
+	}
+	for _, name := range names {
+		// Skip administrative shares like IPC$ and C$
+		if strings.HasSuffix(name, "$") {
+			continue
+		}
+		entries = append(entries, fs.NewDir(name, time.Time{}))
+	}
+	return entries, nil
+}
+
+// List the objects and directories in dir into entries.  The
+// entries can be returned in any order but should be for a
+// complete directory.
+//
+// dir should be "" to list the root, and should not have
+// trailing slashes.
+//
+// This should return ErrDirNotFound if the directory isn't
+// found.
+func (f *Fs) List(ctx context.Context, dir string) (entries fs.DirEntries, err error) {
+	absPath := path.Join(f.root, dir)
+	shareName, sharePath := split(absPath)
+	if shareName == "" {
+		return f.listShares()
+	}
+	c, err := f.getConnection()
+	if err != nil {
+		return nil, errors.Wrap(err, "List")
+	}
+	share, err := c.getShare(shareName)
+	if err != nil {
+		f.putConnection(&c, err)
+		return nil, fs.ErrorDirNotFound
+	}
+	infos, err := share.ReadDir(sharePath)
+	f.putConnection(&c, err)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, fs.ErrorDirNotFound
+		}
+		return nil, errors.Wrapf(err, "error listing %q", dir)
+	}
+	for _, info := range infos {
+		remote := path.Join(dir, info.Name())
+		if info.IsDir() {
+			d := fs.NewDir(remote, info.ModTime())
+			entries = append(entries, d)
+		} else {
+			o := &Object{
+				fs:     f,
+				remote: remote,
+			}
+			o.setMetadata(info)
+			entries = append(entries, o)
+		}
+	}
+	return entries, nil
+}
+
+// Put the object
+//
+// Copy the reader in to the new object which is returned
+//
+// The new object may have been created if an error is returned
+func (f *Fs) Put(ctx context.Context, in io.Reader, src fs.ObjectInfo, options ...fs.OpenOption) (fs.Object, error) {
+	err := f.mkParentDir(src.Remote())
+	if err != nil {
+		return nil, errors.Wrap(err, "Put mkParentDir failed")
+	}
+	// Temporary object under construction
+	o := &Object{
+		fs:     f,
+		remote: src.Remote(),
+	}
+	err = o.Update(ctx, in, src, options...)
+	if err != nil {
+		return nil, err
+	}
+	return o, nil
+}
+
+// PutStream uploads to the remote path with the modTime given of indeterminate size
+func (f *Fs) PutStream(ctx context.Context, in io.Reader, src fs.ObjectInfo, options ...fs.OpenOption) (fs.Object, error) {
+	return f.Put(ctx, in, src, options...)
+}
+
+// mkParentDir makes the parent of remote if necessary and any
+// directories above that
+func (f *Fs) mkParentDir(remote string) error {
+	return f.mkdir(path.Dir(path.Join(f.root, remote)))
+}
+
+// mkdir makes the directory and parents using share/path
+//
+// It is not possible to create shares so it just checks the share
+// exists if absPath is the root of a share.
+func (f *Fs) mkdir(absPath string) error {
+	shareName, sharePath := split(absPath)
+	if shareName == "" {
+		return nil
+	}
+	c, err := f.getConnection()
+	if err != nil {
+		return errors.Wrap(err, "mkdir")
+	}
+	share, err := c.getShare(shareName)
+	if err == nil && sharePath != "" {
+		err = share.MkdirAll(sharePath, 0777)
+	}
+	f.putConnection(&c, err)
+	if err != nil {
+		return errors.Wrapf(err, "mkdir %q failed", absPath)
+	}
+	return nil
+}
+
+// Mkdir makes the directory (container, bucket)
+//
+// Shouldn't return an error if it already exists
+func (f *Fs) Mkdir(ctx context.Context, dir string) error {
+	return f.mkdir(path.Join(f.root, dir))
+}
+
+// Rmdir removes the directory (container, bucket) if empty
+//
+// Return an error if it doesn't exist or isn't empty
+func (f *Fs) Rmdir(ctx context.Context, dir string) error {
+	absPath := path.Join(f.root, dir)
+	shareName, sharePath := split(absPath)
+	if sharePath == "" {
+		return errors.Errorf("can't remove share %q", shareName)
+	}
+	c, err := f.getConnection()
+	if err != nil {
+		return errors.Wrap(err, "Rmdir")
+	}
+	share, err := c.getShare(shareName)
+	if err == nil {
+		err = share.Remove(sharePath)
+	}
+	f.putConnection(&c, err)
+	return err
+}
+
+// DirSetModTime sets the modification time of the directory
+func (f *Fs) DirSetModTime(ctx context.Context, dir string, modTime time.Time) error {
+	shareName, sharePath := split(path.Join(f.root, dir))
+	if sharePath == "" {
+		return errors.Errorf("can't set modification time of share %q", shareName)
+	}
+	c, err := f.getConnection()
+	if err != nil {
+		return errors.Wrap(err, "DirSetModTime")
+	}
+	share, err := c.getShare(shareName)
+	if err == nil {
+		err = share.Chtimes(sharePath, modTime, modTime)
+	}
+	f.putConnection(&c, err)
+	if os.IsNotExist(err) {
+		return fs.ErrorDirNotFound
+	}
+	return err
+}
+
+// Move src to this remote using server side move operations.
+//
+// This is stored with the remote path given
+//
+// It returns the destination Object and a possible error
+//
+// Will only be called if src.Fs().Name() == f.Name()
+//
+// If it isn't possible then return fs.ErrorCantMove
+func (f *Fs) Move(ctx context.Context, src fs.Object, remote string) (fs.Object, error) {
+	srcObj, ok := src.(*Object)
+	if !ok {
+		fs.Debugf(src, "Can't move - not same remote type")
+		return nil, fs.ErrorCantMove
+	}
+	srcShare, srcPath := split(srcObj.absPath())
+	dstShare, dstPath := split(path.Join(f.root, remote))
+	if srcShare != dstShare {
+		fs.Debugf(src, "Can't move - not same share")
+		return nil, fs.ErrorCantMove
+	}
+	err := f.mkParentDir(remote)
+	if err != nil {
+		return nil, errors.Wrap(err, "Move mkParentDir failed")
+	}
+	c, err := f.getConnection()
+	if err != nil {
+		return nil, errors.Wrap(err, "Move")
+	}
+	share, err := c.getShare(dstShare)
+	if err == nil {
+		// SMB won't rename over an existing file so remove it first
+		err = share.Remove(dstPath)
+		if err != nil && os.IsNotExist(err) {
+			err = nil
+		}
+	}
+	if err == nil {
+		err = share.Rename(srcPath, dstPath)
+	}
+	f.putConnection(&c, err)
+	if err != nil {
+		return nil, errors.Wrap(err, "Move Rename failed")
+	}
+	dstObj, err := f.NewObject(ctx, remote)
+	if err != nil {
+		return nil, errors.Wrap(err, "Move NewObject failed")
+	}
+	return dstObj, nil
+}
+
+// DirMove moves src, srcRemote to this remote at dstRemote
+// using server side move operations.
+//
+// Will only be called if src.Fs().Name() == f.Name()
+//
+// If it isn't possible then return fs.ErrorCantDirMove
+//
+// If destination exists then return fs.ErrorDirExists
+func (f *Fs) DirMove(ctx context.Context, src fs.Fs, srcRemote, dstRemote string) error {
+	srcFs, ok := src.(*Fs)
+	if !ok {
+		fs.Debugf(srcFs, "Can't move directory - not same remote type")
+		return fs.ErrorCantDirMove
+	}
+	srcShare, srcPath := split(path.Join(srcFs.root, srcRemote))
+	dstAbsPath := path.Join(f.root, dstRemote)
+	dstShare, dstPath := split(dstAbsPath)
+	if srcShare != dstShare || srcPath == "" || dstPath == "" {
+		fs.Debugf(srcFs, "Can't move directory - not same share")
+		return fs.ErrorCantDirMove
+	}
+
+	// Check if destination exists
+	ok, err := f.dirExists(dstAbsPath)
+	if err != nil {
+		return errors.Wrap(err, "DirMove dirExists dst failed")
+	}
+	if ok {
+		return fs.ErrorDirExists
+	}
+
+	// Make sure the parent directory exists
+	err = f.mkdir(path.Dir(dstAbsPath))
+	if err != nil {
+		return errors.Wrap(err, "DirMove mkParentDir dst failed")
+	}
+
+	// Do the move
+	c, err := f.getConnection()
+	if err != nil {
+		return errors.Wrap(err, "DirMove")
+	}
+	share, err := c.getShare(dstShare)
+	if err == nil {
+		err = share.Rename(srcPath, dstPath)
+	}
+	f.putConnection(&c, err)
+	if err != nil {
+		return errors.Wrapf(err, "DirMove Rename(%q,%q) failed", srcPath, dstPath)
+	}
+	return nil
+}
+
+// ------------------------------------------------------------
+
+// Fs returns the parent Fs
+func (o *Object) Fs() fs.Info {
+	return o.fs
+}
+
+// Return a string version
+func (o *Object) String() string {
+	if o == nil {
+		return "<nil>"
+	}
+	return o.remote
+}
+
+// Remote returns the remote path
+func (o *Object) Remote() string {
+	return o.remote
+}
+
+// Hash returns the requested hash of the object content
+//
+// SMB doesn't support any hashes
+func (o *Object) Hash(r hash.Type) (string, error) {
+	return "", hash.ErrUnsupported
+}
+
+// Size returns the size of an object in bytes
+func (o *Object) Size() int64 {
+	return o.size
+}
+
+// ModTime returns the modification time of the object
+func (o *Object) ModTime() time.Time {
+	return o.modTime
+}
+
+// absPath returns the share/path of the object
+func (o *Object) absPath() string {
+	return path.Join(o.fs.root, o.remote)
+}
+
+// setMetadata updates the info in the object from the stat result passed in
+func (o *Object) setMetadata(info os.FileInfo) {
+	o.modTime = info.ModTime()
+	o.size = info.Size()
+	o.mode = info.Mode()
+}
+
+// stat updates the info in the Object
+func (o *Object) stat() error {
+	if _, sharePath := split(o.absPath()); sharePath == "" {
+		return errors.Wrapf(fs.ErrorNotAFile, "%q", o.remote)
+	}
+	info, err := o.fs.stat(o.absPath())
+	if err != nil {
+		if os.IsNotExist(err) {
+			return fs.ErrorObjectNotFound
+		}
+		return errors.Wrap(err, "stat failed")
+	}
+	if info.IsDir() {
+		return errors.Wrapf(fs.ErrorNotAFile, "%q", o.remote)
+	}
+	o.setMetadata(info)
+	return nil
+}
+
+// SetModTime sets the modification time of the object
+//
+// it also updates the info field
+func (o *Object) SetModTime(ctx context.Context, modTime time.Time) error {
+	shareName, sharePath := split(o.absPath())
+	c, err := o.fs.getConnection()
+	if err != nil {
+		return errors.Wrap(err, "SetModTime")
+	}
+	share, err := c.getShare(shareName)
+	if err == nil {
+		err = share.Chtimes(sharePath, modTime, modTime)
+	}
+	o.fs.putConnection(&c, err)
+	if err != nil {
+		return errors.Wrap(err, "SetModTime failed")
+	}
+	err = o.stat()
+	if err != nil {
+		return errors.Wrap(err, "SetModTime stat failed")
+	}
+	return nil
+}
+
+// Storable returns whether the object is a regular file (not a symbolic link)
+func (o *Object) Storable() bool {
+	return o.mode.IsRegular()
+}
+
+// objectReader holds the file open for reading along with the
+// connection it was opened on
+type objectReader struct {
+	fs   *Fs
+	c    *conn
+	file *smb2.File
+}
+
+// Read from the file
+func (r *objectReader) Read(p []byte) (n int, err error) {
+	return r.file.Read(p)
+}
+
+// Close the file and return the connection to the pool
+func (r *objectReader) Close() (err error) {
+	err = r.file.Close()
+	r.fs.putConnection(&r.c, err)
+	return err
+}
+
+// Open an object for read
+func (o *Object) Open(ctx context.Context, options ...fs.OpenOption) (in io.ReadCloser, err error) {
+	var offset, limit int64 = 0, -1
+	for _, option := range options {
+		switch x := option.(type) {
+		case *fs.SeekOption:
+			offset = x.Offset
+		case *fs.RangeOption:
+			offset, limit = x.Decode(o.Size())
+		default:
+			if option.Mandatory() {
+				fs.Logf(o, "Unsupported mandatory option: %v", option)
+			}
+		}
+	}
+	shareName, sharePath := split(o.absPath())
+	c, err := o.fs.getConnection()
+	if err != nil {
+		return nil, errors.Wrap(err, "Open")
+	}
+	share, err := c.getShare(shareName)
+	if err != nil {
+		o.fs.putConnection(&c, err)
+		return nil, errors.Wrap(err, "Open failed")
+	}
+	file, err := share.Open(sharePath)
+	if err != nil {
+		o.fs.putConnection(&c, err)
+		return nil, errors.Wrap(err, "Open failed")
+	}
+	if offset > 0 {
+		off, err := file.Seek(offset, io.SeekStart)
+		if err != nil || off != offset {
+			_ = file.Close()
+			o.fs.putConnection(&c, err)
+			return nil, errors.Wrap(err, "Open Seek failed")
+		}
+	}
+	in = readers.NewLimitedReadCloser(&objectReader{
+		fs:   o.fs,
+		c:    c,
+		file: file,
+	}, limit)
+	return in, nil
+}
+
+// Update the object with the contents of the io.Reader, modTime and size
+//
+// The new object may have been created if an error is returned
+func (o *Object) Update(ctx context.Context, in io.Reader, src fs.ObjectInfo, options ...fs.OpenOption) (err error) {
+	shareName, sharePath := split(o.absPath())
+	c, err := o.fs.getConnection()
+	if err != nil {
+		return errors.Wrap(err, "Update")
+	}
+	defer func() {
+		o.fs.putConnection(&c, err)
+	}()
+	share, err := c.getShare(shareName)
+	if err != nil {
+		return errors.Wrap(err, "Update")
+	}
+	file, err := share.OpenFile(sharePath, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0666)
+	if err != nil {
+		return errors.Wrap(err, "Update Create failed")
+	}
+	// remove the file if upload failed
+	remove := func() {
+		removeErr := share.Remove(sharePath)
+		if removeErr != nil {
+			fs.Debugf(src, "Failed to remove: %v", removeErr)
+		} else {
+			fs.Debugf(src, "Removed after failed upload: %v", err)
+		}
+	}
+	_, err = file.ReadFrom(in)
+	if err != nil {
+		_ = file.Close()
+		remove()
+		return errors.Wrap(err, "Update ReadFrom failed")
+	}
+	err = file.Close()
+	if err != nil {
+		remove()
+		return errors.Wrap(err, "Update Close failed")
+	}
+	modTime := src.ModTime()
+	err = share.Chtimes(sharePath, modTime, modTime)
+	if err != nil {
+		return errors.Wrap(err, "Update SetModTime failed")
+	}
+	info, err := share.Stat(sharePath)
+	if err != nil {
+		return errors.Wrap(err, "Update stat failed")
+	}
+	o.setMetadata(info)
+	return nil
+}
+
+// Remove an object
+func (o *Object) Remove(ctx context.Context) error {
+	shareName, sharePath := split(o.absPath())
+	c, err := o.fs.getConnection()
+	if err != nil {
+		return errors.Wrap(err, "Remove")
+	}
+	share, err := c.getShare(shareName)
+	if err == nil {
+		err = share.Remove(sharePath)
+	}
+	o.fs.putConnection(&c, err)
+	return err
+}
+
+// Check the interfaces are satisfied
+var (
+	_ fs.Fs             = &Fs{}
+	_ fs.PutStreamer    = &Fs{}
+	_ fs.Mover          = &Fs{}
+	_ fs.DirMover       = &Fs{}
+	_ fs.DirSetModTimer = &Fs{}
+	_ fs.Object         = &Object{}
+)
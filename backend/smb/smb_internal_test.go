@@ -0,0 +1,29 @@
+// +build go1.8
+
+package smb
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSplit(t *testing.T) {
+	for _, test := range []struct {
+		in        string
+		wantShare string
+		wantPath  string
+	}{
+		{"", "", ""},
+		{".", "", ""},
+		{"/", "", ""},
+		{"share", "share", ""},
+		{"share/", "share", ""},
+		{"/share/dir", "share", "dir"},
+		{"share/dir/file.txt", "share", "dir/file.txt"},
+	} {
+		share, sharePath := split(test.in)
+		assert.Equal(t, test.wantShare, share, test.in)
+		assert.Equal(t, test.wantPath, sharePath, test.in)
+	}
+}
@@ -1,7 +1,11 @@
 package swift
 
 import (
+	"bytes"
+	"encoding/json"
 	"net/http"
+	"os"
+	"strings"
 
 	"github.com/ncw/swift"
 )
@@ -75,3 +79,172 @@ func (a *auth) CdnUrl() string {
 
 // Check the interfaces are satisfied
 var _ swift.Authenticator = (*auth)(nil)
+
+// appCredAuth is an authenticator for Keystone v3 application
+// credentials which the swift library doesn't support.
+type appCredAuth struct {
+	id      string // application credential ID
+	name    string // application credential name - needs a user
+	secret  string // application credential secret
+	region  string // region to find the endpoint in
+	catalog []v3CatalogEntry
+	token   string
+}
+
+// v3CatalogEntry is a service in the Keystone v3 catalog
+type v3CatalogEntry struct {
+	Type      string `json:"type"`
+	Endpoints []struct {
+		Region    string             `json:"region"`
+		Interface swift.EndpointType `json:"interface"`
+		URL       string             `json:"url"`
+	} `json:"endpoints"`
+}
+
+// v3AppCredRequest is the body of a Keystone v3 auth request using
+// an application credential
+type v3AppCredRequest struct {
+	Auth struct {
+		Identity struct {
+			Methods               []string  `json:"methods"`
+			ApplicationCredential v3AppCred `json:"application_credential"`
+		} `json:"identity"`
+	} `json:"auth"`
+}
+
+// v3AppCred identifies the application credential
+type v3AppCred struct {
+	ID     string  `json:"id,omitempty"`
+	Name   string  `json:"name,omitempty"`
+	Secret string  `json:"secret"`
+	User   *v3User `json:"user,omitempty"`
+}
+
+// v3User identifies the owner of an application credential by name
+type v3User struct {
+	ID     string    `json:"id,omitempty"`
+	Name   string    `json:"name,omitempty"`
+	Domain *v3Domain `json:"domain,omitempty"`
+}
+
+// v3Domain identifies the domain of a user
+type v3Domain struct {
+	ID   string `json:"id,omitempty"`
+	Name string `json:"name,omitempty"`
+}
+
+// applyEnvironment reads any unset parameters from the standard
+// OpenStack environment variables
+func (a *appCredAuth) applyEnvironment() {
+	for _, item := range []struct {
+		param *string
+		name  string
+	}{
+		{&a.id, "OS_APPLICATION_CREDENTIAL_ID"},
+		{&a.name, "OS_APPLICATION_CREDENTIAL_NAME"},
+		{&a.secret, "OS_APPLICATION_CREDENTIAL_SECRET"},
+	} {
+		if *item.param == "" {
+			*item.param = os.Getenv(item.name)
+		}
+	}
+}
+
+// Request creates an http.Request for the auth - return nil if not needed
+func (a *appCredAuth) Request(c *swift.Connection) (*http.Request, error) {
+	a.region = c.Region
+	var v3 v3AppCredRequest
+	v3.Auth.Identity.Methods = []string{"application_credential"}
+	v3.Auth.Identity.ApplicationCredential = v3AppCred{
+		ID:     a.id,
+		Secret: a.secret,
+	}
+	if a.id == "" {
+		user := &v3User{ID: c.UserId}
+		if c.UserId == "" {
+			user.Name = c.UserName
+			switch {
+			case c.Domain != "":
+				user.Domain = &v3Domain{Name: c.Domain}
+			case c.DomainId != "":
+				user.Domain = &v3Domain{ID: c.DomainId}
+			default:
+				user.Domain = &v3Domain{Name: "Default"}
+			}
+		}
+		v3.Auth.Identity.ApplicationCredential.Name = a.name
+		v3.Auth.Identity.ApplicationCredential.User = user
+	}
+	body, err := json.Marshal(&v3)
+	if err != nil {
+		return nil, err
+	}
+	url := c.AuthUrl
+	if !strings.HasSuffix(url, "/") {
+		url += "/"
+	}
+	url += "auth/tokens"
+	req, err := http.NewRequest("POST", url, bytes.NewBuffer(body))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", c.UserAgent)
+	return req, nil
+}
+
+// Response parses the http.Response
+func (a *appCredAuth) Response(resp *http.Response) error {
+	var result struct {
+		Token struct {
+			Catalog []v3CatalogEntry `json:"catalog"`
+		} `json:"token"`
+	}
+	err := json.NewDecoder(resp.Body).Decode(&result)
+	if err != nil {
+		return err
+	}
+	a.catalog = result.Token.Catalog
+	a.token = resp.Header.Get("X-Subject-Token")
+	return nil
+}
+
+// The public storage URL - set Internal to true to read
+// internal/service net URL
+func (a *appCredAuth) StorageUrl(Internal bool) string {
+	if Internal {
+		return a.StorageUrlForEndpoint(swift.EndpointTypeInternal)
+	}
+	return a.StorageUrlForEndpoint(swift.EndpointTypePublic)
+}
+
+// StorageUrlForEndpoint returns the storage URL for the endpoint type
+func (a *appCredAuth) StorageUrlForEndpoint(endpointType swift.EndpointType) string {
+	for _, entry := range a.catalog {
+		if entry.Type != "object-store" {
+			continue
+		}
+		for _, endpoint := range entry.Endpoints {
+			if endpoint.Interface == endpointType && (a.region == "" || a.region == endpoint.Region) {
+				return endpoint.URL
+			}
+		}
+	}
+	return ""
+}
+
+// The access token
+func (a *appCredAuth) Token() string {
+	return a.token
+}
+
+// The CDN url if available
+func (a *appCredAuth) CdnUrl() string {
+	return ""
+}
+
+// Check the interfaces are satisfied
+var (
+	_ swift.Authenticator               = (*appCredAuth)(nil)
+	_ swift.CustomEndpointAuthenticator = (*appCredAuth)(nil)
+)
@@ -4,8 +4,10 @@ package swift
 import (
 	"bufio"
 	"bytes"
+	"encoding/json"
 	"fmt"
 	"io"
+	"net/url"
 	"path"
 	"regexp"
 	"strconv"
@@ -21,8 +23,10 @@ import (
 	"github.com/ncw/rclone/fs/hash"
 	"github.com/ncw/rclone/fs/operations"
 	"github.com/ncw/rclone/fs/walk"
+	"github.com/ncw/rclone/lib/readers"
 	"github.com/ncw/swift"
 	"github.com/pkg/errors"
+	"golang.org/x/net/context"
 )
 
 // Constants
@@ -33,7 +37,8 @@ const (
 
 // Globals
 var (
-	chunkSize = fs.SizeSuffix(5 * 1024 * 1024 * 1024)
+	chunkSize        = fs.SizeSuffix(5 * 1024 * 1024 * 1024)
+	noCheckContainer = flags.BoolP("swift-no-check-container", "", false, "Don't check the container exists or try to create it.")
 )
 
 // Register with Fs
@@ -122,6 +127,40 @@ func init() {
 				Help:  "Admin",
 				Value: "admin",
 			}},
+		}, {
+			Name: "application_credential_id",
+			Help: "Application Credential ID - optional - Keystone v3 only (OS_APPLICATION_CREDENTIAL_ID)",
+		}, {
+			Name: "application_credential_name",
+			Help: "Application Credential name - optional - needs user or user_id (OS_APPLICATION_CREDENTIAL_NAME)",
+		}, {
+			Name: "application_credential_secret",
+			Help: "Application Credential secret - use instead of key (OS_APPLICATION_CREDENTIAL_SECRET)",
+		}, {
+			Name: "segments_container",
+			Help: "Container to store the segments of large files in - optional - default is the container name with _segments added",
+		}, {
+			Name: "no_check_container",
+			Help: "Don't check the container exists or try to create it.",
+			Examples: []fs.OptionExample{{
+				Value: "false",
+				Help:  "Check the container exists and create it if not [default if left blank].",
+			}, {
+				Value: "true",
+				Help:  "Assume the container exists - use if the credentials can't create or check containers.",
+			}},
+		}, {
+			Name: "use_slo",
+			Help: "Upload large files as Static Large Objects instead of Dynamic Large Objects.",
+			Examples: []fs.OptionExample{
+				{
+					Value: "false",
+					Help:  "Use Dynamic Large Objects (DLO) - the default",
+				}, {
+					Value: "true",
+					Help:  "Use Static Large Objects (SLO) - the server must support them",
+				},
+			},
 		},
 		},
 	})
@@ -138,7 +177,9 @@ type Fs struct {
 	containerOKMu     sync.Mutex        // mutex to protect container OK
 	containerOK       bool              // true if we have created the container
 	segmentsContainer string            // container to store the segments (if any) in
+	segmentsPrefix    string            // prefix for the segment names in segmentsContainer
 	noCheckContainer  bool              // don't check the container before creating it
+	useSLO            bool              // upload large files as static large objects
 }
 
 // Object describes a swift object
@@ -213,13 +254,36 @@ func swiftConnection(name string) (*swift.Connection, error) {
 		EndpointType:   swift.EndpointType(config.FileGet(name, "endpoint_type", "public")),
 		ConnectTimeout: 10 * fs.Config.ConnectTimeout, // Use the timeouts in the transport
 		Timeout:        10 * fs.Config.Timeout,        // Use the timeouts in the transport
-		Transport:      fshttp.NewTransport(fs.Config),
+		Transport:      fshttp.NewTransport(fs.ConfigForRemote(name)),
+	}
+	appCred := &appCredAuth{
+		id:     config.FileGet(name, "application_credential_id"),
+		name:   config.FileGet(name, "application_credential_name"),
+		secret: config.FileGet(name, "application_credential_secret"),
 	}
 	if config.FileGetBool(name, "env_auth", false) {
 		err := c.ApplyEnvironment()
 		if err != nil {
 			return nil, errors.Wrap(err, "failed to read environment variables")
 		}
+		appCred.applyEnvironment()
+	}
+	if !c.Authenticated() && appCred.secret != "" {
+		if appCred.id == "" && appCred.name == "" {
+			return nil, errors.New("application credential id or name not found")
+		}
+		if appCred.id == "" && c.UserName == "" && c.UserId == "" {
+			return nil, errors.New("user name or user id needed with application credential name")
+		}
+		if c.AuthUrl == "" {
+			return nil, errors.New("auth not found")
+		}
+		c.AuthVersion = 3
+		c.Auth = appCred
+		err := c.Authenticate()
+		if err != nil {
+			return nil, err
+		}
 	}
 	if !c.Authenticated() {
 		if c.UserName == "" && c.UserId == "" {
@@ -261,6 +325,13 @@ func NewFsWithConnection(name, root string, c *swift.Connection, noCheckContaine
 		segmentsContainer: container + "_segments",
 		root:              directory,
 		noCheckContainer:  noCheckContainer,
+		useSLO:            config.FileGetBool(name, "use_slo", false),
+	}
+	if segmentsContainer := config.FileGet(name, "segments_container"); segmentsContainer != "" {
+		// segments of different containers may be in the same
+		// segments container so keep them apart
+		f.segmentsContainer = segmentsContainer
+		f.segmentsPrefix = container + "/"
 	}
 	f.features = (&fs.Features{
 		ReadMimeType:  true,
@@ -291,7 +362,12 @@ func NewFs(name, root string) (fs.Fs, error) {
 	if err != nil {
 		return nil, err
 	}
-	return NewFsWithConnection(name, root, c, false)
+	f, err := NewFsWithConnection(name, root, c, false)
+	if sf, ok := f.(*Fs); ok && (*noCheckContainer || config.FileGetBool(name, "no_check_container")) {
+		// Assume the container exists so Mkdir doesn't touch it
+		sf.containerOK = true
+	}
+	return f, err
 }
 
 // Return an Object from a path
@@ -323,7 +399,7 @@ func (f *Fs) newObjectWithInfo(remote string, info *swift.Object) (fs.Object, er
 
 // NewObject finds the Object at remote.  If it can't be found it
 // returns the error fs.ErrorObjectNotFound.
-func (f *Fs) NewObject(remote string) (fs.Object, error) {
+func (f *Fs) NewObject(ctx context.Context, remote string) (fs.Object, error) {
 	return f.newObjectWithInfo(remote, nil)
 }
 
@@ -446,7 +522,7 @@ func (f *Fs) listContainers(dir string) (entries fs.DirEntries, err error) {
 //
 // This should return ErrDirNotFound if the directory isn't
 // found.
-func (f *Fs) List(dir string) (entries fs.DirEntries, err error) {
+func (f *Fs) List(ctx context.Context, dir string) (entries fs.DirEntries, err error) {
 	if f.container == "" {
 		return f.listContainers(dir)
 	}
@@ -469,7 +545,7 @@ func (f *Fs) List(dir string) (entries fs.DirEntries, err error) {
 //
 // Don't implement this unless you have a more efficient way
 // of listing recursively that doing a directory traversal.
-func (f *Fs) ListR(dir string, callback fs.ListRCallback) (err error) {
+func (f *Fs) ListR(ctx context.Context, dir string, callback fs.ListRCallback) (err error) {
 	if f.container == "" {
 		return errors.New("container needed for recursive list")
 	}
@@ -488,23 +564,23 @@ func (f *Fs) ListR(dir string, callback fs.ListRCallback) (err error) {
 // Copy the reader in to the new object which is returned
 //
 // The new object may have been created if an error is returned
-func (f *Fs) Put(in io.Reader, src fs.ObjectInfo, options ...fs.OpenOption) (fs.Object, error) {
+func (f *Fs) Put(ctx context.Context, in io.Reader, src fs.ObjectInfo, options ...fs.OpenOption) (fs.Object, error) {
 	// Temporary Object under construction
 	fs := &Object{
 		fs:      f,
 		remote:  src.Remote(),
 		headers: swift.Headers{}, // Empty object headers to stop readMetaData being called
 	}
-	return fs, fs.Update(in, src, options...)
+	return fs, fs.Update(ctx, in, src, options...)
 }
 
 // PutStream uploads to the remote path with the modTime given of indeterminate size
-func (f *Fs) PutStream(in io.Reader, src fs.ObjectInfo, options ...fs.OpenOption) (fs.Object, error) {
-	return f.Put(in, src, options...)
+func (f *Fs) PutStream(ctx context.Context, in io.Reader, src fs.ObjectInfo, options ...fs.OpenOption) (fs.Object, error) {
+	return f.Put(ctx, in, src, options...)
 }
 
 // Mkdir creates the container if it doesn't exist
-func (f *Fs) Mkdir(dir string) error {
+func (f *Fs) Mkdir(ctx context.Context, dir string) error {
 	f.containerOKMu.Lock()
 	defer f.containerOKMu.Unlock()
 	if f.containerOK {
@@ -531,7 +607,7 @@ func (f *Fs) Mkdir(dir string) error {
 // Rmdir deletes the container if the fs is at the root
 //
 // Returns an error if it isn't empty
-func (f *Fs) Rmdir(dir string) error {
+func (f *Fs) Rmdir(ctx context.Context, dir string) error {
 	f.containerOKMu.Lock()
 	defer f.containerOKMu.Unlock()
 	if f.root != "" || dir != "" {
@@ -552,12 +628,12 @@ func (f *Fs) Precision() time.Duration {
 // Purge deletes all the files and directories
 //
 // Implemented here so we can make sure we delete directory markers
-func (f *Fs) Purge() error {
+func (f *Fs) Purge(ctx context.Context) error {
 	// Delete all the files including the directory markers
 	toBeDeleted := make(chan fs.Object, fs.Config.Transfers)
 	delErr := make(chan error, 1)
 	go func() {
-		delErr <- operations.DeleteFiles(toBeDeleted)
+		delErr <- operations.DeleteFiles(ctx, toBeDeleted)
 	}()
 	err := f.list("", true, func(entry fs.DirEntry) error {
 		if o, ok := entry.(*Object); ok {
@@ -573,7 +649,7 @@ func (f *Fs) Purge() error {
 	if err != nil {
 		return err
 	}
-	return f.Rmdir("")
+	return f.Rmdir(ctx, "")
 }
 
 // Copy src to this remote using server side copy operations.
@@ -585,8 +661,8 @@ func (f *Fs) Purge() error {
 // Will only be called if src.Fs().Name() == f.Name()
 //
 // If it isn't possible then return fs.ErrorCantCopy
-func (f *Fs) Copy(src fs.Object, remote string) (fs.Object, error) {
-	err := f.Mkdir("")
+func (f *Fs) Copy(ctx context.Context, src fs.Object, remote string) (fs.Object, error) {
+	err := f.Mkdir(ctx, "")
 	if err != nil {
 		return nil, err
 	}
@@ -600,7 +676,7 @@ func (f *Fs) Copy(src fs.Object, remote string) (fs.Object, error) {
 	if err != nil {
 		return nil, err
 	}
-	return f.NewObject(remote)
+	return f.NewObject(ctx, remote)
 }
 
 // Hashes returns the supported hash sets.
@@ -672,6 +748,16 @@ func (o *Object) isStaticLargeObject() (bool, error) {
 	return o.hasHeader("X-Static-Large-Object")
 }
 
+// isLargeObject checks whether the object is a dynamic or static
+// large object
+func (o *Object) isLargeObject() (bool, error) {
+	isDynamicLargeObject, err := o.isDynamicLargeObject()
+	if err != nil || isDynamicLargeObject {
+		return isDynamicLargeObject, err
+	}
+	return o.isStaticLargeObject()
+}
+
 // Size returns the size of an object in bytes
 func (o *Object) Size() int64 {
 	return o.info.Bytes
@@ -718,7 +804,7 @@ func (o *Object) ModTime() time.Time {
 }
 
 // SetModTime sets the modification time of the local fs object
-func (o *Object) SetModTime(modTime time.Time) error {
+func (o *Object) SetModTime(ctx context.Context, modTime time.Time) error {
 	err := o.readMetaData()
 	if err != nil {
 		return err
@@ -747,7 +833,7 @@ func (o *Object) Storable() bool {
 }
 
 // Open an object for read
-func (o *Object) Open(options ...fs.OpenOption) (in io.ReadCloser, err error) {
+func (o *Object) Open(ctx context.Context, options ...fs.OpenOption) (in io.ReadCloser, err error) {
 	headers := fs.OpenOptionHeaders(options)
 	_, isRanging := headers["Range"]
 	in, _, err = o.fs.c.ObjectOpen(o.fs.container, o.fs.root+o.remote, !isRanging, headers)
@@ -766,7 +852,7 @@ func min(x, y int64) int64 {
 //
 // if except is passed in then segments with that prefix won't be deleted
 func (o *Object) removeSegments(except string) error {
-	segmentsRoot := o.fs.root + o.remote + "/"
+	segmentsRoot := o.fs.segmentsPrefix + o.fs.root + o.remote + "/"
 	err := o.fs.listContainerRoot(o.fs.segmentsContainer, segmentsRoot, "", true, func(remote string, object *swift.Object, isDirectory bool) error {
 		if isDirectory {
 			return nil
@@ -823,8 +909,9 @@ func (o *Object) updateChunks(in0 io.Reader, headers swift.Headers, size int64,
 	left := size
 	i := 0
 	uniquePrefix := fmt.Sprintf("%s/%d", swift.TimeToFloatString(time.Now()), size)
-	segmentsPath := fmt.Sprintf("%s%s/%s", o.fs.root, o.remote, uniquePrefix)
+	segmentsPath := fmt.Sprintf("%s%s%s/%s", o.fs.segmentsPrefix, o.fs.root, o.remote, uniquePrefix)
 	in := bufio.NewReader(in0)
+	var segments []sloSegment
 	for {
 		// can we read at least one byte?
 		if _, err := in.Peek(1); err != nil {
@@ -840,15 +927,24 @@ func (o *Object) updateChunks(in0 io.Reader, headers swift.Headers, size int64,
 			headers["Content-Length"] = strconv.FormatInt(n, 10) // set Content-Length as we know it
 			left -= n
 		}
-		segmentReader := io.LimitReader(in, n)
+		segmentReader := readers.NewCountingReader(io.LimitReader(in, n))
 		segmentPath := fmt.Sprintf("%s/%08d", segmentsPath, i)
 		fs.Debugf(o, "Uploading segment file %q into %q", segmentPath, o.fs.segmentsContainer)
-		_, err := o.fs.c.ObjectPut(o.fs.segmentsContainer, segmentPath, segmentReader, true, "", "", headers)
+		segmentHeaders, err := o.fs.c.ObjectPut(o.fs.segmentsContainer, segmentPath, segmentReader, true, "", "", headers)
 		if err != nil {
 			return "", err
 		}
+		segments = append(segments, sloSegment{
+			Path: o.fs.segmentsContainer + "/" + segmentPath,
+			Etag: segmentHeaders["Etag"],
+			Size: int64(segmentReader.BytesRead()),
+		})
 		i++
 	}
+	delete(headers, "Content-Length")
+	if o.fs.useSLO {
+		return uniquePrefix + "/", o.putSLOManifest(segments, headers, contentType)
+	}
 	// Upload the manifest
 	headers["X-Object-Manifest"] = urlEncode(fmt.Sprintf("%s/%s", o.fs.segmentsContainer, segmentsPath))
 	headers["Content-Length"] = "0" // set Content-Length as we know it
@@ -858,22 +954,52 @@ func (o *Object) updateChunks(in0 io.Reader, headers swift.Headers, size int64,
 	return uniquePrefix + "/", err
 }
 
+// sloSegment is an entry in a static large object manifest
+type sloSegment struct {
+	Path string `json:"path"`
+	Etag string `json:"etag"`
+	Size int64  `json:"size_bytes"`
+}
+
+// putSLOManifest uploads the manifest of a static large object made
+// of segments
+func (o *Object) putSLOManifest(segments []sloSegment, headers swift.Headers, contentType string) error {
+	manifest, err := json.Marshal(segments)
+	if err != nil {
+		return err
+	}
+	headers["Content-Type"] = contentType
+	_, _, err = o.fs.c.Call(o.fs.c.StorageUrl, swift.RequestOpts{
+		Container:  o.fs.container,
+		ObjectName: o.fs.root + o.remote,
+		Operation:  "PUT",
+		Parameters: url.Values{"multipart-manifest": []string{"put"}},
+		Headers:    headers,
+		Body:       bytes.NewReader(manifest),
+		NoResponse: true,
+		OnReAuth: func() (string, error) {
+			return o.fs.c.StorageUrl, nil
+		},
+	})
+	return err
+}
+
 // Update the object with the contents of the io.Reader, modTime and size
 //
 // The new object may have been created if an error is returned
-func (o *Object) Update(in io.Reader, src fs.ObjectInfo, options ...fs.OpenOption) error {
+func (o *Object) Update(ctx context.Context, in io.Reader, src fs.ObjectInfo, options ...fs.OpenOption) error {
 	if o.fs.container == "" {
 		return fserrors.FatalError(errors.New("container name needed in remote"))
 	}
-	err := o.fs.Mkdir("")
+	err := o.fs.Mkdir(ctx, "")
 	if err != nil {
 		return err
 	}
 	size := src.Size()
 	modTime := src.ModTime()
 
-	// Note whether this is a dynamic large object before starting
-	isDynamicLargeObject, err := o.isDynamicLargeObject()
+	// Note whether this is a large object before starting
+	isLargeObject, err := o.isLargeObject()
 	if err != nil {
 		return err
 	}
@@ -883,6 +1009,7 @@ func (o *Object) Update(in io.Reader, src fs.ObjectInfo, options ...fs.OpenOptio
 	m.SetModTime(modTime)
 	contentType := fs.MimeType(src)
 	headers := m.ObjectHeaders()
+	fs.OpenOptionAddHeaders(options, headers)
 	uniquePrefix := ""
 	if size > int64(chunkSize) || size == -1 {
 		uniquePrefix, err = o.updateChunks(in, headers, size, contentType)
@@ -897,8 +1024,8 @@ func (o *Object) Update(in io.Reader, src fs.ObjectInfo, options ...fs.OpenOptio
 		}
 	}
 
-	// If file was a dynamic large object then remove old/all segments
-	if isDynamicLargeObject {
+	// If file was a large object then remove old/all segments
+	if isLargeObject {
 		err = o.removeSegments(uniquePrefix)
 		if err != nil {
 			fs.Logf(o, "Failed to remove old segments - carrying on with upload: %v", err)
@@ -911,8 +1038,8 @@ func (o *Object) Update(in io.Reader, src fs.ObjectInfo, options ...fs.OpenOptio
 }
 
 // Remove an object
-func (o *Object) Remove() error {
-	isDynamicLargeObject, err := o.isDynamicLargeObject()
+func (o *Object) Remove(ctx context.Context) error {
+	isLargeObject, err := o.isLargeObject()
 	if err != nil {
 		return err
 	}
@@ -922,7 +1049,7 @@ func (o *Object) Remove() error {
 		return err
 	}
 	// ...then segments if required
-	if isDynamicLargeObject {
+	if isLargeObject {
 		err = o.removeSegments("")
 		if err != nil {
 			return err
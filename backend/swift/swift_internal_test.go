@@ -1,6 +1,22 @@
 package swift
 
-import "testing"
+import (
+	"bytes"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/ncw/rclone/fs"
+	"github.com/ncw/rclone/fs/config"
+	"github.com/ncw/rclone/fs/object"
+	"github.com/ncw/swift"
+	"github.com/ncw/swift/swifttest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/net/context"
+)
 
 func TestInternalUrlEncode(t *testing.T) {
 	for _, test := range []struct {
@@ -23,3 +39,105 @@ func TestInternalUrlEncode(t *testing.T) {
 		}
 	}
 }
+
+func TestInternalAppCredAuth(t *testing.T) {
+	c := &swift.Connection{
+		AuthUrl:  "https://keystone.example.com/v3",
+		UserName: "user",
+		Domain:   "domain",
+		Region:   "RegionTwo",
+	}
+
+	// by ID
+	a := &appCredAuth{id: "ID", secret: "SECRET"}
+	req, err := a.Request(c)
+	require.NoError(t, err)
+	assert.Equal(t, "https://keystone.example.com/v3/auth/tokens", req.URL.String())
+	body, err := ioutil.ReadAll(req.Body)
+	require.NoError(t, err)
+	assert.JSONEq(t, `{"auth":{"identity":{"methods":["application_credential"],"application_credential":{"id":"ID","secret":"SECRET"}}}}`, string(body))
+
+	// by name
+	a = &appCredAuth{name: "NAME", secret: "SECRET"}
+	req, err = a.Request(c)
+	require.NoError(t, err)
+	body, err = ioutil.ReadAll(req.Body)
+	require.NoError(t, err)
+	assert.JSONEq(t, `{"auth":{"identity":{"methods":["application_credential"],"application_credential":{"name":"NAME","secret":"SECRET","user":{"name":"user","domain":{"name":"domain"}}}}}}`, string(body))
+
+	// parse the response
+	resp := &http.Response{
+		Header: http.Header{"X-Subject-Token": {"TOKEN"}},
+		Body: ioutil.NopCloser(bytes.NewBufferString(`{"token":{"catalog":[
+{"type":"identity","endpoints":[{"region":"RegionTwo","interface":"public","url":"https://keystone.example.com"}]},
+{"type":"object-store","endpoints":[
+{"region":"RegionOne","interface":"public","url":"https://one.example.com/v1/AUTH_x"},
+{"region":"RegionTwo","interface":"public","url":"https://two.example.com/v1/AUTH_x"},
+{"region":"RegionTwo","interface":"internal","url":"https://internal.example.com/v1/AUTH_x"}]}]}}`)),
+	}
+	require.NoError(t, a.Response(resp))
+	assert.Equal(t, "TOKEN", a.Token())
+	assert.Equal(t, "https://two.example.com/v1/AUTH_x", a.StorageUrl(false))
+	assert.Equal(t, "https://internal.example.com/v1/AUTH_x", a.StorageUrl(true))
+	assert.Equal(t, "", a.StorageUrlForEndpoint(swift.EndpointTypeAdmin))
+}
+
+func TestInternalStaticLargeObject(t *testing.T) {
+	ctx := context.Background()
+	config.LoadConfig()
+	srv, err := swifttest.NewSwiftServer("localhost")
+	require.NoError(t, err)
+	defer srv.Close()
+	c := &swift.Connection{
+		UserName: swifttest.TEST_ACCOUNT,
+		ApiKey:   swifttest.TEST_ACCOUNT,
+		AuthUrl:  srv.AuthURL,
+	}
+	require.NoError(t, c.Authenticate())
+
+	oldChunkSize := chunkSize
+	chunkSize = 1024
+	defer func() { chunkSize = oldChunkSize }()
+
+	fi, err := NewFsWithConnection("TestSwiftSLO", "container", c, false)
+	require.NoError(t, err)
+	f := fi.(*Fs)
+	f.useSLO = true
+
+	contents := bytes.Repeat([]byte("potato"), 1000)
+	modTime := time.Date(2001, 2, 3, 4, 5, 6, 0, time.UTC)
+	src := object.NewStaticObjectInfo("file.txt", modTime, int64(len(contents)), true, nil, nil)
+	o, err := f.Put(ctx, bytes.NewReader(contents), src)
+	require.NoError(t, err)
+
+	_, headers, err := c.Object("container", "file.txt")
+	require.NoError(t, err)
+	assert.True(t, headers.IsLargeObjectSLO())
+	_, segments, err := c.LargeObjectGetSegments("container", "file.txt")
+	require.NoError(t, err)
+	assert.Equal(t, 6, len(segments))
+
+	in, err := o.Open(ctx)
+	require.NoError(t, err)
+	got, err := ioutil.ReadAll(in)
+	require.NoError(t, err)
+	require.NoError(t, in.Close())
+	assert.Equal(t, contents, got)
+	assert.True(t, modTime.Equal(o.ModTime()))
+
+	// removing it should remove the segments too
+	require.NoError(t, o.Remove(ctx))
+	objects, err := c.ObjectNamesAll("container_segments", nil)
+	if err != swift.ContainerNotFound {
+		require.NoError(t, err)
+		assert.Equal(t, 0, len(objects))
+	}
+	_, err = f.NewObject(ctx, "file.txt")
+	assert.Equal(t, fs.ErrorObjectNotFound, err)
+}
+
+func TestInternalSLOManifest(t *testing.T) {
+	manifest, err := json.Marshal([]sloSegment{{Path: "c_segments/a/00000000", Etag: "abc", Size: 10}})
+	require.NoError(t, err)
+	assert.Equal(t, `[{"path":"c_segments/a/00000000","etag":"abc","size_bytes":10}]`, string(manifest))
+}
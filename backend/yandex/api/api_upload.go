@@ -13,7 +13,9 @@ const RootAddr = "https://cloud-api.yandex.com" //also https://cloud-api.yandex.
 func (c *Client) setRequestScope(req *http.Request) {
 	req.Header.Add("Accept", "application/json")
 	req.Header.Add("Content-Type", "application/json")
-	req.Header.Add("Authorization", "OAuth "+c.token)
+	if c.token != "" {
+		req.Header.Add("Authorization", "OAuth "+c.token)
+	}
 }
 
 func (c *Client) scopedRequest(method, urlPath string, body io.Reader) (*http.Request, error) {
@@ -18,7 +18,9 @@ func createPostRequest(client *Client, path string, params map[string]interface{
 
 func createRequest(client *Client, method string, path string, parameters map[string]interface{}) *HTTPRequest {
 	var headers = make(map[string][]string)
-	headers["Authorization"] = []string{"OAuth " + client.token}
+	if client.token != "" {
+		headers["Authorization"] = []string{"OAuth " + client.token}
+	}
 	return &HTTPRequest{
 		Method:     method,
 		Path:       path,
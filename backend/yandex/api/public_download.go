@@ -0,0 +1,40 @@
+package src
+
+import (
+	"io"
+	"net/url"
+)
+
+// PublicDownload will get the data at path inside the public resource
+// identified by publicKey supplying the extra headers
+func (c *Client) PublicDownload(publicKey string, remotePath string, headers map[string]string) (io.ReadCloser, error) {
+	ur, err := c.PublicDownloadRequest(publicKey, remotePath)
+	if err != nil {
+		return nil, err
+	}
+	return c.PerformDownload(ur.HRef, headers)
+}
+
+// PublicDownloadRequest will make a download request for a path inside
+// a public resource and return a URL to download data from.
+func (c *Client) PublicDownloadRequest(publicKey string, remotePath string) (ur *DownloadResponse, err error) {
+	values := url.Values{}
+	values.Add("public_key", publicKey)
+	values.Add("path", remotePath)
+
+	req, err := c.scopedRequest("GET", "/v1/disk/public/resources/download?"+values.Encode(), nil)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := c.HTTPClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	if err := CheckAPIError(resp); err != nil {
+		return nil, err
+	}
+	defer CheckClose(resp.Body, &err)
+
+	return ParseDownloadResponse(resp.Body)
+}
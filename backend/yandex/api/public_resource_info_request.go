@@ -0,0 +1,15 @@
+package src
+
+// NewPublicResourceInfoRequest create new ResourceInfo Request for a
+// path inside the public resource identified by publicKey
+//
+// publicKey may be the public key or the public URL of the resource.
+// No authorization is needed for this request.
+func (c *Client) NewPublicResourceInfoRequest(publicKey string, path string, options ...ResourceInfoRequestOptions) *ResourceInfoRequest {
+	req := createResourceInfoRequest(c, "/public/resources", path, options...)
+	req.Parameters["public_key"] = publicKey
+	return &ResourceInfoRequest{
+		client:      c,
+		HTTPRequest: req,
+	}
+}
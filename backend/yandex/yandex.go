@@ -22,6 +22,7 @@ import (
 	"github.com/ncw/rclone/lib/oauthutil"
 	"github.com/ncw/rclone/lib/readers"
 	"github.com/pkg/errors"
+	"golang.org/x/net/context"
 	"golang.org/x/oauth2"
 )
 
@@ -43,6 +44,7 @@ var (
 		ClientSecret: obscure.MustReveal(rcloneEncryptedClientSecret),
 		RedirectURL:  oauthutil.RedirectURL,
 	}
+	errorReadOnly = errors.New("yandex remote is read only as it was configured with a public link")
 )
 
 // Register with Fs
@@ -52,6 +54,10 @@ func init() {
 		Description: "Yandex Disk",
 		NewFs:       NewFs,
 		Config: func(name string) {
+			if config.FileGet(name, "public_link") != "" {
+				// No login is needed to read public links
+				return
+			}
 			err := oauthutil.Config("yandex", name, oauthConfig)
 			if err != nil {
 				log.Fatalf("Failed to configure token: %v", err)
@@ -63,6 +69,10 @@ func init() {
 		}, {
 			Name: config.ConfigClientSecret,
 			Help: "Yandex Client Secret - leave blank normally.",
+		}, {
+			Name:     "public_link",
+			Help:     "Public link to a shared folder to download from - leave blank normally.\nIf set the remote is read only and no login is needed.",
+			Optional: true,
 		}},
 	})
 }
@@ -74,6 +84,7 @@ type Fs struct {
 	features   *fs.Features   // optional features
 	yd         *yandex.Client // client for rest api
 	diskRoot   string         //root path with "disk:/" container name
+	publicKey  string         // public link being read if set
 	mkdircache map[string]int
 }
 
@@ -125,30 +136,45 @@ func getAccessToken(name string) (*oauth2.Token, error) {
 
 // NewFs constructs an Fs from the path, container:path
 func NewFs(name, root string) (fs.Fs, error) {
-	//read access token from config
-	token, err := getAccessToken(name)
-	if err != nil {
-		return nil, err
+	publicKey := config.FileGet(name, "public_link")
+
+	//read access token from config - public links don't need one
+	accessToken := ""
+	if publicKey == "" {
+		token, err := getAccessToken(name)
+		if err != nil {
+			return nil, err
+		}
+		accessToken = token.AccessToken
 	}
 
 	//create new client
-	yandexDisk := yandex.NewClient(token.AccessToken, fshttp.NewClient(fs.Config))
+	yandexDisk := yandex.NewClient(accessToken, fshttp.NewClient(fs.ConfigForRemote(name)))
 
 	f := &Fs{
-		name: name,
-		yd:   yandexDisk,
+		name:      name,
+		yd:        yandexDisk,
+		publicKey: publicKey,
 	}
 	f.features = (&fs.Features{
 		ReadMimeType:            true,
 		WriteMimeType:           true,
 		CanHaveEmptyDirectories: true,
 	}).Fill(f)
+	if publicKey != "" {
+		// the flat file list and trash only exist for the
+		// user's own disk
+		f.features.ListR = nil
+		f.features.CleanUp = nil
+		f.features.Purge = nil
+		f.features.PutStream = nil
+	}
 	f.setRoot(root)
 
 	// Check to see if the object exists and is a file
 	//request object meta info
 	var opt2 yandex.ResourceInfoRequestOptions
-	if ResourceInfoResponse, err := yandexDisk.NewResourceInfoRequest(root, opt2).Exec(); err != nil {
+	if ResourceInfoResponse, err := f.resourceInfo(strings.TrimSuffix(f.diskRoot, "/"), opt2); err != nil {
 		//return err
 	} else {
 		if ResourceInfoResponse.ResourceType == "file" {
@@ -167,15 +193,29 @@ func (f *Fs) setRoot(root string) {
 	f.root = strings.Trim(root, "/")
 	//Set disk root path.
 	//Adding "disk:" to root path as all paths on disk start with it
+	//Paths inside a public link are relative to the link instead
+	prefix := "disk:/"
+	if f.publicKey != "" {
+		prefix = "/"
+	}
 	var diskRoot string
 	if f.root == "" {
-		diskRoot = "disk:/"
+		diskRoot = prefix
 	} else {
-		diskRoot = "disk:/" + f.root + "/"
+		diskRoot = prefix + f.root + "/"
 	}
 	f.diskRoot = diskRoot
 }
 
+// resourceInfo reads the info for path, looking inside the public
+// link if one is configured
+func (f *Fs) resourceInfo(path string, opt yandex.ResourceInfoRequestOptions) (*yandex.ResourceInfoResponse, error) {
+	if f.publicKey != "" {
+		return f.yd.NewPublicResourceInfoRequest(f.publicKey, path, opt).Exec()
+	}
+	return f.yd.NewResourceInfoRequest(path, opt).Exec()
+}
+
 // Convert a list item into a DirEntry
 func (f *Fs) itemToDirEntry(remote string, object *yandex.ResourceInfoResponse) (fs.DirEntry, error) {
 	switch object.ResourceType {
@@ -207,7 +247,7 @@ func (f *Fs) itemToDirEntry(remote string, object *yandex.ResourceInfoResponse)
 //
 // This should return ErrDirNotFound if the directory isn't
 // found.
-func (f *Fs) List(dir string) (entries fs.DirEntries, err error) {
+func (f *Fs) List(ctx context.Context, dir string) (entries fs.DirEntries, err error) {
 	//request object meta info
 	var opt yandex.ResourceInfoRequestOptions
 	root := f.diskRoot
@@ -222,7 +262,7 @@ func (f *Fs) List(dir string) (entries fs.DirEntries, err error) {
 
 	//query each page of list until itemCount is less then limit
 	for {
-		ResourceInfoResponse, err := f.yd.NewResourceInfoRequest(root, opt).Exec()
+		ResourceInfoResponse, err := f.resourceInfo(root, opt)
 		if err != nil {
 			yErr, ok := err.(yandex.DiskClientError)
 			if ok && yErr.Code == "DiskNotFoundError" {
@@ -272,7 +312,7 @@ func (f *Fs) List(dir string) (entries fs.DirEntries, err error) {
 //
 // Don't implement this unless you have a more efficient way
 // of listing recursively that doing a directory traversal.
-func (f *Fs) ListR(dir string, callback fs.ListRCallback) (err error) {
+func (f *Fs) ListR(ctx context.Context, dir string, callback fs.ListRCallback) (err error) {
 	//request files list. list is divided into pages. We send request for each page
 	//items per page is limited by limit
 	//TODO may be add config parameter for the items per page limit
@@ -334,7 +374,7 @@ func (f *Fs) ListR(dir string, callback fs.ListRCallback) (err error) {
 
 // NewObject finds the Object at remote.  If it can't be found it
 // returns the error fs.ErrorObjectNotFound.
-func (f *Fs) NewObject(remote string) (fs.Object, error) {
+func (f *Fs) NewObject(ctx context.Context, remote string) (fs.Object, error) {
 	return f.newObjectWithInfo(remote, nil)
 }
 
@@ -394,7 +434,7 @@ func (o *Object) readMetaData() (err error) {
 
 	//request meta info
 	var opt2 yandex.ResourceInfoRequestOptions
-	ResourceInfoResponse, err := o.fs.yd.NewResourceInfoRequest(o.remotePath(), opt2).Exec()
+	ResourceInfoResponse, err := o.fs.resourceInfo(o.remotePath(), opt2)
 	if err != nil {
 		if dcErr, ok := err.(yandex.DiskClientError); ok {
 			if dcErr.Code == "DiskNotFoundError" {
@@ -411,7 +451,10 @@ func (o *Object) readMetaData() (err error) {
 // Copy the reader in to the new object which is returned
 //
 // The new object may have been created if an error is returned
-func (f *Fs) Put(in io.Reader, src fs.ObjectInfo, options ...fs.OpenOption) (fs.Object, error) {
+func (f *Fs) Put(ctx context.Context, in io.Reader, src fs.ObjectInfo, options ...fs.OpenOption) (fs.Object, error) {
+	if f.publicKey != "" {
+		return nil, errorReadOnly
+	}
 	remote := src.Remote()
 	size := src.Size()
 	modTime := src.ModTime()
@@ -423,16 +466,19 @@ func (f *Fs) Put(in io.Reader, src fs.ObjectInfo, options ...fs.OpenOption) (fs.
 		modTime: modTime,
 	}
 	//TODO maybe read metadata after upload to check if file uploaded successfully
-	return o, o.Update(in, src, options...)
+	return o, o.Update(ctx, in, src, options...)
 }
 
 // PutStream uploads to the remote path with the modTime given of indeterminate size
-func (f *Fs) PutStream(in io.Reader, src fs.ObjectInfo, options ...fs.OpenOption) (fs.Object, error) {
-	return f.Put(in, src, options...)
+func (f *Fs) PutStream(ctx context.Context, in io.Reader, src fs.ObjectInfo, options ...fs.OpenOption) (fs.Object, error) {
+	return f.Put(ctx, in, src, options...)
 }
 
 // Mkdir creates the container if it doesn't exist
-func (f *Fs) Mkdir(dir string) error {
+func (f *Fs) Mkdir(ctx context.Context, dir string) error {
+	if f.publicKey != "" {
+		return errorReadOnly
+	}
 	root := f.diskRoot
 	if dir != "" {
 		root += dir + "/"
@@ -443,13 +489,16 @@ func (f *Fs) Mkdir(dir string) error {
 // Rmdir deletes the container
 //
 // Returns an error if it isn't empty
-func (f *Fs) Rmdir(dir string) error {
+func (f *Fs) Rmdir(ctx context.Context, dir string) error {
 	return f.purgeCheck(dir, true)
 }
 
 // purgeCheck remotes the root directory, if check is set then it
 // refuses to do so if it has anything in
 func (f *Fs) purgeCheck(dir string, check bool) error {
+	if f.publicKey != "" {
+		return errorReadOnly
+	}
 	root := f.diskRoot
 	if dir != "" {
 		root += dir + "/"
@@ -480,12 +529,12 @@ func (f *Fs) Precision() time.Duration {
 // Optional interface: Only implement this if you have a way of
 // deleting all the files quicker than just running Remove() on the
 // result of List()
-func (f *Fs) Purge() error {
+func (f *Fs) Purge(ctx context.Context) error {
 	return f.purgeCheck("", false)
 }
 
 // CleanUp permanently deletes all trashed files/folders
-func (f *Fs) CleanUp() error {
+func (f *Fs) CleanUp(ctx context.Context) error {
 	return f.yd.EmptyTrash()
 }
 
@@ -542,19 +591,28 @@ func (o *Object) ModTime() time.Time {
 }
 
 // Open an object for read
-func (o *Object) Open(options ...fs.OpenOption) (in io.ReadCloser, err error) {
+func (o *Object) Open(ctx context.Context, options ...fs.OpenOption) (in io.ReadCloser, err error) {
+	if o.fs.publicKey != "" {
+		return o.fs.yd.PublicDownload(o.fs.publicKey, o.remotePath(), fs.OpenOptionHeaders(options))
+	}
 	return o.fs.yd.Download(o.remotePath(), fs.OpenOptionHeaders(options))
 }
 
 // Remove an object
-func (o *Object) Remove() error {
+func (o *Object) Remove(ctx context.Context) error {
+	if o.fs.publicKey != "" {
+		return errorReadOnly
+	}
 	return o.fs.yd.Delete(o.remotePath(), true)
 }
 
 // SetModTime sets the modification time of the local fs object
 //
 // Commits the datastore
-func (o *Object) SetModTime(modTime time.Time) error {
+func (o *Object) SetModTime(ctx context.Context, modTime time.Time) error {
+	if o.fs.publicKey != "" {
+		return errorReadOnly
+	}
 	remote := o.remotePath()
 	// set custom_property 'rclone_modified' of object to modTime
 	err := o.fs.yd.SetCustomProperty(remote, "rclone_modified", modTime.Format(time.RFC3339Nano))
@@ -580,7 +638,10 @@ func (o *Object) remotePath() string {
 // Copy the reader into the object updating modTime and size
 //
 // The new object may have been created if an error is returned
-func (o *Object) Update(in0 io.Reader, src fs.ObjectInfo, options ...fs.OpenOption) error {
+func (o *Object) Update(ctx context.Context, in0 io.Reader, src fs.ObjectInfo, options ...fs.OpenOption) error {
+	if o.fs.publicKey != "" {
+		return errorReadOnly
+	}
 	in := readers.NewCountingReader(in0)
 	modTime := src.ModTime()
 
@@ -600,7 +661,7 @@ func (o *Object) Update(in0 io.Reader, src fs.ObjectInfo, options ...fs.OpenOpti
 		o.modTime = modTime
 		o.md5sum = "" // according to unit tests after put the md5 is empty.
 		//and set modTime of uploaded file
-		err = o.SetModTime(modTime)
+		err = o.SetModTime(ctx, modTime)
 	}
 	return err
 }
@@ -32,14 +32,20 @@ docs = [
     "ftp.md",
     "googlecloudstorage.md",
     "drive.md",
+    "hdfs.md",
     "http.md",
     "hubic.md",
+    "jottacloud.md",
+    "mega.md",
+    "memory.md",
     "azureblob.md",
     "onedrive.md",
     "qingstor.md",
     "swift.md",
     "pcloud.md",
     "sftp.md",
+    "sia.md",
+    "smb.md",
     "webdav.md",
     "yandex.md",
 
@@ -0,0 +1,267 @@
+package archive
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"path"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/ncw/rclone/cmd"
+	"github.com/ncw/rclone/fs"
+	"github.com/ncw/rclone/fs/accounting"
+	"github.com/ncw/rclone/fs/config/flags"
+	"github.com/ncw/rclone/fs/object"
+	"github.com/ncw/rclone/fs/operations"
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+	"golang.org/x/net/context"
+)
+
+// Options controls which files Archive moves and what it leaves
+// behind
+type Options struct {
+	OlderThan  fs.Duration   // archive files modified longer ago than this if > 0
+	LargerThan fs.SizeSuffix // archive files bigger than this if >= 0
+	StubSuffix string        // leave a stub with this suffix for each file if set
+	Manifest   string        // append the archived files to this file in the source if set
+}
+
+// Globals
+var (
+	opt = Options{
+		LargerThan: -1,
+	}
+)
+
+func init() {
+	cmd.Root.AddCommand(commandDefintion)
+	flagSet := commandDefintion.Flags()
+	flags.FVarP(flagSet, &opt.OlderThan, "older-than", "", "Archive files older than this in s or suffix ms|s|m|h|d|w|M|y")
+	flags.FVarP(flagSet, &opt.LargerThan, "larger-than", "", "Archive files larger than this in k or suffix b|k|M|G")
+	flagSet.StringVarP(&opt.StubSuffix, "stub-suffix", "", opt.StubSuffix, "Leave a stub file with this suffix in place of each archived file")
+	flagSet.StringVarP(&opt.Manifest, "manifest", "", opt.Manifest, "Record the archived files in this file in source:path")
+}
+
+var commandDefintion = &cobra.Command{
+	Use:   "archive source:path dest:path",
+	Short: `Move old or large files from source to an archive remote.`,
+	Long: `
+Moves the files in ` + "`source:path`" + ` which are older than --older-than
+or larger than --larger-than into the same place in ` + "`dest:path`" + `.
+At least one of these must be given.  If both are given then files
+matching either are archived.
+
+This is for moving cold data from a fast (and expensive) remote to a
+cheaper one, eg
+
+    rclone archive --older-than 90d --larger-than 1G hot:data cold:data
+
+Filters are applied as normal so only the files selected by the
+filters are considered for archiving.  Files are moved as with
+` + "`rclone move`" + ` so will be moved server side if possible, and
+--delete-after-verification can be used to check each archived file
+before the original is deleted.  Empty directories are left in the
+source.
+
+If --stub-suffix is set then a small stub file is left in place of
+each archived file, named after the file with the suffix added.  It
+says where the file was archived to along with its size and
+modification time, which it also has as its own modification time.
+Files ending in the suffix are never archived themselves.
+
+    rclone archive --older-than 1y --stub-suffix .archived hot:data cold:data
+
+If --manifest is set then a line for each archived file is appended
+to the named file in ` + "`source:path`" + ` giving its modification time, size
+and path, separated by tabs, eg
+
+    2017-02-03 04:05:06	1048576	photos/2017/img001.jpg
+
+The manifest itself is never archived.
+
+**Important**: Since this can cause data loss, test first with the
+--dry-run flag.
+`,
+	Run: func(command *cobra.Command, args []string) {
+		cmd.CheckArgs(2, 2, command, args)
+		fsrc, fdst := cmd.NewFsSrcDst(args)
+		cmd.Run(true, true, command, func() error {
+			return Archive(context.Background(), fdst, fsrc, opt)
+		})
+	},
+}
+
+// archived records a file which has been archived
+type archived struct {
+	remote  string
+	size    int64
+	modTime time.Time
+}
+
+// archivedByRemote sorts archived files by their remote
+type archivedByRemote []archived
+
+func (as archivedByRemote) Len() int           { return len(as) }
+func (as archivedByRemote) Swap(i, j int)      { as[i], as[j] = as[j], as[i] }
+func (as archivedByRemote) Less(i, j int) bool { return as[i].remote < as[j].remote }
+
+// selected returns true if o should be archived with opt when the
+// time is now
+func (opt *Options) selected(o fs.Object, now time.Time) bool {
+	remote := o.Remote()
+	if remote == opt.Manifest || (opt.StubSuffix != "" && strings.HasSuffix(remote, opt.StubSuffix)) {
+		return false
+	}
+	if opt.OlderThan > 0 && o.ModTime().Before(now.Add(-time.Duration(opt.OlderThan))) {
+		return true
+	}
+	if opt.LargerThan >= 0 && o.Size() > int64(opt.LargerThan) {
+		return true
+	}
+	return false
+}
+
+// Archive moves the files in fsrc selected by opt into fdst
+func Archive(ctx context.Context, fdst, fsrc fs.Fs, opt Options) error {
+	if opt.OlderThan <= 0 && opt.LargerThan < 0 {
+		return errors.New("need --older-than or --larger-than to select the files to archive")
+	}
+	if operations.Overlapping(fdst, fsrc) {
+		return fs.ErrorCantMoveOverlapping
+	}
+
+	// Find the files to archive
+	now := time.Now()
+	var (
+		mu        sync.Mutex
+		toArchive []fs.Object
+	)
+	err := operations.ListFn(ctx, fsrc, func(o fs.Object) {
+		if opt.selected(o, now) {
+			mu.Lock()
+			toArchive = append(toArchive, o)
+			mu.Unlock()
+		}
+	})
+	if err != nil {
+		return errors.Wrap(err, "failed to list files to archive")
+	}
+	fs.Infof(fsrc, "Archiving %d files to %v", len(toArchive), fdst)
+
+	// Move them with --transfers workers
+	var (
+		wg      sync.WaitGroup
+		in      = make(chan fs.Object, fs.Config.Transfers)
+		done    []archived
+		lastErr error
+	)
+	wg.Add(fs.Config.Transfers)
+	for i := 0; i < fs.Config.Transfers; i++ {
+		go func() {
+			defer wg.Done()
+			for src := range in {
+				entry := archived{remote: src.Remote(), size: src.Size(), modTime: src.ModTime()}
+				err := archiveFile(ctx, fdst, fsrc, src, opt)
+				mu.Lock()
+				if err != nil {
+					lastErr = err
+				} else {
+					done = append(done, entry)
+				}
+				mu.Unlock()
+			}
+		}()
+	}
+	for _, o := range toArchive {
+		in <- o
+	}
+	close(in)
+	wg.Wait()
+
+	if opt.Manifest != "" && len(done) > 0 {
+		err = writeManifest(ctx, fsrc, opt.Manifest, done)
+		if err != nil {
+			fs.CountError(err)
+			fs.Errorf(opt.Manifest, "Failed to write manifest: %v", err)
+			lastErr = err
+		}
+	}
+	return lastErr
+}
+
+// archiveFile moves src into fdst and leaves a stub behind if required
+func archiveFile(ctx context.Context, fdst, fsrc fs.Fs, src fs.Object, opt Options) (err error) {
+	remote := src.Remote()
+	accounting.Stats.Transferring(remote)
+	defer func() {
+		accounting.Stats.DoneTransferring(remote, err == nil)
+	}()
+	dst, err := fdst.NewObject(ctx, remote)
+	if err != nil {
+		dst = nil
+	}
+	size, modTime := src.Size(), src.ModTime()
+	_, err = operations.Move(ctx, fdst, dst, remote, src)
+	if err != nil {
+		return err
+	}
+	if opt.StubSuffix == "" {
+		return nil
+	}
+	stubRemote := remote + opt.StubSuffix
+	if operations.SkipDryRun(stubRemote, "leave archive stub") {
+		return nil
+	}
+	stub := fmt.Sprintf("Archived to: %s\nSize: %d\nModified: %s\n", fdst.Name()+":"+path.Join(fdst.Root(), remote), size, modTime.Format(time.RFC3339Nano))
+	_, err = put(ctx, fsrc, stubRemote, []byte(stub), modTime)
+	if err != nil {
+		fs.CountError(err)
+		fs.Errorf(stubRemote, "Failed to leave archive stub: %v", err)
+		return err
+	}
+	return nil
+}
+
+// writeManifest appends a line for each of done to the manifest
+// called remote in f, creating it if necessary
+func writeManifest(ctx context.Context, f fs.Fs, remote string, done []archived) error {
+	if operations.SkipDryRun(remote, "update manifest") {
+		return nil
+	}
+	var buf bytes.Buffer
+	existing, err := f.NewObject(ctx, remote)
+	found := err == nil
+	if found {
+		in, err := existing.Open(ctx)
+		if err != nil {
+			return errors.Wrap(err, "failed to open manifest")
+		}
+		b, err := ioutil.ReadAll(in)
+		_ = in.Close()
+		if err != nil {
+			return errors.Wrap(err, "failed to read manifest")
+		}
+		buf.Write(b)
+	} else if err != fs.ErrorObjectNotFound {
+		return errors.Wrap(err, "failed to find manifest")
+	}
+	sort.Sort(archivedByRemote(done))
+	for _, entry := range done {
+		fmt.Fprintf(&buf, "%s\t%d\t%s\n", entry.modTime.Local().Format("2006-01-02 15:04:05"), entry.size, entry.remote)
+	}
+	if found {
+		return existing.Update(ctx, &buf, object.NewStaticObjectInfo(remote, time.Now(), int64(buf.Len()), true, nil, f))
+	}
+	_, err = put(ctx, f, remote, buf.Bytes(), time.Now())
+	return err
+}
+
+// put uploads data to remote in f with the modification time given
+func put(ctx context.Context, f fs.Fs, remote string, data []byte, modTime time.Time) (fs.Object, error) {
+	src := object.NewStaticObjectInfo(remote, modTime, int64(len(data)), true, nil, f)
+	return f.Put(ctx, bytes.NewReader(data), src)
+}
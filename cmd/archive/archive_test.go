@@ -0,0 +1,84 @@
+package archive
+
+import (
+	"fmt"
+	"io/ioutil"
+	"path"
+	"testing"
+	"time"
+
+	_ "github.com/ncw/rclone/backend/local"
+	"github.com/ncw/rclone/fs"
+	"github.com/ncw/rclone/fstest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/net/context"
+)
+
+var (
+	t1 = fstest.Time("2001-02-03T04:05:06.499999999Z")
+	t2 = fstest.Time("2011-12-25T12:59:59.123456789Z")
+)
+
+// TestMain drives the tests
+func TestMain(m *testing.M) {
+	fstest.TestMain(m)
+}
+
+// readObject returns the contents of remote in f
+func readObject(t *testing.T, f fs.Fs, remote string) string {
+	o, err := f.NewObject(context.Background(), remote)
+	require.NoError(t, err)
+	in, err := o.Open(context.Background())
+	require.NoError(t, err)
+	defer func() { _ = in.Close() }()
+	b, err := ioutil.ReadAll(in)
+	require.NoError(t, err)
+	return string(b)
+}
+
+func TestArchiveNoCriteria(t *testing.T) {
+	r := fstest.NewRun(t)
+	defer r.Finalise()
+
+	err := Archive(context.Background(), r.Fremote, r.Flocal, Options{LargerThan: -1})
+	assert.Error(t, err)
+}
+
+func TestArchive(t *testing.T) {
+	r := fstest.NewRun(t)
+	defer r.Finalise()
+	now := time.Now()
+	file1 := r.WriteFile("old.txt", "old", t1)
+	file2 := r.WriteFile("sub/big.txt", "big file which is big", now)
+	file3 := r.WriteFile("new.txt", "new", now)
+	file4 := r.WriteFile("old.txt.archived", "stub from before", t2)
+	fstest.CheckItems(t, r.Flocal, file1, file2, file3, file4)
+
+	opt := Options{
+		OlderThan:  fs.Duration(365 * 24 * time.Hour),
+		LargerThan: 10,
+		StubSuffix: ".archived",
+		Manifest:   "MANIFEST",
+	}
+	err := Archive(context.Background(), r.Fremote, r.Flocal, opt)
+	require.NoError(t, err)
+
+	stub := func(item fstest.Item) fstest.Item {
+		content := fmt.Sprintf("Archived to: %s:%s\nSize: %d\nModified: %s\n", r.Fremote.Name(), path.Join(r.Fremote.Root(), item.Path), item.Size, item.ModTime.Format(time.RFC3339Nano))
+		return fstest.NewItem(item.Path+".archived", content, item.ModTime)
+	}
+	fstest.CheckItems(t, r.Fremote, file1, file2)
+	fstest.CheckListingWithPrecision(t, r.Flocal, []fstest.Item{file3, stub(file1), stub(file2), fstest.NewItem("MANIFEST", readObject(t, r.Flocal, "MANIFEST"), now)}, nil, fs.ModTimeNotSupported)
+
+	manifest := fmt.Sprintf("%s\t3\told.txt\n%s\t21\tsub/big.txt\n", t1.Local().Format("2006-01-02 15:04:05"), now.Local().Format("2006-01-02 15:04:05"))
+	assert.Equal(t, manifest, readObject(t, r.Flocal, "MANIFEST"))
+
+	// Archive again to check the manifest is appended to
+	file5 := r.WriteFile("new.txt", "new and now big", now)
+	err = Archive(context.Background(), r.Fremote, r.Flocal, opt)
+	require.NoError(t, err)
+	fstest.CheckItems(t, r.Fremote, file1, file2, file5)
+	manifest += fmt.Sprintf("%s\t15\tnew.txt\n", now.Local().Format("2006-01-02 15:04:05"))
+	assert.Equal(t, manifest, readObject(t, r.Flocal, "MANIFEST"))
+}
@@ -0,0 +1,188 @@
+package backend
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/ncw/rclone/cmd"
+	"github.com/ncw/rclone/fs"
+	"github.com/ncw/rclone/fs/config/flags"
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+	"golang.org/x/net/context"
+)
+
+var (
+	options  []string
+	useJSON  bool
+	helpName = "help"
+)
+
+func init() {
+	cmd.Root.AddCommand(commandDefintion)
+	cmdFlags := commandDefintion.Flags()
+	flags.StringArrayVarP(cmdFlags, &options, "option", "o", options, "Option in the form name=value or name.")
+	flags.BoolVarP(cmdFlags, &useJSON, "json", "", useJSON, "Always output in JSON format.")
+}
+
+var commandDefintion = &cobra.Command{
+	Use:   "backend <command> remote:path [opts] <args>",
+	Short: `Run a backend specific command.`,
+	Long: `
+This runs a backend specific command. The commands themselves (except
+for "help" and "features") are defined by the backends and you should
+see the backend docs for definitions.
+
+You can discover what commands a backend implements by using
+
+    rclone backend help remote:
+    rclone backend help <backendname>
+
+You can also discover which optional features a remote supports with
+
+    rclone backend features remote:
+
+Pass options to the backend command with -o. This should be key=value
+or key, eg:
+
+    rclone backend restore -o priority=Bulk -o lifetime=7 s3:bucket/path
+
+Pass arguments to the backend by placing them on the end of the line
+
+    rclone backend untrash drive:path dir1 dir2
+
+The result is printed as text if it is a string or a list of strings,
+otherwise as JSON.  Use --json to always get JSON output.
+`,
+	Run: func(command *cobra.Command, args []string) {
+		cmd.CheckArgs(2, 1E6, command, args)
+		name, remote := args[0], args[1]
+		cmd.Run(false, false, command, func() error {
+			// show help if remote is a backend name
+			if name == helpName {
+				fsInfo, err := fs.Find(remote)
+				if err == nil {
+					return showHelp(fsInfo)
+				}
+			}
+			// Create remote
+			fsInfo, configName, fsPath, err := fs.ParseRemote(remote)
+			if err != nil {
+				return err
+			}
+			f, err := fsInfo.NewFs(configName, fsPath)
+			if err != nil && err != fs.ErrorIsFile {
+				return err
+			}
+			// Run the command
+			var out interface{}
+			switch name {
+			case helpName:
+				return showHelp(fsInfo)
+			case "features":
+				out = f.Features().Enabled()
+			default:
+				doCommand := f.Features().Command
+				if doCommand == nil {
+					return errors.Errorf("%v: doesn't support backend commands", f)
+				}
+				arg := args[2:]
+				opt := parseOptions(options)
+				out, err = doCommand(context.Background(), name, arg, opt)
+			}
+			if err != nil {
+				if err == fs.ErrorCommandNotFound {
+					return errors.Errorf("%q is not a backend command for %v - see \"rclone backend help %s\"", name, f, remote)
+				}
+				return errors.Wrapf(err, "command %q failed", name)
+			}
+			// Output the result
+			writeJSON := false
+			if useJSON {
+				writeJSON = true
+			} else {
+				switch x := out.(type) {
+				case nil:
+				case string:
+					fmt.Println(out)
+				case []string:
+					for _, line := range x {
+						fmt.Println(line)
+					}
+				default:
+					writeJSON = true
+				}
+			}
+			if writeJSON {
+				raw, err := json.MarshalIndent(out, "", "\t")
+				if err != nil {
+					return errors.Wrap(err, "failed to write JSON")
+				}
+				fmt.Printf("%s\n", raw)
+			}
+			return nil
+		})
+	},
+}
+
+// parseOptions parses options in the form name=value or name into a
+// map - an option without a value is set to "true"
+func parseOptions(options []string) map[string]string {
+	opt := make(map[string]string, len(options))
+	for _, option := range options {
+		equals := strings.IndexRune(option, '=')
+		name := option
+		value := "true"
+		if equals >= 0 {
+			name = option[:equals]
+			value = option[equals+1:]
+		}
+		opt[name] = value
+	}
+	return opt
+}
+
+// showHelp shows help for the backend commands of fsInfo
+func showHelp(fsInfo *fs.RegInfo) error {
+	cmds := fsInfo.CommandHelp
+	name := fsInfo.Name
+	if len(cmds) == 0 {
+		return errors.Errorf("%s backend has no commands", name)
+	}
+	fmt.Printf("### Backend commands\n\n")
+	fmt.Printf(`Here are the commands specific to the %s backend.
+
+Run them with
+
+    rclone backend COMMAND remote:
+
+The help below will explain what arguments each command takes.
+
+See [the "rclone backend" command](/commands/rclone_backend/) for more
+info on how to pass options and arguments.
+
+`, name)
+	for _, c := range cmds {
+		fmt.Printf("#### %s\n\n", c.Name)
+		fmt.Printf("%s\n\n", c.Short)
+		fmt.Printf("    rclone backend %s remote: [options] [<arguments>+]\n\n", c.Name)
+		if c.Long != "" {
+			fmt.Printf("%s\n\n", c.Long)
+		}
+		if len(c.Opts) != 0 {
+			fmt.Printf("Options:\n\n")
+			keys := make([]string, 0, len(c.Opts))
+			for k := range c.Opts {
+				keys = append(keys, k)
+			}
+			sort.Strings(keys)
+			for _, key := range keys {
+				fmt.Printf("- %q: %s\n", key, c.Opts[key])
+			}
+			fmt.Printf("\n")
+		}
+	}
+	return nil
+}
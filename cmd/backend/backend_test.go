@@ -0,0 +1,22 @@
+package backend
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseOptions(t *testing.T) {
+	for _, test := range []struct {
+		in   []string
+		want map[string]string
+	}{
+		{nil, map[string]string{}},
+		{[]string{"a"}, map[string]string{"a": "true"}},
+		{[]string{"a=b"}, map[string]string{"a": "b"}},
+		{[]string{"a=b=c", "d="}, map[string]string{"a": "b=c", "d": ""}},
+	} {
+		got := parseOptions(test.in)
+		assert.Equal(t, test.want, got, test.in)
+	}
+}
@@ -9,6 +9,7 @@ import (
 	"github.com/ncw/rclone/cmd"
 	"github.com/ncw/rclone/fs/operations"
 	"github.com/spf13/cobra"
+	"golang.org/x/net/context"
 )
 
 // Globals
@@ -74,7 +75,7 @@ Note that if offset is negative it will count from the end, so
 			w = ioutil.Discard
 		}
 		cmd.Run(false, false, command, func() error {
-			return operations.Cat(fsrc, w, offset, count)
+			return operations.Cat(context.Background(), fsrc, w, offset, count)
 		})
 	},
 }
@@ -2,22 +2,29 @@ package check
 
 import (
 	"github.com/ncw/rclone/cmd"
+	"github.com/ncw/rclone/cmd/hashsum"
+	"github.com/ncw/rclone/fs/hash"
 	"github.com/ncw/rclone/fs/operations"
 	"github.com/spf13/cobra"
+	"golang.org/x/net/context"
 )
 
 // Globals
 var (
-	download = false
+	download      = false
+	checkFile     = ""
+	checkFileHash = hash.MD5
 )
 
 func init() {
 	cmd.Root.AddCommand(commandDefintion)
 	commandDefintion.Flags().BoolVarP(&download, "download", "", download, "Check by downloading rather than with hash.")
+	commandDefintion.Flags().StringVarP(&checkFile, "checkfile", "", checkFile, "Check the remote against this SUM file rather than a source.")
+	commandDefintion.Flags().VarP(&checkFileHash, "checkfile-hash", "", "Type of the hashes in the --checkfile.")
 }
 
 var commandDefintion = &cobra.Command{
-	Use:   "check source:path dest:path",
+	Use:   "check [source:path] dest:path",
 	Short: `Checks the files in the source and destination match.`,
 	Long: `
 Checks the files in the source and destination match.  It compares
@@ -31,15 +38,37 @@ If you supply the --download flag, it will download the data from
 both remotes and check them against each other on the fly.  This can
 be useful for remotes that don't support hashes or if you really want
 to check all the data.
+
+If you supply the --checkfile flag, it will check dest:path against a
+SUM file in the format written by md5sum, sha1sum or rclone hashsum,
+which can be local or on a remote, instead of a source.  Use this for
+periodic integrity checks against a manifest made when the files were
+copied without needing the source any more, eg
+
+    rclone md5sum source:path --output-file source.md5
+    rclone check --checkfile source.md5 dest:path
+
+The hashes are taken to be MD5 unless you set --checkfile-hash, eg
+--checkfile-hash SHA-1.  Files with a different hash, files which
+aren't in the SUM file and files listed in it which are missing from
+dest:path are reported.
 `,
 	Run: func(command *cobra.Command, args []string) {
+		if checkFile != "" {
+			cmd.CheckArgs(1, 1, command, args)
+			fdst := cmd.NewFsSrc(args)
+			cmd.Run(false, false, command, func() error {
+				return hashsum.CheckHashes(context.Background(), checkFileHash, fdst, checkFile)
+			})
+			return
+		}
 		cmd.CheckArgs(2, 2, command, args)
 		fsrc, fdst := cmd.NewFsSrcDst(args)
 		cmd.Run(false, false, command, func() error {
 			if download {
-				return operations.CheckDownload(fdst, fsrc)
+				return operations.CheckDownload(context.Background(), fdst, fsrc)
 			}
-			return operations.Check(fdst, fsrc)
+			return operations.Check(context.Background(), fdst, fsrc)
 		})
 	},
 }
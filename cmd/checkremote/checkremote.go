@@ -0,0 +1,350 @@
+// Package checkremote implements the "rclone checkremote" command
+// which checks a remote is fit for use before trusting it with data.
+package checkremote
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/ncw/rclone/cmd"
+	"github.com/ncw/rclone/fs"
+	"github.com/ncw/rclone/fs/hash"
+	"github.com/ncw/rclone/fs/object"
+	"github.com/ncw/rclone/fstest"
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+	"golang.org/x/net/context"
+)
+
+var (
+	jsonOutput  bool
+	listRetries = 3
+	retryDelay  = time.Second
+)
+
+func init() {
+	cmd.Root.AddCommand(commandDefintion)
+	commandDefintion.Flags().BoolVarP(&jsonOutput, "json", "", false, "Format output as JSON")
+	commandDefintion.Flags().IntVarP(&listRetries, "list-retries", "", listRetries, "Number of times to retry listings which don't show changes yet")
+}
+
+var commandDefintion = &cobra.Command{
+	Use:   "checkremote remote:path",
+	Short: `Check a remote works before trusting it with data.`,
+	Long: `
+rclone checkremote checks the credentials for the remote work, that
+files can be written, read back and deleted, that the hashes and
+modification times the remote says it supports are stored correctly
+and that listings show the changes.
+
+It writes a small test file into a temporary directory under
+remote:path and removes it again afterwards.
+
+It prints a report like this
+
+    Remote:     s3:bucket
+    Hashes:     MD5
+    Precision:  1ns
+    Features:   BucketBased, Copy, GetTier, PutStream, SetTier
+
+    list        OK
+    mkdir       OK
+    write       OK
+    read        OK
+    hash        OK      MD5
+    modtime     OK      read back exactly
+    setmodtime  OK
+    listing     OK
+    delete      OK
+
+Use the --json flag for a computer readable output.
+
+If any of the checks fail rclone will exit with a non zero exit code.
+Listings are retried --list-retries times to allow for remotes which
+are eventually consistent.
+`,
+	Run: func(command *cobra.Command, args []string) {
+		cmd.CheckArgs(1, 1, command, args)
+		f := cmd.NewFsDst(args)
+		cmd.Run(false, false, command, func() error {
+			r := checkRemote(context.Background(), f)
+			if jsonOutput {
+				out, err := json.MarshalIndent(r, "", "\t")
+				if err != nil {
+					return err
+				}
+				fmt.Printf("%s\n", out)
+			} else {
+				r.print(os.Stdout)
+			}
+			if failed := r.failed(); failed > 0 {
+				return errors.Errorf("%d checks failed", failed)
+			}
+			return nil
+		})
+	},
+}
+
+// Check is the result of a single check
+type Check struct {
+	Name   string
+	OK     bool
+	Detail string `json:",omitempty"`
+}
+
+// Report is the result of all the checks on a remote
+type Report struct {
+	Remote    string
+	Hashes    []string
+	Precision string
+	Features  []string
+	Checks    []Check
+}
+
+// add a check to the report
+func (r *Report) add(name string, ok bool, format string, a ...interface{}) {
+	r.Checks = append(r.Checks, Check{
+		Name:   name,
+		OK:     ok,
+		Detail: fmt.Sprintf(format, a...),
+	})
+}
+
+// pass records a successful check
+func (r *Report) pass(name string, format string, a ...interface{}) {
+	r.add(name, true, format, a...)
+}
+
+// fail records a failed check
+func (r *Report) fail(name string, format string, a ...interface{}) {
+	r.add(name, false, format, a...)
+}
+
+// failed returns the number of failed checks
+func (r *Report) failed() (n int) {
+	for _, check := range r.Checks {
+		if !check.OK {
+			n++
+		}
+	}
+	return n
+}
+
+// print the report in human readable form
+func (r *Report) print(out io.Writer) {
+	_, _ = fmt.Fprintf(out, "%-12s%s\n", "Remote:", r.Remote)
+	_, _ = fmt.Fprintf(out, "%-12s%s\n", "Hashes:", strings.Join(r.Hashes, ", "))
+	_, _ = fmt.Fprintf(out, "%-12s%s\n", "Precision:", r.Precision)
+	_, _ = fmt.Fprintf(out, "%-12s%s\n", "Features:", strings.Join(r.Features, ", "))
+	_, _ = fmt.Fprintln(out)
+	for _, check := range r.Checks {
+		result := "OK"
+		if !check.OK {
+			result = "FAILED"
+		}
+		_, _ = fmt.Fprintln(out, strings.TrimRight(fmt.Sprintf("%-12s%-8s%s", check.Name, result, check.Detail), " "))
+	}
+}
+
+// findListing lists dir looking for remote and returns whether it
+// was found with the size passed in, retrying the listing to allow
+// for eventually consistent remotes.
+//
+// If size is < 0 then it checks the file has gone from the listing.
+func findListing(ctx context.Context, f fs.Fs, dir, remote string, size int64) (found bool, err error) {
+	for try := 1; ; try++ {
+		var entries fs.DirEntries
+		entries, err = f.List(ctx, dir)
+		found = false
+		if err == nil {
+			for _, entry := range entries {
+				if o, ok := entry.(fs.Object); ok && o.Remote() == remote && (size < 0 || o.Size() == size) {
+					found = true
+					break
+				}
+			}
+		}
+		if (err == nil && found == (size >= 0)) || try >= listRetries {
+			return found, err
+		}
+		fs.Debugf(f, "Listing doesn't show %q yet - retry %d/%d", remote, try, listRetries)
+		time.Sleep(retryDelay)
+	}
+}
+
+// checkRemote runs all the checks on f returning a report
+func checkRemote(ctx context.Context, f fs.Fs) *Report {
+	r := &Report{
+		Remote:   f.Name() + ":" + f.Root(),
+		Hashes:   []string{},
+		Features: []string{},
+	}
+	for _, ht := range f.Hashes().Array() {
+		r.Hashes = append(r.Hashes, ht.String())
+	}
+	precision := f.Precision()
+	if precision == fs.ModTimeNotSupported {
+		r.Precision = "not supported"
+	} else {
+		r.Precision = precision.String()
+	}
+	for name, enabled := range f.Features().Enabled() {
+		if enabled {
+			r.Features = append(r.Features, name)
+		}
+	}
+	sort.Strings(r.Features)
+
+	// Check the credentials by listing the root
+	_, err := f.List(ctx, "")
+	if err != nil && err != fs.ErrorDirNotFound {
+		r.fail("list", "%v", err)
+		return r
+	}
+	r.pass("list", "")
+
+	dir := "rclone-checkremote-" + fstest.RandomString(8)
+	err = f.Mkdir(ctx, dir)
+	if err != nil {
+		r.fail("mkdir", "%v", err)
+		return r
+	}
+	r.pass("mkdir", "")
+	defer func() {
+		err := f.Rmdir(ctx, dir)
+		if err != nil {
+			fs.Errorf(f, "Failed to remove test directory %q: %v", dir, err)
+		}
+	}()
+
+	// Write a test file
+	remote := dir + "/file.txt"
+	contents := fstest.RandomString(1024)
+	modTime := fstest.Time("2001-02-03T04:05:06.499999999Z")
+	hasher := hash.NewMultiHasher()
+	_, _ = hasher.Write([]byte(contents))
+	sums := hasher.Sums()
+	src := object.NewStaticObjectInfo(remote, modTime, int64(len(contents)), true, nil, f)
+	o, err := f.Put(ctx, bytes.NewBufferString(contents), src)
+	if err != nil {
+		r.fail("write", "%v", err)
+		return r
+	}
+	r.pass("write", "")
+	defer func() {
+		if o == nil {
+			return
+		}
+		err := o.Remove(ctx)
+		if err != nil {
+			r.fail("delete", "%v", err)
+			return
+		}
+		found, err := findListing(ctx, f, dir, remote, -1)
+		if err != nil {
+			r.fail("delete", "listing failed: %v", err)
+		} else if found {
+			r.fail("delete", "file still listed after delete")
+		} else {
+			r.pass("delete", "")
+		}
+	}()
+
+	// Read it back
+	in, err := o.Open(ctx)
+	if err != nil {
+		r.fail("read", "%v", err)
+	} else {
+		data, err := ioutil.ReadAll(in)
+		closeErr := in.Close()
+		if err == nil {
+			err = closeErr
+		}
+		if err != nil {
+			r.fail("read", "%v", err)
+		} else if string(data) != contents {
+			r.fail("read", "contents differ: read %d bytes expecting %d", len(data), len(contents))
+		} else {
+			r.pass("read", "")
+		}
+	}
+
+	// Check the hashes the remote claims to support
+	if len(r.Hashes) == 0 {
+		r.pass("hash", "no hashes supported")
+	} else {
+		ok := true
+		var details []string
+		for _, ht := range f.Hashes().Array() {
+			sum, err := o.Hash(ht)
+			switch {
+			case err != nil:
+				ok = false
+				details = append(details, fmt.Sprintf("%v: %v", ht, err))
+			case sum == "":
+				details = append(details, fmt.Sprintf("%v: not available", ht))
+			case !hash.Equals(sum, sums[ht]):
+				ok = false
+				details = append(details, fmt.Sprintf("%v: got %q expecting %q", ht, sum, sums[ht]))
+			default:
+				details = append(details, ht.String())
+			}
+		}
+		r.add("hash", ok, "%s", strings.Join(details, ", "))
+	}
+
+	// Check the modification time is preserved within the precision
+	checkModTime := func(name string, want time.Time) {
+		o2, err := f.NewObject(ctx, remote)
+		if err != nil {
+			r.fail(name, "couldn't find object: %v", err)
+			return
+		}
+		dt := o2.ModTime().Sub(want)
+		if dt < 0 {
+			dt = -dt
+		}
+		switch {
+		case precision == fs.ModTimeNotSupported:
+			r.pass(name, "not supported")
+		case dt > precision:
+			r.fail(name, "read back %v out, more than the precision %v", dt, precision)
+		case dt == 0:
+			r.pass(name, "read back exactly")
+		default:
+			r.pass(name, "read back %v out", dt)
+		}
+	}
+	checkModTime("modtime", modTime)
+	if precision != fs.ModTimeNotSupported {
+		newModTime := fstest.Time("2011-12-13T14:15:16.999999999Z")
+		err = o.SetModTime(ctx, newModTime)
+		switch err {
+		case nil:
+			checkModTime("setmodtime", newModTime)
+		case fs.ErrorCantSetModTime, fs.ErrorCantSetModTimeWithoutDelete:
+			r.pass("setmodtime", "not supported - files are uploaded again to change it")
+		default:
+			r.fail("setmodtime", "%v", err)
+		}
+	}
+
+	// Check the listing shows the file
+	found, err := findListing(ctx, f, dir, remote, int64(len(contents)))
+	if err != nil {
+		r.fail("listing", "%v", err)
+	} else if !found {
+		r.fail("listing", "file not listed after %d tries", listRetries)
+	} else {
+		r.pass("listing", "")
+	}
+
+	return r
+}
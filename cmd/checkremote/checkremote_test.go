@@ -0,0 +1,75 @@
+package checkremote
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	_ "github.com/ncw/rclone/backend/local"
+	"github.com/ncw/rclone/fstest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/net/context"
+)
+
+var (
+	t1 = fstest.Time("2001-02-03T04:05:06.499999999Z")
+)
+
+// TestMain drives the tests
+func TestMain(m *testing.M) {
+	fstest.TestMain(m)
+}
+
+func TestCheckRemote(t *testing.T) {
+	ctx := context.Background()
+	r := fstest.NewRun(t)
+	defer r.Finalise()
+
+	report := checkRemote(ctx, r.Fremote)
+	var names []string
+	for _, check := range report.Checks {
+		assert.True(t, check.OK, "%s: %s", check.Name, check.Detail)
+		names = append(names, check.Name)
+	}
+	assert.Equal(t, []string{"list", "mkdir", "write", "read", "hash", "modtime", "setmodtime", "listing", "delete"}, names)
+	assert.Equal(t, 0, report.failed())
+	assert.Contains(t, report.Hashes, "MD5")
+
+	// Check the test directory was tidied up
+	fstest.CheckListingWithPrecision(t, r.Fremote, nil, []string{}, r.Fremote.Precision())
+
+	var buf bytes.Buffer
+	report.print(&buf)
+	out := buf.String()
+	assert.True(t, strings.HasPrefix(out, "Remote:     "+report.Remote+"\n"), out)
+	assert.Contains(t, out, "\nwrite       OK\n")
+}
+
+func TestFindListing(t *testing.T) {
+	ctx := context.Background()
+	r := fstest.NewRun(t)
+	defer r.Finalise()
+	oldListRetries := listRetries
+	listRetries = 1
+	defer func() { listRetries = oldListRetries }()
+
+	file1 := r.WriteObject("dir/file1", "hello", t1)
+	fstest.CheckItems(t, r.Fremote, file1)
+
+	found, err := findListing(ctx, r.Fremote, "dir", "dir/file1", 5)
+	require.NoError(t, err)
+	assert.True(t, found)
+
+	found, err = findListing(ctx, r.Fremote, "dir", "dir/file1", 6)
+	require.NoError(t, err)
+	assert.False(t, found)
+
+	found, err = findListing(ctx, r.Fremote, "dir", "dir/file1", -1)
+	require.NoError(t, err)
+	assert.True(t, found)
+
+	found, err = findListing(ctx, r.Fremote, "dir", "dir/file2", -1)
+	require.NoError(t, err)
+	assert.False(t, found)
+}
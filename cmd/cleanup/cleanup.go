@@ -4,6 +4,7 @@ import (
 	"github.com/ncw/rclone/cmd"
 	"github.com/ncw/rclone/fs/operations"
 	"github.com/spf13/cobra"
+	"golang.org/x/net/context"
 )
 
 func init() {
@@ -14,14 +15,15 @@ var commandDefintion = &cobra.Command{
 	Use:   "cleanup remote:path",
 	Short: `Clean up the remote if possible`,
 	Long: `
-Clean up the remote if possible.  Empty the trash or delete old file
-versions. Not supported by all remotes.
+Clean up the remote if possible.  Empty the trash, delete old file
+versions or abort stale unfinished uploads. Not supported by all
+remotes.
 `,
 	Run: func(command *cobra.Command, args []string) {
 		cmd.CheckArgs(1, 1, command, args)
 		fsrc := cmd.NewFsSrc(args)
 		cmd.Run(true, false, command, func() error {
-			return operations.CleanUp(fsrc)
+			return operations.CleanUp(context.Background(), fsrc)
 		})
 	},
 }
@@ -7,13 +7,16 @@ package cmd
 // would probably mean bringing all the flags in to here? Or define some flagsets in fs...
 
 import (
+	"encoding/json"
 	"fmt"
+	"io/ioutil"
 	"log"
 	"os"
 	"path"
 	"regexp"
 	"runtime"
 	"runtime/pprof"
+	"sort"
 	"time"
 
 	"github.com/pkg/errors"
@@ -42,6 +45,12 @@ var (
 	dataRateUnit  = flags.StringP("stats-unit", "", "bytes", "Show data rate in stats as either 'bits' or 'bytes'/s")
 	version       bool
 	retries       = flags.IntP("retries", "", 3, "Retry operations this many times if they fail")
+	errorSummary  = flags.StringP("error-summary", "", "", "Write a JSON summary of the files which failed to this file")
+	// Stats push flags
+	statsPushURL      = flags.StringP("stats-push-url", "", "", "Push stats to this pushgateway or InfluxDB URL or statsd host:port")
+	statsPushFormat   = flags.StringP("stats-push-format", "", accounting.PushFormatPrometheus, "Format to push stats in: prometheus|influxdb|statsd")
+	statsPushInterval = flags.DurationP("stats-push-interval", "", time.Minute*1, "Interval between pushing stats. (0 to push only at the end)")
+	statsPushJob      = flags.StringP("stats-push-job", "", "rclone", "Job name to label pushed stats with")
 	// Errors
 	errorCommandNotFound    = errors.New("command not found")
 	errorUncategorized      = errors.New("uncategorized error")
@@ -59,6 +68,7 @@ const (
 	exitCodeRetryError
 	exitCodeNoRetryError
 	exitCodeFatalError
+	exitCodeTransferExceeded
 )
 
 // Root is the main rclone command
@@ -77,14 +87,19 @@ from various cloud storage systems and using file transfer services, such as:
   * FTP
   * Google Cloud Storage
   * Google Drive
+  * HDFS (Hadoop Distributed File System)
   * HTTP
   * Hubic
+  * Jottacloud
+  * Mega
   * Microsoft Azure Blob Storage
   * Microsoft OneDrive
   * Openstack Swift / Rackspace cloud files / Memset Memstore
   * pCloud
   * QingStor
   * SFTP
+  * Sia
+  * SMB / CIFS
   * Webdav / Owncloud / Nextcloud
   * Yandex Disk
   * The local filesystem
@@ -132,6 +147,27 @@ func init() {
 	cobra.OnInitialize(initConfig)
 }
 
+// Main runs rclone interpreting flags and commands out of os.Args
+func Main() {
+	setDefaultsFromEnv(Root)
+	if err := Root.Execute(); err != nil {
+		log.Fatalf("Fatal error: %v", err)
+	}
+}
+
+// setDefaultsFromEnv sets the defaults for the flags of command and
+// all its sub commands from the environment.
+//
+// This catches the command flags which weren't defined with the
+// functions in the flags package.
+func setDefaultsFromEnv(command *cobra.Command) {
+	flags.SetDefaultsFromEnv(command.PersistentFlags())
+	flags.SetDefaultsFromEnv(command.Flags())
+	for _, subCommand := range command.Commands() {
+		setDefaultsFromEnv(subCommand)
+	}
+}
+
 // ShowVersion prints the version to stdout
 func ShowVersion() {
 	fmt.Printf("rclone %s\n", fs.Version)
@@ -265,6 +301,17 @@ func NewFsDstFile(args []string) (fdst fs.Fs, dstFileName string) {
 	return
 }
 
+// NewFsFile creates a new fs from the arguments
+//
+// If the argument points to a file then it returns the Fs of the
+// parent directory and the name of the file, otherwise it returns the
+// Fs of the directory and an empty file name
+func NewFsFile(args []string) (f fs.Fs, fileName string) {
+	f, fileName = newFsFile(args[0])
+	fs.CalculateModifyWindow(f)
+	return
+}
+
 // ShowStats returns true if the user added a `--stats` flag to the command line.
 //
 // This is called by Run to override the default value of the
@@ -278,12 +325,15 @@ func ShowStats() bool {
 func Run(Retry bool, showStats bool, cmd *cobra.Command, f func() error) {
 	var err error
 	var stopStats chan struct{}
+	start := time.Now()
 	if !showStats && ShowStats() {
 		showStats = true
 	}
 	if showStats {
 		stopStats = StartStats()
 	}
+	stopPush := startPush()
+	originalFilter := filter.Active
 	for try := 1; try <= *retries; try++ {
 		err = f()
 		if !Retry || (err == nil && !accounting.Stats.Errored()) {
@@ -306,12 +356,28 @@ func Run(Retry bool, showStats bool, cmd *cobra.Command, f func() error) {
 			fs.Errorf(nil, "Attempt %d/%d failed with %d errors", try, *retries, accounting.Stats.GetErrors())
 		}
 		if try < *retries {
+			noRetry, ok := setRetryFilter(originalFilter)
+			if !ok {
+				fs.Errorf(nil, "Can't retry the failed files - not attempting retries")
+				break
+			}
 			accounting.Stats.ResetErrors()
+			// Carry the failures which can't be retried into the next attempt
+			for remote, err := range noRetry {
+				accounting.Stats.Error(err)
+				accounting.Stats.FailedFile(remote, err)
+			}
 		}
 	}
+	filter.Active = originalFilter
 	if showStats {
 		close(stopStats)
 	}
+	stopPush()
+	if *errorSummary != "" {
+		writeErrorSummary(*errorSummary)
+	}
+	runHooks(cmd, start, err)
 	if err != nil {
 		log.Printf("Failed to %s: %v", cmd.Name(), err)
 		resolveExitCode(err)
@@ -325,6 +391,79 @@ func Run(Retry bool, showStats bool, cmd *cobra.Command, f func() error) {
 	}
 }
 
+// setRetryFilter sets up filter.Active for the next attempt after a
+// failed one.
+//
+// If the errors in the last attempt were all in individual files then
+// the next attempt is restricted to the files which failed with
+// retryable errors, otherwise the original filter is restored so
+// everything is retried.
+//
+// It returns the files which failed with errors which can't be
+// retried, and false if there is nothing left to retry.
+func setRetryFilter(originalFilter *filter.Filter) (noRetry map[string]error, ok bool) {
+	failed := accounting.Stats.FailedFiles()
+	if len(failed) == 0 || accounting.Stats.GetRetryAll() {
+		filter.Active = originalFilter
+		return nil, true
+	}
+	retryFilter, err := filter.NewFilter(nil)
+	if err != nil {
+		filter.Active = originalFilter
+		return nil, true
+	}
+	noRetry = make(map[string]error)
+	for remote, err := range failed {
+		if fserrors.Classify(err) == fserrors.ClassNoRetry {
+			noRetry[remote] = err
+			continue
+		}
+		_ = retryFilter.AddFile(remote)
+	}
+	if len(retryFilter.Files()) == 0 {
+		return noRetry, false
+	}
+	fs.Infof(nil, "Retrying only the %d files which failed", len(retryFilter.Files()))
+	filter.Active = retryFilter
+	return noRetry, true
+}
+
+// failedFile is an entry in the --error-summary output
+type failedFile struct {
+	Path  string `json:"path"`
+	Error string `json:"error"`
+	Class string `json:"class"`
+}
+
+// writeErrorSummary writes the files which failed and why to the
+// file name as JSON
+func writeErrorSummary(name string) {
+	failed := accounting.Stats.FailedFiles()
+	remotes := make([]string, 0, len(failed))
+	for remote := range failed {
+		remotes = append(remotes, remote)
+	}
+	sort.Strings(remotes)
+	summary := make([]failedFile, 0, len(failed))
+	for _, remote := range remotes {
+		err := failed[remote]
+		summary = append(summary, failedFile{
+			Path:  remote,
+			Error: err.Error(),
+			Class: fserrors.Classify(err).String(),
+		})
+	}
+	out, err := json.MarshalIndent(summary, "", "\t")
+	if err != nil {
+		fs.Errorf(nil, "Failed to make error summary: %v", err)
+		return
+	}
+	err = ioutil.WriteFile(name, append(out, '\n'), 0666)
+	if err != nil {
+		fs.Errorf(nil, "Failed to write error summary: %v", err)
+	}
+}
+
 // CheckArgs checks there are enough arguments and prints a message if not
 func CheckArgs(MinArgs, MaxArgs int, cmd *cobra.Command, args []string) {
 	if len(args) < MinArgs {
@@ -362,6 +501,26 @@ func StartStats() chan struct{} {
 	return stopStats
 }
 
+// startPush starts pushing the stats if --stats-push-url is set
+//
+// It returns a function which should be called to push the final
+// stats and stop.
+func startPush() (stop func()) {
+	if *statsPushURL == "" {
+		return func() {}
+	}
+	stop, err := accounting.StartPush(accounting.PushOptions{
+		URL:      *statsPushURL,
+		Format:   *statsPushFormat,
+		Interval: *statsPushInterval,
+		Job:      *statsPushJob,
+	})
+	if err != nil {
+		log.Fatalf("Failed to start pushing stats: %v", err)
+	}
+	return stop
+}
+
 // initConfig is run by cobra after initialising the flags
 func initConfig() {
 	// Start the logger
@@ -432,6 +591,8 @@ func initConfig() {
 }
 
 func resolveExitCode(err error) {
+	// Run the exit handlers as os.Exit skips PersistentPostRun
+	atexit.Run()
 	if err == nil {
 		os.Exit(exitCodeSuccess)
 	}
@@ -439,6 +600,10 @@ func resolveExitCode(err error) {
 	err = errors.Cause(err)
 
 	switch {
+	case err == accounting.ErrorMaxTransferLimitReached,
+		err == accounting.ErrorMaxTransferLimitReachedFatal,
+		err == accounting.ErrorMaxTransferLimitReachedGraceful:
+		os.Exit(exitCodeTransferExceeded)
 	case err == fs.ErrorDirNotFound:
 		os.Exit(exitCodeDirNotFound)
 	case err == fs.ErrorObjectNotFound:
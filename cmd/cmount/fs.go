@@ -275,6 +275,18 @@ func (fsys *FS) Statfs(path string, stat *fuse.Statfs_t) (errc int) {
 	stat.Bsize = blockSize  // Block size
 	stat.Namemax = 255      // Maximum file name length?
 	stat.Frsize = blockSize // Fragment size, smallest addressable data size in the file system.
+	// Use the real figures from the remote if it knows them
+	total, used, free := fsys.VFS.Statfs()
+	if total >= 0 {
+		stat.Blocks = uint64(total) / blockSize
+	}
+	if free >= 0 {
+		stat.Bfree = uint64(free) / blockSize
+		stat.Bavail = stat.Bfree
+	} else if used >= 0 && uint64(used)/blockSize < stat.Blocks {
+		stat.Bfree = stat.Blocks - uint64(used)/blockSize
+		stat.Bavail = stat.Bfree
+	}
 	return 0
 }
 
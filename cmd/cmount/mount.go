@@ -52,9 +52,28 @@ func mountOptions(device string, mountpoint string) (options []string) {
 
 	// OSX options
 	if runtime.GOOS == "darwin" {
-		options = append(options, "-o", "volname="+device)
-		options = append(options, "-o", "noappledouble")
-		options = append(options, "-o", "noapplexattr")
+		if mountlib.NoAppleDouble {
+			options = append(options, "-o", "noappledouble")
+		}
+		if mountlib.NoAppleXattr {
+			options = append(options, "-o", "noapplexattr")
+		}
+	}
+
+	// Volume name - supported by OSX and WinFsp
+	if runtime.GOOS == "darwin" || runtime.GOOS == "windows" {
+		volumeName := mountlib.VolumeName
+		if volumeName == "" {
+			volumeName = device
+		}
+		options = append(options, "-o", "volname="+volumeName)
+	}
+
+	// Attribute caching - WinFsp uses its own option for this
+	if runtime.GOOS == "windows" {
+		options = append(options, "-o", fmt.Sprintf("FileInfoTimeout=%d", int64(mountlib.AttrTimeout/time.Millisecond)))
+	} else {
+		options = append(options, "-o", fmt.Sprintf("attr_timeout=%g", mountlib.AttrTimeout.Seconds()))
 	}
 
 	// Windows options
@@ -194,42 +213,61 @@ func mount(f fs.Fs, mountpoint string) (*vfs.VFS, <-chan error, func() error, er
 //
 // If noModTime is set then it
 func Mount(f fs.Fs, mountpoint string) error {
-	// Mount it
-	FS, errChan, _, err := mount(f, mountpoint)
-	if err != nil {
-		return errors.Wrap(err, "failed to mount FUSE fs")
-	}
-
 	// Note cgofuse unmounts the fs on SIGINT etc
 
 	sigHup := make(chan os.Signal, 1)
 	signal.Notify(sigHup, syscall.SIGHUP)
 
-	if err := sdnotify.SdNotifyReady(); err != nil && err != sdnotify.SdNotifyNoSocket {
-		return errors.Wrap(err, "failed to notify systemd")
-	}
-
-waitloop:
+	notified := false
 	for {
-		select {
-		// umount triggered outside the app
-		case err = <-errChan:
-			break waitloop
-		// user sent SIGHUP to clear the cache
-		case <-sigHup:
-			root, err := FS.Root()
-			if err != nil {
-				fs.Errorf(f, "Error reading root: %v", err)
-			} else {
-				root.ForgetAll()
+		// Mount it
+		FS, errChan, unmount, err := mount(f, mountpoint)
+		if err != nil {
+			return errors.Wrap(err, "failed to mount FUSE fs")
+		}
+
+		if !notified {
+			if err := sdnotify.SdNotifyReady(); err != nil && err != sdnotify.SdNotifyNoSocket {
+				return errors.Wrap(err, "failed to notify systemd")
 			}
+			notified = true
 		}
-	}
 
-	_ = sdnotify.SdNotifyStopping()
-	if err != nil {
-		return errors.Wrap(err, "failed to umount FUSE fs")
+		unhealthy, stopHealthCheck := mountlib.HealthCheck(mountpoint)
+		remount := false
+	waitloop:
+		for {
+			select {
+			// umount triggered outside the app
+			case err = <-errChan:
+				break waitloop
+			// user sent SIGHUP to clear the cache
+			case <-sigHup:
+				root, err := FS.Root()
+				if err != nil {
+					fs.Errorf(f, "Error reading root: %v", err)
+				} else {
+					root.ForgetAll()
+				}
+			// mount stopped responding: unmount and mount again
+			case err = <-unhealthy:
+				fs.Errorf(nil, "Mount not responding, remounting: %v", err)
+				err = unmount()
+				if err != nil {
+					fs.Debugf(nil, "Unmount failed, forcing: %v", err)
+					err = mountlib.ForceUnmount(mountpoint)
+				}
+				remount = err == nil
+				break waitloop
+			}
+		}
+		stopHealthCheck()
+		if !remount {
+			_ = sdnotify.SdNotifyStopping()
+			if err != nil {
+				return errors.Wrap(err, "failed to umount FUSE fs")
+			}
+			return nil
+		}
 	}
-
-	return nil
 }
@@ -4,10 +4,17 @@ import (
 	"github.com/ncw/rclone/cmd"
 	"github.com/ncw/rclone/fs/sync"
 	"github.com/spf13/cobra"
+	"golang.org/x/net/context"
+)
+
+// Globals
+var (
+	createEmptySrcDirs = false
 )
 
 func init() {
 	cmd.Root.AddCommand(commandDefintion)
+	commandDefintion.Flags().BoolVarP(&createEmptySrcDirs, "create-empty-src-dirs", "", createEmptySrcDirs, "Create empty source dirs on destination after copy")
 }
 
 var commandDefintion = &cobra.Command{
@@ -49,12 +56,17 @@ If you are familiar with ` + "`rsync`" + `, rclone always works as if you had
 written a trailing / - meaning "copy the contents of this directory".
 This applies to all commands and whether you are talking about the
 source or destination.
+
+Rclone doesn't normally create empty directories on the destination.
+If you want the empty directories in source:path to be created on
+dest:path too then use the --create-empty-src-dirs flag.  Note that
+some remotes (eg s3, b2) can't have empty directories.
 `,
 	Run: func(command *cobra.Command, args []string) {
 		cmd.CheckArgs(2, 2, command, args)
 		fsrc, fdst := cmd.NewFsSrcDst(args)
 		cmd.Run(true, true, command, func() error {
-			return sync.CopyDir(fdst, fsrc)
+			return sync.CopyDir(context.Background(), fdst, fsrc, createEmptySrcDirs)
 		})
 	},
 }
@@ -0,0 +1,67 @@
+package copyurl
+
+import (
+	"os"
+
+	"github.com/ncw/rclone/cmd"
+	"github.com/ncw/rclone/fs"
+	"github.com/ncw/rclone/fs/operations"
+	"github.com/spf13/cobra"
+	"golang.org/x/net/context"
+)
+
+var (
+	autoFilename = false
+	stdout       = false
+)
+
+func init() {
+	cmd.Root.AddCommand(commandDefintion)
+	commandDefintion.Flags().BoolVarP(&autoFilename, "auto-filename", "a", autoFilename, "Get the file name from the URL and use it for destination file path")
+	commandDefintion.Flags().BoolVarP(&stdout, "stdout", "", stdout, "Write the output to stdout rather than a file")
+}
+
+var commandDefintion = &cobra.Command{
+	Use:   "copyurl https://example.com dest:path",
+	Short: `Copy url content to dest.`,
+	Long: `
+Download urls content and copy it to destination without saving it in
+tmp storage.
+
+The data is streamed straight to the destination so nothing is stored
+locally.
+
+Setting --auto-filename will cause the file name to be retrieved from
+the Content-Disposition header of the response, or failing that the
+end of the URL, and dest:path will be treated as a directory, eg
+
+    rclone copyurl -a https://example.com/download?id=42 remote:dir
+
+Setting --stdout will cause the output to be written to standard
+output instead, in which case there is no dest:path.
+`,
+	Run: func(command *cobra.Command, args []string) {
+		minArgs := 2
+		if stdout {
+			minArgs = 1
+		}
+		cmd.CheckArgs(minArgs, minArgs, command, args)
+
+		var fdst fs.Fs
+		var dstFileName string
+		if !stdout {
+			if autoFilename {
+				fdst = cmd.NewFsDst(args[1:])
+			} else {
+				fdst, dstFileName = cmd.NewFsDstFile(args[1:])
+			}
+		}
+		cmd.Run(!stdout, !stdout, command, func() error {
+			if stdout {
+				return operations.CopyURLToWriter(context.Background(), args[0], os.Stdout)
+			}
+			_, err := operations.CopyURL(context.Background(), fdst, dstFileName, args[0], autoFilename)
+			return err
+		})
+	},
+}
@@ -8,6 +8,7 @@ import (
 	"github.com/ncw/rclone/fs/operations"
 	"github.com/pkg/errors"
 	"github.com/spf13/cobra"
+	"golang.org/x/net/context"
 )
 
 func init() {
@@ -81,7 +82,7 @@ func cryptCheck(fdst, fsrc fs.Fs) error {
 		if underlyingHash == "" {
 			return false, true
 		}
-		cryptHash, err := fcrypt.ComputeHash(cryptDst, src, hashType)
+		cryptHash, err := fcrypt.ComputeHash(context.Background(), cryptDst, src, hashType)
 		if err != nil {
 			fs.CountError(err)
 			fs.Errorf(dst, "Error computing hash: %v", err)
@@ -100,5 +101,5 @@ func cryptCheck(fdst, fsrc fs.Fs) error {
 		return false, false
 	}
 
-	return operations.CheckFn(fcrypt, fsrc, checkIdentical)
+	return operations.CheckFn(context.Background(), fcrypt, fsrc, checkIdentical)
 }
@@ -1,15 +1,16 @@
 package dbhashsum
 
 import (
-	"os"
-
 	"github.com/ncw/rclone/cmd"
-	"github.com/ncw/rclone/fs/operations"
+	"github.com/ncw/rclone/cmd/hashsum"
+	"github.com/ncw/rclone/fs/hash"
 	"github.com/spf13/cobra"
+	"golang.org/x/net/context"
 )
 
 func init() {
 	cmd.Root.AddCommand(commandDefintion)
+	hashsum.AddHashFlags(commandDefintion.Flags())
 }
 
 var commandDefintion = &cobra.Command{
@@ -20,12 +21,15 @@ Produces a Dropbox hash file for all the objects in the path.  The
 hashes are calculated according to [Dropbox content hash
 rules](https://www.dropbox.com/developers/reference/content-hash).
 The output is in the same format as md5sum and sha1sum.
+
+See ` + "`rclone hashsum`" + ` for the --base64, --output-file and
+--checkfile flags.
 `,
 	Run: func(command *cobra.Command, args []string) {
 		cmd.CheckArgs(1, 1, command, args)
 		fsrc := cmd.NewFsSrc(args)
 		cmd.Run(false, false, command, func() error {
-			return operations.DropboxHashSum(fsrc, os.Stdout)
+			return hashsum.Hashsum(context.Background(), hash.Dropbox, fsrc)
 		})
 	},
 }
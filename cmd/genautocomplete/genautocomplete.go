@@ -10,10 +10,14 @@ func init() {
 }
 
 var completionDefinition = &cobra.Command{
-	Use:   "genautocomplete [shell]",
-	Short: `Output completion script for a given shell.`,
+	Use:     "genautocomplete [shell]",
+	Aliases: []string{"completion"},
+	Short:   `Output completion script for a given shell.`,
 	Long: `
 Generates a shell completion script for rclone.
 Run with --help to list the supported shells.
+
+As well as the commands and flags the scripts complete the names of
+the remotes in the config file and paths on the remotes.
 `,
 }
@@ -29,6 +29,10 @@ them directly
 
 If you supply a command line argument the script will be written
 there.
+
+Remote names are completed from the config file and paths on remotes
+by listing the remote, giving up after ` + "`$RCLONE_COMPLETION_TIMEOUT`" + `
+(default 2s).  Set it to 0 to only complete remote names.
 `,
 	Run: func(command *cobra.Command, args []string) {
 		cmd.CheckArgs(0, 1, command, args)
@@ -36,9 +40,42 @@ there.
 		if len(args) > 0 {
 			out = args[0]
 		}
+		cmd.Root.BashCompletionFunction = bashCompletionFunc
 		err := cmd.Root.GenBashCompletionFile(out)
 		if err != nil {
 			log.Fatal(err)
 		}
 	},
 }
+
+// bashCompletionFunc is called by the cobra generated script when it
+// doesn't have any completions, ie for the arguments of commands
+const bashCompletionFunc = `
+# Complete remote names and paths on remotes using rclone itself
+__custom_func() {
+    # the word being completed including any colons
+    local cur
+    if declare -F _get_comp_words_by_ref >/dev/null 2>&1; then
+        _get_comp_words_by_ref -n : cur
+    else
+        cur=${COMP_LINE:0:COMP_POINT}
+        cur=${cur##*[[:space:]]}
+    fi
+    local line nospace=
+    while IFS= read -r line; do
+        COMPREPLY+=("$line")
+        [[ $line == *[:/] ]] && nospace=1
+    done < <(rclone genautocomplete list --list-timeout "${RCLONE_COMPLETION_TIMEOUT:-2s}" -- "$cur" 2>/dev/null)
+    if [[ -n $nospace && $(type -t compopt) = "builtin" ]]; then
+        compopt -o nospace
+    fi
+    # bash only replaces the part of the word after the last colon
+    if [[ $cur == *:* && $COMP_WORDBREAKS == *:* ]]; then
+        local colon_word=${cur%"${cur##*:}"}
+        local i=${#COMPREPLY[*]}
+        while [[ $((--i)) -ge 0 ]]; do
+            COMPREPLY[$i]=${COMPREPLY[$i]#"$colon_word"}
+        done
+    fi
+}
+`
@@ -0,0 +1,111 @@
+package genautocomplete
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"strings"
+
+	"github.com/ncw/rclone/cmd"
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+)
+
+func init() {
+	completionDefinition.AddCommand(fishCommandDefinition)
+}
+
+var fishCommandDefinition = &cobra.Command{
+	Use:   "fish [output_file]",
+	Short: `Output fish completion script for rclone.`,
+	Long: `
+Generates a fish autocompletion script for rclone.
+
+This writes to /etc/fish/completions/rclone.fish by default so will
+probably need to be run with sudo or as root, eg
+
+    sudo rclone genautocomplete fish
+
+Start a new fish shell to use the autocompletion script.
+
+If you supply a command line argument the script will be written
+there.
+
+Remote names are completed from the config file and paths on remotes
+by listing the remote, giving up after ` + "`$RCLONE_COMPLETION_TIMEOUT`" + `
+(default 2s).  Set it to 0 to only complete remote names.
+`,
+	Run: func(command *cobra.Command, args []string) {
+		cmd.CheckArgs(0, 1, command, args)
+		out := "/etc/fish/completions/rclone.fish"
+		if len(args) > 0 {
+			out = args[0]
+		}
+		outFile, err := os.Create(out)
+		if err != nil {
+			log.Fatal(err)
+		}
+		defer func() { _ = outFile.Close() }()
+		err = genFishCompletion(outFile)
+		if err != nil {
+			log.Fatal(err)
+		}
+	},
+}
+
+// fishCompletionFunc completes the arguments of commands
+const fishCompletionFunc = `
+# Complete remote names and paths on remotes using rclone itself
+function __rclone_complete_paths
+    set -l token (commandline -ct)
+    set -l timeout 2s
+    set -q RCLONE_COMPLETION_TIMEOUT; and set timeout $RCLONE_COMPLETION_TIMEOUT
+    set -l completions (rclone genautocomplete list --list-timeout $timeout -- $token 2>/dev/null)
+    if test (count $completions) -eq 0
+        __fish_complete_path $token
+    else
+        printf '%s\n' $completions
+    end
+end
+
+complete -c rclone -f
+complete -c rclone -n 'not __fish_use_subcommand' -a '(__rclone_complete_paths)'
+`
+
+// fishQuote quotes s for use in a fish script
+func fishQuote(s string) string {
+	return "'" + strings.NewReplacer(`\`, `\\`, `'`, `\'`).Replace(s) + "'"
+}
+
+// genFishCompletion writes a fish completion script for the rclone
+// commands, the global flags and remotes to out
+func genFishCompletion(out io.Writer) error {
+	var buf bytes.Buffer
+	buf.WriteString("# fish completion for rclone\n")
+	buf.WriteString(fishCompletionFunc)
+	buf.WriteString("\n# Commands\n")
+	for _, command := range cmd.Root.Commands() {
+		if command.Hidden {
+			continue
+		}
+		fmt.Fprintf(&buf, "complete -c rclone -n __fish_use_subcommand -a %s -d %s\n", fishQuote(command.Name()), fishQuote(command.Short))
+	}
+	buf.WriteString("\n# Global flags\n")
+	pflag.CommandLine.VisitAll(func(flag *pflag.Flag) {
+		if flag.Hidden {
+			return
+		}
+		fmt.Fprintf(&buf, "complete -c rclone -l %s", flag.Name)
+		if flag.Shorthand != "" {
+			fmt.Fprintf(&buf, " -s %s", flag.Shorthand)
+		}
+		if flag.Value.Type() != "bool" {
+			buf.WriteString(" -r")
+		}
+		fmt.Fprintf(&buf, " -d %s\n", fishQuote(flag.Usage))
+	})
+	_, err := buf.WriteTo(out)
+	return err
+}
@@ -0,0 +1,120 @@
+package genautocomplete
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/ncw/rclone/cmd"
+	"github.com/ncw/rclone/fs"
+	"github.com/ncw/rclone/fs/config"
+	"github.com/spf13/cobra"
+	"golang.org/x/net/context"
+)
+
+// Globals
+var (
+	listTimeout = 2 * time.Second
+)
+
+func init() {
+	completionDefinition.AddCommand(listCommandDefinition)
+	listCommandDefinition.Flags().DurationVarP(&listTimeout, "list-timeout", "", listTimeout, "Give up listing the remote after this long. (0 to only complete remote names)")
+}
+
+// listCommandDefinition is called by the completion scripts to
+// complete remote names and paths on remotes
+var listCommandDefinition = &cobra.Command{
+	Use:    "list [prefix]",
+	Short:  `List completions of remotes and remote paths starting with prefix.`,
+	Hidden: true,
+	Run: func(command *cobra.Command, args []string) {
+		cmd.CheckArgs(0, 1, command, args)
+		prefix := ""
+		if len(args) > 0 {
+			prefix = args[0]
+		}
+		writeCompletions(os.Stdout, prefix)
+	},
+}
+
+// writeCompletions writes the completions for prefix to out one per
+// line.  Remote names end in ":" and directories in "/".
+func writeCompletions(out io.Writer, prefix string) {
+	var completions []string
+	colon := strings.IndexRune(prefix, ':')
+	if colon == 0 {
+		// on the fly remote, eg :local:path
+		colon = strings.IndexRune(prefix[1:], ':')
+		if colon < 0 {
+			return
+		}
+		colon++
+	}
+	if colon < 0 {
+		completions = completeRemotes(prefix)
+	} else if listTimeout > 0 {
+		completions = completePaths(prefix[:colon+1], prefix[colon+1:])
+	}
+	for _, completion := range completions {
+		_, _ = fmt.Fprintln(out, completion)
+	}
+}
+
+// completeRemotes returns the remotes starting with prefix
+func completeRemotes(prefix string) (completions []string) {
+	for _, remote := range config.FileSections() {
+		if strings.HasPrefix(remote, prefix) {
+			completions = append(completions, remote+":")
+		}
+	}
+	sort.Strings(completions)
+	return completions
+}
+
+// completePaths returns the paths on remote starting with prefix
+//
+// It gives up and returns nothing if making the Fs and listing the
+// remote takes longer than listTimeout.
+func completePaths(remote, prefix string) (completions []string) {
+	dir, leaf := path.Split(prefix)
+	ctx, cancel := context.WithTimeout(context.Background(), listTimeout)
+	defer cancel()
+	listed := make(chan fs.DirEntries, 1)
+	go func() {
+		f, err := fs.NewFs(remote + dir)
+		if err != nil {
+			listed <- nil
+			return
+		}
+		entries, err := f.List(ctx, "")
+		if err != nil {
+			entries = nil
+		}
+		listed <- entries
+	}()
+	var entries fs.DirEntries
+	select {
+	case entries = <-listed:
+	case <-ctx.Done():
+		fs.Debugf(remote+dir, "Timed out listing for completion")
+		return nil
+	}
+	for _, entry := range entries {
+		name := path.Base(entry.Remote())
+		if !strings.HasPrefix(name, leaf) {
+			continue
+		}
+		completion := remote + dir + name
+		if _, ok := entry.(fs.Directory); ok {
+			completion += "/"
+		}
+		completions = append(completions, completion)
+	}
+	sort.Strings(completions)
+	return completions
+}
@@ -1,10 +1,13 @@
 package genautocomplete
 
 import (
+	"bytes"
 	"io/ioutil"
 	"os"
 	"testing"
 
+	_ "github.com/ncw/rclone/backend/local"
+	"github.com/ncw/rclone/fs/config"
 	"github.com/stretchr/testify/assert"
 )
 
@@ -33,3 +36,40 @@ func TestCompletionZsh(t *testing.T) {
 	assert.NoError(t, err)
 	assert.NotEmpty(t, string(bs))
 }
+
+func TestCompletionFish(t *testing.T) {
+	tempFile, err := ioutil.TempFile("", "completion_fish")
+	assert.NoError(t, err)
+	defer func() { _ = tempFile.Close() }()
+	defer func() { _ = os.Remove(tempFile.Name()) }()
+
+	fishCommandDefinition.Run(fishCommandDefinition, []string{tempFile.Name()})
+
+	bs, err := ioutil.ReadFile(tempFile.Name())
+	assert.NoError(t, err)
+	assert.Contains(t, string(bs), "__rclone_complete_paths")
+	assert.Contains(t, string(bs), "-a 'genautocomplete'")
+}
+
+func TestCompletionList(t *testing.T) {
+	config.LoadConfig()
+	var buf bytes.Buffer
+	writeCompletions(&buf, "potato-remote-which-does-not-exist")
+	assert.Equal(t, "", buf.String())
+
+	// Paths on remotes which don't exist complete to nothing
+	buf.Reset()
+	writeCompletions(&buf, "potato-remote-which-does-not-exist:dir/")
+	assert.Equal(t, "", buf.String())
+
+	// Paths on an on the fly remote
+	dir, err := ioutil.TempDir("", "completion_list")
+	assert.NoError(t, err)
+	defer func() { _ = os.RemoveAll(dir) }()
+	assert.NoError(t, os.Mkdir(dir+"/sub", 0777))
+	assert.NoError(t, ioutil.WriteFile(dir+"/file", []byte("hello"), 0666))
+	buf.Reset()
+	writeCompletions(&buf, ":local:"+dir+"/")
+	assert.Equal(t, ":local:"+dir+"/file\n:local:"+dir+"/sub/\n", buf.String())
+	assert.Equal(t, []string{":local:" + dir + "/sub/"}, completePaths(":local:", dir+"/s"))
+}
@@ -1,8 +1,11 @@
 package genautocomplete
 
 import (
+	"bytes"
+	"io"
 	"log"
 	"os"
+	"strings"
 
 	"github.com/ncw/rclone/cmd"
 	"github.com/spf13/cobra"
@@ -30,6 +33,10 @@ them directly
 
 If you supply a command line argument the script will be written
 there.
+
+Remote names are completed from the config file and paths on remotes
+by listing the remote, giving up after ` + "`$RCLONE_COMPLETION_TIMEOUT`" + `
+(default 2s).  Set it to 0 to only complete remote names.
 `,
 	Run: func(command *cobra.Command, args []string) {
 		cmd.CheckArgs(0, 1, command, args)
@@ -42,9 +49,48 @@ there.
 			log.Fatal(err)
 		}
 		defer func() { _ = outFile.Close() }()
-		err = cmd.Root.GenZshCompletion(outFile)
+		err = genZshCompletion(outFile)
 		if err != nil {
 			log.Fatal(err)
 		}
 	},
 }
+
+// zshCompletionFunc completes the arguments of commands instead of
+// _files in the cobra generated script
+const zshCompletionFunc = `
+# Complete remote names and paths on remotes using rclone itself
+_rclone_files() {
+    local -a dirs files
+    local line
+    for line in ${(f)"$(rclone genautocomplete list --list-timeout "${RCLONE_COMPLETION_TIMEOUT:-2s}" -- "$PREFIX" 2>/dev/null)"}; do
+        if [[ $line == *[:/] ]]; then
+            dirs+=("$line")
+        else
+            files+=("$line")
+        fi
+    done
+    if (( ${#dirs} + ${#files} == 0 )); then
+        _files
+        return
+    fi
+    compadd -S '' -- $dirs
+    compadd -- $files
+}
+
+`
+
+// genZshCompletion writes the cobra generated zsh completion script
+// to out using _rclone_files to complete arguments
+func genZshCompletion(out io.Writer) error {
+	var buf bytes.Buffer
+	err := cmd.Root.GenZshCompletion(&buf)
+	if err != nil {
+		return err
+	}
+	script := strings.Replace(buf.String(), ":_files'", ":_rclone_files'", -1)
+	header := "#compdef " + cmd.Root.Name() + "\n"
+	script = strings.Replace(script, header, header+zshCompletionFunc, 1)
+	_, err = io.WriteString(out, script)
+	return err
+}
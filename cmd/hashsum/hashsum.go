@@ -0,0 +1,125 @@
+package hashsum
+
+import (
+	"fmt"
+	"io"
+	"log"
+	"os"
+
+	"github.com/ncw/rclone/cmd"
+	"github.com/ncw/rclone/fs"
+	"github.com/ncw/rclone/fs/hash"
+	"github.com/ncw/rclone/fs/operations"
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+	"golang.org/x/net/context"
+)
+
+// Globals
+var (
+	OutputBase64 = false
+	OutputFile   = ""
+	CheckFile    = ""
+)
+
+func init() {
+	cmd.Root.AddCommand(commandDefintion)
+	AddHashFlags(commandDefintion.Flags())
+}
+
+// AddHashFlags adds the flags common to the hash commands to flags
+func AddHashFlags(flags *pflag.FlagSet) {
+	flags.BoolVarP(&OutputBase64, "base64", "", OutputBase64, "Output base64 encoded hashsum")
+	flags.StringVarP(&OutputFile, "output-file", "", OutputFile, "Output hashsums to a file rather than the terminal")
+	flags.StringVarP(&CheckFile, "checkfile", "", CheckFile, "Validate hashes against a given SUM file instead of printing them")
+}
+
+// Hashsum lists the hashes of type ht for all the objects in fsrc to
+// stdout or --output-file, or checks them against --checkfile if set.
+func Hashsum(ctx context.Context, ht hash.Type, fsrc fs.Fs) (err error) {
+	if CheckFile != "" {
+		return CheckHashes(ctx, ht, fsrc, CheckFile)
+	}
+	var out io.Writer = os.Stdout
+	if OutputFile != "" {
+		var file *os.File
+		file, err = os.Create(OutputFile)
+		if err != nil {
+			return errors.Wrap(err, "failed to create output file")
+		}
+		defer fs.CheckClose(file, &err)
+		out = file
+	}
+	return operations.HashLister(ctx, ht, OutputBase64, fsrc, out)
+}
+
+// CheckHashes checks the hashes in fsrc against checkFile which may
+// be a local file or on a remote
+func CheckHashes(ctx context.Context, ht hash.Type, fsrc fs.Fs, checkFile string) (err error) {
+	fsum, sumFile := cmd.NewFsFile([]string{checkFile})
+	if sumFile == "" {
+		return errors.Errorf("checkfile %q is a directory", checkFile)
+	}
+	o, err := fsum.NewObject(ctx, sumFile)
+	if err != nil {
+		return errors.Wrap(err, "failed to find checkfile")
+	}
+	in, err := o.Open(ctx)
+	if err != nil {
+		return errors.Wrap(err, "failed to open checkfile")
+	}
+	defer fs.CheckClose(in, &err)
+	return operations.CheckSum(ctx, fsrc, ht, in)
+}
+
+var commandDefintion = &cobra.Command{
+	Use:   "hashsum <hash> remote:path",
+	Short: `Produces a hashsum file for all the objects in the path.`,
+	Long: `
+Produces a hash file for all the objects in the path using the hash
+named.  The output is in the same format as the standard
+md5sum/sha1sum tool.
+
+Run without a hash to see the list of supported hashes, eg
+
+    $ rclone hashsum
+    Supported hashes are:
+      * MD5
+      * SHA-1
+      * DropboxHash
+
+Then
+
+    $ rclone hashsum MD5 remote:path
+
+Use --base64 to write the hashes in base64 rather than hex and
+--output-file to write them to a file rather than the terminal.
+
+Use --checkfile to check the objects against an existing hash file
+(which can be on a remote or local) rather than printing the hashes.
+Any objects with a different hash, objects missing from the file and
+files listed which are missing from the remote are reported.
+`,
+	Run: func(command *cobra.Command, args []string) {
+		cmd.CheckArgs(0, 2, command, args)
+		if len(args) == 0 {
+			fmt.Printf("Supported hashes are:\n")
+			for _, ht := range hash.Supported.Array() {
+				fmt.Printf("  * %v\n", ht)
+			}
+			return
+		} else if len(args) == 1 {
+			log.Fatalf("Need hash type and remote")
+		}
+		var ht hash.Type
+		err := ht.Set(args[0])
+		if err != nil {
+			log.Fatal(err)
+		}
+		fsrc := cmd.NewFsSrc(args[1:])
+		cmd.Run(false, false, command, func() error {
+			return Hashsum(context.Background(), ht, fsrc)
+		})
+	},
+}
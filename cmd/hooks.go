@@ -0,0 +1,134 @@
+// Run commands or post to webhooks when a command finishes
+
+package cmd
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/exec"
+	"runtime"
+	"strings"
+	"time"
+
+	"github.com/ncw/rclone/fs"
+	"github.com/ncw/rclone/fs/accounting"
+	"github.com/ncw/rclone/fs/config/flags"
+	"github.com/ncw/rclone/fs/fshttp"
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+)
+
+// Flags
+var (
+	onSuccess = flags.StringP("on-success", "", "", "Command to run or URL to POST to when the command succeeds")
+	onFailure = flags.StringP("on-failure", "", "", "Command to run or URL to POST to when the command fails")
+)
+
+// hookResult is the result of the command passed to the hooks
+type hookResult struct {
+	Command   string   `json:"command"`
+	Args      []string `json:"args"`
+	Success   bool     `json:"success"`
+	Error     string   `json:"error,omitempty"`
+	Bytes     int64    `json:"bytes"`
+	Transfers int64    `json:"transfers"`
+	Checks    int64    `json:"checks"`
+	Deletes   int64    `json:"deletes"`
+	Errors    int64    `json:"errors"`
+	Elapsed   float64  `json:"elapsed"` // in seconds
+}
+
+// runHooks runs the --on-success or --on-failure hook for the
+// command which has just finished with err.
+//
+// Errors running the hook are logged but don't change the exit code.
+func runHooks(cmd *cobra.Command, start time.Time, err error) {
+	if err == nil && accounting.Stats.Errored() {
+		err = accounting.Stats.GetLastError()
+		if err == nil {
+			err = errors.New("failed with errors")
+		}
+	}
+	hook := *onSuccess
+	if err != nil {
+		hook = *onFailure
+	}
+	if hook == "" {
+		return
+	}
+	result := hookResult{
+		Command:   cmd.Name(),
+		Args:      cmd.Flags().Args(),
+		Success:   err == nil,
+		Bytes:     accounting.Stats.GetBytes(),
+		Transfers: accounting.Stats.GetTransfers(),
+		Checks:    accounting.Stats.GetChecks(),
+		Deletes:   accounting.Stats.GetDeletes(),
+		Errors:    accounting.Stats.GetErrors(),
+		Elapsed:   time.Since(start).Seconds(),
+	}
+	if err != nil {
+		result.Error = err.Error()
+	}
+	if strings.HasPrefix(hook, "http://") || strings.HasPrefix(hook, "https://") {
+		err = postHook(hook, &result)
+	} else {
+		err = execHook(hook, &result)
+	}
+	if err != nil {
+		fs.Errorf(nil, "Hook %q failed: %v", hook, err)
+	}
+}
+
+// postHook POSTs the result as JSON to url
+func postHook(url string, result *hookResult) error {
+	body, err := json.Marshal(result)
+	if err != nil {
+		return err
+	}
+	client := fshttp.NewClient(fs.Config)
+	resp, err := client.Post(url, "application/json", bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	_ = resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return errors.Errorf("HTTP error %s", resp.Status)
+	}
+	fs.Debugf(nil, "Posted result to hook %q", url)
+	return nil
+}
+
+// execHook runs command with the shell passing the result in
+// RCLONE_HOOK_* environment variables
+func execHook(command string, result *hookResult) error {
+	var c *exec.Cmd
+	if runtime.GOOS == "windows" {
+		c = exec.Command("cmd", "/C", command)
+	} else {
+		c = exec.Command("sh", "-c", command)
+	}
+	status := "success"
+	if !result.Success {
+		status = "failure"
+	}
+	c.Env = append(os.Environ(),
+		"RCLONE_HOOK_COMMAND="+result.Command,
+		"RCLONE_HOOK_ARGS="+strings.Join(result.Args, " "),
+		"RCLONE_HOOK_STATUS="+status,
+		"RCLONE_HOOK_ERROR="+result.Error,
+		fmt.Sprintf("RCLONE_HOOK_BYTES=%d", result.Bytes),
+		fmt.Sprintf("RCLONE_HOOK_TRANSFERS=%d", result.Transfers),
+		fmt.Sprintf("RCLONE_HOOK_CHECKS=%d", result.Checks),
+		fmt.Sprintf("RCLONE_HOOK_DELETES=%d", result.Deletes),
+		fmt.Sprintf("RCLONE_HOOK_ERRORS=%d", result.Errors),
+		fmt.Sprintf("RCLONE_HOOK_ELAPSED=%.3f", result.Elapsed),
+	)
+	out, err := c.CombinedOutput()
+	if len(out) > 0 {
+		fs.Infof(nil, "Hook %q output: %s", command, strings.TrimSpace(string(out)))
+	}
+	return err
+}
@@ -0,0 +1,74 @@
+package cmd
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var testHookResult = hookResult{
+	Command:   "copy",
+	Args:      []string{"src:", "dst:"},
+	Success:   false,
+	Error:     "failed with errors",
+	Bytes:     1024,
+	Transfers: 2,
+	Checks:    3,
+	Deletes:   4,
+	Errors:    1,
+	Elapsed:   1.5,
+}
+
+func TestPostHook(t *testing.T) {
+	var (
+		method, contentType string
+		got                 hookResult
+	)
+	status := http.StatusOK
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		method, contentType = r.Method, r.Header.Get("Content-Type")
+		body, _ := ioutil.ReadAll(r.Body)
+		_ = json.Unmarshal(body, &got)
+		w.WriteHeader(status)
+	}))
+	defer ts.Close()
+
+	err := postHook(ts.URL, &testHookResult)
+	require.NoError(t, err)
+	assert.Equal(t, "POST", method)
+	assert.Equal(t, "application/json", contentType)
+	assert.Equal(t, testHookResult, got)
+
+	status = http.StatusInternalServerError
+	err = postHook(ts.URL, &testHookResult)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "500")
+}
+
+func TestExecHook(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("hook command uses sh syntax")
+	}
+	dir, err := ioutil.TempDir("", "rclone-hook-test")
+	require.NoError(t, err)
+	defer func() { _ = os.RemoveAll(dir) }()
+	out := filepath.Join(dir, "env")
+
+	err = execHook(`echo "$RCLONE_HOOK_COMMAND|$RCLONE_HOOK_ARGS|$RCLONE_HOOK_STATUS|$RCLONE_HOOK_ERROR|$RCLONE_HOOK_BYTES|$RCLONE_HOOK_TRANSFERS|$RCLONE_HOOK_CHECKS|$RCLONE_HOOK_DELETES|$RCLONE_HOOK_ERRORS|$RCLONE_HOOK_ELAPSED" > `+out, &testHookResult)
+	require.NoError(t, err)
+	env, err := ioutil.ReadFile(out)
+	require.NoError(t, err)
+	assert.Equal(t, "copy|src: dst:|failure|failed with errors|1024|2|3|4|1|1.500\n", string(env))
+
+	err = execHook("exit 3", &testHookResult)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "exit status 3")
+}
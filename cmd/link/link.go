@@ -0,0 +1,54 @@
+package link
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/ncw/rclone/cmd"
+	"github.com/ncw/rclone/fs"
+	"github.com/ncw/rclone/fs/operations"
+	"github.com/spf13/cobra"
+	"golang.org/x/net/context"
+)
+
+var (
+	expire = fs.Duration(0)
+)
+
+func init() {
+	cmd.Root.AddCommand(commandDefintion)
+	commandDefintion.Flags().VarP(&expire, "expire", "", "The amount of time that the link will be valid, in s or suffix ms|s|m|h|d|w|M|y")
+}
+
+var commandDefintion = &cobra.Command{
+	Use:   "link remote:path",
+	Short: `Generate public link to file/folder.`,
+	Long: `
+rclone link will create or retrieve a public link to the given file or folder.
+
+    rclone link remote:path/to/file
+    rclone link remote:path/to/folder/
+    rclone link --expire 24h remote:path/to/file
+
+If successful, the last line of the output will contain the link. Exact
+capabilities depend on the remote, but the link will always be created
+with the least constraints – e.g. no expiry, no password protection,
+accessible without account.
+
+Use the --expire flag to set the amount of time the link should be
+valid for.  This is only supported by some remotes (eg S3 where it is
+needed for the presigned URL) - others will ignore it.
+`,
+	Run: func(command *cobra.Command, args []string) {
+		cmd.CheckArgs(1, 1, command, args)
+		fsrc, remote := cmd.NewFsFile(args)
+		cmd.Run(false, false, command, func() error {
+			link, err := operations.PublicLink(context.Background(), fsrc, remote, time.Duration(expire))
+			if err != nil {
+				return err
+			}
+			fmt.Println(link)
+			return nil
+		})
+	},
+}
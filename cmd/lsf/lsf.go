@@ -13,6 +13,7 @@ import (
 	"github.com/ncw/rclone/fs/walk"
 	"github.com/pkg/errors"
 	"github.com/spf13/cobra"
+	"golang.org/x/net/context"
 )
 
 var (
@@ -23,6 +24,9 @@ var (
 	hashType  = hash.MD5
 	filesOnly bool
 	dirsOnly  bool
+	csv       bool
+
+	separatorFlagSupplied = false
 )
 
 func init() {
@@ -34,6 +38,7 @@ func init() {
 	flags.VarP(&hashType, "hash", "", "Use this hash when `h` is used in the format MD5|SHA-1|DropboxHash")
 	flags.BoolVarP(&filesOnly, "files-only", "", false, "Only list files.")
 	flags.BoolVarP(&dirsOnly, "dirs-only", "", false, "Only list directories.")
+	flags.BoolVarP(&csv, "csv", "", false, "Output in CSV format.")
 	commandDefintion.Flags().BoolVarP(&recurse, "recursive", "R", false, "Recurse into the listing.")
 }
 
@@ -54,6 +59,8 @@ output:
     s - size
     t - modification time
     h - hash
+    i - ID of object if known
+    m - MimeType of object if known
 
 So if you wanted the path, size and modification time, you would use
 --format "pst", or maybe --format "tsp" to put the path last.
@@ -74,10 +81,27 @@ For example to emulate the md5sum command you can use
 By default the separator is ";" this can be changed with the
 --separator flag.  Note that separators aren't escaped in the path so
 putting it last is a good strategy.
+
+The "i" format shows the ID the remote uses for the object or
+directory, or an empty string if the remote doesn't have IDs.  The "m"
+format shows the MimeType the remote stores, or one guessed from the
+file extension, and "inode/directory" for directories.
+
+Use the --csv flag to output in CSV format (RFC 4180).  Fields which
+contain the separator, quotes or line breaks are quoted so the output
+can be parsed reliably whatever the file names are.  The separator is
+"," with --csv unless --separator is given, in which case it must be
+a single character.  Eg
+
+    rclone lsf --csv --files-only --format ps remote:path
+
+Use the --files-only or --dirs-only flags to list only files or only
+directories.
 ` + lshelp.Help,
 	Run: func(command *cobra.Command, args []string) {
 		cmd.CheckArgs(1, 1, command, args)
 		fsrc := cmd.NewFsSrc(args)
+		separatorFlagSupplied = command.Flags().Changed("separator")
 		cmd.Run(false, false, command, func() error {
 			return Lsf(fsrc, os.Stdout)
 		})
@@ -90,6 +114,15 @@ func Lsf(fsrc fs.Fs, out io.Writer) error {
 	var list operations.ListFormat
 	list.SetSeparator(separator)
 	list.SetDirSlash(dirSlash)
+	if csv {
+		if !separatorFlagSupplied {
+			list.SetSeparator(",")
+		}
+		err := list.SetCSV(true)
+		if err != nil {
+			return err
+		}
+	}
 
 	for _, char := range format {
 		switch char {
@@ -101,12 +134,16 @@ func Lsf(fsrc fs.Fs, out io.Writer) error {
 			list.AddSize()
 		case 'h':
 			list.AddHash(hashType)
+		case 'i':
+			list.AddID()
+		case 'm':
+			list.AddMimeType()
 		default:
 			return errors.Errorf("Unknown format character %q", char)
 		}
 	}
 
-	return walk.Walk(fsrc, "", false, operations.ConfigMaxDepth(recurse), func(path string, entries fs.DirEntries, err error) error {
+	return walk.Walk(context.Background(), fsrc, "", false, operations.ConfigMaxDepth(recurse), func(path string, entries fs.DirEntries, err error) error {
 		if err != nil {
 			fs.CountError(err)
 			fs.Errorf(path, "error listing: %v", err)
@@ -11,6 +11,7 @@ import (
 	"github.com/stretchr/testify/require"
 
 	_ "github.com/ncw/rclone/backend/local"
+	"golang.org/x/net/context"
 )
 
 func TestDefaultLsf(t *testing.T) {
@@ -79,6 +80,7 @@ subdir
 }
 
 func TestFormat(t *testing.T) {
+	ctx := context.Background()
 	fstest.Initialise()
 	f, err := fs.NewFs("testfiles")
 	require.NoError(t, err)
@@ -138,7 +140,7 @@ file3
 	err = Lsf(f, buf)
 	require.NoError(t, err)
 
-	items, _ := list.DirSorted(f, true, "")
+	items, _ := list.DirSorted(ctx, f, true, "")
 	var expectedOutput string
 	for _, item := range items {
 		expectedOutput += item.ModTime().Format("2006-01-02 15:04:05") + "\n"
@@ -186,7 +188,47 @@ subdir__SEP__-1
 	separator = ""
 }
 
+func TestCSV(t *testing.T) {
+	fstest.Initialise()
+	f, err := fs.NewFs("testfiles")
+	require.NoError(t, err)
+	format = "psm"
+	csv = true
+	dirsOnly = false
+	filesOnly = false
+
+	buf := new(bytes.Buffer)
+	err = Lsf(f, buf)
+	require.NoError(t, err)
+	assert.Equal(t, `file1,0,application/octet-stream
+file2,321,application/octet-stream
+file3,1234,application/octet-stream
+subdir,-1,inode/directory
+`, buf.String())
+
+	separatorFlagSupplied = true
+	separator = "__SEP__"
+	buf = new(bytes.Buffer)
+	err = Lsf(f, buf)
+	require.Error(t, err)
+
+	separator = "|"
+	buf = new(bytes.Buffer)
+	err = Lsf(f, buf)
+	require.NoError(t, err)
+	assert.Equal(t, `file1|0|application/octet-stream
+file2|321|application/octet-stream
+file3|1234|application/octet-stream
+subdir|-1|inode/directory
+`, buf.String())
+	format = ""
+	separator = ""
+	separatorFlagSupplied = false
+	csv = false
+}
+
 func TestWholeLsf(t *testing.T) {
+	ctx := context.Background()
 	fstest.Initialise()
 	f, err := fs.NewFs("testfiles")
 	require.NoError(t, err)
@@ -199,8 +241,8 @@ func TestWholeLsf(t *testing.T) {
 	err = Lsf(f, buf)
 	require.NoError(t, err)
 
-	items, _ := list.DirSorted(f, true, "")
-	itemsInSubdir, _ := list.DirSorted(f, true, "subdir")
+	items, _ := list.DirSorted(ctx, f, true, "")
+	itemsInSubdir, _ := list.DirSorted(ctx, f, true, "subdir")
 	var expectedOutput []string
 	for _, item := range items {
 		expectedOutput = append(expectedOutput, item.ModTime().Format("2006-01-02 15:04:05"))
@@ -14,6 +14,7 @@ import (
 	"github.com/ncw/rclone/fs/walk"
 	"github.com/pkg/errors"
 	"github.com/spf13/cobra"
+	"golang.org/x/net/context"
 )
 
 var (
@@ -37,6 +38,7 @@ type lsJSON struct {
 	ModTime Timestamp //`json:",omitempty"`
 	IsDir   bool
 	Hashes  map[string]string `json:",omitempty"`
+	ID      string            `json:",omitempty"`
 }
 
 // Timestamp a time in RFC3339 format with Nanosecond precision secongs
@@ -68,11 +70,18 @@ The output is an array of Items, where each Item looks like this
       "ModTime" : "2017-05-31T16:15:57.034468261+01:00",
       "Name" : "file.txt",
       "Path" : "full/path/goes/here/file.txt",
-      "Size" : 6
+      "Size" : 6,
+      "ID" : "y2djkhiujf83u33"
    }
 
 If --hash is not specified the the Hashes property won't be emitted.
 
+The ID is the ID the remote uses for the object or directory.  It
+won't be emitted if the remote doesn't have IDs.  Some remotes can
+then address the object by ID - see the documentation for the remote,
+eg the drive remote can use "drive:{ID}" for directories and
+"rclone backend copyid" for files.
+
 If --no-modtime is specified then ModTime will be blank.
 
 The Path field will only show folders below the remote path being listed.
@@ -91,7 +100,7 @@ can be processed line by line as each item is written one to a line.
 		cmd.Run(false, false, command, func() error {
 			fmt.Println("[")
 			first := true
-			err := walk.Walk(fsrc, "", false, operations.ConfigMaxDepth(recurse), func(dirPath string, entries fs.DirEntries, err error) error {
+			err := walk.Walk(context.Background(), fsrc, "", false, operations.ConfigMaxDepth(recurse), func(dirPath string, entries fs.DirEntries, err error) error {
 				if err != nil {
 					fs.CountError(err)
 					fs.Errorf(dirPath, "error listing: %v", err)
@@ -109,8 +118,12 @@ can be processed line by line as each item is written one to a line.
 					switch x := entry.(type) {
 					case fs.Directory:
 						item.IsDir = true
+						item.ID = x.ID()
 					case fs.Object:
 						item.IsDir = false
+						if do, ok := x.(fs.IDer); ok {
+							item.ID = do.ID()
+						}
 						if showHash {
 							item.Hashes = make(map[string]string)
 							for _, hashType := range x.Fs().Hashes().Array() {
@@ -1,15 +1,16 @@
 package md5sum
 
 import (
-	"os"
-
 	"github.com/ncw/rclone/cmd"
-	"github.com/ncw/rclone/fs/operations"
+	"github.com/ncw/rclone/cmd/hashsum"
+	"github.com/ncw/rclone/fs/hash"
 	"github.com/spf13/cobra"
+	"golang.org/x/net/context"
 )
 
 func init() {
 	cmd.Root.AddCommand(commandDefintion)
+	hashsum.AddHashFlags(commandDefintion.Flags())
 }
 
 var commandDefintion = &cobra.Command{
@@ -18,12 +19,15 @@ var commandDefintion = &cobra.Command{
 	Long: `
 Produces an md5sum file for all the objects in the path.  This
 is in the same format as the standard md5sum tool produces.
+
+See ` + "`rclone hashsum`" + ` for the --base64, --output-file and
+--checkfile flags.
 `,
 	Run: func(command *cobra.Command, args []string) {
 		cmd.CheckArgs(1, 1, command, args)
 		fsrc := cmd.NewFsSrc(args)
 		cmd.Run(false, false, command, func() error {
-			return operations.Md5sum(fsrc, os.Stdout)
+			return hashsum.Hashsum(context.Background(), hash.MD5, fsrc)
 		})
 	},
 }
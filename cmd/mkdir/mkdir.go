@@ -1,23 +1,68 @@
 package mkdir
 
 import (
+	"log"
+
 	"github.com/ncw/rclone/cmd"
+	"github.com/ncw/rclone/fs"
 	"github.com/ncw/rclone/fs/operations"
 	"github.com/spf13/cobra"
+	"golang.org/x/net/context"
+)
+
+var (
+	parents = false
 )
 
 func init() {
 	cmd.Root.AddCommand(commandDefintion)
+	commandDefintion.Flags().BoolVarP(&parents, "parents", "p", parents, "Make parent directories as needed.")
 }
 
 var commandDefintion = &cobra.Command{
 	Use:   "mkdir remote:path",
 	Short: `Make the path if it doesn't already exist.`,
+	Long: `
+Make the path if it doesn't already exist.
+
+Most remotes create any missing parent directories automatically.
+For those that don't, use the ` + "`--parents`" + ` flag to make each parent
+directory in turn.
+`,
 	Run: func(command *cobra.Command, args []string) {
 		cmd.CheckArgs(1, 1, command, args)
+		if parents {
+			fdst, dir := newFsRoot(args[0])
+			cmd.Run(true, false, command, func() error {
+				return operations.MkdirAll(context.Background(), fdst, dir)
+			})
+			return
+		}
 		fdst := cmd.NewFsDst(args)
 		cmd.Run(true, false, command, func() error {
-			return operations.Mkdir(fdst, "")
+			return operations.Mkdir(context.Background(), fdst, "")
 		})
 	},
 }
+
+// newFsRoot makes an Fs for the root of the remote and returns it
+// along with the path of remote within it.
+//
+// The local filesystem always makes parent directories so for that
+// the Fs for the path itself is returned.
+func newFsRoot(remote string) (fs.Fs, string) {
+	fsInfo, configName, fsPath, err := fs.ParseRemote(remote)
+	if err != nil {
+		fs.CountError(err)
+		log.Fatalf("Failed to create file system for %q: %v", remote, err)
+	}
+	if fsInfo.Name == "local" {
+		return cmd.NewFsDst([]string{remote}), ""
+	}
+	f, err := fsInfo.NewFs(configName, "")
+	if err != nil {
+		fs.CountError(err)
+		log.Fatalf("Failed to create file system for %q: %v", remote, err)
+	}
+	return f, fsPath
+}
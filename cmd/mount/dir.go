@@ -8,6 +8,7 @@ import (
 
 	"bazil.org/fuse"
 	fusefs "bazil.org/fuse/fs"
+	"github.com/ncw/rclone/cmd/mountlib"
 	"github.com/ncw/rclone/fs/log"
 	"github.com/ncw/rclone/vfs"
 	"github.com/pkg/errors"
@@ -17,6 +18,7 @@ import (
 // Dir represents a directory entry
 type Dir struct {
 	*vfs.Dir
+	fsys *FS
 }
 
 // Check interface satsified
@@ -33,7 +35,7 @@ func (d *Dir) Attr(ctx context.Context, a *fuse.Attr) (err error) {
 	a.Mtime = modTime
 	a.Ctime = modTime
 	a.Crtime = modTime
-	// FIXME include Valid so get some caching?
+	a.Valid = mountlib.AttrTimeout
 	// FIXME fs.Debugf(d.path, "Dir.Attr %+v", a)
 	return nil
 }
@@ -72,11 +74,12 @@ func (d *Dir) Lookup(ctx context.Context, req *fuse.LookupRequest, resp *fuse.Lo
 	if err != nil {
 		return nil, translateError(err)
 	}
+	resp.EntryValid = mountlib.AttrTimeout
 	switch x := mnode.(type) {
 	case *vfs.File:
 		return &File{x}, nil
 	case *vfs.Dir:
-		return &Dir{x}, nil
+		return d.fsys.newDir(x), nil
 	}
 	panic("bad type")
 }
@@ -132,7 +135,7 @@ func (d *Dir) Mkdir(ctx context.Context, req *fuse.MkdirRequest) (node fusefs.No
 	if err != nil {
 		return nil, translateError(err)
 	}
-	return &Dir{dir}, nil
+	return d.fsys.newDir(dir), nil
 }
 
 var _ fusefs.NodeRemover = (*Dir)(nil)
@@ -180,3 +183,11 @@ func (d *Dir) Fsync(ctx context.Context, req *fuse.FsyncRequest) (err error) {
 	}
 	return nil
 }
+
+// Check interface satisfied
+var _ fusefs.NodeForgetter = (*Dir)(nil)
+
+// Forget is called when the kernel forgets the directory
+func (d *Dir) Forget() {
+	d.fsys.forgetDir(d)
+}
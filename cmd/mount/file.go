@@ -8,6 +8,7 @@ import (
 
 	"bazil.org/fuse"
 	fusefs "bazil.org/fuse/fs"
+	"github.com/ncw/rclone/cmd/mountlib"
 	"github.com/ncw/rclone/fs/log"
 	"github.com/ncw/rclone/vfs"
 	"golang.org/x/net/context"
@@ -36,6 +37,7 @@ func (f *File) Attr(ctx context.Context, a *fuse.Attr) (err error) {
 	a.Ctime = modTime
 	a.Crtime = modTime
 	a.Blocks = Blocks
+	a.Valid = mountlib.AttrTimeout
 	return nil
 }
 
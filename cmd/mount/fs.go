@@ -5,6 +5,9 @@
 package mount
 
 import (
+	"path"
+	"strings"
+	"sync"
 	"syscall"
 
 	"bazil.org/fuse"
@@ -20,7 +23,11 @@ import (
 // FS represents the top level filing system
 type FS struct {
 	*vfs.VFS
-	f fs.Fs
+	f      fs.Fs
+	server *fusefs.Server // set once mounted
+
+	mu   sync.Mutex
+	dirs map[string]*Dir // directory nodes given to the kernel by path
 }
 
 // Check interface satistfied
@@ -29,12 +36,63 @@ var _ fusefs.FS = (*FS)(nil)
 // NewFS makes a new FS
 func NewFS(f fs.Fs) *FS {
 	fsys := &FS{
-		VFS: vfs.New(f, &vfsflags.Opt),
-		f:   f,
+		VFS:  vfs.New(f, &vfsflags.Opt),
+		f:    f,
+		dirs: make(map[string]*Dir),
 	}
 	return fsys
 }
 
+// newDir makes the node for the directory x, remembering it so it
+// can be invalidated
+func (f *FS) newDir(x *vfs.Dir) *Dir {
+	d := &Dir{Dir: x, fsys: f}
+	f.mu.Lock()
+	f.dirs[x.Path()] = d
+	f.mu.Unlock()
+	return d
+}
+
+// forgetDir is called when the kernel forgets the directory node d
+func (f *FS) forgetDir(d *Dir) {
+	f.mu.Lock()
+	if f.dirs[d.Path()] == d {
+		delete(f.dirs, d.Path())
+	}
+	f.mu.Unlock()
+}
+
+// invalidate tells the kernel that relativePath has changed on the
+// remote so it reads it again.  This makes the macOS Finder refresh
+// its windows.
+func (f *FS) invalidate(relativePath string) {
+	if f.server == nil {
+		return
+	}
+	relativePath = strings.Trim(relativePath, "/")
+	parentPath, leaf := path.Split(relativePath)
+	parentPath = strings.TrimRight(parentPath, "/")
+	f.mu.Lock()
+	node := f.dirs[relativePath]
+	parent := f.dirs[parentPath]
+	f.mu.Unlock()
+	if node != nil {
+		f.logInvalidateError(relativePath, f.server.InvalidateNodeData(node))
+	}
+	if parent != nil && relativePath != "" {
+		f.logInvalidateError(relativePath, f.server.InvalidateEntry(parent, leaf))
+		f.logInvalidateError(parentPath, f.server.InvalidateNodeData(parent))
+	}
+}
+
+// logInvalidateError logs err from invalidating remote unless it is
+// just that the kernel hasn't got it cached
+func (f *FS) logInvalidateError(remote string, err error) {
+	if err != nil && err != fuse.ErrNotCached {
+		fs.Debugf(remote, "Failed to invalidate kernel cache: %v", err)
+	}
+}
+
 // Root returns the root node
 func (f *FS) Root() (node fusefs.Node, err error) {
 	defer log.Trace("", "")("node=%+v, err=%v", &node, &err)
@@ -42,7 +100,7 @@ func (f *FS) Root() (node fusefs.Node, err error) {
 	if err != nil {
 		return nil, translateError(err)
 	}
-	return &Dir{root}, nil
+	return f.newDir(root), nil
 }
 
 // Check interface satsified
@@ -62,6 +120,18 @@ func (f *FS) Statfs(ctx context.Context, req *fuse.StatfsRequest, resp *fuse.Sta
 	resp.Bsize = blockSize  // Block size
 	resp.Namelen = 255      // Maximum file name length?
 	resp.Frsize = blockSize // Fragment size, smallest addressable data size in the file system.
+	// Use the real figures from the remote if it knows them
+	total, used, free := f.VFS.Statfs()
+	if total >= 0 {
+		resp.Blocks = uint64(total) / blockSize
+	}
+	if free >= 0 {
+		resp.Bfree = uint64(free) / blockSize
+		resp.Bavail = resp.Bfree
+	} else if used >= 0 && uint64(used)/blockSize < resp.Blocks {
+		resp.Bfree = resp.Blocks - uint64(used)/blockSize
+		resp.Bavail = resp.Bfree
+	}
 	return nil
 }
 
@@ -28,9 +28,7 @@ func mountOptions(device string) (options []fuse.MountOption) {
 	options = []fuse.MountOption{
 		fuse.MaxReadahead(uint32(mountlib.MaxReadAhead)),
 		fuse.Subtype("rclone"),
-		fuse.FSName(device), fuse.VolumeName(device),
-		fuse.NoAppleDouble(),
-		fuse.NoAppleXattr(),
+		fuse.FSName(device),
 
 		// Options from benchmarking in the fuse module
 		//fuse.MaxReadahead(64 * 1024 * 1024),
@@ -39,6 +37,17 @@ func mountOptions(device string) (options []fuse.MountOption) {
 		// which is probably related to errors people are having
 		//fuse.WritebackCache(),
 	}
+	volumeName := mountlib.VolumeName
+	if volumeName == "" {
+		volumeName = device
+	}
+	options = append(options, fuse.VolumeName(volumeName))
+	if mountlib.NoAppleDouble {
+		options = append(options, fuse.NoAppleDouble())
+	}
+	if mountlib.NoAppleXattr {
+		options = append(options, fuse.NoAppleXattr())
+	}
 	if mountlib.AllowNonEmpty {
 		options = append(options, fuse.AllowNonEmptyMount())
 	}
@@ -58,9 +67,9 @@ func mountOptions(device string) (options []fuse.MountOption) {
 		options = append(options, fuse.WritebackCache())
 	}
 	if len(mountlib.ExtraOptions) > 0 {
-		fs.Errorf(nil, "-o/--option not supported with this FUSE backend")
+		fs.Errorf(nil, "-o/--option %q not supported with this FUSE backend", mountlib.ExtraOptions)
 	}
-	if len(mountlib.ExtraOptions) > 0 {
+	if len(mountlib.ExtraFlags) > 0 {
 		fs.Errorf(nil, "--fuse-flag not supported with this FUSE backend")
 	}
 	return options
@@ -81,6 +90,8 @@ func mount(f fs.Fs, mountpoint string) (*vfs.VFS, <-chan error, func() error, er
 
 	filesys := NewFS(f)
 	server := fusefs.New(c, nil)
+	filesys.server = server
+	filesys.VFS.AddChangeNotify(filesys.invalidate)
 
 	// Serve the mount point in the background returning error to errChan
 	errChan := make(chan error, 1)
@@ -118,46 +129,65 @@ func Mount(f fs.Fs, mountpoint string) error {
 		}
 	}
 
-	// Mount it
-	FS, errChan, unmount, err := mount(f, mountpoint)
-	if err != nil {
-		return errors.Wrap(err, "failed to mount FUSE fs")
-	}
-
 	sigInt := make(chan os.Signal, 1)
 	signal.Notify(sigInt, syscall.SIGINT, syscall.SIGTERM)
 	sigHup := make(chan os.Signal, 1)
 	signal.Notify(sigHup, syscall.SIGHUP)
 
-	if err := sdnotify.SdNotifyReady(); err != nil && err != sdnotify.SdNotifyNoSocket {
-		return errors.Wrap(err, "failed to notify systemd")
-	}
-
-waitloop:
+	notified := false
 	for {
-		select {
-		// umount triggered outside the app
-		case err = <-errChan:
-			break waitloop
-		// Program abort: umount
-		case <-sigInt:
-			err = unmount()
-			break waitloop
-		// user sent SIGHUP to clear the cache
-		case <-sigHup:
-			root, err := FS.Root()
-			if err != nil {
-				fs.Errorf(f, "Error reading root: %v", err)
-			} else {
-				root.ForgetAll()
+		// Mount it
+		FS, errChan, unmount, err := mount(f, mountpoint)
+		if err != nil {
+			return errors.Wrap(err, "failed to mount FUSE fs")
+		}
+
+		if !notified {
+			if err := sdnotify.SdNotifyReady(); err != nil && err != sdnotify.SdNotifyNoSocket {
+				return errors.Wrap(err, "failed to notify systemd")
 			}
+			notified = true
 		}
-	}
 
-	_ = sdnotify.SdNotifyStopping()
-	if err != nil {
-		return errors.Wrap(err, "failed to umount FUSE fs")
+		unhealthy, stopHealthCheck := mountlib.HealthCheck(mountpoint)
+		remount := false
+	waitloop:
+		for {
+			select {
+			// umount triggered outside the app
+			case err = <-errChan:
+				break waitloop
+			// Program abort: umount
+			case <-sigInt:
+				err = unmount()
+				break waitloop
+			// user sent SIGHUP to clear the cache
+			case <-sigHup:
+				root, err := FS.Root()
+				if err != nil {
+					fs.Errorf(f, "Error reading root: %v", err)
+				} else {
+					root.ForgetAll()
+				}
+			// mount stopped responding: unmount and mount again
+			case err = <-unhealthy:
+				fs.Errorf(nil, "Mount not responding, remounting: %v", err)
+				err = unmount()
+				if err != nil {
+					fs.Debugf(nil, "Unmount failed, forcing: %v", err)
+					err = mountlib.ForceUnmount(mountpoint)
+				}
+				remount = err == nil
+				break waitloop
+			}
+		}
+		stopHealthCheck()
+		if !remount {
+			_ = sdnotify.SdNotifyStopping()
+			if err != nil {
+				return errors.Wrap(err, "failed to umount FUSE fs")
+			}
+			return nil
+		}
 	}
-
-	return nil
 }
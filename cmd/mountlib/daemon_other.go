@@ -0,0 +1,25 @@
+// Daemon and unmount functions for unsupported platforms
+
+// +build !linux,!darwin,!freebsd
+
+package mountlib
+
+import (
+	"github.com/pkg/errors"
+)
+
+// isDaemon returns true if this is the background process started
+// by --daemon
+func isDaemon() bool {
+	return false
+}
+
+// startDaemon isn't supported on this OS
+func startDaemon(mountpoint string) error {
+	return errors.New("--daemon is not supported on this OS")
+}
+
+// ForceUnmount isn't supported on this OS
+func ForceUnmount(mountpoint string) error {
+	return errors.New("force unmount is not supported on this OS")
+}
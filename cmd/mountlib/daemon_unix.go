@@ -0,0 +1,104 @@
+// Daemon and unmount functions for unix
+
+// +build linux darwin freebsd
+
+package mountlib
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"syscall"
+	"time"
+
+	"github.com/ncw/rclone/fs"
+	"github.com/pkg/errors"
+)
+
+// daemonEnv is set in the environment of the background process
+const daemonEnv = "_RCLONE_MOUNT_DAEMON"
+
+// daemonWait is how long to wait for the background process to mount
+const daemonWait = 60 * time.Second
+
+// isDaemon returns true if this is the background process started
+// by --daemon
+func isDaemon() bool {
+	return os.Getenv(daemonEnv) != ""
+}
+
+// startDaemon runs rclone again with the same arguments in the
+// background, detached from the terminal, and waits for it to mount
+// mountpoint.
+func startDaemon(mountpoint string) error {
+	cmd := exec.Command(os.Args[0], os.Args[1:]...)
+	cmd.Env = append(os.Environ(), daemonEnv+"=1")
+	cmd.SysProcAttr = &syscall.SysProcAttr{Setsid: true}
+	err := cmd.Start()
+	if err != nil {
+		return errors.Wrap(err, "failed to start daemon")
+	}
+	exited := make(chan error, 1)
+	go func() {
+		exited <- cmd.Wait()
+	}()
+	ticker := time.NewTicker(100 * time.Millisecond)
+	defer ticker.Stop()
+	timeout := time.After(daemonWait)
+	for {
+		select {
+		case err := <-exited:
+			if err == nil {
+				err = errors.New("exited")
+			}
+			return errors.Wrap(err, "daemon failed to mount - use --log-file to see why")
+		case <-ticker.C:
+			mounted, err := isMountpoint(mountpoint)
+			if err != nil {
+				fs.Debugf(nil, "Checking mountpoint: %v", err)
+			}
+			if mounted {
+				fs.Infof(nil, "Mounted %q in daemon with PID %d", mountpoint, cmd.Process.Pid)
+				return nil
+			}
+		case <-timeout:
+			return errors.Errorf("daemon didn't mount %q after %v", mountpoint, daemonWait)
+		}
+	}
+}
+
+// isMountpoint returns true if there is a file system mounted on dir
+// which isn't the one it is in
+func isMountpoint(dir string) (bool, error) {
+	fi, err := os.Stat(dir)
+	if err != nil {
+		return false, err
+	}
+	parentFi, err := os.Stat(filepath.Dir(filepath.Clean(dir)))
+	if err != nil {
+		return false, err
+	}
+	st, ok := fi.Sys().(*syscall.Stat_t)
+	parentSt, parentOk := parentFi.Sys().(*syscall.Stat_t)
+	if !ok || !parentOk {
+		return false, errors.New("can't read device")
+	}
+	return st.Dev != parentSt.Dev, nil
+}
+
+// ForceUnmount unmounts the mountpoint even if it is busy or not
+// responding
+func ForceUnmount(mountpoint string) error {
+	var cmd *exec.Cmd
+	if runtime.GOOS == "linux" {
+		cmd = exec.Command("fusermount", "-u", "-z", mountpoint)
+	} else {
+		cmd = exec.Command("umount", "-f", mountpoint)
+	}
+	out, err := cmd.CombinedOutput()
+	if err != nil {
+		return errors.Wrapf(err, "force unmount failed: %s", out)
+	}
+	return nil
+}
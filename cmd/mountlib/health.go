@@ -0,0 +1,56 @@
+package mountlib
+
+import (
+	"os"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// checkMountpoint stats the mountpoint returning an error if it fails
+// or doesn't respond within timeout
+func checkMountpoint(mountpoint string, timeout time.Duration) error {
+	// buffered so the goroutine can finish if the stat returns late
+	errChan := make(chan error, 1)
+	go func() {
+		_, err := os.Stat(mountpoint)
+		errChan <- err
+	}()
+	select {
+	case err := <-errChan:
+		return err
+	case <-time.After(timeout):
+		return errors.Errorf("mountpoint didn't respond within %v", timeout)
+	}
+}
+
+// HealthCheck checks the mountpoint is responding every
+// --health-check-interval.
+//
+// The channel returned receives an error if the mountpoint stops
+// responding.  It is nil if health checks are disabled so it never
+// fires in a select.  Call stop when finished with it.
+func HealthCheck(mountpoint string) (unhealthy <-chan error, stop func()) {
+	if HealthCheckInterval <= 0 {
+		return nil, func() {}
+	}
+	errChan := make(chan error, 1)
+	done := make(chan struct{})
+	go func() {
+		ticker := time.NewTicker(HealthCheckInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-done:
+				return
+			case <-ticker.C:
+				err := checkMountpoint(mountpoint, HealthCheckTimeout)
+				if err != nil {
+					errChan <- err
+					return
+				}
+			}
+		}
+	}()
+	return errChan, func() { close(done) }
+}
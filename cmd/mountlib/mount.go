@@ -5,6 +5,8 @@ import (
 	"log"
 	"os"
 	"runtime"
+	"strings"
+	"time"
 
 	"github.com/ncw/rclone/cmd"
 	"github.com/ncw/rclone/fs"
@@ -17,17 +19,81 @@ import (
 
 // Options set by command line flags
 var (
-	DebugFUSE                        = false
-	AllowNonEmpty                    = false
-	AllowRoot                        = false
-	AllowOther                       = false
-	DefaultPermissions               = false
-	WritebackCache                   = false
-	MaxReadAhead       fs.SizeSuffix = 128 * 1024
-	ExtraOptions       []string
-	ExtraFlags         []string
+	DebugFUSE                         = false
+	AllowNonEmpty                     = false
+	AllowRoot                         = false
+	AllowOther                        = false
+	DefaultPermissions                = false
+	WritebackCache                    = false
+	MaxReadAhead        fs.SizeSuffix = 128 * 1024
+	AttrTimeout                       = 1 * time.Second
+	Daemon                            = false
+	HealthCheckInterval               = time.Duration(0)
+	HealthCheckTimeout                = 10 * time.Second
+	NoAppleDouble                     = true
+	NoAppleXattr                      = true
+	ExtraOptions        []string
+	ExtraFlags          []string
+	VolumeName          string
 )
 
+// Options which only make sense in fstab and are ignored if passed
+// with -o
+var fstabOptions = map[string]bool{
+	"auto":     true,
+	"noauto":   true,
+	"user":     true,
+	"nouser":   true,
+	"users":    true,
+	"_netdev":  true,
+	"nofail":   true,
+	"defaults": true,
+	"dev":      true,
+	"nodev":    true,
+	"suid":     true,
+	"nosuid":   true,
+	"exec":     true,
+	"noexec":   true,
+}
+
+// parseExtraOptions sets the first class flags from any options in
+// ExtraOptions that rclone knows about, removing them and any
+// fstab only options.  The options may be comma separated as in
+// fstab.  It returns the remaining options to pass to FUSE.
+func parseExtraOptions(extraOptions []string) (rest []string) {
+	for _, options := range extraOptions {
+		for _, option := range strings.Split(options, ",") {
+			option = strings.TrimSpace(option)
+			switch {
+			case option == "":
+			case option == "ro":
+				vfsflags.Opt.ReadOnly = true
+			case option == "rw":
+				vfsflags.Opt.ReadOnly = false
+			case option == "allow_other":
+				AllowOther = true
+			case option == "allow_root":
+				AllowRoot = true
+			case option == "default_permissions":
+				DefaultPermissions = true
+			case option == "nonempty":
+				AllowNonEmpty = true
+			case strings.HasPrefix(option, "volname="):
+				VolumeName = option[len("volname="):]
+			case option == "noappledouble":
+				NoAppleDouble = true
+			case option == "noapplexattr":
+				NoAppleXattr = true
+			case fstabOptions[option] || strings.HasPrefix(option, "x-systemd."):
+				fs.Debugf(nil, "Ignoring fstab mount option %q", option)
+			default:
+				rest = append(rest, option)
+			}
+		}
+	}
+	return rest
+}
+
 // Check is folder is empty
 func checkMountEmpty(mountpoint string) error {
 	fp, fpErr := os.Open(mountpoint)
@@ -147,16 +213,73 @@ for solutions to make ` + commandName + ` mount more reliable.
 Note that all the rclone filters can be used to select a subset of the
 files to be visible in the mount.
 
+### Mount options
+
+The ` + "`--read-only`" + `, ` + "`--allow-other`" + `, ` + "`--allow-root`" + `,
+` + "`--allow-non-empty`" + ` and ` + "`--default-permissions`" + ` flags may also be
+given as FUSE style options with ` + "`-o`" + `, which may be comma separated, eg
+
+    rclone ` + commandName + ` remote:path /path/to/mount -o ro,allow_other
+
+Options which only make sense in fstab (eg ` + "`noauto`" + `, ` + "`user`" + `,
+` + "`_netdev`" + ` and ` + "`x-systemd.*`" + `) are ignored so the same option string
+can be used there.  Any other options are passed on to libfuse/WinFsp.
+
+Use ` + "`--attr-timeout`" + ` to set how long the kernel caches the attributes
+(size, modification time etc) of files and directories.  The default
+is 1s.  Setting it higher makes the mount quicker, but changes made
+on the remote or through the mount may take longer to be noticed.
+Setting it to 0 disables caching.
+
+### macOS
+
+The volume shows up in the Finder with the name of the remote, eg
+` + "`remote:path`" + `.  Use ` + "`--volname`" + ` to give it a different name, eg
+
+    rclone ` + commandName + ` remote:path /path/to/mount --volname "My Files"
+
+By default the Finder's ` + "`.DS_Store`" + ` and ` + "`._*`" + ` (AppleDouble resource
+fork) files aren't stored on the remote, and ` + "`com.apple.*`" + ` extended
+attributes are refused.  Use ` + "`--noappledouble=false`" + ` or
+` + "`--noapplexattr=false`" + ` to allow them if an application needs them.
+These flags do nothing on other platforms.
+
+If ` + "`--poll-interval`" + ` is set and the remote supports it, then when rclone
+is told something has changed on the remote it tells the kernel, so
+a Finder window showing the directory refreshes without having to be
+reopened.  This only works with rclone mount, not rclone cmount.
+
+### Running in the background
+
+Use ` + "`--daemon`" + ` to run the mount in the background.  rclone waits for
+the mount to be ready then exits, leaving the mount running, so it
+can be used in scripts.  Output from the background process is lost
+unless you use ` + "`--log-file`" + ` or ` + "`--syslog`" + `.  Stop the mount by
+unmounting it.  This isn't supported on Windows.
+
+If the FUSE connection stops responding (eg ` + "`ls`" + ` on the mountpoint
+hangs) then ` + "`--health-check-interval`" + ` can be used to detect this and
+remount automatically.  The mountpoint is checked at the interval
+given and if it doesn't respond within ` + "`--health-check-timeout`" + ` then
+it is unmounted, forcibly if necessary, and mounted again.
+
+Whichever way rclone ` + commandName + ` is run, it exits when the mountpoint is
+unmounted with fusermount or umount.
+
 ### systemd
 
 When running rclone ` + commandName + ` as a systemd service, it is possible
 to use Type=notify. In this case the service will enter the started state
 after the mountpoint has been successfully set up.
 Units having the rclone ` + commandName + ` service specified as a requirement
-will see all files and folders immediately in this mode.
+will see all files and folders immediately in this mode.  Don't use
+` + "`--daemon`" + ` with Type=notify - systemd does the daemonisation.  As
+rclone exits when the mountpoint is unmounted, ` + "`ExecStop=/bin/fusermount -u /path/to/local/mount`" + `
+stops the service cleanly.
 ` + vfs.Help,
 		Run: func(command *cobra.Command, args []string) {
 			cmd.CheckArgs(2, 2, command, args)
+			ExtraOptions = parseExtraOptions(ExtraOptions)
 			fdst := cmd.NewFsDst(args)
 
 			// Show stats if the user has specifically requested them
@@ -174,6 +297,15 @@ will see all files and folders immediately in this mode.
 				}
 			}
 
+			// Start the mount in the background and exit once it is ready
+			if Daemon && !isDaemon() {
+				err := startDaemon(args[1])
+				if err != nil {
+					log.Fatalf("Fatal error: %v", err)
+				}
+				return
+			}
+
 			err := Mount(fdst, args[1])
 			if err != nil {
 				log.Fatalf("Fatal error: %v", err)
@@ -194,9 +326,15 @@ will see all files and folders immediately in this mode.
 	flags.BoolVarP(flagSet, &DefaultPermissions, "default-permissions", "", DefaultPermissions, "Makes kernel enforce access control based on the file mode.")
 	flags.BoolVarP(flagSet, &WritebackCache, "write-back-cache", "", WritebackCache, "Makes kernel buffer writes before sending them to rclone. Without this, writethrough caching is used.")
 	flags.FVarP(flagSet, &MaxReadAhead, "max-read-ahead", "", "The number of bytes that can be prefetched for sequential reads.")
+	flags.DurationVarP(flagSet, &AttrTimeout, "attr-timeout", "", AttrTimeout, "Time for which file/directory attributes are cached.")
 	flags.StringArrayVarP(flagSet, &ExtraOptions, "option", "o", []string{}, "Option for libfuse/WinFsp. Repeat if required.")
 	flags.StringArrayVarP(flagSet, &ExtraFlags, "fuse-flag", "", []string{}, "Flags or arguments to be passed direct to libfuse/WinFsp. Repeat if required.")
-	//flags.BoolVarP(flagSet, &foreground, "foreground", "", foreground, "Do not detach.")
+	flags.BoolVarP(flagSet, &Daemon, "daemon", "", Daemon, "Run mount as a daemon (background mode).")
+	flags.DurationVarP(flagSet, &HealthCheckInterval, "health-check-interval", "", HealthCheckInterval, "Interval between checks that the mount is responding, remounting if not. 0 to disable.")
+	flags.DurationVarP(flagSet, &HealthCheckTimeout, "health-check-timeout", "", HealthCheckTimeout, "Time the mount has to respond to a health check.")
+	flags.StringVarP(flagSet, &VolumeName, "volname", "", VolumeName, "Set the volume name (supported by OSX and Windows only).")
+	flags.BoolVarP(flagSet, &NoAppleDouble, "noappledouble", "", NoAppleDouble, "Sets the OSX noappledouble option, ignoring .DS_Store and ._ files (OSX only).")
+	flags.BoolVarP(flagSet, &NoAppleXattr, "noapplexattr", "", NoAppleXattr, "Sets the OSX noapplexattr option, refusing com.apple.* extended attributes (OSX only).")
 
 	// Add in the generic flags
 	vfsflags.AddFlags(flagSet)
@@ -0,0 +1,77 @@
+package mountlib
+
+import (
+	"io/ioutil"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/ncw/rclone/vfs/vfsflags"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseExtraOptions(t *testing.T) {
+	defer func() {
+		vfsflags.Opt.ReadOnly = false
+		AllowOther = false
+		AllowRoot = false
+		DefaultPermissions = false
+		AllowNonEmpty = false
+	}()
+
+	rest := parseExtraOptions([]string{"ro,allow_other,noauto,x-systemd.automount", "uid=1000", "default_permissions"})
+	assert.Equal(t, []string{"uid=1000"}, rest)
+	assert.True(t, vfsflags.Opt.ReadOnly)
+	assert.True(t, AllowOther)
+	assert.True(t, DefaultPermissions)
+	assert.False(t, AllowRoot)
+	assert.False(t, AllowNonEmpty)
+
+	rest = parseExtraOptions([]string{"rw", "nonempty,allow_root,,"})
+	assert.Nil(t, rest)
+	assert.False(t, vfsflags.Opt.ReadOnly)
+	assert.True(t, AllowRoot)
+	assert.True(t, AllowNonEmpty)
+
+	NoAppleDouble, NoAppleXattr = false, false
+	rest = parseExtraOptions([]string{"volname=My Files,noappledouble", "noapplexattr"})
+	assert.Nil(t, rest)
+	assert.Equal(t, "My Files", VolumeName)
+	assert.True(t, NoAppleDouble)
+	assert.True(t, NoAppleXattr)
+}
+
+func TestCheckMountpoint(t *testing.T) {
+	dir, err := ioutil.TempDir("", "rclone-mountlib-test")
+	require.NoError(t, err)
+	defer func() {
+		require.NoError(t, os.Remove(dir))
+	}()
+
+	assert.NoError(t, checkMountpoint(dir, time.Second))
+	assert.Error(t, checkMountpoint(dir+"-notfound", time.Second))
+}
+
+func TestHealthCheckDisabled(t *testing.T) {
+	unhealthy, stop := HealthCheck("/notfound")
+	defer stop()
+	assert.Nil(t, unhealthy)
+}
+
+func TestHealthCheck(t *testing.T) {
+	oldInterval := HealthCheckInterval
+	HealthCheckInterval = 10 * time.Millisecond
+	defer func() {
+		HealthCheckInterval = oldInterval
+	}()
+
+	unhealthy, stop := HealthCheck("/notfound/rclone-mountlib-test")
+	defer stop()
+	select {
+	case err := <-unhealthy:
+		assert.Error(t, err)
+	case <-time.After(10 * time.Second):
+		t.Fatal("health check didn't fail")
+	}
+}
@@ -4,16 +4,19 @@ import (
 	"github.com/ncw/rclone/cmd"
 	"github.com/ncw/rclone/fs/sync"
 	"github.com/spf13/cobra"
+	"golang.org/x/net/context"
 )
 
 // Globals
 var (
 	deleteEmptySrcDirs = false
+	createEmptySrcDirs = false
 )
 
 func init() {
 	cmd.Root.AddCommand(commandDefintion)
 	commandDefintion.Flags().BoolVarP(&deleteEmptySrcDirs, "delete-empty-src-dirs", "", deleteEmptySrcDirs, "Delete empty source dirs after move")
+	commandDefintion.Flags().BoolVarP(&createEmptySrcDirs, "create-empty-src-dirs", "", createEmptySrcDirs, "Create empty source dirs on destination after move")
 }
 
 var commandDefintion = &cobra.Command{
@@ -34,8 +37,18 @@ move will be used, otherwise it will copy it (server side if possible)
 into ` + "`dest:path`" + ` then delete the original (if no errors on copy) in
 ` + "`source:path`" + `.
 
+If you want to check each file has arrived safely before the original
+is deleted, use the --delete-after-verification flag.  After the copy
+rclone finds the file on the destination again and compares its hash
+with the source, or downloads it and compares the contents if they
+don't share a hash, and only then deletes the source.  Server side
+moves aren't checked as no copy is made.
+
 If you want to delete empty source directories after move, use the --delete-empty-src-dirs flag.
 
+If you want the empty source directories to be created on the
+destination, use the --create-empty-src-dirs flag.
+
 **Important**: Since this can cause data loss, test first with the
 --dry-run flag.
 `,
@@ -44,7 +57,7 @@ If you want to delete empty source directories after move, use the --delete-empt
 		fsrc, fdst := cmd.NewFsSrcDst(args)
 		cmd.Run(true, true, command, func() error {
 
-			return sync.MoveDir(fdst, fsrc, deleteEmptySrcDirs)
+			return sync.MoveDir(context.Background(), fdst, fsrc, deleteEmptySrcDirs, createEmptySrcDirs)
 		})
 	},
 }
@@ -8,6 +8,7 @@ import (
 	"github.com/ncw/rclone/fs"
 	"github.com/ncw/rclone/fs/walk"
 	"github.com/pkg/errors"
+	"golang.org/x/net/context"
 )
 
 // Dir represents a directory found in the remote
@@ -130,7 +131,7 @@ func Scan(f fs.Fs) (chan *Dir, chan error, chan struct{}) {
 	updated := make(chan struct{}, 1)
 	go func() {
 		parents := map[string]*Dir{}
-		err := walk.Walk(f, "", false, fs.Config.MaxDepth, func(dirPath string, entries fs.DirEntries, err error) error {
+		err := walk.Walk(context.Background(), f, "", false, fs.Config.MaxDepth, func(dirPath string, entries fs.DirEntries, err error) error {
 			if err != nil {
 				return err // FIXME mark directory as errored instead of aborting
 			}
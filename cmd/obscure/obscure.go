@@ -15,6 +15,19 @@ func init() {
 var commandDefintion = &cobra.Command{
 	Use:   "obscure password",
 	Short: `Obscure password for use in the rclone.conf`,
+	Long: `Obscure password for use in the rclone.conf
+
+Obscuring is reversible - it stops passwords being read at a glance
+but anyone with the config file can recover them.  If that isn't
+good enough, set the config value to a reference to a secret held
+elsewhere instead, eg
+
+    pass = env:SFTP_PASSWORD
+    pass = file:/run/secrets/sftp_password
+    pass = exec:pass show sftp
+
+These are read when the config is used and don't need obscuring.
+`,
 	Run: func(command *cobra.Command, args []string) {
 		cmd.CheckArgs(1, 1, command, args)
 		cmd.Run(false, false, command, func() error {
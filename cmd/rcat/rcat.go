@@ -8,10 +8,16 @@ import (
 	"github.com/ncw/rclone/cmd"
 	"github.com/ncw/rclone/fs/operations"
 	"github.com/spf13/cobra"
+	"golang.org/x/net/context"
+)
+
+var (
+	size = int64(-1)
 )
 
 func init() {
 	cmd.Root.AddCommand(commandDefintion)
+	commandDefintion.Flags().Int64VarP(&size, "size", "", size, "Size of the data on stdin if known, -1 if not.")
 }
 
 var commandDefintion = &cobra.Command{
@@ -39,7 +45,14 @@ setting this cutoff too high will decrease your performance.
 Note that the upload can also not be retried because the data is
 not kept around until the upload succeeds. If you need to transfer
 a lot of data, you're better off caching locally and then
-` + "`rclone move`" + ` it to the destination.`,
+` + "`rclone move`" + ` it to the destination.
+
+If you know the size of the data in advance then pass it with
+` + "`--size`" + `. This lets rcat upload the data directly with a single
+streaming request even on remotes which need to know the length of the
+file before the upload starts (eg B2 and S3 single part uploads)
+rather than spooling it to memory or disk first. The data must be
+exactly this size or the upload will fail.`,
 	Run: func(command *cobra.Command, args []string) {
 		cmd.CheckArgs(1, 1, command, args)
 
@@ -50,7 +63,7 @@ a lot of data, you're better off caching locally and then
 
 		fdst, dstFileName := cmd.NewFsDstFile(args)
 		cmd.Run(false, false, command, func() error {
-			_, err := operations.Rcat(fdst, dstFileName, os.Stdin, time.Now())
+			_, err := operations.RcatSize(context.Background(), fdst, dstFileName, os.Stdin, size, time.Now())
 			return err
 		})
 	},
@@ -0,0 +1,199 @@
+package resticlayout
+
+import (
+	"path"
+	"sync"
+
+	"github.com/ncw/rclone/cmd"
+	"github.com/ncw/rclone/fs"
+	"github.com/ncw/rclone/fs/accounting"
+	"github.com/ncw/rclone/fs/operations"
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+	"golang.org/x/net/context"
+)
+
+// Layouts of the data directory of a restic repository
+const (
+	LayoutSharded = "sharded" // data/<xx>/<id> where xx is the first 2 characters of id
+	LayoutFlat    = "flat"    // data/<id>
+)
+
+// dataDir is the directory in the repository the layouts apply to
+const dataDir = "data"
+
+func init() {
+	cmd.Root.AddCommand(commandDefintion)
+}
+
+var commandDefintion = &cobra.Command{
+	Use:   "resticlayout sharded|flat remote:repo",
+	Short: `Convert a restic repository between the sharded and flat layouts.`,
+	Long: `
+Moves the data files of the restic repository in ` + "`remote:repo`" + `
+into the layout given.
+
+restic's default layout, which rest-server and its clients expect,
+keeps the data files in 256 directories named after the first two
+characters of each file, eg
+
+    data/5f/5f1ae4...
+
+Some other hosting setups (eg restic's old s3 layout) keep them all
+in data itself
+
+    data/5f1ae4...
+
+Use ` + "`sharded`" + ` to convert a flat repository to restic's default layout,
+or ` + "`flat`" + ` to convert back.  Only the data directory is changed.
+
+The files are moved server side if the remote supports it so they
+aren't downloaded and uploaded again.  Files already in the right
+place are left alone so it is safe to run again if interrupted.
+Files which don't look like restic data files are left alone and
+logged.
+
+Make sure no restic clients are using the repository while it is
+converted, and test first with the --dry-run flag.
+
+    rclone resticlayout --dry-run sharded remote:repo
+`,
+	Run: func(command *cobra.Command, args []string) {
+		cmd.CheckArgs(2, 2, command, args)
+		layout := args[0]
+		f := cmd.NewFsDst(args[1:])
+		cmd.Run(true, true, command, func() error {
+			return Convert(context.Background(), f, layout)
+		})
+	},
+}
+
+// isHex returns true if s is all lower case hex characters
+func isHex(s string) bool {
+	for _, c := range s {
+		if (c < '0' || c > '9') && (c < 'a' || c > 'f') {
+			return false
+		}
+	}
+	return true
+}
+
+// isBlobID returns true if name looks like the name of a restic data
+// file - 64 lower case hex characters
+func isBlobID(name string) bool {
+	return len(name) == 64 && isHex(name)
+}
+
+// isShard returns true if name is a directory of the sharded layout
+func isShard(name string) bool {
+	return len(name) == 2 && isHex(name)
+}
+
+// move records an object to be moved to remote
+type move struct {
+	o      fs.Object
+	remote string
+}
+
+// Convert moves the data files of the restic repository in f into
+// layout
+func Convert(ctx context.Context, f fs.Fs, layout string) error {
+	if layout != LayoutSharded && layout != LayoutFlat {
+		return errors.Errorf("unknown layout %q - must be %q or %q", layout, LayoutSharded, LayoutFlat)
+	}
+	entries, err := f.List(ctx, dataDir)
+	if err != nil {
+		return errors.Wrap(err, "failed to list restic data directory")
+	}
+
+	// Find the files which need moving
+	var (
+		moves  []move
+		shards []string
+	)
+	for _, entry := range entries {
+		name := path.Base(entry.Remote())
+		switch x := entry.(type) {
+		case fs.Object:
+			if !isBlobID(name) {
+				fs.Logf(x, "Ignoring file which isn't a restic data file")
+			} else if layout == LayoutSharded {
+				moves = append(moves, move{o: x, remote: path.Join(dataDir, name[:2], name)})
+			}
+		case fs.Directory:
+			if !isShard(name) {
+				fs.Logf(x, "Ignoring directory which isn't a restic data directory")
+			} else if layout == LayoutFlat {
+				shards = append(shards, x.Remote())
+			}
+		}
+	}
+	for _, shard := range shards {
+		entries, err := f.List(ctx, shard)
+		if err != nil {
+			return errors.Wrapf(err, "failed to list %q", shard)
+		}
+		for _, entry := range entries {
+			name := path.Base(entry.Remote())
+			if o, ok := entry.(fs.Object); ok && isBlobID(name) && name[:2] == path.Base(shard) {
+				moves = append(moves, move{o: o, remote: path.Join(dataDir, name)})
+			} else {
+				fs.Logf(entry, "Ignoring entry which isn't a restic data file")
+			}
+		}
+	}
+	fs.Infof(f, "Moving %d restic data files into the %s layout", len(moves), layout)
+
+	// Move them with --transfers workers
+	var (
+		wg      sync.WaitGroup
+		mu      sync.Mutex
+		in      = make(chan move, fs.Config.Transfers)
+		lastErr error
+	)
+	wg.Add(fs.Config.Transfers)
+	for i := 0; i < fs.Config.Transfers; i++ {
+		go func() {
+			defer wg.Done()
+			for m := range in {
+				err := moveFile(ctx, f, m)
+				if err != nil {
+					mu.Lock()
+					lastErr = err
+					mu.Unlock()
+				}
+			}
+		}()
+	}
+	for _, m := range moves {
+		in <- m
+	}
+	close(in)
+	wg.Wait()
+
+	// Remove the shard directories which are now empty
+	if lastErr == nil {
+		for _, shard := range shards {
+			err = operations.Rmdir(ctx, f, shard)
+			if err != nil {
+				fs.Errorf(shard, "Failed to remove restic data directory: %v", err)
+				lastErr = err
+			}
+		}
+	}
+	return lastErr
+}
+
+// moveFile moves m.o to m.remote
+func moveFile(ctx context.Context, f fs.Fs, m move) (err error) {
+	accounting.Stats.Transferring(m.remote)
+	defer func() {
+		accounting.Stats.DoneTransferring(m.remote, err == nil)
+	}()
+	dst, err := f.NewObject(ctx, m.remote)
+	if err != nil {
+		dst = nil
+	}
+	_, err = operations.Move(ctx, f, dst, m.remote, m.o)
+	return err
+}
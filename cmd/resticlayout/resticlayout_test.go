@@ -0,0 +1,67 @@
+package resticlayout
+
+import (
+	"strings"
+	"testing"
+
+	_ "github.com/ncw/rclone/backend/local"
+	"github.com/ncw/rclone/fs"
+	"github.com/ncw/rclone/fstest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/net/context"
+)
+
+var t1 = fstest.Time("2001-02-03T04:05:06.499999999Z")
+
+// TestMain drives the tests
+func TestMain(m *testing.M) {
+	fstest.TestMain(m)
+}
+
+func TestIsBlobID(t *testing.T) {
+	assert.True(t, isBlobID(strings.Repeat("0123456789abcdef", 4)))
+	assert.False(t, isBlobID(strings.Repeat("0123456789ABCDEF", 4)))
+	assert.False(t, isBlobID("0123456789abcdef"))
+	assert.True(t, isShard("5f"))
+	assert.False(t, isShard("5g"))
+	assert.False(t, isShard("5f5"))
+}
+
+func TestConvertUnknownLayout(t *testing.T) {
+	r := fstest.NewRun(t)
+	defer r.Finalise()
+
+	err := Convert(context.Background(), r.Flocal, "potato")
+	assert.Error(t, err)
+}
+
+func TestConvert(t *testing.T) {
+	r := fstest.NewRun(t)
+	defer r.Finalise()
+	ctx := context.Background()
+	id1 := strings.Repeat("5f", 32)
+	id2 := strings.Repeat("a0", 32)
+	config := r.WriteFile("config", "config", t1)
+	readme := r.WriteFile("data/README", "not a data file", t1)
+	flat1 := r.WriteFile("data/"+id1, "one", t1)
+	flat2 := r.WriteFile("data/"+id2, "two", t1)
+	sharded1 := fstest.NewItem("data/5f/"+id1, "one", t1)
+	sharded2 := fstest.NewItem("data/a0/"+id2, "two", t1)
+
+	// Converting to flat does nothing as it is flat already
+	require.NoError(t, Convert(ctx, r.Flocal, LayoutFlat))
+	fstest.CheckItems(t, r.Flocal, config, readme, flat1, flat2)
+
+	// Convert to sharded
+	require.NoError(t, Convert(ctx, r.Flocal, LayoutSharded))
+	fstest.CheckItems(t, r.Flocal, config, readme, sharded1, sharded2)
+
+	// Running it again does nothing
+	require.NoError(t, Convert(ctx, r.Flocal, LayoutSharded))
+	fstest.CheckItems(t, r.Flocal, config, readme, sharded1, sharded2)
+
+	// Convert back to flat which removes the shard directories
+	require.NoError(t, Convert(ctx, r.Flocal, LayoutFlat))
+	fstest.CheckListingWithPrecision(t, r.Flocal, []fstest.Item{config, readme, flat1, flat2}, []string{"data"}, fs.ModTimeNotSupported)
+}
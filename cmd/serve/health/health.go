@@ -0,0 +1,133 @@
+// Package health implements liveness and readiness endpoints for the
+// serve commands
+package health
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/ncw/rclone/cmd/serve/httplib"
+	"github.com/ncw/rclone/fs"
+	"github.com/ncw/rclone/fs/config/flags"
+	"github.com/spf13/pflag"
+	"golang.org/x/net/context"
+)
+
+// Options for the health endpoints
+type Options struct {
+	Addr    string        // if set serve the endpoints on this address only
+	Timeout time.Duration // time the backend has to respond
+}
+
+// DefaultOpt is the default values used for Options
+var DefaultOpt = Options{
+	Timeout: 10 * time.Second,
+}
+
+// Opt is options set by command line flags
+var Opt = DefaultOpt
+
+// AddFlags adds the health check flags to the command
+func AddFlags(flagSet *pflag.FlagSet) {
+	flags.StringVarP(flagSet, &Opt.Addr, "health-addr", "", Opt.Addr, "IPaddress:Port to serve /healthz and /readyz on instead of the main address.")
+	flags.DurationVarP(flagSet, &Opt.Timeout, "health-timeout", "", Opt.Timeout, "Time the remote has to respond to a health check.")
+}
+
+// Help describes the health endpoints for the serve commands
+var Help = `
+### Health checks
+
+The server responds to ` + "`/healthz`" + ` and ` + "`/readyz`" + ` with
+` + "`200 OK`" + ` if the remote responds to a cheap call (quota
+information if the remote supports it, otherwise a listing of the
+root) within ` + "`--health-timeout`" + `, and with ` + "`503 Service Unavailable`" + `
+if it doesn't.  These can be used as the liveness and readiness probes
+of a Kubernetes deployment.  The endpoints don't need authentication.
+
+Use ` + "`--health-addr`" + ` to serve them on a separate IP address and
+port instead, eg ` + "`--health-addr :8090`" + `.  This also stops them
+hiding files called healthz or readyz in the root of the remote.
+`
+
+// Checker checks the health of an Fs
+type Checker struct {
+	f       fs.Fs
+	timeout time.Duration
+}
+
+// New makes a Checker for f using the options in Opt
+func New(f fs.Fs) *Checker {
+	return &Checker{
+		f:       f,
+		timeout: Opt.Timeout,
+	}
+}
+
+// Check makes a cheap call to the Fs to see whether it is responding
+func (c *Checker) Check() error {
+	ctx, cancel := context.WithTimeout(context.Background(), c.timeout)
+	defer cancel()
+	errs := make(chan error, 1)
+	go func() {
+		if do := c.f.Features().About; do != nil {
+			_, err := do(ctx)
+			errs <- err
+			return
+		}
+		_, err := c.f.List(ctx, "")
+		if err == fs.ErrorDirNotFound {
+			// the remote responded even if the root isn't there yet
+			err = nil
+		}
+		errs <- err
+	}()
+	select {
+	case err := <-errs:
+		return err
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// ServeHTTP replies to a health check
+func (c *Checker) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != "GET" && r.Method != "HEAD" {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	w.Header().Set("Cache-Control", "no-cache")
+	err := c.Check()
+	if err != nil {
+		fs.Errorf(c.f, "%s: health check failed: %v", r.URL.Path, err)
+		http.Error(w, "Remote not responding: "+err.Error(), http.StatusServiceUnavailable)
+		return
+	}
+	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	w.WriteHeader(http.StatusOK)
+	if r.Method == "GET" {
+		_, _ = w.Write([]byte("OK\n"))
+	}
+}
+
+// register adds the health endpoints using handle
+func (c *Checker) register(handle func(pattern string, handler http.Handler)) {
+	handle("/healthz", c)
+	handle("/readyz", c)
+}
+
+// Handle adds the health endpoints for f to s, or if --health-addr is
+// set starts a server of their own on that address.
+func Handle(f fs.Fs, s *httplib.Server) {
+	c := New(f)
+	if Opt.Addr == "" {
+		c.register(s.HandleUnauthenticated)
+		return
+	}
+	healthMux := http.NewServeMux()
+	c.register(healthMux.Handle)
+	fs.Logf(f, "Serving health checks on http://%s/", Opt.Addr)
+	go func() {
+		err := http.ListenAndServe(Opt.Addr, healthMux)
+		fs.Errorf(f, "Health check server failed: %v", err)
+	}()
+}
@@ -0,0 +1,60 @@
+package health
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/ncw/rclone/fs"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/net/context"
+)
+
+// testFs is an fs.Fs whose List returns err after delay
+type testFs struct {
+	fs.Fs
+	delay time.Duration
+	err   error
+}
+
+func (f *testFs) Features() *fs.Features { return &fs.Features{} }
+
+func (f *testFs) String() string { return "testFs" }
+
+func (f *testFs) List(ctx context.Context, dir string) (fs.DirEntries, error) {
+	time.Sleep(f.delay)
+	return nil, f.err
+}
+
+func TestHealth(t *testing.T) {
+	for _, test := range []struct {
+		name   string
+		f      *testFs
+		method string
+		status int
+		body   string
+	}{
+		{"OK", &testFs{}, "GET", http.StatusOK, "OK\n"},
+		{"Head", &testFs{}, "HEAD", http.StatusOK, ""},
+		{"NoRoot", &testFs{err: fs.ErrorDirNotFound}, "GET", http.StatusOK, "OK\n"},
+		{"Error", &testFs{err: errors.New("boom")}, "GET", http.StatusServiceUnavailable, "Remote not responding: boom\n"},
+		{"Timeout", &testFs{delay: time.Second}, "GET", http.StatusServiceUnavailable, "Remote not responding: context deadline exceeded\n"},
+		{"Method", &testFs{}, "POST", http.StatusMethodNotAllowed, "Method not allowed\n"},
+	} {
+		c := New(test.f)
+		c.timeout = 50 * time.Millisecond
+		mux := http.NewServeMux()
+		c.register(mux.Handle)
+		for _, path := range []string{"/healthz", "/readyz"} {
+			req, err := http.NewRequest(test.method, path, nil)
+			require.NoError(t, err)
+			w := httptest.NewRecorder()
+			mux.ServeHTTP(w, req)
+			assert.Equal(t, test.status, w.Code, test.name+path)
+			assert.Equal(t, test.body, w.Body.String(), test.name+path)
+		}
+	}
+}
@@ -3,7 +3,7 @@ package http
 import (
 	"fmt"
 	"html/template"
-	"log"
+	"io"
 	"net/http"
 	"os"
 	"path"
@@ -11,22 +11,26 @@ import (
 	"strings"
 
 	"github.com/ncw/rclone/cmd"
+	"github.com/ncw/rclone/cmd/serve/health"
+	"github.com/ncw/rclone/cmd/serve/httplib"
 	"github.com/ncw/rclone/fs"
 	"github.com/ncw/rclone/fs/accounting"
 	"github.com/ncw/rclone/lib/rest"
 	"github.com/ncw/rclone/vfs"
 	"github.com/ncw/rclone/vfs/vfsflags"
 	"github.com/spf13/cobra"
+	"golang.org/x/net/context"
 )
 
-// Globals
+// Options set by command line flags
 var (
-	bindAddress = "localhost:8080"
+	Opt = httplib.DefaultOpt
 )
 
 func init() {
-	Command.Flags().StringVarP(&bindAddress, "addr", "", bindAddress, "IPaddress:Port to bind server to.")
+	httplib.AddFlags(Command.Flags(), &Opt)
 	vfsflags.AddFlags(Command.Flags())
+	health.AddFlags(Command.Flags())
 }
 
 // Command definition for cobra
@@ -37,58 +41,45 @@ var Command = &cobra.Command{
 over HTTP.  This can be viewed in a web browser or you can make a
 remote of type http read from it.
 
-Use --addr to specify which IP address and port the server should
-listen on, eg --addr 1.2.3.4:8000 or --addr :8080 to listen to all
-IPs.  By default it only listens on localhost.
-
 You can use the filter flags (eg --include, --exclude) to control what
 is served.
 
-The server will log errors.  Use -v to see access logs.
-
 --bwlimit will be respected for file transfers.  Use --stats to
 control the stats printing.
-` + vfs.Help,
+` + httplib.Help + health.Help + vfs.Help,
 	Run: func(command *cobra.Command, args []string) {
 		cmd.CheckArgs(1, 1, command, args)
 		f := cmd.NewFsSrc(args)
 		cmd.Run(false, true, command, func() error {
-			s := newServer(f, bindAddress)
-			s.serve()
-			return nil
+			s := newServer(f, &Opt)
+			return s.serve()
 		})
 	},
 }
 
 // server contains everything to run the server
 type server struct {
-	f           fs.Fs
-	bindAddress string
-	vfs         *vfs.VFS
+	*httplib.Server
+	f   fs.Fs
+	vfs *vfs.VFS
 }
 
-func newServer(f fs.Fs, bindAddress string) *server {
+func newServer(f fs.Fs, opt *httplib.Options) *server {
+	mux := http.NewServeMux()
 	s := &server{
-		f:           f,
-		bindAddress: bindAddress,
-		vfs:         vfs.New(f, &vfsflags.Opt),
+		Server: httplib.NewServer(mux, opt),
+		f:      f,
+		vfs:    vfs.New(f, &vfsflags.Opt),
 	}
+	mux.HandleFunc("/", s.handler)
+	health.Handle(f, s.Server)
 	return s
 }
 
-// serve creates the http server
-func (s *server) serve() {
-	mux := http.NewServeMux()
-	mux.HandleFunc("/", s.handler)
-	// FIXME make a transport?
-	httpServer := &http.Server{
-		Addr:           s.bindAddress,
-		Handler:        mux,
-		MaxHeaderBytes: 1 << 20,
-	}
-	initServer(httpServer)
-	fs.Logf(s.f, "Serving on http://%s/", bindAddress)
-	log.Fatal(httpServer.ListenAndServe())
+// serve runs the http server - doesn't return unless there is an error
+func (s *server) serve() error {
+	fs.Logf(s.f, "Serving on %s", s.URL())
+	return s.Serve()
 }
 
 // handler reads incoming requests and dispatches them
@@ -208,6 +199,28 @@ func (s *server) serveDir(w http.ResponseWriter, r *http.Request, dirRemote stri
 	}
 }
 
+// readSeekCloser is a file being served
+type readSeekCloser interface {
+	io.ReadSeeker
+	io.Closer
+}
+
+// cancelReader stops reading if ctx is cancelled, eg because the
+// client disconnected.  The file is then closed which stops the
+// transfer from the backend.
+type cancelReader struct {
+	readSeekCloser
+	ctx context.Context
+}
+
+// Read the data if the request hasn't been cancelled
+func (r *cancelReader) Read(p []byte) (n int, err error) {
+	if err = r.ctx.Err(); err != nil {
+		return 0, err
+	}
+	return r.readSeekCloser.Read(p)
+}
+
 // serveFile serves a file object at remote
 func (s *server) serveFile(w http.ResponseWriter, r *http.Request, remote string) {
 	node, err := s.vfs.Stat(remote)
@@ -231,9 +244,6 @@ func (s *server) serveFile(w http.ResponseWriter, r *http.Request, remote string
 	obj := entry.(fs.Object)
 	file := node.(*vfs.File)
 
-	// Set content length since we know how long the object is
-	w.Header().Set("Content-Length", strconv.FormatInt(node.Size(), 10))
-
 	// Set content type
 	mimeType := fs.MimeType(obj)
 	if mimeType == "application/octet-stream" && path.Ext(remote) == "" {
@@ -244,15 +254,32 @@ func (s *server) serveFile(w http.ResponseWriter, r *http.Request, remote string
 
 	// If HEAD no need to read the object since we have set the headers
 	if r.Method == "HEAD" {
+		// Set content length since we know how long the object is
+		w.Header().Set("Content-Length", strconv.FormatInt(node.Size(), 10))
 		return
 	}
 
-	// open the object
-	in, err := file.Open(os.O_RDONLY)
+	// open the object - directly from the OS if possible so the
+	// data can be sent with sendfile
+	var in readSeekCloser
+	osFile, err := file.OpenOSFile()
 	if err != nil {
 		internalError(remote, w, "Failed to open file", err)
 		return
 	}
+	if osFile != nil {
+		// Account the data as it is sent as it isn't read
+		// through the VFS
+		in = osFile
+		w = &httplib.AccountingResponseWriter{ResponseWriter: w, Account: true}
+	} else {
+		handle, err := file.Open(os.O_RDONLY)
+		if err != nil {
+			internalError(remote, w, "Failed to open file", err)
+			return
+		}
+		in = &cancelReader{readSeekCloser: handle, ctx: httplib.RequestContext(r)}
+	}
 	defer func() {
 		err := in.Close()
 		if err != nil {
@@ -265,6 +292,10 @@ func (s *server) serveFile(w http.ResponseWriter, r *http.Request, remote string
 	defer accounting.Stats.DoneTransferring(remote, true)
 	// FIXME in = fs.NewAccount(in, obj).WithBuffer() // account the transfer
 
-	// Serve the file
+	// Serve the file - this deals with the Range header, including
+	// suffix ranges (bytes=-500), multiple ranges which are sent as
+	// multipart/byteranges and replying 416 Requested Range Not
+	// Satisfiable with a Content-Range of */size.  It sets the
+	// Content-Length for the part of the file being sent.
 	http.ServeContent(w, r, remote, node.ModTime(), in)
 }
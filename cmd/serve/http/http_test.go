@@ -4,6 +4,7 @@ package http
 
 import (
 	"flag"
+	"io"
 	"io/ioutil"
 	"net"
 	"net/http"
@@ -13,11 +14,14 @@ import (
 	"time"
 
 	_ "github.com/ncw/rclone/backend/local"
+	"github.com/ncw/rclone/cmd/serve/httplib"
 	"github.com/ncw/rclone/fs"
+	"github.com/ncw/rclone/fs/accounting"
 	"github.com/ncw/rclone/fs/config"
 	"github.com/ncw/rclone/fs/filter"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
+	"golang.org/x/net/context"
 )
 
 var updateGolden = flag.Bool("updategolden", false, "update golden files for regression test")
@@ -28,8 +32,12 @@ const (
 )
 
 func startServer(t *testing.T, f fs.Fs) {
-	s := newServer(f, testBindAddress)
-	go s.serve()
+	opt := httplib.DefaultOpt
+	opt.ListenAddr = testBindAddress
+	s := newServer(f, &opt)
+	go func() {
+		_ = s.serve()
+	}()
 
 	// try to connect to the test server
 	pause := time.Millisecond
@@ -83,11 +91,13 @@ func checkGolden(t *testing.T, fileName string, got []byte) {
 
 func TestGET(t *testing.T) {
 	for _, test := range []struct {
-		URL    string
-		Status int
-		Golden string
-		Method string
-		Range  string
+		URL          string
+		Status       int
+		Golden       string
+		Method       string
+		Range        string
+		ContentRange string
+		ContentType  string
 	}{
 		{
 			URL:    "",
@@ -176,6 +186,33 @@ func TestGET(t *testing.T) {
 			Range:  "bytes=3-",
 			Golden: "testdata/golden/two3-.txt",
 		},
+		{
+			URL:          "two.txt",
+			Status:       http.StatusPartialContent,
+			Range:        "bytes=-3",
+			ContentRange: "bytes 8-10/11",
+			Golden:       "testdata/golden/two-3.txt",
+		},
+		{
+			URL:          "two.txt",
+			Status:       http.StatusPartialContent,
+			Range:        "bytes=-100",
+			ContentRange: "bytes 0-10/11",
+			Golden:       "testdata/golden/two.txt",
+		},
+		{
+			URL:         "two.txt",
+			Status:      http.StatusPartialContent,
+			Range:       "bytes=0-1,5-6",
+			ContentType: "multipart/byteranges; boundary=",
+		},
+		{
+			URL:          "two.txt",
+			Status:       http.StatusRequestedRangeNotSatisfiable,
+			Range:        "bytes=100-",
+			ContentRange: "bytes */11",
+			Golden:       "testdata/golden/two100-.txt",
+		},
 	} {
 		method := test.Method
 		if method == "" {
@@ -189,11 +226,56 @@ func TestGET(t *testing.T) {
 		resp, err := http.DefaultClient.Do(req)
 		require.NoError(t, err)
 		assert.Equal(t, test.Status, resp.StatusCode, test.Golden)
+		if test.ContentRange != "" {
+			assert.Equal(t, test.ContentRange, resp.Header.Get("Content-Range"), test.Golden)
+		}
+		if test.ContentType != "" {
+			assert.Contains(t, resp.Header.Get("Content-Type"), test.ContentType, test.Range)
+		}
 		body, err := ioutil.ReadAll(resp.Body)
 		require.NoError(t, err)
+		if method != "HEAD" && resp.ContentLength >= 0 {
+			assert.Equal(t, resp.ContentLength, int64(len(body)), test.Golden)
+		}
+
+		if test.Golden != "" {
+			checkGolden(t, test.Golden, body)
+		}
+	}
+}
 
-		checkGolden(t, test.Golden, body)
+func TestGETAccounting(t *testing.T) {
+	before := accounting.Stats.GetBytes()
+	resp, err := http.Get(testURL + "two.txt")
+	require.NoError(t, err)
+	body, err := ioutil.ReadAll(resp.Body)
+	require.NoError(t, err)
+	require.NoError(t, resp.Body.Close())
+	// The server may account the bytes after the client has read them
+	for i := 0; i < 100 && accounting.Stats.GetBytes()-before < int64(len(body)); i++ {
+		time.Sleep(10 * time.Millisecond)
 	}
+	assert.Equal(t, int64(len(body)), accounting.Stats.GetBytes()-before)
+}
+
+// nopCloser adds a Close method to an io.ReadSeeker
+type nopCloser struct {
+	io.ReadSeeker
+}
+
+func (nopCloser) Close() error { return nil }
+
+func TestCancelReader(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	in := &cancelReader{readSeekCloser: nopCloser{strings.NewReader("hello")}, ctx: ctx}
+	buf := make([]byte, 2)
+	n, err := in.Read(buf)
+	require.NoError(t, err)
+	assert.Equal(t, "he", string(buf[:n]))
+
+	cancel()
+	_, err = in.Read(buf)
+	assert.Equal(t, context.Canceled, err)
 }
 
 type mockNode struct {
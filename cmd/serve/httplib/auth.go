@@ -0,0 +1,209 @@
+package httplib
+
+import (
+	"bufio"
+	"crypto/sha1"
+	"crypto/subtle"
+	"encoding/base64"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/ncw/rclone/fs"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// readMethods are the HTTP methods which only read from the server
+var readMethods = map[string]bool{
+	"GET":      true,
+	"HEAD":     true,
+	"OPTIONS":  true,
+	"PROPFIND": true,
+}
+
+// basicAuth wraps handler so it needs a user and password which check
+// accepts.
+//
+// With --anonymous-read requests which only read and carry no
+// credentials are let through without a user.
+func (s *Server) basicAuth(handler http.Handler, check func(user, pass string) bool) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		user, pass, ok := r.BasicAuth()
+		if !ok && s.Opt.AnonymousRead && readMethods[r.Method] {
+			handler.ServeHTTP(w, r)
+			return
+		}
+		if !ok || !check(user, pass) {
+			if ok {
+				fs.Infof(r.URL.Path, "%s: Unauthorized request from user %q", r.RemoteAddr, user)
+			}
+			w.Header().Set("WWW-Authenticate", `Basic realm="`+s.Opt.Realm+`"`)
+			http.Error(w, http.StatusText(http.StatusUnauthorized), http.StatusUnauthorized)
+			return
+		}
+		handler.ServeHTTP(w, r)
+	})
+}
+
+// checkUser checks user and pass against --user and --pass
+func (s *Server) checkUser(user, pass string) bool {
+	userOK := subtle.ConstantTimeCompare([]byte(user), []byte(s.Opt.BasicUser)) == 1
+	passOK := subtle.ConstantTimeCompare([]byte(pass), []byte(s.Opt.BasicPass)) == 1
+	return userOK && passOK
+}
+
+// htPasswd checks users against an apache style htpasswd file,
+// re-reading it if it changes
+type htPasswd struct {
+	path    string
+	mu      sync.Mutex
+	modTime time.Time
+	users   map[string]string // user to password hash
+}
+
+// newHtPasswd makes an htPasswd reading the file at path
+func newHtPasswd(path string) *htPasswd {
+	h := &htPasswd{path: path}
+	if err := h.reload(); err != nil {
+		fs.Errorf(nil, "Failed to read htpasswd file: %v", err)
+	}
+	return h
+}
+
+// reload reads the htpasswd file if it has changed - call with the
+// lock held
+func (h *htPasswd) reload() error {
+	fi, err := os.Stat(h.path)
+	if err != nil {
+		return err
+	}
+	if fi.ModTime().Equal(h.modTime) && h.users != nil {
+		return nil
+	}
+	in, err := os.Open(h.path)
+	if err != nil {
+		return err
+	}
+	defer fs.CheckClose(in, &err)
+	users := make(map[string]string)
+	scanner := bufio.NewScanner(in)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		i := strings.IndexByte(line, ':')
+		if i < 0 {
+			fs.Errorf(nil, "Ignoring malformed line in htpasswd file %q", h.path)
+			continue
+		}
+		users[line[:i]] = line[i+1:]
+	}
+	if err = scanner.Err(); err != nil {
+		return err
+	}
+	h.users = users
+	h.modTime = fi.ModTime()
+	return nil
+}
+
+// check returns whether user and pass are in the htpasswd file
+func (h *htPasswd) check(user, pass string) bool {
+	h.mu.Lock()
+	if err := h.reload(); err != nil {
+		fs.Errorf(nil, "Failed to re-read htpasswd file: %v", err)
+	}
+	hash, ok := h.users[user]
+	h.mu.Unlock()
+	if !ok {
+		return false
+	}
+	return checkHash(hash, pass)
+}
+
+// checkHash checks pass against an htpasswd hash
+//
+// Only bcrypt and SHA1 hashes are supported
+func checkHash(hash, pass string) bool {
+	switch {
+	case strings.HasPrefix(hash, "$2"):
+		return bcrypt.CompareHashAndPassword([]byte(hash), []byte(pass)) == nil
+	case strings.HasPrefix(hash, "{SHA}"):
+		sum := sha1.Sum([]byte(pass))
+		want := base64.StdEncoding.EncodeToString(sum[:])
+		return subtle.ConstantTimeCompare([]byte(hash[5:]), []byte(want)) == 1
+	}
+	return false
+}
+
+// dirRules restricts directories to some users
+type dirRules map[string][]string // directory to users allowed
+
+// loadDirRules reads the --dir-auth file
+func loadDirRules(file string) (rules dirRules, err error) {
+	in, err := os.Open(file)
+	if err != nil {
+		return nil, err
+	}
+	defer fs.CheckClose(in, &err)
+	rules = make(dirRules)
+	scanner := bufio.NewScanner(in)
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) == 0 || strings.HasPrefix(fields[0], "#") {
+			continue
+		}
+		rules[cleanDir(fields[0])] = fields[1:]
+	}
+	return rules, scanner.Err()
+}
+
+// cleanDir makes dir into the form used as a key in dirRules
+func cleanDir(dir string) string {
+	return path.Clean("/" + dir)
+}
+
+// allowed returns whether user may access urlPath
+func (rules dirRules) allowed(user, urlPath string) bool {
+	dir := cleanDir(urlPath)
+	for {
+		if users, ok := rules[dir]; ok {
+			for _, allowed := range users {
+				if allowed == user {
+					return true
+				}
+			}
+			return false
+		}
+		if dir == "/" {
+			return true
+		}
+		dir = path.Dir(dir)
+	}
+}
+
+// restrict wraps handler so requests are only let through if the
+// user is allowed into the path and the Destination of any webdav
+// COPY or MOVE.
+//
+// This must be wrapped by basicAuth so the user is checked.
+func (rules dirRules) restrict(handler http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		user, _, _ := r.BasicAuth()
+		ok := rules.allowed(user, r.URL.Path)
+		if dest := r.Header.Get("Destination"); ok && dest != "" {
+			u, err := url.Parse(dest)
+			ok = err == nil && rules.allowed(user, u.Path)
+		}
+		if !ok {
+			fs.Infof(r.URL.Path, "%s: Forbidden request from user %q", r.RemoteAddr, user)
+			http.Error(w, http.StatusText(http.StatusForbidden), http.StatusForbidden)
+			return
+		}
+		handler.ServeHTTP(w, r)
+	})
+}
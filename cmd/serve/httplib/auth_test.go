@@ -0,0 +1,199 @@
+package httplib
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/crypto/bcrypt"
+)
+
+func TestCheckHash(t *testing.T) {
+	bcryptHash, err := bcrypt.GenerateFromPassword([]byte("secret"), bcrypt.MinCost)
+	require.NoError(t, err)
+	for _, test := range []struct {
+		hash string
+		pass string
+		want bool
+	}{
+		{string(bcryptHash), "secret", true},
+		{string(bcryptHash), "wrong", false},
+		{"{SHA}5en6G6MezRroT3XKqkdPOmY/BfQ=", "secret", true},
+		{"{SHA}5en6G6MezRroT3XKqkdPOmY/BfQ=", "wrong", false},
+		{"secret", "secret", false},
+	} {
+		assert.Equal(t, test.want, checkHash(test.hash, test.pass), test.hash)
+	}
+}
+
+// do makes a request to s returning the recorded response
+func do(t *testing.T, s *Server, r *http.Request) *httptest.ResponseRecorder {
+	w := httptest.NewRecorder()
+	s.httpServer.Handler.ServeHTTP(w, r)
+	return w
+}
+
+func TestBasicAuth(t *testing.T) {
+	dir, err := ioutil.TempDir("", "rclone-httplib-test")
+	require.NoError(t, err)
+	defer func() {
+		require.NoError(t, os.RemoveAll(dir))
+	}()
+	htpasswd := filepath.Join(dir, "htpasswd")
+	require.NoError(t, ioutil.WriteFile(htpasswd, []byte("# comment\nuser:{SHA}5en6G6MezRroT3XKqkdPOmY/BfQ=\n"), 0600))
+
+	userOpt := DefaultOpt
+	userOpt.BasicUser = "user"
+	userOpt.BasicPass = "secret"
+	fileOpt := DefaultOpt
+	fileOpt.HtPasswd = htpasswd
+	for _, opt := range []*Options{&userOpt, &fileOpt} {
+		s := NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			_, _ = w.Write([]byte("hello"))
+		}), opt)
+		s.HandleUnauthenticated("/open", http.NotFoundHandler())
+
+		r, err := http.NewRequest("GET", "/", nil)
+		require.NoError(t, err)
+		w := do(t, s, r)
+		assert.Equal(t, http.StatusUnauthorized, w.Code)
+		assert.Equal(t, `Basic realm="rclone"`, w.Header().Get("WWW-Authenticate"))
+
+		r.SetBasicAuth("user", "wrong")
+		w = do(t, s, r)
+		assert.Equal(t, http.StatusUnauthorized, w.Code)
+
+		r.SetBasicAuth("user", "secret")
+		w = do(t, s, r)
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.Equal(t, "hello", w.Body.String())
+
+		r, err = http.NewRequest("GET", "/open", nil)
+		require.NoError(t, err)
+		w = do(t, s, r)
+		assert.Equal(t, http.StatusNotFound, w.Code)
+	}
+}
+
+func TestAnonymousRead(t *testing.T) {
+	opt := DefaultOpt
+	opt.BasicUser = "user"
+	opt.BasicPass = "secret"
+	opt.AnonymousRead = true
+	s := NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte("hello"))
+	}), &opt)
+
+	for _, test := range []struct {
+		method string
+		user   string
+		pass   string
+		want   int
+	}{
+		{"GET", "", "", http.StatusOK},
+		{"HEAD", "", "", http.StatusOK},
+		{"PROPFIND", "", "", http.StatusOK},
+		{"GET", "user", "wrong", http.StatusUnauthorized},
+		{"POST", "", "", http.StatusUnauthorized},
+		{"DELETE", "", "", http.StatusUnauthorized},
+		{"POST", "user", "wrong", http.StatusUnauthorized},
+		{"POST", "user", "secret", http.StatusOK},
+		{"DELETE", "user", "secret", http.StatusOK},
+	} {
+		r, err := http.NewRequest(test.method, "/", nil)
+		require.NoError(t, err)
+		if test.user != "" {
+			r.SetBasicAuth(test.user, test.pass)
+		}
+		w := do(t, s, r)
+		assert.Equal(t, test.want, w.Code, "%s %s", test.method, test.user)
+	}
+}
+
+func TestCORS(t *testing.T) {
+	opt := DefaultOpt
+	opt.AllowOrigin = "*"
+	s := NewServer(http.NotFoundHandler(), &opt)
+
+	r, err := http.NewRequest("OPTIONS", "/", nil)
+	require.NoError(t, err)
+	r.Header.Set("Origin", "http://example.com")
+	r.Header.Set("Access-Control-Request-Method", "PUT")
+	r.Header.Set("Access-Control-Request-Headers", "Authorization")
+	w := do(t, s, r)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "*", w.Header().Get("Access-Control-Allow-Origin"))
+	assert.Equal(t, "Authorization", w.Header().Get("Access-Control-Allow-Headers"))
+	assert.NotEqual(t, "", w.Header().Get("Access-Control-Allow-Methods"))
+
+	r, err = http.NewRequest("GET", "/", nil)
+	require.NoError(t, err)
+	w = do(t, s, r)
+	assert.Equal(t, http.StatusNotFound, w.Code)
+	assert.Equal(t, "*", w.Header().Get("Access-Control-Allow-Origin"))
+}
+
+func TestDirAuth(t *testing.T) {
+	dir, err := ioutil.TempDir("", "rclone-httplib-test")
+	require.NoError(t, err)
+	defer func() {
+		require.NoError(t, os.RemoveAll(dir))
+	}()
+	rulesFile := filepath.Join(dir, "rules")
+	require.NoError(t, ioutil.WriteFile(rulesFile, []byte("# comment\n/private alice bob\nprivate/alice/ alice\n"), 0600))
+	rules, err := loadDirRules(rulesFile)
+	require.NoError(t, err)
+	assert.Equal(t, dirRules{
+		"/private":       {"alice", "bob"},
+		"/private/alice": {"alice"},
+	}, rules)
+
+	for _, test := range []struct {
+		user string
+		path string
+		want bool
+	}{
+		{"carol", "/", true},
+		{"carol", "/public/file.txt", true},
+		{"carol", "/privateer/", true},
+		{"carol", "/private", false},
+		{"carol", "/private/", false},
+		{"carol", "/private/../private/file.txt", false},
+		{"bob", "/private/file.txt", true},
+		{"bob", "/private/alice/file.txt", false},
+		{"alice", "/private/alice/file.txt", true},
+	} {
+		assert.Equal(t, test.want, rules.allowed(test.user, test.path), test.user+" "+test.path)
+	}
+
+	opt := DefaultOpt
+	opt.BasicUser = "bob"
+	opt.BasicPass = "secret"
+	opt.DirAuth = rulesFile
+	s := NewServer(http.NotFoundHandler(), &opt)
+	for _, test := range []struct {
+		method string
+		path   string
+		dest   string
+		want   int
+	}{
+		{"GET", "/private/file.txt", "", http.StatusNotFound},
+		{"GET", "/private/alice/file.txt", "", http.StatusForbidden},
+		{"MOVE", "/private/file.txt", "http://localhost/private/alice/file.txt", http.StatusForbidden},
+		{"MOVE", "/private/file.txt", "http://localhost/file.txt", http.StatusNotFound},
+	} {
+		r, err := http.NewRequest(test.method, test.path, nil)
+		require.NoError(t, err)
+		r.SetBasicAuth("bob", "secret")
+		if test.dest != "" {
+			r.Header.Set("Destination", test.dest)
+		}
+		w := do(t, s, r)
+		assert.Equal(t, test.want, w.Code, test.method+" "+test.path)
+	}
+}
@@ -0,0 +1,23 @@
+// Request context parts go1.7+
+
+//+build go1.7
+
+package httplib
+
+import (
+	"net/http"
+
+	"golang.org/x/net/context" // switch to "context" when we stop supporting go1.6
+)
+
+// RequestContext returns the context of r.  It is cancelled when the
+// client goes away.
+func RequestContext(r *http.Request) context.Context {
+	return r.Context()
+}
+
+// WithContext returns a shallow copy of r with its context changed to
+// ctx
+func WithContext(r *http.Request, ctx context.Context) *http.Request {
+	return r.WithContext(ctx)
+}
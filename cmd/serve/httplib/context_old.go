@@ -0,0 +1,23 @@
+// Request context parts pre go1.7
+
+//+build !go1.7
+
+package httplib
+
+import (
+	"net/http"
+
+	"golang.org/x/net/context"
+)
+
+// RequestContext returns a background context as requests don't
+// have one before go1.7
+func RequestContext(r *http.Request) context.Context {
+	return context.Background()
+}
+
+// WithContext returns r unchanged as requests can't carry a context
+// before go1.7
+func WithContext(r *http.Request, ctx context.Context) *http.Request {
+	return r
+}
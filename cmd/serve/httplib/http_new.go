@@ -0,0 +1,15 @@
+// HTTP parts go1.8+
+
+//+build go1.8
+
+package httplib
+
+import (
+	"net/http"
+)
+
+// Initialise the http.Server for go1.8+
+func initServer(s *http.Server, opt *Options) {
+	s.ReadHeaderTimeout = opt.ServerReadHeaderTimeout // time to send the headers
+	s.IdleTimeout = opt.ServerIdleTimeout             // time to keep idle connections open
+}
@@ -2,12 +2,12 @@
 
 //+build !go1.8
 
-package http
+package httplib
 
 import (
 	"net/http"
 )
 
 // Initialise the http.Server for pre go1.8
-func initServer(s *http.Server) {
+func initServer(s *http.Server, opt *Options) {
 }
@@ -0,0 +1,350 @@
+// Package httplib provides common functionality for http servers
+package httplib
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/ncw/rclone/fs"
+	"github.com/ncw/rclone/fs/accounting"
+	"github.com/ncw/rclone/fs/config/flags"
+	"github.com/spf13/pflag"
+)
+
+// Help contains text describing the http server to add to the command
+// help.
+var Help = `
+### Server options
+
+Use --addr to specify which IP address and port the server should
+listen on, eg --addr 1.2.3.4:8000 or --addr :8080 to listen to all
+IPs.  By default it only listens on localhost.
+
+--server-read-timeout and --server-write-timeout can be used to
+control the timeouts on the server.  Note that this is the total time
+for a transfer.
+
+--server-read-header-timeout is the time a client has to send the
+request headers and --server-idle-timeout is how long an idle keep
+alive connection is kept open.  Lowering these helps protect a public
+facing server against clients which hold connections open (eg
+slowloris) whereas raising --server-idle-timeout suits clients making
+lots of small requests.  These two need rclone built with go1.8 or
+later.
+
+--max-header-bytes controls the maximum number of bytes the server
+will accept in the HTTP header.
+
+HTTP/2 is used automatically when the server is serving TLS and the
+client supports it.  Use --disable-http2 to only use HTTP/1.1.
+
+Use --allow-origin to set the Access-Control-Allow-Origin header so
+the server can be used from web pages on other sites, eg
+--allow-origin "*".
+
+The server logs errors.  Use -v to see access logs.
+
+#### Authentication
+
+By default this will serve files without needing a login.
+
+You can either use an htpasswd file which can take lots of users, or
+set a single username and password with the --user and --pass flags.
+
+Use --htpasswd /path/to/htpasswd to provide an htpasswd file.  This is
+in standard apache format and supports bcrypt and SHA1 passwords.
+Using bcrypt is recommended.
+
+To create an htpasswd file:
+
+    touch htpasswd
+    htpasswd -B htpasswd user
+    htpasswd -B htpasswd anotherUser
+
+The password file can be updated while rclone is running.
+
+Use --realm to set the authentication realm.
+
+Use --anonymous-read to let clients read (GET, HEAD, OPTIONS and
+PROPFIND) without logging in while still needing a login to write or
+delete, eg so machines on a trusted network can restore from a
+repository which only the backup agent can write to.
+
+Use --dir-auth /path/to/rules to only allow some of the users into
+some directories.  Each line of the file is a directory followed by
+the users allowed into it and everything under it, eg
+
+    # only alice and bob can see /private
+    /private alice bob
+    # only alice can see /private/alice
+    /private/alice alice
+
+The longest matching directory is used.  Directories without a rule
+are available to any user who can log in.  Other users get 403
+Forbidden, however they can still see the names of the directories in
+the listing of the parent directory.
+
+#### SSL/TLS
+
+By default this will serve over http.  If you want you can serve over
+https.  You will need to supply the --cert and --key flags.  If you
+wish to do client side certificate validation then you will need to
+supply --client-ca also.
+
+--cert should be a either a PEM encoded certificate or a concatenation
+of that with the CA certificate.  --key should be the PEM encoded
+private key and --client-ca should be the PEM encoded client
+certificate authority certificate.
+`
+
+// Options contains options for the http Server
+type Options struct {
+	ListenAddr              string        // Port to listen on
+	ServerReadTimeout       time.Duration // Timeout for server reading data
+	ServerWriteTimeout      time.Duration // Timeout for server writing data
+	ServerReadHeaderTimeout time.Duration // Timeout for server reading the request headers
+	ServerIdleTimeout       time.Duration // Timeout for idle keep alive connections
+	MaxHeaderBytes          int           // Maximum size of request header
+	DisableHTTP2            bool          // Set to only serve HTTP/1.1
+	SslCert                 string        // SSL PEM key (concatenation of certificate and CA certificate)
+	SslKey                  string        // SSL PEM Private key
+	ClientCA                string        // Client certificate authority to verify clients with
+	HtPasswd                string        // htpasswd file - if not provided no authentication is done
+	Realm                   string        // realm for authentication
+	BasicUser               string        // single username for basic auth if not using Htpasswd
+	BasicPass               string        // password for BasicUser
+	DirAuth                 string        // file of per directory rules for which users can access what
+	AnonymousRead           bool          // allow reading without authentication
+	AllowOrigin             string        // value for the Access-Control-Allow-Origin header
+}
+
+// DefaultOpt is the default values used for Options
+var DefaultOpt = Options{
+	ListenAddr:              "localhost:8080",
+	ServerReadTimeout:       1 * time.Hour,
+	ServerWriteTimeout:      1 * time.Hour,
+	ServerReadHeaderTimeout: 10 * time.Second,
+	ServerIdleTimeout:       60 * time.Second,
+	MaxHeaderBytes:          1 << 20,
+	Realm:                   "rclone",
+}
+
+// AddFlags adds the flags for the http server to flagSet, storing
+// the results in opt.
+func AddFlags(flagSet *pflag.FlagSet, opt *Options) {
+	flags.StringVarP(flagSet, &opt.ListenAddr, "addr", "", opt.ListenAddr, "IPaddress:Port or :Port to bind server to.")
+	flags.DurationVarP(flagSet, &opt.ServerReadTimeout, "server-read-timeout", "", opt.ServerReadTimeout, "Timeout for server reading data")
+	flags.DurationVarP(flagSet, &opt.ServerWriteTimeout, "server-write-timeout", "", opt.ServerWriteTimeout, "Timeout for server writing data")
+	flags.DurationVarP(flagSet, &opt.ServerReadHeaderTimeout, "server-read-header-timeout", "", opt.ServerReadHeaderTimeout, "Timeout for server reading the request headers")
+	flags.DurationVarP(flagSet, &opt.ServerIdleTimeout, "server-idle-timeout", "", opt.ServerIdleTimeout, "Timeout for idle keep alive connections")
+	flags.IntVarP(flagSet, &opt.MaxHeaderBytes, "max-header-bytes", "", opt.MaxHeaderBytes, "Maximum size of request header")
+	flags.BoolVarP(flagSet, &opt.DisableHTTP2, "disable-http2", "", opt.DisableHTTP2, "Only serve HTTP/1.1")
+	flags.StringVarP(flagSet, &opt.SslCert, "cert", "", opt.SslCert, "SSL PEM key (concatenation of certificate and CA certificate)")
+	flags.StringVarP(flagSet, &opt.SslKey, "key", "", opt.SslKey, "SSL PEM Private key")
+	flags.StringVarP(flagSet, &opt.ClientCA, "client-ca", "", opt.ClientCA, "Client certificate authority to verify clients with")
+	flags.StringVarP(flagSet, &opt.HtPasswd, "htpasswd", "", opt.HtPasswd, "htpasswd file - if not provided no authentication is done")
+	flags.StringVarP(flagSet, &opt.Realm, "realm", "", opt.Realm, "realm for authentication")
+	flags.StringVarP(flagSet, &opt.BasicUser, "user", "", opt.BasicUser, "User name for authentication.")
+	flags.StringVarP(flagSet, &opt.BasicPass, "pass", "", opt.BasicPass, "Password for authentication.")
+	flags.StringVarP(flagSet, &opt.DirAuth, "dir-auth", "", opt.DirAuth, "File of directories and the users allowed to access them.")
+	flags.BoolVarP(flagSet, &opt.AnonymousRead, "anonymous-read", "", opt.AnonymousRead, "Allow reading without authentication, only writes need a login.")
+	flags.StringVarP(flagSet, &opt.AllowOrigin, "allow-origin", "", opt.AllowOrigin, "Origin which cross-domain requests (CORS) can be executed from.")
+}
+
+// Server contains info about the running http server
+type Server struct {
+	Opt        Options
+	handler    http.Handler   // original handler
+	mux        *http.ServeMux // routes unauthenticated paths round the auth
+	httpServer *http.Server
+	useSSL     bool // if server is configured for SSL/TLS
+}
+
+// NewServer creates an http server serving handler with the options
+// in opt.
+//
+// The handler is wrapped with the authentication, CORS and access
+// logging configured in opt.
+func NewServer(handler http.Handler, opt *Options) *Server {
+	s := &Server{
+		Opt:     *opt,
+		handler: handler,
+		mux:     http.NewServeMux(),
+	}
+
+	// Use htpasswd if required on everything
+	authHandler := handler
+	if s.Opt.DirAuth != "" {
+		if s.Opt.HtPasswd == "" && s.Opt.BasicUser == "" {
+			log.Fatalf("Need --htpasswd or --user to use --dir-auth")
+		}
+		rules, err := loadDirRules(s.Opt.DirAuth)
+		if err != nil {
+			log.Fatalf("Failed to read --dir-auth file: %v", err)
+		}
+		authHandler = rules.restrict(handler)
+	}
+	if s.Opt.HtPasswd != "" {
+		fs.Infof(nil, "Using %q as htpasswd storage", s.Opt.HtPasswd)
+		authHandler = s.basicAuth(authHandler, newHtPasswd(s.Opt.HtPasswd).check)
+	} else if s.Opt.BasicUser != "" {
+		fs.Infof(nil, "Using --user %s --pass XXXX as authenticated user", s.Opt.BasicUser)
+		authHandler = s.basicAuth(authHandler, s.checkUser)
+	}
+	if s.Opt.AnonymousRead && s.Opt.HtPasswd == "" && s.Opt.BasicUser == "" {
+		log.Fatalf("Need --htpasswd or --user to use --anonymous-read")
+	}
+	s.mux.Handle("/", authHandler)
+
+	s.useSSL = s.Opt.SslKey != ""
+	if (s.Opt.SslCert != "") != s.useSSL {
+		log.Fatalf("Need both -cert and -key to use SSL")
+	}
+
+	s.httpServer = &http.Server{
+		Addr:           s.Opt.ListenAddr,
+		Handler:        s.logRequests(s.cors(s.mux)),
+		ReadTimeout:    s.Opt.ServerReadTimeout,
+		WriteTimeout:   s.Opt.ServerWriteTimeout,
+		MaxHeaderBytes: s.Opt.MaxHeaderBytes,
+	}
+	if s.Opt.DisableHTTP2 {
+		// A non nil empty map stops net/http negotiating HTTP/2
+		s.httpServer.TLSNextProto = map[string]func(*http.Server, *tls.Conn, http.Handler){}
+	}
+	initServer(s.httpServer, &s.Opt)
+
+	if s.Opt.ClientCA != "" {
+		if !s.useSSL {
+			log.Fatalf("Can't use --client-ca without --cert and --key")
+		}
+		certpool := x509.NewCertPool()
+		pem, err := ioutil.ReadFile(s.Opt.ClientCA)
+		if err != nil {
+			log.Fatalf("Failed to read client certificate authority: %v", err)
+		}
+		if !certpool.AppendCertsFromPEM(pem) {
+			log.Fatalf("Can't parse client certificate authority")
+		}
+		s.httpServer.TLSConfig = &tls.Config{
+			ClientCAs:  certpool,
+			ClientAuth: tls.RequireAndVerifyClientCert,
+		}
+	}
+	return s
+}
+
+// HandleUnauthenticated serves pattern with handler without needing
+// authentication, eg for health checks.
+func (s *Server) HandleUnauthenticated(pattern string, handler http.Handler) {
+	s.mux.Handle(pattern, handler)
+}
+
+// Serve runs the server, returning an error if it stops
+func (s *Server) Serve() error {
+	if s.useSSL {
+		return s.httpServer.ListenAndServeTLS(s.Opt.SslCert, s.Opt.SslKey)
+	}
+	return s.httpServer.ListenAndServe()
+}
+
+// URL returns the serving address of this server
+func (s *Server) URL() string {
+	proto := "http"
+	if s.useSSL {
+		proto = "https"
+	}
+	return fmt.Sprintf("%s://%s/", proto, s.Opt.ListenAddr)
+}
+
+// cors adds the Access-Control headers if --allow-origin is set and
+// answers preflight requests without passing them on.
+func (s *Server) cors(handler http.Handler) http.Handler {
+	if s.Opt.AllowOrigin == "" {
+		return handler
+	}
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Access-Control-Allow-Origin", s.Opt.AllowOrigin)
+		if r.Method == "OPTIONS" && r.Header.Get("Origin") != "" && r.Header.Get("Access-Control-Request-Method") != "" {
+			w.Header().Set("Access-Control-Allow-Methods", "GET, HEAD, PUT, POST, DELETE, OPTIONS, PROPFIND, PROPPATCH, MKCOL, COPY, MOVE, LOCK, UNLOCK")
+			if headers := r.Header.Get("Access-Control-Request-Headers"); headers != "" {
+				w.Header().Set("Access-Control-Allow-Headers", headers)
+			}
+			w.WriteHeader(http.StatusOK)
+			return
+		}
+		handler.ServeHTTP(w, r)
+	})
+}
+
+// loggingResponseWriter records the status code of the response
+type loggingResponseWriter struct {
+	http.ResponseWriter
+	status int
+}
+
+// WriteHeader records the status code then writes it
+func (w *loggingResponseWriter) WriteHeader(status int) {
+	w.status = status
+	w.ResponseWriter.WriteHeader(status)
+}
+
+// ReadFrom passes the data on to the underlying ResponseWriter so it
+// can still use sendfile if possible
+func (w *loggingResponseWriter) ReadFrom(in io.Reader) (n int64, err error) {
+	if rf, ok := w.ResponseWriter.(io.ReaderFrom); ok {
+		return rf.ReadFrom(in)
+	}
+	return io.Copy(w.ResponseWriter, in)
+}
+
+// logRequests logs each request with its response status
+func (s *Server) logRequests(handler http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		lw := &loggingResponseWriter{ResponseWriter: w, status: http.StatusOK}
+		handler.ServeHTTP(lw, r)
+		fs.Infof(r.URL.Path, "%s: %s %d", r.RemoteAddr, r.Method, lw.status)
+	})
+}
+
+// AccountingResponseWriter counts the data written to it in the
+// transfer stats if Account is set.
+//
+// Use it when sending data which isn't read through an Account, eg
+// an *os.File sent with sendfile.
+type AccountingResponseWriter struct {
+	http.ResponseWriter
+	Account bool
+}
+
+// Write the data, counting it in the stats
+func (w *AccountingResponseWriter) Write(p []byte) (n int, err error) {
+	n, err = w.ResponseWriter.Write(p)
+	w.account(int64(n))
+	return n, err
+}
+
+// ReadFrom passes the data on to the underlying ResponseWriter so it
+// can still use sendfile if possible, counting it in the stats
+func (w *AccountingResponseWriter) ReadFrom(in io.Reader) (n int64, err error) {
+	if rf, ok := w.ResponseWriter.(io.ReaderFrom); ok {
+		n, err = rf.ReadFrom(in)
+	} else {
+		n, err = io.Copy(w.ResponseWriter, in)
+	}
+	w.account(n)
+	return n, err
+}
+
+// account n bytes if required
+func (w *AccountingResponseWriter) account(n int64) {
+	if w.Account {
+		accounting.Stats.Bytes(n)
+	}
+}
@@ -0,0 +1,28 @@
+package httplib
+
+import (
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNewServer(t *testing.T) {
+	opt := DefaultOpt
+	opt.ListenAddr = "localhost:51778"
+	opt.ServerReadTimeout = 5 * time.Second
+	opt.MaxHeaderBytes = 4096
+	s := NewServer(http.NotFoundHandler(), &opt)
+	assert.Equal(t, "http://localhost:51778/", s.URL())
+	assert.Equal(t, "localhost:51778", s.httpServer.Addr)
+	assert.Equal(t, 5*time.Second, s.httpServer.ReadTimeout)
+	assert.Equal(t, time.Hour, s.httpServer.WriteTimeout)
+	assert.Equal(t, 4096, s.httpServer.MaxHeaderBytes)
+	assert.Nil(t, s.httpServer.TLSNextProto)
+
+	opt.DisableHTTP2 = true
+	s = NewServer(http.NotFoundHandler(), &opt)
+	assert.NotNil(t, s.httpServer.TLSNextProto)
+	assert.Len(t, s.httpServer.TLSNextProto, 0)
+}
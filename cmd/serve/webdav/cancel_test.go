@@ -0,0 +1,71 @@
+package webdav
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	_ "github.com/ncw/rclone/backend/local"
+	"github.com/ncw/rclone/fs"
+	"github.com/ncw/rclone/fs/config"
+	"github.com/ncw/rclone/vfs"
+	"github.com/ncw/rclone/vfs/vfsflags"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/net/context"
+)
+
+func TestReadFileCancel(t *testing.T) {
+	config.LoadConfig()
+	dir, err := ioutil.TempDir("", "rclone-webdav-test")
+	require.NoError(t, err)
+	defer func() {
+		require.NoError(t, os.RemoveAll(dir))
+	}()
+	require.NoError(t, ioutil.WriteFile(filepath.Join(dir, "file.txt"), []byte("hello"), 0600))
+	f, err := fs.NewFs(dir)
+	require.NoError(t, err)
+	webdavFS := &WebDAV{f: f, vfs: vfs.New(f, &vfsflags.Opt)}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	file, err := webdavFS.OpenFile(ctx, "file.txt", os.O_RDONLY, 0)
+	require.NoError(t, err)
+	buf := make([]byte, 2)
+	n, err := file.Read(buf)
+	require.NoError(t, err)
+	assert.Equal(t, "he", string(buf[:n]))
+
+	cancel()
+	_, err = file.Read(buf)
+	assert.Equal(t, context.Canceled, err)
+	require.NoError(t, file.Close())
+}
+
+func TestWriteFileCancel(t *testing.T) {
+	config.LoadConfig()
+	dir, err := ioutil.TempDir("", "rclone-webdav-test")
+	require.NoError(t, err)
+	defer func() {
+		require.NoError(t, os.RemoveAll(dir))
+	}()
+	f, err := fs.NewFs(dir)
+	require.NoError(t, err)
+	webdavFS := &WebDAV{f: f, vfs: vfs.New(f, &vfsflags.Opt)}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	file, err := webdavFS.OpenFile(ctx, "file.txt", os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0600)
+	require.NoError(t, err)
+	_, err = file.Write([]byte("hello"))
+	require.NoError(t, err)
+
+	// Cancel the upload as a client disconnecting would
+	cancel()
+	assert.Error(t, file.Close())
+
+	// Check it leaves nothing behind
+	_, err = webdavFS.Stat(context.Background(), "file.txt")
+	assert.True(t, os.IsNotExist(err), "got %v", err)
+	_, err = os.Stat(filepath.Join(dir, "file.txt"))
+	assert.True(t, os.IsNotExist(err), "got %v", err)
+}
@@ -0,0 +1,179 @@
+package webdav
+
+// Checksums in the style of ownCloud/Nextcloud
+//
+// These are reported in the oc:checksums property on PROPFIND and
+// read from the OC-Checksum header on PUT, eg "SHA1:abcdef..."
+
+import (
+	"encoding/xml"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/ncw/rclone/cmd/serve/httplib"
+	"github.com/ncw/rclone/fs"
+	"github.com/ncw/rclone/fs/accounting"
+	"github.com/ncw/rclone/fs/hash"
+	"github.com/ncw/rclone/vfs"
+	"github.com/pkg/errors"
+	"golang.org/x/net/context"
+	"golang.org/x/net/webdav"
+)
+
+// ocNamespace is the XML namespace of the ownCloud properties
+const ocNamespace = "http://owncloud.org/ns"
+
+// checksumsProp is the name of the property holding the checksums
+var checksumsProp = xml.Name{Space: ocNamespace, Local: "checksums"}
+
+// ocHashNames are the ownCloud names of the hashes we report
+var ocHashNames = []struct {
+	ht   hash.Type
+	name string
+}{
+	{hash.SHA1, "SHA1"},
+	{hash.MD5, "MD5"},
+}
+
+// requestInfo is the parts of the request needed by the FileSystem
+type requestInfo struct {
+	method   string                            // HTTP method
+	checksum string                            // OC-Checksum header
+	out      *httplib.AccountingResponseWriter // response for a GET
+	remote   string                            // file being sent from out if set
+}
+
+// contextKey is the type of the keys stored in the request context
+type contextKey int
+
+// requestInfoKey is the key for the requestInfo
+const requestInfoKey contextKey = 0
+
+// addRequestInfo wraps handler so the requestInfo is in the request
+// context passed to the FileSystem.
+//
+// Before go1.7 requests don't have a context so the FileSystem gets
+// an empty requestInfo, which means checksums aren't reported or
+// checked and files are never sent with sendfile.
+func addRequestInfo(handler http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		info := &requestInfo{
+			method:   r.Method,
+			checksum: r.Header.Get("OC-Checksum"),
+		}
+		if r.Method == "GET" {
+			info.out = &httplib.AccountingResponseWriter{ResponseWriter: w}
+			w = info.out
+		}
+		r = httplib.WithContext(r, context.WithValue(httplib.RequestContext(r), requestInfoKey, info))
+		handler.ServeHTTP(w, r)
+		if info.remote != "" {
+			accounting.Stats.DoneTransferring(info.remote, true)
+		}
+	})
+}
+
+// getRequestInfo returns the requestInfo from ctx or an empty one
+func getRequestInfo(ctx context.Context) *requestInfo {
+	if info, ok := ctx.Value(requestInfoKey).(*requestInfo); ok {
+		return info
+	}
+	return &requestInfo{}
+}
+
+// parseChecksum parses an OC-Checksum header returning the first
+// checksum of a type which f supports.
+//
+// It returns hash.None if there isn't one.
+func parseChecksum(f fs.Fs, header string) (ht hash.Type, sum string) {
+	for _, checksum := range strings.Fields(header) {
+		i := strings.IndexByte(checksum, ':')
+		if i < 0 {
+			continue
+		}
+		if err := ht.Set(checksum[:i]); err != nil {
+			continue
+		}
+		if f.Hashes().Contains(ht) {
+			return ht, checksum[i+1:]
+		}
+	}
+	return hash.None, ""
+}
+
+// checkChecksum checks the object at name against the checksum sum
+// of type ht, removing it if it doesn't match.
+func checkChecksum(VFS *vfs.VFS, name string, ht hash.Type, sum string) error {
+	node, err := VFS.Stat(name)
+	if err != nil {
+		return err
+	}
+	o, ok := node.DirEntry().(fs.Object)
+	if !ok {
+		return nil
+	}
+	got, err := o.Hash(ht)
+	if err != nil || got == "" {
+		fs.Debugf(o, "Couldn't read %v to check checksum: %v", ht, err)
+		return nil
+	}
+	if strings.EqualFold(got, sum) {
+		return nil
+	}
+	err = errors.Errorf("corrupted on transfer: %v hash differ %q vs %q", ht, sum, got)
+	fs.Errorf(o, "%v", err)
+	if removeErr := node.Remove(); removeErr != nil {
+		fs.Errorf(o, "Failed to remove corrupted upload: %v", removeErr)
+	}
+	return err
+}
+
+// checksumFile adds the oc:checksums property to a file
+type checksumFile struct {
+	webdav.File
+	o fs.Object
+}
+
+// check interface
+var _ webdav.DeadPropsHolder = (*checksumFile)(nil)
+
+// DeadProps returns the checksums of the file
+func (f *checksumFile) DeadProps() (map[xml.Name]webdav.Property, error) {
+	hashes := f.o.Fs().Hashes()
+	var sums []string
+	for _, oc := range ocHashNames {
+		if !hashes.Contains(oc.ht) {
+			continue
+		}
+		sum, err := f.o.Hash(oc.ht)
+		if err != nil {
+			fs.Debugf(f.o, "Failed to read %v: %v", oc.ht, err)
+			continue
+		}
+		if sum != "" {
+			sums = append(sums, oc.name+":"+sum)
+		}
+	}
+	if len(sums) == 0 {
+		return nil, nil
+	}
+	innerXML := fmt.Sprintf(`<checksum xmlns=%q>%s</checksum>`, ocNamespace, strings.Join(sums, " "))
+	return map[xml.Name]webdav.Property{
+		checksumsProp: {
+			XMLName:  checksumsProp,
+			InnerXML: []byte(innerXML),
+		},
+	}, nil
+}
+
+// Patch refuses to change any properties
+func (f *checksumFile) Patch(patches []webdav.Proppatch) ([]webdav.Propstat, error) {
+	pstat := webdav.Propstat{Status: http.StatusForbidden}
+	for _, patch := range patches {
+		for _, p := range patch.Props {
+			pstat.Props = append(pstat.Props, webdav.Property{XMLName: p.XMLName})
+		}
+	}
+	return []webdav.Propstat{pstat}, nil
+}
@@ -0,0 +1,96 @@
+// The requestInfo needs the request context which is go1.7+
+
+// +build go1.7
+
+package webdav
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	_ "github.com/ncw/rclone/backend/local"
+	"github.com/ncw/rclone/fs"
+	"github.com/ncw/rclone/fs/accounting"
+	"github.com/ncw/rclone/fs/config"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// do makes a request to handler returning the recorded response
+func do(t *testing.T, handler http.Handler, method, path, body string, headers map[string]string) *httptest.ResponseRecorder {
+	r, err := http.NewRequest(method, path, strings.NewReader(body))
+	require.NoError(t, err)
+	for k, v := range headers {
+		r.Header.Set(k, v)
+	}
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, r)
+	return w
+}
+
+func TestChecksums(t *testing.T) {
+	config.LoadConfig()
+	dir, err := ioutil.TempDir("", "rclone-webdav-test")
+	require.NoError(t, err)
+	defer func() {
+		require.NoError(t, os.RemoveAll(dir))
+	}()
+	require.NoError(t, ioutil.WriteFile(filepath.Join(dir, "props.txt"), []byte("hello"), 0600))
+	f, err := fs.NewFs(dir)
+	require.NoError(t, err)
+	handler := newHandler(f)
+
+	const (
+		md5sum  = "5d41402abc4b2a76b9719d911017c592"
+		sha1sum = "aaf4c61ddcc5e8a2dabede0f3b482cd9aea9434d"
+	)
+
+	// PUT with the wrong checksum is removed
+	w := do(t, handler, "PUT", "/bad.txt", "hello", map[string]string{"OC-Checksum": "MD5:" + md5sum[1:] + "0"})
+	assert.NotEqual(t, http.StatusCreated, w.Code)
+	_, err = os.Stat(filepath.Join(dir, "bad.txt"))
+	assert.True(t, os.IsNotExist(err))
+
+	// PUT with a checksum type which isn't supported is ignored
+	w = do(t, handler, "PUT", "/adler.txt", "hello", map[string]string{"OC-Checksum": "ADLER32:062c0215"})
+	assert.Equal(t, http.StatusCreated, w.Code)
+
+	// PUT with the right checksum works
+	w = do(t, handler, "PUT", "/good.txt", "hello", map[string]string{"OC-Checksum": "ADLER32:062c0215 MD5:" + md5sum})
+	assert.Equal(t, http.StatusCreated, w.Code)
+	data, err := ioutil.ReadFile(filepath.Join(dir, "good.txt"))
+	require.NoError(t, err)
+	assert.Equal(t, "hello", string(data))
+
+	// PROPFIND reports the checksums
+	w = do(t, handler, "PROPFIND", "/props.txt", `<?xml version="1.0"?>
+<d:propfind xmlns:d="DAV:" xmlns:oc="http://owncloud.org/ns">
+  <d:prop><oc:checksums/></d:prop>
+</d:propfind>`, map[string]string{"Depth": "0"})
+	assert.Equal(t, http.StatusMultiStatus, w.Code)
+	assert.Contains(t, w.Body.String(), "SHA1:"+sha1sum+" MD5:"+md5sum)
+}
+
+func TestGetAccounting(t *testing.T) {
+	config.LoadConfig()
+	dir, err := ioutil.TempDir("", "rclone-webdav-test")
+	require.NoError(t, err)
+	defer func() {
+		require.NoError(t, os.RemoveAll(dir))
+	}()
+	require.NoError(t, ioutil.WriteFile(filepath.Join(dir, "file.txt"), []byte("hello"), 0600))
+	f, err := fs.NewFs(dir)
+	require.NoError(t, err)
+	handler := newHandler(f)
+
+	before := accounting.Stats.GetBytes()
+	w := do(t, handler, "GET", "/file.txt", "", nil)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "hello", w.Body.String())
+	assert.Equal(t, int64(5), accounting.Stats.GetBytes()-before)
+}
@@ -4,11 +4,16 @@ package webdav
 // override for getcontenttype property?
 
 import (
+	"io"
 	"net/http"
 	"os"
 
 	"github.com/ncw/rclone/cmd"
+	"github.com/ncw/rclone/cmd/serve/health"
+	"github.com/ncw/rclone/cmd/serve/httplib"
 	"github.com/ncw/rclone/fs"
+	"github.com/ncw/rclone/fs/accounting"
+	"github.com/ncw/rclone/fs/hash"
 	"github.com/ncw/rclone/fs/log"
 	"github.com/ncw/rclone/vfs"
 	"github.com/ncw/rclone/vfs/vfsflags"
@@ -17,14 +22,16 @@ import (
 	"golang.org/x/net/webdav"
 )
 
-// Globals
+// Options set by command line flags
 var (
-	bindAddress = "localhost:8081"
+	Opt = httplib.DefaultOpt
 )
 
 func init() {
-	Command.Flags().StringVarP(&bindAddress, "addr", "", bindAddress, "IPaddress:Port to bind server to.")
+	Opt.ListenAddr = "localhost:8081"
+	httplib.AddFlags(Command.Flags(), &Opt)
 	vfsflags.AddFlags(Command.Flags())
+	health.AddFlags(Command.Flags())
 }
 
 // Command definition for cobra
@@ -40,7 +47,11 @@ write it.
 NB at the moment each directory listing reads the start of each file
 which is undesirable: see https://github.com/golang/go/issues/22577
 
-` + vfs.Help,
+The hashes of files are reported in the ownCloud/Nextcloud style
+oc:checksums property, and if the client sends an OC-Checksum header
+with an upload the file is checked against it and removed if it
+doesn't match.  Use --no-checksum to turn this off.
+` + httplib.Help + health.Help + vfs.Help,
 	Run: func(command *cobra.Command, args []string) {
 		cmd.CheckArgs(1, 1, command, args)
 		fsrc := cmd.NewFsSrc(args)
@@ -52,8 +63,14 @@ which is undesirable: see https://github.com/golang/go/issues/22577
 
 // serve the remote
 func serveWebDav(f fs.Fs) error {
-	fs.Logf(f, "WebDav Server started on %v", bindAddress)
+	s := httplib.NewServer(newHandler(f), &Opt)
+	health.Handle(f, s)
+	fs.Logf(f, "WebDav Server started on %s", s.URL())
+	return s.Serve()
+}
 
+// newHandler makes the http.Handler to serve f over webdav
+func newHandler(f fs.Fs) http.Handler {
 	webdavFS := &WebDAV{
 		f:   f,
 		vfs: vfs.New(f, &vfsflags.Opt),
@@ -65,9 +82,7 @@ func serveWebDav(f fs.Fs) error {
 		Logger:     webdavFS.logRequest, // FIXME
 	}
 
-	// FIXME use our HTTP transport
-	http.Handle("/", handler)
-	return http.ListenAndServe(bindAddress, nil)
+	return addRequestInfo(cancelOnBodyError(handler))
 }
 
 // WebDAV is a webdav.FileSystem interface
@@ -91,8 +106,12 @@ type WebDAV struct {
 var _ webdav.FileSystem = (*WebDAV)(nil)
 
 // logRequest is called by the webdav module on every request
+//
+// The requests themselves are logged by httplib so only log errors
 func (w *WebDAV) logRequest(r *http.Request, err error) {
-	fs.Infof(r.URL.Path, "%s from %s", r.Method, r.RemoteAddr)
+	if err != nil {
+		fs.Infof(r.URL.Path, "%s from %s: %v", r.Method, r.RemoteAddr, err)
+	}
 }
 
 // Mkdir creates a directory
@@ -106,10 +125,143 @@ func (w *WebDAV) Mkdir(ctx context.Context, name string, perm os.FileMode) (err
 	return err
 }
 
+// cancelOnBodyError wraps handler so that the request context is
+// cancelled if reading the request body fails, eg because the client
+// disconnected in the middle of an upload.
+func cancelOnBodyError(handler http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ctx, cancel := context.WithCancel(httplib.RequestContext(r))
+		defer cancel()
+		r = httplib.WithContext(r, ctx)
+		r.Body = &cancelReader{ReadCloser: r.Body, cancel: cancel}
+		handler.ServeHTTP(w, r)
+	})
+}
+
+// cancelReader calls cancel if Read returns an error other than io.EOF
+type cancelReader struct {
+	io.ReadCloser
+	cancel context.CancelFunc
+}
+
+// Read bytes from the request body
+func (r *cancelReader) Read(p []byte) (n int, err error) {
+	n, err = r.ReadCloser.Read(p)
+	if err != nil && err != io.EOF {
+		r.cancel()
+	}
+	return n, err
+}
+
+// readFile wraps a file opened for reading so the download stops if
+// the request context is cancelled, eg because the client
+// disconnected.  The file is then closed which stops the transfer
+// from the backend.
+type readFile struct {
+	webdav.File
+	ctx context.Context
+}
+
+// Read the data if the request hasn't been cancelled
+func (f *readFile) Read(p []byte) (n int, err error) {
+	if err = f.ctx.Err(); err != nil {
+		return 0, err
+	}
+	return f.File.Read(p)
+}
+
+// writeFile wraps a file opened for writing so the upload is cancelled
+// rather than completed if the request context is cancelled.
+//
+// If the client sent a checksum the upload is checked against it.
+type writeFile struct {
+	webdav.File
+	ctx  context.Context
+	vfs  *vfs.VFS
+	name string
+	ht   hash.Type // type of checksum or hash.None
+	sum  string    // checksum from the client
+}
+
+// Write the data if the request hasn't been cancelled
+func (f *writeFile) Write(p []byte) (n int, err error) {
+	if err = f.ctx.Err(); err != nil {
+		return 0, err
+	}
+	return f.File.Write(p)
+}
+
+// Close the file, cancelling the upload if the request was cancelled
+func (f *writeFile) Close() error {
+	if err := f.ctx.Err(); err != nil {
+		if closer, ok := f.File.(interface {
+			CloseWithError(error) error
+		}); ok {
+			fs.Infof(f.File, "Cancelling upload: %v", err)
+			return closer.CloseWithError(err)
+		}
+	}
+	err := f.File.Close()
+	if err != nil || f.ht == hash.None {
+		return err
+	}
+	return checkChecksum(f.vfs, f.name, f.ht, f.sum)
+}
+
 // OpenFile opens a file or a directory
 func (w *WebDAV) OpenFile(ctx context.Context, name string, flags int, perm os.FileMode) (file webdav.File, err error) {
 	defer log.Trace(name, "flags=%v, perm=%v", flags, perm)("err = %v", &err)
-	return w.vfs.OpenFile(name, flags, perm)
+	info := getRequestInfo(ctx)
+	if flags == os.O_RDONLY && info.out != nil {
+		// Open files for GET directly from the OS if possible so
+		// the data can be sent with sendfile.  It is accounted as
+		// it is written to the response.
+		if node, err := w.vfs.Stat(name); err == nil && node.IsFile() {
+			osFile, err := node.(*vfs.File).OpenOSFile()
+			if err != nil {
+				return nil, err
+			}
+			if osFile != nil {
+				info.out.Account = true
+				info.remote = name
+				accounting.Stats.Transferring(name)
+				return osFile, nil
+			}
+		}
+	}
+	file, err = w.vfs.OpenFile(name, flags, perm)
+	if err != nil {
+		return nil, err
+	}
+	if flags&(os.O_WRONLY|os.O_RDWR) != 0 {
+		wf := &writeFile{File: file, ctx: ctx, vfs: w.vfs, name: name}
+		if !w.vfs.Opt.NoChecksum {
+			wf.ht, wf.sum = parseChecksum(w.f, info.checksum)
+		}
+		file = wf
+	} else if flags == os.O_RDONLY {
+		file = w.addChecksums(info, name, &readFile{File: file, ctx: ctx})
+	}
+	return file, nil
+}
+
+// addChecksums adds the checksums property to file if the request is
+// a PROPFIND.
+//
+// Other requests get file back unchanged so GET can still use sendfile.
+func (w *WebDAV) addChecksums(info *requestInfo, name string, file webdav.File) webdav.File {
+	if w.vfs.Opt.NoChecksum || info.method != "PROPFIND" {
+		return file
+	}
+	node, err := w.vfs.Stat(name)
+	if err != nil || !node.IsFile() {
+		return file
+	}
+	o, ok := node.DirEntry().(fs.Object)
+	if !ok {
+		return file
+	}
+	return &checksumFile{File: file, o: o}
 }
 
 // RemoveAll removes a file or a directory and its contents
@@ -0,0 +1,54 @@
+package settier
+
+import (
+	"github.com/ncw/rclone/cmd"
+	"github.com/ncw/rclone/fs/operations"
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+	"golang.org/x/net/context"
+)
+
+func init() {
+	cmd.Root.AddCommand(commandDefintion)
+}
+
+var commandDefintion = &cobra.Command{
+	Use:   "settier tier remote:path",
+	Short: `Changes storage class/tier of objects in remote.`,
+	Long: `
+rclone settier changes the storage tier or class of objects in place,
+without uploading them again, on remotes which support it.  Some
+cloud storage services provide different storage classes for objects,
+for example S3 has STANDARD, STANDARD_IA and GLACIER and Azure Blob
+storage has Hot, Cool and Archive.
+
+Note that some tier changes make objects unavailable to read
+immediately.  For example moving objects to the Archive tier in Azure
+Blob storage freezes them until they are moved back to Hot or Cool,
+which can take several hours.
+
+You can use it to change the tier of a single object
+
+    rclone settier Cool remote:path/file
+
+Or use rclone filters to change the tier of only some files
+
+    rclone --include "*.txt" settier Hot remote:path/dir
+
+Or give a directory to change the tier of all the files in it
+
+    rclone settier tier remote:path/dir
+`,
+	Run: func(command *cobra.Command, args []string) {
+		cmd.CheckArgs(2, 2, command, args)
+		tier := args[0]
+		input := args[1:]
+		fsrc := cmd.NewFsSrc(input)
+		cmd.Run(false, false, command, func() error {
+			if !fsrc.Features().SetTier {
+				return errors.Errorf("remote %s does not support settier", fsrc.Name())
+			}
+			return operations.SetTier(context.Background(), fsrc, tier)
+		})
+	},
+}
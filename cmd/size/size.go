@@ -1,31 +1,105 @@
 package size
 
 import (
+	"encoding/json"
 	"fmt"
+	"sort"
 
 	"github.com/ncw/rclone/cmd"
 	"github.com/ncw/rclone/fs"
 	"github.com/ncw/rclone/fs/operations"
 	"github.com/spf13/cobra"
+	"golang.org/x/net/context"
+)
+
+var (
+	jsonOutput bool
+	byDir      bool
 )
 
 func init() {
 	cmd.Root.AddCommand(commandDefintion)
+	commandDefintion.Flags().BoolVarP(&jsonOutput, "json", "", false, "Format output as JSON")
+	commandDefintion.Flags().BoolVarP(&byDir, "by-dir", "", false, "Show the size of each top level directory too")
+}
+
+// sizeOutput is the JSON output of the size command
+type sizeOutput struct {
+	operations.SizeResult
+	Dirs map[string]*operations.SizeResult `json:"dirs,omitempty"`
+}
+
+// printResult prints a single result in human readable form
+func printResult(prefix string, result *operations.SizeResult) {
+	fmt.Printf("%sTotal objects: %d\n", prefix, result.Count)
+	fmt.Printf("%sTotal size: %s (%d Bytes)\n", prefix, fs.SizeSuffix(result.Bytes).Unit("Bytes"), result.Bytes)
+	if result.Sizeless > 0 {
+		fmt.Printf("%sObjects of unknown size: %d\n", prefix, result.Sizeless)
+	}
 }
 
 var commandDefintion = &cobra.Command{
 	Use:   "size remote:path",
 	Short: `Prints the total size and number of objects in remote:path.`,
+	Long: `
+Prints the total size and number of objects in remote:path.
+
+Some remotes can't tell the size of some objects (eg Google Docs on
+Google Drive).  These are counted but their sizes are not included in
+the total, and the number of them is shown separately.
+
+Use the --by-dir flag to show the size and number of objects in each
+top level directory of remote:path as well.  Objects in the root of
+remote:path are shown under ".".
+
+Use the --json flag for a computer readable output, eg
+
+    {
+        "count": 3,
+        "bytes": 2048,
+        "sizeless": 0,
+        "dirs": {
+            ".": {
+                "count": 1,
+                "bytes": 1024,
+                "sizeless": 0
+            },
+            "dir": {
+                "count": 2,
+                "bytes": 1024,
+                "sizeless": 0
+            }
+        }
+    }
+
+The "dirs" entry is only present if --by-dir is used.
+`,
 	Run: func(command *cobra.Command, args []string) {
 		cmd.CheckArgs(1, 1, command, args)
 		fsrc := cmd.NewFsSrc(args)
 		cmd.Run(false, false, command, func() error {
-			objects, size, err := operations.Count(fsrc)
+			total, dirs, err := operations.CountDirs(context.Background(), fsrc, byDir)
 			if err != nil {
 				return err
 			}
-			fmt.Printf("Total objects: %d\n", objects)
-			fmt.Printf("Total size: %s (%d Bytes)\n", fs.SizeSuffix(size).Unit("Bytes"), size)
+			if jsonOutput {
+				out, err := json.MarshalIndent(sizeOutput{SizeResult: total, Dirs: dirs}, "", "\t")
+				if err != nil {
+					return err
+				}
+				fmt.Printf("%s\n", out)
+				return nil
+			}
+			printResult("", &total)
+			names := make([]string, 0, len(dirs))
+			for name := range dirs {
+				names = append(names, name)
+			}
+			sort.Strings(names)
+			for _, name := range names {
+				fmt.Printf("\n%s\n", name)
+				printResult("  ", dirs[name])
+			}
 			return nil
 		})
 	},
@@ -0,0 +1,250 @@
+// Package bench implements "rclone test bench"
+package bench
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"math/rand"
+	"path"
+	"sync"
+	"time"
+
+	"github.com/ncw/rclone/cmd"
+	"github.com/ncw/rclone/cmd/test"
+	"github.com/ncw/rclone/fs"
+	"github.com/ncw/rclone/fs/object"
+	"github.com/ncw/rclone/fs/operations"
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+	"golang.org/x/net/context"
+)
+
+var (
+	numberOfFiles = 10
+	fileSize      = fs.SizeSuffix(10 * 1024 * 1024)
+	listRuns      = 3
+	keep          = false
+)
+
+func init() {
+	test.Command.AddCommand(commandDefintion)
+	flags := commandDefintion.Flags()
+	flags.IntVarP(&numberOfFiles, "files", "", numberOfFiles, "Number of files to upload and download")
+	flags.VarP(&fileSize, "size", "", "Size of each file")
+	flags.IntVarP(&listRuns, "list-runs", "", listRuns, "Number of times to time listing the files")
+	flags.BoolVarP(&keep, "keep", "", keep, "Don't delete the files uploaded at the end")
+}
+
+var commandDefintion = &cobra.Command{
+	Use:   "bench remote:path",
+	Short: `Measure upload, download and listing speed of a remote`,
+	Long: `This uploads --files files of --size bytes of random data to a new
+directory in remote:path, times listing that directory --list-runs
+times, downloads the files again then deletes the directory.
+
+The results are printed as JSON on stdout, eg
+
+    {
+    	"remote": "s3:bucket/path",
+    	"directory": "rclone-bench-4yt9ilkpdhwu",
+    	"files": 10,
+    	"size": 10485760,
+    	"transfers": 4,
+    	"upload": {
+    		"seconds": 3.21,
+    		"bytes_per_second": 32665918
+    	},
+    	"download": {
+    		"seconds": 1.05,
+    		"bytes_per_second": 99864380
+    	},
+    	"list": {
+    		"seconds": [0.12, 0.08, 0.09],
+    		"mean": 0.096
+    	}
+    }
+
+The files are transferred --transfers at a time, so running this with
+different values of --transfers, --buffer-size or backend flags (eg
+--s3-upload-concurrency) shows which work best with the remote.
+`,
+	Run: func(command *cobra.Command, args []string) {
+		cmd.CheckArgs(1, 1, command, args)
+		f := cmd.NewFsDst(args)
+		cmd.Run(false, false, command, func() error {
+			result, err := bench(context.Background(), f)
+			if err != nil {
+				return err
+			}
+			out, err := json.MarshalIndent(result, "", "\t")
+			if err != nil {
+				return err
+			}
+			fmt.Printf("%s\n", out)
+			return nil
+		})
+	},
+}
+
+// speed is the result of timing a transfer
+type speed struct {
+	Seconds        float64 `json:"seconds"`
+	BytesPerSecond float64 `json:"bytes_per_second"`
+}
+
+// newSpeed makes a speed from the bytes transferred in dt
+func newSpeed(bytes int64, dt time.Duration) speed {
+	return speed{
+		Seconds:        dt.Seconds(),
+		BytesPerSecond: float64(bytes) / dt.Seconds(),
+	}
+}
+
+// listTimes is the result of timing listings
+type listTimes struct {
+	Seconds []float64 `json:"seconds"`
+	Mean    float64   `json:"mean"`
+}
+
+// result is the output of the benchmark
+type result struct {
+	Remote    string    `json:"remote"`
+	Directory string    `json:"directory"`
+	Files     int       `json:"files"`
+	Size      int64     `json:"size"`
+	Transfers int       `json:"transfers"`
+	Upload    speed     `json:"upload"`
+	Download  speed     `json:"download"`
+	List      listTimes `json:"list"`
+}
+
+// parallel runs fn for 0..n-1 with --transfers at once returning
+// the first error
+func parallel(n int, fn func(i int) error) error {
+	var (
+		wg       sync.WaitGroup
+		mu       sync.Mutex
+		firstErr error
+	)
+	in := make(chan int)
+	for t := 0; t < fs.Config.Transfers; t++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range in {
+				err := fn(i)
+				if err != nil {
+					mu.Lock()
+					if firstErr == nil {
+						firstErr = err
+					}
+					mu.Unlock()
+				}
+			}
+		}()
+	}
+	for i := 0; i < n; i++ {
+		in <- i
+	}
+	close(in)
+	wg.Wait()
+	return firstErr
+}
+
+// bench runs the benchmark on f
+func bench(ctx context.Context, f fs.Fs) (r *result, err error) {
+	if numberOfFiles < 1 || listRuns < 0 {
+		return nil, errors.New("--files must be at least 1 and --list-runs at least 0")
+	}
+	rnd := rand.New(rand.NewSource(time.Now().UnixNano()))
+	dir := fmt.Sprintf("rclone-bench-%x", rnd.Int63())
+	r = &result{
+		Remote:    f.Name() + ":" + f.Root(),
+		Directory: dir,
+		Files:     numberOfFiles,
+		Size:      int64(fileSize),
+		Transfers: fs.Config.Transfers,
+	}
+	remote := func(i int) string {
+		return path.Join(dir, fmt.Sprintf("file-%04d", i))
+	}
+	objs := make([]fs.Object, numberOfFiles)
+	if !keep {
+		defer func() {
+			fs.Infof(f, "Deleting %q", dir)
+			for _, o := range objs {
+				if o != nil {
+					removeErr := operations.DeleteFile(ctx, o)
+					if err == nil {
+						err = removeErr
+					}
+				}
+			}
+			_ = operations.TryRmdir(ctx, f, dir)
+		}()
+	}
+
+	fs.Infof(f, "Uploading %d files of %v to %q", numberOfFiles, fileSize, dir)
+	start := time.Now()
+	err = parallel(numberOfFiles, func(i int) error {
+		in := io.LimitReader(rand.New(rand.NewSource(int64(i))), int64(fileSize))
+		info := object.NewStaticObjectInfo(remote(i), time.Now(), int64(fileSize), true, nil, f)
+		o, err := f.Put(ctx, in, info)
+		if err != nil {
+			return errors.Wrapf(err, "failed to upload %q", remote(i))
+		}
+		objs[i] = o
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	r.Upload = newSpeed(int64(numberOfFiles)*int64(fileSize), time.Since(start))
+
+	fs.Infof(f, "Listing %q %d times", dir, listRuns)
+	total := 0.0
+	for run := 0; run < listRuns; run++ {
+		start = time.Now()
+		entries, err := f.List(ctx, dir)
+		if err != nil {
+			return nil, errors.Wrapf(err, "failed to list %q", dir)
+		}
+		dt := time.Since(start).Seconds()
+		if len(entries) != numberOfFiles {
+			fs.Logf(f, "Listing found %d files not %d - remote may be eventually consistent", len(entries), numberOfFiles)
+		}
+		r.List.Seconds = append(r.List.Seconds, dt)
+		total += dt
+	}
+	if listRuns > 0 {
+		r.List.Mean = total / float64(listRuns)
+	}
+
+	fs.Infof(f, "Downloading %d files from %q", numberOfFiles, dir)
+	start = time.Now()
+	err = parallel(numberOfFiles, func(i int) error {
+		in, err := objs[i].Open(ctx)
+		if err != nil {
+			return errors.Wrapf(err, "failed to open %q", remote(i))
+		}
+		n, err := io.Copy(ioutil.Discard, in)
+		closeErr := in.Close()
+		if err == nil {
+			err = closeErr
+		}
+		if err == nil && n != int64(fileSize) {
+			err = errors.Errorf("downloaded %d bytes not %d", n, int64(fileSize))
+		}
+		if err != nil {
+			return errors.Wrapf(err, "failed to download %q", remote(i))
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	r.Download = newSpeed(int64(numberOfFiles)*int64(fileSize), time.Since(start))
+	return r, nil
+}
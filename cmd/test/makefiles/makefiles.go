@@ -0,0 +1,166 @@
+// Package makefiles implements "rclone test makefiles"
+package makefiles
+
+import (
+	"io"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/ncw/rclone/cmd"
+	"github.com/ncw/rclone/cmd/test"
+	"github.com/ncw/rclone/fs"
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+)
+
+var (
+	numberOfFiles     = 1000
+	filesPerDirectory = 10
+	minFileSize       = fs.SizeSuffix(0)
+	maxFileSize       = fs.SizeSuffix(100)
+	minFileNameLength = 4
+	maxFileNameLength = 12
+	seed              = int64(1)
+)
+
+func init() {
+	test.Command.AddCommand(commandDefintion)
+	flags := commandDefintion.Flags()
+	flags.IntVarP(&numberOfFiles, "files", "", numberOfFiles, "Number of files to create")
+	flags.IntVarP(&filesPerDirectory, "files-per-directory", "", filesPerDirectory, "Average number of files per directory")
+	flags.VarP(&minFileSize, "min-file-size", "", "Minimum size of file to create")
+	flags.VarP(&maxFileSize, "max-file-size", "", "Maximum size of files to create")
+	flags.IntVarP(&minFileNameLength, "min-name-length", "", minFileNameLength, "Minimum size of file names")
+	flags.IntVarP(&maxFileNameLength, "max-name-length", "", maxFileNameLength, "Maximum size of file names")
+	flags.Int64VarP(&seed, "seed", "", seed, "Seed for the random number generator (0 for random)")
+}
+
+var commandDefintion = &cobra.Command{
+	Use:   "makefiles <dir>",
+	Short: `Make a random file hierarchy in <dir>`,
+	Long: `This makes a tree of random files and directories in the local
+directory <dir> for testing and benchmarking, eg
+
+    rclone test makefiles --files 10000 --max-file-size 1M /tmp/files
+    rclone copy /tmp/files remote:files
+
+The tree made, including the contents of the files, only depends on
+the flags so the same tree can be made again elsewhere.  Use --seed
+to make a different one, or --seed 0 to make a different one each
+time.
+`,
+	Run: func(command *cobra.Command, args []string) {
+		cmd.CheckArgs(1, 1, command, args)
+		cmd.Run(false, false, command, func() error {
+			return makeFiles(args[0])
+		})
+	},
+}
+
+// fileNameChars are the characters used in file names
+const fileNameChars = "abcdefghijklmnopqrstuvwxyz0123456789"
+
+// maker makes the random files
+type maker struct {
+	rand  *rand.Rand
+	root  string
+	dirs  []string        // directories made so far
+	names map[string]bool // names used so far
+	bytes int64           // bytes written so far
+}
+
+// makeFiles makes the random files in root
+func makeFiles(root string) error {
+	if minFileSize > maxFileSize {
+		return errors.New("--min-file-size must be less than or equal to --max-file-size")
+	}
+	if minFileNameLength < 1 || minFileNameLength > maxFileNameLength {
+		return errors.New("--min-name-length must be at least 1 and less than or equal to --max-name-length")
+	}
+	if filesPerDirectory < 1 {
+		return errors.New("--files-per-directory must be at least 1")
+	}
+	if seed == 0 {
+		seed = time.Now().UnixNano()
+		fs.Logf(nil, "Using random seed %d", seed)
+	}
+	m := &maker{
+		rand:  rand.New(rand.NewSource(seed)),
+		root:  root,
+		dirs:  []string{""},
+		names: map[string]bool{},
+	}
+	start := time.Now()
+	err := os.MkdirAll(root, 0777)
+	if err != nil {
+		return err
+	}
+	for i := 0; i < numberOfFiles/filesPerDirectory; i++ {
+		err := m.makeDir()
+		if err != nil {
+			return err
+		}
+	}
+	for i := 0; i < numberOfFiles; i++ {
+		err := m.makeFile()
+		if err != nil {
+			return err
+		}
+	}
+	dt := time.Since(start)
+	fs.Logf(nil, "Made %d files in %d directories totalling %v in %v", numberOfFiles, len(m.dirs), fs.SizeSuffix(m.bytes), dt)
+	return nil
+}
+
+// randomName makes a random file name which hasn't been used yet
+func (m *maker) randomName() string {
+	for {
+		length := minFileNameLength + m.rand.Intn(maxFileNameLength-minFileNameLength+1)
+		name := make([]byte, length)
+		for i := range name {
+			name[i] = fileNameChars[m.rand.Intn(len(fileNameChars))]
+		}
+		if !m.names[string(name)] {
+			m.names[string(name)] = true
+			return string(name)
+		}
+	}
+}
+
+// randomDir returns a random directory made so far
+func (m *maker) randomDir() string {
+	return m.dirs[m.rand.Intn(len(m.dirs))]
+}
+
+// makeDir makes a new directory in a random directory
+func (m *maker) makeDir() error {
+	dir := filepath.Join(m.randomDir(), m.randomName())
+	err := os.MkdirAll(filepath.Join(m.root, dir), 0777)
+	if err != nil {
+		return err
+	}
+	m.dirs = append(m.dirs, dir)
+	return nil
+}
+
+// makeFile makes a new file of random size and contents in a random
+// directory
+func (m *maker) makeFile() (err error) {
+	name := filepath.Join(m.root, m.randomDir(), m.randomName())
+	size := int64(minFileSize) + m.rand.Int63n(int64(maxFileSize-minFileSize)+1)
+	out, err := os.Create(name)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		closeErr := out.Close()
+		if err == nil {
+			err = closeErr
+		}
+	}()
+	n, err := io.CopyN(out, m.rand, size)
+	m.bytes += n
+	return err
+}
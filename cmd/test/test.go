@@ -0,0 +1,31 @@
+// Package test implements the hidden "rclone test" command which
+// holds commands for testing and benchmarking rclone and remotes.
+package test
+
+import (
+	"github.com/ncw/rclone/cmd"
+	"github.com/spf13/cobra"
+)
+
+func init() {
+	cmd.Root.AddCommand(Command)
+}
+
+// Command is the "rclone test" command which the test commands are
+// added to
+var Command = &cobra.Command{
+	Use:   "test <subcommand>",
+	Short: `Run a test command`,
+	Long: `Rclone test is used to run test commands.
+
+Select which test comand you want with the subcommand, eg
+
+    rclone test makefiles /tmp/test-files
+
+Each subcommand has its own options which you can see in their help.
+
+**NB** Be careful running these commands, they may do strange things
+so reading their documentation first is recommended.
+`,
+	Hidden: true,
+}
@@ -7,8 +7,10 @@ import (
 	"github.com/ncw/rclone/cmd"
 	"github.com/ncw/rclone/fs"
 	"github.com/ncw/rclone/fs/object"
+	"github.com/ncw/rclone/fs/operations"
 	"github.com/pkg/errors"
 	"github.com/spf13/cobra"
+	"golang.org/x/net/context"
 )
 
 var (
@@ -29,6 +31,13 @@ func init() {
 var commandDefintion = &cobra.Command{
 	Use:   "touch remote:path",
 	Short: `Create new file or change file modification time.`,
+	Long: `
+Create a new empty file or change the modification time of an
+existing file to the current time or the time given with --timestamp.
+
+If the remote can't set the modification time of an existing file
+then the file will be uploaded again with the new time.
+`,
 	Run: func(command *cobra.Command, args []string) {
 		cmd.CheckArgs(1, 1, command, args)
 		fsrc, srcFileName := cmd.NewFsDstFile(args)
@@ -52,19 +61,22 @@ func Touch(fsrc fs.Fs, srcFileName string) error {
 		}
 		timeAtr = timeAtrFromFlags
 	}
-	file, err := fsrc.NewObject(srcFileName)
+	file, err := fsrc.NewObject(context.Background(), srcFileName)
 	if err != nil {
 		if !notCreateNewFile {
+			if operations.SkipDryRun(srcFileName, "create empty file") {
+				return nil
+			}
 			var buffer []byte
 			src := object.NewStaticObjectInfo(srcFileName, timeAtr, int64(len(buffer)), true, nil, fsrc)
-			_, err = fsrc.Put(bytes.NewBuffer(buffer), src)
+			_, err = fsrc.Put(context.Background(), bytes.NewBuffer(buffer), src)
 			if err != nil {
 				return err
 			}
 		}
 		return nil
 	}
-	err = file.SetModTime(timeAtr)
+	err = operations.SetModTime(context.Background(), file, timeAtr)
 	if err != nil {
 		return errors.Wrap(err, "touch: couldn't set mod time")
 	}
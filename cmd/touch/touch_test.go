@@ -9,6 +9,7 @@ import (
 	"github.com/stretchr/testify/require"
 
 	_ "github.com/ncw/rclone/backend/local"
+	"golang.org/x/net/context"
 )
 
 var (
@@ -32,23 +33,25 @@ func TestMain(m *testing.M) {
 }
 
 func TestTouchOneFile(t *testing.T) {
+	ctx := context.Background()
 	r := fstest.NewRun(t)
 	defer r.Finalise()
 
 	err := Touch(r.Fremote, "newFile")
 	require.NoError(t, err)
-	_, err = r.Fremote.NewObject("newFile")
+	_, err = r.Fremote.NewObject(ctx, "newFile")
 	require.NoError(t, err)
 }
 
 func TestTouchWithNoCreateFlag(t *testing.T) {
+	ctx := context.Background()
 	r := fstest.NewRun(t)
 	defer r.Finalise()
 
 	notCreateNewFile = true
 	err := Touch(r.Fremote, "newFile")
 	require.NoError(t, err)
-	_, err = r.Fremote.NewObject("newFile")
+	_, err = r.Fremote.NewObject(ctx, "newFile")
 	require.Error(t, err)
 	notCreateNewFile = false
 }
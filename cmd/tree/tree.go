@@ -16,6 +16,7 @@ import (
 	"github.com/ncw/rclone/fs/walk"
 	"github.com/pkg/errors"
 	"github.com/spf13/cobra"
+	"golang.org/x/net/context"
 )
 
 var (
@@ -58,7 +59,7 @@ func init() {
 	flags.BoolVarP(&opts.DirSort, "dirsfirst", "", false, "List directories before files (-U disables).")
 	flags.StringVarP(&sort, "sort", "", "", "Select sort: name,version,size,mtime,ctime.")
 	// Graphics
-	flags.BoolVarP(&opts.NoIndent, "noindent", "i", false, "Don't print indentation lines.")
+	flags.BoolVarP(&opts.NoIndent, "noindent", "", false, "Don't print indentation lines.")
 	flags.BoolVarP(&opts.Colorize, "color", "C", false, "Turn colorization on always.")
 }
 
@@ -88,6 +89,9 @@ You can use any of the filtering options with the tree command (eg
 The tree command has many options for controlling the listing which
 are compatible with the tree command.  Note that not all of them have
 short options as they conflict with rclone's short options.
+
+In particular -i is now rclone's --interactive flag so use --noindent
+in full to turn off the indentation lines.
 `,
 	RunE: func(command *cobra.Command, args []string) error {
 		cmd.CheckArgs(1, 1, command, args)
@@ -117,7 +121,7 @@ short options as they conflict with rclone's short options.
 
 // Tree lists fsrc to outFile using the Options passed in
 func Tree(fsrc fs.Fs, outFile io.Writer, opts *tree.Options) error {
-	dirs, err := walk.NewDirTree(fsrc, "", false, opts.DeepLevel)
+	dirs, err := walk.NewDirTree(context.Background(), fsrc, "", false, opts.DeepLevel)
 	if err != nil {
 		return err
 	}
@@ -10,8 +10,47 @@ import (
 	"github.com/VividCortex/ewma"
 	"github.com/ncw/rclone/fs"
 	"github.com/ncw/rclone/fs/asyncreader"
+	"github.com/ncw/rclone/fs/fserrors"
+	"github.com/pkg/errors"
 )
 
+// Errors returned when --max-transfer is reached
+var (
+	ErrorMaxTransferLimitReached = errors.New("Max transfer limit reached as set by --max-transfer")
+	// returned with --cutoff-mode HARD to stop everything
+	ErrorMaxTransferLimitReachedFatal = fserrors.FatalError(ErrorMaxTransferLimitReached)
+	// returned with --cutoff-mode SOFT or CAUTIOUS to stop new transfers
+	ErrorMaxTransferLimitReachedGraceful = fserrors.NoRetryError(ErrorMaxTransferLimitReached)
+)
+
+// CheckMaxTransfer returns an error if a transfer of size bytes
+// shouldn't be started because of --max-transfer and --cutoff-mode.
+// size may be -1 if it isn't known.
+func CheckMaxTransfer(size int64) error {
+	maxTransfer := int64(fs.Config.MaxTransfer)
+	if maxTransfer < 0 {
+		return nil
+	}
+	switch fs.Config.CutoffMode {
+	case fs.CutoffModeCautious:
+		if size < 0 {
+			size = 0
+		}
+		if Stats.GetBytesWithPending()+size > maxTransfer {
+			return ErrorMaxTransferLimitReachedGraceful
+		}
+	case fs.CutoffModeSoft:
+		if Stats.GetBytes() >= maxTransfer {
+			return ErrorMaxTransferLimitReachedGraceful
+		}
+	default:
+		if Stats.GetBytes() >= maxTransfer {
+			return ErrorMaxTransferLimitReachedFatal
+		}
+	}
+	return nil
+}
+
 // Account limits and accounts for one transfer
 type Account struct {
 	// The mutex is to make sure Read() and Close() aren't called
@@ -19,21 +58,25 @@ type Account struct {
 	// in http transport calls Read() after Do() returns on
 	// CancelRequest so this race can happen when it apparently
 	// shouldn't.
-	mu      sync.Mutex
-	in      io.Reader
-	origIn  io.ReadCloser
-	close   io.Closer
-	size    int64
-	name    string
-	statmu  sync.Mutex         // Separate mutex for stat values.
-	bytes   int64              // Total number of bytes read
-	start   time.Time          // Start time of first read
-	lpTime  time.Time          // Time of last average measurement
-	lpBytes int                // Number of bytes read since last measurement
-	avg     ewma.MovingAverage // Moving average of last few measurements
-	closed  bool               // set if the file is closed
-	exit    chan struct{}      // channel that will be closed when transfer is finished
-	withBuf bool               // is using a buffered in
+	mu       sync.Mutex
+	in       io.Reader
+	origIn   io.ReadCloser
+	close    io.Closer
+	size     int64
+	name     string
+	statmu   sync.Mutex         // Separate mutex for stat values.
+	bytes    int64              // Total number of bytes read
+	start    time.Time          // Start time of first read
+	lpTime   time.Time          // Time of last average measurement
+	lpBytes  int                // Number of bytes read since last measurement
+	avg      ewma.MovingAverage // Moving average of last few measurements
+	closed   bool               // set if the file is closed
+	exit     chan struct{}      // channel that will be closed when transfer is finished
+	withBuf  bool               // is using a buffered in
+	upload   bool               // limit with the upload bandwidth
+	download bool               // limit with the download bandwidth
+	srcTbs   *tokenBuckets      // bandwidth limits of the source remote if set
+	dstTbs   *tokenBuckets      // bandwidth limits of the destination remote if set
 }
 
 // NewAccountSizeName makes a Account reader for an io.ReadCloser of
@@ -48,6 +91,9 @@ func NewAccountSizeName(in io.ReadCloser, size int64, name string) *Account {
 		exit:   make(chan struct{}),
 		avg:    ewma.NewMovingAverage(),
 		lpTime: time.Now(),
+		// limit by both bandwidths unless WithLimits is called
+		upload:   true,
+		download: true,
 	}
 	go acc.averageLoop()
 	Stats.inProgress.set(acc.name, acc)
@@ -59,12 +105,44 @@ func NewAccount(in io.ReadCloser, obj fs.Object) *Account {
 	return NewAccountSizeName(in, obj.Size(), obj.Remote())
 }
 
+// WithLimits sets the bandwidth limits for a transfer from src to
+// dst, either of which may be nil if they aren't an Fs.
+//
+// Transfers from a remote are limited by the download bandwidth and
+// transfers to a remote by the upload bandwidth, as well as by the
+// bwlimit set in the config of those remotes.  Transfers which don't
+// involve a remote are limited by both bandwidths.
+func (acc *Account) WithLimits(src, dst fs.Info) *Account {
+	srcRemote, dstRemote := isRemote(src), isRemote(dst)
+	if srcRemote || dstRemote {
+		acc.upload, acc.download = dstRemote, srcRemote
+	}
+	if srcRemote {
+		acc.srcTbs = getRemoteTokenBuckets(src.Name())
+	}
+	if dstRemote {
+		acc.dstTbs = getRemoteTokenBuckets(dst.Name())
+	}
+	return acc
+}
+
+// isRemote returns true if f is a remote rather than the local disk
+func isRemote(f fs.Info) bool {
+	return f != nil && !f.Features().IsLocal
+}
+
 // WithBuffer - If the file is above a certain size it adds an Async reader
 func (acc *Account) WithBuffer() *Account {
+	return acc.WithBufferSize(fs.Config.BufferSize)
+}
+
+// WithBufferSize adds an Async reader using at most bufferSize of
+// memory if the file is above a certain size
+func (acc *Account) WithBufferSize(bufferSize fs.SizeSuffix) *Account {
 	acc.withBuf = true
 	var buffers int
-	if acc.size >= int64(fs.Config.BufferSize) || acc.size == -1 {
-		buffers = int(int64(fs.Config.BufferSize) / asyncreader.BufferSize)
+	if acc.size >= int64(bufferSize) || acc.size == -1 {
+		buffers = int(int64(bufferSize) / asyncreader.BufferSize)
 	} else {
 		buffers = int(acc.size / asyncreader.BufferSize)
 	}
@@ -138,6 +216,12 @@ func (acc *Account) read(in io.Reader, p []byte) (n int, err error) {
 	}
 	acc.statmu.Unlock()
 
+	// Stop transfers in progress if --max-transfer is reached with
+	// --cutoff-mode HARD
+	if fs.Config.MaxTransfer >= 0 && fs.Config.CutoffMode == fs.CutoffModeHard && Stats.GetBytes() >= int64(fs.Config.MaxTransfer) {
+		return 0, ErrorMaxTransferLimitReachedFatal
+	}
+
 	n, err = in.Read(p)
 
 	// Update Stats
@@ -148,7 +232,13 @@ func (acc *Account) read(in io.Reader, p []byte) (n int, err error) {
 
 	Stats.Bytes(int64(n))
 
-	limitBandwidth(n)
+	limitBandwidth(n, acc.upload, acc.download)
+	if acc.srcTbs != nil {
+		waitTokenBuckets(acc.srcTbs, false, true, n)
+	}
+	if acc.dstTbs != nil {
+		waitTokenBuckets(acc.dstTbs, true, false, n)
+	}
 	return
 }
 
@@ -6,8 +6,11 @@ import (
 	"io/ioutil"
 	"strings"
 	"testing"
+	"time"
 
+	"github.com/ncw/rclone/fs"
 	"github.com/ncw/rclone/fs/asyncreader"
+	"github.com/ncw/rclone/fs/hash"
 	"github.com/ncw/rclone/fstest/mockobject"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
@@ -42,6 +45,88 @@ func TestNewAccount(t *testing.T) {
 	assert.Nil(t, Stats.inProgress.get("test"))
 }
 
+// testInfo is an fs.Info for testing WithLimits
+type testInfo struct {
+	name     string
+	features fs.Features
+}
+
+func (f *testInfo) Name() string             { return f.name }
+func (f *testInfo) Root() string             { return "" }
+func (f *testInfo) String() string           { return f.name }
+func (f *testInfo) Precision() time.Duration { return time.Second }
+func (f *testInfo) Hashes() hash.Set         { return hash.Set(hash.None) }
+func (f *testInfo) Features() *fs.Features   { return &f.features }
+
+func TestAccountWithLimits(t *testing.T) {
+	oldConfigFileGet := fs.ConfigFileGet
+	defer func() {
+		fs.ConfigFileGet = oldConfigFileGet
+	}()
+	fs.ConfigFileGet = func(section, key string, defaultVal ...string) string {
+		if section == "limited" && key == "bwlimit" {
+			return "10M:1M"
+		}
+		return ""
+	}
+	local := &testInfo{name: "local", features: fs.Features{IsLocal: true}}
+	remote := &testInfo{name: "remote"}
+	limited := &testInfo{name: "limited"}
+
+	for _, test := range []struct {
+		src, dst       fs.Info
+		upload         bool
+		download       bool
+		srcTbs, dstTbs bool
+	}{
+		{nil, nil, true, true, false, false},
+		{local, local, true, true, false, false},
+		{local, remote, true, false, false, false},
+		{remote, local, false, true, false, false},
+		{remote, remote, true, true, false, false},
+		{nil, limited, true, false, false, true},
+		{limited, nil, false, true, true, false},
+		{limited, remote, true, true, true, false},
+	} {
+		in := ioutil.NopCloser(bytes.NewBuffer([]byte{1}))
+		acc := NewAccountSizeName(in, 1, "test").WithLimits(test.src, test.dst)
+		assert.Equal(t, test.upload, acc.upload)
+		assert.Equal(t, test.download, acc.download)
+		assert.Equal(t, test.srcTbs, acc.srcTbs != nil)
+		assert.Equal(t, test.dstTbs, acc.dstTbs != nil)
+		require.NoError(t, acc.Close())
+	}
+
+	tbs := getRemoteTokenBuckets("limited")
+	require.NotNil(t, tbs)
+	assert.Equal(t, 10*1024*1024, int(tbs[bucketUpload].Limit()))
+	assert.Equal(t, 1024*1024, int(tbs[bucketDownload].Limit()))
+	assert.Nil(t, getRemoteTokenBuckets("remote"))
+}
+
+func TestLimitsBandwidth(t *testing.T) {
+	oldConfigFileGet, oldBwLimit := fs.ConfigFileGet, fs.Config.BwLimit
+	defer func() {
+		fs.ConfigFileGet, fs.Config.BwLimit = oldConfigFileGet, oldBwLimit
+	}()
+	fs.ConfigFileGet = func(section, key string, defaultVal ...string) string {
+		if section == "limited" && key == "bwlimit" {
+			return "10M"
+		}
+		return ""
+	}
+	local := &testInfo{name: "local", features: fs.Features{IsLocal: true}}
+	limited := &testInfo{name: "limited"}
+
+	fs.Config.BwLimit = nil
+	assert.False(t, LimitsBandwidth(nil))
+	assert.False(t, LimitsBandwidth(local))
+	assert.True(t, LimitsBandwidth(limited))
+
+	require.NoError(t, fs.Config.BwLimit.Set("08:00,1M 18:00,off"))
+	assert.True(t, LimitsBandwidth(local))
+}
+
 func TestAccountWithBuffer(t *testing.T) {
 	in := ioutil.NopCloser(bytes.NewBuffer([]byte{1}))
 
@@ -60,6 +145,24 @@ func TestAccountWithBuffer(t *testing.T) {
 	assert.NoError(t, acc.Close())
 }
 
+func TestAccountWithBufferSize(t *testing.T) {
+	in := ioutil.NopCloser(bytes.NewBuffer([]byte{1}))
+
+	acc := NewAccountSizeName(in, -1, "test")
+	acc.WithBufferSize(0)
+	// should not have a buffer if the size is 0
+	_, ok := acc.in.(*asyncreader.AsyncReader)
+	require.False(t, ok)
+	assert.NoError(t, acc.Close())
+
+	acc = NewAccountSizeName(in, -1, "test")
+	acc.WithBufferSize(4 * asyncreader.BufferSize)
+	// should have a buffer for an unknown size
+	_, ok = acc.in.(*asyncreader.AsyncReader)
+	require.True(t, ok)
+	assert.NoError(t, acc.Close())
+}
+
 func TestAccountGetUpdateReader(t *testing.T) {
 	in := ioutil.NopCloser(bytes.NewBuffer([]byte{1}))
 	acc := NewAccountSizeName(in, 1, "test")
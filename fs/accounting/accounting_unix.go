@@ -26,7 +26,7 @@ func startSignalHandler() {
 			bwLimitToggledOff = !bwLimitToggledOff
 			tokenBucket, prevTokenBucket = prevTokenBucket, tokenBucket
 			s := "disabled"
-			if tokenBucket != nil {
+			if tokenBucket.isSet() {
 				s = "enabled"
 			}
 			tokenBucketMu.Unlock()
@@ -33,6 +33,20 @@ func (ip *inProgress) clear(name string) {
 	delete(ip.m, name)
 }
 
+// pending returns the number of bytes still to be read by the
+// transfers in progress where their size is known
+func (ip *inProgress) pending() (bytes int64) {
+	ip.mu.Lock()
+	defer ip.mu.Unlock()
+	for _, acc := range ip.m {
+		done, size := acc.progress()
+		if size > done {
+			bytes += size - done
+		}
+	}
+	return bytes
+}
+
 // get gets the account for name, of nil if not found
 func (ip *inProgress) get(name string) *Account {
 	ip.mu.Lock()
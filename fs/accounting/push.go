@@ -0,0 +1,206 @@
+// Push the stats to an external monitoring system
+
+package accounting
+
+import (
+	"bytes"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/ncw/rclone/fs"
+	"github.com/ncw/rclone/fs/fshttp"
+	"github.com/ncw/rclone/lib/rest"
+	"github.com/pkg/errors"
+)
+
+// Stats push formats
+const (
+	PushFormatPrometheus = "prometheus"
+	PushFormatInfluxDB   = "influxdb"
+	PushFormatStatsd     = "statsd"
+)
+
+// PushOptions control where and how often the stats are pushed
+type PushOptions struct {
+	URL      string        // where to push the stats to
+	Format   string        // one of the PushFormat constants
+	Interval time.Duration // how often to push the stats
+	Job      string        // name of the job the stats are for
+}
+
+// metric is a single named value pushed to the monitoring system
+type metric struct {
+	name    string
+	counter bool // set if the value only ever goes up
+	value   float64
+}
+
+// metrics returns a snapshot of the stats as metrics
+func (s *StatsInfo) metrics() []metric {
+	s.lock.RLock()
+	defer s.lock.RUnlock()
+	return []metric{
+		{"bytes_transferred_total", true, float64(s.bytes)},
+		{"errors_total", true, float64(s.errors)},
+		{"checks_total", true, float64(s.checks)},
+		{"transfers_total", true, float64(s.transfers)},
+		{"deletes_total", true, float64(s.deletes)},
+		{"checking", false, float64(len(s.checking))},
+		{"transferring", false, float64(len(s.transferring))},
+		{"elapsed_seconds", false, time.Now().Sub(s.start).Seconds()},
+	}
+}
+
+// formatPrometheus formats the metrics in the Prometheus text format
+func formatPrometheus(metrics []metric) []byte {
+	var buf bytes.Buffer
+	for _, m := range metrics {
+		kind := "gauge"
+		if m.counter {
+			kind = "counter"
+		}
+		fmt.Fprintf(&buf, "# TYPE rclone_%s %s\n", m.name, kind)
+		fmt.Fprintf(&buf, "rclone_%s %g\n", m.name, m.value)
+	}
+	return buf.Bytes()
+}
+
+// formatInfluxDB formats the metrics as an InfluxDB line protocol point
+func formatInfluxDB(job string, metrics []metric, now time.Time) []byte {
+	fields := make([]string, len(metrics))
+	for i, m := range metrics {
+		fields[i] = fmt.Sprintf("%s=%g", m.name, m.value)
+	}
+	job = strings.NewReplacer(",", `\,`, "=", `\=`, " ", `\ `).Replace(job)
+	return []byte(fmt.Sprintf("rclone,job=%s %s %d\n", job, strings.Join(fields, ","), now.UnixNano()))
+}
+
+// formatStatsd formats the metrics as statsd gauges
+func formatStatsd(job string, metrics []metric) []byte {
+	var buf bytes.Buffer
+	for _, m := range metrics {
+		fmt.Fprintf(&buf, "rclone.%s.%s:%g|g\n", job, m.name, m.value)
+	}
+	return buf.Bytes()
+}
+
+// pusher sends the stats to the monitoring system
+type pusher struct {
+	opt    PushOptions
+	client *http.Client
+}
+
+// newPusher checks the options and makes a pusher
+func newPusher(opt PushOptions) (*pusher, error) {
+	if opt.Job == "" {
+		opt.Job = "rclone"
+	}
+	switch opt.Format {
+	case PushFormatPrometheus, PushFormatInfluxDB:
+		u, err := url.Parse(opt.URL)
+		if err != nil {
+			return nil, errors.Wrap(err, "bad stats push URL")
+		}
+		if u.Scheme != "http" && u.Scheme != "https" {
+			return nil, errors.Errorf("stats push URL for %s must be http or https, not %q", opt.Format, u.Scheme)
+		}
+		if opt.Format == PushFormatPrometheus {
+			opt.URL = strings.TrimRight(u.String(), "/") + "/metrics/job/" + rest.URLPathEscape(opt.Job)
+		}
+	case PushFormatStatsd:
+		if _, _, err := net.SplitHostPort(opt.URL); err != nil {
+			return nil, errors.Wrap(err, "stats push URL for statsd must be host:port")
+		}
+	default:
+		return nil, errors.Errorf("unknown stats push format %q", opt.Format)
+	}
+	return &pusher{
+		opt:    opt,
+		client: fshttp.NewClient(fs.Config),
+	}, nil
+}
+
+// push sends the metrics once
+func (p *pusher) push(metrics []metric) error {
+	switch p.opt.Format {
+	case PushFormatStatsd:
+		conn, err := net.Dial("udp", p.opt.URL)
+		if err != nil {
+			return err
+		}
+		_, err = conn.Write(formatStatsd(p.opt.Job, metrics))
+		closeErr := conn.Close()
+		if err == nil {
+			err = closeErr
+		}
+		return err
+	case PushFormatPrometheus:
+		return p.send("PUT", "text/plain; version=0.0.4", formatPrometheus(metrics))
+	default:
+		return p.send("POST", "text/plain", formatInfluxDB(p.opt.Job, metrics, time.Now()))
+	}
+}
+
+// send body to the push URL over http
+func (p *pusher) send(method, contentType string, body []byte) error {
+	req, err := http.NewRequest(method, p.opt.URL, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", contentType)
+	req.Header.Set("User-Agent", fs.Config.UserAgent)
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return err
+	}
+	_ = resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return errors.Errorf("HTTP error %v (%v) returned", resp.StatusCode, resp.Status)
+	}
+	return nil
+}
+
+// StartPush pushes the stats every opt.Interval until the returned
+// stop function is called.  Stop pushes the final stats before
+// returning.
+//
+// Errors pushing the stats are logged but don't stop the transfers.
+func StartPush(opt PushOptions) (stop func(), err error) {
+	p, err := newPusher(opt)
+	if err != nil {
+		return nil, err
+	}
+	pushOnce := func() {
+		if err := p.push(Stats.metrics()); err != nil {
+			fs.Errorf(nil, "Failed to push stats to %s: %v", p.opt.Format, err)
+		}
+	}
+	stopPush := make(chan struct{})
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		if opt.Interval <= 0 {
+			<-stopPush
+			return
+		}
+		ticker := time.NewTicker(opt.Interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+				pushOnce()
+			case <-stopPush:
+				return
+			}
+		}
+	}()
+	return func() {
+		close(stopPush)
+		<-done
+		pushOnce()
+	}, nil
+}
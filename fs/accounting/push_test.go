@@ -0,0 +1,82 @@
+package accounting
+
+import (
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var testMetrics = []metric{
+	{"errors_total", true, 2},
+	{"transferring", false, 3},
+}
+
+func TestPushFormats(t *testing.T) {
+	assert.Equal(t, `# TYPE rclone_errors_total counter
+rclone_errors_total 2
+# TYPE rclone_transferring gauge
+rclone_transferring 3
+`, string(formatPrometheus(testMetrics)))
+
+	now := time.Unix(1, 5)
+	assert.Equal(t, "rclone,job=my\\ job errors_total=2,transferring=3 1000000005\n", string(formatInfluxDB("my job", testMetrics, now)))
+
+	assert.Equal(t, "rclone.backup.errors_total:2|g\nrclone.backup.transferring:3|g\n", string(formatStatsd("backup", testMetrics)))
+}
+
+func TestNewPusher(t *testing.T) {
+	p, err := newPusher(PushOptions{URL: "http://localhost:9091/", Format: PushFormatPrometheus})
+	require.NoError(t, err)
+	assert.Equal(t, "http://localhost:9091/metrics/job/rclone", p.opt.URL)
+	p, err = newPusher(PushOptions{URL: "http://localhost:9091/", Format: PushFormatPrometheus, Job: "my job"})
+	require.NoError(t, err)
+	assert.Equal(t, "http://localhost:9091/metrics/job/my%20job", p.opt.URL)
+
+	_, err = newPusher(PushOptions{URL: "localhost:8125", Format: PushFormatPrometheus})
+	assert.Error(t, err)
+	_, err = newPusher(PushOptions{URL: "http://localhost:8125/", Format: PushFormatStatsd})
+	assert.Error(t, err)
+	_, err = newPusher(PushOptions{URL: "localhost:8125", Format: "potato"})
+	assert.Error(t, err)
+}
+
+func TestPushPrometheus(t *testing.T) {
+	var (
+		method, path string
+		body         []byte
+	)
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		method, path = r.Method, r.URL.Path
+		body, _ = ioutil.ReadAll(r.Body)
+	}))
+	defer ts.Close()
+
+	p, err := newPusher(PushOptions{URL: ts.URL, Format: PushFormatPrometheus, Job: "backup"})
+	require.NoError(t, err)
+	require.NoError(t, p.push(testMetrics))
+	assert.Equal(t, "PUT", method)
+	assert.Equal(t, "/metrics/job/backup", path)
+	assert.Equal(t, formatPrometheus(testMetrics), body)
+}
+
+func TestPushStatsd(t *testing.T) {
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	require.NoError(t, err)
+	defer func() { _ = conn.Close() }()
+
+	p, err := newPusher(PushOptions{URL: conn.LocalAddr().String(), Format: PushFormatStatsd, Job: "backup"})
+	require.NoError(t, err)
+	require.NoError(t, p.push(testMetrics))
+
+	buf := make([]byte, 1024)
+	require.NoError(t, conn.SetReadDeadline(time.Now().Add(5*time.Second)))
+	n, _, err := conn.ReadFrom(buf)
+	require.NoError(t, err)
+	assert.Equal(t, formatStatsd("backup", testMetrics), buf[:n])
+}
@@ -33,6 +33,8 @@ type StatsInfo struct {
 	deletes      int64
 	start        time.Time
 	inProgress   *inProgress
+	failedFiles  map[string]error // files which failed and why
+	retryAll     bool             // set if an error can't be retried by retrying failedFiles
 }
 
 // NewStats cretates an initialised StatsInfo
@@ -95,6 +97,23 @@ func (s *StatsInfo) Bytes(bytes int64) {
 	s.bytes += bytes
 }
 
+// GetBytes returns the number of bytes transferred so far
+func (s *StatsInfo) GetBytes() int64 {
+	s.lock.RLock()
+	defer s.lock.RUnlock()
+	return s.bytes
+}
+
+// GetBytesWithPending returns the number of bytes transferred so far
+// plus the bytes still to be transferred by the transfers in
+// progress
+func (s *StatsInfo) GetBytesWithPending() int64 {
+	s.lock.RLock()
+	bytes := s.bytes
+	s.lock.RUnlock()
+	return bytes + s.inProgress.pending()
+}
+
 // Errors updates the stats for errors
 func (s *StatsInfo) Errors(errors int64) {
 	s.lock.Lock()
@@ -116,6 +135,20 @@ func (s *StatsInfo) GetLastError() error {
 	return s.lastError
 }
 
+// GetChecks reads the number of checks
+func (s *StatsInfo) GetChecks() int64 {
+	s.lock.RLock()
+	defer s.lock.RUnlock()
+	return s.checks
+}
+
+// GetDeletes reads the number of deletes
+func (s *StatsInfo) GetDeletes() int64 {
+	s.lock.RLock()
+	defer s.lock.RUnlock()
+	return s.deletes
+}
+
 // Deletes updates the stats for deletes
 func (s *StatsInfo) Deletes(deletes int64) int64 {
 	s.lock.Lock()
@@ -126,20 +159,24 @@ func (s *StatsInfo) Deletes(deletes int64) int64 {
 
 // ResetCounters sets the counters (bytes, checks, errors, transfers) to 0
 func (s *StatsInfo) ResetCounters() {
-	s.lock.RLock()
-	defer s.lock.RUnlock()
+	s.lock.Lock()
+	defer s.lock.Unlock()
 	s.bytes = 0
 	s.errors = 0
 	s.checks = 0
 	s.transfers = 0
 	s.deletes = 0
+	s.failedFiles = nil
+	s.retryAll = false
 }
 
-// ResetErrors sets the errors count to 0
+// ResetErrors sets the errors count to 0 and forgets the failed files
 func (s *StatsInfo) ResetErrors() {
-	s.lock.RLock()
-	defer s.lock.RUnlock()
+	s.lock.Lock()
+	defer s.lock.Unlock()
 	s.errors = 0
+	s.failedFiles = nil
+	s.retryAll = false
 }
 
 // Errored returns whether there have been any errors
@@ -157,6 +194,44 @@ func (s *StatsInfo) Error(err error) {
 	s.lastError = err
 }
 
+// FailedFile records that the file remote failed with err.  This
+// doesn't count the error - use Error for that.
+func (s *StatsInfo) FailedFile(remote string, err error) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	if s.failedFiles == nil {
+		s.failedFiles = make(map[string]error)
+	}
+	s.failedFiles[remote] = err
+}
+
+// FailedFiles returns a copy of the files which have failed and why
+func (s *StatsInfo) FailedFiles() map[string]error {
+	s.lock.RLock()
+	defer s.lock.RUnlock()
+	failed := make(map[string]error, len(s.failedFiles))
+	for remote, err := range s.failedFiles {
+		failed[remote] = err
+	}
+	return failed
+}
+
+// RetryAll records that an error happened which can't be fixed by
+// retrying just the failed files, eg a directory listing failed
+func (s *StatsInfo) RetryAll() {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	s.retryAll = true
+}
+
+// GetRetryAll returns whether an error happened which can't be fixed
+// by retrying just the failed files
+func (s *StatsInfo) GetRetryAll() bool {
+	s.lock.RLock()
+	defer s.lock.RUnlock()
+	return s.retryAll
+}
+
 // Checking adds a check into the stats
 func (s *StatsInfo) Checking(remote string) {
 	s.lock.Lock()
@@ -0,0 +1,29 @@
+package accounting
+
+import (
+	"testing"
+
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestStatsFailedFiles(t *testing.T) {
+	s := NewStats()
+	assert.Equal(t, map[string]error{}, s.FailedFiles())
+	assert.False(t, s.GetRetryAll())
+
+	err1 := errors.New("potato")
+	err2 := errors.New("sausage")
+	s.FailedFile("a", err1)
+	s.FailedFile("b/c", err2)
+	s.FailedFile("a", err2)
+	assert.Equal(t, map[string]error{"a": err2, "b/c": err2}, s.FailedFiles())
+	assert.False(t, s.Errored(), "FailedFile shouldn't count errors")
+
+	s.RetryAll()
+	assert.True(t, s.GetRetryAll())
+
+	s.ResetErrors()
+	assert.Equal(t, map[string]error{}, s.FailedFiles())
+	assert.False(t, s.GetRetryAll())
+}
@@ -1,6 +1,7 @@
 package accounting
 
 import (
+	"fmt"
 	"sync"
 	"time"
 
@@ -12,15 +13,30 @@ import (
 // Globals
 var (
 	tokenBucketMu     sync.Mutex // protects the token bucket variables
-	tokenBucket       *rate.Limiter
+	tokenBucket       tokenBuckets
 	prevTokenBucket   = tokenBucket
 	bwLimitToggledOff = false
 	currLimitMu       sync.Mutex // protects changes to the timeslot
 	currLimit         fs.BwTimeSlot
+	remoteBucketsMu   sync.Mutex                   // protects remoteBuckets
+	remoteBuckets     = map[string]*tokenBuckets{} // token buckets for remotes with bwlimit set in the config
 )
 
 const maxBurstSize = 1 * 1024 * 1024 // must be bigger than the biggest request
 
+// Indexes into tokenBuckets
+const (
+	bucketUpload = iota
+	bucketDownload
+	bucketCount
+)
+
+// tokenBuckets holds the token buckets for uploads and downloads.
+//
+// These are the same bucket if the limits are the same and nil if
+// that direction is unlimited.
+type tokenBuckets [bucketCount]*rate.Limiter
+
 // make a new empty token bucket with the bandwidth given
 func newTokenBucket(bandwidth fs.SizeSuffix) *rate.Limiter {
 	newTokenBucket := rate.NewLimiter(rate.Limit(bandwidth), maxBurstSize)
@@ -32,15 +48,46 @@ func newTokenBucket(bandwidth fs.SizeSuffix) *rate.Limiter {
 	return newTokenBucket
 }
 
+// make new empty token buckets for the bandwidths given
+func newTokenBuckets(bandwidth fs.BwPair) (tbs tokenBuckets) {
+	if bandwidth.Tx > 0 {
+		tbs[bucketUpload] = newTokenBucket(bandwidth.Tx)
+	}
+	if bandwidth.Rx == bandwidth.Tx {
+		tbs[bucketDownload] = tbs[bucketUpload]
+	} else if bandwidth.Rx > 0 {
+		tbs[bucketDownload] = newTokenBucket(bandwidth.Rx)
+	}
+	return tbs
+}
+
+// isSet returns true if any of the token buckets are limiting
+func (tbs *tokenBuckets) isSet() bool {
+	for _, tb := range tbs {
+		if tb != nil {
+			return true
+		}
+	}
+	return false
+}
+
+// describeBandwidth returns a description of bandwidth for the logs
+func describeBandwidth(bandwidth fs.BwPair) string {
+	if bandwidth.Tx == bandwidth.Rx {
+		return fmt.Sprintf("%vBytes/s", &bandwidth.Tx)
+	}
+	return fmt.Sprintf("%vBytes/s upload and %vBytes/s download", &bandwidth.Tx, &bandwidth.Rx)
+}
+
 // StartTokenBucket starts the token bucket if necessary
 func StartTokenBucket() {
 	currLimitMu.Lock()
 	currLimit := fs.Config.BwLimit.LimitAt(time.Now())
 	currLimitMu.Unlock()
 
-	if currLimit.Bandwidth > 0 {
-		tokenBucket = newTokenBucket(currLimit.Bandwidth)
-		fs.Infof(nil, "Starting bandwidth limiter at %vBytes/s", &currLimit.Bandwidth)
+	if currLimit.Bandwidth.IsSet() {
+		tokenBucket = newTokenBuckets(currLimit.Bandwidth)
+		fs.Infof(nil, "Starting bandwidth limiter at %s", describeBandwidth(currLimit.Bandwidth))
 
 		// Start the SIGUSR2 signal handler to toggle bandwidth.
 		// This function does nothing in windows systems.
@@ -68,24 +115,23 @@ func StartTokenTicker() {
 				// If bwlimit is toggled off, the change should only
 				// become active on the next toggle, which causes
 				// an exchange of tokenBucket <-> prevTokenBucket
-				var targetBucket **rate.Limiter
+				var targetBucket *tokenBuckets
 				if bwLimitToggledOff {
 					targetBucket = &prevTokenBucket
 				} else {
 					targetBucket = &tokenBucket
 				}
 
-				// Set new bandwidth. If unlimited, set tokenbucket to nil.
-				if limitNow.Bandwidth > 0 {
-					*targetBucket = newTokenBucket(limitNow.Bandwidth)
+				// Set new bandwidth. If unlimited, the token buckets are nil.
+				*targetBucket = newTokenBuckets(limitNow.Bandwidth)
+				if limitNow.Bandwidth.IsSet() {
 					if bwLimitToggledOff {
 						fs.Logf(nil, "Scheduled bandwidth change. "+
-							"Limit will be set to %vBytes/s when toggled on again.", &limitNow.Bandwidth)
+							"Limit will be set to %s when toggled on again.", describeBandwidth(limitNow.Bandwidth))
 					} else {
-						fs.Logf(nil, "Scheduled bandwidth change. Limit set to %vBytes/s", &limitNow.Bandwidth)
+						fs.Logf(nil, "Scheduled bandwidth change. Limit set to %s", describeBandwidth(limitNow.Bandwidth))
 					}
 				} else {
-					*targetBucket = nil
 					fs.Logf(nil, "Scheduled bandwidth change. Bandwidth limits disabled")
 				}
 
@@ -97,18 +143,80 @@ func StartTokenTicker() {
 	}()
 }
 
+// getRemoteTokenBuckets returns the token buckets for the remote
+// called name or nil if it doesn't have bwlimit set in the config.
+func getRemoteTokenBuckets(name string) *tokenBuckets {
+	if name == "" {
+		return nil
+	}
+	remoteBucketsMu.Lock()
+	defer remoteBucketsMu.Unlock()
+	tbs, ok := remoteBuckets[name]
+	if ok {
+		return tbs
+	}
+	if limit := fs.ConfigFileGet(name, "bwlimit"); limit != "" {
+		var bandwidth fs.BwPair
+		err := bandwidth.Set(limit)
+		if err != nil {
+			fs.Errorf(nil, "Ignoring bad bwlimit %q for remote %q: %v", limit, name, err)
+		} else if bandwidth.IsSet() {
+			newTbs := newTokenBuckets(bandwidth)
+			tbs = &newTbs
+			fs.Infof(nil, "Starting bandwidth limiter for remote %q at %s", name, describeBandwidth(bandwidth))
+		}
+	}
+	remoteBuckets[name] = tbs
+	return tbs
+}
+
+// LimitsBandwidth returns true if transfers from f may be limited by
+// --bwlimit, at any time in its timetable, or by the bwlimit set in
+// the config of f.
+//
+// Use this to decide whether data can be sent without reading it
+// through an Account, eg with sendfile.
+func LimitsBandwidth(f fs.Info) bool {
+	for _, slot := range fs.Config.BwLimit {
+		if slot.Bandwidth.IsSet() {
+			return true
+		}
+	}
+	return f != nil && getRemoteTokenBuckets(f.Name()) != nil
+}
+
+// waitTokenBuckets waits for n bytes worth of tokens from each of the
+// buckets for the directions given which are in use.  A bucket shared
+// between directions is only waited on once.
+func waitTokenBuckets(tbs *tokenBuckets, upload, download bool, n int) {
+	var tbUpload *rate.Limiter
+	if upload {
+		tbUpload = tbs[bucketUpload]
+		if tbUpload != nil {
+			err := tbUpload.WaitN(context.Background(), n)
+			if err != nil {
+				fs.Errorf(nil, "Token bucket error: %v", err)
+			}
+		}
+	}
+	if download {
+		if tb := tbs[bucketDownload]; tb != nil && tb != tbUpload {
+			err := tb.WaitN(context.Background(), n)
+			if err != nil {
+				fs.Errorf(nil, "Token bucket error: %v", err)
+			}
+		}
+	}
+}
+
 // limitBandwith sleeps for the correct amount of time for the passage
-// of n bytes according to the current bandwidth limit
-func limitBandwidth(n int) {
+// of n bytes according to the current bandwidth limit for the
+// directions given.
+func limitBandwidth(n int, upload, download bool) {
 	tokenBucketMu.Lock()
 
 	// Limit the transfer speed if required
-	if tokenBucket != nil {
-		err := tokenBucket.WaitN(context.Background(), n)
-		if err != nil {
-			fs.Errorf(nil, "Token bucket error: %v", err)
-		}
-	}
+	waitTokenBuckets(&tokenBucket, upload, download, n)
 
 	tokenBucketMu.Unlock()
 }
@@ -9,10 +9,47 @@ import (
 	"github.com/pkg/errors"
 )
 
+// BwPair represents an upload and a download bandwidth
+type BwPair struct {
+	Tx SizeSuffix // upload bandwidth
+	Rx SizeSuffix // download bandwidth
+}
+
+// String returns a printable representation of a BwPair
+func (bp BwPair) String() string {
+	if bp.Tx == bp.Rx {
+		return bp.Tx.String()
+	}
+	return bp.Tx.String() + ":" + bp.Rx.String()
+}
+
+// Set the bandwidth from a string which is either the value to use
+// for upload and download or an upload:download pair, eg "10M:1M"
+func (bp *BwPair) Set(s string) (err error) {
+	colon := strings.Index(s, ":")
+	if colon < 0 {
+		err = bp.Tx.Set(s)
+		bp.Rx = bp.Tx
+		return err
+	}
+	if err = bp.Tx.Set(s[:colon]); err != nil {
+		return errors.Wrap(err, "bad upload bandwidth")
+	}
+	if err = bp.Rx.Set(s[colon+1:]); err != nil {
+		return errors.Wrap(err, "bad download bandwidth")
+	}
+	return nil
+}
+
+// IsSet returns true if either of the bandwidths is limited
+func (bp BwPair) IsSet() bool {
+	return bp.Tx > 0 || bp.Rx > 0
+}
+
 // BwTimeSlot represents a bandwidth configuration at a point in time.
 type BwTimeSlot struct {
 	HHMM      int
-	Bandwidth SizeSuffix
+	Bandwidth BwPair
 }
 
 // BwTimetable contains all configured time slots.
@@ -32,6 +69,7 @@ func (x *BwTimetable) Set(s string) error {
 	// The timetable is formatted as:
 	// "hh:mm,bandwidth hh:mm,banwidth..." ex: "10:00,10G 11:30,1G 18:00,off"
 	// If only a single bandwidth identifier is provided, we assume constant bandwidth.
+	// Each bandwidth may be an upload:download pair, ex: "10:00,10G:1G"
 
 	if len(s) == 0 {
 		return errors.New("empty string")
@@ -91,7 +129,7 @@ func (x *BwTimetable) Set(s string) error {
 func (x BwTimetable) LimitAt(tt time.Time) BwTimeSlot {
 	// If the timetable is empty, we return an unlimited BwTimeSlot starting at midnight.
 	if len(x) == 0 {
-		return BwTimeSlot{HHMM: 0, Bandwidth: -1}
+		return BwTimeSlot{HHMM: 0, Bandwidth: BwPair{Tx: -1, Rx: -1}}
 	}
 
 	HHMM := tt.Hour()*100 + tt.Minute()
@@ -19,19 +19,31 @@ func TestBwTimetableSet(t *testing.T) {
 		err  bool
 	}{
 		{"", BwTimetable{}, true},
-		{"0", BwTimetable{BwTimeSlot{HHMM: 0, Bandwidth: 0}}, false},
-		{"666", BwTimetable{BwTimeSlot{HHMM: 0, Bandwidth: 666 * 1024}}, false},
-		{"10:20,666", BwTimetable{BwTimeSlot{HHMM: 1020, Bandwidth: 666 * 1024}}, false},
+		{"0", BwTimetable{BwTimeSlot{HHMM: 0, Bandwidth: BwPair{Tx: 0, Rx: 0}}}, false},
+		{"666", BwTimetable{BwTimeSlot{HHMM: 0, Bandwidth: BwPair{Tx: 666 * 1024, Rx: 666 * 1024}}}, false},
+		{"10:20,666", BwTimetable{BwTimeSlot{HHMM: 1020, Bandwidth: BwPair{Tx: 666 * 1024, Rx: 666 * 1024}}}, false},
 		{
 			"11:00,333 13:40,666 23:50,10M 23:59,off",
 			BwTimetable{
-				BwTimeSlot{HHMM: 1100, Bandwidth: 333 * 1024},
-				BwTimeSlot{HHMM: 1340, Bandwidth: 666 * 1024},
-				BwTimeSlot{HHMM: 2350, Bandwidth: 10 * 1024 * 1024},
-				BwTimeSlot{HHMM: 2359, Bandwidth: -1},
+				BwTimeSlot{HHMM: 1100, Bandwidth: BwPair{Tx: 333 * 1024, Rx: 333 * 1024}},
+				BwTimeSlot{HHMM: 1340, Bandwidth: BwPair{Tx: 666 * 1024, Rx: 666 * 1024}},
+				BwTimeSlot{HHMM: 2350, Bandwidth: BwPair{Tx: 10 * 1024 * 1024, Rx: 10 * 1024 * 1024}},
+				BwTimeSlot{HHMM: 2359, Bandwidth: BwPair{Tx: -1, Rx: -1}},
 			},
 			false,
 		},
+		{"10M:1M", BwTimetable{BwTimeSlot{HHMM: 0, Bandwidth: BwPair{Tx: 10 * 1024 * 1024, Rx: 1024 * 1024}}}, false},
+		{"off:1M", BwTimetable{BwTimeSlot{HHMM: 0, Bandwidth: BwPair{Tx: -1, Rx: 1024 * 1024}}}, false},
+		{
+			"11:00,333:off 13:40,666",
+			BwTimetable{
+				BwTimeSlot{HHMM: 1100, Bandwidth: BwPair{Tx: 333 * 1024, Rx: -1}},
+				BwTimeSlot{HHMM: 1340, Bandwidth: BwPair{Tx: 666 * 1024, Rx: 666 * 1024}},
+			},
+			false,
+		},
+		{"10M:bad", BwTimetable{}, true},
+		{"11:00,bad:10M", BwTimetable{}, true},
 		{"bad,bad", BwTimetable{}, true},
 		{"bad bad", BwTimetable{}, true},
 		{"bad", BwTimetable{}, true},
@@ -50,6 +62,20 @@ func TestBwTimetableSet(t *testing.T) {
 	}
 }
 
+func TestBwPairString(t *testing.T) {
+	for _, test := range []struct {
+		in   BwPair
+		want string
+	}{
+		{BwPair{Tx: -1, Rx: -1}, "off"},
+		{BwPair{Tx: 10 * 1024 * 1024, Rx: 10 * 1024 * 1024}, "10M"},
+		{BwPair{Tx: 10 * 1024 * 1024, Rx: 1024}, "10M:1k"},
+		{BwPair{Tx: -1, Rx: 1024}, "off:1k"},
+	} {
+		assert.Equal(t, test.want, test.in.String())
+	}
+}
+
 func TestBwTimetableLimitAt(t *testing.T) {
 	for _, test := range []struct {
 		tt   BwTimetable
@@ -59,52 +85,52 @@ func TestBwTimetableLimitAt(t *testing.T) {
 		{
 			BwTimetable{},
 			time.Date(2017, time.April, 20, 15, 0, 0, 0, time.UTC),
-			BwTimeSlot{HHMM: 0, Bandwidth: -1},
+			BwTimeSlot{HHMM: 0, Bandwidth: BwPair{Tx: -1, Rx: -1}},
 		},
 		{
-			BwTimetable{BwTimeSlot{HHMM: 1100, Bandwidth: 333 * 1024}},
+			BwTimetable{BwTimeSlot{HHMM: 1100, Bandwidth: BwPair{Tx: 333 * 1024, Rx: 333 * 1024}}},
 			time.Date(2017, time.April, 20, 15, 0, 0, 0, time.UTC),
-			BwTimeSlot{HHMM: 1100, Bandwidth: 333 * 1024},
+			BwTimeSlot{HHMM: 1100, Bandwidth: BwPair{Tx: 333 * 1024, Rx: 333 * 1024}},
 		},
 		{
 			BwTimetable{
-				BwTimeSlot{HHMM: 1100, Bandwidth: 333 * 1024},
-				BwTimeSlot{HHMM: 1300, Bandwidth: 666 * 1024},
-				BwTimeSlot{HHMM: 2301, Bandwidth: 1024 * 1024},
-				BwTimeSlot{HHMM: 2350, Bandwidth: -1},
+				BwTimeSlot{HHMM: 1100, Bandwidth: BwPair{Tx: 333 * 1024, Rx: 333 * 1024}},
+				BwTimeSlot{HHMM: 1300, Bandwidth: BwPair{Tx: 666 * 1024, Rx: 666 * 1024}},
+				BwTimeSlot{HHMM: 2301, Bandwidth: BwPair{Tx: 1024 * 1024, Rx: 1024 * 1024}},
+				BwTimeSlot{HHMM: 2350, Bandwidth: BwPair{Tx: -1, Rx: -1}},
 			},
 			time.Date(2017, time.April, 20, 10, 15, 0, 0, time.UTC),
-			BwTimeSlot{HHMM: 2350, Bandwidth: -1},
+			BwTimeSlot{HHMM: 2350, Bandwidth: BwPair{Tx: -1, Rx: -1}},
 		},
 		{
 			BwTimetable{
-				BwTimeSlot{HHMM: 1100, Bandwidth: 333 * 1024},
-				BwTimeSlot{HHMM: 1300, Bandwidth: 666 * 1024},
-				BwTimeSlot{HHMM: 2301, Bandwidth: 1024 * 1024},
-				BwTimeSlot{HHMM: 2350, Bandwidth: -1},
+				BwTimeSlot{HHMM: 1100, Bandwidth: BwPair{Tx: 333 * 1024, Rx: 333 * 1024}},
+				BwTimeSlot{HHMM: 1300, Bandwidth: BwPair{Tx: 666 * 1024, Rx: 666 * 1024}},
+				BwTimeSlot{HHMM: 2301, Bandwidth: BwPair{Tx: 1024 * 1024, Rx: 1024 * 1024}},
+				BwTimeSlot{HHMM: 2350, Bandwidth: BwPair{Tx: -1, Rx: -1}},
 			},
 			time.Date(2017, time.April, 20, 11, 0, 0, 0, time.UTC),
-			BwTimeSlot{HHMM: 1100, Bandwidth: 333 * 1024},
+			BwTimeSlot{HHMM: 1100, Bandwidth: BwPair{Tx: 333 * 1024, Rx: 333 * 1024}},
 		},
 		{
 			BwTimetable{
-				BwTimeSlot{HHMM: 1100, Bandwidth: 333 * 1024},
-				BwTimeSlot{HHMM: 1300, Bandwidth: 666 * 1024},
-				BwTimeSlot{HHMM: 2301, Bandwidth: 1024 * 1024},
-				BwTimeSlot{HHMM: 2350, Bandwidth: -1},
+				BwTimeSlot{HHMM: 1100, Bandwidth: BwPair{Tx: 333 * 1024, Rx: 333 * 1024}},
+				BwTimeSlot{HHMM: 1300, Bandwidth: BwPair{Tx: 666 * 1024, Rx: 666 * 1024}},
+				BwTimeSlot{HHMM: 2301, Bandwidth: BwPair{Tx: 1024 * 1024, Rx: 1024 * 1024}},
+				BwTimeSlot{HHMM: 2350, Bandwidth: BwPair{Tx: -1, Rx: -1}},
 			},
 			time.Date(2017, time.April, 20, 13, 1, 0, 0, time.UTC),
-			BwTimeSlot{HHMM: 1300, Bandwidth: 666 * 1024},
+			BwTimeSlot{HHMM: 1300, Bandwidth: BwPair{Tx: 666 * 1024, Rx: 666 * 1024}},
 		},
 		{
 			BwTimetable{
-				BwTimeSlot{HHMM: 1100, Bandwidth: 333 * 1024},
-				BwTimeSlot{HHMM: 1300, Bandwidth: 666 * 1024},
-				BwTimeSlot{HHMM: 2301, Bandwidth: 1024 * 1024},
-				BwTimeSlot{HHMM: 2350, Bandwidth: -1},
+				BwTimeSlot{HHMM: 1100, Bandwidth: BwPair{Tx: 333 * 1024, Rx: 333 * 1024}},
+				BwTimeSlot{HHMM: 1300, Bandwidth: BwPair{Tx: 666 * 1024, Rx: 666 * 1024}},
+				BwTimeSlot{HHMM: 2301, Bandwidth: BwPair{Tx: 1024 * 1024, Rx: 1024 * 1024}},
+				BwTimeSlot{HHMM: 2350, Bandwidth: BwPair{Tx: -1, Rx: -1}},
 			},
 			time.Date(2017, time.April, 20, 23, 59, 0, 0, time.UTC),
-			BwTimeSlot{HHMM: 2350, Bandwidth: -1},
+			BwTimeSlot{HHMM: 2350, Bandwidth: BwPair{Tx: -1, Rx: -1}},
 		},
 	} {
 		slot := test.tt.LimitAt(test.now)
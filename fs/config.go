@@ -27,32 +27,44 @@ var (
 // ConfigInfo is filesystem config options
 type ConfigInfo struct {
 	LogLevel              LogLevel
+	SubsystemLogLevels    map[string]LogLevel // per subsystem overrides of LogLevel
 	StatsLogLevel         LogLevel
+	UseJSONLog            bool
 	DryRun                bool
+	Interactive           bool
 	CheckSum              bool
 	SizeOnly              bool
 	IgnoreTimes           bool
 	IgnoreExisting        bool
+	NoCheckDest           bool // don't look at the destination, just upload
+	NoTraverse            bool // look up the source files in the destination rather than listing it
 	ModifyWindow          time.Duration
 	Checkers              int
 	Transfers             int
 	ConnectTimeout        time.Duration // Connect timeout
 	Timeout               time.Duration // Data channel timeout
 	Dump                  DumpFlags
+	DumpDir               string // write HTTP dumps to numbered files in this directory
 	InsecureSkipVerify    bool // Skip server certificate verification
 	DeleteMode            DeleteMode
 	MaxDelete             int64
 	TrackRenames          bool // Track file renames.
+	CopyByHash            bool // Server side copy files which already exist on the destination
 	LowLevelRetries       int
+	CorruptedRetries      int
+	DeleteAfterVerify     bool
 	UpdateOlder           bool // Skip files that are newer on the destination
 	NoGzip                bool // Disable compression
 	MaxDepth              int
 	IgnoreSize            bool
 	IgnoreChecksum        bool
 	NoUpdateModTime       bool
+	NoUpdateDirModTime    bool
+	RefreshTimes          bool // set the mod times of files which are the same but have different times
 	DataRateUnit          string
 	BackupDir             string
 	Suffix                string
+	SuffixKeepExtension   bool
 	UseListR              bool
 	BufferSize            SizeSuffix
 	BwLimit               BwTimetable
@@ -64,8 +76,22 @@ type ConfigInfo struct {
 	Immutable             bool
 	AutoConfirm           bool
 	StreamingUploadCutoff SizeSuffix
+	StreamingBufferSize   SizeSuffix // buffer transfers between remotes in memory rather than on disk
 	StatsFileNameLength   int
 	AskPassword           bool
+	Metadata              bool // Copy object metadata where possible
+	MaxTransfer           SizeSuffix
+	CutoffMode            CutoffMode
+	AdaptiveConcurrency   bool // Reduce the connections to a backend when rate limited
+	Headers               []*HTTPOption
+	UploadHeaders         []*HTTPOption
+	DownloadHeaders       []*HTTPOption
+	UseCookies            bool
+	Proxy                 string
+	DstListCache          string        // database of destination listings from previous syncs
+	DstListCacheMaxAge    time.Duration // re-list directories cached longer than this
+	JobStateFile          string        // journal of files finished by an interrupted copy or sync
+	ListBuffer            int           // max entries to read ahead when streaming listings
 }
 
 // NewConfig creates a new config with everything set to the default
@@ -85,6 +111,9 @@ func NewConfig() *ConfigInfo {
 	c.DeleteMode = DeleteModeDefault
 	c.MaxDelete = -1
 	c.LowLevelRetries = 10
+	c.CorruptedRetries = 2
+	c.DstListCacheMaxAge = 7 * 24 * time.Hour
+	c.ListBuffer = 10000
 	c.MaxDepth = -1
 	c.DataRateUnit = "bytes"
 	c.BufferSize = SizeSuffix(16 << 20)
@@ -93,6 +122,23 @@ func NewConfig() *ConfigInfo {
 	c.StatsFileNameLength = 40
 	c.AskPassword = true
 	c.TPSLimitBurst = 1
+	c.MaxTransfer = -1
+	c.CutoffMode = CutoffModeDefault
 
 	return c
 }
+
+// ConfigForRemote returns the config the remote called name should
+// use for its HTTP connections.
+//
+// This is Config unless the remote has a "proxy" set in the config
+// file, in which case it is a copy of Config using that proxy.
+func ConfigForRemote(name string) *ConfigInfo {
+	proxy := ConfigFileGet(name, "proxy")
+	if proxy == "" {
+		return Config
+	}
+	ci := *Config
+	ci.Proxy = proxy
+	return &ci
+}
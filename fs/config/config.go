@@ -61,6 +61,10 @@ var (
 	// configData is the config file data structure
 	configData *goconfig.ConfigFile
 
+	// configTrailer is any comments after the last key in the
+	// config file which goconfig would otherwise drop on save
+	configTrailer string
+
 	// ConfigPath points to the config file
 	ConfigPath = makeConfigPath()
 
@@ -160,6 +164,10 @@ func LoadConfig() {
 	} else {
 		fs.Debugf(nil, "Using config file from %q", ConfigPath)
 	}
+	if err = loadIncludes(); err != nil {
+		log.Fatalf("Failed to load config file %q: %v", ConfigPath, err)
+	}
+	validateConfig()
 
 	// Start the token bucket limiter
 	accounting.StartTokenBucket()
@@ -190,7 +198,7 @@ func loadConfigFile() (*goconfig.ConfigFile, error) {
 		line, _, err := r.ReadLine()
 		if err != nil {
 			if err == io.EOF {
-				return goconfig.LoadFromReader(bytes.NewBuffer(b))
+				return parseConfig(b)
 			}
 			return nil, err
 		}
@@ -205,7 +213,7 @@ func loadConfigFile() (*goconfig.ConfigFile, error) {
 		if strings.HasPrefix(l, "RCLONE_ENCRYPT_V") {
 			return nil, errors.New("unsupported configuration encryption - update rclone for support")
 		}
-		return goconfig.LoadFromReader(bytes.NewBuffer(b))
+		return parseConfig(b)
 	}
 
 	// Encrypted content is base64 encoded.
@@ -254,7 +262,32 @@ func loadConfigFile() (*goconfig.ConfigFile, error) {
 		configKey = nil
 		envpw = ""
 	}
-	return goconfig.LoadFromReader(bytes.NewBuffer(out))
+	return parseConfig(out)
+}
+
+// parseConfig parses the decrypted config file data, remembering any
+// trailing comments so SaveConfig can put them back.
+func parseConfig(b []byte) (*goconfig.ConfigFile, error) {
+	configTrailer = trailingComments(b)
+	return goconfig.LoadFromReader(bytes.NewBuffer(b))
+}
+
+// trailingComments returns the comment lines after the last key or
+// section in the config file data.
+func trailingComments(b []byte) string {
+	lines := strings.Split(strings.Replace(string(b), "\r\n", "\n", -1), "\n")
+	var comments []string
+	for _, line := range lines {
+		line = strings.TrimSpace(line)
+		switch {
+		case line == "":
+		case line[0] == '#' || line[0] == ';':
+			comments = append(comments, line)
+		default:
+			comments = nil
+		}
+	}
+	return strings.Join(comments, goconfig.LineBreak)
 }
 
 // checkPassword normalises and validates the password
@@ -368,6 +401,9 @@ func SaveConfig() {
 	if err != nil {
 		log.Fatalf("Failed to save config file: %v", err)
 	}
+	if configTrailer != "" {
+		buf.WriteString(configTrailer + goconfig.LineBreak)
+	}
 
 	if len(configKey) == 0 {
 		if _, err := buf.WriteTo(f); err != nil {
@@ -463,7 +499,7 @@ func SetValueAndSave(name, key, value string) (err error) {
 
 // ShowRemotes shows an overview of the config file
 func ShowRemotes() {
-	remotes := configData.GetSectionList()
+	remotes := fileSections()
 	if len(remotes) == 0 {
 		return
 	}
@@ -477,7 +513,7 @@ func ShowRemotes() {
 
 // ChooseRemote chooses a remote name
 func ChooseRemote() string {
-	remotes := configData.GetSectionList()
+	remotes := fileSections()
 	sort.Strings(remotes)
 	return Choose("remote", remotes, nil, false)
 }
@@ -604,7 +640,7 @@ func ShowRemote(name string) {
 	fmt.Printf("--------------------\n")
 	fmt.Printf("[%s]\n", name)
 	fs := MustFindByName(name)
-	for _, key := range configData.GetKeyList(name) {
+	for _, key := range sectionData(name).GetKeyList(name) {
 		isPassword := false
 		for _, option := range fs.Options {
 			if option.Name == key && option.IsPassword {
@@ -612,8 +648,8 @@ func ShowRemote(name string) {
 				break
 			}
 		}
-		value := FileGet(name, key)
-		if isPassword && value != "" {
+		value := fileGetRaw(name, key)
+		if isPassword && value != "" && !isSecretRef(value) {
 			fmt.Printf("%s = *** ENCRYPTED ***\n", key)
 		} else {
 			fmt.Printf("%s = %s\n", key, value)
@@ -859,8 +895,9 @@ func DeleteRemote(name string) {
 func copyRemote(name string) string {
 	newName := NewRemoteName()
 	// Copy the keys
-	for _, key := range configData.GetKeyList(name) {
-		value := configData.MustValue(name, key, "")
+	data := sectionData(name)
+	for _, key := range data.GetKeyList(name) {
+		value := data.MustValue(name, key, "")
 		configData.SetValue(newName, key, value)
 	}
 	return newName
@@ -909,7 +946,7 @@ func ShowConfig() {
 // EditConfig edits the config file interactively
 func EditConfig() {
 	for {
-		haveRemotes := len(configData.GetSectionList()) != 0
+		haveRemotes := len(fileSections()) != 0
 		what := []string{"eEdit existing remote", "nNew remote", "dDelete remote", "rRename remote", "cCopy remote", "sSet configuration password", "qQuit config"}
 		if haveRemotes {
 			fmt.Printf("Current remotes:\n\n")
@@ -1023,14 +1060,45 @@ func configToEnv(section, name string) string {
 // FileGet gets the config key under section returning the
 // default or empty string if not set.
 //
-// It looks up defaults in the environment if they are present
+// It looks up defaults in the environment if they are present.
+//
+// If key is a password option of the remote's backend and its value
+// is a reference to an external secret (env:VAR, file:/path or
+// exec:command) then the secret is returned obscured instead.  If
+// the secret can't be read the error is logged and the value is
+// empty - use FileGetErr to get the error.
 func FileGet(section, key string, defaultVal ...string) string {
+	value, err := FileGetErr(section, key, defaultVal...)
+	if err != nil {
+		fs.Errorf(nil, "%v", err)
+	}
+	return value
+}
+
+// FileGetErr gets the config key under section as FileGet does but
+// returns an error if the value refers to a secret which can't be
+// read.
+func FileGetErr(section, key string, defaultVal ...string) (string, error) {
+	value := fileGetRaw(section, key, defaultVal...)
+	if !isSecretRef(value) || !isPasswordOption(section, key) {
+		return value, nil
+	}
+	secret, err := resolveSecret(value)
+	if err != nil {
+		return "", errors.Wrapf(err, "failed to read secret for %q in remote %q", key, section)
+	}
+	return obscure.MustObscure(secret), nil
+}
+
+// fileGetRaw gets the config key under section as FileGet does but
+// without resolving secret references.
+func fileGetRaw(section, key string, defaultVal ...string) string {
 	envKey := configToEnv(section, key)
 	newValue, found := os.LookupEnv(envKey)
 	if found {
 		defaultVal = []string{newValue}
 	}
-	return configData.MustValue(section, key, defaultVal...)
+	return sectionData(section).MustValue(section, key, defaultVal...)
 }
 
 // FileGetBool gets the config key under section returning the
@@ -1048,7 +1116,7 @@ func FileGetBool(section, key string, defaultVal ...bool) bool {
 			defaultVal = []bool{newBool}
 		}
 	}
-	return configData.MustBool(section, key, defaultVal...)
+	return sectionData(section).MustBool(section, key, defaultVal...)
 }
 
 // FileGetInt gets the config key under section returning the
@@ -1066,12 +1134,13 @@ func FileGetInt(section, key string, defaultVal ...int) int {
 			defaultVal = []int{newInt}
 		}
 	}
-	return configData.MustInt(section, key, defaultVal...)
+	return sectionData(section).MustInt(section, key, defaultVal...)
 }
 
 // FileSet sets the key in section to value.  It doesn't save
 // the config file.
 func FileSet(section, key, value string) {
+	copyIncluded(section)
 	configData.SetValue(section, key, value)
 }
 
@@ -1079,31 +1148,64 @@ func FileSet(section, key, value string) {
 // It returns true if the key was deleted,
 // or returns false if the section or key didn't exist.
 func FileDeleteKey(section, key string) bool {
+	copyIncluded(section)
 	return configData.DeleteKey(section, key)
 }
 
 var matchEnv = regexp.MustCompile(`^RCLONE_CONFIG_(.*?)_TYPE=.*$`)
 
-// FileSections returns the sections in the config file
-// including any defined by environment variables.
+// FileSections returns the sections in the config file and any
+// included config files including any defined by environment
+// variables.
 func FileSections() []string {
-	sections := configData.GetSectionList()
+	sections := fileSections()
+	seen := make(map[string]struct{}, len(sections))
+	for _, section := range sections {
+		seen[section] = struct{}{}
+	}
 	for _, item := range os.Environ() {
 		matches := matchEnv.FindStringSubmatch(item)
 		if len(matches) == 2 {
-			sections = append(sections, strings.ToLower(matches[1]))
+			section := strings.ToLower(matches[1])
+			if _, found := seen[section]; !found {
+				seen[section] = struct{}{}
+				sections = append(sections, section)
+			}
 		}
 	}
 	return sections
 }
 
+// FileKeys returns the keys set in section of the config file
+// including any defined by environment variables.
+func FileKeys(section string) []string {
+	keys := sectionData(section).GetKeyList(section)
+	seen := make(map[string]struct{}, len(keys))
+	for _, key := range keys {
+		seen[key] = struct{}{}
+	}
+	prefix := configToEnv(section, "")
+	for _, item := range os.Environ() {
+		equals := strings.IndexRune(item, '=')
+		if equals < 0 || !strings.HasPrefix(item[:equals], prefix) {
+			continue
+		}
+		key := strings.ToLower(item[len(prefix):equals])
+		if _, found := seen[key]; !found && key != "" {
+			seen[key] = struct{}{}
+			keys = append(keys, key)
+		}
+	}
+	return keys
+}
+
 // Dump dumps all the config as a JSON file
 func Dump() error {
 	dump := make(map[string]map[string]string)
-	for _, name := range configData.GetSectionList() {
+	for _, name := range FileSections() {
 		params := make(map[string]string)
-		for _, key := range configData.GetKeyList(name) {
-			params[key] = FileGet(name, key)
+		for _, key := range FileKeys(name) {
+			params[key] = fileGetRaw(name, key)
 		}
 		dump[name] = params
 	}
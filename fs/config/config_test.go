@@ -1,10 +1,15 @@
 package config
 
 import (
+	"bytes"
 	"io/ioutil"
 	"os"
+	"path/filepath"
+	"runtime"
+	"sort"
 	"testing"
 
+	"github.com/Unknwon/goconfig"
 	"github.com/ncw/rclone/fs"
 	"github.com/ncw/rclone/fs/config/obscure"
 	"github.com/stretchr/testify/assert"
@@ -205,3 +210,204 @@ func hashedKeyCompare(t *testing.T, a, b string, shouldMatch bool) {
 		assert.NotEqual(t, k1, k2)
 	}
 }
+
+func TestFileSectionsAndKeysFromEnv(t *testing.T) {
+	oldConfigData := configData
+	defer func() {
+		configData = oldConfigData
+	}()
+	var err error
+	configData, err = goconfig.LoadFromReader(bytes.NewBufferString("[file]\ntype = local\nnounc = true\n"))
+	require.NoError(t, err)
+
+	for key, value := range map[string]string{
+		"RCLONE_CONFIG_FILE_TYPE":       "local",
+		"RCLONE_CONFIG_FILE_COPY_LINKS": "true",
+		"RCLONE_CONFIG_ENVREMOTE_TYPE":  "s3",
+		"RCLONE_CONFIG_ENVREMOTE_ACL":   "private",
+	} {
+		require.NoError(t, os.Setenv(key, value))
+		defer func(key string) {
+			_ = os.Unsetenv(key)
+		}(key)
+	}
+
+	assert.Equal(t, []string{"file", "envremote"}, FileSections())
+	keys := FileKeys("file")
+	sort.Strings(keys)
+	assert.Equal(t, []string{"copy_links", "nounc", "type"}, keys)
+	keys = FileKeys("envremote")
+	sort.Strings(keys)
+	assert.Equal(t, []string{"acl", "type"}, keys)
+	assert.Equal(t, "private", FileGet("envremote", "acl"))
+}
+
+func TestFileGetSecretRefs(t *testing.T) {
+	oldConfigData := configData
+	defer func() {
+		configData = oldConfigData
+	}()
+
+	secretFile, err := ioutil.TempFile("", "rclone-secret")
+	require.NoError(t, err)
+	defer func() {
+		require.NoError(t, os.Remove(secretFile.Name()))
+	}()
+	_, err = secretFile.WriteString("fromfile\n")
+	require.NoError(t, err)
+	require.NoError(t, secretFile.Close())
+
+	require.NoError(t, os.Setenv("RCLONE_TEST_SECRET", "fromenv"))
+	defer func() {
+		_ = os.Unsetenv("RCLONE_TEST_SECRET")
+	}()
+
+	fs.Register(&fs.RegInfo{
+		Name: "config_test_secret",
+		Options: []fs.Option{{
+			Name:       "pass",
+			IsPassword: true,
+		}, {
+			Name:       "key",
+			IsPassword: true,
+		}, {
+			Name:       "missing",
+			IsPassword: true,
+		}},
+	})
+	configData, err = goconfig.LoadFromReader(bytes.NewBufferString(
+		"[remote]\ntype = config_test_secret\nuser = env:RCLONE_TEST_SECRET\nkey = file:" +
+			secretFile.Name() + "\npass = env:RCLONE_TEST_SECRET\nplain = value\nremote = file:path\n" +
+			"missing = env:RCLONE_TEST_SECRET_NOT_SET\n"))
+	require.NoError(t, err)
+
+	// Only password options are resolved
+	assert.Equal(t, "env:RCLONE_TEST_SECRET", FileGet("remote", "user"))
+	assert.Equal(t, "file:path", FileGet("remote", "remote"))
+	assert.Equal(t, "value", FileGet("remote", "plain"))
+	assert.Equal(t, "fromfile", obscure.MustReveal(FileGet("remote", "key")))
+	assert.Equal(t, "fromenv", obscure.MustReveal(FileGet("remote", "pass")))
+	assert.Equal(t, "env:RCLONE_TEST_SECRET", fileGetRaw("remote", "pass"))
+
+	// Secrets which can't be read give an error not a value
+	_, err = FileGetErr("remote", "missing")
+	assert.Error(t, err)
+	assert.Equal(t, "", FileGet("remote", "missing"))
+
+	if runtime.GOOS != "windows" {
+		secret, err := resolveSecret("exec:echo fromexec")
+		require.NoError(t, err)
+		assert.Equal(t, "fromexec", secret)
+	}
+
+	_, err = resolveSecret("env:RCLONE_TEST_SECRET_NOT_SET")
+	assert.Error(t, err)
+	_, err = resolveSecret("exec:")
+	assert.Error(t, err)
+}
+
+func TestConfigIncludes(t *testing.T) {
+	oldConfigPath := ConfigPath
+	oldConfigData := configData
+	oldIncludeData := includeData
+	defer func() {
+		ConfigPath = oldConfigPath
+		configData = oldConfigData
+		includeData = oldIncludeData
+	}()
+
+	dir, err := ioutil.TempDir("", "rclone-config-include")
+	require.NoError(t, err)
+	defer func() {
+		require.NoError(t, os.RemoveAll(dir))
+	}()
+	ConfigPath = filepath.Join(dir, "rclone.conf")
+	require.NoError(t, ioutil.WriteFile(ConfigPath, []byte("include = team.conf\n\n[mine]\ntype = local\n\n[shared]\ntype = local\nnounc = main\n"), 0600))
+	require.NoError(t, ioutil.WriteFile(filepath.Join(dir, "team.conf"), []byte("[shared]\ntype = local\nnounc = team\n\n[team]\ntype = local\nnounc = team\n"), 0600))
+
+	configData, err = loadConfigFile()
+	require.NoError(t, err)
+	require.NoError(t, loadIncludes())
+
+	assert.Equal(t, []string{"mine", "shared", "team"}, fileSections())
+	assert.Equal(t, "main", FileGet("shared", "nounc"))
+	assert.Equal(t, "team", FileGet("team", "nounc"))
+	assert.Equal(t, []string{"type", "nounc"}, FileKeys("team"))
+
+	// Changing an included remote copies it into the main config
+	FileSet("team", "copy_links", "true")
+	assert.Equal(t, "team", configData.MustValue("team", "nounc"))
+	assert.Equal(t, "true", configData.MustValue("team", "copy_links"))
+	assert.Equal(t, "", includeData.MustValue("team", "copy_links"))
+
+	// Missing include files are an error
+	configData.SetValue(goconfig.DEFAULT_SECTION, includeKey, "missing.conf")
+	assert.Error(t, loadIncludes())
+}
+
+func TestValidateConfig(t *testing.T) {
+	oldConfigData := configData
+	oldIncludeData := includeData
+	defer func() {
+		configData = oldConfigData
+		includeData = oldIncludeData
+	}()
+	includeData = nil
+
+	fs.Register(&fs.RegInfo{
+		Name: "config_test_validate",
+		Options: []fs.Option{{
+			Name: "chunk_size",
+		}, {
+			Name: "endpoint",
+		}},
+	})
+	fs.Register(&fs.RegInfo{
+		Name:    "config_test_validate_any",
+		AnyKeys: true,
+	})
+	var err error
+	configData, err = goconfig.LoadFromReader(bytes.NewBufferString(
+		"[good]\ntype = config_test_validate\nchunk_size = 1M\ntoken = {}\n\n" +
+			"[typo]\ntype = config_test_validate\nendpiont = x\npotato = y\n\n" +
+			"[notype]\nendpoint = x\n\n" +
+			"[badtype]\ntype = config_test_validate_missing\n\n" +
+			"[anykeys]\ntype = config_test_validate_any\nplugin_setting = x\n"))
+	require.NoError(t, err)
+
+	assert.Equal(t, 4, validateConfig())
+	assert.Equal(t, "endpoint", similarOption("endpiont", []fs.Option{{Name: "chunk_size"}, {Name: "endpoint"}}))
+	assert.Equal(t, "chunk_size", similarOption("chunk-size", []fs.Option{{Name: "chunk_size"}}))
+	assert.Equal(t, "", similarOption("potato", []fs.Option{{Name: "endpoint"}}))
+}
+
+func TestValidateConfigBackendKeys(t *testing.T) {
+	oldConfigData := configData
+	oldIncludeData := includeData
+	defer func() {
+		configData = oldConfigData
+		includeData = oldIncludeData
+	}()
+	includeData = nil
+
+	// Stand ins for the backends which save keys to the config
+	// which aren't options
+	fs.Register(&fs.RegInfo{
+		Name: "amazon cloud drive",
+	})
+	fs.Register(&fs.RegInfo{
+		Name: "crypt",
+	})
+	var err error
+	configData, err = goconfig.LoadFromReader(bytes.NewBufferString(
+		"[acd]\ntype = amazon cloud drive\ncheckpoint = xyz\n\n" +
+			"[secret]\ntype = crypt\nrekey_password = x\nrekey_password2 = y\n"))
+	require.NoError(t, err)
+
+	assert.Equal(t, 0, validateConfig())
+}
+
+func TestTrailingComments(t *testing.T) {
+	assert.Equal(t, "", trailingComments([]byte("[remote]\ntype = local\n")))
+	assert.Equal(t, "# one"+goconfig.LineBreak+"; two", trailingComments([]byte("# section\n[remote]\n# key\ntype = local\n\n# one\n; two\n")))
+}
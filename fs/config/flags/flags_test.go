@@ -0,0 +1,45 @@
+package flags
+
+import (
+	"os"
+	"testing"
+
+	"github.com/spf13/pflag"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSetDefaultsFromEnv(t *testing.T) {
+	require.NoError(t, os.Setenv("RCLONE_FLAGS_TEST_STRING", "potato"))
+	require.NoError(t, os.Setenv("RCLONE_FLAGS_TEST_ARRAY", "a"))
+	defer func() {
+		_ = os.Unsetenv("RCLONE_FLAGS_TEST_STRING")
+		_ = os.Unsetenv("RCLONE_FLAGS_TEST_ARRAY")
+	}()
+
+	flagSet := pflag.NewFlagSet("test", pflag.ContinueOnError)
+
+	// Defined with this package on a flag set other than the global one
+	var s string
+	StringVarP(flagSet, &s, "flags-test-string", "", "default", "")
+	assert.Equal(t, "potato", s)
+
+	// Defined directly with pflag
+	var array []string
+	var n int
+	flagSet.StringArrayVarP(&array, "flags-test-array", "", nil, "")
+	flagSet.IntVarP(&n, "flags-test-int", "", 42, "")
+	assert.Equal(t, []string(nil), array)
+
+	// Setting them twice should only set them once
+	SetDefaultsFromEnv(flagSet)
+	SetDefaultsFromEnv(flagSet)
+	assert.Equal(t, "potato", s)
+	assert.Equal(t, []string{"a"}, array)
+	assert.Equal(t, 42, n)
+	assert.Equal(t, "potato", flagSet.Lookup("flags-test-string").DefValue)
+
+	// The command line overrides the environment
+	require.NoError(t, flagSet.Parse([]string{"--flags-test-string", "sausage"}))
+	assert.Equal(t, "sausage", s)
+}
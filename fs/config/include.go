@@ -0,0 +1,120 @@
+// Read remotes from config files included by the main config file
+
+package config
+
+import (
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+
+	"github.com/Unknwon/goconfig"
+	"github.com/ncw/rclone/fs"
+	"github.com/pkg/errors"
+)
+
+// includeKey is the key at the top of the config file, before any
+// remotes, listing the other config files to read remotes from.
+const includeKey = "include"
+
+// includeData holds the remotes read from the included config
+// files.  It is never saved - remotes in the main config file
+// override those in the included files.
+var includeData *goconfig.ConfigFile
+
+// loadIncludes reads the config files listed by the include key of
+// the main config file into includeData.
+func loadIncludes() error {
+	includeData, _ = goconfig.LoadFromReader(strings.NewReader(""))
+	include := configData.MustValue(goconfig.DEFAULT_SECTION, includeKey, "")
+	for _, name := range strings.Split(include, ",") {
+		name = strings.TrimSpace(name)
+		if name == "" {
+			continue
+		}
+		if !filepath.IsAbs(name) {
+			name = filepath.Join(filepath.Dir(ConfigPath), name)
+		}
+		b, err := ioutil.ReadFile(name)
+		if err != nil {
+			return errors.Wrap(err, "failed to read included config file")
+		}
+		if strings.Contains(string(b), "RCLONE_ENCRYPT_V") {
+			return errors.Errorf("included config file %q can't be encrypted", name)
+		}
+		included, err := goconfig.LoadFromReader(strings.NewReader(string(b)))
+		if err != nil {
+			return errors.Wrapf(err, "failed to parse included config file %q", name)
+		}
+		for _, section := range included.GetSectionList() {
+			if section == goconfig.DEFAULT_SECTION {
+				fs.Logf(nil, "Ignoring top level keys in included config file %q - includes can't be nested", name)
+				continue
+			}
+			if hasSection(includeData, section) {
+				fs.Logf(nil, "Remote %q in included config file %q is already defined - ignoring", section, name)
+				continue
+			}
+			copySection(includeData, included, section)
+		}
+		fs.Debugf(nil, "Included config file %q", name)
+	}
+	return nil
+}
+
+// hasSection returns true if section is defined in data
+func hasSection(data *goconfig.ConfigFile, section string) bool {
+	_, err := data.GetSection(section)
+	return err == nil
+}
+
+// sectionData returns the config holding section - the main config
+// unless the section is only in an included config file.
+func sectionData(section string) *goconfig.ConfigFile {
+	if includeData != nil && !hasSection(configData, section) && hasSection(includeData, section) {
+		return includeData
+	}
+	return configData
+}
+
+// copyIncluded copies section from the included config files into
+// the main config so it can be changed.  The changed remote is saved
+// in the main config file which then overrides the included one.
+func copyIncluded(section string) {
+	data := sectionData(section)
+	if data == configData {
+		return
+	}
+	fs.Logf(nil, "Copying remote %q from an included config file into %q so it can be changed", section, ConfigPath)
+	copySection(configData, data, section)
+}
+
+// copySection copies section from src to dst
+func copySection(dst, src *goconfig.ConfigFile, section string) {
+	// Add the blank key goconfig uses to mark the start of a
+	// section as it does when reading a file, otherwise
+	// GetKeyList misses the first key
+	dst.SetValue(section, " ", " ")
+	for _, key := range src.GetKeyList(section) {
+		dst.SetValue(section, key, src.MustValue(section, key))
+	}
+}
+
+// fileSections returns the remotes in the config file and the
+// included config files.
+func fileSections() []string {
+	var sections []string
+	seen := make(map[string]struct{})
+	for _, data := range []*goconfig.ConfigFile{configData, includeData} {
+		if data == nil {
+			continue
+		}
+		for _, section := range data.GetSectionList() {
+			if _, found := seen[section]; found || section == goconfig.DEFAULT_SECTION {
+				continue
+			}
+			seen[section] = struct{}{}
+			sections = append(sections, section)
+		}
+	}
+	return sections
+}
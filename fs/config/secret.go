@@ -0,0 +1,104 @@
+// Secret references in the password values of the config file
+
+package config
+
+import (
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"strings"
+	"sync"
+
+	"github.com/ncw/rclone/fs"
+	"github.com/pkg/errors"
+)
+
+// Prefixes of password config values which refer to a secret stored
+// outside the config file rather than being the value itself.
+const (
+	secretEnvPrefix  = "env:"
+	secretFilePrefix = "file:"
+	secretExecPrefix = "exec:"
+)
+
+// secrets caches resolved secret references so each file is read
+// and each command is run at most once.
+var secrets = struct {
+	mu     sync.Mutex
+	values map[string]string
+}{
+	values: make(map[string]string),
+}
+
+// isSecretRef returns true if value is a reference to an external
+// secret.
+func isSecretRef(value string) bool {
+	return strings.HasPrefix(value, secretEnvPrefix) ||
+		strings.HasPrefix(value, secretFilePrefix) ||
+		strings.HasPrefix(value, secretExecPrefix)
+}
+
+// resolveSecret returns the secret that ref refers to.
+//
+//   - env:VAR reads the environment variable VAR
+//   - file:/path reads the file, removing any trailing newline
+//   - exec:command args runs the command (split on spaces, no shell)
+//     and uses its output, removing any trailing newline
+func resolveSecret(ref string) (string, error) {
+	secrets.mu.Lock()
+	defer secrets.mu.Unlock()
+	if value, ok := secrets.values[ref]; ok {
+		return value, nil
+	}
+	var value string
+	switch {
+	case strings.HasPrefix(ref, secretEnvPrefix):
+		name := ref[len(secretEnvPrefix):]
+		var found bool
+		value, found = os.LookupEnv(name)
+		if !found {
+			return "", errors.Errorf("environment variable %q is not set", name)
+		}
+	case strings.HasPrefix(ref, secretFilePrefix):
+		path := ref[len(secretFilePrefix):]
+		data, err := ioutil.ReadFile(path)
+		if err != nil {
+			return "", errors.Wrap(err, "failed to read secret file")
+		}
+		value = strings.TrimRight(string(data), "\r\n")
+	case strings.HasPrefix(ref, secretExecPrefix):
+		args := strings.Fields(ref[len(secretExecPrefix):])
+		if len(args) == 0 {
+			return "", errors.New("no command supplied")
+		}
+		cmd := exec.Command(args[0], args[1:]...)
+		cmd.Stderr = os.Stderr
+		out, err := cmd.Output()
+		if err != nil {
+			return "", errors.Wrapf(err, "failed to run %q", args[0])
+		}
+		value = strings.TrimRight(string(out), "\r\n")
+	default:
+		return "", errors.Errorf("%q is not a secret reference", ref)
+	}
+	secrets.values[ref] = value
+	return value, nil
+}
+
+// isPasswordOption returns true if key is an option marked
+// IsPassword by the backend of section.
+func isPasswordOption(section, key string) bool {
+	if key == "type" {
+		return false
+	}
+	regInfo, err := fs.Find(fileGetRaw(section, "type"))
+	if err != nil {
+		return false
+	}
+	for _, option := range regInfo.Options {
+		if option.Name == key {
+			return option.IsPassword
+		}
+	}
+	return false
+}
@@ -0,0 +1,133 @@
+// Check the remotes in the config file against their backends
+
+package config
+
+import (
+	"strings"
+
+	"github.com/ncw/rclone/fs"
+)
+
+// genericKeys are the keys which may be set in any remote as well as
+// the options of its backend.
+var genericKeys = map[string]struct{}{
+	"type":             {},
+	ConfigToken:        {},
+	ConfigClientID:     {},
+	ConfigClientSecret: {},
+	ConfigAuthURL:      {},
+	ConfigTokenURL:     {},
+	ConfigAutomatic:    {},
+	"proxy":            {},
+	"bwlimit":          {},
+}
+
+// backendKeys are the keys which backends read or write as well as
+// their options, eg old names for options and values saved by the
+// config process.
+var backendKeys = map[string][]string{
+	"amazon cloud drive": {"checkpoint"},
+	"cache":              {"plex_token"},
+	"crypt":              {"rekey_password", "rekey_password2"},
+	"drive":              {"team_drive"},
+	"ftp":                {"url", "username", "password"},
+	"jottacloud":         {"user"},
+	"onedrive":           {"resource_url"},
+	"s3":                 {"session_token"},
+	"sftp":               {"set_modtime"},
+}
+
+// validateConfig logs a warning for each remote with a missing or
+// unknown type and for each key which isn't an option of the
+// remote's backend, which is usually a typo.  Keys aren't checked
+// for backends which set AnyKeys.
+//
+// It returns the number of problems found.
+func validateConfig() (problems int) {
+	for _, section := range fileSections() {
+		data := sectionData(section)
+		backend := data.MustValue(section, "type", "")
+		if backend == "" {
+			fs.Logf(nil, "Remote %q in config file has no type", section)
+			problems++
+			continue
+		}
+		regInfo, err := fs.Find(backend)
+		if err != nil {
+			fs.Logf(nil, "Remote %q in config file has unknown type %q", section, backend)
+			problems++
+			continue
+		}
+		if regInfo.AnyKeys {
+			continue
+		}
+		options := make(map[string]struct{}, len(regInfo.Options))
+		for _, option := range regInfo.Options {
+			options[option.Name] = struct{}{}
+		}
+		for _, key := range backendKeys[regInfo.Name] {
+			options[key] = struct{}{}
+		}
+		for _, key := range data.GetKeyList(section) {
+			if _, found := genericKeys[key]; found {
+				continue
+			}
+			if _, found := options[key]; found {
+				continue
+			}
+			if suggestion := similarOption(key, regInfo.Options); suggestion != "" {
+				fs.Logf(nil, "Unknown key %q in remote %q of type %q - did you mean %q?", key, section, backend, suggestion)
+			} else {
+				fs.Logf(nil, "Unknown key %q in remote %q of type %q", key, section, backend)
+			}
+			problems++
+		}
+	}
+	return problems
+}
+
+// similarOption returns the name of an option which key is probably
+// a misspelling of, or "" if there isn't one.
+func similarOption(key string, options []fs.Option) string {
+	normalise := func(s string) string {
+		return strings.Replace(strings.ToLower(s), "-", "_", -1)
+	}
+	for _, option := range options {
+		if normalise(key) == normalise(option.Name) || (len(key) > 4 && editDistance(key, option.Name) <= 2) {
+			return option.Name
+		}
+	}
+	return ""
+}
+
+// editDistance returns the Levenshtein distance between a and b
+func editDistance(a, b string) int {
+	prev := make([]int, len(b)+1)
+	cur := make([]int, len(b)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(a); i++ {
+		cur[0] = i
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			cur[j] = min3(prev[j]+1, cur[j-1]+1, prev[j-1]+cost)
+		}
+		prev, cur = cur, prev
+	}
+	return prev[len(b)]
+}
+
+// min3 returns the smallest of a, b and c
+func min3(a, b, c int) int {
+	if b < a {
+		a = b
+	}
+	if c < a {
+		a = c
+	}
+	return a
+}
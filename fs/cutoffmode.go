@@ -0,0 +1,49 @@
+package fs
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// CutoffMode describes what happens when --max-transfer is reached
+type CutoffMode byte
+
+// CutoffMode constants
+const (
+	CutoffModeHard     CutoffMode = iota // stop all transfers immediately
+	CutoffModeSoft                       // finish the transfers in progress but start no more
+	CutoffModeCautious                   // don't start transfers which would exceed the limit
+	CutoffModeDefault  = CutoffModeHard
+)
+
+var cutoffModeNames = []string{
+	CutoffModeHard:     "HARD",
+	CutoffModeSoft:     "SOFT",
+	CutoffModeCautious: "CAUTIOUS",
+}
+
+// String turns a CutoffMode into a string
+func (m CutoffMode) String() string {
+	if int(m) >= len(cutoffModeNames) {
+		return fmt.Sprintf("CutoffMode(%d)", m)
+	}
+	return cutoffModeNames[m]
+}
+
+// Set a CutoffMode
+func (m *CutoffMode) Set(s string) error {
+	for n, name := range cutoffModeNames {
+		if strings.EqualFold(s, name) {
+			*m = CutoffMode(n)
+			return nil
+		}
+	}
+	return errors.Errorf("Unknown cutoff mode %q", s)
+}
+
+// Type of the value
+func (m *CutoffMode) Type() string {
+	return "string"
+}
@@ -0,0 +1,45 @@
+package fs
+
+import (
+	"testing"
+
+	"github.com/spf13/pflag"
+	"github.com/stretchr/testify/assert"
+)
+
+// Check it satisfies the interface
+var _ pflag.Value = (*CutoffMode)(nil)
+
+func TestCutoffModeString(t *testing.T) {
+	assert.Equal(t, "HARD", CutoffModeHard.String())
+	assert.Equal(t, "SOFT", CutoffModeSoft.String())
+	assert.Equal(t, "CAUTIOUS", CutoffModeCautious.String())
+	assert.Equal(t, "CutoffMode(17)", CutoffMode(17).String())
+}
+
+func TestCutoffModeSet(t *testing.T) {
+	for _, test := range []struct {
+		in      string
+		want    CutoffMode
+		wantErr bool
+	}{
+		{"hard", CutoffModeHard, false},
+		{"SOFT", CutoffModeSoft, false},
+		{"Cautious", CutoffModeCautious, false},
+		{"potato", CutoffModeSoft, true},
+	} {
+		m := CutoffModeSoft
+		err := m.Set(test.in)
+		if test.wantErr {
+			assert.Error(t, err, test.in)
+		} else {
+			assert.NoError(t, err, test.in)
+		}
+		assert.Equal(t, test.want, m, test.in)
+	}
+}
+
+func TestCutoffModeType(t *testing.T) {
+	m := CutoffModeHard
+	assert.Equal(t, "string", m.Type())
+}
@@ -9,10 +9,12 @@ import (
 	"path"
 	"regexp"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/ncw/rclone/fs"
 	"github.com/pkg/errors"
+	"golang.org/x/net/context"
 )
 
 // Active is the globally active filter
@@ -277,16 +279,23 @@ func (f *Filter) Add(Include bool, glob string) error {
 //
 // '+' includes the glob, '-' excludes it and '!' resets the filter list
 //
+// The rsync long forms "include glob", "exclude glob" and "clear"
+// may be used instead.
+//
 // Line comments may be introduced with '#' or ';'
 func (f *Filter) AddRule(rule string) error {
 	switch {
-	case rule == "!":
+	case rule == "!", rule == "clear":
 		f.Clear()
 		return nil
 	case strings.HasPrefix(rule, "- "):
 		return f.Add(false, rule[2:])
 	case strings.HasPrefix(rule, "+ "):
 		return f.Add(true, rule[2:])
+	case strings.HasPrefix(rule, "exclude "):
+		return f.Add(false, rule[8:])
+	case strings.HasPrefix(rule, "include "):
+		return f.Add(true, rule[8:])
 	}
 	return errors.Errorf("malformed rule %q", rule)
 }
@@ -372,41 +381,78 @@ func (f *Filter) ListContainsExcludeFile(entries fs.DirEntries) bool {
 
 // IncludeDirectory returns a function which checks whether this
 // directory should be included in the sync or not.
-func (f *Filter) IncludeDirectory(fs fs.Fs) func(string) (bool, error) {
+func (f *Filter) IncludeDirectory(ctx context.Context, fs fs.Fs) func(string) (bool, error) {
 	return func(remote string) (bool, error) {
 		remote = strings.Trim(remote, "/")
 		// first check if we need to remove directory based on
 		// the exclude file
-		excl, err := f.DirContainsExcludeFile(fs, remote)
+		excl, err := f.DirContainsExcludeFile(ctx, fs, remote)
 		if err != nil {
 			return false, err
 		}
 		if excl {
 			return false, nil
 		}
+		return f.includeDirectoryRules(remote), nil
+	}
+}
 
-		// filesFrom takes precedence
-		if f.files != nil {
-			_, include := f.dirs[remote]
-			return include, nil
-		}
-		remote += "/"
-		for _, rule := range f.dirRules.rules {
-			if rule.Match(remote) {
-				return rule.Include, nil
-			}
+// includeDirectoryRules returns whether the directory remote is
+// included by the --files-from list or the directory rules
+func (f *Filter) includeDirectoryRules(remote string) bool {
+	// filesFrom takes precedence
+	if f.files != nil {
+		_, include := f.dirs[remote]
+		return include
+	}
+	remote += "/"
+	for _, rule := range f.dirRules.rules {
+		if rule.Match(remote) {
+			return rule.Include
 		}
+	}
+	return true
+}
 
-		return true, nil
+// IncludeDirectoryTree returns a function which checks whether a
+// directory and all its parents below root are included.
+//
+// Everything below an excluded directory can then be pruned from a
+// recursive listing in the same way it would be pruned from a
+// directory by directory traversal.  The results are cached so it
+// is cheap to call for every entry in the listing.
+//
+// This doesn't look for the --exclude-if-present file.
+func (f *Filter) IncludeDirectoryTree(root string) func(string) bool {
+	root = strings.Trim(root, "/")
+	var mu sync.Mutex
+	cache := make(map[string]bool)
+	var include func(dir string) bool
+	include = func(dir string) bool {
+		// root and anything outside it are always included
+		if dir == "." || dir == root || (root != "" && !strings.HasPrefix(dir+"/", root+"/")) {
+			return true
+		}
+		inc, found := cache[dir]
+		if !found {
+			inc = include(path.Dir(dir)) && f.includeDirectoryRules(dir)
+			cache[dir] = inc
+		}
+		return inc
+	}
+	return func(dir string) bool {
+		mu.Lock()
+		defer mu.Unlock()
+		return include(strings.Trim(dir, "/"))
 	}
 }
 
 // DirContainsExcludeFile checks if exclude file is present in a
 // directroy. If fs is nil, it works properly if ExcludeFile is an
 // empty string (for testing).
-func (f *Filter) DirContainsExcludeFile(fremote fs.Fs, remote string) (bool, error) {
+func (f *Filter) DirContainsExcludeFile(ctx context.Context, fremote fs.Fs, remote string) (bool, error) {
 	if len(f.Opt.ExcludeFile) > 0 {
-		exists, err := fs.FileExists(fremote, path.Join(remote, f.Opt.ExcludeFile))
+		exists, err := fs.FileExists(ctx, fremote, path.Join(remote, f.Opt.ExcludeFile))
 		if err != nil {
 			return false, err
 		}
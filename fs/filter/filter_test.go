@@ -11,6 +11,7 @@ import (
 	"github.com/ncw/rclone/fs"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
+	"golang.org/x/net/context"
 )
 
 func TestNewFilterDefault(t *testing.T) {
@@ -123,8 +124,9 @@ type includeDirTest struct {
 }
 
 func testDirInclude(t *testing.T, f *Filter, tests []includeDirTest) {
+	ctx := context.Background()
 	for _, test := range tests {
-		got, err := f.IncludeDirectory(nil)(test.in)
+		got, err := f.IncludeDirectory(ctx, nil)(test.in)
 		require.NoError(t, err)
 		assert.Equal(t, test.want, got, test.in)
 	}
@@ -300,6 +302,67 @@ func TestNewFilterMatches(t *testing.T) {
 	assert.False(t, f.InActive())
 }
 
+func TestNewFilterMatchesRsyncLongForms(t *testing.T) {
+	f, err := NewFilter(nil)
+	require.NoError(t, err)
+	add := func(s string) {
+		err := f.AddRule(s)
+		require.NoError(t, err)
+	}
+	add("include cleared")
+	add("clear")
+	add("exclude /tmp/")
+	add("include *.{{jpe?g}}")
+	add("exclude *")
+	testInclude(t, f, []includeTest{
+		{"cleared", 100, 0, false},
+		{"one.jpg", 100, 0, true},
+		{"one.jpeg", 100, 0, true},
+		{"one.jpeeg", 100, 0, false},
+		{"dir/one.jpg", 100, 0, true},
+		{"one.png", 100, 0, false},
+	})
+	testDirInclude(t, f, []includeDirTest{
+		{"tmp", false},
+		{"dir", true},
+		{"dir/tmp", true},
+	})
+	assert.Error(t, f.AddRule("exclude"))
+	assert.Error(t, f.AddRule("include"))
+}
+
+func TestFilterIncludeDirectoryTree(t *testing.T) {
+	f, err := NewFilter(nil)
+	require.NoError(t, err)
+	require.NoError(t, f.AddRule("- /a/b/"))
+	require.NoError(t, f.AddRule("- c/"))
+	for _, test := range []struct {
+		root string
+		dir  string
+		want bool
+	}{
+		{"", "", true},
+		{"", "a", true},
+		{"", "a/b", false},
+		{"", "a/b/d", false},
+		{"", "a/b/d/e", false},
+		{"", "a/bb", true},
+		{"", "x/c", false},
+		{"", "x/c/y", false},
+		{"", "x/cc/y", true},
+		{"a/b", "a/b", true},
+		{"a/b", "a/b/d", true},
+		{"a/b", "a/b/c/d", false},
+	} {
+		includeDirectoryTree := f.IncludeDirectoryTree(test.root)
+		got := includeDirectoryTree(test.dir)
+		assert.Equal(t, test.want, got, fmt.Sprintf("root=%q dir=%q", test.root, test.dir))
+		// second time from the cache
+		got = includeDirectoryTree(test.dir)
+		assert.Equal(t, test.want, got, fmt.Sprintf("root=%q dir=%q cached", test.root, test.dir))
+	}
+}
+
 func TestFilterAddDirRuleOrFileRule(t *testing.T) {
 	for _, test := range []struct {
 		included bool
@@ -39,7 +39,9 @@ func globToRegexp(glob string) (*regexp.Regexp, error) {
 	inBraces := false
 	inBrackets := 0
 	slashed := false
-	for _, c := range glob {
+	runes := []rune(glob)
+	for i := 0; i < len(runes); i++ {
+		c := runes[i]
 		if slashed {
 			_, _ = re.WriteRune(c)
 			slashed = false
@@ -75,6 +77,19 @@ func globToRegexp(glob string) (*regexp.Regexp, error) {
 		case ']':
 			return nil, errors.Errorf("mismatched ']' in glob %q", glob)
 		case '{':
+			if !inBraces && i+1 < len(runes) && runes[i+1] == '{' {
+				// {{regexp}} is copied into the regexp verbatim
+				end := strings.Index(string(runes[i+2:]), "}}")
+				if end < 0 {
+					return nil, errors.Errorf("mismatched '{{' and '}}' in glob %q", glob)
+				}
+				regexpPart := []rune(string(runes[i+2:])[:end])
+				_, _ = re.WriteRune('(')
+				_, _ = re.WriteString(string(regexpPart))
+				_, _ = re.WriteRune(')')
+				i += 2 + len(regexpPart) + 1
+				continue
+			}
 			if inBraces {
 				return nil, errors.Errorf("can't nest '{' '}' in glob %q", glob)
 			}
@@ -118,8 +133,8 @@ func globToRegexp(glob string) (*regexp.Regexp, error) {
 }
 
 var (
-	// Can't deal with / or ** in {}
-	tooHardRe = regexp.MustCompile(`{[^{}]*(\*\*|/)[^{}]*}`)
+	// Can't deal with / or ** in {} or with {{regexp}}
+	tooHardRe = regexp.MustCompile(`{[^{}]*(\*\*|/)[^{}]*}|{{`)
 
 	// Squash all /
 	squashSlash = regexp.MustCompile(`/{2,}`)
@@ -32,7 +32,12 @@ func TestGlobToRegexp(t *testing.T) {
 		{`***`, `(^|/)`, `too many stars`},
 		{`ab]c`, `(^|/)`, `mismatched ']'`},
 		{`ab[c`, `(^|/)`, `mismatched '[' and ']'`},
-		{`ab{{cd`, `(^|/)`, `can't nest`},
+		{`ab{c{d}e}`, `(^|/)`, `can't nest`},
+		{`ab{{cd`, `(^|/)`, `mismatched '{{' and '}}'`},
+		{`*.{{jpe?g}}`, `(^|/)[^/]*\.(jpe?g)$`, ``},
+		{`{{.*\.(jpg|png)}}`, `(^|/)(.*\.(jpg|png))$`, ``},
+		{`/dir{{[0-9]+}}/*`, `^dir([0-9]+)/[^/]*$`, ``},
+		{`a{{[}}`, `(^|/)`, `bad glob pattern`},
 		{`ab{}}cd`, `(^|/)`, `mismatched '{' and '}'`},
 		{`ab}c`, `(^|/)`, `mismatched '{' and '}'`},
 		{`ab{c`, `(^|/)`, `mismatched '{' and '}'`},
@@ -77,6 +82,7 @@ func TestGlobToDirGlobs(t *testing.T) {
 		{`a/b/*.{jpg,png,gif}`, []string{"a/b/", "a/"}},
 		{`/a/{jpg,png,gif}/*.{jpg,png,gif}`, []string{"/a/{jpg,png,gif}/", "/a/", "/"}},
 		{`a/{a,a*b,a**c}/d/`, []string{"/**"}},
+		{`a/{{b+}}/*.jpg`, []string{"/**"}},
 		{`/a/{a,a*b,a/c,d}/d/`, []string{"/**"}},
 		{`**`, []string{"**/"}},
 		{`a**`, []string{"a**/"}},
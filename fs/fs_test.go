@@ -5,11 +5,12 @@ import (
 	"testing"
 
 	"github.com/stretchr/testify/assert"
+	"golang.org/x/net/context"
 )
 
 func TestFeaturesDisable(t *testing.T) {
 	ft := new(Features)
-	ft.Copy = func(src Object, remote string) (Object, error) {
+	ft.Copy = func(ctx context.Context, src Object, remote string) (Object, error) {
 		return nil, nil
 	}
 	ft.CaseInsensitive = true
@@ -35,7 +36,7 @@ func TestFeaturesList(t *testing.T) {
 
 func TestFeaturesDisableList(t *testing.T) {
 	ft := new(Features)
-	ft.Copy = func(src Object, remote string) (Object, error) {
+	ft.Copy = func(ctx context.Context, src Object, remote string) (Object, error) {
 		return nil, nil
 	}
 	ft.CaseInsensitive = true
@@ -52,3 +53,28 @@ func TestFeaturesDisableList(t *testing.T) {
 	assert.False(t, ft.CaseInsensitive)
 	assert.False(t, ft.DuplicateFiles)
 }
+
+func TestFeaturesEnabled(t *testing.T) {
+	ft := new(Features)
+	ft.CaseInsensitive = true
+	ft.Purge = func(ctx context.Context) error { return nil }
+	enabled := ft.Enabled()
+
+	flag, ok := enabled["CaseInsensitive"]
+	assert.Equal(t, true, ok)
+	assert.Equal(t, true, flag, enabled)
+
+	flag, ok = enabled["Purge"]
+	assert.Equal(t, true, ok)
+	assert.Equal(t, true, flag, enabled)
+
+	flag, ok = enabled["DuplicateFiles"]
+	assert.Equal(t, true, ok)
+	assert.Equal(t, false, flag, enabled)
+
+	flag, ok = enabled["Copy"]
+	assert.Equal(t, true, ok)
+	assert.Equal(t, false, flag, enabled)
+
+	assert.Equal(t, len(ft.List()), len(enabled))
+}
@@ -166,6 +166,42 @@ func IsNoRetryError(err error) bool {
 	return false
 }
 
+// Class is the classification of an error by how it should be
+// handled at a high level
+type Class int
+
+// Error classes
+const (
+	ClassRetry   Class = iota // the operation may succeed if retried
+	ClassNoRetry              // the operation won't succeed if retried
+	ClassFatal                // the whole operation should be stopped
+)
+
+// String turns a Class into a string
+func (c Class) String() string {
+	switch c {
+	case ClassRetry:
+		return "retry"
+	case ClassNoRetry:
+		return "no-retry"
+	case ClassFatal:
+		return "fatal"
+	}
+	return fmt.Sprintf("Class(%d)", int(c))
+}
+
+// Classify returns the Class of err.  Errors which aren't fatal or
+// marked as not to be retried are retryable.
+func Classify(err error) Class {
+	switch {
+	case IsFatalError(err):
+		return ClassFatal
+	case IsNoRetryError(err):
+		return ClassNoRetry
+	}
+	return ClassRetry
+}
+
 // Cause is a souped up errors.Cause which can unwrap some standard
 // library errors too.  It returns true if any of the intermediate
 // errors had a Timeout() or Temporary() method which returned true.
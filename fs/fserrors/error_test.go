@@ -118,3 +118,25 @@ func TestShouldRetry(t *testing.T) {
 		assert.Equal(t, test.want, got, fmt.Sprintf("test #%d: %v", i, test.err))
 	}
 }
+
+func TestClassify(t *testing.T) {
+	for i, test := range []struct {
+		err  error
+		want Class
+	}{
+		{nil, ClassRetry},
+		{errors.New("potato"), ClassRetry},
+		{RetryErrorf("potato"), ClassRetry},
+		{NoRetryError(errors.New("potato")), ClassNoRetry},
+		{errors.Wrap(NoRetryError(errors.New("potato")), "wrapped"), ClassNoRetry},
+		{FatalError(errors.New("potato")), ClassFatal},
+		{errors.Wrap(FatalError(errors.New("potato")), "wrapped"), ClassFatal},
+	} {
+		got := Classify(test.err)
+		assert.Equal(t, test.want, got, fmt.Sprintf("test #%d: %v", i, test.err))
+	}
+	assert.Equal(t, "retry", ClassRetry.String())
+	assert.Equal(t, "no-retry", ClassNoRetry.String())
+	assert.Equal(t, "fatal", ClassFatal.String())
+	assert.Equal(t, "Class(99)", Class(99).String())
+}
@@ -4,15 +4,21 @@ package fshttp
 import (
 	"bytes"
 	"crypto/tls"
+	"fmt"
+	"io/ioutil"
 	"net"
 	"net/http"
+	"net/http/cookiejar"
 	"net/http/httputil"
+	"path/filepath"
 	"reflect"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/ncw/rclone/fs"
 	"golang.org/x/net/context" // switch to "context" when we stop supporting go1.6
+	"golang.org/x/net/publicsuffix"
 	"golang.org/x/time/rate"
 )
 
@@ -25,6 +31,8 @@ var (
 	transport   http.RoundTripper
 	noTransport sync.Once
 	tpsBucket   *rate.Limiter // for limiting number of http transactions per second
+	cookieJar   *cookiejar.Jar
+	noCookieJar sync.Once
 )
 
 // StartHTTPTokenBucket starts the token bucket if necessary
@@ -109,46 +117,75 @@ func setDefaults(a, b interface{}) {
 }
 
 // NewTransport returns an http.RoundTripper with the correct timeouts
+//
+// If ci.Proxy is set then the transport will use that proxy, otherwise
+// it uses the proxy from the environment.
 func NewTransport(ci *fs.ConfigInfo) http.RoundTripper {
+	if ci.Proxy != "" {
+		return newProxyTransport(ci)
+	}
 	noTransport.Do(func() {
-		// Start with a sensible set of defaults then override.
-		// This also means we get new stuff when it gets added to go
-		t := new(http.Transport)
-		setDefaults(t, http.DefaultTransport.(*http.Transport))
-		t.Proxy = http.ProxyFromEnvironment
-		t.MaxIdleConnsPerHost = 4 * (ci.Checkers + ci.Transfers + 1)
-		t.TLSHandshakeTimeout = ci.ConnectTimeout
-		t.ResponseHeaderTimeout = ci.Timeout
-		t.TLSClientConfig = &tls.Config{InsecureSkipVerify: ci.InsecureSkipVerify}
-		t.DisableCompression = ci.NoGzip
-		// Set in http_old.go initTransport
-		//   t.Dial
-		// Set in http_new.go initTransport
-		//   t.DialContext
-		//   t.IdelConnTimeout
-		//   t.ExpectContinueTimeout
-		initTransport(ci, t)
-		// Wrap that http.Transport in our own transport
-		transport = newTransport(ci, t)
+		transport = newTransport(ci, newHTTPTransport(ci))
 	})
 	return transport
 }
 
+// newHTTPTransport makes an http.Transport from the config
+func newHTTPTransport(ci *fs.ConfigInfo) *http.Transport {
+	// Start with a sensible set of defaults then override.
+	// This also means we get new stuff when it gets added to go
+	t := new(http.Transport)
+	setDefaults(t, http.DefaultTransport.(*http.Transport))
+	t.Proxy = http.ProxyFromEnvironment
+	t.MaxIdleConnsPerHost = 4 * (ci.Checkers + ci.Transfers + 1)
+	t.TLSHandshakeTimeout = ci.ConnectTimeout
+	t.ResponseHeaderTimeout = ci.Timeout
+	t.TLSClientConfig = &tls.Config{InsecureSkipVerify: ci.InsecureSkipVerify}
+	t.DisableCompression = ci.NoGzip
+	// Set in http_old.go initTransport
+	//   t.Dial
+	// Set in http_new.go initTransport
+	//   t.DialContext
+	//   t.IdelConnTimeout
+	//   t.ExpectContinueTimeout
+	initTransport(ci, t)
+	return t
+}
+
 // NewClient returns an http.Client with the correct timeouts
 func NewClient(ci *fs.ConfigInfo) *http.Client {
-	return &http.Client{
+	client := &http.Client{
 		Transport: NewTransport(ci),
 	}
+	if ci.UseCookies {
+		client.Jar = getCookieJar()
+	}
+	return client
+}
+
+// getCookieJar returns the cookie jar shared by all the clients
+func getCookieJar() *cookiejar.Jar {
+	noCookieJar.Do(func() {
+		var err error
+		cookieJar, err = cookiejar.New(&cookiejar.Options{PublicSuffixList: publicsuffix.List})
+		if err != nil {
+			fs.Errorf(nil, "Failed to make cookie jar: %v", err)
+		}
+	})
+	return cookieJar
 }
 
 // Transport is a our http Transport which wraps an http.Transport
 // * Sets the User Agent
+// * Sets the headers from --header
 // * Does logging
 type Transport struct {
 	*http.Transport
 	dump          fs.DumpFlags
+	dumpDir       string
 	filterRequest func(req *http.Request)
 	userAgent     string
+	headers       []*fs.HTTPOption
 }
 
 // newTransport wraps the http.Transport passed in and logs all
@@ -157,7 +194,9 @@ func newTransport(ci *fs.ConfigInfo, transport *http.Transport) *Transport {
 	return &Transport{
 		Transport: transport,
 		dump:      ci.Dump,
+		dumpDir:   ci.DumpDir,
 		userAgent: ci.UserAgent,
+		headers:   ci.Headers,
 	}
 }
 
@@ -203,39 +242,55 @@ func checkServerTime(req *http.Request, resp *http.Response) {
 	checkedHostMu.Unlock()
 }
 
-// cleanAuth gets rid of one authBuf header within the first 4k
+// cleanAuth gets rid of the authBuf headers within the first 4k
 func cleanAuth(buf, authBuf []byte) []byte {
 	// Find how much buffer to check
 	n := 4096
 	if len(buf) < n {
 		n = len(buf)
 	}
-	// See if there is an Authorization: header
-	i := bytes.Index(buf[:n], authBuf)
-	if i < 0 {
-		return buf
-	}
-	i += len(authBuf)
-	// Overwrite the next 4 chars with 'X'
-	for j := 0; i < len(buf) && j < 4; j++ {
-		if buf[i] == '\n' {
-			break
+	for start := 0; start < n; {
+		// See if there is an Authorization: header at the start of a line
+		i := bytes.Index(buf[start:n], authBuf)
+		if i < 0 {
+			return buf
 		}
-		buf[i] = 'X'
-		i++
-	}
-	// Snip out to the next '\n'
-	j := bytes.IndexByte(buf[i:], '\n')
-	if j < 0 {
-		return buf[:i]
+		i += start
+		if i > 0 && buf[i-1] != '\n' {
+			start = i + len(authBuf)
+			continue
+		}
+		i += len(authBuf)
+		// Overwrite the next 4 chars with 'X'
+		for j := 0; i < len(buf) && j < 4; j++ {
+			if buf[i] == '\n' {
+				break
+			}
+			buf[i] = 'X'
+			i++
+		}
+		// Snip out to the next '\n'
+		j := bytes.IndexByte(buf[i:], '\n')
+		if j < 0 {
+			return buf[:i]
+		}
+		removed := copy(buf[i:], buf[i+j:])
+		buf = buf[:i+removed]
+		if len(buf) < n {
+			n = len(buf)
+		}
+		start = i
 	}
-	n = copy(buf[i:], buf[i+j:])
-	return buf[:i+n]
+	return buf
 }
 
 var authBufs = [][]byte{
 	[]byte("Authorization: "),
+	[]byte("Proxy-Authorization: "),
 	[]byte("X-Auth-Token: "),
+	[]byte("X-Amz-Security-Token: "),
+	[]byte("Cookie: "),
+	[]byte("Set-Cookie: "),
 }
 
 // cleanAuths gets rid of all the possible Auth headers
@@ -246,6 +301,21 @@ func cleanAuths(buf []byte) []byte {
 	return buf
 }
 
+// dumpNumber is the number of the last transaction written to
+// --dump-dir
+var dumpNumber int64
+
+// writeDump writes buf to a file named after the transaction number
+// and kind in --dump-dir, returning the file name.
+func (t *Transport) writeDump(number int64, kind string, buf []byte) string {
+	name := filepath.Join(t.dumpDir, fmt.Sprintf("%06d-%s.txt", number, kind))
+	err := ioutil.WriteFile(name, buf, 0600)
+	if err != nil {
+		fs.Errorf(nil, "Failed to write HTTP %s dump: %v", kind, err)
+	}
+	return name
+}
+
 // RoundTrip implements the RoundTripper interface.
 func (t *Transport) RoundTrip(req *http.Request) (resp *http.Response, err error) {
 	// Get transactions per second token first if limiting
@@ -257,34 +327,55 @@ func (t *Transport) RoundTrip(req *http.Request) (resp *http.Response, err error
 	}
 	// Force user agent
 	req.Header.Set("User-Agent", t.userAgent)
+	// Add any headers the user asked for
+	for _, header := range t.headers {
+		req.Header.Set(header.Key, header.Value)
+	}
 	// Filter the request if required
 	if t.filterRequest != nil {
 		t.filterRequest(req)
 	}
+	dumping := t.dump&(fs.DumpHeaders|fs.DumpBodies|fs.DumpAuth|fs.DumpRequests|fs.DumpResponses) != 0
+	var number int64
+	if dumping && t.dumpDir != "" {
+		number = atomic.AddInt64(&dumpNumber, 1)
+	}
 	// Logf request
-	if t.dump&(fs.DumpHeaders|fs.DumpBodies|fs.DumpAuth|fs.DumpRequests|fs.DumpResponses) != 0 {
+	if dumping {
 		buf, _ := httputil.DumpRequestOut(req, t.dump&(fs.DumpBodies|fs.DumpRequests) != 0)
 		if t.dump&fs.DumpAuth == 0 {
 			buf = cleanAuths(buf)
 		}
-		fs.Debugf(nil, "%s", separatorReq)
-		fs.Debugf(nil, "%s (req %p)", "HTTP REQUEST", req)
-		fs.Debugf(nil, "%s", string(buf))
-		fs.Debugf(nil, "%s", separatorReq)
+		if t.dumpDir != "" {
+			fs.Debugf(nil, "HTTP REQUEST (req %p) written to %q", req, t.writeDump(number, "request", buf))
+		} else {
+			fs.Debugf(nil, "%s", separatorReq)
+			fs.Debugf(nil, "%s (req %p)", "HTTP REQUEST", req)
+			fs.Debugf(nil, "%s", string(buf))
+			fs.Debugf(nil, "%s", separatorReq)
+		}
 	}
 	// Do round trip
 	resp, err = t.Transport.RoundTrip(req)
 	// Logf response
-	if t.dump&(fs.DumpHeaders|fs.DumpBodies|fs.DumpAuth|fs.DumpRequests|fs.DumpResponses) != 0 {
-		fs.Debugf(nil, "%s", separatorResp)
-		fs.Debugf(nil, "%s (req %p)", "HTTP RESPONSE", req)
+	if dumping {
+		var buf []byte
 		if err != nil {
-			fs.Debugf(nil, "Error: %v", err)
+			buf = []byte(fmt.Sprintf("Error: %v", err))
+		} else {
+			buf, _ = httputil.DumpResponse(resp, t.dump&(fs.DumpBodies|fs.DumpResponses) != 0)
+			if t.dump&fs.DumpAuth == 0 {
+				buf = cleanAuths(buf)
+			}
+		}
+		if t.dumpDir != "" {
+			fs.Debugf(nil, "HTTP RESPONSE (req %p) written to %q", req, t.writeDump(number, "response", buf))
 		} else {
-			buf, _ := httputil.DumpResponse(resp, t.dump&(fs.DumpBodies|fs.DumpResponses) != 0)
+			fs.Debugf(nil, "%s", separatorResp)
+			fs.Debugf(nil, "%s (req %p)", "HTTP RESPONSE", req)
 			fs.Debugf(nil, "%s", string(buf))
+			fs.Debugf(nil, "%s", separatorResp)
 		}
-		fs.Debugf(nil, "%s", separatorResp)
 	}
 	if err == nil {
 		checkServerTime(req, resp)
@@ -27,3 +27,15 @@ func initTransport(ci *fs.ConfigInfo, t *http.Transport) {
 		return dialTimeout(network, addr, ci)
 	}
 }
+
+// setDial makes t connect using dial, applying the timeouts to the
+// connections it returns
+func setDial(ci *fs.ConfigInfo, t *http.Transport, dial func(network, addr string) (net.Conn, error)) {
+	t.Dial = func(network, addr string) (net.Conn, error) {
+		c, err := dial(network, addr)
+		if err != nil {
+			return c, err
+		}
+		return newTimeoutConn(c, ci.Timeout), nil
+	}
+}
@@ -0,0 +1,94 @@
+// Proxy configuration for the http Transport
+
+package fshttp
+
+import (
+	"net"
+	"net/http"
+	"net/url"
+	"sync"
+
+	"github.com/ncw/rclone/fs"
+	"github.com/pkg/errors"
+	"golang.org/x/net/proxy"
+)
+
+var (
+	proxyTransportsMu sync.Mutex
+	proxyTransports   = map[string]http.RoundTripper{} // transports indexed by proxy URL
+)
+
+// newProxyTransport returns the transport for ci.Proxy, making it if
+// necessary.
+//
+// If the proxy can't be parsed then the transport returned fails
+// every request rather than bypassing the proxy.
+func newProxyTransport(ci *fs.ConfigInfo) http.RoundTripper {
+	proxyTransportsMu.Lock()
+	defer proxyTransportsMu.Unlock()
+	if t, ok := proxyTransports[ci.Proxy]; ok {
+		return t
+	}
+	var rt http.RoundTripper
+	t := newHTTPTransport(ci)
+	err := setProxy(ci, t)
+	if err != nil {
+		fs.Errorf(nil, "%v", err)
+		rt = errorTransport{err: err}
+	} else {
+		rt = newTransport(ci, t)
+	}
+	proxyTransports[ci.Proxy] = rt
+	return rt
+}
+
+// setProxy configures t to connect through the proxy in ci.Proxy
+//
+// http and https proxies are supported along with socks5 proxies,
+// optionally with a user and password in the URL.
+func setProxy(ci *fs.ConfigInfo, t *http.Transport) error {
+	u, err := url.Parse(ci.Proxy)
+	if err != nil {
+		return errors.Wrapf(err, "failed to parse proxy %q", ci.Proxy)
+	}
+	if u.Host == "" {
+		return errors.Errorf("proxy %q must be a URL, eg socks5://host:1080", ci.Proxy)
+	}
+	switch u.Scheme {
+	case "http", "https":
+		t.Proxy = http.ProxyURL(u)
+	case "socks5", "socks5h":
+		var auth *proxy.Auth
+		if u.User != nil {
+			password, _ := u.User.Password()
+			auth = &proxy.Auth{
+				User:     u.User.Username(),
+				Password: password,
+			}
+		}
+		dialer, err := proxy.SOCKS5("tcp", u.Host, auth, NewDialer(ci))
+		if err != nil {
+			return errors.Wrapf(err, "failed to make socks5 proxy %q", u.Host)
+		}
+		t.Proxy = nil
+		setDial(ci, t, func(network, addr string) (net.Conn, error) {
+			return dialer.Dial(network, addr)
+		})
+	default:
+		return errors.Errorf("unsupported proxy scheme %q - use http, https or socks5", u.Scheme)
+	}
+	return nil
+}
+
+// errorTransport is an http.RoundTripper which always returns err
+type errorTransport struct {
+	err error
+}
+
+// RoundTrip implements the RoundTripper interface.
+func (t errorTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if req.Body != nil {
+		_ = req.Body.Close()
+	}
+	return nil, t.err
+}
@@ -88,15 +88,18 @@ func (h Type) String() string {
 }
 
 // Set a Type from a flag
+//
+// The names are matched case insensitively and sha1 and dropbox are
+// accepted as aliases for SHA-1 and DropboxHash.
 func (h *Type) Set(s string) error {
-	switch s {
-	case "None":
+	switch strings.ToLower(s) {
+	case "none":
 		*h = None
-	case "MD5":
+	case "md5":
 		*h = MD5
-	case "SHA-1":
+	case "sha-1", "sha1":
 		*h = SHA1
-	case "DropboxHash":
+	case "dropboxhash", "dropbox":
 		*h = Dropbox
 	default:
 		return errors.Errorf("Unknown hash type %q", s)
@@ -165,3 +165,23 @@ func TestHashStringer(t *testing.T) {
 	h = hash.None
 	assert.Equal(t, h.String(), "None")
 }
+
+func TestHashSetter(t *testing.T) {
+	var ht hash.Type
+	for _, test := range []struct {
+		in   string
+		want hash.Type
+	}{
+		{"None", hash.None},
+		{"MD5", hash.MD5},
+		{"md5", hash.MD5},
+		{"SHA-1", hash.SHA1},
+		{"sha1", hash.SHA1},
+		{"DropboxHash", hash.Dropbox},
+		{"dropbox", hash.Dropbox},
+	} {
+		require.NoError(t, ht.Set(test.in), test.in)
+		assert.Equal(t, test.want, ht, test.in)
+	}
+	assert.Error(t, ht.Set("potato"))
+}
@@ -0,0 +1,110 @@
+// Package jobstate keeps a journal of the files a copy or sync has
+// finished so an interrupted run can be restarted without checking
+// them again.
+
+// +build !plan9,go1.7
+
+package jobstate
+
+import (
+	"encoding/json"
+	"time"
+
+	bolt "github.com/coreos/bbolt"
+	"github.com/ncw/rclone/fs"
+	"github.com/pkg/errors"
+)
+
+// Journal records the source files a job has transferred or found
+// up to date in a database.
+//
+// Each job is identified by its source and destination so the same
+// database can be used for several jobs.
+type Journal struct {
+	db     *bolt.DB
+	bucket []byte
+}
+
+// record is a finished file as stored in the database
+type record struct {
+	Size    int64
+	ModTime time.Time
+}
+
+// Open opens or creates the journal database at dbPath for the job
+// copying fsrc to fdst.
+func Open(fsrc, fdst fs.Fs, dbPath string) (*Journal, error) {
+	db, err := bolt.Open(dbPath, 0600, &bolt.Options{Timeout: 1 * time.Second})
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to open job state file %q", dbPath)
+	}
+	j := &Journal{
+		db:     db,
+		bucket: []byte(fsrc.Name() + ":" + fsrc.Root() + " -> " + fdst.Name() + ":" + fdst.Root()),
+	}
+	err = db.Update(func(tx *bolt.Tx) error {
+		_, err := tx.CreateBucketIfNotExists(j.bucket)
+		return err
+	})
+	if err != nil {
+		_ = db.Close()
+		return nil, errors.Wrapf(err, "failed to open job state file %q", dbPath)
+	}
+	return j, nil
+}
+
+// Done records src as finished.
+//
+// It is written to the database straight away so it survives the
+// job being interrupted.
+func (j *Journal) Done(src fs.ObjectInfo) {
+	data, err := json.Marshal(record{
+		Size:    src.Size(),
+		ModTime: src.ModTime(),
+	})
+	if err == nil {
+		err = j.db.Batch(func(tx *bolt.Tx) error {
+			return tx.Bucket(j.bucket).Put([]byte(src.Remote()), data)
+		})
+	}
+	if err != nil {
+		fs.Errorf(src, "Failed to record in job state file: %v", err)
+	}
+}
+
+// IsDone returns true if src was finished by a previous run of the
+// job and hasn't changed since.
+func (j *Journal) IsDone(src fs.ObjectInfo) bool {
+	var data []byte
+	err := j.db.View(func(tx *bolt.Tx) error {
+		if v := tx.Bucket(j.bucket).Get([]byte(src.Remote())); v != nil {
+			data = append([]byte(nil), v...)
+		}
+		return nil
+	})
+	if err != nil || data == nil {
+		return false
+	}
+	var r record
+	err = json.Unmarshal(data, &r)
+	if err != nil {
+		fs.Debugf(src, "Ignoring corrupted job state entry: %v", err)
+		return false
+	}
+	return r.Size == src.Size() && r.ModTime.Equal(src.ModTime())
+}
+
+// Close closes the database.  If finished is set the job completed
+// so its journal is removed and the next run starts afresh.
+func (j *Journal) Close(finished bool) error {
+	if finished {
+		err := j.db.Update(func(tx *bolt.Tx) error {
+			return tx.DeleteBucket(j.bucket)
+		})
+		if err != nil {
+			_ = j.db.Close()
+			return errors.Wrap(err, "failed to remove finished job from job state file")
+		}
+	}
+	return j.db.Close()
+}
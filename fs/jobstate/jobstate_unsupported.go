@@ -0,0 +1,34 @@
+// Stub job state journal for platforms bbolt doesn't support so
+// fs/sync still builds there
+
+// +build plan9 !go1.7
+
+package jobstate
+
+import (
+	"github.com/ncw/rclone/fs"
+	"github.com/pkg/errors"
+)
+
+// Journal is unused as the job state file isn't supported on this
+// platform
+type Journal struct{}
+
+// Open returns an error as the job state file isn't supported on
+// this platform
+func Open(fsrc, fdst fs.Fs, dbPath string) (*Journal, error) {
+	return nil, errors.New("--job-state-file is not supported on this platform")
+}
+
+// Done does nothing
+func (j *Journal) Done(src fs.ObjectInfo) {}
+
+// IsDone returns false
+func (j *Journal) IsDone(src fs.ObjectInfo) bool {
+	return false
+}
+
+// Close does nothing
+func (j *Journal) Close(finished bool) error {
+	return nil
+}
@@ -8,6 +8,7 @@ import (
 	"github.com/ncw/rclone/fs"
 	"github.com/ncw/rclone/fs/filter"
 	"github.com/pkg/errors"
+	"golang.org/x/net/context"
 )
 
 // DirSorted reads Object and *Dir into entries for the given Fs.
@@ -18,9 +19,18 @@ import (
 // files and directories passing the filter will be added.
 //
 // Files will be returned in sorted order
-func DirSorted(f fs.Fs, includeAll bool, dir string) (entries fs.DirEntries, err error) {
+func DirSorted(ctx context.Context, f fs.Fs, includeAll bool, dir string) (entries fs.DirEntries, err error) {
+	return DirSortedFn(ctx, f, includeAll, dir, f.List)
+}
+
+// ListFn lists the directory dir returning unfiltered entries
+type ListFn func(ctx context.Context, dir string) (entries fs.DirEntries, err error)
+
+// DirSortedFn is like DirSorted but reads the unfiltered entries
+// using listFn rather than f.List
+func DirSortedFn(ctx context.Context, f fs.Fs, includeAll bool, dir string, listFn ListFn) (entries fs.DirEntries, err error) {
 	// Get unfiltered entries from the fs
-	entries, err = f.List(dir)
+	entries, err = listFn(ctx, dir)
 	if err != nil {
 		return nil, err
 	}
@@ -31,7 +41,7 @@ func DirSorted(f fs.Fs, includeAll bool, dir string) (entries fs.DirEntries, err
 		fs.Debugf(dir, "Excluded from sync (and deletion)")
 		return nil, nil
 	}
-	return filterAndSortDir(entries, includeAll, dir, filter.Active.IncludeObject, filter.Active.IncludeDirectory(f))
+	return filterAndSortDir(entries, includeAll, dir, filter.Active.IncludeObject, filter.Active.IncludeDirectory(ctx, f))
 }
 
 // filter (if required) and check the entries, then sort them
@@ -0,0 +1,353 @@
+// Package listcache caches directory listings of the destination of
+// a sync between runs so unchanged directories needn't be listed
+// again.
+
+// +build !plan9,go1.7
+
+package listcache
+
+import (
+	"encoding/json"
+	"io"
+	"path"
+	"sync"
+	"time"
+
+	bolt "github.com/coreos/bbolt"
+	"github.com/ncw/rclone/fs"
+	"github.com/ncw/rclone/fs/hash"
+	"github.com/pkg/errors"
+	"golang.org/x/net/context"
+)
+
+// Cache reads listings of the directories of an Fs from a database
+// falling back to listing the Fs.
+//
+// Listings read from the Fs are saved to the database when the Cache
+// is closed unless the directory was marked dirty.  The sync must
+// mark every directory it changes as dirty with Dirty.
+type Cache struct {
+	f      fs.Fs
+	db     *bolt.DB
+	bucket []byte
+	maxAge time.Duration
+
+	mu     sync.Mutex
+	listed map[string][]byte   // encoded listings read from the Fs this run
+	dirty  map[string]struct{} // directories changed this run
+}
+
+// dirRecord is a directory listing as stored in the database
+type dirRecord struct {
+	Time    time.Time     // when the directory was listed
+	Entries []entryRecord // the unfiltered entries
+}
+
+// entryRecord is an entry in a dirRecord
+type entryRecord struct {
+	Remote  string
+	Size    int64
+	ModTime time.Time
+	Dir     bool `json:",omitempty"`
+}
+
+// Open opens or creates the cache database at dbPath for listings of
+// f.  Listings older than maxAge aren't used - 0 means no limit.
+func Open(f fs.Fs, dbPath string, maxAge time.Duration) (*Cache, error) {
+	db, err := bolt.Open(dbPath, 0600, &bolt.Options{Timeout: 1 * time.Second})
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to open listing cache %q", dbPath)
+	}
+	c := &Cache{
+		f:      f,
+		db:     db,
+		bucket: []byte(f.Name() + ":" + f.Root()),
+		maxAge: maxAge,
+		listed: make(map[string][]byte),
+		dirty:  make(map[string]struct{}),
+	}
+	err = db.Update(func(tx *bolt.Tx) error {
+		_, err := tx.CreateBucketIfNotExists(c.bucket)
+		return err
+	})
+	if err != nil {
+		_ = db.Close()
+		return nil, errors.Wrapf(err, "failed to open listing cache %q", dbPath)
+	}
+	return c, nil
+}
+
+// dirKey returns the database key for dir - keys can't be empty so
+// they all start with "/"
+func dirKey(dir string) []byte {
+	return []byte("/" + dir)
+}
+
+// List returns the unfiltered entries of dir from the cache if
+// present and fresh, otherwise it lists the Fs.
+//
+// It can be used as a list.ListFn.
+func (c *Cache) List(ctx context.Context, dir string) (entries fs.DirEntries, err error) {
+	var data []byte
+	err = c.db.View(func(tx *bolt.Tx) error {
+		if v := tx.Bucket(c.bucket).Get(dirKey(dir)); v != nil {
+			data = append([]byte(nil), v...)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	if data != nil {
+		var record dirRecord
+		err = json.Unmarshal(data, &record)
+		if err != nil {
+			fs.Debugf(dir, "Ignoring corrupted listing cache entry: %v", err)
+		} else if c.maxAge > 0 && time.Since(record.Time) > c.maxAge {
+			fs.Debugf(dir, "Listing cache entry too old")
+		} else {
+			fs.Debugf(dir, "Using cached listing from %v", record.Time)
+			return c.decode(ctx, &record), nil
+		}
+	}
+	entries, err = c.f.List(ctx, dir)
+	if err != nil {
+		return nil, err
+	}
+	data, err = json.Marshal(encode(entries))
+	if err != nil {
+		return nil, err
+	}
+	c.mu.Lock()
+	c.listed[dir] = data
+	c.mu.Unlock()
+	return entries, nil
+}
+
+// encode makes a dirRecord from entries
+func encode(entries fs.DirEntries) *dirRecord {
+	record := &dirRecord{
+		Time:    time.Now(),
+		Entries: make([]entryRecord, 0, len(entries)),
+	}
+	for _, entry := range entries {
+		_, isDir := entry.(fs.Directory)
+		record.Entries = append(record.Entries, entryRecord{
+			Remote:  entry.Remote(),
+			Size:    entry.Size(),
+			ModTime: entry.ModTime(),
+			Dir:     isDir,
+		})
+	}
+	return record
+}
+
+// decode makes entries from a dirRecord
+func (c *Cache) decode(ctx context.Context, record *dirRecord) (entries fs.DirEntries) {
+	entries = make(fs.DirEntries, 0, len(record.Entries))
+	for _, e := range record.Entries {
+		if e.Dir {
+			entries = append(entries, fs.NewDir(e.Remote, e.ModTime).SetSize(e.Size))
+		} else {
+			entries = append(entries, &Object{
+				ctx:     ctx,
+				f:       c.f,
+				remote:  e.Remote,
+				size:    e.Size,
+				modTime: e.ModTime,
+			})
+		}
+	}
+	return entries
+}
+
+// Dirty marks dir as changed so its listing will be read from the Fs
+// next time.
+//
+// The cached listing is removed straight away so an interrupted sync
+// can't leave it behind.
+func (c *Cache) Dirty(dir string) {
+	if dir == "." || dir == "/" {
+		dir = ""
+	}
+	c.mu.Lock()
+	_, found := c.dirty[dir]
+	c.dirty[dir] = struct{}{}
+	delete(c.listed, dir)
+	c.mu.Unlock()
+	if found {
+		return
+	}
+	err := c.db.Batch(func(tx *bolt.Tx) error {
+		return tx.Bucket(c.bucket).Delete(dirKey(dir))
+	})
+	if err != nil {
+		fs.Errorf(dir, "Failed to remove listing from cache: %v", err)
+	}
+}
+
+// DirtyParent marks the directory containing remote as changed
+func (c *Cache) DirtyParent(remote string) {
+	c.Dirty(path.Dir(remote))
+}
+
+// Close saves the listings read from the Fs in directories which
+// weren't changed and closes the database.
+func (c *Cache) Close() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	err := c.db.Update(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket(c.bucket)
+		for dir, data := range c.listed {
+			err := bucket.Put(dirKey(dir), data)
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	fs.Debugf(c.f, "Saved %d directory listings to the listing cache", len(c.listed))
+	c.listed = nil
+	closeErr := c.db.Close()
+	if err != nil {
+		return errors.Wrap(err, "failed to save listing cache")
+	}
+	return closeErr
+}
+
+// Object is an object read from the listing cache.
+//
+// The size and modification time come from the cache.  Anything
+// else reads the object from the Fs first, after which everything
+// comes from the object read.
+type Object struct {
+	ctx     context.Context
+	f       fs.Fs
+	remote  string
+	size    int64
+	modTime time.Time
+
+	mu  sync.Mutex
+	obj fs.Object // the object read from the Fs or nil
+}
+
+// object reads the object from the Fs if not already read
+func (o *Object) object() (fs.Object, error) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	if o.obj == nil {
+		obj, err := o.f.NewObject(o.ctx, o.remote)
+		if err != nil {
+			return nil, err
+		}
+		o.obj = obj
+	}
+	return o.obj, nil
+}
+
+// Fs returns the Fs this object is part of
+func (o *Object) Fs() fs.Info {
+	return o.f
+}
+
+// String returns a description of the Object
+func (o *Object) String() string {
+	if o == nil {
+		return "<nil>"
+	}
+	return o.remote
+}
+
+// Remote returns the remote path
+func (o *Object) Remote() string {
+	return o.remote
+}
+
+// resolved returns the object read from the Fs or nil if it hasn't
+// been read yet
+func (o *Object) resolved() fs.Object {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	return o.obj
+}
+
+// ModTime returns the modification time from the cache, or from the
+// Fs if the object has been read from there
+func (o *Object) ModTime() time.Time {
+	if obj := o.resolved(); obj != nil {
+		return obj.ModTime()
+	}
+	return o.modTime
+}
+
+// Size returns the size from the cache, or from the Fs if the object
+// has been read from there
+func (o *Object) Size() int64 {
+	if obj := o.resolved(); obj != nil {
+		return obj.Size()
+	}
+	return o.size
+}
+
+// Storable says whether this object can be stored
+func (o *Object) Storable() bool {
+	return true
+}
+
+// Hash returns the selected checksum of the file
+func (o *Object) Hash(ht hash.Type) (string, error) {
+	obj, err := o.object()
+	if err != nil {
+		return "", err
+	}
+	return obj.Hash(ht)
+}
+
+// SetModTime sets the modification time of the object
+func (o *Object) SetModTime(ctx context.Context, t time.Time) error {
+	obj, err := o.object()
+	if err != nil {
+		return err
+	}
+	return obj.SetModTime(ctx, t)
+}
+
+// Open opens the object for read
+func (o *Object) Open(ctx context.Context, options ...fs.OpenOption) (io.ReadCloser, error) {
+	obj, err := o.object()
+	if err != nil {
+		return nil, err
+	}
+	return obj.Open(ctx, options...)
+}
+
+// Update the object with the contents of in
+func (o *Object) Update(ctx context.Context, in io.Reader, src fs.ObjectInfo, options ...fs.OpenOption) error {
+	obj, err := o.object()
+	if err != nil {
+		return err
+	}
+	return obj.Update(ctx, in, src, options...)
+}
+
+// Remove the object
+func (o *Object) Remove(ctx context.Context) error {
+	obj, err := o.object()
+	if err != nil {
+		return err
+	}
+	return obj.Remove(ctx)
+}
+
+// UnWrap returns the object read from the Fs or nil if it couldn't
+// be read
+func (o *Object) UnWrap() fs.Object {
+	obj, _ := o.object()
+	return obj
+}
+
+// Check the interfaces are satisfied
+var (
+	_ fs.Object          = (*Object)(nil)
+	_ fs.ObjectUnWrapper = (*Object)(nil)
+)
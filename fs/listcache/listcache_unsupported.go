@@ -0,0 +1,41 @@
+// Stub listing cache for platforms bbolt doesn't support so fs/sync
+// still builds there
+
+// +build plan9 !go1.7
+
+package listcache
+
+import (
+	"time"
+
+	"github.com/ncw/rclone/fs"
+	"github.com/pkg/errors"
+	"golang.org/x/net/context"
+)
+
+// Cache is unused as the listing cache isn't supported on this
+// platform
+type Cache struct{}
+
+// Open returns an error as the listing cache isn't supported on this
+// platform
+func Open(f fs.Fs, dbPath string, maxAge time.Duration) (*Cache, error) {
+	return nil, errors.New("--dst-list-cache is not supported on this platform")
+}
+
+// List returns an error as the listing cache isn't supported on this
+// platform
+func (c *Cache) List(ctx context.Context, dir string) (entries fs.DirEntries, err error) {
+	return nil, errors.New("listing cache not supported")
+}
+
+// Dirty does nothing
+func (c *Cache) Dirty(dir string) {}
+
+// DirtyParent does nothing
+func (c *Cache) DirtyParent(remote string) {}
+
+// Close does nothing
+func (c *Cache) Close() error {
+	return nil
+}
@@ -1,8 +1,13 @@
 package fs
 
 import (
+	"encoding/json"
 	"fmt"
 	"log"
+	"path"
+	"runtime"
+	"strings"
+	"time"
 
 	"github.com/pkg/errors"
 )
@@ -67,15 +72,115 @@ func (l *LogLevel) Type() string {
 	return "string"
 }
 
+// ParseSubsystemLogLevel parses a per subsystem log level of the
+// form "subsystem=LEVEL", eg "vfs=DEBUG"
+func ParseSubsystemLogLevel(s string) (subsystem string, level LogLevel, err error) {
+	equals := strings.IndexRune(s, '=')
+	if equals <= 0 {
+		return "", level, errors.Errorf("Bad subsystem log level %q - expecting subsystem=LEVEL", s)
+	}
+	subsystem = strings.TrimSpace(s[:equals])
+	err = level.Set(strings.TrimSpace(s[equals+1:]))
+	return subsystem, level, err
+}
+
+// Packages whose frames are skipped when finding the caller of the
+// log functions
+const (
+	rclonePackage = "github.com/ncw/rclone/"
+	fsPackage     = rclonePackage + "fs"
+	fsLogPackage  = rclonePackage + "fs/log"
+)
+
+// logCaller returns the package path and the file:line of the first
+// caller outside the logging functions
+func logCaller() (pkg string, source string) {
+	pcs := make([]uintptr, 16)
+	n := runtime.Callers(3, pcs)
+	frames := runtime.CallersFrames(pcs[:n])
+	for {
+		frame, more := frames.Next()
+		// Function names look like "path/to/pkg.Func.func1"
+		name := frame.Function
+		slash := strings.LastIndex(name, "/")
+		dot := strings.IndexRune(name[slash+1:], '.')
+		if dot >= 0 {
+			pkg = name[:slash+1+dot]
+		} else {
+			pkg = name
+		}
+		inLog := (pkg == fsPackage || pkg == fsLogPackage) && path.Base(frame.File) == "log.go"
+		if !inLog || !more {
+			return pkg, fmt.Sprintf("%s:%d", path.Base(frame.File), frame.Line)
+		}
+	}
+}
+
+// effectiveLogLevel returns the log level for the caller taking into
+// account any overrides in Config.SubsystemLogLevels.
+//
+// The subsystem of the caller is found from its package path, so
+// "vfs" will match logs from github.com/ncw/rclone/vfs and "serve"
+// will match logs from github.com/ncw/rclone/cmd/serve/webdav.  The
+// most specific match wins.
+func effectiveLogLevel() LogLevel {
+	if len(Config.SubsystemLogLevels) == 0 {
+		return Config.LogLevel
+	}
+	pkg, _ := logCaller()
+	pkg = strings.TrimPrefix(pkg, rclonePackage)
+	for pkg != "" && pkg != "." {
+		if level, ok := Config.SubsystemLogLevels[path.Base(pkg)]; ok {
+			return level
+		}
+		pkg = path.Dir(pkg)
+	}
+	return Config.LogLevel
+}
+
+// LogEnabled returns true if logs at level would be output by the
+// caller.  Use this to avoid doing expensive work to make log
+// messages which won't be seen.
+func LogEnabled(level LogLevel) bool {
+	return effectiveLogLevel() >= level
+}
+
 // LogPrint sends the text to the logger of level
 var LogPrint = func(level LogLevel, text string) {
-	text = fmt.Sprintf("%-6s: %s", level, text)
+	if !Config.UseJSONLog {
+		text = fmt.Sprintf("%-6s: %s", level, text)
+	}
 	log.Print(text)
 }
 
+// jsonLog is a log entry as output by --use-json-log
+type jsonLog struct {
+	Time   string `json:"time"`
+	Level  string `json:"level"`
+	Msg    string `json:"msg"`
+	Object string `json:"object,omitempty"`
+	Source string `json:"source,omitempty"`
+}
+
 // LogPrintf produces a log string from the arguments passed in
 func LogPrintf(level LogLevel, o interface{}, text string, args ...interface{}) {
 	out := fmt.Sprintf(text, args...)
+	if Config.UseJSONLog {
+		entry := jsonLog{
+			Time:  time.Now().Format(time.RFC3339Nano),
+			Level: strings.ToLower(level.String()),
+			Msg:   out,
+		}
+		if o != nil {
+			entry.Object = fmt.Sprint(o)
+		}
+		_, entry.Source = logCaller()
+		buf, err := json.Marshal(&entry)
+		if err == nil {
+			LogPrint(level, string(buf))
+			return
+		}
+	}
 	if o != nil {
 		out = fmt.Sprintf("%v: %s", o, out)
 	}
@@ -84,7 +189,7 @@ func LogPrintf(level LogLevel, o interface{}, text string, args ...interface{})
 
 // LogLevelPrintf writes logs at the given level
 func LogLevelPrintf(level LogLevel, o interface{}, text string, args ...interface{}) {
-	if Config.LogLevel >= level {
+	if effectiveLogLevel() >= level {
 		LogPrintf(level, o, text, args...)
 	}
 }
@@ -92,7 +197,7 @@ func LogLevelPrintf(level LogLevel, o interface{}, text string, args ...interfac
 // Errorf writes error log output for this Object or Fs.  It
 // should always be seen by the user.
 func Errorf(o interface{}, text string, args ...interface{}) {
-	if Config.LogLevel >= LogLevelError {
+	if effectiveLogLevel() >= LogLevelError {
 		LogPrintf(LogLevelError, o, text, args...)
 	}
 }
@@ -103,7 +208,7 @@ func Errorf(o interface{}, text string, args ...interface{}) {
 // important things the user should see.  The user can filter these
 // out with the -q flag.
 func Logf(o interface{}, text string, args ...interface{}) {
-	if Config.LogLevel >= LogLevelNotice {
+	if effectiveLogLevel() >= LogLevelNotice {
 		LogPrintf(LogLevelNotice, o, text, args...)
 	}
 }
@@ -112,7 +217,7 @@ func Logf(o interface{}, text string, args ...interface{}) {
 // level for logging transfers, deletions and things which should
 // appear with the -v flag.
 func Infof(o interface{}, text string, args ...interface{}) {
-	if Config.LogLevel >= LogLevelInfo {
+	if effectiveLogLevel() >= LogLevelInfo {
 		LogPrintf(LogLevelInfo, o, text, args...)
 	}
 }
@@ -120,7 +225,7 @@ func Infof(o interface{}, text string, args ...interface{}) {
 // Debugf writes debugging output for this Object or Fs.  Use this for
 // debug only.  The user must have to specify -vv to see this.
 func Debugf(o interface{}, text string, args ...interface{}) {
-	if Config.LogLevel >= LogLevelDebug {
+	if effectiveLogLevel() >= LogLevelDebug {
 		LogPrintf(LogLevelDebug, o, text, args...)
 	}
 }
@@ -7,6 +7,7 @@ import (
 	"reflect"
 	"runtime"
 	"strings"
+	"time"
 
 	"github.com/ncw/rclone/fs"
 	"github.com/ncw/rclone/fs/config/flags"
@@ -17,8 +18,16 @@ var (
 	logFile        = flags.StringP("log-file", "", "", "Log everything to this file")
 	useSyslog      = flags.BoolP("syslog", "", false, "Use Syslog for logging")
 	syslogFacility = flags.StringP("syslog-facility", "", "DAEMON", "Facility for syslog, eg KERN,USER,...")
+	logFileMaxSize = fs.SizeSuffix(-1)
+	logFileMaxAge  = fs.Duration(0)
+	logFileBackups = flags.IntP("log-file-max-backups", "", 0, "Number of rotated log files to keep, 0 to keep all")
 )
 
+func init() {
+	flags.VarP(&logFileMaxSize, "log-file-max-size", "", "Rotate the log file when it gets bigger than this, eg 100M, off to disable")
+	flags.VarP(&logFileMaxAge, "log-file-max-age", "", "Rotate the log file when it gets older than this, eg 24h, 0 to disable")
+}
+
 // fnName returns the name of the calling +2 function
 func fnName() string {
 	pc, _, _, ok := runtime.Caller(2)
@@ -40,7 +49,7 @@ func fnName() string {
 //
 // Any pointers in the exit function will be dereferenced
 func Trace(o interface{}, format string, a ...interface{}) func(string, ...interface{}) {
-	if fs.Config.LogLevel < fs.LogLevelDebug {
+	if !fs.LogEnabled(fs.LogLevelDebug) {
 		return func(format string, a ...interface{}) {}
 	}
 	name := fnName()
@@ -67,16 +76,32 @@ func Trace(o interface{}, format string, a ...interface{}) func(string, ...inter
 func InitLogging() {
 	// Log file output
 	if *logFile != "" {
-		f, err := os.OpenFile(*logFile, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0640)
-		if err != nil {
-			log.Fatalf("Failed to open log file: %v", err)
-		}
-		_, err = f.Seek(0, os.SEEK_END)
-		if err != nil {
-			fs.Errorf(nil, "Failed to seek log file to end: %v", err)
+		if logFileMaxSize > 0 || logFileMaxAge > 0 {
+			// Redirect stderr to each new log file so panics end up in it
+			w, err := newRotatingFile(*logFile, int64(logFileMaxSize), time.Duration(logFileMaxAge), *logFileBackups, redirectStderr)
+			if err != nil {
+				log.Fatalf("Failed to open log file: %v", err)
+			}
+			log.SetOutput(w)
+		} else {
+			f, err := os.OpenFile(*logFile, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0640)
+			if err != nil {
+				log.Fatalf("Failed to open log file: %v", err)
+			}
+			_, err = f.Seek(0, os.SEEK_END)
+			if err != nil {
+				fs.Errorf(nil, "Failed to seek log file to end: %v", err)
+			}
+			log.SetOutput(f)
+			redirectStderr(f)
 		}
-		log.SetOutput(f)
-		redirectStderr(f)
+	} else if logFileMaxSize > 0 || logFileMaxAge > 0 {
+		log.Fatalf("Can't use --log-file-max-size or --log-file-max-age without --log-file")
+	}
+
+	// JSON logs carry their own time stamp
+	if fs.Config.UseJSONLog {
+		log.SetFlags(0)
 	}
 
 	// Syslog output
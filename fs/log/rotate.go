@@ -0,0 +1,164 @@
+// Log file rotation
+
+package log
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// Format of the time stamp added to rotated log files
+const rotateTimeFormat = "2006-01-02T15-04-05.000"
+
+// rotatingFile is an io.Writer which writes to a log file and
+// rotates it when it gets too big or too old
+type rotatingFile struct {
+	mu         sync.Mutex
+	name       string        // name of the log file
+	maxSize    int64         // rotate when the file gets bigger than this if > 0
+	maxAge     time.Duration // rotate when the file gets older than this if > 0
+	maxBackups int           // number of rotated files to keep if > 0
+	fd         *os.File      // the open log file
+	size       int64         // current size of the log file
+	opened     time.Time     // when the log file was started
+	onOpen     func(*os.File)
+}
+
+// newRotatingFile opens the log file called name for appending.
+//
+// onOpen, if set, is called with each new file opened.
+func newRotatingFile(name string, maxSize int64, maxAge time.Duration, maxBackups int, onOpen func(*os.File)) (*rotatingFile, error) {
+	r := &rotatingFile{
+		name:       name,
+		maxSize:    maxSize,
+		maxAge:     maxAge,
+		maxBackups: maxBackups,
+		onOpen:     onOpen,
+	}
+	err := r.open()
+	if err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+// open the log file for appending, reading its size and age
+func (r *rotatingFile) open() error {
+	fd, err := os.OpenFile(r.name, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0640)
+	if err != nil {
+		return errors.Wrap(err, "failed to open log file")
+	}
+	fi, err := fd.Stat()
+	if err != nil {
+		_ = fd.Close()
+		return errors.Wrap(err, "failed to stat log file")
+	}
+	r.fd = fd
+	r.size = fi.Size()
+	r.opened = time.Now()
+	if r.size > 0 {
+		// Age an existing file from when it was last written
+		r.opened = fi.ModTime()
+	}
+	if r.onOpen != nil {
+		r.onOpen(fd)
+	}
+	return nil
+}
+
+// needsRotate returns true if writing n more bytes needs a rotation
+func (r *rotatingFile) needsRotate(n int) bool {
+	if r.size == 0 {
+		return false
+	}
+	if r.maxSize > 0 && r.size+int64(n) > r.maxSize {
+		return true
+	}
+	if r.maxAge > 0 && time.Since(r.opened) > r.maxAge {
+		return true
+	}
+	return false
+}
+
+// rotate renames the current log file out of the way and starts a
+// new one
+//
+// If the rename fails then rotation is disabled and logging carries
+// on to the current file.
+func (r *rotatingFile) rotate() error {
+	_ = r.fd.Close()
+	backup := r.name + "." + time.Now().Format(rotateTimeFormat)
+	renameErr := os.Rename(r.name, backup)
+	err := r.open()
+	if err != nil {
+		r.fd = nil
+		return err
+	}
+	if renameErr != nil {
+		r.maxSize, r.maxAge = 0, 0
+		return errors.Wrap(renameErr, "failed to rename log file - disabling rotation")
+	}
+	r.removeOldBackups()
+	return nil
+}
+
+// removeOldBackups removes all but the newest maxBackups rotated files
+func (r *rotatingFile) removeOldBackups() {
+	if r.maxBackups <= 0 {
+		return
+	}
+	backups, err := filepath.Glob(r.name + ".*")
+	if err != nil {
+		return
+	}
+	// Only consider files we made
+	prefix := r.name + "."
+	i := 0
+	for _, backup := range backups {
+		if _, err := time.Parse(rotateTimeFormat, strings.TrimPrefix(backup, prefix)); err == nil {
+			backups[i] = backup
+			i++
+		}
+	}
+	backups = backups[:i]
+	// The time stamps sort in time order
+	sort.Strings(backups)
+	for len(backups) > r.maxBackups {
+		err = os.Remove(backups[0])
+		if err != nil {
+			logError(err)
+		}
+		backups = backups[1:]
+	}
+}
+
+// logError writes an error about log rotation to stderr.  It can't
+// use the logger as that would deadlock.
+func logError(err error) {
+	_, _ = fmt.Fprintf(os.Stderr, "Log file rotation: %v\n", err)
+}
+
+// Write the data to the log file, rotating it first if needed
+func (r *rotatingFile) Write(p []byte) (n int, err error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.needsRotate(len(p)) {
+		err = r.rotate()
+		if err != nil {
+			logError(err)
+		}
+	}
+	if r.fd == nil {
+		return 0, errors.New("log file not open")
+	}
+	n, err = r.fd.Write(p)
+	r.size += int64(n)
+	return n, err
+}
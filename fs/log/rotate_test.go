@@ -0,0 +1,77 @@
+package log
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRotatingFileSize(t *testing.T) {
+	dir, err := ioutil.TempDir("", "rclone-log-test")
+	require.NoError(t, err)
+	defer func() {
+		require.NoError(t, os.RemoveAll(dir))
+	}()
+	name := filepath.Join(dir, "rclone.log")
+
+	opened := 0
+	r, err := newRotatingFile(name, 10, 0, 2, func(*os.File) { opened++ })
+	require.NoError(t, err)
+	assert.Equal(t, 1, opened)
+
+	for i := 0; i < 5; i++ {
+		_, err = r.Write([]byte("12345678\n"))
+		require.NoError(t, err)
+		// make sure the time stamps of the backups differ
+		time.Sleep(2 * time.Millisecond)
+	}
+	require.NoError(t, r.fd.Close())
+
+	// Every write after the first should rotate
+	assert.Equal(t, 5, opened)
+	data, err := ioutil.ReadFile(name)
+	require.NoError(t, err)
+	assert.Equal(t, "12345678\n", string(data))
+
+	// Only 2 backups should be kept
+	backups, err := filepath.Glob(name + ".*")
+	require.NoError(t, err)
+	assert.Equal(t, 2, len(backups))
+}
+
+func TestRotatingFileAge(t *testing.T) {
+	dir, err := ioutil.TempDir("", "rclone-log-test")
+	require.NoError(t, err)
+	defer func() {
+		require.NoError(t, os.RemoveAll(dir))
+	}()
+	name := filepath.Join(dir, "rclone.log")
+
+	r, err := newRotatingFile(name, 0, time.Hour, 0, nil)
+	require.NoError(t, err)
+	_, err = r.Write([]byte("old\n"))
+	require.NoError(t, err)
+	_, err = r.Write([]byte("still old\n"))
+	require.NoError(t, err)
+
+	// Pretend the file is old
+	r.opened = time.Now().Add(-2 * time.Hour)
+	_, err = r.Write([]byte("new\n"))
+	require.NoError(t, err)
+	require.NoError(t, r.fd.Close())
+
+	data, err := ioutil.ReadFile(name)
+	require.NoError(t, err)
+	assert.Equal(t, "new\n", string(data))
+	backups, err := filepath.Glob(name + ".*")
+	require.NoError(t, err)
+	require.Equal(t, 1, len(backups))
+	data, err = ioutil.ReadFile(backups[0])
+	require.NoError(t, err)
+	assert.Equal(t, "old\nstill old\n", string(data))
+}
@@ -1,6 +1,6 @@
-// Syslog interface for non-Unix variants only
+// Syslog interface for non-Unix, non-Windows variants only
 
-// +build windows nacl plan9
+// +build nacl plan9
 
 package log
 
@@ -0,0 +1,48 @@
+// Syslog interface for Windows which logs to the Windows event log
+
+// +build windows
+
+package log
+
+import (
+	"log"
+	"os"
+	"path"
+	"strings"
+
+	"github.com/ncw/rclone/fs"
+	"golang.org/x/sys/windows/svc/eventlog"
+)
+
+// Event ID used for all the log messages
+const eventID = 1
+
+// Starts logging to the Windows event log
+func startSysLog() bool {
+	source := strings.TrimSuffix(path.Base(strings.Replace(os.Args[0], `\`, "/", -1)), ".exe")
+	// Register the event source - this needs administrator
+	// rights the first time so ignore errors if it exists already
+	err := eventlog.InstallAsEventCreate(source, eventlog.Error|eventlog.Warning|eventlog.Info)
+	if err != nil && !strings.Contains(err.Error(), "registry key already exists") {
+		fs.Debugf(nil, "Failed to register event log source %q: %v", source, err)
+	}
+	w, err := eventlog.Open(source)
+	if err != nil {
+		log.Fatalf("Failed to open the Windows event log: %v", err)
+	}
+	if *syslogFacility != "DAEMON" {
+		fs.Logf(nil, "--syslog-facility is ignored on Windows")
+	}
+	log.SetFlags(0)
+	fs.LogPrint = func(level fs.LogLevel, text string) {
+		switch {
+		case level <= fs.LogLevelError:
+			_ = w.Error(eventID, text)
+		case level == fs.LogLevelWarning:
+			_ = w.Warning(eventID, text)
+		default:
+			_ = w.Info(eventID, text)
+		}
+	}
+	return true
+}
@@ -1,6 +1,84 @@
 package fs
 
-import "github.com/spf13/pflag"
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/spf13/pflag"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
 
 // Check it satisfies the interface
 var _ pflag.Value = (*LogLevel)(nil)
+
+func TestParseSubsystemLogLevel(t *testing.T) {
+	for _, test := range []struct {
+		in            string
+		wantSubsystem string
+		wantLevel     LogLevel
+		wantErr       bool
+	}{
+		{"vfs=DEBUG", "vfs", LogLevelDebug, false},
+		{" sync = INFO ", "sync", LogLevelInfo, false},
+		{"DEBUG", "", LogLevelEmergency, true},
+		{"=DEBUG", "", LogLevelEmergency, true},
+		{"vfs=POTATO", "vfs", LogLevelEmergency, true},
+	} {
+		subsystem, level, err := ParseSubsystemLogLevel(test.in)
+		assert.Equal(t, test.wantErr, err != nil, test.in)
+		assert.Equal(t, test.wantSubsystem, subsystem, test.in)
+		assert.Equal(t, test.wantLevel, level, test.in)
+	}
+}
+
+func TestSubsystemLogLevels(t *testing.T) {
+	oldLevel, oldSubsystems := Config.LogLevel, Config.SubsystemLogLevels
+	defer func() {
+		Config.LogLevel, Config.SubsystemLogLevels = oldLevel, oldSubsystems
+	}()
+
+	Config.LogLevel = LogLevelNotice
+	Config.SubsystemLogLevels = nil
+	assert.False(t, LogEnabled(LogLevelDebug))
+	assert.True(t, LogEnabled(LogLevelNotice))
+
+	// This test is in the fs package
+	Config.SubsystemLogLevels = map[string]LogLevel{"fs": LogLevelDebug}
+	assert.True(t, LogEnabled(LogLevelDebug))
+
+	Config.SubsystemLogLevels = map[string]LogLevel{"vfs": LogLevelDebug}
+	assert.False(t, LogEnabled(LogLevelDebug))
+
+	Config.SubsystemLogLevels = map[string]LogLevel{"fs": LogLevelError}
+	assert.False(t, LogEnabled(LogLevelNotice))
+}
+
+func TestJSONLog(t *testing.T) {
+	oldLevel, oldJSON, oldLogPrint := Config.LogLevel, Config.UseJSONLog, LogPrint
+	defer func() {
+		Config.LogLevel, Config.UseJSONLog, LogPrint = oldLevel, oldJSON, oldLogPrint
+	}()
+
+	var gotLevel LogLevel
+	var gotText string
+	LogPrint = func(level LogLevel, text string) {
+		gotLevel, gotText = level, text
+	}
+	Config.LogLevel = LogLevelNotice
+	Config.UseJSONLog = true
+
+	Logf("potato", "hello %d", 42)
+	assert.Equal(t, LogLevelNotice, gotLevel)
+	var entry jsonLog
+	require.NoError(t, json.Unmarshal([]byte(gotText), &entry))
+	assert.Equal(t, "notice", entry.Level)
+	assert.Equal(t, "hello 42", entry.Msg)
+	assert.Equal(t, "potato", entry.Object)
+	assert.Contains(t, entry.Source, "log_test.go:")
+	assert.NotEqual(t, "", entry.Time)
+
+	Config.UseJSONLog = false
+	Logf("potato", "hello %d", 42)
+	assert.Equal(t, "potato: hello 42", gotText)
+}
@@ -8,6 +8,7 @@ import (
 	"sync"
 
 	"github.com/ncw/rclone/fs"
+	"github.com/ncw/rclone/fs/accounting"
 	"github.com/ncw/rclone/fs/filter"
 	"github.com/ncw/rclone/fs/list"
 	"github.com/ncw/rclone/fs/walk"
@@ -28,6 +29,7 @@ type March struct {
 	srcListDir listDirFn // function to call to list a directory in the src
 	dstListDir listDirFn // function to call to list a directory in the dst
 	transforms []matchTransformFn
+	noTraverse bool // look up the src objects in the dst rather than listing it
 }
 
 // Marcher is called on each match
@@ -49,8 +51,8 @@ func New(ctx context.Context, fdst, fsrc fs.Fs, dir string, callback Marcher) *M
 		dir:      dir,
 		callback: callback,
 	}
-	m.srcListDir = m.makeListDir(fsrc, false)
-	m.dstListDir = m.makeListDir(fdst, filter.Active.Opt.DeleteExcluded)
+	m.srcListDir = m.makeListDir(ctx, fsrc, false)
+	m.dstListDir = m.makeListDir(ctx, fdst, filter.Active.Opt.DeleteExcluded)
 	// Now create the matching transform
 	// ..normalise the UTF8 first
 	m.transforms = append(m.transforms, norm.NFC.String)
@@ -66,14 +68,53 @@ func New(ctx context.Context, fdst, fsrc fs.Fs, dir string, callback Marcher) *M
 	return m
 }
 
+// SetDstListFn makes the march read the unfiltered destination
+// listings with listFn rather than listing fdst.
+//
+// It must be called before Run.
+func (m *March) SetDstListFn(listFn list.ListFn) {
+	includeAll := filter.Active.Opt.DeleteExcluded
+	m.dstListDir = func(dir string) (entries fs.DirEntries, err error) {
+		return list.DirSortedFn(m.ctx, m.fdst, includeAll, dir, listFn)
+	}
+}
+
+// SetNoTraverse makes the march look up each object in the source
+// listings in the destination with NewObject rather than listing the
+// destination directories.  Objects only in the destination are not
+// found.
+//
+// It must be called before Run.
+func (m *March) SetNoTraverse() {
+	m.noTraverse = true
+}
+
+// lookupDst finds the objects in srcList which are in the destination
+func (m *March) lookupDst(srcList fs.DirEntries) (dstList fs.DirEntries, err error) {
+	for _, entry := range srcList {
+		src, ok := entry.(fs.Object)
+		if !ok {
+			continue
+		}
+		dst, err := m.fdst.NewObject(m.ctx, src.Remote())
+		if err == fs.ErrorObjectNotFound {
+			continue
+		} else if err != nil {
+			return nil, err
+		}
+		dstList = append(dstList, dst)
+	}
+	return dstList, nil
+}
+
 // list a directory into entries, err
 type listDirFn func(dir string) (entries fs.DirEntries, err error)
 
 // makeListDir makes a listing function for the given fs and includeAll flags
-func (m *March) makeListDir(f fs.Fs, includeAll bool) listDirFn {
+func (m *March) makeListDir(ctx context.Context, f fs.Fs, includeAll bool) listDirFn {
 	if !fs.Config.UseListR || f.Features().ListR == nil {
 		return func(dir string) (entries fs.DirEntries, err error) {
-			return list.DirSorted(f, includeAll, dir)
+			return list.DirSorted(ctx, f, includeAll, dir)
 		}
 	}
 	var (
@@ -86,7 +127,7 @@ func (m *March) makeListDir(f fs.Fs, includeAll bool) listDirFn {
 		mu.Lock()
 		defer mu.Unlock()
 		if !started {
-			dirs, dirsErr = walk.NewDirTree(f, m.dir, includeAll, fs.Config.MaxDepth)
+			dirs, dirsErr = walk.NewDirTree(ctx, f, m.dir, includeAll, fs.Config.MaxDepth)
 			started = true
 		}
 		if dirsErr != nil {
@@ -139,17 +180,16 @@ func (m *March) Run() {
 					if !ok {
 						return
 					}
-					jobs := m.processJob(job)
-					if len(jobs) > 0 {
+					m.processJob(job, func(jobs []listDirJob) {
 						traversing.Add(len(jobs))
 						go func() {
-							// Now we have traversed this directory, send these
-							// jobs off for traversal in the background
+							// Send the subdirectories off for
+							// traversal in the background
 							for _, newJob := range jobs {
 								in <- newJob
 							}
 						}()
-					}
+					})
 					traversing.Done()
 				}
 			}
@@ -317,11 +357,15 @@ func matchListings(srcListEntries, dstListEntries fs.DirEntries, transforms []ma
 }
 
 // processJob processes a listDirJob listing the source and
-// destination directories, comparing them and returning a slice of
-// more jobs
+// destination directories and comparing them.
+//
+// The directories are compared first and any subdirectories to
+// traverse are passed to dispatch so they can be listed while the
+// files are being compared.  Comparing the files can block while the
+// checkers and transfers catch up so this keeps the listing going.
 //
 // returns errors using processError
-func (m *March) processJob(job listDirJob) (jobs []listDirJob) {
+func (m *March) processJob(job listDirJob, dispatch func(jobs []listDirJob)) {
 	var (
 		srcList, dstList       fs.DirEntries
 		srcListErr, dstListErr error
@@ -336,7 +380,7 @@ func (m *March) processJob(job listDirJob) (jobs []listDirJob) {
 			srcList, srcListErr = m.srcListDir(job.srcRemote)
 		}()
 	}
-	if !job.noDst {
+	if !job.noDst && !m.noTraverse {
 		wg.Add(1)
 		go func() {
 			defer wg.Done()
@@ -349,19 +393,39 @@ func (m *March) processJob(job listDirJob) (jobs []listDirJob) {
 	if srcListErr != nil {
 		fs.Errorf(job.srcRemote, "error reading source directory: %v", srcListErr)
 		fs.CountError(srcListErr)
-		return nil
+		accounting.Stats.RetryAll()
+		return
+	}
+	if !job.noDst && m.noTraverse {
+		dstList, dstListErr = m.lookupDst(srcList)
 	}
 	if dstListErr == fs.ErrorDirNotFound {
 		// Copy the stuff anyway
 	} else if dstListErr != nil {
 		fs.Errorf(job.dstRemote, "error reading destination directory: %v", dstListErr)
 		fs.CountError(dstListErr)
-		return nil
+		accounting.Stats.RetryAll()
+		return
 	}
 
-	// Work out what to do and do it
+	// Work out what to do and do it, directories first then files
 	srcOnly, dstOnly, matches := matchListings(srcList, dstList, m.transforms)
+	for _, dirs := range []bool{true, false} {
+		jobs := m.processEntries(job, srcOnly, dstOnly, matches, dirs)
+		if len(jobs) > 0 {
+			dispatch(jobs)
+		}
+	}
+}
+
+// processEntries calls the callbacks for the directories in the
+// listings if dirs is set otherwise for the files, returning a slice
+// of more jobs
+func (m *March) processEntries(job listDirJob, srcOnly, dstOnly fs.DirEntries, matches []matchPair, dirs bool) (jobs []listDirJob) {
 	for _, src := range srcOnly {
+		if isDir(src) != dirs {
+			continue
+		}
 		if m.aborting() {
 			return nil
 		}
@@ -370,12 +434,15 @@ func (m *March) processJob(job listDirJob) (jobs []listDirJob) {
 			jobs = append(jobs, listDirJob{
 				srcRemote: src.Remote(),
 				srcDepth:  job.srcDepth - 1,
-				noDst:     true,
+				noDst:     !m.noTraverse,
 			})
 		}
 
 	}
 	for _, dst := range dstOnly {
+		if isDir(dst) != dirs {
+			continue
+		}
 		if m.aborting() {
 			return nil
 		}
@@ -389,6 +456,9 @@ func (m *March) processJob(job listDirJob) (jobs []listDirJob) {
 		}
 	}
 	for _, match := range matches {
+		if isDir(match.src) != dirs {
+			continue
+		}
 		if m.aborting() {
 			return nil
 		}
@@ -404,3 +474,9 @@ func (m *March) processJob(job listDirJob) (jobs []listDirJob) {
 	}
 	return jobs
 }
+
+// isDir returns true if entry is a directory
+func isDir(entry fs.DirEntry) bool {
+	_, ok := entry.(fs.Directory)
+	return ok
+}
@@ -5,10 +5,12 @@ package march
 import (
 	"strings"
 	"testing"
+	"time"
 
 	"github.com/ncw/rclone/fs"
 	"github.com/ncw/rclone/fstest/mockobject"
 	"github.com/stretchr/testify/assert"
+	"golang.org/x/net/context"
 )
 
 func TestNewMatchEntries(t *testing.T) {
@@ -169,3 +171,54 @@ func TestMatchListings(t *testing.T) {
 		assert.Equal(t, test.matches, matches, test.what)
 	}
 }
+
+// recordMarcher records the callbacks made and recurses into every
+// directory
+type recordMarcher struct {
+	calls []string
+}
+
+func (r *recordMarcher) SrcOnly(src fs.DirEntry) (recurse bool) {
+	r.calls = append(r.calls, "src "+src.Remote())
+	return isDir(src)
+}
+
+func (r *recordMarcher) DstOnly(dst fs.DirEntry) (recurse bool) {
+	r.calls = append(r.calls, "dst "+dst.Remote())
+	return isDir(dst)
+}
+
+func (r *recordMarcher) Match(dst, src fs.DirEntry) (recurse bool) {
+	r.calls = append(r.calls, "match "+src.Remote())
+	return isDir(src)
+}
+
+func TestProcessEntries(t *testing.T) {
+	var (
+		a    = mockobject.Object("a")
+		b    = mockobject.Object("b")
+		c    = mockobject.Object("c")
+		dirA = fs.NewDir("dirA", time.Now())
+		dirB = fs.NewDir("dirB", time.Now())
+		dirC = fs.NewDir("dirC", time.Now())
+	)
+	r := &recordMarcher{}
+	m := &March{ctx: context.Background(), callback: r}
+	job := listDirJob{srcDepth: 1, dstDepth: 1}
+	srcOnly := fs.DirEntries{a, dirA}
+	dstOnly := fs.DirEntries{b, dirB}
+	matches := []matchPair{{src: c, dst: c}, {src: dirC, dst: dirC}}
+
+	jobs := m.processEntries(job, srcOnly, dstOnly, matches, true)
+	assert.Equal(t, []string{"src dirA", "dst dirB", "match dirC"}, r.calls)
+	assert.Equal(t, []listDirJob{
+		{srcRemote: "dirA", noDst: true},
+		{dstRemote: "dirB", noSrc: true},
+		{srcRemote: "dirC", dstRemote: "dirC"},
+	}, jobs)
+
+	r.calls = nil
+	jobs = m.processEntries(job, srcOnly, dstOnly, matches, false)
+	assert.Equal(t, []string{"src a", "dst b", "match c"}, r.calls)
+	assert.Nil(t, jobs)
+}
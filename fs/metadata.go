@@ -0,0 +1,84 @@
+// Object metadata
+
+package fs
+
+// Metadata represents Object metadata in a standardised form
+//
+// The keys are lower case and the values are strings.  Well known
+// keys which backends should map to and from their native metadata
+// where possible are
+//
+//   mtime        - time of last modification in RFC 3339 format
+//   atime        - time of last access in RFC 3339 format
+//   btime        - time of file birth (creation) in RFC 3339 format
+//   mode         - file type and permissions in octal, unix style
+//   uid          - user id of the owner
+//   gid          - group id of the owner
+//   content-type - the MIME type of the object
+//
+// Any other keys are treated as user metadata and passed through
+// unchanged if the destination supports it.
+type Metadata map[string]string
+
+// Set k to v on m
+//
+// If m is nil, then it will get made
+func (m *Metadata) Set(k, v string) {
+	if *m == nil {
+		*m = make(Metadata, 1)
+	}
+	(*m)[k] = v
+}
+
+// Merge other into m
+//
+// If m is nil, then it will get made
+func (m *Metadata) Merge(other Metadata) {
+	for k, v := range other {
+		m.Set(k, v)
+	}
+}
+
+// Metadataer is an optional interface for Object
+type Metadataer interface {
+	// Metadata returns metadata for an object
+	//
+	// It should return nil if there is no Metadata
+	Metadata() (Metadata, error)
+}
+
+// GetMetadata from an ObjectInfo
+//
+// If the object has no metadata then metadata will be nil
+func GetMetadata(o ObjectInfo) (metadata Metadata, err error) {
+	do, ok := o.(Metadataer)
+	if !ok {
+		return nil, nil
+	}
+	return do.Metadata()
+}
+
+// GetMetadataOptions from an ObjectInfo and merge it with any in
+// options
+//
+// This is for use by backends in Put and Update to find out the
+// metadata they should set on the object.
+//
+// If --metadata isn't in use it will return nil
+//
+// If the object has no metadata then metadata will be nil
+func GetMetadataOptions(o ObjectInfo, options []OpenOption) (metadata Metadata, err error) {
+	if !Config.Metadata {
+		return nil, nil
+	}
+	metadata, err = GetMetadata(o)
+	if err != nil {
+		return nil, err
+	}
+	for _, option := range options {
+		if metadataOption, ok := option.(MetadataOption); ok {
+			metadata.Merge(Metadata(metadataOption))
+		}
+	}
+	return metadata, nil
+}
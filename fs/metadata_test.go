@@ -0,0 +1,46 @@
+package fs
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMetadataSet(t *testing.T) {
+	var m Metadata
+	assert.Nil(t, m)
+	m.Set("key", "value")
+	assert.NotNil(t, m)
+	assert.Equal(t, "value", m["key"])
+	m.Set("key", "value2")
+	assert.Equal(t, "value2", m["key"])
+}
+
+func TestMetadataMerge(t *testing.T) {
+	var m Metadata
+	m.Merge(Metadata{"a": "1", "b": "2"})
+	assert.Equal(t, Metadata{"a": "1", "b": "2"}, m)
+	m.Merge(Metadata{"b": "3", "c": "4"})
+	assert.Equal(t, Metadata{"a": "1", "b": "3", "c": "4"}, m)
+}
+
+func TestGetMetadataOptions(t *testing.T) {
+	oldMetadata := Config.Metadata
+	defer func() {
+		Config.Metadata = oldMetadata
+	}()
+	options := []OpenOption{
+		&HashesOption{},
+		MetadataOption{"potato": "sausage"},
+	}
+
+	Config.Metadata = false
+	metadata, err := GetMetadataOptions(nil, options)
+	assert.NoError(t, err)
+	assert.Nil(t, metadata)
+
+	Config.Metadata = true
+	metadata, err = GetMetadataOptions(nil, options)
+	assert.NoError(t, err)
+	assert.Equal(t, Metadata{"potato": "sausage"}, metadata)
+}
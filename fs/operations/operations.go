@@ -2,12 +2,20 @@
 package operations
 
 import (
+	"bufio"
 	"bytes"
+	"encoding/base64"
+	"encoding/csv"
+	"encoding/hex"
 	"fmt"
 	"io"
 	"io/ioutil"
 	"log"
+	"mime"
+	"net/http"
+	"os"
 	"path"
+	"regexp"
 	"sort"
 	"strconv"
 	"strings"
@@ -19,10 +27,12 @@ import (
 	"github.com/ncw/rclone/fs/accounting"
 	"github.com/ncw/rclone/fs/config"
 	"github.com/ncw/rclone/fs/fserrors"
+	"github.com/ncw/rclone/fs/fshttp"
 	"github.com/ncw/rclone/fs/hash"
 	"github.com/ncw/rclone/fs/march"
 	"github.com/ncw/rclone/fs/object"
 	"github.com/ncw/rclone/fs/walk"
+	"github.com/ncw/rclone/lib/atexit"
 	"github.com/ncw/rclone/lib/readers"
 	"github.com/pkg/errors"
 	"github.com/spf13/pflag"
@@ -92,8 +102,8 @@ func CheckHashes(src fs.ObjectInfo, dst fs.Object) (equal bool, ht hash.Type, er
 //
 // Otherwise the file is considered to be not equal including if there
 // were errors reading info.
-func Equal(src fs.ObjectInfo, dst fs.Object) bool {
-	return equal(src, dst, fs.Config.SizeOnly, fs.Config.CheckSum)
+func Equal(ctx context.Context, src fs.ObjectInfo, dst fs.Object) bool {
+	return equal(ctx, src, dst, fs.Config.SizeOnly, fs.Config.CheckSum)
 }
 
 // sizeDiffers compare the size of src and dst taking into account the
@@ -105,14 +115,14 @@ func sizeDiffers(src, dst fs.ObjectInfo) bool {
 	return src.Size() != dst.Size()
 }
 
-func equal(src fs.ObjectInfo, dst fs.Object, sizeOnly, checkSum bool) bool {
+func equal(ctx context.Context, src fs.ObjectInfo, dst fs.Object, sizeOnly, checkSum bool) bool {
 	if sizeDiffers(src, dst) {
 		fs.Debugf(src, "Sizes differ (src %d vs dst %d)", src.Size(), dst.Size())
 		return false
 	}
 	if sizeOnly {
 		fs.Debugf(src, "Sizes identical")
-		return true
+		return refreshModTime(ctx, src, dst)
 	}
 
 	// Assert: Size is equal or being ignored
@@ -130,7 +140,7 @@ func equal(src fs.ObjectInfo, dst fs.Object, sizeOnly, checkSum bool) bool {
 		} else {
 			fs.Debugf(src, "Size and %v of src and dst objects identical", ht)
 		}
-		return true
+		return refreshModTime(ctx, src, dst)
 	}
 
 	// Sizes the same so check the mtime
@@ -155,54 +165,137 @@ func equal(src fs.ObjectInfo, dst fs.Object, sizeOnly, checkSum bool) bool {
 		fs.Debugf(src, "%v differ", ht)
 		return false
 	}
-	if ht == hash.None {
+	if ht == hash.None && !fs.Config.RefreshTimes {
 		// if couldn't check hash, return that they differ
 		return false
 	}
 
-	// mod time differs but hash is the same to reset mod time if required
-	if !fs.Config.NoUpdateModTime {
-		if fs.Config.DryRun {
-			fs.Logf(src, "Not updating modification time as --dry-run")
-		} else {
-			// Size and hash the same but mtime different
-			// Error if objects are treated as immutable
-			if fs.Config.Immutable {
-				fs.Errorf(dst, "Timestamp mismatch between immutable objects")
-				return false
-			}
-			// Update the mtime of the dst object here
-			err := dst.SetModTime(srcModTime)
-			if err == fs.ErrorCantSetModTime {
-				fs.Debugf(dst, "src and dst identical but can't set mod time without re-uploading")
-				return false
-			} else if err == fs.ErrorCantSetModTimeWithoutDelete {
-				fs.Debugf(dst, "src and dst identical but can't set mod time without deleting and re-uploading")
-				err = dst.Remove()
-				if err != nil {
-					fs.Errorf(dst, "failed to delete before re-upload: %v", err)
-				}
-				return false
-			} else if err != nil {
-				fs.CountError(err)
-				fs.Errorf(dst, "Failed to set modification time: %v", err)
-			} else {
-				fs.Infof(src, "Updated modification time in destination")
-			}
+	// mod time differs but hash is the same (or can't be checked
+	// and --refresh-times is set) so reset mod time if required
+	return updateModTime(ctx, src, dst, srcModTime)
+}
+
+// refreshModTime sets the mod time of dst to that of src if
+// --refresh-times is set and they differ.  It is used when the
+// objects have been found equal without checking the mod times.
+//
+// It returns false if dst needs to be re-uploaded after all.
+func refreshModTime(ctx context.Context, src fs.ObjectInfo, dst fs.Object) bool {
+	if !fs.Config.RefreshTimes || fs.Config.ModifyWindow == fs.ModTimeNotSupported {
+		return true
+	}
+	srcModTime := src.ModTime()
+	dt := dst.ModTime().Sub(srcModTime)
+	if dt < fs.Config.ModifyWindow && dt > -fs.Config.ModifyWindow {
+		return true
+	}
+	fs.Debugf(src, "Modification times differ by %s", dt)
+	return updateModTime(ctx, src, dst, srcModTime)
+}
+
+// updateModTime sets the mod time of dst, which has the same contents
+// as src, to srcModTime on the server rather than re-uploading it.
+//
+// It returns false if dst needs to be re-uploaded after all.
+func updateModTime(ctx context.Context, src fs.ObjectInfo, dst fs.Object, srcModTime time.Time) bool {
+	if fs.Config.NoUpdateModTime || SkipDryRun(src, "update modification time") {
+		return true
+	}
+	// Error if objects are treated as immutable
+	if fs.Config.Immutable {
+		fs.Errorf(dst, "Timestamp mismatch between immutable objects")
+		return false
+	}
+	// Update the mtime of the dst object here
+	err := dst.SetModTime(ctx, srcModTime)
+	if err == fs.ErrorCantSetModTime {
+		fs.Debugf(dst, "src and dst identical but can't set mod time without re-uploading")
+		return false
+	} else if err == fs.ErrorCantSetModTimeWithoutDelete {
+		fs.Debugf(dst, "src and dst identical but can't set mod time without deleting and re-uploading")
+		err = dst.Remove(ctx)
+		if err != nil {
+			fs.Errorf(dst, "failed to delete before re-upload: %v", err)
 		}
+		return false
+	} else if err != nil {
+		fs.CountError(err)
+		fs.Errorf(dst, "Failed to set modification time: %v", err)
+	} else {
+		fs.Infof(src, "Updated modification time in destination")
 	}
 	return true
 }
 
+// State for the --interactive prompts
+var (
+	interactiveMu  sync.Mutex          // only one prompt at once
+	interactiveAll = map[string]bool{} // actions the user has said yes to all of
+)
+
+// SkipDestructive should be called before doing a destructive
+// operation, eg deleting or overwriting a file.
+//
+// It returns true if the operation should be skipped, either because
+// --dry-run is set or because --interactive is set and the user
+// declined.  It logs the reason for skipping.
+//
+// subject should be the object or directory in use and action should
+// be a short phrase, so that "rclone is about to action subject"
+// makes sense.
+func SkipDestructive(subject interface{}, action string) bool {
+	if SkipDryRun(subject, action) {
+		return true
+	}
+	if !fs.Config.Interactive {
+		return false
+	}
+	interactiveMu.Lock()
+	defer interactiveMu.Unlock()
+	if interactiveAll[action] {
+		return false
+	}
+	fmt.Printf("rclone: %s %q?\n", action, fmt.Sprint(subject))
+	switch config.Command([]string{
+		"yYes, this is OK",
+		"nNo, skip this",
+		fmt.Sprintf("aYes to all remaining %s operations", action),
+		"qQuit rclone now",
+	}) {
+	case 'n':
+		fs.Logf(subject, "Skipped %s as not confirmed with --interactive", action)
+		return true
+	case 'a':
+		interactiveAll[action] = true
+	case 'q':
+		fs.Logf(nil, "Exiting as requested with --interactive")
+		atexit.Run()
+		os.Exit(0)
+	}
+	return false
+}
+
+// SkipDryRun should be called before doing an operation which changes
+// the destination but doesn't lose data, eg making a directory.
+//
+// It returns true and logs a message if --dry-run is set.
+func SkipDryRun(subject interface{}, action string) bool {
+	if fs.Config.DryRun {
+		fs.Logf(subject, "Skipped %s as --dry-run is set", action)
+		return true
+	}
+	return false
+}
+
 // Used to remove a failed copy
 //
 // Returns whether the file was succesfully removed or not
-func removeFailedCopy(dst fs.Object) bool {
+func removeFailedCopy(ctx context.Context, dst fs.Object) bool {
 	if dst == nil {
 		return false
 	}
 	fs.Infof(dst, "Removing failed copy")
-	removeErr := dst.Remove()
+	removeErr := dst.Remove(ctx)
 	if removeErr != nil {
 		fs.Infof(dst, "Failed to remove failed copy: %s", removeErr)
 		return false
@@ -230,18 +323,40 @@ func (o *overrideRemoteObject) MimeType() string {
 	return ""
 }
 
+// Metadata returns metadata for an object
+//
+// It should return nil if there is no Metadata
+func (o *overrideRemoteObject) Metadata() (fs.Metadata, error) {
+	return fs.GetMetadata(o.Object)
+}
+
 // Check interface is satisfied
-var _ fs.MimeTyper = (*overrideRemoteObject)(nil)
+var (
+	_ fs.MimeTyper  = (*overrideRemoteObject)(nil)
+	_ fs.Metadataer = (*overrideRemoteObject)(nil)
+)
+
+// uploadHeaderOptions returns options with the headers from
+// --header-upload appended
+func uploadHeaderOptions(options ...fs.OpenOption) []fs.OpenOption {
+	for _, option := range fs.Config.UploadHeaders {
+		options = append(options, option)
+	}
+	return options
+}
 
 // Copy src object to dst or f if nil.  If dst is nil then it uses
 // remote as the name of the new object.
 //
 // It returns the destination object if possible.  Note that this may
 // be nil.
-func Copy(f fs.Fs, dst fs.Object, remote string, src fs.Object) (newDst fs.Object, err error) {
+func Copy(ctx context.Context, f fs.Fs, dst fs.Object, remote string, src fs.Object) (newDst fs.Object, err error) {
 	newDst = dst
-	if fs.Config.DryRun {
-		fs.Logf(src, "Not copying as --dry-run")
+	if dst != nil {
+		if SkipDestructive(dst, "overwrite") {
+			return newDst, nil
+		}
+	} else if SkipDryRun(src, "copy") {
 		return newDst, nil
 	}
 	maxTries := fs.Config.LowLevelRetries
@@ -258,100 +373,139 @@ func Copy(f fs.Fs, dst fs.Object, remote string, src fs.Object) (newDst fs.Objec
 		}
 	}
 	hashOption := &fs.HashesOption{Hashes: common}
+	downloadOptions := []fs.OpenOption{hashOption}
+	for _, option := range fs.Config.DownloadHeaders {
+		downloadOptions = append(downloadOptions, option)
+	}
+	uploadOptions := uploadHeaderOptions(hashOption)
 	var actionTaken string
+	corruptedTries := 0
+	// Retry the whole transfer if it is corrupted
 	for {
-		// Try server side copy first - if has optional interface and
-		// is same underlying remote
-		actionTaken = "Copied (server side copy)"
-		if doCopy := f.Features().Copy; doCopy != nil && SameConfig(src.Fs(), f) {
-			newDst, err = doCopy(src, remote)
-			if err == nil {
-				dst = newDst
-			}
-		} else {
-			err = fs.ErrorCantCopy
-		}
-		// If can't server side copy, do it manually
-		if err == fs.ErrorCantCopy {
-			var in0 io.ReadCloser
-			in0, err = src.Open(hashOption)
-			if err != nil {
-				err = errors.Wrap(err, "failed to open source object")
-			} else {
-				in := accounting.NewAccount(in0, src).WithBuffer() // account and buffer the transfer
-				var wrappedSrc fs.ObjectInfo = src
-				// We try to pass the original object if possible
-				if src.Remote() != remote {
-					wrappedSrc = &overrideRemoteObject{Object: src, remote: remote}
+		// Retry low level errors
+		for {
+			// Try server side copy first - if has optional interface and
+			// is same underlying remote
+			actionTaken = "Copied (server side copy)"
+			if doCopy := f.Features().Copy; doCopy != nil && SameConfig(src.Fs(), f) {
+				newDst, err = doCopy(ctx, src, remote)
+				if err == nil {
+					dst = newDst
 				}
-				if doUpdate {
-					actionTaken = "Copied (replaced existing)"
-					err = dst.Update(in, wrappedSrc, hashOption)
-				} else {
-					actionTaken = "Copied (new)"
-					dst, err = f.Put(in, wrappedSrc, hashOption)
+			} else {
+				err = fs.ErrorCantCopy
+			}
+			// If can't server side copy, do it manually
+			if err == fs.ErrorCantCopy {
+				var in0 io.ReadCloser
+				err = accounting.CheckMaxTransfer(src.Size())
+				if err == nil {
+					in0, err = src.Open(ctx, downloadOptions...)
+					if err != nil {
+						err = errors.Wrap(err, "failed to open source object")
+					}
 				}
-				closeErr := in.Close()
 				if err == nil {
-					newDst = dst
-					err = closeErr
+					in := accounting.NewAccount(in0, src).WithLimits(src.Fs(), f) // account the transfer
+					if fs.Config.StreamingBufferSize > 0 && !src.Fs().Features().IsLocal && !f.Features().IsLocal {
+						in.WithBufferSize(fs.Config.StreamingBufferSize) // stream remote to remote through memory
+					} else {
+						in.WithBuffer() // buffer the transfer
+					}
+					var wrappedSrc fs.ObjectInfo = src
+					// We try to pass the original object if possible
+					if src.Remote() != remote {
+						wrappedSrc = &overrideRemoteObject{Object: src, remote: remote}
+					}
+					if doUpdate {
+						actionTaken = "Copied (replaced existing)"
+						err = dst.Update(ctx, in, wrappedSrc, uploadOptions...)
+					} else {
+						actionTaken = "Copied (new)"
+						dst, err = f.Put(ctx, in, wrappedSrc, uploadOptions...)
+					}
+					closeErr := in.Close()
+					if err == nil {
+						newDst = dst
+						err = closeErr
+					}
 				}
 			}
+			tries++
+			if tries >= maxTries {
+				break
+			}
+			// Retry if err returned a retry error
+			if fserrors.IsRetryError(err) || fserrors.ShouldRetry(err) {
+				fs.Debugf(src, "Received error: %v - low level retry %d/%d", err, tries, maxTries)
+				continue
+			}
+			// otherwise finish
+			break
+		}
+		if err != nil {
+			fs.CountError(err)
+			fs.Errorf(src, "Failed to copy: %v", err)
+			return newDst, err
 		}
-		tries++
-		if tries >= maxTries {
+
+		// Verify the transfer and start again if it was corrupted
+		var corrupted bool
+		corrupted, err = checkCopied(src, dst, hashType)
+		if !corrupted {
 			break
 		}
-		// Retry if err returned a retry error
-		if fserrors.IsRetryError(err) || fserrors.ShouldRetry(err) {
-			fs.Debugf(src, "Received error: %v - low level retry %d/%d", err, tries, maxTries)
-			continue
+		fs.Errorf(dst, "%v", err)
+		removeFailedCopy(ctx, dst)
+		if corruptedTries >= fs.Config.CorruptedRetries {
+			fs.CountError(err)
+			return newDst, err
 		}
-		// otherwise finish
-		break
-	}
-	if err != nil {
-		fs.CountError(err)
-		fs.Errorf(src, "Failed to copy: %v", err)
-		return newDst, err
+		corruptedTries++
+		fs.Logf(src, "Retrying corrupted transfer %d/%d", corruptedTries, fs.Config.CorruptedRetries)
+		dst, newDst, doUpdate, tries = nil, nil, false, 0
 	}
 
+	fs.Infof(src, actionTaken)
+	return newDst, err
+}
+
+// checkCopied checks dst is the same size as src and has the same
+// hash of hashType if set, ignoring blank hashes.
+//
+// It returns corrupted with an error if they differ.  Errors reading
+// the hashes are counted and returned without corrupted being set.
+func checkCopied(src fs.Object, dst fs.Object, hashType hash.Type) (corrupted bool, err error) {
 	// Verify sizes are the same after transfer
 	if sizeDiffers(src, dst) {
-		err = errors.Errorf("corrupted on transfer: sizes differ %d vs %d", src.Size(), dst.Size())
-		fs.Errorf(dst, "%v", err)
-		fs.CountError(err)
-		removeFailedCopy(dst)
-		return newDst, err
+		return true, errors.Errorf("corrupted on transfer: sizes differ %d vs %d", src.Size(), dst.Size())
 	}
 
 	// Verify hashes are the same after transfer - ignoring blank hashes
 	// TODO(klauspost): This could be extended, so we always create a hash type matching
 	// the destination, and calculate it while sending.
-	if hashType != hash.None {
-		var srcSum string
-		srcSum, err = src.Hash(hashType)
-		if err != nil {
-			fs.CountError(err)
-			fs.Errorf(src, "Failed to read src hash: %v", err)
-		} else if srcSum != "" {
-			var dstSum string
-			dstSum, err = dst.Hash(hashType)
-			if err != nil {
-				fs.CountError(err)
-				fs.Errorf(dst, "Failed to read hash: %v", err)
-			} else if !fs.Config.IgnoreChecksum && !hash.Equals(srcSum, dstSum) {
-				err = errors.Errorf("corrupted on transfer: %v hash differ %q vs %q", hashType, srcSum, dstSum)
-				fs.Errorf(dst, "%v", err)
-				fs.CountError(err)
-				removeFailedCopy(dst)
-				return newDst, err
-			}
-		}
+	if hashType == hash.None {
+		return false, nil
 	}
-
-	fs.Infof(src, actionTaken)
-	return newDst, err
+	srcSum, err := src.Hash(hashType)
+	if err != nil {
+		fs.CountError(err)
+		fs.Errorf(src, "Failed to read src hash: %v", err)
+		return false, err
+	}
+	if srcSum == "" {
+		return false, nil
+	}
+	dstSum, err := dst.Hash(hashType)
+	if err != nil {
+		fs.CountError(err)
+		fs.Errorf(dst, "Failed to read hash: %v", err)
+		return false, err
+	}
+	if !fs.Config.IgnoreChecksum && !hash.Equals(srcSum, dstSum) {
+		return true, errors.Errorf("corrupted on transfer: %v hash differ %q vs %q", hashType, srcSum, dstSum)
+	}
+	return false, nil
 }
 
 // Move src object to dst or fdst if nil.  If dst is nil then it uses
@@ -359,23 +513,22 @@ func Copy(f fs.Fs, dst fs.Object, remote string, src fs.Object) (newDst fs.Objec
 //
 // It returns the destination object if possible.  Note that this may
 // be nil.
-func Move(fdst fs.Fs, dst fs.Object, remote string, src fs.Object) (newDst fs.Object, err error) {
+func Move(ctx context.Context, fdst fs.Fs, dst fs.Object, remote string, src fs.Object) (newDst fs.Object, err error) {
 	newDst = dst
-	if fs.Config.DryRun {
-		fs.Logf(src, "Not moving as --dry-run")
+	if SkipDestructive(src, "move") {
 		return newDst, nil
 	}
 	// See if we have Move available
 	if doMove := fdst.Features().Move; doMove != nil && SameConfig(src.Fs(), fdst) {
 		// Delete destination if it exists
 		if dst != nil {
-			err = DeleteFile(dst)
+			err = DeleteFile(ctx, dst)
 			if err != nil {
 				return newDst, err
 			}
 		}
 		// Move dst <- src
-		newDst, err = doMove(src, remote)
+		newDst, err = doMove(ctx, src, remote)
 		switch err {
 		case nil:
 			fs.Infof(src, "Moved (server side)")
@@ -389,13 +542,56 @@ func Move(fdst fs.Fs, dst fs.Object, remote string, src fs.Object) (newDst fs.Ob
 		}
 	}
 	// Move not found or didn't work so copy dst <- src
-	newDst, err = Copy(fdst, dst, remote, src)
+	newDst, err = Copy(ctx, fdst, dst, remote, src)
 	if err != nil {
 		fs.Errorf(src, "Not deleting source as copy failed: %v", err)
 		return newDst, err
 	}
+	if fs.Config.DeleteAfterVerify {
+		err = verifyCopy(ctx, fdst, remote, src)
+		if err != nil {
+			fs.CountError(err)
+			fs.Errorf(src, "Not deleting source as verification failed: %v", err)
+			return newDst, err
+		}
+	}
 	// Delete src if no error on copy
-	return newDst, DeleteFile(src)
+	return newDst, DeleteFile(ctx, src)
+}
+
+// verifyCopy checks the copy of src at remote in fdst independently
+// of the checks made during the transfer by finding it in fdst again.
+//
+// It compares the hashes if src and fdst have one in common,
+// otherwise it downloads the copy and compares it with src.
+func verifyCopy(ctx context.Context, fdst fs.Fs, remote string, src fs.Object) error {
+	dst, err := fdst.NewObject(ctx, remote)
+	if err != nil {
+		return errors.Wrap(err, "failed to find copy")
+	}
+	if sizeDiffers(src, dst) {
+		return errors.Errorf("sizes differ %d vs %d", src.Size(), dst.Size())
+	}
+	equal, ht, err := CheckHashes(src, dst)
+	if err != nil {
+		return err
+	}
+	if ht != hash.None {
+		if !equal {
+			return errors.Errorf("%v hash differ", ht)
+		}
+		fs.Debugf(src, "Verified %v hash of copy", ht)
+		return nil
+	}
+	differ, err := CheckIdentical(ctx, dst, src)
+	if err != nil {
+		return errors.Wrap(err, "failed to compare with copy")
+	}
+	if differ {
+		return errors.New("contents differ")
+	}
+	fs.Debugf(src, "Verified copy by downloading it")
+	return nil
 }
 
 // CanServerSideMove returns true if fdst support server side moves or
@@ -409,38 +605,98 @@ func CanServerSideMove(fdst fs.Fs) bool {
 	return canMove || canCopy
 }
 
+// Placeholders which may be used in --suffix
+const (
+	suffixDate    = "{date}" // replaced with the date of the backup
+	suffixTime    = "{time}" // replaced with the time of the backup
+	suffixCounter = "{n}"    // replaced with a counter to avoid overwriting
+)
+
+var (
+	backupTimeOnce sync.Once
+	backupTime     time.Time // time used for {date} and {time} in --suffix
+)
+
+// SuffixName adds --suffix to remote, before the extension if
+// --suffix-keep-extension is set.
+//
+// Any {date} and {time} in the suffix are replaced with the date
+// and time rclone started making backups and {n} is replaced with n.
+func SuffixName(remote string, n int) string {
+	suffix := fs.Config.Suffix
+	if suffix == "" {
+		return remote
+	}
+	backupTimeOnce.Do(func() {
+		backupTime = time.Now()
+	})
+	suffix = strings.Replace(suffix, suffixDate, backupTime.Format("2006-01-02"), -1)
+	suffix = strings.Replace(suffix, suffixTime, backupTime.Format("150405"), -1)
+	suffix = strings.Replace(suffix, suffixCounter, strconv.Itoa(n), -1)
+	if fs.Config.SuffixKeepExtension {
+		leaf := path.Base(remote)
+		ext := path.Ext(leaf)
+		// don't treat dot files such as .bashrc as all extension
+		if ext != "" && ext != leaf {
+			return remote[:len(remote)-len(ext)] + suffix + ext
+		}
+	}
+	return remote + suffix
+}
+
+// MoveBackupDir moves dst into backupDir, renaming it with --suffix
+//
+// If the suffix contains {n} then the lowest n starting from 1 which
+// doesn't overwrite an existing file in backupDir is used, otherwise
+// any existing file with the same name is overwritten.
+func MoveBackupDir(ctx context.Context, backupDir fs.Fs, dst fs.Object) (err error) {
+	remoteWithSuffix := SuffixName(dst.Remote(), 1)
+	overwritten, err := backupDir.NewObject(ctx, remoteWithSuffix)
+	if strings.Contains(fs.Config.Suffix, suffixCounter) {
+		for n := 2; err == nil; n++ {
+			remoteWithSuffix = SuffixName(dst.Remote(), n)
+			overwritten, err = backupDir.NewObject(ctx, remoteWithSuffix)
+		}
+		if err != fs.ErrorObjectNotFound {
+			return errors.Wrap(err, "failed to find unused backup name")
+		}
+		overwritten = nil
+	}
+	_, err = Move(ctx, backupDir, overwritten, remoteWithSuffix, dst)
+	return err
+}
+
 // DeleteFileWithBackupDir deletes a single file respecting --dry-run
 // and accumulating stats and errors.
 //
 // If backupDir is set then it moves the file to there instead of
 // deleting
-func DeleteFileWithBackupDir(dst fs.Object, backupDir fs.Fs) (err error) {
+func DeleteFileWithBackupDir(ctx context.Context, dst fs.Object, backupDir fs.Fs) (err error) {
 	accounting.Stats.Checking(dst.Remote())
 	numDeletes := accounting.Stats.Deletes(1)
 	if fs.Config.MaxDelete != -1 && numDeletes > fs.Config.MaxDelete {
 		return fserrors.FatalError(errors.New("--max-delete threshold reached"))
 	}
-	action, actioned, actioning := "delete", "Deleted", "deleting"
+	action, actioned := "delete", "Deleted"
 	if backupDir != nil {
-		action, actioned, actioning = "move into backup dir", "Moved into backup dir", "moving into backup dir"
+		action, actioned = "move into backup dir", "Moved into backup dir"
 	}
-	if fs.Config.DryRun {
-		fs.Logf(dst, "Not %s as --dry-run", actioning)
+	skip := SkipDestructive(dst, action)
+	if skip {
+		// do nothing
 	} else if backupDir != nil {
 		if !SameConfig(dst.Fs(), backupDir) {
 			err = errors.New("parameter to --backup-dir has to be on the same remote as destination")
 		} else {
-			remoteWithSuffix := dst.Remote() + fs.Config.Suffix
-			overwritten, _ := backupDir.NewObject(remoteWithSuffix)
-			_, err = Move(backupDir, overwritten, remoteWithSuffix, dst)
+			err = MoveBackupDir(ctx, backupDir, dst)
 		}
 	} else {
-		err = dst.Remove()
+		err = dst.Remove(ctx)
 	}
 	if err != nil {
 		fs.CountError(err)
 		fs.Errorf(dst, "Couldn't %s: %v", action, err)
-	} else if !fs.Config.DryRun {
+	} else if !skip {
 		fs.Infof(dst, actioned)
 	}
 	accounting.Stats.DoneChecking(dst.Remote())
@@ -451,8 +707,8 @@ func DeleteFileWithBackupDir(dst fs.Object, backupDir fs.Fs) (err error) {
 //
 // If useBackupDir is set and --backup-dir is in effect then it moves
 // the file to there instead of deleting
-func DeleteFile(dst fs.Object) (err error) {
-	return DeleteFileWithBackupDir(dst, nil)
+func DeleteFile(ctx context.Context, dst fs.Object) (err error) {
+	return DeleteFileWithBackupDir(ctx, dst, nil)
 }
 
 // DeleteFilesWithBackupDir removes all the files passed in the
@@ -460,7 +716,7 @@ func DeleteFile(dst fs.Object) (err error) {
 //
 // If backupDir is set the files will be placed into that directory
 // instead of being deleted.
-func DeleteFilesWithBackupDir(toBeDeleted fs.ObjectsChan, backupDir fs.Fs) error {
+func DeleteFilesWithBackupDir(ctx context.Context, toBeDeleted fs.ObjectsChan, backupDir fs.Fs) error {
 	var wg sync.WaitGroup
 	wg.Add(fs.Config.Transfers)
 	var errorCount int32
@@ -470,7 +726,7 @@ func DeleteFilesWithBackupDir(toBeDeleted fs.ObjectsChan, backupDir fs.Fs) error
 		go func() {
 			defer wg.Done()
 			for dst := range toBeDeleted {
-				err := DeleteFileWithBackupDir(dst, backupDir)
+				err := DeleteFileWithBackupDir(ctx, dst, backupDir)
 				if err != nil {
 					atomic.AddInt32(&errorCount, 1)
 					if fserrors.IsFatalError(err) {
@@ -495,8 +751,8 @@ func DeleteFilesWithBackupDir(toBeDeleted fs.ObjectsChan, backupDir fs.Fs) error
 }
 
 // DeleteFiles removes all the files passed in the channel
-func DeleteFiles(toBeDeleted fs.ObjectsChan) error {
-	return DeleteFilesWithBackupDir(toBeDeleted, nil)
+func DeleteFiles(ctx context.Context, toBeDeleted fs.ObjectsChan) error {
+	return DeleteFilesWithBackupDir(ctx, toBeDeleted, nil)
 }
 
 // Read a Objects into add() for the given Fs.
@@ -506,8 +762,8 @@ func DeleteFiles(toBeDeleted fs.ObjectsChan) error {
 //
 // Each object is passed ito the function provided.  If that returns
 // an error then the listing will be aborted and that error returned.
-func readFilesFn(f fs.Fs, includeAll bool, dir string, add func(fs.Object) error) (err error) {
-	return walk.Walk(f, "", includeAll, fs.Config.MaxDepth, func(dirPath string, entries fs.DirEntries, err error) error {
+func readFilesFn(ctx context.Context, f fs.Fs, includeAll bool, dir string, add func(fs.Object) error) (err error) {
+	return walk.Walk(ctx, f, "", includeAll, fs.Config.MaxDepth, func(dirPath string, entries fs.DirEntries, err error) error {
 		if err != nil {
 			return err
 		}
@@ -680,7 +936,7 @@ func (c *checkMarch) Match(dst, src fs.DirEntry) (recurse bool) {
 //
 // it returns true if differences were found
 // it also returns whether it couldn't be hashed
-func CheckFn(fdst, fsrc fs.Fs, check checkFn) error {
+func CheckFn(ctx context.Context, fdst, fsrc fs.Fs, check checkFn) error {
 	c := &checkMarch{
 		fdst:  fdst,
 		fsrc:  fsrc,
@@ -688,7 +944,7 @@ func CheckFn(fdst, fsrc fs.Fs, check checkFn) error {
 	}
 
 	// set up a march over fdst and fsrc
-	m := march.New(context.Background(), fdst, fsrc, "", c)
+	m := march.New(ctx, fdst, fsrc, "", c)
 	fs.Infof(fdst, "Waiting for checks to finish")
 	m.Run()
 
@@ -710,8 +966,8 @@ func CheckFn(fdst, fsrc fs.Fs, check checkFn) error {
 }
 
 // Check the files in fsrc and fdst according to Size and hash
-func Check(fdst, fsrc fs.Fs) error {
-	return CheckFn(fdst, fsrc, checkIdentical)
+func Check(ctx context.Context, fdst, fsrc fs.Fs) error {
+	return CheckFn(ctx, fdst, fsrc, checkIdentical)
 }
 
 // CheckEqualReaders checks to see if in1 and in2 have the same
@@ -748,19 +1004,19 @@ func CheckEqualReaders(in1, in2 io.Reader) (differ bool, err error) {
 // reading all their bytes if necessary.
 //
 // it returns true if differences were found
-func CheckIdentical(dst, src fs.Object) (differ bool, err error) {
-	in1, err := dst.Open()
+func CheckIdentical(ctx context.Context, dst, src fs.Object) (differ bool, err error) {
+	in1, err := dst.Open(ctx)
 	if err != nil {
 		return true, errors.Wrapf(err, "failed to open %q", dst)
 	}
-	in1 = accounting.NewAccount(in1, dst).WithBuffer() // account and buffer the transfer
+	in1 = accounting.NewAccount(in1, dst).WithLimits(dst.Fs(), nil).WithBuffer() // account and buffer the transfer
 	defer fs.CheckClose(in1, &err)
 
-	in2, err := src.Open()
+	in2, err := src.Open(ctx)
 	if err != nil {
 		return true, errors.Wrapf(err, "failed to open %q", src)
 	}
-	in2 = accounting.NewAccount(in2, src).WithBuffer() // account and buffer the transfer
+	in2 = accounting.NewAccount(in2, src).WithLimits(src.Fs(), nil).WithBuffer() // account and buffer the transfer
 	defer fs.CheckClose(in2, &err)
 
 	return CheckEqualReaders(in1, in2)
@@ -768,9 +1024,9 @@ func CheckIdentical(dst, src fs.Object) (differ bool, err error) {
 
 // CheckDownload checks the files in fsrc and fdst according to Size
 // and the actual contents of the files.
-func CheckDownload(fdst, fsrc fs.Fs) error {
+func CheckDownload(ctx context.Context, fdst, fsrc fs.Fs) error {
 	check := func(a, b fs.Object) (differ bool, noHash bool) {
-		differ, err := CheckIdentical(a, b)
+		differ, err := CheckIdentical(ctx, a, b)
 		if err != nil {
 			fs.CountError(err)
 			fs.Errorf(a, "Failed to download: %v", err)
@@ -778,18 +1034,14 @@ func CheckDownload(fdst, fsrc fs.Fs) error {
 		}
 		return differ, false
 	}
-	return CheckFn(fdst, fsrc, check)
+	return CheckFn(ctx, fdst, fsrc, check)
 }
 
 // ListFn lists the Fs to the supplied function
 //
 // Lists in parallel which may get them out of order
-func ListFn(f fs.Fs, fn func(fs.Object)) error {
-	return walk.Walk(f, "", false, fs.Config.MaxDepth, func(dirPath string, entries fs.DirEntries, err error) error {
-		if err != nil {
-			// FIXME count errors and carry on for listing
-			return err
-		}
+func ListFn(ctx context.Context, f fs.Fs, fn func(fs.Object)) error {
+	return walk.ListR(ctx, f, "", false, fs.Config.MaxDepth, walk.ListObjects, func(entries fs.DirEntries) error {
 		entries.ForObject(fn)
 		return nil
 	})
@@ -812,8 +1064,8 @@ func syncFprintf(w io.Writer, format string, a ...interface{}) {
 // Shows size and path - obeys includes and excludes
 //
 // Lists in parallel which may get them out of order
-func List(f fs.Fs, w io.Writer) error {
-	return ListFn(f, func(o fs.Object) {
+func List(ctx context.Context, f fs.Fs, w io.Writer) error {
+	return ListFn(ctx, f, func(o fs.Object) {
 		syncFprintf(w, "%9d %s\n", o.Size(), o.Remote())
 	})
 }
@@ -823,8 +1075,8 @@ func List(f fs.Fs, w io.Writer) error {
 // Shows size, mod time and path - obeys includes and excludes
 //
 // Lists in parallel which may get them out of order
-func ListLong(f fs.Fs, w io.Writer) error {
-	return ListFn(f, func(o fs.Object) {
+func ListLong(ctx context.Context, f fs.Fs, w io.Writer) error {
+	return ListFn(ctx, f, func(o fs.Object) {
 		accounting.Stats.Checking(o.Remote())
 		modTime := o.ModTime()
 		accounting.Stats.DoneChecking(o.Remote())
@@ -838,8 +1090,8 @@ func ListLong(f fs.Fs, w io.Writer) error {
 // excludes
 //
 // Lists in parallel which may get them out of order
-func Md5sum(f fs.Fs, w io.Writer) error {
-	return hashLister(hash.MD5, f, w)
+func Md5sum(ctx context.Context, f fs.Fs, w io.Writer) error {
+	return HashLister(ctx, hash.MD5, false, f, w)
 }
 
 // Sha1sum list the Fs to the supplied writer
@@ -847,8 +1099,8 @@ func Md5sum(f fs.Fs, w io.Writer) error {
 // Obeys includes and excludes
 //
 // Lists in parallel which may get them out of order
-func Sha1sum(f fs.Fs, w io.Writer) error {
-	return hashLister(hash.SHA1, f, w)
+func Sha1sum(ctx context.Context, f fs.Fs, w io.Writer) error {
+	return HashLister(ctx, hash.SHA1, false, f, w)
 }
 
 // DropboxHashSum list the Fs to the supplied writer
@@ -856,8 +1108,8 @@ func Sha1sum(f fs.Fs, w io.Writer) error {
 // Obeys includes and excludes
 //
 // Lists in parallel which may get them out of order
-func DropboxHashSum(f fs.Fs, w io.Writer) error {
-	return hashLister(hash.Dropbox, f, w)
+func DropboxHashSum(ctx context.Context, f fs.Fs, w io.Writer) error {
+	return HashLister(ctx, hash.Dropbox, false, f, w)
 }
 
 // hashSum returns the human readable hash for ht passed in.  This may
@@ -875,22 +1127,219 @@ func hashSum(ht hash.Type, o fs.Object) string {
 	return sum
 }
 
-func hashLister(ht hash.Type, f fs.Fs, w io.Writer) error {
-	return ListFn(f, func(o fs.Object) {
+// HashLister lists the hashes of type ht for all the objects in f to
+// w in the same format as the md5sum and sha1sum tools.
+//
+// If outputBase64 is set then the hashes are written in URL safe
+// base64 rather than hex.
+//
+// Obeys includes and excludes
+//
+// Lists in parallel which may get them out of order
+func HashLister(ctx context.Context, ht hash.Type, outputBase64 bool, f fs.Fs, w io.Writer) error {
+	width := hash.Width[ht]
+	if outputBase64 {
+		width = base64.URLEncoding.EncodedLen(width / 2)
+	}
+	return ListFn(ctx, f, func(o fs.Object) {
 		sum := hashSum(ht, o)
-		syncFprintf(w, "%*s  %s\n", hash.Width[ht], sum, o.Remote())
+		if outputBase64 {
+			if raw, err := hex.DecodeString(sum); err == nil {
+				sum = base64.URLEncoding.EncodeToString(raw)
+			}
+		}
+		syncFprintf(w, "%*s  %s\n", width, sum, o.Remote())
+	})
+}
+
+// checksumLineRe matches a line of a checksum file in the format
+// written by md5sum, sha1sum or HashLister
+var checksumLineRe = regexp.MustCompile(`^([0-9a-zA-Z_=-]+) [ *](.+)$`)
+
+// parseChecksumFile reads a checksum file from in returning a map of
+// remote to lower case hex hash.  Hashes may be in hex or URL safe
+// base64.
+func parseChecksumFile(in io.Reader) (map[string]string, error) {
+	sums := map[string]string{}
+	scanner := bufio.NewScanner(in)
+	lineNumber := 0
+	for scanner.Scan() {
+		lineNumber++
+		line := strings.TrimRight(scanner.Text(), "\r")
+		if line == "" {
+			continue
+		}
+		parts := checksumLineRe.FindStringSubmatch(strings.TrimSpace(line))
+		if parts == nil {
+			return nil, errors.Errorf("checksum file line %d: invalid format: %q", lineNumber, line)
+		}
+		sum, remote := parts[1], parts[2]
+		if raw, err := hex.DecodeString(sum); err == nil {
+			sum = hex.EncodeToString(raw)
+		} else if raw, err := base64.URLEncoding.DecodeString(sum); err == nil {
+			sum = hex.EncodeToString(raw)
+		} else {
+			return nil, errors.Errorf("checksum file line %d: bad hash %q", lineNumber, sum)
+		}
+		sums[remote] = sum
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, errors.Wrap(err, "failed to read checksum file")
+	}
+	return sums, nil
+}
+
+// CheckSum checks the hashes of type ht of all the objects in f
+// against the checksum file read from in.
+//
+// The checksum file should be in the format written by md5sum,
+// sha1sum or HashLister with the hashes in hex or base64.
+//
+// Objects with a different hash, objects which aren't in the
+// checksum file and entries in the checksum file which are missing
+// from f are all logged and counted as differences.
+//
+// Obeys includes and excludes
+func CheckSum(ctx context.Context, f fs.Fs, ht hash.Type, in io.Reader) error {
+	sums, err := parseChecksumFile(in)
+	if err != nil {
+		return err
+	}
+	var (
+		mu          sync.Mutex
+		differences int
+		noHashes    int
+		seen        = make(map[string]struct{}, len(sums))
+	)
+	err = ListFn(ctx, f, func(o fs.Object) {
+		remote := o.Remote()
+		sum := strings.ToLower(hashSum(ht, o))
+		mu.Lock()
+		defer mu.Unlock()
+		want, ok := sums[remote]
+		if !ok {
+			err := errors.New("file not in checksum file")
+			fs.CountError(err)
+			fs.Errorf(o, "%v", err)
+			differences++
+			return
+		}
+		seen[remote] = struct{}{}
+		switch {
+		case sum == "unsupported" || sum == "error":
+			fs.Errorf(o, "Couldn't read %v", ht)
+			noHashes++
+		case sum != want:
+			err := errors.Errorf("%v differ", ht)
+			fs.CountError(err)
+			fs.Errorf(o, "%v", err)
+			differences++
+		default:
+			fs.Debugf(o, "%v OK", ht)
+		}
+	})
+	if err != nil {
+		return err
+	}
+	for remote := range sums {
+		if _, ok := seen[remote]; !ok {
+			err := errors.Errorf("file in checksum file not found in %v", f)
+			fs.CountError(err)
+			fs.Errorf(remote, "%v", err)
+			differences++
+		}
+	}
+	fs.Logf(f, "%d differences found", differences)
+	if noHashes > 0 {
+		fs.Logf(f, "%d hashes could not be checked", noHashes)
+	}
+	if differences > 0 {
+		return errors.Errorf("%d differences found", differences)
+	}
+	return nil
+}
+
+// SetTier changes the storage tier of all the objects in f to tier
+//
+// Obeys includes and excludes
+func SetTier(ctx context.Context, f fs.Fs, tier string) error {
+	return ListFn(ctx, f, func(o fs.Object) {
+		do, ok := o.(fs.SetTierer)
+		if !ok {
+			err := errors.New("object does not support SetTier")
+			fs.CountError(err)
+			fs.Errorf(o, "%v", err)
+			return
+		}
+		if SkipDryRun(o, "set tier to "+tier) {
+			return
+		}
+		err := do.SetTier(tier)
+		if err != nil {
+			fs.CountError(err)
+			fs.Errorf(o, "Failed to set tier: %v", err)
+			return
+		}
+		fs.Infof(o, "Set tier to %s", tier)
 	})
 }
 
 // Count counts the objects and their sizes in the Fs
 //
 // Obeys includes and excludes
-func Count(f fs.Fs) (objects int64, size int64, err error) {
-	err = ListFn(f, func(o fs.Object) {
-		atomic.AddInt64(&objects, 1)
-		atomic.AddInt64(&size, o.Size())
+func Count(ctx context.Context, f fs.Fs) (objects int64, size int64, err error) {
+	total, _, err := CountDirs(ctx, f, false)
+	return total.Count, total.Bytes, err
+}
+
+// SizeResult is the number and size of some objects as returned by
+// CountDirs
+type SizeResult struct {
+	Count    int64 `json:"count"`    // number of objects
+	Bytes    int64 `json:"bytes"`    // total size of the objects of known size
+	Sizeless int64 `json:"sizeless"` // number of objects of unknown size
+}
+
+// add o to the result
+func (r *SizeResult) add(o fs.Object) {
+	r.Count++
+	if size := o.Size(); size >= 0 {
+		r.Bytes += size
+	} else {
+		r.Sizeless++
+	}
+}
+
+// CountDirs counts the objects and their sizes in the Fs
+//
+// If byDir is set then it also returns the counts broken down by top
+// level directory.  Objects in the root are counted under ".".
+//
+// Obeys includes and excludes
+func CountDirs(ctx context.Context, f fs.Fs, byDir bool) (total SizeResult, dirs map[string]*SizeResult, err error) {
+	var mu sync.Mutex
+	if byDir {
+		dirs = make(map[string]*SizeResult)
+	}
+	err = ListFn(ctx, f, func(o fs.Object) {
+		mu.Lock()
+		defer mu.Unlock()
+		total.add(o)
+		if !byDir {
+			return
+		}
+		dir := "."
+		if i := strings.IndexRune(o.Remote(), '/'); i >= 0 {
+			dir = o.Remote()[:i]
+		}
+		result := dirs[dir]
+		if result == nil {
+			result = new(SizeResult)
+			dirs[dir] = result
+		}
+		result.add(o)
 	})
-	return
+	return total, dirs, err
 }
 
 // ConfigMaxDepth returns the depth to use for a recursive or non recursive listing.
@@ -903,8 +1352,8 @@ func ConfigMaxDepth(recursive bool) int {
 }
 
 // ListDir lists the directories/buckets/containers in the Fs to the supplied writer
-func ListDir(f fs.Fs, w io.Writer) error {
-	return walk.Walk(f, "", false, ConfigMaxDepth(false), func(dirPath string, entries fs.DirEntries, err error) error {
+func ListDir(ctx context.Context, f fs.Fs, w io.Writer) error {
+	return walk.Walk(ctx, f, "", false, ConfigMaxDepth(false), func(dirPath string, entries fs.DirEntries, err error) error {
 		if err != nil {
 			// FIXME count errors and carry on for listing
 			return err
@@ -919,13 +1368,12 @@ func ListDir(f fs.Fs, w io.Writer) error {
 }
 
 // Mkdir makes a destination directory or container
-func Mkdir(f fs.Fs, dir string) error {
-	if fs.Config.DryRun {
-		fs.Logf(fs.LogDirName(f, dir), "Not making directory as dry run is set")
+func Mkdir(ctx context.Context, f fs.Fs, dir string) error {
+	if SkipDryRun(fs.LogDirName(f, dir), "make directory") {
 		return nil
 	}
 	fs.Debugf(fs.LogDirName(f, dir), "Making directory")
-	err := f.Mkdir(dir)
+	err := f.Mkdir(ctx, dir)
 	if err != nil {
 		fs.CountError(err)
 		return err
@@ -933,20 +1381,116 @@ func Mkdir(f fs.Fs, dir string) error {
 	return nil
 }
 
+// MkdirAll makes dir and any of its parents which don't exist one
+// at a time for backends which won't create intermediate directories
+func MkdirAll(ctx context.Context, f fs.Fs, dir string) error {
+	dir = strings.TrimRight(dir, "/")
+	for i := 1; i < len(dir); i++ {
+		if dir[i] != '/' || dir[i-1] == '/' {
+			continue
+		}
+		err := Mkdir(ctx, f, dir[:i])
+		if err != nil {
+			return err
+		}
+	}
+	return Mkdir(ctx, f, dir)
+}
+
+// SetDirModTime sets the modification time of dir on f to modTime
+// respecting --dry-run and --no-update-dir-modtime.
+//
+// It does nothing if f can't set the modification time of
+// directories.  It returns fs.ErrorDirNotFound if dir doesn't exist.
+func SetDirModTime(ctx context.Context, f fs.Fs, dir string, modTime time.Time) error {
+	dirSetModTime := f.Features().DirSetModTime
+	if dirSetModTime == nil || fs.Config.NoUpdateDirModTime {
+		return nil
+	}
+	if SkipDryRun(fs.LogDirName(f, dir), "set directory modification time") {
+		return nil
+	}
+	fs.Debugf(fs.LogDirName(f, dir), "Setting directory modification time to %v", modTime)
+	err := dirSetModTime(ctx, dir, modTime)
+	if err != nil && err != fs.ErrorDirNotFound {
+		fs.CountError(err)
+	}
+	return err
+}
+
+// SetModTime sets the modification time of o to modTime respecting
+// --dry-run.
+//
+// If the backend can't set the modification time of an existing
+// object then the object is uploaded again with the new time.
+func SetModTime(ctx context.Context, o fs.Object, modTime time.Time) error {
+	if SkipDryRun(o, "update modification time") {
+		return nil
+	}
+	err := o.SetModTime(ctx, modTime)
+	if err != fs.ErrorCantSetModTime && err != fs.ErrorCantSetModTimeWithoutDelete {
+		return err
+	}
+	fs.Debugf(o, "Can't set modification time - re-uploading: %v", err)
+	f, ok := o.Fs().(fs.Fs)
+	if !ok {
+		return err
+	}
+	withoutDelete := err == fs.ErrorCantSetModTimeWithoutDelete
+
+	// Take a copy of the contents as the object can't be read
+	// while it is being replaced
+	tmp, err := ioutil.TempFile("", "rclone-modtime-")
+	if err != nil {
+		return errors.Wrap(err, "failed to make temporary file for re-upload")
+	}
+	defer func() {
+		_ = tmp.Close()
+		_ = os.Remove(tmp.Name())
+	}()
+	in, err := o.Open(ctx)
+	if err != nil {
+		return errors.Wrap(err, "failed to open for re-upload")
+	}
+	size, err := io.Copy(tmp, in)
+	fs.CheckClose(in, &err)
+	if err != nil {
+		return errors.Wrap(err, "failed to read for re-upload")
+	}
+	_, err = tmp.Seek(0, 0) //io.SeekStart
+	if err != nil {
+		return errors.Wrap(err, "failed to rewind for re-upload")
+	}
+
+	src := object.NewStaticObjectInfo(o.Remote(), modTime, size, true, nil, f)
+	if withoutDelete {
+		err = o.Remove(ctx)
+		if err != nil {
+			return errors.Wrap(err, "failed to delete before re-upload")
+		}
+		_, err = f.Put(ctx, tmp, src)
+	} else {
+		err = o.Update(ctx, tmp, src)
+	}
+	if err != nil {
+		return errors.Wrap(err, "failed to re-upload")
+	}
+	return nil
+}
+
 // TryRmdir removes a container but not if not empty.  It doesn't
 // count errors but may return one.
-func TryRmdir(f fs.Fs, dir string) error {
-	if fs.Config.DryRun {
-		fs.Logf(fs.LogDirName(f, dir), "Not deleting as dry run is set")
+func TryRmdir(ctx context.Context, f fs.Fs, dir string) error {
+	if SkipDestructive(fs.LogDirName(f, dir), "remove directory") {
 		return nil
 	}
 	fs.Debugf(fs.LogDirName(f, dir), "Removing directory")
-	return f.Rmdir(dir)
+	return f.Rmdir(ctx, dir)
 }
 
 // Rmdir removes a container but not if not empty
-func Rmdir(f fs.Fs, dir string) error {
-	err := TryRmdir(f, dir)
+func Rmdir(ctx context.Context, f fs.Fs, dir string) error {
+	err := TryRmdir(ctx, f, dir)
 	if err != nil {
 		fs.CountError(err)
 		return err
@@ -955,17 +1499,15 @@ func Rmdir(f fs.Fs, dir string) error {
 }
 
 // Purge removes a directory and all of its contents
-func Purge(f fs.Fs, dir string) error {
+func Purge(ctx context.Context, f fs.Fs, dir string) error {
 	doFallbackPurge := true
 	var err error
 	if dir == "" {
 		// FIXME change the Purge interface so it takes a dir - see #1891
 		if doPurge := f.Features().Purge; doPurge != nil {
 			doFallbackPurge = false
-			if fs.Config.DryRun {
-				fs.Logf(f, "Not purging as --dry-run set")
-			} else {
-				err = doPurge()
+			if !SkipDestructive(f, "purge") {
+				err = doPurge(ctx)
 				if err == fs.ErrorCantPurge {
 					doFallbackPurge = true
 				}
@@ -974,11 +1516,11 @@ func Purge(f fs.Fs, dir string) error {
 	}
 	if doFallbackPurge {
 		// DeleteFiles and Rmdir observe --dry-run
-		err = DeleteFiles(listToChan(f, dir))
+		err = DeleteFiles(ctx, listToChan(ctx, f, dir))
 		if err != nil {
 			return err
 		}
-		err = Rmdirs(f, "", false)
+		err = Rmdirs(ctx, f, "", false)
 	}
 	if err != nil {
 		fs.CountError(err)
@@ -989,13 +1531,13 @@ func Purge(f fs.Fs, dir string) error {
 
 // Delete removes all the contents of a container.  Unlike Purge, it
 // obeys includes and excludes.
-func Delete(f fs.Fs) error {
+func Delete(ctx context.Context, f fs.Fs) error {
 	delete := make(fs.ObjectsChan, fs.Config.Transfers)
 	delErr := make(chan error, 1)
 	go func() {
-		delErr <- DeleteFiles(delete)
+		delErr <- DeleteFiles(ctx, delete)
 	}()
-	err := ListFn(f, func(o fs.Object) {
+	err := ListFn(ctx, f, func(o fs.Object) {
 		delete <- o
 	})
 	close(delete)
@@ -1007,7 +1549,7 @@ func Delete(f fs.Fs) error {
 }
 
 // dedupeRename renames the objs slice to different names
-func dedupeRename(remote string, objs []fs.Object) {
+func dedupeRename(ctx context.Context, remote string, objs []fs.Object) {
 	f := objs[0].Fs()
 	doMove := f.Features().Move
 	if doMove == nil {
@@ -1017,33 +1559,31 @@ func dedupeRename(remote string, objs []fs.Object) {
 	base := remote[:len(remote)-len(ext)]
 	for i, o := range objs {
 		newName := fmt.Sprintf("%s-%d%s", base, i+1, ext)
-		if !fs.Config.DryRun {
-			newObj, err := doMove(o, newName)
+		if !SkipDryRun(o, fmt.Sprintf("rename to %q", newName)) {
+			newObj, err := doMove(ctx, o, newName)
 			if err != nil {
 				fs.CountError(err)
 				fs.Errorf(o, "Failed to rename: %v", err)
 				continue
 			}
 			fs.Infof(newObj, "renamed from: %v", o)
-		} else {
-			fs.Logf(remote, "Not renaming to %q as --dry-run", newName)
 		}
 	}
 }
 
 // dedupeDeleteAllButOne deletes all but the one in keep
-func dedupeDeleteAllButOne(keep int, remote string, objs []fs.Object) {
+func dedupeDeleteAllButOne(ctx context.Context, keep int, remote string, objs []fs.Object) {
 	for i, o := range objs {
 		if i == keep {
 			continue
 		}
-		_ = DeleteFile(o)
+		_ = DeleteFile(ctx, o)
 	}
 	fs.Logf(remote, "Deleted %d extra copies", len(objs)-1)
 }
 
 // dedupeDeleteIdentical deletes all but one of identical (by hash) copies
-func dedupeDeleteIdentical(remote string, objs []fs.Object) []fs.Object {
+func dedupeDeleteIdentical(ctx context.Context, remote string, objs []fs.Object) []fs.Object {
 	// See how many of these duplicates are identical
 	byHash := make(map[string][]fs.Object, len(objs))
 	for _, o := range objs {
@@ -1059,7 +1599,7 @@ func dedupeDeleteIdentical(remote string, objs []fs.Object) []fs.Object {
 		if len(hashObjs) > 1 {
 			fs.Logf(remote, "Deleting %d/%d identical duplicates (md5sum %q)", len(hashObjs)-1, len(hashObjs), md5sum)
 			for _, o := range hashObjs[1:] {
-				_ = DeleteFile(o)
+				_ = DeleteFile(ctx, o)
 			}
 		}
 		objs = append(objs, hashObjs[0])
@@ -1069,7 +1609,7 @@ func dedupeDeleteIdentical(remote string, objs []fs.Object) []fs.Object {
 }
 
 // dedupeInteractive interactively dedupes the slice of objects
-func dedupeInteractive(remote string, objs []fs.Object) {
+func dedupeInteractive(ctx context.Context, remote string, objs []fs.Object) {
 	fmt.Printf("%s: %d duplicates remain\n", remote, len(objs))
 	for i, o := range objs {
 		md5sum, err := o.Hash(hash.MD5)
@@ -1082,9 +1622,9 @@ func dedupeInteractive(remote string, objs []fs.Object) {
 	case 's':
 	case 'k':
 		keep := config.ChooseNumber("Enter the number of the file to keep", 1, len(objs))
-		dedupeDeleteAllButOne(keep-1, remote, objs)
+		dedupeDeleteAllButOne(ctx, keep-1, remote, objs)
 	case 'r':
-		dedupeRename(remote, objs)
+		dedupeRename(ctx, remote, objs)
 	}
 }
 
@@ -1096,6 +1636,12 @@ func (objs objectsSortedByModTime) Less(i, j int) bool {
 	return objs[i].ModTime().Before(objs[j].ModTime())
 }
 
+type objectsSortedBySize []fs.Object
+
+func (objs objectsSortedBySize) Len() int           { return len(objs) }
+func (objs objectsSortedBySize) Swap(i, j int)      { objs[i], objs[j] = objs[j], objs[i] }
+func (objs objectsSortedBySize) Less(i, j int) bool { return objs[i].Size() < objs[j].Size() }
+
 // DeduplicateMode is how the dedupe command chooses what to do
 type DeduplicateMode int
 
@@ -1107,6 +1653,7 @@ const (
 	DeduplicateNewest                             // choose the newest object
 	DeduplicateOldest                             // choose the oldest object
 	DeduplicateRename                             // rename the objects
+	DeduplicateLargest                            // choose the largest object
 )
 
 func (x DeduplicateMode) String() string {
@@ -1123,6 +1670,8 @@ func (x DeduplicateMode) String() string {
 		return "oldest"
 	case DeduplicateRename:
 		return "rename"
+	case DeduplicateLargest:
+		return "largest"
 	}
 	return "unknown"
 }
@@ -1142,6 +1691,8 @@ func (x *DeduplicateMode) Set(s string) error {
 		*x = DeduplicateOldest
 	case "rename":
 		*x = DeduplicateRename
+	case "largest":
+		*x = DeduplicateLargest
 	default:
 		return errors.Errorf("Unknown mode for dedupe %q.", s)
 	}
@@ -1157,9 +1708,9 @@ func (x *DeduplicateMode) Type() string {
 var _ pflag.Value = (*DeduplicateMode)(nil)
 
 // dedupeFindDuplicateDirs scans f for duplicate directories
-func dedupeFindDuplicateDirs(f fs.Fs) ([][]fs.Directory, error) {
+func dedupeFindDuplicateDirs(ctx context.Context, f fs.Fs) ([][]fs.Directory, error) {
 	duplicateDirs := [][]fs.Directory{}
-	err := walk.Walk(f, "", true, fs.Config.MaxDepth, func(dirPath string, entries fs.DirEntries, err error) error {
+	err := walk.Walk(ctx, f, "", true, fs.Config.MaxDepth, func(dirPath string, entries fs.DirEntries, err error) error {
 		if err != nil {
 			return err
 		}
@@ -1181,7 +1732,7 @@ func dedupeFindDuplicateDirs(f fs.Fs) ([][]fs.Directory, error) {
 }
 
 // dedupeMergeDuplicateDirs merges all the duplicate directories found
-func dedupeMergeDuplicateDirs(f fs.Fs, duplicateDirs [][]fs.Directory) error {
+func dedupeMergeDuplicateDirs(ctx context.Context, f fs.Fs, duplicateDirs [][]fs.Directory) error {
 	mergeDirs := f.Features().MergeDirs
 	if mergeDirs == nil {
 		return errors.Errorf("%v: can't merge directories", f)
@@ -1191,14 +1742,12 @@ func dedupeMergeDuplicateDirs(f fs.Fs, duplicateDirs [][]fs.Directory) error {
 		return errors.Errorf("%v: can't flush dir cache", f)
 	}
 	for _, dirs := range duplicateDirs {
-		if !fs.Config.DryRun {
+		if !SkipDryRun(dirs[0], "merge contents of duplicate directories") {
 			fs.Infof(dirs[0], "Merging contents of duplicate directories")
-			err := mergeDirs(dirs)
+			err := mergeDirs(ctx, dirs)
 			if err != nil {
 				return errors.Wrap(err, "merge duplicate dirs")
 			}
-		} else {
-			fs.Infof(dirs[0], "NOT Merging contents of duplicate directories as --dry-run")
 		}
 	}
 	dirCacheFlush()
@@ -1208,20 +1757,20 @@ func dedupeMergeDuplicateDirs(f fs.Fs, duplicateDirs [][]fs.Directory) error {
 // Deduplicate interactively finds duplicate files and offers to
 // delete all but one or rename them to be different. Only useful with
 // Google Drive which can have duplicate file names.
-func Deduplicate(f fs.Fs, mode DeduplicateMode) error {
+func Deduplicate(ctx context.Context, f fs.Fs, mode DeduplicateMode) error {
 	fs.Infof(f, "Looking for duplicates using %v mode.", mode)
 
 	// Find duplicate directories first and fix them - repeat
 	// until all fixed
 	for {
-		duplicateDirs, err := dedupeFindDuplicateDirs(f)
+		duplicateDirs, err := dedupeFindDuplicateDirs(ctx, f)
 		if err != nil {
 			return err
 		}
 		if len(duplicateDirs) == 0 {
 			break
 		}
-		err = dedupeMergeDuplicateDirs(f, duplicateDirs)
+		err = dedupeMergeDuplicateDirs(ctx, f, duplicateDirs)
 		if err != nil {
 			return err
 		}
@@ -1232,7 +1781,7 @@ func Deduplicate(f fs.Fs, mode DeduplicateMode) error {
 
 	// Now find duplicate files
 	files := map[string][]fs.Object{}
-	err := walk.Walk(f, "", true, fs.Config.MaxDepth, func(dirPath string, entries fs.DirEntries, err error) error {
+	err := walk.Walk(ctx, f, "", true, fs.Config.MaxDepth, func(dirPath string, entries fs.DirEntries, err error) error {
 		if err != nil {
 			return err
 		}
@@ -1248,24 +1797,27 @@ func Deduplicate(f fs.Fs, mode DeduplicateMode) error {
 	for remote, objs := range files {
 		if len(objs) > 1 {
 			fs.Logf(remote, "Found %d duplicates - deleting identical copies", len(objs))
-			objs = dedupeDeleteIdentical(remote, objs)
+			objs = dedupeDeleteIdentical(ctx, remote, objs)
 			if len(objs) <= 1 {
 				fs.Logf(remote, "All duplicates removed")
 				continue
 			}
 			switch mode {
 			case DeduplicateInteractive:
-				dedupeInteractive(remote, objs)
+				dedupeInteractive(ctx, remote, objs)
 			case DeduplicateFirst:
-				dedupeDeleteAllButOne(0, remote, objs)
+				dedupeDeleteAllButOne(ctx, 0, remote, objs)
 			case DeduplicateNewest:
 				sort.Sort(objectsSortedByModTime(objs)) // sort oldest first
-				dedupeDeleteAllButOne(len(objs)-1, remote, objs)
+				dedupeDeleteAllButOne(ctx, len(objs)-1, remote, objs)
 			case DeduplicateOldest:
 				sort.Sort(objectsSortedByModTime(objs)) // sort oldest first
-				dedupeDeleteAllButOne(0, remote, objs)
+				dedupeDeleteAllButOne(ctx, 0, remote, objs)
+			case DeduplicateLargest:
+				sort.Sort(objectsSortedBySize(objs)) // sort smallest first
+				dedupeDeleteAllButOne(ctx, len(objs)-1, remote, objs)
 			case DeduplicateRename:
-				dedupeRename(remote, objs)
+				dedupeRename(ctx, remote, objs)
 			case DeduplicateSkip:
 				// skip
 			default:
@@ -1282,11 +1834,11 @@ func Deduplicate(f fs.Fs, mode DeduplicateMode) error {
 // channel.
 //
 // If the error was ErrorDirNotFound then it will be ignored
-func listToChan(f fs.Fs, dir string) fs.ObjectsChan {
+func listToChan(ctx context.Context, f fs.Fs, dir string) fs.ObjectsChan {
 	o := make(fs.ObjectsChan, fs.Config.Checkers)
 	go func() {
 		defer close(o)
-		_ = walk.Walk(f, dir, true, fs.Config.MaxDepth, func(dirPath string, entries fs.DirEntries, err error) error {
+		_ = walk.Walk(ctx, f, dir, true, fs.Config.MaxDepth, func(dirPath string, entries fs.DirEntries, err error) error {
 			if err != nil {
 				if err == fs.ErrorDirNotFound {
 					return nil
@@ -1306,16 +1858,24 @@ func listToChan(f fs.Fs, dir string) fs.ObjectsChan {
 }
 
 // CleanUp removes the trash for the Fs
-func CleanUp(f fs.Fs) error {
+func CleanUp(ctx context.Context, f fs.Fs) error {
 	doCleanUp := f.Features().CleanUp
 	if doCleanUp == nil {
 		return errors.Errorf("%v doesn't support cleanup", f)
 	}
-	if fs.Config.DryRun {
-		fs.Logf(f, "Not running cleanup as --dry-run set")
+	if SkipDestructive(f, "clean up") {
 		return nil
 	}
-	return doCleanUp()
+	return doCleanUp(ctx)
+}
+
+// PublicLink adds a "readable by anyone with link" permission on the given file or folder.
+func PublicLink(ctx context.Context, f fs.Fs, remote string, expire time.Duration) (string, error) {
+	doPublicLink := f.Features().PublicLink
+	if doPublicLink == nil {
+		return "", errors.Errorf("%v doesn't support public links", f)
+	}
+	return doPublicLink(ctx, remote, expire)
 }
 
 // wrap a Reader and a Closer together into a ReadCloser
@@ -1332,9 +1892,9 @@ type readCloser struct {
 //
 // if count < 0 then it will be ignored
 // if count >= 0 then only that many characters will be output
-func Cat(f fs.Fs, w io.Writer, offset, count int64) error {
+func Cat(ctx context.Context, f fs.Fs, w io.Writer, offset, count int64) error {
 	var mu sync.Mutex
-	return ListFn(f, func(o fs.Object) {
+	return ListFn(ctx, f, func(o fs.Object) {
 		var err error
 		accounting.Stats.Transferring(o.Remote())
 		defer func() {
@@ -1348,10 +1908,14 @@ func Cat(f fs.Fs, w io.Writer, offset, count int64) error {
 		// size remaining is now reduced by thisOffset
 		size -= thisOffset
 		var options []fs.OpenOption
-		if thisOffset > 0 {
-			options = append(options, &fs.SeekOption{Offset: thisOffset})
+		if thisOffset > 0 || count > 0 {
+			rangeOption := &fs.RangeOption{Start: thisOffset, End: -1}
+			if count > 0 {
+				rangeOption.End = thisOffset + count - 1
+			}
+			options = append(options, rangeOption)
 		}
-		in, err := o.Open(options...)
+		in, err := o.Open(ctx, options...)
 		if err != nil {
 			fs.CountError(err)
 			fs.Errorf(o, "Failed to open: %v", err)
@@ -1364,7 +1928,7 @@ func Cat(f fs.Fs, w io.Writer, offset, count int64) error {
 				size = count
 			}
 		}
-		in = accounting.NewAccountSizeName(in, size, o.Remote()).WithBuffer() // account and buffer the transfer
+		in = accounting.NewAccountSizeName(in, size, o.Remote()).WithLimits(o.Fs(), nil).WithBuffer() // account and buffer the transfer
 		defer func() {
 			err = in.Close()
 			if err != nil {
@@ -1384,9 +1948,9 @@ func Cat(f fs.Fs, w io.Writer, offset, count int64) error {
 }
 
 // Rcat reads data from the Reader until EOF and uploads it to a file on remote
-func Rcat(fdst fs.Fs, dstFileName string, in io.ReadCloser, modTime time.Time) (dst fs.Object, err error) {
+func Rcat(ctx context.Context, fdst fs.Fs, dstFileName string, in io.ReadCloser, modTime time.Time) (dst fs.Object, err error) {
 	accounting.Stats.Transferring(dstFileName)
-	in = accounting.NewAccountSizeName(in, -1, dstFileName).WithBuffer()
+	in = accounting.NewAccountSizeName(in, -1, dstFileName).WithLimits(nil, fdst).WithBuffer()
 	defer func() {
 		accounting.Stats.DoneTransferring(dstFileName, err == nil)
 		if otherErr := in.Close(); otherErr != nil {
@@ -1394,6 +1958,19 @@ func Rcat(fdst fs.Fs, dstFileName string, in io.ReadCloser, modTime time.Time) (
 		}
 	}()
 
+	// Check --dry-run and --interactive before reading any data
+	var skip bool
+	if existing, err := fdst.NewObject(ctx, dstFileName); err == nil {
+		skip = SkipDestructive(existing, "overwrite")
+	} else {
+		skip = SkipDryRun(dstFileName, "upload")
+	}
+	if skip {
+		// prevents "broken pipe" errors
+		_, err = io.Copy(ioutil.Discard, in)
+		return nil, err
+	}
+
 	hashOption := &fs.HashesOption{Hashes: fdst.Hashes()}
 	hash, err := hash.NewMultiHasherTypes(fdst.Hashes())
 	if err != nil {
@@ -1404,7 +1981,7 @@ func Rcat(fdst fs.Fs, dstFileName string, in io.ReadCloser, modTime time.Time) (
 
 	compare := func(dst fs.Object) error {
 		src := object.NewStaticObjectInfo(dstFileName, modTime, int64(readCounter.BytesRead()), false, hash.Sums(), fdst)
-		if !Equal(src, dst) {
+		if !Equal(ctx, src, dst) {
 			err = errors.Errorf("corrupted on transfer")
 			fs.CountError(err)
 			fs.Errorf(dst, "%v", err)
@@ -1418,7 +1995,7 @@ func Rcat(fdst fs.Fs, dstFileName string, in io.ReadCloser, modTime time.Time) (
 	if n, err := io.ReadFull(trackingIn, buf); err == io.EOF || err == io.ErrUnexpectedEOF {
 		fs.Debugf(fdst, "File to upload is small (%d bytes), uploading instead of streaming", n)
 		src := object.NewMemoryObject(dstFileName, modTime, buf[:n])
-		return Copy(fdst, nil, dstFileName, src)
+		return Copy(ctx, fdst, nil, dstFileName, src)
 	}
 
 	// Make a new ReadCloser with the bits we've already read
@@ -1429,6 +2006,19 @@ func Rcat(fdst fs.Fs, dstFileName string, in io.ReadCloser, modTime time.Time) (
 
 	fStreamTo := fdst
 	canStream := fdst.Features().PutStream != nil
+	if !canStream && fs.Config.StreamingBufferSize > 0 {
+		// Buffer the file in memory rather than spooling it to disk
+		data, err := ioutil.ReadAll(io.LimitReader(in, int64(fs.Config.StreamingBufferSize)+1))
+		if err != nil {
+			return nil, errors.Wrap(err, "failed to read file into streaming buffer")
+		}
+		if int64(len(data)) > int64(fs.Config.StreamingBufferSize) {
+			return nil, errors.Errorf("file is bigger than --streaming-buffer-size %v and the target remote doesn't support streaming uploads", fs.Config.StreamingBufferSize)
+		}
+		fs.Debugf(fdst, "Target remote doesn't support streaming uploads, buffering file in memory (%d bytes)", len(data))
+		src := object.NewMemoryObject(dstFileName, modTime, data)
+		return Copy(ctx, fdst, nil, dstFileName, src)
+	}
 	if !canStream {
 		fs.Debugf(fdst, "Target remote doesn't support streaming uploads, creating temporary local FS to spool file")
 		tmpLocalFs, err := fs.TemporaryLocalFs()
@@ -1436,7 +2026,8 @@ func Rcat(fdst fs.Fs, dstFileName string, in io.ReadCloser, modTime time.Time) (
 			return nil, errors.Wrap(err, "Failed to create temporary local FS to spool file")
 		}
 		defer func() {
-			err := Purge(tmpLocalFs, "")
+			// Purge directly so this isn't affected by --interactive
+			err := tmpLocalFs.Features().Purge(ctx)
 			if err != nil {
 				fs.Infof(tmpLocalFs, "Failed to cleanup temporary FS: %v", err)
 			}
@@ -1444,15 +2035,8 @@ func Rcat(fdst fs.Fs, dstFileName string, in io.ReadCloser, modTime time.Time) (
 		fStreamTo = tmpLocalFs
 	}
 
-	if fs.Config.DryRun {
-		fs.Logf("stdin", "Not uploading as --dry-run")
-		// prevents "broken pipe" errors
-		_, err = io.Copy(ioutil.Discard, in)
-		return nil, err
-	}
-
 	objInfo := object.NewStaticObjectInfo(dstFileName, modTime, -1, false, nil, nil)
-	if dst, err = fStreamTo.Features().PutStream(in, objInfo, hashOption); err != nil {
+	if dst, err = fStreamTo.Features().PutStream(ctx, in, objInfo, uploadHeaderOptions(hashOption)...); err != nil {
 		return dst, err
 	}
 	if err = compare(dst); err != nil {
@@ -1460,17 +2044,138 @@ func Rcat(fdst fs.Fs, dstFileName string, in io.ReadCloser, modTime time.Time) (
 	}
 	if !canStream {
 		// copy dst (which is the local object we have just streamed to) to the remote
-		return Copy(fdst, nil, dstFileName, dst)
+		return Copy(ctx, fdst, nil, dstFileName, dst)
 	}
 	return dst, nil
 }
 
+// RcatSize reads data from the Reader until EOF and uploads it to a
+// file on remote.
+//
+// If size >= 0 then it is the expected size of the data which lets it
+// be uploaded with Put directly without needing to spool it first.
+// If size < 0 then this is the same as Rcat.
+func RcatSize(ctx context.Context, fdst fs.Fs, dstFileName string, in io.ReadCloser, size int64, modTime time.Time) (dst fs.Object, err error) {
+	if size < 0 {
+		return Rcat(ctx, fdst, dstFileName, in, modTime)
+	}
+	accounting.Stats.Transferring(dstFileName)
+	in = accounting.NewAccountSizeName(in, size, dstFileName).WithLimits(nil, fdst) // account the transfer (no buffering)
+	defer func() {
+		accounting.Stats.DoneTransferring(dstFileName, err == nil)
+		if otherErr := in.Close(); otherErr != nil {
+			fs.Debugf(fdst, "RcatSize: failed to close source: %v", otherErr)
+		}
+	}()
+
+	// Check --dry-run and --interactive before reading any data
+	var skip bool
+	if existing, err := fdst.NewObject(ctx, dstFileName); err == nil {
+		skip = SkipDestructive(existing, "overwrite")
+	} else {
+		skip = SkipDryRun(dstFileName, "upload")
+	}
+	if skip {
+		// prevents "broken pipe" errors
+		_, err = io.Copy(ioutil.Discard, in)
+		return nil, err
+	}
+
+	readCounter := readers.NewCountingReader(in)
+	src := object.NewStaticObjectInfo(dstFileName, modTime, size, true, nil, fdst)
+	dst, err = fdst.Put(ctx, readCounter, src, uploadHeaderOptions()...)
+	if err != nil {
+		fs.CountError(err)
+		fs.Errorf(dstFileName, "Failed to upload: %v", err)
+		return dst, err
+	}
+	if n := int64(readCounter.BytesRead()); n != size {
+		err = errors.Errorf("corrupted on transfer: expected %d bytes but read %d", size, n)
+		fs.CountError(err)
+		fs.Errorf(dst, "%v", err)
+		if removeErr := dst.Remove(ctx); removeErr != nil {
+			fs.Errorf(dst, "Failed to remove corrupted object: %v", removeErr)
+		}
+		return nil, err
+	}
+	return dst, nil
+}
+
+// getURL starts fetching url returning the response which the caller
+// must close
+func getURL(url string) (*http.Response, error) {
+	resp, err := fshttp.NewClient(fs.Config).Get(url)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		_ = resp.Body.Close()
+		return nil, errors.Errorf("CopyURL failed: %s", resp.Status)
+	}
+	return resp, nil
+}
+
+// urlFilename works out the name of the file being downloaded in resp
+// from the Content-Disposition header or failing that the URL.
+func urlFilename(resp *http.Response) (string, error) {
+	if _, params, err := mime.ParseMediaType(resp.Header.Get("Content-Disposition")); err == nil {
+		if name := path.Base(params["filename"]); name != "." && name != "/" && name != ".." {
+			return name, nil
+		}
+	}
+	name := path.Base(resp.Request.URL.Path)
+	if name == "." || name == "/" || name == ".." {
+		return "", errors.Errorf("CopyURL can't work out a file name from %q", resp.Request.URL.String())
+	}
+	return name, nil
+}
+
+// CopyURL copies the data from the url to (fdst, dstFileName)
+//
+// The data is streamed straight to the remote.  If autoFilename is
+// set then dstFileName is ignored and the name is taken from the
+// Content-Disposition header or the end of the URL.
+func CopyURL(ctx context.Context, fdst fs.Fs, dstFileName string, url string, autoFilename bool) (dst fs.Object, err error) {
+	resp, err := getURL(url)
+	if err != nil {
+		return nil, err
+	}
+	if autoFilename {
+		dstFileName, err = urlFilename(resp)
+		if err != nil {
+			_ = resp.Body.Close()
+			return nil, err
+		}
+	}
+	modTime := time.Now()
+	if lastModified, err := http.ParseTime(resp.Header.Get("Last-Modified")); err == nil {
+		modTime = lastModified
+	}
+	return RcatSize(ctx, fdst, dstFileName, resp.Body, resp.ContentLength, modTime)
+}
+
+// CopyURLToWriter copies the data from the url to out
+func CopyURLToWriter(ctx context.Context, url string, out io.Writer) (err error) {
+	resp, err := getURL(url)
+	if err != nil {
+		return err
+	}
+	in := accounting.NewAccountSizeName(resp.Body, resp.ContentLength, url) // closes resp.Body
+	defer fs.CheckClose(in, &err)
+	accounting.Stats.Transferring(url)
+	defer func() {
+		accounting.Stats.DoneTransferring(url, err == nil)
+	}()
+	_, err = io.Copy(out, in)
+	return err
+}
+
 // Rmdirs removes any empty directories (or directories only
 // containing empty directories) under f, including f.
-func Rmdirs(f fs.Fs, dir string, leaveRoot bool) error {
+func Rmdirs(ctx context.Context, f fs.Fs, dir string, leaveRoot bool) error {
 	dirEmpty := make(map[string]bool)
 	dirEmpty[""] = !leaveRoot
-	err := walk.Walk(f, dir, true, fs.Config.MaxDepth, func(dirPath string, entries fs.DirEntries, err error) error {
+	err := walk.Walk(ctx, f, dir, true, fs.Config.MaxDepth, func(dirPath string, entries fs.DirEntries, err error) error {
 		if err != nil {
 			fs.CountError(err)
 			fs.Errorf(f, "Failed to list %q: %v", dirPath, err)
@@ -1517,7 +2222,7 @@ func Rmdirs(f fs.Fs, dir string, leaveRoot bool) error {
 	sort.Strings(toDelete)
 	for i := len(toDelete) - 1; i >= 0; i-- {
 		dir := toDelete[i]
-		err := TryRmdir(f, dir)
+		err := TryRmdir(ctx, f, dir)
 		if err != nil {
 			fs.CountError(err)
 			fs.Errorf(dir, "Failed to rmdir: %v", err)
@@ -1532,7 +2237,7 @@ func Rmdirs(f fs.Fs, dir string, leaveRoot bool) error {
 //
 // Returns a flag which indicates whether the file needs to be
 // transferred or not.
-func NeedTransfer(dst, src fs.Object) bool {
+func NeedTransfer(ctx context.Context, dst, src fs.Object) bool {
 	if dst == nil {
 		fs.Debugf(src, "Couldn't find file - need to transfer")
 		return true
@@ -1575,7 +2280,7 @@ func NeedTransfer(dst, src fs.Object) bool {
 		}
 	} else {
 		// Check to see if changed or not
-		if Equal(src, dst) {
+		if Equal(ctx, src, dst) {
 			fs.Debugf(src, "Unchanged skipping")
 			return false
 		}
@@ -1584,7 +2289,7 @@ func NeedTransfer(dst, src fs.Object) bool {
 }
 
 // moveOrCopyFile moves or copies a single file possibly to a new name
-func moveOrCopyFile(fdst fs.Fs, fsrc fs.Fs, dstFileName string, srcFileName string, cp bool) (err error) {
+func moveOrCopyFile(ctx context.Context, fdst fs.Fs, fsrc fs.Fs, dstFileName string, srcFileName string, cp bool) (err error) {
 	dstFilePath := path.Join(fdst.Root(), dstFileName)
 	srcFilePath := path.Join(fsrc.Root(), srcFileName)
 	if fdst.Name() == fsrc.Name() && dstFilePath == srcFilePath {
@@ -1599,27 +2304,30 @@ func moveOrCopyFile(fdst fs.Fs, fsrc fs.Fs, dstFileName string, srcFileName stri
 	}
 
 	// Find src object
-	srcObj, err := fsrc.NewObject(srcFileName)
+	srcObj, err := fsrc.NewObject(ctx, srcFileName)
 	if err != nil {
 		return err
 	}
 
-	// Find dst object if it exists
-	dstObj, err := fdst.NewObject(dstFileName)
-	if err == fs.ErrorObjectNotFound {
-		dstObj = nil
-	} else if err != nil {
-		return err
+	// Find dst object if it exists, unless told not to look
+	var dstObj fs.Object
+	if !fs.Config.NoCheckDest {
+		dstObj, err = fdst.NewObject(ctx, dstFileName)
+		if err == fs.ErrorObjectNotFound {
+			dstObj = nil
+		} else if err != nil {
+			return err
+		}
 	}
 
-	if NeedTransfer(dstObj, srcObj) {
+	if NeedTransfer(ctx, dstObj, srcObj) {
 		accounting.Stats.Transferring(srcFileName)
-		_, err = Op(fdst, dstObj, dstFileName, srcObj)
+		_, err = Op(ctx, fdst, dstObj, dstFileName, srcObj)
 		accounting.Stats.DoneTransferring(srcFileName, err == nil)
 	} else {
 		accounting.Stats.Checking(srcFileName)
 		if !cp {
-			err = DeleteFile(srcObj)
+			err = DeleteFile(ctx, srcObj)
 		}
 		defer accounting.Stats.DoneChecking(srcFileName)
 	}
@@ -1627,13 +2335,13 @@ func moveOrCopyFile(fdst fs.Fs, fsrc fs.Fs, dstFileName string, srcFileName stri
 }
 
 // MoveFile moves a single file possibly to a new name
-func MoveFile(fdst fs.Fs, fsrc fs.Fs, dstFileName string, srcFileName string) (err error) {
-	return moveOrCopyFile(fdst, fsrc, dstFileName, srcFileName, false)
+func MoveFile(ctx context.Context, fdst fs.Fs, fsrc fs.Fs, dstFileName string, srcFileName string) (err error) {
+	return moveOrCopyFile(ctx, fdst, fsrc, dstFileName, srcFileName, false)
 }
 
 // CopyFile moves a single file possibly to a new name
-func CopyFile(fdst fs.Fs, fsrc fs.Fs, dstFileName string, srcFileName string) (err error) {
-	return moveOrCopyFile(fdst, fsrc, dstFileName, srcFileName, true)
+func CopyFile(ctx context.Context, fdst fs.Fs, fsrc fs.Fs, dstFileName string, srcFileName string) (err error) {
+	return moveOrCopyFile(ctx, fdst, fsrc, dstFileName, srcFileName, true)
 }
 
 // ListFormat defines files information print format
@@ -1643,6 +2351,8 @@ type ListFormat struct {
 	output    []func() string
 	entry     fs.DirEntry
 	hash      bool
+	csv       *csv.Writer
+	buf       bytes.Buffer
 }
 
 // SetSeparator changes separator in struct
@@ -1655,6 +2365,26 @@ func (l *ListFormat) SetDirSlash(dirSlash bool) {
 	l.dirSlash = dirSlash
 }
 
+// SetCSV defines if the output should be csv
+//
+// Note that you should call SetSeparator before this if you want a
+// custom separator - it must be a single character
+func (l *ListFormat) SetCSV(useCSV bool) error {
+	if !useCSV {
+		l.csv = nil
+		return nil
+	}
+	l.csv = csv.NewWriter(&l.buf)
+	if l.separator != "" {
+		separator := []rune(l.separator)
+		if len(separator) != 1 {
+			return errors.Errorf("separator %q must be a single character for CSV output", l.separator)
+		}
+		l.csv.Comma = separator[0]
+	}
+	return nil
+}
+
 // SetOutput sets functions used to create files information
 func (l *ListFormat) SetOutput(output []func() string) {
 	l.output = output
@@ -1695,20 +2425,49 @@ func (l *ListFormat) AddHash(ht hash.Type) {
 	})
 }
 
+// AddID adds file's ID to the output if known
+func (l *ListFormat) AddID() {
+	l.AppendOutput(func() string {
+		switch x := l.entry.(type) {
+		case fs.Directory:
+			return x.ID()
+		case fs.IDer:
+			return x.ID()
+		}
+		return ""
+	})
+}
+
+// AddMimeType adds file's MimeType to the output
+func (l *ListFormat) AddMimeType() {
+	l.AppendOutput(func() string {
+		switch x := l.entry.(type) {
+		case fs.Directory:
+			return "inode/directory"
+		case fs.Object:
+			return fs.MimeType(x)
+		}
+		return ""
+	})
+}
+
 // AppendOutput adds string generated by specific function to printed output
 func (l *ListFormat) AppendOutput(functionToAppend func() string) {
-	if len(l.output) > 0 {
-		l.output = append(l.output, func() string { return l.separator })
-	}
 	l.output = append(l.output, functionToAppend)
 }
 
 // ListFormatted prints information about specific file in specific format
 func ListFormatted(entry *fs.DirEntry, list *ListFormat) string {
 	list.entry = *entry
-	var out string
-	for _, fun := range list.output {
-		out += fun()
+	out := make([]string, len(list.output))
+	for i, fun := range list.output {
+		out[i] = fun()
+	}
+	if list.csv != nil {
+		list.buf.Reset()
+		_ = list.csv.Write(out)
+		list.csv.Flush()
+		return strings.TrimRight(list.buf.String(), "\r\n")
 	}
-	return out
+	return strings.Join(out, list.separator)
 }
@@ -38,3 +38,29 @@ func TestSizeDiffers(t *testing.T) {
 		assert.Equal(t, test.want, got, fmt.Sprintf("ignoreSize=%v, srcSize=%v, dstSize=%v", test.ignoreSize, test.srcSize, test.dstSize))
 	}
 }
+
+func TestSkipDestructive(t *testing.T) {
+	oldDryRun, oldInteractive := fs.Config.DryRun, fs.Config.Interactive
+	defer func() {
+		fs.Config.DryRun, fs.Config.Interactive = oldDryRun, oldInteractive
+		interactiveAll = map[string]bool{}
+	}()
+
+	fs.Config.DryRun, fs.Config.Interactive = false, false
+	assert.False(t, SkipDestructive("file", "delete"))
+	assert.False(t, SkipDryRun("file", "copy"))
+
+	fs.Config.DryRun = true
+	assert.True(t, SkipDestructive("file", "delete"))
+	assert.True(t, SkipDryRun("file", "copy"))
+
+	// --dry-run wins over --interactive so no prompt is shown
+	fs.Config.Interactive = true
+	assert.True(t, SkipDestructive("file", "delete"))
+
+	// Interactive with "yes to all" already given doesn't prompt
+	fs.Config.DryRun = false
+	interactiveAll["delete"] = true
+	assert.False(t, SkipDestructive("file", "delete"))
+	assert.False(t, SkipDryRun("file", "copy"))
+}
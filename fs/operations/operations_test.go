@@ -25,6 +25,8 @@ import (
 	"fmt"
 	"io"
 	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
 	"regexp"
 	"strings"
 	"testing"
@@ -34,6 +36,7 @@ import (
 	"github.com/ncw/rclone/fs"
 	"github.com/ncw/rclone/fs/accounting"
 	"github.com/ncw/rclone/fs/filter"
+	"github.com/ncw/rclone/fs/fserrors"
 	"github.com/ncw/rclone/fs/hash"
 	"github.com/ncw/rclone/fs/list"
 	"github.com/ncw/rclone/fs/operations"
@@ -41,6 +44,7 @@ import (
 	"github.com/ncw/rclone/fstest"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
+	"golang.org/x/net/context"
 )
 
 // Some times used in the tests
@@ -56,18 +60,70 @@ func TestMain(m *testing.M) {
 }
 
 func TestMkdir(t *testing.T) {
+	ctx := context.Background()
 	r := fstest.NewRun(t)
 	defer r.Finalise()
 
-	err := operations.Mkdir(r.Fremote, "")
+	err := operations.Mkdir(ctx, r.Fremote, "")
 	require.NoError(t, err)
 	fstest.CheckListing(t, r.Fremote, []fstest.Item{})
 
-	err = operations.Mkdir(r.Fremote, "")
+	err = operations.Mkdir(ctx, r.Fremote, "")
 	require.NoError(t, err)
 }
 
+func TestMkdirAll(t *testing.T) {
+	ctx := context.Background()
+	r := fstest.NewRun(t)
+	defer r.Finalise()
+
+	err := operations.MkdirAll(ctx, r.Fremote, "a/b//c/")
+	require.NoError(t, err)
+	fstest.CheckListingWithPrecision(t, r.Fremote, []fstest.Item{}, []string{"a", "a/b", "a/b/c"}, fs.Config.ModifyWindow)
+
+	err = operations.MkdirAll(ctx, r.Fremote, "a/b/c")
+	require.NoError(t, err)
+}
+
+// cantSetModTimeObject is an fs.Object which returns err from SetModTime
+type cantSetModTimeObject struct {
+	fs.Object
+	err error
+}
+
+func (o cantSetModTimeObject) SetModTime(ctx context.Context, t time.Time) error {
+	return o.err
+}
+
+func TestSetModTime(t *testing.T) {
+	ctx := context.Background()
+	r := fstest.NewRun(t)
+	defer r.Finalise()
+	file1 := r.WriteObject("file1", "file1 contents", t1)
+	fstest.CheckItems(t, r.Fremote, file1)
+
+	for _, test := range []struct {
+		err     error
+		modTime time.Time
+	}{
+		{nil, t2},
+		{fs.ErrorCantSetModTime, t3},
+		{fs.ErrorCantSetModTimeWithoutDelete, t1},
+	} {
+		o, err := r.Fremote.NewObject(ctx, file1.Path)
+		require.NoError(t, err)
+		if test.err != nil {
+			o = cantSetModTimeObject{Object: o, err: test.err}
+		}
+		err = operations.SetModTime(ctx, o, test.modTime)
+		require.NoError(t, err, fmt.Sprintf("SetModTime error %v", test.err))
+		file1.ModTime = test.modTime
+		fstest.CheckItems(t, r.Fremote, file1)
+	}
+}
+
 func TestLsd(t *testing.T) {
+	ctx := context.Background()
 	r := fstest.NewRun(t)
 	defer r.Finalise()
 	file1 := r.WriteObject("sub dir/hello world", "hello world", t1)
@@ -75,13 +131,14 @@ func TestLsd(t *testing.T) {
 	fstest.CheckItems(t, r.Fremote, file1)
 
 	var buf bytes.Buffer
-	err := operations.ListDir(r.Fremote, &buf)
+	err := operations.ListDir(ctx, r.Fremote, &buf)
 	require.NoError(t, err)
 	res := buf.String()
 	assert.Contains(t, res, "sub dir\n")
 }
 
 func TestLs(t *testing.T) {
+	ctx := context.Background()
 	r := fstest.NewRun(t)
 	defer r.Finalise()
 	file1 := r.WriteBoth("potato2", "------------------------------------------------------------", t1)
@@ -90,7 +147,7 @@ func TestLs(t *testing.T) {
 	fstest.CheckItems(t, r.Fremote, file1, file2)
 
 	var buf bytes.Buffer
-	err := operations.List(r.Fremote, &buf)
+	err := operations.List(ctx, r.Fremote, &buf)
 	require.NoError(t, err)
 	res := buf.String()
 	assert.Contains(t, res, "        0 empty space\n")
@@ -98,6 +155,7 @@ func TestLs(t *testing.T) {
 }
 
 func TestLsLong(t *testing.T) {
+	ctx := context.Background()
 	r := fstest.NewRun(t)
 	defer r.Finalise()
 	file1 := r.WriteBoth("potato2", "------------------------------------------------------------", t1)
@@ -106,7 +164,7 @@ func TestLsLong(t *testing.T) {
 	fstest.CheckItems(t, r.Fremote, file1, file2)
 
 	var buf bytes.Buffer
-	err := operations.ListLong(r.Fremote, &buf)
+	err := operations.ListLong(ctx, r.Fremote, &buf)
 	require.NoError(t, err)
 	res := buf.String()
 	lines := strings.Split(strings.Trim(res, "\n"), "\n")
@@ -143,6 +201,7 @@ func TestLsLong(t *testing.T) {
 }
 
 func TestHashSums(t *testing.T) {
+	ctx := context.Background()
 	r := fstest.NewRun(t)
 	defer r.Finalise()
 	file1 := r.WriteBoth("potato2", "------------------------------------------------------------", t1)
@@ -153,7 +212,7 @@ func TestHashSums(t *testing.T) {
 	// MD5 Sum
 
 	var buf bytes.Buffer
-	err := operations.Md5sum(r.Fremote, &buf)
+	err := operations.Md5sum(ctx, r.Fremote, &buf)
 	require.NoError(t, err)
 	res := buf.String()
 	if !strings.Contains(res, "d41d8cd98f00b204e9800998ecf8427e  empty space\n") &&
@@ -170,7 +229,7 @@ func TestHashSums(t *testing.T) {
 	// SHA1 Sum
 
 	buf.Reset()
-	err = operations.Sha1sum(r.Fremote, &buf)
+	err = operations.Sha1sum(ctx, r.Fremote, &buf)
 	require.NoError(t, err)
 	res = buf.String()
 	if !strings.Contains(res, "da39a3ee5e6b4b0d3255bfef95601890afd80709  empty space\n") &&
@@ -187,7 +246,7 @@ func TestHashSums(t *testing.T) {
 	// Dropbox Hash Sum
 
 	buf.Reset()
-	err = operations.DropboxHashSum(r.Fremote, &buf)
+	err = operations.DropboxHashSum(ctx, r.Fremote, &buf)
 	require.NoError(t, err)
 	res = buf.String()
 	if !strings.Contains(res, "e3b0c44298fc1c149afbf4c8996fb92427ae41e4649b934ca495991b7852b855  empty space\n") &&
@@ -202,7 +261,51 @@ func TestHashSums(t *testing.T) {
 	}
 }
 
+func TestHashListerBase64(t *testing.T) {
+	ctx := context.Background()
+	r := fstest.NewRun(t)
+	defer r.Finalise()
+	file1 := r.WriteBoth("potato2", "------------------------------------------------------------", t1)
+	fstest.CheckItems(t, r.Fremote, file1)
+
+	var buf bytes.Buffer
+	err := operations.HashLister(ctx, hash.MD5, true, r.Fremote, &buf)
+	require.NoError(t, err)
+	assert.Equal(t, "1lSLFW6mik4APnht-Z7udg==  potato2\n", buf.String())
+}
+
+func TestCheckSum(t *testing.T) {
+	ctx := context.Background()
+	r := fstest.NewRun(t)
+	defer r.Finalise()
+	file1 := r.WriteBoth("potato2", "------------------------------------------------------------", t1)
+	file2 := r.WriteBoth("empty space", "", t2)
+	fstest.CheckItems(t, r.Fremote, file1, file2)
+
+	check := func(sums string, wantErr bool) {
+		err := operations.CheckSum(ctx, r.Fremote, hash.MD5, strings.NewReader(sums))
+		if wantErr {
+			assert.Error(t, err, sums)
+		} else {
+			assert.NoError(t, err, sums)
+		}
+	}
+
+	// hex and base64 in md5sum format
+	check("d6548b156ea68a4e003e786df99eee76  potato2\nd41d8cd98f00b204e9800998ecf8427e  empty space\n", false)
+	check("1lSLFW6mik4APnht-Z7udg==  potato2\n1B2M2Y8AsgTpgAmY7PhCfg== *empty space\n", false)
+	// wrong hash
+	check("d6548b156ea68a4e003e786df99eee77  potato2\nd41d8cd98f00b204e9800998ecf8427e  empty space\n", true)
+	// missing from checksum file
+	check("d6548b156ea68a4e003e786df99eee76  potato2\n", true)
+	// missing from remote
+	check("d6548b156ea68a4e003e786df99eee76  potato2\nd41d8cd98f00b204e9800998ecf8427e  empty space\nd41d8cd98f00b204e9800998ecf8427e  potato3\n", true)
+	// bad format
+	check("potato", true)
+}
+
 func TestCount(t *testing.T) {
+	ctx := context.Background()
 	r := fstest.NewRun(t)
 	defer r.Finalise()
 	file1 := r.WriteBoth("potato2", "------------------------------------------------------------", t1)
@@ -215,13 +318,40 @@ func TestCount(t *testing.T) {
 	fs.Config.MaxDepth = 1
 	defer func() { fs.Config.MaxDepth = -1 }()
 
-	objects, size, err := operations.Count(r.Fremote)
+	objects, size, err := operations.Count(ctx, r.Fremote)
 	require.NoError(t, err)
 	assert.Equal(t, int64(2), objects)
 	assert.Equal(t, int64(60), size)
 }
 
+func TestCountDirs(t *testing.T) {
+	ctx := context.Background()
+	r := fstest.NewRun(t)
+	defer r.Finalise()
+	file1 := r.WriteBoth("potato2", "------------------------------------------------------------", t1)
+	file2 := r.WriteBoth("sub dir/potato3", "hello", t2)
+	file3 := r.WriteBoth("sub dir/sub sub dir/potato4", "hello", t2)
+	file4 := r.WriteBoth("other/potato5", "potato", t2)
+
+	fstest.CheckItems(t, r.Fremote, file1, file2, file3, file4)
+
+	total, dirs, err := operations.CountDirs(ctx, r.Fremote, false)
+	require.NoError(t, err)
+	assert.Equal(t, operations.SizeResult{Count: 4, Bytes: 76}, total)
+	assert.Nil(t, dirs)
+
+	total, dirs, err = operations.CountDirs(ctx, r.Fremote, true)
+	require.NoError(t, err)
+	assert.Equal(t, operations.SizeResult{Count: 4, Bytes: 76}, total)
+	assert.Equal(t, map[string]*operations.SizeResult{
+		".":       {Count: 1, Bytes: 60},
+		"sub dir": {Count: 2, Bytes: 10},
+		"other":   {Count: 1, Bytes: 6},
+	}, dirs)
+}
+
 func TestDelete(t *testing.T) {
+	ctx := context.Background()
 	r := fstest.NewRun(t)
 	defer r.Finalise()
 	file1 := r.WriteObject("small", "1234567890", t2)                                                                                           // 10 bytes
@@ -234,19 +364,59 @@ func TestDelete(t *testing.T) {
 		filter.Active.Opt.MaxSize = -1
 	}()
 
-	err := operations.Delete(r.Fremote)
+	err := operations.Delete(ctx, r.Fremote)
 	require.NoError(t, err)
 	fstest.CheckItems(t, r.Fremote, file3)
 }
 
-func testCheck(t *testing.T, checkFunction func(fdst, fsrc fs.Fs) error) {
+func TestSuffixName(t *testing.T) {
+	origSuffix, origKeepExt := fs.Config.Suffix, fs.Config.SuffixKeepExtension
+	defer func() {
+		fs.Config.Suffix, fs.Config.SuffixKeepExtension = origSuffix, origKeepExt
+	}()
+	for _, test := range []struct {
+		remote  string
+		suffix  string
+		keepExt bool
+		n       int
+		want    string
+	}{
+		{"test.txt", "", false, 1, "test.txt"},
+		{"test.txt", "", true, 1, "test.txt"},
+		{"test.txt", "-suffix", false, 1, "test.txt-suffix"},
+		{"test.txt", "-suffix", true, 1, "test-suffix.txt"},
+		{"test.txt.csv", "-suffix", false, 1, "test.txt.csv-suffix"},
+		{"test.txt.csv", "-suffix", true, 1, "test.txt-suffix.csv"},
+		{"test", "-suffix", false, 1, "test-suffix"},
+		{"test", "-suffix", true, 1, "test-suffix"},
+		{".bashrc", "-suffix", true, 1, ".bashrc-suffix"},
+		{"dir.d/test", "-suffix", true, 1, "dir.d/test-suffix"},
+		{"dir/test.txt", ".{n}", false, 3, "dir/test.txt.3"},
+		{"dir/test.txt", ".{n}", true, 3, "dir/test.3.txt"},
+	} {
+		fs.Config.Suffix = test.suffix
+		fs.Config.SuffixKeepExtension = test.keepExt
+		got := operations.SuffixName(test.remote, test.n)
+		assert.Equal(t, test.want, got, fmt.Sprintf("%+v", test))
+	}
+
+	// Check the date and time are substituted
+	fs.Config.Suffix = "-{date}-{time}"
+	fs.Config.SuffixKeepExtension = true
+	got := operations.SuffixName("test.txt", 1)
+	assert.Regexp(t, `^test-\d{4}-\d{2}-\d{2}-\d{6}\.txt$`, got)
+	assert.Equal(t, got, operations.SuffixName("test.txt", 1), "time should be the same for every backup")
+}
+
+func testCheck(t *testing.T, checkFunction func(ctx context.Context, fdst, fsrc fs.Fs) error) {
+	ctx := context.Background()
 	r := fstest.NewRun(t)
 	defer r.Finalise()
 
 	check := func(i int, wantErrors int64) {
 		fs.Debugf(r.Fremote, "%d: Starting check test", i)
 		oldErrors := accounting.Stats.GetErrors()
-		err := checkFunction(r.Flocal, r.Fremote)
+		err := checkFunction(ctx, r.Flocal, r.Fremote)
 		gotErrors := accounting.Stats.GetErrors() - oldErrors
 		if wantErrors == 0 && err != nil {
 			t.Errorf("%d: Got error when not expecting one: %v", i, err)
@@ -314,6 +484,7 @@ func skipIfCantDedupe(t *testing.T, f fs.Fs) {
 }
 
 func TestDeduplicateInteractive(t *testing.T) {
+	ctx := context.Background()
 	r := fstest.NewRun(t)
 	defer r.Finalise()
 	skipIfCantDedupe(t, r.Fremote)
@@ -323,13 +494,14 @@ func TestDeduplicateInteractive(t *testing.T) {
 	file3 := r.WriteUncheckedObject("one", "This is one", t1)
 	r.CheckWithDuplicates(t, file1, file2, file3)
 
-	err := operations.Deduplicate(r.Fremote, operations.DeduplicateInteractive)
+	err := operations.Deduplicate(ctx, r.Fremote, operations.DeduplicateInteractive)
 	require.NoError(t, err)
 
 	fstest.CheckItems(t, r.Fremote, file1)
 }
 
 func TestDeduplicateSkip(t *testing.T) {
+	ctx := context.Background()
 	r := fstest.NewRun(t)
 	defer r.Finalise()
 	skipIfCantDedupe(t, r.Fremote)
@@ -339,13 +511,14 @@ func TestDeduplicateSkip(t *testing.T) {
 	file3 := r.WriteUncheckedObject("one", "This is another one", t1)
 	r.CheckWithDuplicates(t, file1, file2, file3)
 
-	err := operations.Deduplicate(r.Fremote, operations.DeduplicateSkip)
+	err := operations.Deduplicate(ctx, r.Fremote, operations.DeduplicateSkip)
 	require.NoError(t, err)
 
 	r.CheckWithDuplicates(t, file1, file3)
 }
 
 func TestDeduplicateFirst(t *testing.T) {
+	ctx := context.Background()
 	r := fstest.NewRun(t)
 	defer r.Finalise()
 	skipIfCantDedupe(t, r.Fremote)
@@ -355,10 +528,10 @@ func TestDeduplicateFirst(t *testing.T) {
 	file3 := r.WriteUncheckedObject("one", "This is one BB", t1)
 	r.CheckWithDuplicates(t, file1, file2, file3)
 
-	err := operations.Deduplicate(r.Fremote, operations.DeduplicateFirst)
+	err := operations.Deduplicate(ctx, r.Fremote, operations.DeduplicateFirst)
 	require.NoError(t, err)
 
-	objects, size, err := operations.Count(r.Fremote)
+	objects, size, err := operations.Count(ctx, r.Fremote)
 	require.NoError(t, err)
 	assert.Equal(t, int64(1), objects)
 	if size != file1.Size && size != file2.Size && size != file3.Size {
@@ -367,6 +540,7 @@ func TestDeduplicateFirst(t *testing.T) {
 }
 
 func TestDeduplicateNewest(t *testing.T) {
+	ctx := context.Background()
 	r := fstest.NewRun(t)
 	defer r.Finalise()
 	skipIfCantDedupe(t, r.Fremote)
@@ -376,13 +550,14 @@ func TestDeduplicateNewest(t *testing.T) {
 	file3 := r.WriteUncheckedObject("one", "This is another one", t3)
 	r.CheckWithDuplicates(t, file1, file2, file3)
 
-	err := operations.Deduplicate(r.Fremote, operations.DeduplicateNewest)
+	err := operations.Deduplicate(ctx, r.Fremote, operations.DeduplicateNewest)
 	require.NoError(t, err)
 
 	fstest.CheckItems(t, r.Fremote, file3)
 }
 
 func TestDeduplicateOldest(t *testing.T) {
+	ctx := context.Background()
 	r := fstest.NewRun(t)
 	defer r.Finalise()
 	skipIfCantDedupe(t, r.Fremote)
@@ -392,13 +567,31 @@ func TestDeduplicateOldest(t *testing.T) {
 	file3 := r.WriteUncheckedObject("one", "This is another one", t3)
 	r.CheckWithDuplicates(t, file1, file2, file3)
 
-	err := operations.Deduplicate(r.Fremote, operations.DeduplicateOldest)
+	err := operations.Deduplicate(ctx, r.Fremote, operations.DeduplicateOldest)
 	require.NoError(t, err)
 
 	fstest.CheckItems(t, r.Fremote, file1)
 }
 
+func TestDeduplicateLargest(t *testing.T) {
+	ctx := context.Background()
+	r := fstest.NewRun(t)
+	defer r.Finalise()
+	skipIfCantDedupe(t, r.Fremote)
+
+	file1 := r.WriteUncheckedObject("one", "This is one", t1)
+	file2 := r.WriteUncheckedObject("one", "This is one too", t2)
+	file3 := r.WriteUncheckedObject("one", "This is another one", t3)
+	r.CheckWithDuplicates(t, file1, file2, file3)
+
+	err := operations.Deduplicate(ctx, r.Fremote, operations.DeduplicateLargest)
+	require.NoError(t, err)
+
+	fstest.CheckItems(t, r.Fremote, file3)
+}
+
 func TestDeduplicateRename(t *testing.T) {
+	ctx := context.Background()
 	r := fstest.NewRun(t)
 	defer r.Finalise()
 	skipIfCantDedupe(t, r.Fremote)
@@ -408,10 +601,10 @@ func TestDeduplicateRename(t *testing.T) {
 	file3 := r.WriteUncheckedObject("one.txt", "This is another one", t3)
 	r.CheckWithDuplicates(t, file1, file2, file3)
 
-	err := operations.Deduplicate(r.Fremote, operations.DeduplicateRename)
+	err := operations.Deduplicate(ctx, r.Fremote, operations.DeduplicateRename)
 	require.NoError(t, err)
 
-	require.NoError(t, walk.Walk(r.Fremote, "", true, -1, func(dirPath string, entries fs.DirEntries, err error) error {
+	require.NoError(t, walk.Walk(ctx, r.Fremote, "", true, -1, func(dirPath string, entries fs.DirEntries, err error) error {
 		if err != nil {
 			return err
 		}
@@ -434,6 +627,7 @@ func TestDeduplicateRename(t *testing.T) {
 // This should really be a unit test, but the test framework there
 // doesn't have enough tools to make it easy
 func TestMergeDirs(t *testing.T) {
+	ctx := context.Background()
 	r := fstest.NewRun(t)
 	defer r.Finalise()
 
@@ -446,19 +640,19 @@ func TestMergeDirs(t *testing.T) {
 	file2 := r.WriteObject("dupe2/two.txt", "This is one too", t2)
 	file3 := r.WriteObject("dupe3/three.txt", "This is another one", t3)
 
-	objs, dirs, err := walk.GetAll(r.Fremote, "", true, 1)
+	objs, dirs, err := walk.GetAll(ctx, r.Fremote, "", true, 1)
 	require.NoError(t, err)
 	assert.Equal(t, 3, len(dirs))
 	assert.Equal(t, 0, len(objs))
 
-	err = mergeDirs(dirs)
+	err = mergeDirs(ctx, dirs)
 	require.NoError(t, err)
 
 	file2.Path = "dupe1/two.txt"
 	file3.Path = "dupe1/three.txt"
 	fstest.CheckItems(t, r.Fremote, file1, file2, file3)
 
-	objs, dirs, err = walk.GetAll(r.Fremote, "", true, 1)
+	objs, dirs, err = walk.GetAll(ctx, r.Fremote, "", true, 1)
 	require.NoError(t, err)
 	assert.Equal(t, 1, len(dirs))
 	assert.Equal(t, 0, len(objs))
@@ -466,6 +660,7 @@ func TestMergeDirs(t *testing.T) {
 }
 
 func TestCat(t *testing.T) {
+	ctx := context.Background()
 	r := fstest.NewRun(t)
 	defer r.Finalise()
 	file1 := r.WriteBoth("file1", "ABCDEFGHIJ", t1)
@@ -485,7 +680,7 @@ func TestCat(t *testing.T) {
 		{1, 3, "BCD", "123"},
 	} {
 		var buf bytes.Buffer
-		err := operations.Cat(r.Fremote, &buf, test.offset, test.count)
+		err := operations.Cat(ctx, r.Fremote, &buf, test.offset, test.count)
 		require.NoError(t, err)
 		res := buf.String()
 
@@ -496,6 +691,7 @@ func TestCat(t *testing.T) {
 }
 
 func TestRcat(t *testing.T) {
+	ctx := context.Background()
 	checkSumBefore := fs.Config.CheckSum
 	defer func() { fs.Config.CheckSum = checkSumBefore }()
 
@@ -518,11 +714,11 @@ func TestRcat(t *testing.T) {
 		path2 := prefix + "big_file_from_pipe"
 
 		in := ioutil.NopCloser(strings.NewReader(data1))
-		_, err := operations.Rcat(r.Fremote, path1, in, t1)
+		_, err := operations.Rcat(ctx, r.Fremote, path1, in, t1)
 		require.NoError(t, err)
 
 		in = ioutil.NopCloser(strings.NewReader(data2))
-		_, err = operations.Rcat(r.Fremote, path2, in, t2)
+		_, err = operations.Rcat(ctx, r.Fremote, path2, in, t2)
 		require.NoError(t, err)
 
 		file1 := fstest.NewItem(path1, data1, t1)
@@ -534,7 +730,70 @@ func TestRcat(t *testing.T) {
 	check(false)
 }
 
+func TestRcatStreamingBuffer(t *testing.T) {
+	ctx := context.Background()
+	r := fstest.NewRun(t)
+	defer r.Finalise()
+
+	// Pretend the remote can't stream uploads
+	features := r.Fremote.Features()
+	putStream := features.PutStream
+	features.PutStream = nil
+	defer func() { features.PutStream = putStream }()
+
+	fs.Config.StreamingBufferSize = fs.Config.StreamingUploadCutoff * 2
+	defer func() { fs.Config.StreamingBufferSize = 0 }()
+
+	// Fits in the buffer so is uploaded from memory
+	data1 := string(make([]byte, fs.Config.StreamingUploadCutoff+1))
+	in := ioutil.NopCloser(strings.NewReader(data1))
+	_, err := operations.Rcat(ctx, r.Fremote, "big_file_from_pipe", in, t1)
+	require.NoError(t, err)
+
+	// Too big for the buffer
+	data2 := string(make([]byte, fs.Config.StreamingBufferSize+1))
+	in = ioutil.NopCloser(strings.NewReader(data2))
+	_, err = operations.Rcat(ctx, r.Fremote, "too_big_file_from_pipe", in, t1)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "--streaming-buffer-size")
+
+	file1 := fstest.NewItem("big_file_from_pipe", data1, t1)
+	fstest.CheckItems(t, r.Fremote, file1)
+}
+
+func TestRcatSize(t *testing.T) {
+	ctx := context.Background()
+	r := fstest.NewRun(t)
+	defer r.Finalise()
+
+	const body = "------------------------------------------------------------"
+	file1 := r.WriteFile("potato1", body, t1)
+	file2 := r.WriteFile("potato2", body, t2)
+	// Test with known length
+	bodyReader := ioutil.NopCloser(strings.NewReader(body))
+	obj, err := operations.RcatSize(ctx, r.Fremote, file1.Path, bodyReader, int64(len(body)), file1.ModTime)
+	require.NoError(t, err)
+	assert.Equal(t, int64(len(body)), obj.Size())
+	assert.Equal(t, file1.Path, obj.Remote())
+
+	// Test with unknown length
+	bodyReader = ioutil.NopCloser(strings.NewReader(body))
+	obj, err = operations.RcatSize(ctx, r.Fremote, file2.Path, bodyReader, -1, file2.ModTime)
+	require.NoError(t, err)
+	assert.Equal(t, int64(len(body)), obj.Size())
+	assert.Equal(t, file2.Path, obj.Remote())
+
+	// Test with wrong length
+	bodyReader = ioutil.NopCloser(strings.NewReader(body))
+	_, err = operations.RcatSize(ctx, r.Fremote, "potato3", bodyReader, int64(len(body))+1, t1)
+	assert.Error(t, err)
+
+	// Check files exist
+	fstest.CheckItems(t, r.Fremote, file1, file2)
+}
+
 func TestRmdirsNoLeaveRoot(t *testing.T) {
+	ctx := context.Background()
 	r := fstest.NewRun(t)
 	defer r.Finalise()
 	r.Mkdir(r.Fremote)
@@ -543,13 +802,13 @@ func TestRmdirsNoLeaveRoot(t *testing.T) {
 	r.ForceMkdir(r.Fremote)
 	file1 := r.WriteObject("A1/B1/C1/one", "aaa", t1)
 	//..and dirs we expect to delete
-	require.NoError(t, operations.Mkdir(r.Fremote, "A2"))
-	require.NoError(t, operations.Mkdir(r.Fremote, "A1/B2"))
-	require.NoError(t, operations.Mkdir(r.Fremote, "A1/B2/C2"))
-	require.NoError(t, operations.Mkdir(r.Fremote, "A1/B1/C3"))
-	require.NoError(t, operations.Mkdir(r.Fremote, "A3"))
-	require.NoError(t, operations.Mkdir(r.Fremote, "A3/B3"))
-	require.NoError(t, operations.Mkdir(r.Fremote, "A3/B3/C4"))
+	require.NoError(t, operations.Mkdir(ctx, r.Fremote, "A2"))
+	require.NoError(t, operations.Mkdir(ctx, r.Fremote, "A1/B2"))
+	require.NoError(t, operations.Mkdir(ctx, r.Fremote, "A1/B2/C2"))
+	require.NoError(t, operations.Mkdir(ctx, r.Fremote, "A1/B1/C3"))
+	require.NoError(t, operations.Mkdir(ctx, r.Fremote, "A3"))
+	require.NoError(t, operations.Mkdir(ctx, r.Fremote, "A3/B3"))
+	require.NoError(t, operations.Mkdir(ctx, r.Fremote, "A3/B3/C4"))
 	//..and one more file at the end
 	file2 := r.WriteObject("A1/two", "bbb", t2)
 
@@ -574,7 +833,7 @@ func TestRmdirsNoLeaveRoot(t *testing.T) {
 		fs.Config.ModifyWindow,
 	)
 
-	require.NoError(t, operations.Rmdirs(r.Fremote, "", false))
+	require.NoError(t, operations.Rmdirs(ctx, r.Fremote, "", false))
 
 	fstest.CheckListingWithPrecision(
 		t,
@@ -593,15 +852,16 @@ func TestRmdirsNoLeaveRoot(t *testing.T) {
 }
 
 func TestRmdirsLeaveRoot(t *testing.T) {
+	ctx := context.Background()
 	r := fstest.NewRun(t)
 	defer r.Finalise()
 	r.Mkdir(r.Fremote)
 
 	r.ForceMkdir(r.Fremote)
 
-	require.NoError(t, operations.Mkdir(r.Fremote, "A1"))
-	require.NoError(t, operations.Mkdir(r.Fremote, "A1/B1"))
-	require.NoError(t, operations.Mkdir(r.Fremote, "A1/B1/C1"))
+	require.NoError(t, operations.Mkdir(ctx, r.Fremote, "A1"))
+	require.NoError(t, operations.Mkdir(ctx, r.Fremote, "A1/B1"))
+	require.NoError(t, operations.Mkdir(ctx, r.Fremote, "A1/B1/C1"))
 
 	fstest.CheckListingWithPrecision(
 		t,
@@ -615,7 +875,7 @@ func TestRmdirsLeaveRoot(t *testing.T) {
 		fs.Config.ModifyWindow,
 	)
 
-	require.NoError(t, operations.Rmdirs(r.Fremote, "A1", true))
+	require.NoError(t, operations.Rmdirs(ctx, r.Fremote, "A1", true))
 
 	fstest.CheckListingWithPrecision(
 		t,
@@ -629,6 +889,7 @@ func TestRmdirsLeaveRoot(t *testing.T) {
 }
 
 func TestMoveFile(t *testing.T) {
+	ctx := context.Background()
 	r := fstest.NewRun(t)
 	defer r.Finalise()
 
@@ -638,7 +899,7 @@ func TestMoveFile(t *testing.T) {
 	file2 := file1
 	file2.Path = "sub/file2"
 
-	err := operations.MoveFile(r.Fremote, r.Flocal, file2.Path, file1.Path)
+	err := operations.MoveFile(ctx, r.Fremote, r.Flocal, file2.Path, file1.Path)
 	require.NoError(t, err)
 	fstest.CheckItems(t, r.Flocal)
 	fstest.CheckItems(t, r.Fremote, file2)
@@ -646,18 +907,19 @@ func TestMoveFile(t *testing.T) {
 	r.WriteFile("file1", "file1 contents", t1)
 	fstest.CheckItems(t, r.Flocal, file1)
 
-	err = operations.MoveFile(r.Fremote, r.Flocal, file2.Path, file1.Path)
+	err = operations.MoveFile(ctx, r.Fremote, r.Flocal, file2.Path, file1.Path)
 	require.NoError(t, err)
 	fstest.CheckItems(t, r.Flocal)
 	fstest.CheckItems(t, r.Fremote, file2)
 
-	err = operations.MoveFile(r.Fremote, r.Fremote, file2.Path, file2.Path)
+	err = operations.MoveFile(ctx, r.Fremote, r.Fremote, file2.Path, file2.Path)
 	require.NoError(t, err)
 	fstest.CheckItems(t, r.Flocal)
 	fstest.CheckItems(t, r.Fremote, file2)
 }
 
 func TestCopyFile(t *testing.T) {
+	ctx := context.Background()
 	r := fstest.NewRun(t)
 	defer r.Finalise()
 
@@ -667,22 +929,166 @@ func TestCopyFile(t *testing.T) {
 	file2 := file1
 	file2.Path = "sub/file2"
 
-	err := operations.CopyFile(r.Fremote, r.Flocal, file2.Path, file1.Path)
+	err := operations.CopyFile(ctx, r.Fremote, r.Flocal, file2.Path, file1.Path)
 	require.NoError(t, err)
 	fstest.CheckItems(t, r.Flocal, file1)
 	fstest.CheckItems(t, r.Fremote, file2)
 
-	err = operations.CopyFile(r.Fremote, r.Flocal, file2.Path, file1.Path)
+	err = operations.CopyFile(ctx, r.Fremote, r.Flocal, file2.Path, file1.Path)
 	require.NoError(t, err)
 	fstest.CheckItems(t, r.Flocal, file1)
 	fstest.CheckItems(t, r.Fremote, file2)
 
-	err = operations.CopyFile(r.Fremote, r.Fremote, file2.Path, file2.Path)
+	err = operations.CopyFile(ctx, r.Fremote, r.Fremote, file2.Path, file2.Path)
 	require.NoError(t, err)
 	fstest.CheckItems(t, r.Flocal, file1)
 	fstest.CheckItems(t, r.Fremote, file2)
 }
 
+// corruptHashObject is an fs.Object which returns a bad hash the
+// bad times its hash is read after the first good times
+type corruptHashObject struct {
+	fs.Object
+	good int
+	bad  int
+}
+
+// Hash returns a bad hash after good is used up until bad is used up
+func (o *corruptHashObject) Hash(ht hash.Type) (string, error) {
+	if o.good > 0 {
+		o.good--
+	} else if o.bad > 0 {
+		o.bad--
+		return "00000000000000000000000000000000", nil
+	}
+	return o.Object.Hash(ht)
+}
+
+func TestCopyCorruptedRetries(t *testing.T) {
+	ctx := context.Background()
+	r := fstest.NewRun(t)
+	defer r.Finalise()
+	oldCorruptedRetries := fs.Config.CorruptedRetries
+	defer func() {
+		fs.Config.CorruptedRetries = oldCorruptedRetries
+		accounting.Stats.ResetCounters()
+	}()
+	if r.Fremote.Hashes().Overlap(r.Flocal.Hashes()).Count() == 0 {
+		t.Skip("Can't check hashes on this remote")
+	}
+
+	file1 := r.WriteFile("file1", "file1 contents", t1)
+	src, err := r.Flocal.NewObject(ctx, file1.Path)
+	require.NoError(t, err)
+
+	// Retried until the hash matches
+	fs.Config.CorruptedRetries = 2
+	dst, err := operations.Copy(ctx, r.Fremote, nil, file1.Path, &corruptHashObject{Object: src, bad: 2})
+	require.NoError(t, err)
+	require.NotNil(t, dst)
+	fstest.CheckItems(t, r.Fremote, file1)
+	require.NoError(t, dst.Remove(ctx))
+
+	// Gives up when the retries run out and removes the bad copy
+	fs.Config.CorruptedRetries = 1
+	_, err = operations.Copy(ctx, r.Fremote, nil, file1.Path, &corruptHashObject{Object: src, bad: 2})
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "corrupted on transfer")
+	fstest.CheckItems(t, r.Fremote)
+}
+
+func TestMoveDeleteAfterVerify(t *testing.T) {
+	ctx := context.Background()
+	r := fstest.NewRun(t)
+	defer r.Finalise()
+	oldDeleteAfterVerify := fs.Config.DeleteAfterVerify
+	features := r.Fremote.Features()
+	oldMove := features.Move
+	defer func() {
+		fs.Config.DeleteAfterVerify = oldDeleteAfterVerify
+		features.Move = oldMove
+		accounting.Stats.ResetCounters()
+	}()
+	fs.Config.DeleteAfterVerify = true
+	// Make sure the move is done with a copy
+	features.Move = nil
+
+	file1 := r.WriteFile("file1", "file1 contents", t1)
+	file2 := r.WriteFile("file2", "file2 contents", t2)
+
+	// Verified so the source is deleted
+	src, err := r.Flocal.NewObject(ctx, file1.Path)
+	require.NoError(t, err)
+	_, err = operations.Move(ctx, r.Fremote, nil, file1.Path, src)
+	require.NoError(t, err)
+	fstest.CheckItems(t, r.Flocal, file2)
+	fstest.CheckItems(t, r.Fremote, file1)
+
+	// The copy passes the transfer checks but fails verification
+	// so the source is kept
+	if r.Fremote.Hashes().Overlap(r.Flocal.Hashes()).Count() == 0 {
+		t.Skip("Can't check hashes on this remote")
+	}
+	src, err = r.Flocal.NewObject(ctx, file2.Path)
+	require.NoError(t, err)
+	_, err = operations.Move(ctx, r.Fremote, nil, file2.Path, &corruptHashObject{Object: src, good: 1, bad: 1})
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "hash differ")
+	fstest.CheckItems(t, r.Flocal, file2)
+}
+
+func TestCopyFileMaxTransfer(t *testing.T) {
+	ctx := context.Background()
+	r := fstest.NewRun(t)
+	defer r.Finalise()
+	oldMaxTransfer, oldCutoffMode := fs.Config.MaxTransfer, fs.Config.CutoffMode
+	defer func() {
+		fs.Config.MaxTransfer, fs.Config.CutoffMode = oldMaxTransfer, oldCutoffMode
+		accounting.Stats.ResetCounters()
+	}()
+	fs.Config.MaxTransfer = 15
+
+	file1 := r.WriteFile("file1", "file1 contents", t1)      // 14 bytes
+	file2 := r.WriteFile("file2", "file2 contents.....", t2) // 19 bytes
+	file3 := r.WriteFile("file3", "3", t3)                   // 1 byte
+	copyFile := func(file fstest.Item) error {
+		return operations.CopyFile(ctx, r.Fremote, r.Flocal, file.Path, file.Path)
+	}
+	deleteFiles := func() {
+		require.NoError(t, operations.Purge(ctx, r.Fremote, ""))
+		accounting.Stats.ResetCounters()
+	}
+
+	// HARD stops the transfer which goes over the limit
+	fs.Config.CutoffMode = fs.CutoffModeHard
+	accounting.Stats.ResetCounters()
+	require.NoError(t, copyFile(file1))
+	err := copyFile(file2)
+	require.Error(t, err)
+	assert.True(t, fserrors.IsFatalError(err), err.Error())
+	err = copyFile(file3)
+	assert.Equal(t, accounting.ErrorMaxTransferLimitReachedFatal, err)
+
+	// SOFT finishes the transfer which goes over the limit but
+	// doesn't start any more
+	deleteFiles()
+	fs.Config.CutoffMode = fs.CutoffModeSoft
+	require.NoError(t, copyFile(file1))
+	require.NoError(t, copyFile(file2))
+	err = copyFile(file3)
+	assert.Equal(t, accounting.ErrorMaxTransferLimitReachedGraceful, err)
+	fstest.CheckItems(t, r.Fremote, file1, file2)
+
+	// CAUTIOUS doesn't start a transfer which would go over the limit
+	deleteFiles()
+	fs.Config.CutoffMode = fs.CutoffModeCautious
+	require.NoError(t, copyFile(file1))
+	err = copyFile(file2)
+	assert.Equal(t, accounting.ErrorMaxTransferLimitReachedGraceful, err)
+	require.NoError(t, copyFile(file3))
+	fstest.CheckItems(t, r.Fremote, file1, file3)
+}
+
 // testFsInfo is for unit testing fs.Info
 type testFsInfo struct {
 	name      string
@@ -848,6 +1254,7 @@ func TestCheckEqualReaders(t *testing.T) {
 }
 
 func TestListFormat(t *testing.T) {
+	ctx := context.Background()
 	r := fstest.NewRun(t)
 	defer r.Finalise()
 	file1 := r.WriteObject("a", "a", t1)
@@ -855,7 +1262,7 @@ func TestListFormat(t *testing.T) {
 
 	fstest.CheckItems(t, r.Fremote, file1, file2)
 
-	items, _ := list.DirSorted(r.Fremote, true, "")
+	items, _ := list.DirSorted(ctx, r.Fremote, true, "")
 	var list operations.ListFormat
 	list.AddPath()
 	list.SetDirSlash(false)
@@ -903,4 +1310,85 @@ func TestListFormat(t *testing.T) {
 			assert.Equal(t, test.want, got)
 		}
 	}
+
+	list.SetOutput(nil)
+	list.AddMimeType()
+	assert.Contains(t, operations.ListFormatted(&items[0], &list), "/")
+	assert.Equal(t, "inode/directory", operations.ListFormatted(&items[1], &list))
+
+	list.SetOutput(nil)
+	list.AddID()
+	_ = operations.ListFormatted(&items[0], &list) // Can't really check anything - at least it didn't panic!
+
+	list.SetOutput(nil)
+	list.AddPath()
+	list.AppendOutput(func() string { return `a "quoted",value` })
+	list.SetSeparator("__SEP__")
+	assert.Error(t, list.SetCSV(true))
+	list.SetSeparator(",")
+	require.NoError(t, list.SetCSV(true))
+	assert.Equal(t, `subdir/,"a ""quoted"",value"`, operations.ListFormatted(&items[1], &list))
+	list.SetSeparator(";")
+	require.NoError(t, list.SetCSV(true))
+	assert.Equal(t, `subdir/;"a ""quoted"",value"`, operations.ListFormatted(&items[1], &list))
+	require.NoError(t, list.SetCSV(false))
+	assert.Equal(t, `subdir/;a "quoted",value`, operations.ListFormatted(&items[1], &list))
+}
+
+func TestCopyURL(t *testing.T) {
+	ctx := context.Background()
+	r := fstest.NewRun(t)
+	defer r.Finalise()
+
+	contents := "file contents\n"
+	file1 := r.WriteFile("file1", contents, t1)
+	file2 := r.WriteFile("file2", contents, t1)
+	r.Mkdir(r.Fremote)
+	fstest.CheckItems(t, r.Fremote)
+
+	// check when reading from regular HTTP server
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/disposition" {
+			w.Header().Set("Content-Disposition", `attachment; filename="file2"`)
+		}
+		if r.URL.Path == "/missing" {
+			http.NotFound(w, r)
+			return
+		}
+		w.Header().Set("Last-Modified", t1.UTC().Format(http.TimeFormat))
+		_, err := w.Write([]byte(contents))
+		assert.NoError(t, err)
+	})
+	ts := httptest.NewServer(handler)
+	defer ts.Close()
+
+	o, err := operations.CopyURL(ctx, r.Fremote, "file1", ts.URL, false)
+	require.NoError(t, err)
+	assert.Equal(t, int64(len(contents)), o.Size())
+	fstest.CheckListingWithPrecision(t, r.Fremote, []fstest.Item{file1}, nil, time.Second)
+
+	// name from Content-Disposition
+	o, err = operations.CopyURL(ctx, r.Fremote, "ignored", ts.URL+"/disposition", true)
+	require.NoError(t, err)
+	assert.Equal(t, "file2", o.Remote())
+	fstest.CheckListingWithPrecision(t, r.Fremote, []fstest.Item{file1, file2}, nil, time.Second)
+
+	// name from the URL
+	o, err = operations.CopyURL(ctx, r.Fremote, "ignored", ts.URL+"/dir/file3", true)
+	require.NoError(t, err)
+	assert.Equal(t, "file3", o.Remote())
+
+	// no name in the URL
+	_, err = operations.CopyURL(ctx, r.Fremote, "ignored", ts.URL+"/", true)
+	assert.Error(t, err)
+
+	// HTTP errors
+	_, err = operations.CopyURL(ctx, r.Fremote, "file4", ts.URL+"/missing", false)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "404")
+
+	// to a writer
+	var buf bytes.Buffer
+	require.NoError(t, operations.CopyURLToWriter(ctx, ts.URL, &buf))
+	assert.Equal(t, contents, buf.String())
 }
@@ -111,7 +111,11 @@ func (o *RangeOption) Decode(size int64) (offset, limit int64) {
 		}
 	} else {
 		if o.End >= 0 {
+			// A suffix longer than the object means the whole object
 			offset = size - o.End
+			if offset < 0 {
+				offset = 0
+			}
 		} else {
 			offset = 0
 		}
@@ -131,7 +135,11 @@ func FixRangeOption(options []OpenOption, size int64) {
 		if x, ok := option.(*RangeOption); ok {
 			// If start is < 0 then fetch from the end
 			if x.Start < 0 {
-				x = &RangeOption{Start: size - x.End, End: -1}
+				start := size - x.End
+				if start < 0 {
+					start = 0
+				}
+				x = &RangeOption{Start: start, End: -1}
 				options[i] = x
 			}
 		}
@@ -237,9 +245,32 @@ func OpenOptionAddHTTPHeaders(headers http.Header, options []OpenOption) {
 	}
 }
 
+// MetadataOption defines an Option which does nothing but carries
+// extra metadata to be set on the object when it is uploaded.
+//
+// These are merged with any metadata read from the source object if
+// --metadata is in use.
+type MetadataOption Metadata
+
+// Header formats the option as an http header
+func (o MetadataOption) Header() (key string, value string) {
+	return "", ""
+}
+
+// String formats the option into human readable form
+func (o MetadataOption) String() string {
+	return fmt.Sprintf("MetadataOption(%v)", Metadata(o))
+}
+
+// Mandatory returns whether the option must be parsed or can be ignored
+func (o MetadataOption) Mandatory() bool {
+	return false
+}
+
 // check interface
 var (
 	_ OpenOption = (*RangeOption)(nil)
 	_ OpenOption = (*SeekOption)(nil)
 	_ OpenOption = (*HTTPOption)(nil)
+	_ OpenOption = MetadataOption(nil)
 )
@@ -50,6 +50,7 @@ func TestRangeOptionDecode(t *testing.T) {
 		{in: RangeOption{Start: 10, End: 9}, size: 100, wantOffset: 10, wantLimit: 0},
 		{in: RangeOption{Start: 1, End: -1}, size: 100, wantOffset: 1, wantLimit: -1},
 		{in: RangeOption{Start: -1, End: 90}, size: 100, wantOffset: 10, wantLimit: -1},
+		{in: RangeOption{Start: -1, End: 110}, size: 100, wantOffset: 0, wantLimit: -1},
 		{in: RangeOption{Start: -1, End: -1}, size: 100, wantOffset: 0, wantLimit: -1},
 	} {
 		gotOffset, gotLimit := test.in.Decode(test.size)
@@ -3,6 +3,7 @@ package sync
 
 import (
 	"fmt"
+	"path"
 	"sort"
 	"sync"
 
@@ -11,6 +12,8 @@ import (
 	"github.com/ncw/rclone/fs/filter"
 	"github.com/ncw/rclone/fs/fserrors"
 	"github.com/ncw/rclone/fs/hash"
+	"github.com/ncw/rclone/fs/jobstate"
+	"github.com/ncw/rclone/fs/listcache"
 	"github.com/ncw/rclone/fs/march"
 	"github.com/ncw/rclone/fs/operations"
 	"github.com/pkg/errors"
@@ -24,6 +27,7 @@ type syncCopyMove struct {
 	deleteMode         fs.DeleteMode // how we are doing deletions
 	DoMove             bool
 	deleteEmptySrcDirs bool
+	copyEmptySrcDirs   bool
 	dir                string
 	// internal state
 	ctx            context.Context        // internal context for controlling go-routines
@@ -41,6 +45,8 @@ type syncCopyMove struct {
 	dstEmptyDirs   []fs.DirEntry          // potentially empty directories
 	srcEmptyDirsMu sync.Mutex             // protect srcEmptyDirs
 	srcEmptyDirs   []fs.DirEntry          // potentially empty directories
+	srcNewDirsMu   sync.Mutex             // protect srcNewDirs
+	srcNewDirs     map[string]fs.DirEntry // src only directories which are empty so far - only used by copyEmptySrcDirs
 	checkerWg      sync.WaitGroup         // wait for checkers
 	toBeChecked    fs.ObjectPairChan      // checkers channel
 	transfersWg    sync.WaitGroup         // wait for transfers
@@ -58,16 +64,24 @@ type syncCopyMove struct {
 	trackRenamesCh chan fs.Object         // objects are pumped in here
 	renameCheck    []fs.Object            // accumulate files to check for rename here
 	backupDir      fs.Fs                  // place to store overwrites/deletes
-	suffix         string                 // suffix to add to files placed in backupDir
+	dirModTimesMu  sync.Mutex             // protect dirModTimes
+	dirModTimes    []fs.Directory         // src directories whose mod times need setting on dst
+	copyByHash     bool                   // set if we should server side copy files with matching hashes
+	dstAllFiles    map[string]fs.Object   // all dst files - only used by copyByHash
+	copyMap        map[string][]fs.Object // dst files by hash - only used by copyByHash
+	noTraverse     bool                   // set if we should look up src files in dst rather than listing it
+	dstListCache   *listcache.Cache       // cache of dst listings or nil if not in use
+	jobState       *jobstate.Journal      // journal of finished files or nil if not in use
 }
 
-func newSyncCopyMove(fdst, fsrc fs.Fs, deleteMode fs.DeleteMode, DoMove bool, deleteEmptySrcDirs bool) (*syncCopyMove, error) {
+func newSyncCopyMove(ctx context.Context, fdst, fsrc fs.Fs, deleteMode fs.DeleteMode, DoMove bool, deleteEmptySrcDirs bool, copyEmptySrcDirs bool) (*syncCopyMove, error) {
 	s := &syncCopyMove{
 		fdst:               fdst,
 		fsrc:               fsrc,
 		deleteMode:         deleteMode,
 		DoMove:             DoMove,
 		deleteEmptySrcDirs: deleteEmptySrcDirs,
+		copyEmptySrcDirs:   copyEmptySrcDirs,
 		dir:                "",
 		srcFilesChan:       make(chan fs.Object, fs.Config.Checkers+fs.Config.Transfers),
 		srcFilesResult:     make(chan error, 1),
@@ -79,8 +93,10 @@ func newSyncCopyMove(fdst, fsrc fs.Fs, deleteMode fs.DeleteMode, DoMove bool, de
 		commonHash:         fsrc.Hashes().Overlap(fdst.Hashes()).GetOne(),
 		toBeRenamed:        make(fs.ObjectPairChan, fs.Config.Transfers),
 		trackRenamesCh:     make(chan fs.Object, fs.Config.Checkers),
+		copyByHash:         fs.Config.CopyByHash,
+		srcNewDirs:         make(map[string]fs.DirEntry),
 	}
-	s.ctx, s.cancel = context.WithCancel(context.Background())
+	s.ctx, s.cancel = context.WithCancel(ctx)
 	if s.trackRenames {
 		// Don't track renames for remotes without server-side move support.
 		if !operations.CanServerSideMove(fdst) {
@@ -92,6 +108,38 @@ func newSyncCopyMove(fdst, fsrc fs.Fs, deleteMode fs.DeleteMode, DoMove bool, de
 			s.trackRenames = false
 		}
 	}
+	if s.copyByHash {
+		// Don't copy by hash for remotes without server-side copy support.
+		if fdst.Features().Copy == nil {
+			fs.Errorf(fdst, "Ignoring --copy-by-hash as the destination does not support server-side copy")
+			s.copyByHash = false
+		}
+		if s.commonHash == hash.None {
+			fs.Errorf(fdst, "Ignoring --copy-by-hash as the source and destination do not have a common hash")
+			s.copyByHash = false
+		}
+	}
+	if s.copyEmptySrcDirs && !fdst.Features().CanHaveEmptyDirectories {
+		fs.Debugf(fdst, "Ignoring --create-empty-src-dirs as the destination can't have empty directories")
+		s.copyEmptySrcDirs = false
+	}
+	if fs.Config.NoCheckDest {
+		if s.deleteMode != fs.DeleteModeOff {
+			return nil, fserrors.FatalError(errors.New("can't use --no-check-dest with sync: use copy instead"))
+		}
+		if s.trackRenames || s.copyByHash {
+			fs.Errorf(fdst, "Ignoring --track-renames and --copy-by-hash as --no-check-dest is set")
+			s.trackRenames = false
+			s.copyByHash = false
+		}
+	}
+	// Look up the files rather than listing the destination if
+	// asked to or if copying a single file
+	if !fs.Config.NoCheckDest && s.deleteMode == fs.DeleteModeOff && !s.deferUploads() {
+		s.noTraverse = fs.Config.NoTraverse || len(filter.Active.Files()) == 1
+	} else if fs.Config.NoTraverse {
+		fs.Errorf(fdst, "Ignoring --no-traverse as it can't be used with sync, --track-renames or --copy-by-hash")
+	}
 	if s.trackRenames {
 		// track renames needs delete after
 		if s.deleteMode != fs.DeleteModeOff {
@@ -117,7 +165,30 @@ func newSyncCopyMove(fdst, fsrc fs.Fs, deleteMode fs.DeleteMode, DoMove bool, de
 		if operations.Overlapping(fsrc, s.backupDir) {
 			return nil, fserrors.FatalError(errors.New("source and parameter to --backup-dir mustn't overlap"))
 		}
-		s.suffix = fs.Config.Suffix
+	}
+	// Open the cache of dst listings if required
+	if fs.Config.DstListCache != "" && !fs.Config.NoCheckDest && !s.noTraverse {
+		if s.trackRenames || s.copyByHash {
+			fs.Errorf(fdst, "Ignoring --dst-list-cache as it can't be used with --track-renames or --copy-by-hash")
+		} else {
+			var err error
+			s.dstListCache, err = listcache.Open(fdst, fs.Config.DstListCache, fs.Config.DstListCacheMaxAge)
+			if err != nil {
+				return nil, fserrors.FatalError(err)
+			}
+		}
+	}
+	// Open the journal of finished files if required
+	if fs.Config.JobStateFile != "" && s.deleteMode != fs.DeleteModeOnly {
+		if s.DoMove {
+			fs.Debugf(fdst, "Ignoring --job-state-file as moved files are removed from the source")
+		} else {
+			var err error
+			s.jobState, err = jobstate.Open(fsrc, fdst, fs.Config.JobStateFile)
+			if err != nil {
+				return nil, fserrors.FatalError(err)
+			}
+		}
 	}
 	return s, nil
 }
@@ -151,10 +222,30 @@ outer:
 }
 
 // This checks the types of errors returned while copying files
+//
+// Use this for errors which can't be fixed by retrying individual
+// files, otherwise use processFileError
 func (s *syncCopyMove) processError(err error) {
 	if err == nil {
 		return
 	}
+	accounting.Stats.RetryAll()
+	s.saveError(err)
+}
+
+// This checks the types of errors returned while copying the file
+// remote and records the file as failed so it can be retried on its
+// own
+func (s *syncCopyMove) processFileError(remote string, err error) {
+	if err == nil {
+		return
+	}
+	accounting.Stats.FailedFile(remote, err)
+	s.saveError(err)
+}
+
+// saveError saves err according to its type
+func (s *syncCopyMove) saveError(err error) {
 	s.errorMu.Lock()
 	defer s.errorMu.Unlock()
 	switch {
@@ -186,6 +277,15 @@ func (s *syncCopyMove) currentError() error {
 	return s.noRetryErr
 }
 
+// sendPair sends pair to ch, giving up if the sync is cancelled so a
+// fatal error can't leave it blocked forever
+func (s *syncCopyMove) sendPair(ch fs.ObjectPairChan, pair fs.ObjectPair) {
+	select {
+	case ch <- pair:
+	case <-s.ctx.Done():
+	}
+}
+
 // pairChecker reads Objects~s on in send to out if they need transferring.
 //
 // FIXME potentially doing lots of hashes at once
@@ -203,35 +303,34 @@ func (s *syncCopyMove) pairChecker(in fs.ObjectPairChan, out fs.ObjectPairChan,
 			src := pair.Src
 			accounting.Stats.Checking(src.Remote())
 			// Check to see if can store this
-			if src.Storable() {
-				if operations.NeedTransfer(pair.Dst, pair.Src) {
+			if src.Storable() && !s.alreadyDone(src) {
+				if operations.NeedTransfer(s.ctx, pair.Dst, pair.Src) {
 					// If files are treated as immutable, fail if destination exists and does not match
 					if fs.Config.Immutable && pair.Dst != nil {
 						fs.Errorf(pair.Dst, "Source and destination exist but do not match: immutable file modified")
-						s.processError(fs.ErrorImmutableModified)
+						s.processFileError(src.Remote(), fs.ErrorImmutableModified)
 					} else {
 						// If destination already exists, then we must move it into --backup-dir if required
 						if pair.Dst != nil && s.backupDir != nil {
-							remoteWithSuffix := pair.Dst.Remote() + s.suffix
-							overwritten, _ := s.backupDir.NewObject(remoteWithSuffix)
-							_, err := operations.Move(s.backupDir, overwritten, remoteWithSuffix, pair.Dst)
+							err := operations.MoveBackupDir(s.ctx, s.backupDir, pair.Dst)
 							if err != nil {
-								s.processError(err)
+								s.processFileError(src.Remote(), err)
 							} else {
 								// If successful zero out the dst as it is no longer there and copy the file
 								pair.Dst = nil
-								out <- pair
+								s.sendPair(out, pair)
 							}
 						} else {
-							out <- pair
+							s.sendPair(out, pair)
 						}
 					}
 				} else {
 					// If moving need to delete the files we don't need to copy
 					if s.DoMove {
 						// Delete src if no error on copy
-						s.processError(operations.DeleteFile(src))
+						s.processFileError(src.Remote(), operations.DeleteFile(s.ctx, src))
 					}
+					s.recordDone(src)
 				}
 			}
 			accounting.Stats.DoneChecking(src.Remote())
@@ -255,9 +354,9 @@ func (s *syncCopyMove) pairRenamer(in fs.ObjectPairChan, out fs.ObjectPairChan,
 				return
 			}
 			src := pair.Src
-			if !s.tryRename(src) {
-				// pass on if not renamed
-				out <- pair
+			if !s.tryRename(src) && !s.tryCopyByHash(src) {
+				// pass on if not renamed or copied
+				s.sendPair(out, pair)
 			}
 		case <-s.ctx.Done():
 			return
@@ -281,11 +380,14 @@ func (s *syncCopyMove) pairCopyOrMove(in fs.ObjectPairChan, fdst fs.Fs, wg *sync
 			src := pair.Src
 			accounting.Stats.Transferring(src.Remote())
 			if s.DoMove {
-				_, err = operations.Move(fdst, pair.Dst, src.Remote(), src)
+				_, err = operations.Move(s.ctx, fdst, pair.Dst, src.Remote(), src)
 			} else {
-				_, err = operations.Copy(fdst, pair.Dst, src.Remote(), src)
+				_, err = operations.Copy(s.ctx, fdst, pair.Dst, src.Remote(), src)
+			}
+			s.processFileError(src.Remote(), err)
+			if err == nil {
+				s.recordDone(src)
 			}
-			s.processError(err)
 			accounting.Stats.DoneTransferring(src.Remote(), err == nil)
 		case <-s.ctx.Done():
 			return
@@ -293,6 +395,23 @@ func (s *syncCopyMove) pairCopyOrMove(in fs.ObjectPairChan, fdst fs.Fs, wg *sync
 	}
 }
 
+// alreadyDone returns true if src was finished by an interrupted
+// run according to the --job-state-file.
+func (s *syncCopyMove) alreadyDone(src fs.Object) bool {
+	if s.jobState == nil || !s.jobState.IsDone(src) {
+		return false
+	}
+	fs.Debugf(src, "Skipping as finished by a previous run according to the job state file")
+	return true
+}
+
+// recordDone records src as finished in the --job-state-file.
+func (s *syncCopyMove) recordDone(src fs.Object) {
+	if s.jobState != nil {
+		s.jobState.Done(src)
+	}
+}
+
 // This starts the background checkers.
 func (s *syncCopyMove) startCheckers() {
 	s.checkerWg.Add(fs.Config.Checkers)
@@ -323,9 +442,15 @@ func (s *syncCopyMove) stopTransfers() {
 	s.transfersWg.Wait()
 }
 
+// deferUploads returns true if files only in the source need
+// checking against the destination before being uploaded.
+func (s *syncCopyMove) deferUploads() bool {
+	return s.trackRenames || s.copyByHash
+}
+
 // This starts the background renamers.
 func (s *syncCopyMove) startRenamers() {
-	if !s.trackRenames {
+	if !s.deferUploads() {
 		return
 	}
 	s.renamerWg.Add(fs.Config.Checkers)
@@ -336,7 +461,7 @@ func (s *syncCopyMove) startRenamers() {
 
 // This stops the background renamers
 func (s *syncCopyMove) stopRenamers() {
-	if !s.trackRenames {
+	if !s.deferUploads() {
 		return
 	}
 	close(s.toBeRenamed)
@@ -346,7 +471,7 @@ func (s *syncCopyMove) stopRenamers() {
 
 // This starts the collection of possible renames
 func (s *syncCopyMove) startTrackRenames() {
-	if !s.trackRenames {
+	if !s.deferUploads() {
 		return
 	}
 	s.trackRenamesWg.Add(1)
@@ -360,7 +485,7 @@ func (s *syncCopyMove) startTrackRenames() {
 
 // This stops the background rename collection
 func (s *syncCopyMove) stopTrackRenames() {
-	if !s.trackRenames {
+	if !s.deferUploads() {
 		return
 	}
 	close(s.trackRenamesCh)
@@ -375,7 +500,7 @@ func (s *syncCopyMove) startDeleters() {
 	s.deletersWg.Add(1)
 	go func() {
 		defer s.deletersWg.Done()
-		err := operations.DeleteFilesWithBackupDir(s.deleteFilesCh, s.backupDir)
+		err := operations.DeleteFilesWithBackupDir(s.ctx, s.deleteFilesCh, s.backupDir)
 		s.processError(err)
 	}()
 }
@@ -417,12 +542,52 @@ func (s *syncCopyMove) deleteFiles(checkSrcMap bool) error {
 		}
 		close(toDelete)
 	}()
-	return operations.DeleteFilesWithBackupDir(toDelete, s.backupDir)
+	return operations.DeleteFilesWithBackupDir(s.ctx, toDelete, s.backupDir)
+}
+
+// This creates the directories in the map passed in on f.  It
+// carries on after errors creating directories but counts them.
+func copyEmptyDirectories(ctx context.Context, f fs.Fs, entries map[string]fs.DirEntry) error {
+	if len(entries) == 0 {
+		return nil
+	}
+
+	// Create the directories starting from the shortest path
+	dirs := make(fs.DirEntries, 0, len(entries))
+	for _, entry := range entries {
+		dirs = append(dirs, entry)
+	}
+	sort.Sort(dirs)
+	var errorCount int
+	var okCount int
+	for _, entry := range dirs {
+		dir, ok := entry.(fs.Directory)
+		if ok {
+			err := operations.Mkdir(ctx, f, dir.Remote())
+			if err != nil {
+				fs.CountError(err)
+				fs.Errorf(fs.LogDirName(f, dir.Remote()), "Failed to Mkdir: %v", err)
+				errorCount++
+			} else {
+				okCount++
+			}
+		} else {
+			fs.Errorf(f, "Not a directory: %v", entry)
+		}
+	}
+	if errorCount > 0 {
+		fs.Debugf(f, "failed to copy %d directories", errorCount)
+		return errors.Errorf("failed to copy %d directories", errorCount)
+	}
+	if okCount > 0 {
+		fs.Debugf(f, "copied %d directories", okCount)
+	}
+	return nil
 }
 
 // This deletes the empty directories in the slice passed in.  It
 // ignores any errors deleting directories
-func deleteEmptyDirectories(f fs.Fs, entries fs.DirEntries) error {
+func deleteEmptyDirectories(ctx context.Context, f fs.Fs, entries fs.DirEntries) error {
 	if len(entries) == 0 {
 		return nil
 	}
@@ -440,7 +605,7 @@ func deleteEmptyDirectories(f fs.Fs, entries fs.DirEntries) error {
 		dir, ok := entry.(fs.Directory)
 		if ok {
 			// TryRmdir only deletes empty directories
-			err := operations.TryRmdir(f, dir.Remote())
+			err := operations.TryRmdir(ctx, f, dir.Remote())
 			if err != nil {
 				fs.Debugf(fs.LogDirName(f, dir.Remote()), "Failed to Rmdir: %v", err)
 				errorCount++
@@ -476,13 +641,6 @@ func (s *syncCopyMove) renameHash(obj fs.Object) (hash string) {
 	return fmt.Sprintf("%d,%s", obj.Size(), hash)
 }
 
-// pushRenameMap adds the object with hash to the rename map
-func (s *syncCopyMove) pushRenameMap(hash string, obj fs.Object) {
-	s.renameMapMu.Lock()
-	s.renameMap[hash] = append(s.renameMap[hash], obj)
-	s.renameMapMu.Unlock()
-}
-
 // popRenameMap finds the object with hash and pop the first match from
 // renameMap or returns nil if not found.
 func (s *syncCopyMove) popRenameMap(hash string) (dst fs.Object) {
@@ -500,10 +658,9 @@ func (s *syncCopyMove) popRenameMap(hash string) (dst fs.Object) {
 	return dst
 }
 
-// makeRenameMap builds a map of the destination files by hash that
-// match sizes in the slice of objects in s.renameCheck
-func (s *syncCopyMove) makeRenameMap() {
-	fs.Infof(s.fdst, "Making map for --track-renames")
+// makeHashMap builds a map of the files passed in by hash that match
+// sizes in the slice of objects in s.renameCheck
+func (s *syncCopyMove) makeHashMap(files map[string]fs.Object) map[string][]fs.Object {
 
 	// first make a map of possible sizes we need to check
 	possibleSizes := map[int64]struct{}{}
@@ -511,13 +668,16 @@ func (s *syncCopyMove) makeRenameMap() {
 		possibleSizes[obj.Size()] = struct{}{}
 	}
 
-	// pump all the dstFiles into in
+	// pump all the files into in
 	in := make(chan fs.Object, fs.Config.Checkers)
-	go s.pumpMapToChan(s.dstFiles, in)
+	go s.pumpMapToChan(files, in)
 
-	// now make a map of size,hash for all dstFiles
-	s.renameMap = make(map[string][]fs.Object)
-	var wg sync.WaitGroup
+	// now make a map of size,hash for all files
+	var (
+		hashMapMu sync.Mutex
+		hashMap   = make(map[string][]fs.Object)
+		wg        sync.WaitGroup
+	)
 	wg.Add(fs.Config.Transfers)
 	for i := 0; i < fs.Config.Transfers; i++ {
 		go func() {
@@ -528,7 +688,9 @@ func (s *syncCopyMove) makeRenameMap() {
 					accounting.Stats.Checking(obj.Remote())
 					hash := s.renameHash(obj)
 					if hash != "" {
-						s.pushRenameMap(hash, obj)
+						hashMapMu.Lock()
+						hashMap[hash] = append(hashMap[hash], obj)
+						hashMapMu.Unlock()
 					}
 					accounting.Stats.DoneChecking(obj.Remote())
 				}
@@ -536,12 +698,31 @@ func (s *syncCopyMove) makeRenameMap() {
 		}()
 	}
 	wg.Wait()
+	return hashMap
+}
+
+// makeRenameMap builds a map of the remaining destination files by
+// hash for --track-renames
+func (s *syncCopyMove) makeRenameMap() {
+	fs.Infof(s.fdst, "Making map for --track-renames")
+	s.renameMap = s.makeHashMap(s.dstFiles)
 	fs.Infof(s.fdst, "Finished making map for --track-renames")
 }
 
+// makeCopyMap builds a map of all the destination files by hash for
+// --copy-by-hash
+func (s *syncCopyMove) makeCopyMap() {
+	fs.Infof(s.fdst, "Making map for --copy-by-hash")
+	s.copyMap = s.makeHashMap(s.dstAllFiles)
+	fs.Infof(s.fdst, "Finished making map for --copy-by-hash")
+}
+
 // tryRename renames a src object when doing track renames if
 // possible, it returns true if the object was renamed.
 func (s *syncCopyMove) tryRename(src fs.Object) bool {
+	if !s.trackRenames {
+		return false
+	}
 	accounting.Stats.Checking(src.Remote())
 	defer accounting.Stats.DoneChecking(src.Remote())
 
@@ -558,10 +739,10 @@ func (s *syncCopyMove) tryRename(src fs.Object) bool {
 	}
 
 	// Find dst object we are about to overwrite if it exists
-	dstOverwritten, _ := s.fdst.NewObject(src.Remote())
+	dstOverwritten, _ := s.fdst.NewObject(s.ctx, src.Remote())
 
 	// Rename dst to have name src.Remote()
-	_, err := operations.Move(s.fdst, dstOverwritten, src.Remote(), dst)
+	_, err := operations.Move(s.ctx, s.fdst, dstOverwritten, src.Remote(), dst)
 	if err != nil {
 		fs.Debugf(src, "Failed to rename to %q: %v", dst.Remote(), err)
 		return false
@@ -576,6 +757,68 @@ func (s *syncCopyMove) tryRename(src fs.Object) bool {
 	return true
 }
 
+// tryCopyByHash server side copies an object already on fdst with
+// the same size and hash as src to src.Remote() instead of uploading
+// src, it returns true if the object was copied.
+func (s *syncCopyMove) tryCopyByHash(src fs.Object) bool {
+	if !s.copyByHash {
+		return false
+	}
+	accounting.Stats.Checking(src.Remote())
+	defer accounting.Stats.DoneChecking(src.Remote())
+
+	// Calculate the hash of the src object
+	hash := s.renameHash(src)
+	if hash == "" {
+		return false
+	}
+
+	// Get a match on fdst - the copyMap isn't modified after
+	// being made so doesn't need locking
+	dsts := s.copyMap[hash]
+	if len(dsts) == 0 {
+		return false
+	}
+	dst := dsts[0]
+
+	// Find dst object we are about to overwrite if it exists
+	dstOverwritten, _ := s.fdst.NewObject(s.ctx, src.Remote())
+
+	// Copy dst to have name src.Remote()
+	newDst, err := operations.Copy(s.ctx, s.fdst, dstOverwritten, src.Remote(), dst)
+	if err != nil {
+		fs.Debugf(src, "Failed to copy from %q: %v", dst.Remote(), err)
+		return false
+	}
+	if newDst != nil && !fs.Config.DryRun {
+		// Check the copy in case dst was overwritten during the sync
+		if newHash := s.renameHash(newDst); newHash != hash {
+			fs.Debugf(src, "Copy from %q has wrong hash - uploading instead", dst.Remote())
+			return false
+		}
+		err = newDst.SetModTime(s.ctx, src.ModTime())
+		if err != nil {
+			fs.Debugf(newDst, "Failed to set modification time: %v", err)
+		}
+	}
+	fs.Infof(src, "Copied (server side) from %q", dst.Remote())
+
+	if s.DoMove {
+		s.processFileError(src.Remote(), operations.DeleteFile(s.ctx, src))
+	}
+	return true
+}
+
+// recordCopyByHash records dst as a possible source for --copy-by-hash
+func (s *syncCopyMove) recordCopyByHash(dst fs.Object) {
+	if !s.copyByHash {
+		return
+	}
+	s.dstFilesMu.Lock()
+	s.dstAllFiles[dst.Remote()] = dst
+	s.dstFilesMu.Unlock()
+}
+
 // Syncs fsrc into fdst
 //
 // If Delete is true then it deletes any files in fdst that aren't in fsrc
@@ -583,7 +826,28 @@ func (s *syncCopyMove) tryRename(src fs.Object) bool {
 // If DoMove is true then files will be moved instead of copied
 //
 // dir is the start directory, "" for root
-func (s *syncCopyMove) run() error {
+func (s *syncCopyMove) run() (err error) {
+	if s.jobState != nil {
+		// Forget the job if it finished, otherwise keep the
+		// journal for the next run
+		defer func() {
+			closeErr := s.jobState.Close(err == nil)
+			if closeErr != nil {
+				fs.Errorf(s.fdst, "%v", closeErr)
+				fs.CountError(closeErr)
+			}
+		}()
+	}
+	if s.dstListCache != nil {
+		// Save the dst listings for next time
+		defer func() {
+			err := s.dstListCache.Close()
+			if err != nil {
+				fs.Errorf(s.fdst, "%v", err)
+				fs.CountError(err)
+			}
+		}()
+	}
 	if operations.Same(s.fdst, s.fsrc) {
 		fs.Errorf(s.fdst, "Nothing to do as source and destination are the same")
 		return nil
@@ -595,20 +859,38 @@ func (s *syncCopyMove) run() error {
 	s.startTransfers()
 	s.startDeleters()
 	s.dstFiles = make(map[string]fs.Object)
+	s.dstAllFiles = make(map[string]fs.Object)
 
 	s.startTrackRenames()
 
 	// set up a march over fdst and fsrc
 	m := march.New(s.ctx, s.fdst, s.fsrc, s.dir, s)
+	if fs.Config.NoCheckDest {
+		// Treat the destination as empty so everything is copied
+		m.SetDstListFn(func(ctx context.Context, dir string) (fs.DirEntries, error) {
+			return nil, nil
+		})
+	} else if s.noTraverse {
+		m.SetNoTraverse()
+	} else if s.dstListCache != nil {
+		m.SetDstListFn(s.dstListCache.List)
+	}
 	m.Run()
 
 	s.stopTrackRenames()
 	if s.trackRenames {
 		// Build the map of the remaining dstFiles by hash
 		s.makeRenameMap()
-		// Attempt renames for all the files which don't have a matching dst
+	}
+	if s.copyByHash {
+		// Build the map of all the dstFiles by hash
+		s.makeCopyMap()
+	}
+	if s.deferUploads() {
+		// Attempt renames or copies for all the files which don't
+		// have a matching dst
 		for _, src := range s.renameCheck {
-			s.toBeRenamed <- fs.ObjectPair{Src: src, Dst: nil}
+			s.sendPair(s.toBeRenamed, fs.ObjectPair{Src: src, Dst: nil})
 		}
 	}
 
@@ -621,6 +903,8 @@ func (s *syncCopyMove) run() error {
 	// Delete files after
 	if s.deleteMode == fs.DeleteModeAfter {
 		if s.currentError() != nil {
+			// the deletions need a complete pass to retry
+			accounting.Stats.RetryAll()
 			fs.Errorf(s.fdst, "%v", fs.ErrorNotDeleting)
 		} else {
 			s.processError(s.deleteFiles(false))
@@ -630,30 +914,127 @@ func (s *syncCopyMove) run() error {
 	// Prune empty directories
 	if s.deleteMode != fs.DeleteModeOff {
 		if s.currentError() != nil {
+			accounting.Stats.RetryAll()
 			fs.Errorf(s.fdst, "%v", fs.ErrorNotDeletingDirs)
 		} else {
-			s.processError(deleteEmptyDirectories(s.fdst, s.dstEmptyDirs))
+			s.processError(deleteEmptyDirectories(s.ctx, s.fdst, s.dstEmptyDirs))
 		}
 	}
 
+	// Create the empty directories from fsrc on fdst
+	if s.copyEmptySrcDirs {
+		s.processError(copyEmptyDirectories(s.ctx, s.fdst, s.srcNewDirs))
+	}
+
+	// Set the directory modification times now the transfers
+	// have finished writing into them
+	s.setDirModTimes()
+
 	// Delete empty fsrc subdirectories
 	// if DoMove and --delete-empty-src-dirs flag is set
 	if s.DoMove && s.deleteEmptySrcDirs {
 		//delete empty subdirectories that were part of the move
-		s.processError(deleteEmptyDirectories(s.fsrc, s.srcEmptyDirs))
+		s.processError(deleteEmptyDirectories(s.ctx, s.fsrc, s.srcEmptyDirs))
 	}
 	return s.currentError()
 }
 
+// srcParentDirCheck removes the parent directory of entry from
+// srcNewDirs as it isn't empty
+func (s *syncCopyMove) srcParentDirCheck(entry fs.DirEntry) {
+	if !s.copyEmptySrcDirs {
+		return
+	}
+	parentDir := path.Dir(entry.Remote())
+	if parentDir == "." {
+		parentDir = ""
+	}
+	s.srcNewDirsMu.Lock()
+	delete(s.srcNewDirs, parentDir)
+	s.srcNewDirsMu.Unlock()
+}
+
+// recordSrcNewDir records the src only directory so it can be
+// created on the destination if it turns out to be empty
+func (s *syncCopyMove) recordSrcNewDir(src fs.Directory) {
+	if !s.copyEmptySrcDirs {
+		return
+	}
+	s.srcNewDirsMu.Lock()
+	s.srcNewDirs[src.Remote()] = src
+	s.srcNewDirsMu.Unlock()
+}
+
+// recordDirModTime records the src directory so its modification
+// time can be set on the destination once the transfers are finished
+func (s *syncCopyMove) recordDirModTime(src fs.Directory) {
+	if s.fdst.Features().DirSetModTime == nil || fs.Config.NoUpdateDirModTime || src.ModTime().IsZero() {
+		return
+	}
+	s.dirModTimesMu.Lock()
+	s.dirModTimes = append(s.dirModTimes, src)
+	s.dirModTimesMu.Unlock()
+}
+
+// setDirModTimes sets the modification times of the destination
+// directories to match the source directories recorded
+//
+// Directories which weren't created on the destination (eg because
+// they were empty) are skipped.
+func (s *syncCopyMove) setDirModTimes() {
+	for _, dir := range s.dirModTimes {
+		if s.aborting() {
+			return
+		}
+		err := operations.SetDirModTime(s.ctx, s.fdst, dir.Remote(), dir.ModTime())
+		if err == fs.ErrorDirNotFound {
+			fs.Debugf(fs.LogDirName(s.fdst, dir.Remote()), "Not setting modification time as directory not found")
+			continue
+		}
+		s.processError(err)
+	}
+}
+
+// dstChanged records that the directory containing entry in the
+// destination may be changed by the sync, so its listing mustn't be
+// cached.
+func (s *syncCopyMove) dstChanged(entry fs.DirEntry) {
+	if s.dstListCache != nil {
+		s.dstListCache.DirtyParent(entry.Remote())
+	}
+}
+
+// sameSizeAndModTime returns true if src and dst have the same size
+// and modification time, in which case the sync won't change dst.
+func sameSizeAndModTime(src, dst fs.Object) bool {
+	if src.Size() != dst.Size() {
+		return false
+	}
+	dt := dst.ModTime().Sub(src.ModTime())
+	return dt < fs.Config.ModifyWindow && dt > -fs.Config.ModifyWindow
+}
+
 // DstOnly have an object which is in the destination only
 func (s *syncCopyMove) DstOnly(dst fs.DirEntry) (recurse bool) {
 	if s.deleteMode == fs.DeleteModeOff {
+		if !s.copyByHash {
+			return false
+		}
+		// Carry on listing the destination for --copy-by-hash
+		switch x := dst.(type) {
+		case fs.Object:
+			s.recordCopyByHash(x)
+		case fs.Directory:
+			return true
+		}
 		return false
 	}
+	s.dstChanged(dst)
 	switch x := dst.(type) {
 	case fs.Object:
 		switch s.deleteMode {
 		case fs.DeleteModeAfter:
+			s.recordCopyByHash(x)
 			// record object as needs deleting
 			s.dstFilesMu.Lock()
 			s.dstFiles[x.Remote()] = x
@@ -684,14 +1065,16 @@ func (s *syncCopyMove) SrcOnly(src fs.DirEntry) (recurse bool) {
 	if s.deleteMode == fs.DeleteModeOnly {
 		return false
 	}
+	s.srcParentDirCheck(src)
+	s.dstChanged(src)
 	switch x := src.(type) {
 	case fs.Object:
-		if s.trackRenames {
-			// Save object to check for a rename later
+		if s.deferUploads() {
+			// Save object to check for a rename or copy later
 			s.trackRenamesCh <- x
-		} else {
+		} else if !s.alreadyDone(x) {
 			// No need to check since doesn't exist
-			s.toBeUploaded <- fs.ObjectPair{Src: x, Dst: nil}
+			s.sendPair(s.toBeUploaded, fs.ObjectPair{Src: x, Dst: nil})
 		}
 	case fs.Directory:
 		// Do the same thing to the entire contents of the directory
@@ -699,6 +1082,8 @@ func (s *syncCopyMove) SrcOnly(src fs.DirEntry) (recurse bool) {
 		s.srcEmptyDirsMu.Lock()
 		s.srcEmptyDirs = append(s.srcEmptyDirs, src)
 		s.srcEmptyDirsMu.Unlock()
+		s.recordSrcNewDir(x)
+		s.recordDirModTime(x)
 		return true
 	default:
 		panic("Bad object in DirEntries")
@@ -708,6 +1093,7 @@ func (s *syncCopyMove) SrcOnly(src fs.DirEntry) (recurse bool) {
 
 // Match is called when src and dst are present, so sync src to dst
 func (s *syncCopyMove) Match(dst, src fs.DirEntry) (recurse bool) {
+	s.srcParentDirCheck(src)
 	switch srcX := src.(type) {
 	case fs.Object:
 		if s.deleteMode == fs.DeleteModeOnly {
@@ -715,12 +1101,21 @@ func (s *syncCopyMove) Match(dst, src fs.DirEntry) (recurse bool) {
 		}
 		dstX, ok := dst.(fs.Object)
 		if ok {
-			s.toBeChecked <- fs.ObjectPair{Src: srcX, Dst: dstX}
+			if !sameSizeAndModTime(srcX, dstX) {
+				s.dstChanged(dst)
+			}
+			s.recordCopyByHash(dstX)
+			if fs.Config.IgnoreExisting && !s.DoMove {
+				// No need to check as existing files are skipped
+				fs.Debugf(src, "Destination exists, skipping")
+				return false
+			}
+			s.sendPair(s.toBeChecked, fs.ObjectPair{Src: srcX, Dst: dstX})
 		} else {
 			// FIXME src is file, dst is directory
 			err := errors.New("can't overwrite directory with file")
 			fs.Errorf(dst, "%v", err)
-			s.processError(err)
+			s.processFileError(src.Remote(), err)
 		}
 	case fs.Directory:
 		// Do the same thing to the entire contents of the directory
@@ -730,6 +1125,9 @@ func (s *syncCopyMove) Match(dst, src fs.DirEntry) (recurse bool) {
 			s.srcEmptyDirsMu.Lock()
 			s.srcEmptyDirs = append(s.srcEmptyDirs, src)
 			s.srcEmptyDirsMu.Unlock()
+			if s.deleteMode != fs.DeleteModeOnly {
+				s.recordDirModTime(srcX)
+			}
 			return true
 		}
 		// FIXME src is dir, dst is file
@@ -749,7 +1147,10 @@ func (s *syncCopyMove) Match(dst, src fs.DirEntry) (recurse bool) {
 // If DoMove is true then files will be moved instead of copied
 //
 // dir is the start directory, "" for root
-func runSyncCopyMove(fdst, fsrc fs.Fs, deleteMode fs.DeleteMode, DoMove bool, deleteEmptySrcDirs bool) error {
+//
+// If copyEmptySrcDirs is true then empty directories in fsrc will be
+// created in fdst
+func runSyncCopyMove(ctx context.Context, fdst, fsrc fs.Fs, deleteMode fs.DeleteMode, DoMove bool, deleteEmptySrcDirs bool, copyEmptySrcDirs bool) error {
 	if deleteMode != fs.DeleteModeOff && DoMove {
 		return fserrors.FatalError(errors.New("can't delete and move at the same time"))
 	}
@@ -759,7 +1160,7 @@ func runSyncCopyMove(fdst, fsrc fs.Fs, deleteMode fs.DeleteMode, DoMove bool, de
 			return fserrors.FatalError(errors.New("can't use --delete-before with --track-renames"))
 		}
 		// only delete stuff during in this pass
-		do, err := newSyncCopyMove(fdst, fsrc, fs.DeleteModeOnly, false, deleteEmptySrcDirs)
+		do, err := newSyncCopyMove(ctx, fdst, fsrc, fs.DeleteModeOnly, false, deleteEmptySrcDirs, false)
 		if err != nil {
 			return err
 		}
@@ -770,7 +1171,7 @@ func runSyncCopyMove(fdst, fsrc fs.Fs, deleteMode fs.DeleteMode, DoMove bool, de
 		// Next pass does a copy only
 		deleteMode = fs.DeleteModeOff
 	}
-	do, err := newSyncCopyMove(fdst, fsrc, deleteMode, DoMove, deleteEmptySrcDirs)
+	do, err := newSyncCopyMove(ctx, fdst, fsrc, deleteMode, DoMove, deleteEmptySrcDirs, copyEmptySrcDirs)
 	if err != nil {
 		return err
 	}
@@ -778,22 +1179,22 @@ func runSyncCopyMove(fdst, fsrc fs.Fs, deleteMode fs.DeleteMode, DoMove bool, de
 }
 
 // Sync fsrc into fdst
-func Sync(fdst, fsrc fs.Fs) error {
-	return runSyncCopyMove(fdst, fsrc, fs.Config.DeleteMode, false, false)
+func Sync(ctx context.Context, fdst, fsrc fs.Fs, copyEmptySrcDirs bool) error {
+	return runSyncCopyMove(ctx, fdst, fsrc, fs.Config.DeleteMode, false, false, copyEmptySrcDirs)
 }
 
 // CopyDir copies fsrc into fdst
-func CopyDir(fdst, fsrc fs.Fs) error {
-	return runSyncCopyMove(fdst, fsrc, fs.DeleteModeOff, false, false)
+func CopyDir(ctx context.Context, fdst, fsrc fs.Fs, copyEmptySrcDirs bool) error {
+	return runSyncCopyMove(ctx, fdst, fsrc, fs.DeleteModeOff, false, false, copyEmptySrcDirs)
 }
 
 // moveDir moves fsrc into fdst
-func moveDir(fdst, fsrc fs.Fs, deleteEmptySrcDirs bool) error {
-	return runSyncCopyMove(fdst, fsrc, fs.DeleteModeOff, true, deleteEmptySrcDirs)
+func moveDir(ctx context.Context, fdst, fsrc fs.Fs, deleteEmptySrcDirs bool, copyEmptySrcDirs bool) error {
+	return runSyncCopyMove(ctx, fdst, fsrc, fs.DeleteModeOff, true, deleteEmptySrcDirs, copyEmptySrcDirs)
 }
 
 // MoveDir moves fsrc into fdst
-func MoveDir(fdst, fsrc fs.Fs, deleteEmptySrcDirs bool) error {
+func MoveDir(ctx context.Context, fdst, fsrc fs.Fs, deleteEmptySrcDirs bool, copyEmptySrcDirs bool) error {
 	if operations.Same(fdst, fsrc) {
 		fs.Errorf(fdst, "Nothing to do as source and destination are the same")
 		return nil
@@ -801,12 +1202,11 @@ func MoveDir(fdst, fsrc fs.Fs, deleteEmptySrcDirs bool) error {
 
 	// First attempt to use DirMover if exists, same Fs and no filters are active
 	if fdstDirMove := fdst.Features().DirMove; fdstDirMove != nil && operations.SameConfig(fsrc, fdst) && filter.Active.InActive() {
-		if fs.Config.DryRun {
-			fs.Logf(fdst, "Not doing server side directory move as --dry-run")
+		if operations.SkipDestructive(fsrc, "server side directory move") {
 			return nil
 		}
 		fs.Debugf(fdst, "Using server side directory move")
-		err := fdstDirMove(fsrc, "", "")
+		err := fdstDirMove(ctx, fsrc, "", "")
 		switch err {
 		case fs.ErrorCantDirMove, fs.ErrorDirExists:
 			fs.Infof(fdst, "Server side directory move failed - fallback to file moves: %v", err)
@@ -828,5 +1228,5 @@ func MoveDir(fdst, fsrc fs.Fs, deleteEmptySrcDirs bool) error {
 	}
 
 	// Otherwise move the files one by one
-	return moveDir(fdst, fsrc, deleteEmptySrcDirs)
+	return moveDir(ctx, fdst, fsrc, deleteEmptySrcDirs, copyEmptySrcDirs)
 }
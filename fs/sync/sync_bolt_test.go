@@ -0,0 +1,113 @@
+// Test sync with the bbolt backed --dst-list-cache and
+// --job-state-file
+
+// +build !plan9,go1.7
+
+package sync
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/ncw/rclone/fs"
+	"github.com/ncw/rclone/fs/accounting"
+	"github.com/ncw/rclone/fs/jobstate"
+	"github.com/ncw/rclone/fstest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/net/context"
+)
+
+// Test a sync with --dst-list-cache
+func TestSyncWithDstListCache(t *testing.T) {
+	ctx := context.Background()
+	r := fstest.NewRun(t)
+	defer r.Finalise()
+
+	dir, err := ioutil.TempDir("", "rclone-list-cache")
+	require.NoError(t, err)
+	defer func() {
+		_ = os.RemoveAll(dir)
+	}()
+	fs.Config.DstListCache = filepath.Join(dir, "list.db")
+	defer func() {
+		fs.Config.DstListCache = ""
+	}()
+
+	f1 := r.WriteFile("potato", "Potato Content", t1)
+	f2 := r.WriteFile("dir/yam", "Yam Content", t2)
+
+	// First sync uploads, the second caches the listings
+	for i := 0; i < 2; i++ {
+		accounting.Stats.ResetCounters()
+		require.NoError(t, Sync(ctx, r.Fremote, r.Flocal, false))
+		fstest.CheckItems(t, r.Fremote, f1, f2)
+	}
+	assert.Equal(t, int64(0), accounting.Stats.GetTransfers())
+
+	// A file added behind rclone's back isn't seen as the root
+	// listing comes from the cache
+	extra := r.WriteObject("extra", "Extra Content", t3)
+	accounting.Stats.ResetCounters()
+	require.NoError(t, Sync(ctx, r.Fremote, r.Flocal, false))
+	fstest.CheckItems(t, r.Fremote, f1, f2, extra)
+
+	// Changing a file transfers it and invalidates the root listing
+	f1 = r.WriteFile("potato", "Potato Content changed", t3)
+	accounting.Stats.ResetCounters()
+	require.NoError(t, Sync(ctx, r.Fremote, r.Flocal, false))
+	assert.Equal(t, int64(1), accounting.Stats.GetTransfers())
+	fstest.CheckItems(t, r.Fremote, f1, f2, extra)
+
+	// So the next sync lists the root again and deletes the extra file
+	accounting.Stats.ResetCounters()
+	require.NoError(t, Sync(ctx, r.Fremote, r.Flocal, false))
+	fstest.CheckItems(t, r.Fremote, f1, f2)
+}
+
+// Test a copy restarted with --job-state-file
+func TestCopyWithJobStateFile(t *testing.T) {
+	ctx := context.Background()
+	r := fstest.NewRun(t)
+	defer r.Finalise()
+
+	dir, err := ioutil.TempDir("", "rclone-job-state")
+	require.NoError(t, err)
+	defer func() {
+		_ = os.RemoveAll(dir)
+	}()
+	fs.Config.JobStateFile = filepath.Join(dir, "job.db")
+	defer func() {
+		fs.Config.JobStateFile = ""
+	}()
+
+	f1 := r.WriteFile("potato", "Potato Content", t1)
+	f2 := r.WriteFile("dir/yam", "Yam Content", t2)
+	f3 := r.WriteFile("sausage", "Sausage Content", t1)
+
+	// Pretend an interrupted run finished potato and sausage
+	journal, err := jobstate.Open(r.Flocal, r.Fremote, fs.Config.JobStateFile)
+	require.NoError(t, err)
+	for _, remote := range []string{"potato", "sausage"} {
+		src, err := r.Flocal.NewObject(ctx, remote)
+		require.NoError(t, err)
+		journal.Done(src)
+	}
+	require.NoError(t, journal.Close(false))
+
+	// Change sausage since then so it isn't skipped
+	f3 = r.WriteFile("sausage", "Sausage Content changed", t3)
+
+	accounting.Stats.ResetCounters()
+	require.NoError(t, CopyDir(ctx, r.Fremote, r.Flocal, false))
+	assert.Equal(t, int64(2), accounting.Stats.GetTransfers())
+	fstest.CheckItems(t, r.Fremote, f2, f3)
+
+	// The job finished so the next run checks everything
+	accounting.Stats.ResetCounters()
+	require.NoError(t, CopyDir(ctx, r.Fremote, r.Flocal, false))
+	assert.Equal(t, int64(1), accounting.Stats.GetTransfers())
+	fstest.CheckItems(t, r.Fremote, f1, f2, f3)
+}
@@ -14,6 +14,7 @@ import (
 	"github.com/ncw/rclone/fs/filter"
 	"github.com/ncw/rclone/fs/list"
 	"github.com/pkg/errors"
+	"golang.org/x/net/context"
 )
 
 // ErrorSkipDir is used as a return value from Walk to indicate that the
@@ -55,35 +56,35 @@ type Func func(path string, entries fs.DirEntries, err error) error
 // and f supports it and level > 1, or WalkN otherwise.
 //
 // NB (f, path) to be replaced by fs.Dir at some point
-func Walk(f fs.Fs, path string, includeAll bool, maxLevel int, fn Func) error {
+func Walk(ctx context.Context, f fs.Fs, path string, includeAll bool, maxLevel int, fn Func) error {
 	if (maxLevel < 0 || maxLevel > 1) && fs.Config.UseListR && f.Features().ListR != nil {
-		return walkListR(f, path, includeAll, maxLevel, fn)
+		return walkListR(ctx, f, path, includeAll, maxLevel, fn)
 	}
-	return walkListDirSorted(f, path, includeAll, maxLevel, fn)
+	return walkListDirSorted(ctx, f, path, includeAll, maxLevel, fn)
 }
 
 // walkListDirSorted lists the directory.
 //
 // It implements Walk using non recursive directory listing.
-func walkListDirSorted(f fs.Fs, path string, includeAll bool, maxLevel int, fn Func) error {
-	return walk(f, path, includeAll, maxLevel, fn, list.DirSorted)
+func walkListDirSorted(ctx context.Context, f fs.Fs, path string, includeAll bool, maxLevel int, fn Func) error {
+	return walk(ctx, f, path, includeAll, maxLevel, fn, list.DirSorted)
 }
 
 // walkListR lists the directory.
 //
 // It implements Walk using recursive directory listing if
 // available, or returns ErrorCantListR if not.
-func walkListR(f fs.Fs, path string, includeAll bool, maxLevel int, fn Func) error {
+func walkListR(ctx context.Context, f fs.Fs, path string, includeAll bool, maxLevel int, fn Func) error {
 	listR := f.Features().ListR
 	if listR == nil {
 		return ErrorCantListR
 	}
-	return walkR(f, path, includeAll, maxLevel, fn, listR)
+	return walkR(ctx, f, path, includeAll, maxLevel, fn, listR)
 }
 
-type listDirFunc func(fs fs.Fs, includeAll bool, dir string) (entries fs.DirEntries, err error)
+type listDirFunc func(ctx context.Context, fs fs.Fs, includeAll bool, dir string) (entries fs.DirEntries, err error)
 
-func walk(f fs.Fs, path string, includeAll bool, maxLevel int, fn Func, listDir listDirFunc) error {
+func walk(ctx context.Context, f fs.Fs, path string, includeAll bool, maxLevel int, fn Func, listDir listDirFunc) error {
 	var (
 		wg         sync.WaitGroup // sync closing of go routines
 		traversing sync.WaitGroup // running directory traversals
@@ -119,7 +120,7 @@ func walk(f fs.Fs, path string, includeAll bool, maxLevel int, fn Func, listDir
 					if !ok {
 						return
 					}
-					entries, err := listDir(f, includeAll, job.remote)
+					entries, err := listDir(ctx, f, includeAll, job.remote)
 					var jobs []listJob
 					if err == nil && job.depth != 0 {
 						entries.ForDir(func(dir fs.Directory) {
@@ -349,14 +350,15 @@ func (dt DirTree) String() string {
 	return out.String()
 }
 
-func walkRDirTree(f fs.Fs, startPath string, includeAll bool, maxLevel int, listR fs.ListRFn) (DirTree, error) {
+func walkRDirTree(ctx context.Context, f fs.Fs, startPath string, includeAll bool, maxLevel int, listR fs.ListRFn) (DirTree, error) {
 	dirs := make(DirTree)
 	// Entries can come in arbitrary order. We use toPrune to keep
 	// all directories to exclude later.
 	toPrune := make(map[string]bool)
-	includeDirectory := filter.Active.IncludeDirectory(f)
+	includeDirectory := filter.Active.IncludeDirectory(ctx, f)
+	includeDirectoryTree := filter.Active.IncludeDirectoryTree(startPath)
 	var mu sync.Mutex
-	err := listR(startPath, func(entries fs.DirEntries) error {
+	err := listR(ctx, startPath, func(entries fs.DirEntries) error {
 		mu.Lock()
 		defer mu.Unlock()
 		for _, entry := range entries {
@@ -364,7 +366,8 @@ func walkRDirTree(f fs.Fs, startPath string, includeAll bool, maxLevel int, list
 			switch x := entry.(type) {
 			case fs.Object:
 				// Make sure we don't delete excluded files if not required
-				if includeAll || filter.Active.IncludeObject(x) {
+				// Objects in excluded directories are excluded too
+				if includeAll || (filter.Active.IncludeObject(x) && includeDirectoryTree(parentDir(x.Remote()))) {
 					if maxLevel < 0 || slashes <= maxLevel-1 {
 						dirs.add(x)
 					} else {
@@ -392,7 +395,7 @@ func walkRDirTree(f fs.Fs, startPath string, includeAll bool, maxLevel int, list
 				if err != nil {
 					return err
 				}
-				if includeAll || inc {
+				if includeAll || (inc && includeDirectoryTree(x.Remote())) {
 					if maxLevel < 0 || slashes <= maxLevel-1 {
 						if slashes == maxLevel-1 {
 							// Just add the object if at maxLevel
@@ -426,7 +429,7 @@ func walkRDirTree(f fs.Fs, startPath string, includeAll bool, maxLevel int, list
 }
 
 // Create a DirTree using List
-func walkNDirTree(f fs.Fs, path string, includeAll bool, maxLevel int, listDir listDirFunc) (DirTree, error) {
+func walkNDirTree(ctx context.Context, f fs.Fs, path string, includeAll bool, maxLevel int, listDir listDirFunc) (DirTree, error) {
 	dirs := make(DirTree)
 	fn := func(dirPath string, entries fs.DirEntries, err error) error {
 		if err == nil {
@@ -434,7 +437,7 @@ func walkNDirTree(f fs.Fs, path string, includeAll bool, maxLevel int, listDir l
 		}
 		return err
 	}
-	err := walk(f, path, includeAll, maxLevel, fn, listDir)
+	err := walk(ctx, f, path, includeAll, maxLevel, fn, listDir)
 	if err != nil {
 		return nil, err
 	}
@@ -453,15 +456,15 @@ func walkNDirTree(f fs.Fs, path string, includeAll bool, maxLevel int, listDir l
 // and f supports it and level > 1, or WalkN otherwise.
 //
 // NB (f, path) to be replaced by fs.Dir at some point
-func NewDirTree(f fs.Fs, path string, includeAll bool, maxLevel int) (DirTree, error) {
+func NewDirTree(ctx context.Context, f fs.Fs, path string, includeAll bool, maxLevel int) (DirTree, error) {
 	if ListR := f.Features().ListR; (maxLevel < 0 || maxLevel > 1) && fs.Config.UseListR && ListR != nil {
-		return walkRDirTree(f, path, includeAll, maxLevel, ListR)
+		return walkRDirTree(ctx, f, path, includeAll, maxLevel, ListR)
 	}
-	return walkNDirTree(f, path, includeAll, maxLevel, list.DirSorted)
+	return walkNDirTree(ctx, f, path, includeAll, maxLevel, list.DirSorted)
 }
 
-func walkR(f fs.Fs, path string, includeAll bool, maxLevel int, fn Func, listR fs.ListRFn) error {
-	dirs, err := walkRDirTree(f, path, includeAll, maxLevel, listR)
+func walkR(ctx context.Context, f fs.Fs, path string, includeAll bool, maxLevel int, fn Func, listR fs.ListRFn) error {
+	dirs, err := walkRDirTree(ctx, f, path, includeAll, maxLevel, listR)
 	if err != nil {
 		return err
 	}
@@ -494,9 +497,160 @@ func walkR(f fs.Fs, path string, includeAll bool, maxLevel int, fn Func, listR f
 	return nil
 }
 
+// ListType is used to choose which sort of entries ListR returns
+type ListType byte
+
+// Types of listing for ListR
+const (
+	ListObjects ListType = 1 << iota // list objects only
+	ListDirs                         // list dirs only
+	ListAll     = ListObjects | ListDirs
+)
+
+// includes returns true if entry should be listed for this ListType
+func (l ListType) includes(entry fs.DirEntry) bool {
+	switch entry.(type) {
+	case fs.Object:
+		return l&ListObjects != 0
+	case fs.Directory:
+		return l&ListDirs != 0
+	}
+	return false
+}
+
+// ListR lists the directory recursively calling fn with tranches of
+// the entries of listType.
+//
+// Unlike Walk the entries come in no particular order and aren't
+// gathered into whole directories first, so this uses much less
+// memory for large directories.
+//
+// If includeAll is not set it will use the filters defined.
+//
+// If maxLevel is < 0 then it will recurse indefinitely, else it will
+// only do maxLevel levels.
+//
+// fn will not be called concurrently.
+//
+// This streams the entries from the recursive listing of f if
+// Config.UseListR is set and f supports it, reading ahead up to
+// Config.ListBuffer entries while fn is busy.  Otherwise, or if the
+// filters need whole directories (eg --exclude-if-present), it uses
+// Walk.
+func ListR(ctx context.Context, f fs.Fs, path string, includeAll bool, maxLevel int, listType ListType, fn fs.ListRCallback) error {
+	listR := f.Features().ListR
+	if (maxLevel >= 0 && maxLevel <= 1) || !fs.Config.UseListR || listR == nil || (!includeAll && filter.Active.Opt.ExcludeFile != "") {
+		return Walk(ctx, f, path, includeAll, maxLevel, func(dirPath string, entries fs.DirEntries, err error) error {
+			if err != nil {
+				return err
+			}
+			var out fs.DirEntries
+			for _, entry := range entries {
+				if listType.includes(entry) {
+					out = append(out, entry)
+				}
+			}
+			if len(out) == 0 {
+				return nil
+			}
+			return fn(out)
+		})
+	}
+	return listRStream(ctx, f, path, includeAll, maxLevel, listType, fn, listR)
+}
+
+// listRStream implements ListR using the recursive listing listR
+func listRStream(ctx context.Context, f fs.Fs, path string, includeAll bool, maxLevel int, listType ListType, fn fs.ListRCallback, listR fs.ListRFn) error {
+	bufferSize := fs.Config.ListBuffer
+	if bufferSize < 1 {
+		bufferSize = 1
+	}
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	prefix := ""
+	if path != "" {
+		prefix = path + "/"
+	}
+	includeDirectory := filter.Active.IncludeDirectory(ctx, f)
+	includeDirectoryTree := filter.Active.IncludeDirectoryTree(path)
+	in := make(chan fs.DirEntry, bufferSize)
+	listErr := make(chan error, 1)
+	go func() {
+		defer close(in)
+		listErr <- listR(ctx, path, func(entries fs.DirEntries) error {
+			for _, entry := range entries {
+				if !listType.includes(entry) {
+					continue
+				}
+				if maxLevel >= 0 && strings.Count(strings.TrimPrefix(entry.Remote(), prefix), "/") > maxLevel-1 {
+					continue
+				}
+				if !includeAll {
+					switch x := entry.(type) {
+					case fs.Object:
+						if !filter.Active.IncludeObject(x) {
+							fs.Debugf(x, "Excluded from listing")
+							continue
+						}
+						if !includeDirectoryTree(parentDir(x.Remote())) {
+							fs.Debugf(x, "Excluded from listing")
+							continue
+						}
+					case fs.Directory:
+						include, err := includeDirectory(x.Remote())
+						if err != nil {
+							return err
+						}
+						if !include || !includeDirectoryTree(x.Remote()) {
+							fs.Debugf(x, "Excluded from listing")
+							continue
+						}
+					}
+				}
+				select {
+				case in <- entry:
+				case <-ctx.Done():
+					return ctx.Err()
+				}
+			}
+			return nil
+		})
+	}()
+
+	// Pass the entries to fn in tranches of what is ready
+	const maxTranche = 100
+	var err error
+	for entry := range in {
+		if err != nil {
+			continue // drain the channel after an error
+		}
+		entries := fs.DirEntries{entry}
+	tranche:
+		for len(entries) < maxTranche {
+			select {
+			case entry, ok := <-in:
+				if !ok {
+					break tranche
+				}
+				entries = append(entries, entry)
+			default:
+				break tranche
+			}
+		}
+		err = fn(entries)
+		if err != nil {
+			cancel()
+		}
+	}
+	if err != nil {
+		return err
+	}
+	return <-listErr
+}
+
 // GetAll runs Walk getting all the results
-func GetAll(f fs.Fs, path string, includeAll bool, maxLevel int) (objs []fs.Object, dirs []fs.Directory, err error) {
-	err = Walk(f, path, includeAll, maxLevel, func(dirPath string, entries fs.DirEntries, err error) error {
+func GetAll(ctx context.Context, f fs.Fs, path string, includeAll bool, maxLevel int) (objs []fs.Object, dirs []fs.Directory, err error) {
+	err = Walk(ctx, f, path, includeAll, maxLevel, func(dirPath string, entries fs.DirEntries, err error) error {
 		if err != nil {
 			return err
 		}
@@ -12,6 +12,7 @@ import (
 	"github.com/pkg/errors"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
+	"golang.org/x/net/context"
 )
 
 type (
@@ -65,7 +66,7 @@ func (ls *listDirs) SetLevel(maxLevel int) *listDirs {
 }
 
 // ListDir returns the expected listing for the directory
-func (ls *listDirs) ListDir(f fs.Fs, includeAll bool, dir string) (entries fs.DirEntries, err error) {
+func (ls *listDirs) ListDir(ctx context.Context, f fs.Fs, includeAll bool, dir string) (entries fs.DirEntries, err error) {
 	ls.mu.Lock()
 	defer ls.mu.Unlock()
 	assert.Equal(ls.t, ls.fs, f)
@@ -86,7 +87,7 @@ func (ls *listDirs) ListDir(f fs.Fs, includeAll bool, dir string) (entries fs.Di
 }
 
 // ListR returns the expected listing for the directory using ListR
-func (ls *listDirs) ListR(dir string, callback fs.ListRCallback) (err error) {
+func (ls *listDirs) ListR(ctx context.Context, dir string, callback fs.ListRCallback) (err error) {
 	ls.mu.Lock()
 	defer ls.mu.Unlock()
 
@@ -147,14 +148,16 @@ func (ls *listDirs) WalkFn(dir string, entries fs.DirEntries, err error) error {
 
 // Walk does the walk and tests the expectations
 func (ls *listDirs) Walk() {
-	err := walk(nil, "", ls.includeAll, ls.maxLevel, ls.WalkFn, ls.ListDir)
+	ctx := context.Background()
+	err := walk(ctx, nil, "", ls.includeAll, ls.maxLevel, ls.WalkFn, ls.ListDir)
 	assert.Equal(ls.t, ls.finalError, err)
 	ls.IsFinished()
 }
 
 // WalkR does the walkR and tests the expectations
 func (ls *listDirs) WalkR() {
-	err := walkR(nil, "", ls.includeAll, ls.maxLevel, ls.WalkFn, ls.ListR)
+	ctx := context.Background()
+	err := walkR(ctx, nil, "", ls.includeAll, ls.maxLevel, ls.WalkFn, ls.ListR)
 	assert.Equal(ls.t, ls.finalError, err)
 	if ls.finalError == nil {
 		ls.IsFinished()
@@ -262,8 +265,9 @@ func TestWalkLevelsNoRecursive10(t *testing.T)  { testWalkLevels(t, 10).Walk() }
 func TestWalkRLevelsNoRecursive10(t *testing.T) { testWalkLevels(t, 10).WalkR() }
 
 func TestWalkNDirTree(t *testing.T) {
+	ctx := context.Background()
 	ls := testWalkLevels(t, -1)
-	entries, err := walkNDirTree(nil, "", ls.includeAll, ls.maxLevel, ls.ListDir)
+	entries, err := walkNDirTree(ctx, nil, "", ls.includeAll, ls.maxLevel, ls.ListDir)
 	require.NoError(t, err)
 	assert.Equal(t, `/
   A
@@ -411,7 +415,7 @@ func TestWalkRMultiErrors(t *testing.T) { testWalkMultiErrors(t).Walk() }
 
 // a very simple listRcallback function
 func makeListRCallback(entries fs.DirEntries, err error) fs.ListRFn {
-	return func(dir string, callback fs.ListRCallback) error {
+	return func(ctx context.Context, dir string, callback fs.ListRCallback) error {
 		if err == nil {
 			err = callback(entries)
 		}
@@ -420,6 +424,7 @@ func makeListRCallback(entries fs.DirEntries, err error) fs.ListRFn {
 }
 
 func TestWalkRDirTree(t *testing.T) {
+	ctx := context.Background()
 	for _, test := range []struct {
 		entries fs.DirEntries
 		want    string
@@ -556,13 +561,14 @@ a/
   b/
 `, nil, "", 2},
 	} {
-		r, err := walkRDirTree(nil, test.root, true, test.level, makeListRCallback(test.entries, test.err))
+		r, err := walkRDirTree(ctx, nil, test.root, true, test.level, makeListRCallback(test.entries, test.err))
 		assert.Equal(t, test.err, err, fmt.Sprintf("%+v", test))
 		assert.Equal(t, test.want, r.String(), fmt.Sprintf("%+v", test))
 	}
 }
 
 func TestWalkRDirTreeExclude(t *testing.T) {
+	ctx := context.Background()
 	for _, test := range []struct {
 		entries     fs.DirEntries
 		want        string
@@ -627,10 +633,105 @@ b/c/d/
 `, nil, "", -1, "ign", true},
 	} {
 		filter.Active.Opt.ExcludeFile = test.excludeFile
-		r, err := walkRDirTree(nil, test.root, test.includeAll, test.level, makeListRCallback(test.entries, test.err))
+		r, err := walkRDirTree(ctx, nil, test.root, test.includeAll, test.level, makeListRCallback(test.entries, test.err))
 		assert.Equal(t, test.err, err, fmt.Sprintf("%+v", test))
 		assert.Equal(t, test.want, r.String(), fmt.Sprintf("%+v", test))
 	}
 	// Set to default value, to avoid side effects
 	filter.Active.Opt.ExcludeFile = ""
 }
+
+func TestWalkRDirTreePrune(t *testing.T) {
+	ctx := context.Background()
+	oldActive := filter.Active
+	defer func() {
+		filter.Active = oldActive
+	}()
+	var err error
+	filter.Active, err = filter.NewFilter(nil)
+	require.NoError(t, err)
+	require.NoError(t, filter.Active.AddRule("- /b/c/"))
+	entries := fs.DirEntries{
+		mockobject.Object("a"),
+		mockobject.Object("b/b"),
+		mockobject.Object("b/c/d/e"),
+		mockobject.Object("b/c/x"),
+		mockobject.Object("b/cc/x"),
+	}
+	r, err := walkRDirTree(ctx, nil, "", false, -1, makeListRCallback(entries, nil))
+	require.NoError(t, err)
+	assert.Equal(t, `/
+  a
+  b/
+b/
+  b
+  cc/
+b/cc/
+  x
+`, r.String())
+}
+
+func TestListRStream(t *testing.T) {
+	ctx := context.Background()
+	oldListBuffer := fs.Config.ListBuffer
+	fs.Config.ListBuffer = 2
+	defer func() {
+		fs.Config.ListBuffer = oldListBuffer
+	}()
+	var (
+		a    = mockobject.Object("a")
+		dir  = mockdir.New("dir")
+		b    = mockobject.Object("dir/b")
+		sub  = mockdir.New("dir/sub")
+		c    = mockobject.Object("dir/sub/c")
+		errC = errors.New("stop")
+	)
+	listR := func(ctx context.Context, path string, callback fs.ListRCallback) error {
+		err := callback(fs.DirEntries{a, dir})
+		if err != nil {
+			return err
+		}
+		return callback(fs.DirEntries{b, sub, c})
+	}
+	list := func(maxLevel int, listType ListType) (remotes []string, err error) {
+		err = listRStream(ctx, nil, "", false, maxLevel, listType, func(entries fs.DirEntries) error {
+			for _, entry := range entries {
+				remotes = append(remotes, entry.Remote())
+			}
+			return nil
+		}, listR)
+		return remotes, err
+	}
+
+	remotes, err := list(-1, ListAll)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"a", "dir", "dir/b", "dir/sub", "dir/sub/c"}, remotes)
+
+	remotes, err = list(-1, ListObjects)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"a", "dir/b", "dir/sub/c"}, remotes)
+
+	remotes, err = list(2, ListDirs)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"dir", "dir/sub"}, remotes)
+
+	remotes, err = list(2, ListAll)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"a", "dir", "dir/b", "dir/sub"}, remotes)
+
+	// Excluded directories are pruned along with their contents
+	oldActive := filter.Active
+	filter.Active, err = filter.NewFilter(nil)
+	require.NoError(t, err)
+	require.NoError(t, filter.Active.AddRule("- sub/"))
+	remotes, err = list(-1, ListAll)
+	filter.Active = oldActive
+	require.NoError(t, err)
+	assert.Equal(t, []string{"a", "dir", "dir/b"}, remotes)
+
+	// An error from the callback stops the listing
+	err = listRStream(ctx, nil, "", false, -1, ListAll, func(entries fs.DirEntries) error {
+		return errC
+	}, listR)
+	assert.Equal(t, errC, err)
+}
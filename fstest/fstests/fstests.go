@@ -29,6 +29,7 @@ import (
 	"github.com/pkg/errors"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
+	"golang.org/x/net/context"
 )
 
 var (
@@ -78,6 +79,7 @@ func newFs(t *testing.T) {
 
 // TestInit tests basic intitialisation
 func TestInit(t *testing.T) {
+	ctx := context.Background()
 	var err error
 
 	// Remove bad characters from Windows file name if set
@@ -106,7 +108,7 @@ func TestInit(t *testing.T) {
 
 	skipIfNotOk(t)
 
-	err = remote.Mkdir("")
+	err = remote.Mkdir(ctx, "")
 	require.NoError(t, err)
 	fstest.CheckListing(t, remote, []fstest.Item{})
 }
@@ -164,20 +166,23 @@ func TestFsRoot(t *testing.T) {
 
 // TestFsRmdirEmpty tests deleting an empty directory
 func TestFsRmdirEmpty(t *testing.T) {
+	ctx := context.Background()
 	skipIfNotOk(t)
-	err := remote.Rmdir("")
+	err := remote.Rmdir(ctx, "")
 	require.NoError(t, err)
 }
 
 // TestFsRmdirNotFound tests deleting a non existent directory
 func TestFsRmdirNotFound(t *testing.T) {
+	ctx := context.Background()
 	skipIfNotOk(t)
-	err := remote.Rmdir("")
+	err := remote.Rmdir(ctx, "")
 	assert.Error(t, err, "Expecting error on Rmdir non existent")
 }
 
 // TestFsMkdir tests tests making a directory
 func TestFsMkdir(t *testing.T) {
+	ctx := context.Background()
 	skipIfNotOk(t)
 
 	// Use a new directory here.  This is for the container based
@@ -185,27 +190,28 @@ func TestFsMkdir(t *testing.T) {
 	// (eg azure blob)
 	newFs(t)
 
-	err := remote.Mkdir("")
+	err := remote.Mkdir(ctx, "")
 	require.NoError(t, err)
 	fstest.CheckListing(t, remote, []fstest.Item{})
 
-	err = remote.Mkdir("")
+	err = remote.Mkdir(ctx, "")
 	require.NoError(t, err)
 }
 
 // TestFsMkdirRmdirSubdir tests making and removing a sub directory
 func TestFsMkdirRmdirSubdir(t *testing.T) {
+	ctx := context.Background()
 	skipIfNotOk(t)
 	dir := "dir/subdir"
-	err := operations.Mkdir(remote, dir)
+	err := operations.Mkdir(ctx, remote, dir)
 	require.NoError(t, err)
 	fstest.CheckListingWithPrecision(t, remote, []fstest.Item{}, []string{"dir", "dir/subdir"}, fs.Config.ModifyWindow)
 
-	err = operations.Rmdir(remote, dir)
+	err = operations.Rmdir(ctx, remote, dir)
 	require.NoError(t, err)
 	fstest.CheckListingWithPrecision(t, remote, []fstest.Item{}, []string{"dir"}, fs.Config.ModifyWindow)
 
-	err = operations.Rmdir(remote, "dir")
+	err = operations.Rmdir(ctx, remote, "dir")
 	require.NoError(t, err)
 	fstest.CheckListingWithPrecision(t, remote, []fstest.Item{}, []string{}, fs.Config.ModifyWindow)
 }
@@ -249,8 +255,9 @@ func objsToNames(objs []fs.Object) []string {
 
 // TestFsListDirEmpty tests listing the directories from an empty directory
 func TestFsListDirEmpty(t *testing.T) {
+	ctx := context.Background()
 	skipIfNotOk(t)
-	objs, dirs, err := walk.GetAll(remote, "", true, 1)
+	objs, dirs, err := walk.GetAll(ctx, remote, "", true, 1)
 	require.NoError(t, err)
 	assert.Equal(t, []string{}, objsToNames(objs))
 	assert.Equal(t, []string{}, dirsToNames(dirs))
@@ -264,22 +271,24 @@ func TestFsListRDirEmpty(t *testing.T) {
 
 // TestFsNewObjectNotFound tests not finding a object
 func TestFsNewObjectNotFound(t *testing.T) {
+	ctx := context.Background()
 	skipIfNotOk(t)
 	// Object in an existing directory
-	o, err := remote.NewObject("potato")
+	o, err := remote.NewObject(ctx, "potato")
 	assert.Nil(t, o)
 	assert.Equal(t, fs.ErrorObjectNotFound, err)
 	// Now try an object in a non existing directory
-	o, err = remote.NewObject("directory/not/found/potato")
+	o, err = remote.NewObject(ctx, "directory/not/found/potato")
 	assert.Nil(t, o)
 	assert.Equal(t, fs.ErrorObjectNotFound, err)
 }
 
 func findObject(t *testing.T, Name string) fs.Object {
+	ctx := context.Background()
 	var obj fs.Object
 	var err error
 	for i := 1; i <= *fstest.ListRetries; i++ {
-		obj, err = remote.NewObject(Name)
+		obj, err = remote.NewObject(ctx, Name)
 		if err == nil {
 			break
 		}
@@ -291,6 +300,7 @@ func findObject(t *testing.T, Name string) fs.Object {
 }
 
 func testPut(t *testing.T, file *fstest.Item) string {
+	ctx := context.Background()
 	tries := 1
 	const maxTries = 10
 again:
@@ -301,7 +311,7 @@ again:
 
 	file.Size = int64(buf.Len())
 	obji := object.NewStaticObjectInfo(file.Path, file.ModTime, file.Size, true, nil, nil)
-	obj, err := remote.Put(in, obji)
+	obj, err := remote.Put(ctx, in, obji)
 	if err != nil {
 		// Retry if err returned a retry error
 		if fserrors.IsRetryError(err) && tries < maxTries {
@@ -340,6 +350,7 @@ func (er errorReader) Read(p []byte) (n int, err error) {
 // It makes sure that aborting a file half way through does not create
 // a file on the remote.
 func TestFsPutError(t *testing.T) {
+	ctx := context.Background()
 	skipIfNotOk(t)
 
 	// Read 50 bytes then produce an error
@@ -349,11 +360,11 @@ func TestFsPutError(t *testing.T) {
 	in := io.MultiReader(buf, er)
 
 	obji := object.NewStaticObjectInfo(file2.Path, file2.ModTime, 100, true, nil, nil)
-	_, err := remote.Put(in, obji)
+	_, err := remote.Put(ctx, in, obji)
 	// assert.Nil(t, obj) - FIXME some remotes return the object even on nil
 	assert.NotNil(t, err)
 
-	obj, err := remote.NewObject(file2.Path)
+	obj, err := remote.NewObject(ctx, file2.Path)
 	assert.Nil(t, obj)
 	assert.Equal(t, fs.ErrorObjectNotFound, err)
 }
@@ -374,13 +385,14 @@ func TestFsUpdateFile1(t *testing.T) {
 // TestFsListDirFile2 tests the files are correctly uploaded by doing
 // Depth 1 directory listings
 func TestFsListDirFile2(t *testing.T) {
+	ctx := context.Background()
 	skipIfNotOk(t)
 	list := func(dir string, expectedDirNames, expectedObjNames []string) {
 		var objNames, dirNames []string
 		for i := 1; i <= *fstest.ListRetries; i++ {
-			objs, dirs, err := walk.GetAll(remote, dir, true, 1)
+			objs, dirs, err := walk.GetAll(ctx, remote, dir, true, 1)
 			if errors.Cause(err) == fs.ErrorDirNotFound {
-				objs, dirs, err = walk.GetAll(remote, winPath(dir), true, 1)
+				objs, dirs, err = walk.GetAll(ctx, remote, winPath(dir), true, 1)
 			}
 			require.NoError(t, err)
 			objNames = objsToNames(objs)
@@ -424,10 +436,11 @@ func TestFsListRDirFile2(t *testing.T) {
 
 // TestFsListDirRoot tests that DirList works in the root
 func TestFsListDirRoot(t *testing.T) {
+	ctx := context.Background()
 	skipIfNotOk(t)
 	rootRemote, err := fs.NewFs(RemoteName)
 	require.NoError(t, err)
-	_, dirs, err := walk.GetAll(rootRemote, "", true, 1)
+	_, dirs, err := walk.GetAll(ctx, rootRemote, "", true, 1)
 	require.NoError(t, err)
 	assert.Contains(t, dirsToNames(dirs), subRemoteLeaf, "Remote leaf not found")
 }
@@ -440,6 +453,7 @@ func TestFsListRDirRoot(t *testing.T) {
 
 // TestFsListSubdir tests List works for a subdirectory
 func TestFsListSubdir(t *testing.T) {
+	ctx := context.Background()
 	skipIfNotOk(t)
 	fileName := file2.Path
 	var err error
@@ -448,7 +462,7 @@ func TestFsListSubdir(t *testing.T) {
 	for i := 0; i < 2; i++ {
 		dir, _ := path.Split(fileName)
 		dir = dir[:len(dir)-1]
-		objs, dirs, err = walk.GetAll(remote, dir, true, -1)
+		objs, dirs, err = walk.GetAll(ctx, remote, dir, true, -1)
 		if err != fs.ErrorDirNotFound {
 			break
 		}
@@ -468,8 +482,9 @@ func TestFsListRSubdir(t *testing.T) {
 
 // TestFsListLevel2 tests List works for 2 levels
 func TestFsListLevel2(t *testing.T) {
+	ctx := context.Background()
 	skipIfNotOk(t)
-	objs, dirs, err := walk.GetAll(remote, "", true, 2)
+	objs, dirs, err := walk.GetAll(ctx, remote, "", true, 2)
 	if err == fs.ErrorLevelNotSupported {
 		return
 	}
@@ -505,15 +520,17 @@ func TestFsListFile1and2(t *testing.T) {
 
 // TestFsNewObjectDir tests NewObject on a directory which should produce an error
 func TestFsNewObjectDir(t *testing.T) {
+	ctx := context.Background()
 	skipIfNotOk(t)
 	dir := path.Dir(file2.Path)
-	obj, err := remote.NewObject(dir)
+	obj, err := remote.NewObject(ctx, dir)
 	assert.Nil(t, obj)
 	assert.NotNil(t, err)
 }
 
 // TestFsCopy tests Copy
 func TestFsCopy(t *testing.T) {
+	ctx := context.Background()
 	skipIfNotOk(t)
 
 	// Check have Copy
@@ -528,7 +545,7 @@ func TestFsCopy(t *testing.T) {
 
 	// do the copy
 	src := findObject(t, file2.Path)
-	dst, err := doCopy(src, file2Copy.Path)
+	dst, err := doCopy(ctx, src, file2Copy.Path)
 	if err == fs.ErrorCantCopy {
 		t.Skip("FS can't copy")
 	}
@@ -541,13 +558,14 @@ func TestFsCopy(t *testing.T) {
 	assert.Equal(t, file2Copy.Path, dst.Remote())
 
 	// Delete copy
-	err = dst.Remove()
+	err = dst.Remove(ctx)
 	require.NoError(t, err)
 
 }
 
 // TestFsMove tests Move
 func TestFsMove(t *testing.T) {
+	ctx := context.Background()
 	skipIfNotOk(t)
 
 	// Check have Move
@@ -568,7 +586,7 @@ func TestFsMove(t *testing.T) {
 	file2Move.Path = "other.txt"
 	file2Move.WinPath = ""
 	src := findObject(t, file2.Path)
-	dst, err := doMove(src, file2Move.Path)
+	dst, err := doMove(ctx, src, file2Move.Path)
 	if err == fs.ErrorCantMove {
 		t.Skip("FS can't move")
 	}
@@ -583,7 +601,7 @@ func TestFsMove(t *testing.T) {
 	// Check conflict on "rename, then move"
 	file1Move.Path = "moveTest/other.txt"
 	src = findObject(t, file1.Path)
-	_, err = doMove(src, file1Move.Path)
+	_, err = doMove(ctx, src, file1Move.Path)
 	require.NoError(t, err)
 	fstest.CheckListing(t, remote, []fstest.Item{file1Move, file2Move})
 	// 1: moveTest/other.txt
@@ -591,14 +609,14 @@ func TestFsMove(t *testing.T) {
 
 	// Check conflict on "move, then rename"
 	src = findObject(t, file1Move.Path)
-	_, err = doMove(src, file1.Path)
+	_, err = doMove(ctx, src, file1.Path)
 	require.NoError(t, err)
 	fstest.CheckListing(t, remote, []fstest.Item{file1, file2Move})
 	// 1: file name.txt
 	// 2: other.txt
 
 	src = findObject(t, file2Move.Path)
-	_, err = doMove(src, file2.Path)
+	_, err = doMove(ctx, src, file2.Path)
 	require.NoError(t, err)
 	fstest.CheckListing(t, remote, []fstest.Item{file1, file2})
 	// 1: file name.txt
@@ -617,6 +635,7 @@ func TestFsMove(t *testing.T) {
 //
 // go test -v -run '^Test(Setup|Init|FsMkdir|FsPutFile1|FsPutFile2|FsUpdateFile1|FsDirMove)$
 func TestFsDirMove(t *testing.T) {
+	ctx := context.Background()
 	skipIfNotOk(t)
 
 	// Check have DirMove
@@ -626,7 +645,7 @@ func TestFsDirMove(t *testing.T) {
 	}
 
 	// Check it can't move onto itself
-	err := doDirMove(remote, "", "")
+	err := doDirMove(ctx, remote, "", "")
 	require.Equal(t, fs.ErrorDirExists, err)
 
 	// new remote
@@ -636,7 +655,7 @@ func TestFsDirMove(t *testing.T) {
 
 	const newName = "new_name/sub_new_name"
 	// try the move
-	err = newRemote.Features().DirMove(remote, "", newName)
+	err = newRemote.Features().DirMove(ctx, remote, "", newName)
 	require.NoError(t, err)
 
 	// check remotes
@@ -650,7 +669,7 @@ func TestFsDirMove(t *testing.T) {
 	fstest.CheckListing(t, newRemote, []fstest.Item{file2Copy, file1Copy})
 
 	// move it back
-	err = doDirMove(newRemote, newName, "")
+	err = doDirMove(ctx, newRemote, newName, "")
 	require.NoError(t, err)
 
 	// check remotes
@@ -660,8 +679,9 @@ func TestFsDirMove(t *testing.T) {
 
 // TestFsRmdirFull tests removing a non empty directory
 func TestFsRmdirFull(t *testing.T) {
+	ctx := context.Background()
 	skipIfNotOk(t)
-	err := remote.Rmdir("")
+	err := remote.Rmdir(ctx, "")
 	require.Error(t, err, "Expecting error on RMdir on non empty remote")
 }
 
@@ -683,6 +703,7 @@ func TestFsPrecision(t *testing.T) {
 //
 // go test -v -remote TestDrive: -run '^Test(Setup|Init|FsDirChangeNotify)$' -verbose
 func TestFsDirChangeNotify(t *testing.T) {
+	ctx := context.Background()
 	skipIfNotOk(t)
 
 	// Check have DirChangeNotify
@@ -691,7 +712,7 @@ func TestFsDirChangeNotify(t *testing.T) {
 		t.Skip("FS has no DirChangeNotify interface")
 	}
 
-	err := operations.Mkdir(remote, "dir")
+	err := operations.Mkdir(ctx, remote, "dir")
 	require.NoError(t, err)
 
 	changes := []string{}
@@ -700,7 +721,7 @@ func TestFsDirChangeNotify(t *testing.T) {
 	}, time.Second)
 	defer func() { close(quitChannel) }()
 
-	err = operations.Mkdir(remote, "dir/subdir")
+	err = operations.Mkdir(ctx, remote, "dir/subdir")
 	require.NoError(t, err)
 
 	time.Sleep(2 * time.Second)
@@ -769,10 +790,11 @@ func TestObjectMimeType(t *testing.T) {
 
 // TestObjectSetModTime tests that SetModTime works
 func TestObjectSetModTime(t *testing.T) {
+	ctx := context.Background()
 	skipIfNotOk(t)
 	newModTime := fstest.Time("2011-12-13T14:15:16.999999999Z")
 	obj := findObject(t, file1.Path)
-	err := obj.SetModTime(newModTime)
+	err := obj.SetModTime(ctx, newModTime)
 	if err == fs.ErrorCantSetModTime || err == fs.ErrorCantSetModTimeWithoutDelete {
 		t.Log(err)
 		return
@@ -793,8 +815,9 @@ func TestObjectSize(t *testing.T) {
 
 // read the contents of an object as a string
 func readObject(t *testing.T, obj fs.Object, limit int64, options ...fs.OpenOption) string {
+	ctx := context.Background()
 	what := fmt.Sprintf("readObject(%q) limit=%d, options=%+v", obj, limit, options)
-	in, err := obj.Open(options...)
+	in, err := obj.Open(ctx, options...)
 	require.NoError(t, err, what)
 	var r io.Reader = in
 	if limit >= 0 {
@@ -856,6 +879,7 @@ func TestObjectPartialRead(t *testing.T) {
 
 // TestObjectUpdate tests that Update works
 func TestObjectUpdate(t *testing.T) {
+	ctx := context.Background()
 	skipIfNotOk(t)
 	contents := fstest.RandomString(200)
 	buf := bytes.NewBufferString(contents)
@@ -865,7 +889,7 @@ func TestObjectUpdate(t *testing.T) {
 	file1.Size = int64(buf.Len())
 	obj := findObject(t, file1.Path)
 	obji := object.NewStaticObjectInfo(file1.Path, file1.ModTime, int64(len(contents)), true, nil, obj.Fs())
-	err := obj.Update(in, obji)
+	err := obj.Update(ctx, in, obji)
 	require.NoError(t, err)
 	file1.Hashes = hash.Sums()
 
@@ -912,15 +936,17 @@ func TestFsIsFileNotFound(t *testing.T) {
 
 // TestObjectRemove tests Remove
 func TestObjectRemove(t *testing.T) {
+	ctx := context.Background()
 	skipIfNotOk(t)
 	obj := findObject(t, file1.Path)
-	err := obj.Remove()
+	err := obj.Remove(ctx)
 	require.NoError(t, err)
 	fstest.CheckListing(t, remote, []fstest.Item{file2})
 }
 
 // TestFsPutStream tests uploading files when size is not known in advance
 func TestFsPutStream(t *testing.T) {
+	ctx := context.Background()
 	skipIfNotOk(t)
 	if remote.Features().PutStream == nil {
 		t.Skip("FS has no PutStream interface")
@@ -943,7 +969,7 @@ again:
 
 	file.Size = -1
 	obji := object.NewStaticObjectInfo(file.Path, file.ModTime, file.Size, true, nil, nil)
-	obj, err := remote.Features().PutStream(in, obji)
+	obj, err := remote.Features().PutStream(ctx, in, obji)
 	if err != nil {
 		// Retry if err returned a retry error
 		if fserrors.IsRetryError(err) && tries < maxTries {
@@ -963,15 +989,58 @@ again:
 	file.Check(t, obj, remote.Precision())
 }
 
+// TestFsPutZeroLength tests uploading empty files with Put and
+// PutStream
+func TestFsPutZeroLength(t *testing.T) {
+	ctx := context.Background()
+	skipIfNotOk(t)
+
+	check := func(obj fs.Object, file *fstest.Item) {
+		file.Size = 0
+		file.Hashes = hash.NewMultiHasher().Sums()
+		file.Check(t, obj, remote.Precision())
+		// Re-read the object and check again
+		obj = findObject(t, file.Path)
+		file.Check(t, obj, remote.Precision())
+		// Read it back
+		in, err := obj.Open(ctx)
+		require.NoError(t, err)
+		data, err := ioutil.ReadAll(in)
+		require.NoError(t, in.Close())
+		require.NoError(t, err)
+		assert.Equal(t, 0, len(data))
+		require.NoError(t, obj.Remove(ctx))
+	}
+
+	file := fstest.Item{
+		ModTime: fstest.Time("2001-02-03T04:05:06.499999999Z"),
+		Path:    "zero length.txt",
+	}
+	obji := object.NewStaticObjectInfo(file.Path, file.ModTime, 0, true, nil, nil)
+	obj, err := remote.Put(ctx, bytes.NewBuffer(nil), obji)
+	require.NoError(t, err, fmt.Sprintf("Put zero length error: %v", err))
+	check(obj, &file)
+
+	if remote.Features().PutStream == nil {
+		return
+	}
+	file.Path = "zero length piped.txt"
+	obji = object.NewStaticObjectInfo(file.Path, file.ModTime, -1, true, nil, nil)
+	obj, err = remote.Features().PutStream(ctx, bytes.NewBuffer(nil), obji)
+	require.NoError(t, err, fmt.Sprintf("PutStream zero length error: %v", err))
+	check(obj, &file)
+}
+
 // TestObjectPurge tests Purge
 func TestObjectPurge(t *testing.T) {
+	ctx := context.Background()
 	skipIfNotOk(t)
 
-	err := operations.Purge(remote, "")
+	err := operations.Purge(ctx, remote, "")
 	require.NoError(t, err)
 	fstest.CheckListing(t, remote, []fstest.Item{})
 
-	err = operations.Purge(remote, "")
+	err = operations.Purge(ctx, remote, "")
 	assert.Error(t, err, "Expecting error after on second purge")
 }
 
@@ -67,6 +67,7 @@ import (
 	"github.com/ncw/rclone/fs"
 	"github.com/ncw/rclone/fstest/fstests"
 {{ if (or (eq .FsName "crypt") (eq .FsName "cache")) }}	_ "github.com/ncw/rclone/backend/local"
+	_ "github.com/ncw/rclone/backend/memory"
 {{end}})
 
 func TestSetup{{ .Suffix }}(t *testing.T)() {
@@ -106,11 +107,14 @@ func generateTestProgram(t *template.Template, fns []string, Fsname string, opti
 	}
 
 	data.TestName = "Test" + data.UpperFsName + data.Suffix + ":"
-	outfile := "../../backend/" + data.FsName + "/" + data.FsName + data.Suffix + "_test.go"
+	outfile := "../../backend/" + data.FsName + "/" + data.FsName + strings.ToLower(data.Suffix) + "_test.go"
 
 	if data.FsName == "local" {
 		data.TestName = ""
 	}
+	if data.FsName == "memory" {
+		data.TestName = ":memory:"
+	}
 
 	cmd := exec.Command("gofmt")
 
@@ -159,6 +163,7 @@ func main() {
 	generateTestProgram(t, fns, "Crypt")
 	generateTestProgram(t, fns, "Crypt", suffix("2"))
 	generateTestProgram(t, fns, "Crypt", suffix("3"))
+	generateTestProgram(t, fns, "Crypt", suffix("Memory"))
 	generateTestProgram(t, fns, "Sftp")
 	generateTestProgram(t, fns, "FTP")
 	generateTestProgram(t, fns, "Box")
@@ -167,5 +172,13 @@ func main() {
 	generateTestProgram(t, fns, "Pcloud")
 	generateTestProgram(t, fns, "Webdav")
 	generateTestProgram(t, fns, "Cache", buildConstraint("!plan9,go1.7"))
+	generateTestProgram(t, fns, "Cache", suffix("Memory"), buildConstraint("!plan9,go1.7"))
+	generateTestProgram(t, fns, "Mega")
+	generateTestProgram(t, fns, "Jottacloud")
+	generateTestProgram(t, fns, "HDFS")
+	generateTestProgram(t, fns, "SMB", buildConstraint("go1.8"))
+	generateTestProgram(t, fns, "Sia")
+	generateTestProgram(t, fns, "Plugin")
+	generateTestProgram(t, fns, "Memory")
 	log.Printf("Done")
 }
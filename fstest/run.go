@@ -43,6 +43,7 @@ import (
 	"github.com/ncw/rclone/fs/walk"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
+	"golang.org/x/net/context"
 )
 
 // Run holds the remotes for a test run
@@ -122,6 +123,7 @@ func (d dirsToRemove) Less(i, j int) bool { return len(d[i]) > len(d[j]) }
 //
 // Finalise() will tidy them away when done.
 func NewRun(t *testing.T) *Run {
+	ctx := context.Background()
 	var r *Run
 	if *Individual {
 		r = newRun()
@@ -131,7 +133,7 @@ func NewRun(t *testing.T) *Run {
 		*r = *oneRun
 		r.cleanRemote = func() {
 			var toDelete dirsToRemove
-			err := walk.Walk(r.Fremote, "", true, -1, func(dirPath string, entries fs.DirEntries, err error) error {
+			err := walk.Walk(ctx, r.Fremote, "", true, -1, func(dirPath string, entries fs.DirEntries, err error) error {
 				if err != nil {
 					if err == fs.ErrorDirNotFound {
 						return nil
@@ -141,7 +143,7 @@ func NewRun(t *testing.T) *Run {
 				for _, entry := range entries {
 					switch x := entry.(type) {
 					case fs.Object:
-						err = x.Remove()
+						err = x.Remove(ctx)
 						if err != nil {
 							t.Errorf("Error removing file %q: %v", x.Remote(), err)
 						}
@@ -157,7 +159,7 @@ func NewRun(t *testing.T) *Run {
 			require.NoError(t, err)
 			sort.Sort(toDelete)
 			for _, dir := range toDelete {
-				err := r.Fremote.Rmdir(dir)
+				err := r.Fremote.Rmdir(ctx, dir)
 				if err != nil {
 					t.Errorf("Error removing dir %q: %v", dir, err)
 				}
@@ -208,7 +210,8 @@ func (r *Run) WriteFile(filePath, content string, t time.Time) Item {
 
 // ForceMkdir creates the remote
 func (r *Run) ForceMkdir(f fs.Fs) {
-	err := f.Mkdir("")
+	ctx := context.Background()
+	err := f.Mkdir(ctx, "")
 	if err != nil {
 		r.Fatalf("Failed to mkdir %q: %v", f, err)
 	}
@@ -224,6 +227,7 @@ func (r *Run) Mkdir(f fs.Fs) {
 
 // WriteObjectTo writes an object to the fs, remote passed in
 func (r *Run) WriteObjectTo(f fs.Fs, remote, content string, modTime time.Time, useUnchecked bool) Item {
+	ctx := context.Background()
 	put := f.Put
 	if useUnchecked {
 		put = f.Features().PutUnchecked
@@ -236,7 +240,7 @@ func (r *Run) WriteObjectTo(f fs.Fs, remote, content string, modTime time.Time,
 	for tries := 1; ; tries++ {
 		in := bytes.NewBufferString(content)
 		objinfo := object.NewStaticObjectInfo(remote, modTime, int64(len(content)), true, nil, nil)
-		_, err := put(in, objinfo)
+		_, err := put(ctx, in, objinfo)
 		if err == nil {
 			break
 		}
@@ -269,6 +273,7 @@ func (r *Run) WriteBoth(remote, content string, modTime time.Time) Item {
 
 // CheckWithDuplicates does a test but allows duplicates
 func (r *Run) CheckWithDuplicates(t *testing.T, items ...Item) {
+	ctx := context.Background()
 	var want, got []string
 
 	// construct a []string of desired items
@@ -278,7 +283,7 @@ func (r *Run) CheckWithDuplicates(t *testing.T, items ...Item) {
 	sort.Strings(want)
 
 	// do the listing
-	objs, _, err := walk.GetAll(r.Fremote, "", true, -1)
+	objs, _, err := walk.GetAll(ctx, r.Fremote, "", true, -1)
 	if err != nil && err != fs.ErrorDirNotFound {
 		t.Fatalf("Error listing: %v", err)
 	}
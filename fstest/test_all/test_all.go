@@ -22,6 +22,7 @@ import (
 	"github.com/ncw/rclone/fs/list"
 	"github.com/ncw/rclone/fs/operations"
 	"github.com/ncw/rclone/fstest"
+	"golang.org/x/net/context"
 )
 
 type remoteConfig struct {
@@ -132,6 +133,31 @@ var (
 			SubDir:   false,
 			FastList: false,
 		},
+		{
+			Name:     "TestMega:",
+			SubDir:   false,
+			FastList: false,
+		},
+		{
+			Name:     "TestJottacloud:",
+			SubDir:   false,
+			FastList: false,
+		},
+		{
+			Name:     "TestHDFS:",
+			SubDir:   false,
+			FastList: false,
+		},
+		{
+			Name:     "TestSMB:rclone",
+			SubDir:   false,
+			FastList: false,
+		},
+		{
+			Name:     "TestSia:",
+			SubDir:   false,
+			FastList: false,
+		},
 	}
 	// Flags
 	maxTries = flag.Int("maxtries", 5, "Number of times to try each test")
@@ -260,11 +286,12 @@ func (t *test) trial() {
 
 // cleanFs runs a single clean fs for left over directories
 func (t *test) cleanFs() error {
+	ctx := context.Background()
 	f, err := fs.NewFs(t.remote)
 	if err != nil {
 		return err
 	}
-	entries, err := list.DirSorted(f, true, "")
+	entries, err := list.DirSorted(ctx, f, true, "")
 	if err != nil {
 		return err
 	}
@@ -276,7 +303,7 @@ func (t *test) cleanFs() error {
 			if err != nil {
 				return err
 			}
-			return operations.Purge(dir, "")
+			return operations.Purge(ctx, dir, "")
 		}
 		return nil
 	})
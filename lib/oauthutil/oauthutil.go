@@ -255,7 +255,7 @@ func NewClientWithBaseClient(name string, config *oauth2.Config, baseClient *htt
 // NewClient gets a token from the config file and configures a Client
 // with it.  It returns the client and a TokenSource which Invalidate may need to be called on
 func NewClient(name string, oauthConfig *oauth2.Config) (*http.Client, *TokenSource, error) {
-	return NewClientWithBaseClient(name, oauthConfig, fshttp.NewClient(fs.Config))
+	return NewClientWithBaseClient(name, oauthConfig, fshttp.NewClient(fs.ConfigForRemote(name)))
 }
 
 // Config does the initial creation of the token
@@ -3,11 +3,16 @@ package pacer
 
 import (
 	"math/rand"
+	"net/http"
+	"strconv"
 	"sync"
 	"time"
 
 	"github.com/ncw/rclone/fs"
+	"github.com/ncw/rclone/fs/config/flags"
 	"github.com/ncw/rclone/fs/fserrors"
+	"github.com/pkg/errors"
+	"github.com/spf13/pflag"
 )
 
 // Pacer state
@@ -18,12 +23,19 @@ type Pacer struct {
 	decayConstant      uint          // decay constant
 	attackConstant     uint          // attack constant
 	pacer              chan struct{} // To pace the operations
+	burst              int           // number of calls which can be made without pacing
 	sleepTime          time.Duration // Time to sleep for each transaction
 	retries            int           // Max number of retries
 	maxConnections     int           // Maximum number of concurrent connections
 	connTokens         chan struct{} // Connection tokens
 	calculatePace      func(bool)    // switchable pacing algorithm - call with mu held
 	consecutiveRetries int           // number of consecutive retries
+	adaptive           bool          // set to adapt the number of connections to rate limiting
+	connLimit          int           // current limit on connections if adaptive
+	connWithhold       int           // number of connection tokens still to take out of use
+	connWithheld       int           // number of connection tokens taken out of use
+	connSuccesses      int           // calls without retries since connLimit last changed
+	connDecreased      time.Time     // time connLimit was last reduced
 }
 
 // Type is for selecting different pacing algorithms
@@ -61,6 +73,55 @@ const (
 	GoogleDrivePacer
 )
 
+// Defaults for the tunable parameters of the Pacer
+const (
+	DefaultMinSleep      = 10 * time.Millisecond // default minimum sleep time
+	DefaultMaxSleep      = 2 * time.Second       // default maximum sleep time
+	DefaultDecayConstant = 2                     // default decay constant
+	DefaultBurst         = 1                     // default number of calls without pacing
+)
+
+// adaptiveCooldown is the minimum time between reductions of the
+// number of connections when adapting to rate limiting, so a burst of
+// concurrent failures only counts once
+var adaptiveCooldown = time.Second
+
+// Options contains the tunable parameters of a Pacer
+type Options struct {
+	MinSleep      time.Duration // minimum sleep time
+	MaxSleep      time.Duration // maximum sleep time
+	DecayConstant uint32        // decay constant - bigger for slower decay
+	Burst         int           // number of calls which can be made without pacing
+}
+
+// AddFlags adds command line flags so the user can tune the pacer of
+// the backend called name, eg --name-pacer-min-sleep.
+//
+// Any zero values in defaults are replaced with the package defaults.
+// It returns a pointer to the Options which will be filled in from
+// the flags, suitable for passing to SetOptions.
+func AddFlags(name string, defaults Options) *Options {
+	if defaults.MinSleep == 0 {
+		defaults.MinSleep = DefaultMinSleep
+	}
+	if defaults.MaxSleep == 0 {
+		defaults.MaxSleep = DefaultMaxSleep
+	}
+	if defaults.DecayConstant == 0 {
+		defaults.DecayConstant = DefaultDecayConstant
+	}
+	if defaults.Burst == 0 {
+		defaults.Burst = DefaultBurst
+	}
+	opt := new(Options)
+	flagSet := pflag.CommandLine
+	flags.DurationVarP(flagSet, &opt.MinSleep, name+"-pacer-min-sleep", "", defaults.MinSleep, "Minimum time to sleep between API calls.")
+	flags.DurationVarP(flagSet, &opt.MaxSleep, name+"-pacer-max-sleep", "", defaults.MaxSleep, "Maximum time to sleep between API calls when being rate limited.")
+	flags.Uint32VarP(flagSet, &opt.DecayConstant, name+"-pacer-decay-constant", "", defaults.DecayConstant, "Decay constant for the sleep time - bigger for slower decay.")
+	flags.IntVarP(flagSet, &opt.Burst, name+"-pacer-burst", "", defaults.Burst, "Number of API calls to allow without sleeping.")
+	return opt
+}
+
 // Paced is a function which is called by the Call and CallNoRetry
 // methods.  It should return a boolean, true if it would like to be
 // retried, and an error.  This error may be returned or returned
@@ -70,19 +131,17 @@ type Paced func() (bool, error)
 // New returns a Pacer with sensible defaults
 func New() *Pacer {
 	p := &Pacer{
-		minSleep:       10 * time.Millisecond,
-		maxSleep:       2 * time.Second,
-		decayConstant:  2,
+		minSleep:       DefaultMinSleep,
+		maxSleep:       DefaultMaxSleep,
+		decayConstant:  DefaultDecayConstant,
 		attackConstant: 1,
 		retries:        fs.Config.LowLevelRetries,
-		pacer:          make(chan struct{}, 1),
 	}
 	p.sleepTime = p.minSleep
 	p.SetPacer(DefaultPacer)
 	p.SetMaxConnections(fs.Config.Checkers + fs.Config.Transfers)
-
-	// Put the first pacing token in
-	p.pacer <- struct{}{}
+	p.SetBurst(DefaultBurst)
+	p.SetAdaptive(fs.Config.AdaptiveConcurrency)
 
 	return p
 }
@@ -128,6 +187,9 @@ func (p *Pacer) SetMaxConnections(n int) *Pacer {
 	p.mu.Lock()
 	defer p.mu.Unlock()
 	p.maxConnections = n
+	p.connLimit = n
+	p.connWithhold = 0
+	p.connWithheld = 0
 	if n <= 0 {
 		p.connTokens = nil
 	} else {
@@ -139,6 +201,52 @@ func (p *Pacer) SetMaxConnections(n int) *Pacer {
 	return p
 }
 
+// SetAdaptive sets whether the number of concurrent connections
+// should adapt to rate limiting.
+//
+// If set then the number of connections allowed is halved when calls
+// need retrying, then increased by one at a time back up to the
+// maximum set with SetMaxConnections as calls succeed.  Should not be
+// changed once you have started calling the pacer.
+func (p *Pacer) SetAdaptive(adaptive bool) *Pacer {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.adaptive = adaptive
+	return p
+}
+
+// SetBurst sets the number of calls which can be made in a burst
+// before the pacing applies.  The calls are then paced so that on
+// average burst calls are made per sleep time.
+//
+// Setting the value to less than 1 will set it to 1.  Should not be
+// changed once you have started calling the pacer.
+func (p *Pacer) SetBurst(n int) *Pacer {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if n < 1 {
+		n = 1
+	}
+	p.burst = n
+	p.pacer = make(chan struct{}, n)
+	// Put the initial pacing tokens in
+	for i := 0; i < n; i++ {
+		p.pacer <- struct{}{}
+	}
+	return p
+}
+
+// SetOptions sets the minimum and maximum sleep, the decay constant
+// and the burst from opt.  Should not be changed once you have started
+// calling the pacer.
+func (p *Pacer) SetOptions(opt *Options) *Pacer {
+	p.SetMinSleep(opt.MinSleep)
+	p.SetMaxSleep(opt.MaxSleep)
+	p.SetDecayConstant(uint(opt.DecayConstant))
+	p.SetBurst(opt.Burst)
+	return p
+}
+
 // SetDecayConstant sets the decay constant for the pacer
 //
 // This is the speed the time falls back to the minimum after errors
@@ -207,12 +315,18 @@ func (p *Pacer) beginCall() {
 	}
 
 	p.mu.Lock()
+	sleepTime := p.sleepTime
+	// Add up to 50% random jitter to the sleep when retrying so
+	// that callers retrying at the same time spread out
+	if p.consecutiveRetries > 0 && sleepTime > 0 {
+		sleepTime += time.Duration(rand.Int63n(int64(sleepTime)/2 + 1))
+	}
 	// Restart the timer
 	go func(t time.Duration) {
 		// fs.Debugf(f, "New sleep for %v at %v", t, time.Now())
 		time.Sleep(t)
 		p.pacer <- struct{}{}
-	}(p.sleepTime)
+	}(sleepTime)
 	p.mu.Unlock()
 }
 
@@ -309,15 +423,62 @@ func (p *Pacer) drivePacer(retry bool) {
 	}
 }
 
+// adaptConnections implements the adaptive concurrency algorithm
+//
+// On retries the number of connections is halved, at most once per
+// adaptiveCooldown.  After connLimit calls without retries it is
+// increased by one, up to maxConnections.
+//
+// Call with p.mu held
+func (p *Pacer) adaptConnections(retry bool) {
+	if retry {
+		p.connSuccesses = 0
+		if p.connLimit <= 1 || time.Since(p.connDecreased) < adaptiveCooldown {
+			return
+		}
+		newLimit := p.connLimit / 2
+		p.connWithhold += p.connLimit - newLimit
+		p.connLimit = newLimit
+		p.connDecreased = time.Now()
+		fs.Debugf("pacer", "Rate limited, reducing connections to %d", p.connLimit)
+		return
+	}
+	if p.connLimit >= p.maxConnections {
+		return
+	}
+	p.connSuccesses++
+	if p.connSuccesses < p.connLimit {
+		return
+	}
+	p.connSuccesses = 0
+	p.connLimit++
+	if p.connWithhold > 0 {
+		p.connWithhold--
+	} else {
+		p.connWithheld--
+		p.connTokens <- struct{}{}
+	}
+	fs.Debugf("pacer", "Increasing connections to %d", p.connLimit)
+}
+
 // endCall implements the pacing algorithm
 //
 // This should calculate a new sleepTime.  It takes a boolean as to
 // whether the operation should be retried or not.
 func (p *Pacer) endCall(retry bool) {
+	p.mu.Lock()
 	if p.maxConnections > 0 {
-		p.connTokens <- struct{}{}
+		if p.connWithhold > 0 {
+			// take the token out of use
+			p.connWithhold--
+			p.connWithheld++
+		} else {
+			p.connTokens <- struct{}{}
+		}
+		if p.adaptive {
+			p.adaptConnections(retry)
+		}
 	}
-	p.mu.Lock()
 	if retry {
 		p.consecutiveRetries++
 	} else {
@@ -338,6 +499,19 @@ func (p *Pacer) call(fn Paced, retries int) (err error) {
 			break
 		}
 		fs.Debugf("pacer", "low level retry %d/%d (error %v)", i, retries, err)
+		if retryAfter, ok := IsRetryAfter(err); ok && i < retries {
+			p.mu.Lock()
+			if p.sleepTime < retryAfter {
+				p.sleepTime = retryAfter
+			}
+			p.mu.Unlock()
+			fs.Debugf("pacer", "Sleeping for %v as asked by the server before retrying", retryAfter)
+			time.Sleep(retryAfter)
+		}
+	}
+	// Return the original error to the caller
+	if r, ok := err.(*retryAfterError); ok {
+		err = r.error
 	}
 	if retry {
 		err = fserrors.RetryError(err)
@@ -366,3 +540,67 @@ func (p *Pacer) Call(fn Paced) (err error) {
 func (p *Pacer) CallNoRetry(fn Paced) error {
 	return p.call(fn, 1)
 }
+
+// retryAfterError wraps an error with the time the server asked us to
+// wait before retrying
+type retryAfterError struct {
+	error
+	retryAfter time.Duration
+}
+
+// Cause returns the underlying error
+func (r *retryAfterError) Cause() error {
+	return r.error
+}
+
+// RetryAfterError wraps err to tell the pacer to wait for at least
+// retryAfter before retrying the call.  This should be returned from a
+// Paced function, eg when the server sent a Retry-After header.
+func RetryAfterError(err error, retryAfter time.Duration) error {
+	if err == nil {
+		err = errors.New("too many requests")
+	}
+	return &retryAfterError{
+		error:      err,
+		retryAfter: retryAfter,
+	}
+}
+
+// IsRetryAfter returns the time to wait and true if err was made with
+// RetryAfterError
+func IsRetryAfter(err error) (retryAfter time.Duration, isRetryAfter bool) {
+	if r, ok := err.(*retryAfterError); ok {
+		return r.retryAfter, true
+	}
+	return 0, false
+}
+
+// RetryAfterHTTP looks for a Retry-After header in a 429 Too Many
+// Requests or 503 Service Unavailable response and if found returns
+// err wrapped with RetryAfterError, otherwise it returns err
+// unchanged.
+//
+// This is for use in the shouldRetry functions of HTTP based
+// backends.
+func RetryAfterHTTP(resp *http.Response, err error) error {
+	if resp == nil || (resp.StatusCode != http.StatusTooManyRequests && resp.StatusCode != http.StatusServiceUnavailable) {
+		return err
+	}
+	retryAfterString := resp.Header.Get("Retry-After")
+	if retryAfterString == "" {
+		return err
+	}
+	var retryAfter time.Duration
+	if seconds, parseErr := strconv.Atoi(retryAfterString); parseErr == nil {
+		retryAfter = time.Duration(seconds) * time.Second
+	} else if when, parseErr := http.ParseTime(retryAfterString); parseErr == nil {
+		retryAfter = when.Sub(time.Now())
+	} else {
+		fs.Debugf("pacer", "Malformed Retry-After header %q", retryAfterString)
+		return err
+	}
+	if retryAfter <= 0 {
+		return err
+	}
+	return RetryAfterError(err, retryAfter)
+}
@@ -2,6 +2,7 @@ package pacer
 
 import (
 	"fmt"
+	"net/http"
 	"testing"
 	"time"
 
@@ -97,6 +98,47 @@ func TestMaxConnections(t *testing.T) {
 	}
 }
 
+func TestSetBurst(t *testing.T) {
+	p := New().SetBurst(3)
+	if p.burst != 3 {
+		t.Errorf("burst")
+	}
+	if cap(p.pacer) != 3 {
+		t.Errorf("pacer 1")
+	}
+	if len(p.pacer) != 3 {
+		t.Errorf("pacer 2")
+	}
+	p = New().SetBurst(0)
+	if p.burst != 1 || cap(p.pacer) != 1 || len(p.pacer) != 1 {
+		t.Errorf("burst 0 not set to 1")
+	}
+}
+
+func TestSetOptions(t *testing.T) {
+	p := New().SetOptions(&Options{
+		MinSleep:      time.Millisecond,
+		MaxSleep:      3 * time.Second,
+		DecayConstant: 5,
+		Burst:         4,
+	})
+	if p.minSleep != time.Millisecond {
+		t.Errorf("minSleep")
+	}
+	if p.maxSleep != 3*time.Second {
+		t.Errorf("maxSleep")
+	}
+	if p.sleepTime != p.minSleep {
+		t.Errorf("sleepTime")
+	}
+	if p.decayConstant != 5 {
+		t.Errorf("decayConstant")
+	}
+	if p.burst != 4 || len(p.pacer) != 4 {
+		t.Errorf("burst")
+	}
+}
+
 func TestSetDecayConstant(t *testing.T) {
 	p := New().SetDecayConstant(17)
 	if p.decayConstant != 17 {
@@ -366,6 +408,66 @@ func TestEndCallZeroConnections(t *testing.T) {
 	}
 }
 
+func TestAdaptiveConnections(t *testing.T) {
+	oldCooldown := adaptiveCooldown
+	adaptiveCooldown = 0
+	defer func() { adaptiveCooldown = oldCooldown }()
+
+	p := New().SetMaxConnections(8).SetAdaptive(true)
+	call := func(retry bool) {
+		<-p.connTokens
+		p.endCall(retry)
+		if len(p.connTokens)+p.connWithheld != p.maxConnections {
+			t.Errorf("Lost tokens: %d in use, %d withheld", len(p.connTokens), p.connWithheld)
+		}
+		if p.connLimit != p.maxConnections-p.connWithheld-p.connWithhold {
+			t.Errorf("Bad connLimit %d: %d withheld, %d to withhold", p.connLimit, p.connWithheld, p.connWithhold)
+		}
+	}
+
+	call(true)
+	if p.connLimit != 4 {
+		t.Errorf("Expecting connLimit 4 got %d", p.connLimit)
+	}
+	call(true)
+	if p.connLimit != 2 {
+		t.Errorf("Expecting connLimit 2 got %d", p.connLimit)
+	}
+	for i := 0; i < 4; i++ {
+		call(true)
+	}
+	if p.connLimit != 1 {
+		t.Errorf("Expecting connLimit 1 got %d", p.connLimit)
+	}
+
+	// Check it ramps back up to the maximum
+	for i := 0; i < 100; i++ {
+		call(false)
+	}
+	if p.connLimit != 8 {
+		t.Errorf("Expecting connLimit 8 got %d", p.connLimit)
+	}
+	if len(p.connTokens) != 8 {
+		t.Errorf("Expecting 8 tokens got %d", len(p.connTokens))
+	}
+
+	// Check it only reduces once per cooldown
+	adaptiveCooldown = time.Hour
+	p.connDecreased = time.Time{}
+	call(true)
+	call(true)
+	if p.connLimit != 4 {
+		t.Errorf("Expecting connLimit 4 got %d", p.connLimit)
+	}
+
+	// Check it doesn't adapt if not set
+	p = New().SetMaxConnections(8)
+	call(true)
+	if p.connLimit != 8 {
+		t.Errorf("Expecting connLimit 8 got %d", p.connLimit)
+	}
+}
+
 var errFoo = errors.New("foo")
 
 type dummyPaced struct {
@@ -435,3 +537,78 @@ func TestCallNoRetry(t *testing.T) {
 		t.Errorf("didn't return a retry error")
 	}
 }
+
+func TestRetryAfterHTTP(t *testing.T) {
+	for _, test := range []struct {
+		status     int
+		retryAfter string
+		want       time.Duration
+		wantOK     bool
+	}{
+		{http.StatusTooManyRequests, "", 0, false},
+		{http.StatusTooManyRequests, "3", 3 * time.Second, true},
+		{http.StatusServiceUnavailable, "120", 120 * time.Second, true},
+		{http.StatusInternalServerError, "3", 0, false},
+		{http.StatusTooManyRequests, "potato", 0, false},
+		{http.StatusTooManyRequests, "0", 0, false},
+		{http.StatusTooManyRequests, "Fri, 31 Dec 1999 23:59:59 GMT", 0, false},
+	} {
+		resp := &http.Response{
+			StatusCode: test.status,
+			Header:     http.Header{},
+		}
+		if test.retryAfter != "" {
+			resp.Header.Set("Retry-After", test.retryAfter)
+		}
+		err := RetryAfterHTTP(resp, errFoo)
+		got, ok := IsRetryAfter(err)
+		if ok != test.wantOK || got != test.want {
+			t.Errorf("%d %q: want %v,%v got %v,%v", test.status, test.retryAfter, test.want, test.wantOK, got, ok)
+		}
+		if errors.Cause(err) != errFoo {
+			t.Errorf("%d %q: cause want %v got %v", test.status, test.retryAfter, errFoo, errors.Cause(err))
+		}
+	}
+
+	// Check a date in the future
+	resp := &http.Response{
+		StatusCode: http.StatusTooManyRequests,
+		Header:     http.Header{},
+	}
+	resp.Header.Set("Retry-After", time.Now().Add(time.Hour).UTC().Format(http.TimeFormat))
+	got, ok := IsRetryAfter(RetryAfterHTTP(resp, errFoo))
+	if !ok || got < 59*time.Minute || got > time.Hour {
+		t.Errorf("date: got %v,%v", got, ok)
+	}
+
+	// Check a nil response
+	if err := RetryAfterHTTP(nil, errFoo); err != errFoo {
+		t.Errorf("nil resp: want %v got %v", errFoo, err)
+	}
+}
+
+func Test_callRetryAfter(t *testing.T) {
+	p := New().SetMinSleep(time.Millisecond).SetMaxSleep(2 * time.Millisecond)
+
+	called := 0
+	start := time.Now()
+	err := p.call(func() (bool, error) {
+		called++
+		return true, RetryAfterError(errFoo, 50*time.Millisecond)
+	}, 3)
+	if called != 3 {
+		t.Errorf("called want %d got %d", 3, called)
+	}
+	if dt := time.Since(start); dt < 100*time.Millisecond {
+		t.Errorf("didn't sleep for long enough: %v", dt)
+	}
+	if p.GetSleep() < 2*time.Millisecond {
+		t.Errorf("sleep time not increased: %v", p.GetSleep())
+	}
+	if err == nil || err.Error() != errFoo.Error() {
+		t.Errorf("err want %v got %v", errFoo, err)
+	}
+	if _, ok := err.(fserrors.Retrier); !ok {
+		t.Errorf("didn't return a retry error")
+	}
+}
@@ -190,7 +190,15 @@ func (api *Client) Call(opts *Opts) (resp *http.Response, err error) {
 	if opts.Parameters != nil && len(opts.Parameters) > 0 {
 		url += "?" + opts.Parameters.Encode()
 	}
-	req, err := http.NewRequest(opts.Method, url, opts.Body)
+	body := opts.Body
+	// For go1.8 (see release notes) a non nil Body with a zero
+	// ContentLength is sent chunked, so nil the Body to get a
+	// "Content-Length: 0" header which many providers require to
+	// upload empty files.
+	if opts.ContentLength != nil && *opts.ContentLength == 0 {
+		body = nil
+	}
+	req, err := http.NewRequest(opts.Method, url, body)
 	if err != nil {
 		return
 	}
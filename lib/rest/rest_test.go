@@ -0,0 +1,40 @@
+// +build go1.8
+
+package rest
+
+import (
+	"bytes"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCallZeroLength(t *testing.T) {
+	var (
+		gotLength   int64
+		gotEncoding []string
+	)
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotLength = r.ContentLength
+		gotEncoding = r.TransferEncoding
+	}))
+	defer ts.Close()
+
+	api := NewClient(http.DefaultClient).SetRoot(ts.URL)
+	size := int64(0)
+	opts := Opts{
+		Method:        "PUT",
+		Path:          "/empty",
+		Body:          io.MultiReader(bytes.NewBuffer(nil)),
+		ContentLength: &size,
+		NoResponse:    true,
+	}
+	_, err := api.Call(&opts)
+	require.NoError(t, err)
+	assert.Equal(t, int64(0), gotLength)
+	assert.Nil(t, gotEncoding)
+}
@@ -4,8 +4,6 @@
 package main
 
 import (
-	"log"
-
 	"github.com/ncw/rclone/cmd"
 
 	_ "github.com/ncw/rclone/backend/all" // import all backends
@@ -13,7 +11,5 @@ import (
 )
 
 func main() {
-	if err := cmd.Root.Execute(); err != nil {
-		log.Fatalf("Fatal error: %v", err)
-	}
+	cmd.Main()
 }
@@ -76,6 +76,7 @@ type cacheItem struct {
 	opens  int       // number of times file is open
 	atime  time.Time // last time file was accessed
 	isFile bool      // if this is a file or a directory
+	size   int64     // size of the file on disk when last seen
 }
 
 // newCacheItem returns an item for the cache
@@ -171,17 +172,19 @@ func (c *cache) get(name string) *cacheItem {
 	return item
 }
 
-// updateTime sets the atime of the name to that passed in if it is
-// newer than the existing or there isn't an existing time.
+// updateStat sets the atime of the name to that passed in if it is
+// newer than the existing or there isn't an existing time, and sets
+// the size.
 //
 // name should be a remote path not an osPath
-func (c *cache) updateTime(name string, when time.Time) {
+func (c *cache) updateStat(name string, when time.Time, size int64) {
 	c.itemMu.Lock()
 	item, found := c._get(true, name)
 	if !found || when.Sub(item.atime) > 0 {
 		fs.Debugf(name, "updateTime: setting atime to %v", when)
 		item.atime = when
 	}
+	item.size = size
 	c.itemMu.Unlock()
 }
 
@@ -315,7 +318,7 @@ func (c *cache) updateAtimes() error {
 		if !fi.IsDir() {
 			// Update the atime with that of the file
 			atime := times.Get(fi).AccessTime()
-			c.updateTime(name, atime)
+			c.updateStat(name, atime, fi.Size())
 		} else {
 			c.cacheDir(name)
 		}
@@ -362,6 +365,71 @@ func (c *cache) _purgeOld(maxAge time.Duration, remove func(name string), remove
 	}
 }
 
+// cacheNamedItem is a cacheItem with a name
+type cacheNamedItem struct {
+	name string
+	item *cacheItem
+}
+
+// cacheNamedItems sorts cacheNamedItem by atime, oldest first
+type cacheNamedItems []cacheNamedItem
+
+func (v cacheNamedItems) Len() int           { return len(v) }
+func (v cacheNamedItems) Swap(i, j int)      { v[i], v[j] = v[j], v[i] }
+func (v cacheNamedItems) Less(i, j int) bool { return v[i].item.atime.Before(v[j].item.atime) }
+
+// purgeOverQuota removes the least recently accessed files which
+// aren't open until the cache is no bigger than quota
+func (c *cache) purgeOverQuota(quota int64) {
+	c._purgeOverQuota(quota, c.remove)
+}
+
+func (c *cache) _purgeOverQuota(quota int64, remove func(name string)) {
+	c.itemMu.Lock()
+	defer c.itemMu.Unlock()
+
+	if quota <= 0 {
+		return
+	}
+
+	var items cacheNamedItems
+	used := c._used()
+	if used <= quota {
+		return
+	}
+	for name, item := range c.item {
+		if item.isFile && item.opens == 0 {
+			items = append(items, cacheNamedItem{name: name, item: item})
+		}
+	}
+	sort.Sort(items)
+
+	// Remove the oldest files first
+	for _, item := range items {
+		if used <= quota {
+			break
+		}
+		remove(item.name)
+		delete(c.item, item.name)
+		used -= item.item.size
+	}
+	if used > quota {
+		fs.Logf(nil, "vfs cache: still %v over quota after removing unused files", fs.SizeSuffix(used-quota))
+	}
+}
+
+// _used returns the total size of the files in the cache
+//
+// must be called with itemMu held
+func (c *cache) _used() (used int64) {
+	for _, item := range c.item {
+		if item.isFile {
+			used += item.size
+		}
+	}
+	return used
+}
+
 // clean empties the cache of stuff if it can
 func (c *cache) clean() {
 	// Cache may be empty so end
@@ -378,9 +446,17 @@ func (c *cache) clean() {
 		fs.Errorf(nil, "Error traversing cache %q: %v", c.root, err)
 	}
 
+	// Remove the least recently used files if over quota
+	c.purgeOverQuota(int64(c.opt.CacheMaxSize))
+
 	// Now remove any files that are over age and any empty
 	// directories
 	c.purgeOld(c.opt.CacheMaxAge)
+
+	c.itemMu.Lock()
+	used := c._used()
+	c.itemMu.Unlock()
+	fs.Debugf(nil, "vfs cache: using %v", fs.SizeSuffix(used))
 }
 
 // cleaner calls clean at regular intervals
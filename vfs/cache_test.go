@@ -88,16 +88,16 @@ func TestCacheNew(t *testing.T) {
 	assert.Equal(t, item, item2)
 	assert.WithinDuration(t, time.Now(), item.atime, time.Second)
 
-	// updateTime
+	// updateStat
 	//.. before
 	t1 := time.Now().Add(-60 * time.Minute)
-	c.updateTime("potato", t1)
+	c.updateStat("potato", t1, 0)
 	item = c.get("potato")
 	assert.NotEqual(t, t1, item.atime)
 	assert.Equal(t, 0, item.opens)
 	//..after
 	t2 := time.Now().Add(60 * time.Minute)
-	c.updateTime("potato", t2)
+	c.updateStat("potato", t2, 0)
 	item = c.get("potato")
 	assert.Equal(t, t2, item.atime)
 	assert.Equal(t, 0, item.opens)
@@ -130,6 +130,7 @@ name="potato" isFile=true opens=1`, itemAsString(c))
 name="potato" isFile=true opens=1`, itemAsString(c))
 	item = c.get("potato")
 	assert.Equal(t, atime, item.atime)
+	assert.Equal(t, int64(5), item.size)
 
 	// updateAtimes - not in the cache
 	oldItem := item
@@ -150,7 +151,7 @@ name="potato" isFile=true opens=0`, itemAsString(c))
 	// close
 	assert.Equal(t, `name="" isFile=false opens=1
 name="potato" isFile=true opens=1`, itemAsString(c))
-	c.updateTime("potato", t2)
+	c.updateStat("potato", t2, 0)
 	assert.Equal(t, `name="" isFile=false opens=1
 name="potato" isFile=true opens=1`, itemAsString(c))
 	c.close("potato")
@@ -325,3 +326,49 @@ name="sub/dir/potato" isFile=true opens=0`, itemAsString(c))
 
 	assert.Equal(t, ``, itemAsString(c))
 }
+
+func TestCachePurgeOverQuota(t *testing.T) {
+	r := fstest.NewRun(t)
+	defer r.Finalise()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	c, err := newCache(ctx, r.Fremote, &DefaultOpt)
+	require.NoError(t, err)
+
+	// Test funcs
+	var removed []string
+	remove := func(name string) {
+		removed = append(removed, name)
+	}
+
+	now := time.Now()
+	c.updateStat("sub/one", now.Add(-3*time.Hour), 100)
+	c.updateStat("sub/two", now.Add(-1*time.Hour), 100)
+	c.updateStat("three", now.Add(-2*time.Hour), 100)
+	c.updateStat("open", now.Add(-4*time.Hour), 100)
+	c.open("open")
+
+	// no quota
+	removed = nil
+	c._purgeOverQuota(-1, remove)
+	assert.Equal(t, []string(nil), removed)
+
+	// under quota
+	removed = nil
+	c._purgeOverQuota(400, remove)
+	assert.Equal(t, []string(nil), removed)
+
+	// over quota - remove oldest first skipping the open file
+	removed = nil
+	c._purgeOverQuota(250, remove)
+	assert.Equal(t, []string{"sub/one", "three"}, removed)
+
+	// over quota but only the open file is older
+	removed = nil
+	c._purgeOverQuota(1, remove)
+	assert.Equal(t, []string{"sub/two"}, removed)
+	assert.Equal(t, `name="" isFile=false opens=1
+name="open" isFile=true opens=1`, itemAsString(c))
+}
@@ -11,6 +11,7 @@ import (
 	"github.com/ncw/rclone/fs"
 	"github.com/ncw/rclone/fs/list"
 	"github.com/pkg/errors"
+	"golang.org/x/net/context"
 )
 
 // Dir represents a directory entry
@@ -180,7 +181,7 @@ func (d *Dir) _readDir() error {
 		}
 		fs.Debugf(d.path, "Re-reading directory (%v old)", age)
 	}
-	entries, err := list.DirSorted(d.f, false, d.path)
+	entries, err := list.DirSorted(context.TODO(), d.f, false, d.path)
 	if err == fs.ErrorDirNotFound {
 		// We treat directory not found as empty because we
 		// create directories on the fly
@@ -271,6 +272,14 @@ func (d *Dir) SetModTime(modTime time.Time) error {
 	}
 	d.mu.Lock()
 	defer d.mu.Unlock()
+	// Set the time on the remote too if it can store it
+	if doDirSetModTime := d.f.Features().DirSetModTime; doDirSetModTime != nil && !fs.Config.NoUpdateDirModTime {
+		err := doDirSetModTime(context.TODO(), d.path, modTime)
+		if err != nil {
+			fs.Errorf(d, "Dir.SetModTime error: %v", err)
+			return err
+		}
+	}
 	d.modTime = modTime
 	return nil
 }
@@ -342,7 +351,7 @@ func (d *Dir) Mkdir(name string) (*Dir, error) {
 	}
 	path := path.Join(d.path, name)
 	// fs.Debugf(path, "Dir.Mkdir")
-	err := d.f.Mkdir(path)
+	err := d.f.Mkdir(context.TODO(), path)
 	if err != nil {
 		fs.Errorf(d, "Dir.Mkdir failed to create directory: %v", err)
 		return nil, err
@@ -370,7 +379,7 @@ func (d *Dir) Remove() error {
 		return ENOTEMPTY
 	}
 	// remove directory
-	err = d.f.Rmdir(d.path)
+	err = d.f.Rmdir(context.TODO(), d.path)
 	if err != nil {
 		fs.Errorf(d, "Dir.Remove failed to remove directory: %v", err)
 		return err
@@ -451,7 +460,7 @@ func (d *Dir) Rename(oldName, newName string, destDir *Dir) error {
 			fs.Errorf(oldPath, "Dir.Rename error: %v", err)
 			return err
 		}
-		newObject, err := doMove(oldObject, newPath)
+		newObject, err := doMove(context.TODO(), oldObject, newPath)
 		if err != nil {
 			fs.Errorf(oldPath, "Dir.Rename error: %v", err)
 			return err
@@ -472,7 +481,7 @@ func (d *Dir) Rename(oldName, newName string, destDir *Dir) error {
 		}
 		srcRemote := x.Remote()
 		dstRemote := newPath
-		err = doDirMove(d.f, srcRemote, dstRemote)
+		err = doDirMove(context.TODO(), d.f, srcRemote, dstRemote)
 		if err != nil {
 			fs.Errorf(oldPath, "Dir.Rename error: %v", err)
 			return err
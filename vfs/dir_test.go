@@ -10,6 +10,7 @@ import (
 	"github.com/ncw/rclone/fstest"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
+	"golang.org/x/net/context"
 )
 
 func dirCreate(t *testing.T, r *fstest.Run) (*VFS, *Dir, fstest.Item) {
@@ -200,6 +201,14 @@ func TestDirSetModTime(t *testing.T) {
 	require.NoError(t, err)
 	assert.WithinDuration(t, t2, dir.ModTime(), time.Second)
 
+	// Check the time was set on the remote if it can store it
+	if r.Fremote.Features().DirSetModTime != nil {
+		entries, err := r.Fremote.List(context.Background(), "")
+		require.NoError(t, err)
+		require.Len(t, entries, 1)
+		assert.WithinDuration(t, t2, entries[0].ModTime(), time.Second)
+	}
+
 	vfs.Opt.ReadOnly = true
 	err = dir.SetModTime(t2)
 	assert.Equal(t, EROFS, err)
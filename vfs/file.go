@@ -8,8 +8,10 @@ import (
 	"time"
 
 	"github.com/ncw/rclone/fs"
+	"github.com/ncw/rclone/fs/accounting"
 	"github.com/ncw/rclone/fs/log"
 	"github.com/pkg/errors"
+	"golang.org/x/net/context"
 )
 
 // File represents a file
@@ -21,6 +23,7 @@ type File struct {
 	o              fs.Object    // NB o may be nil if file is being written
 	leaf           string       // leaf name of the object
 	writers        []Handle     // writers for this file
+	rwOpens        int          // number of RW handles with the cache file open
 	pendingModTime time.Time    // will be applied once o becomes available, i.e. after file was written
 }
 
@@ -116,6 +119,32 @@ func (f *File) delWriter(h Handle) {
 	f.mu.Unlock()
 }
 
+// addRWOpen records that a RW handle has opened the cache file
+// returning the number of RW handles which had it open already
+func (f *File) addRWOpen() (opens int) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	opens = f.rwOpens
+	f.rwOpens++
+	return opens
+}
+
+// delRWOpen records that a RW handle has closed the cache file
+// returning the number of RW handles which still have it open
+func (f *File) delRWOpen() (opens int) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.rwOpens--
+	return f.rwOpens
+}
+
+// rwOpen returns true if any RW handles have the cache file open
+func (f *File) rwOpen() bool {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.rwOpens > 0
+}
+
 // activeWriters returns the number of writers on the file
 func (f *File) activeWriters() int {
 	f.mu.Lock()
@@ -194,7 +223,7 @@ func (f *File) applyPendingModTime() error {
 		return errors.New("Cannot apply ModTime, file object is not available")
 	}
 
-	err := f.o.SetModTime(f.pendingModTime)
+	err := f.o.SetModTime(context.TODO(), f.pendingModTime)
 	switch err {
 	case nil:
 		fs.Debugf(f.o, "File.applyPendingModTime OK")
@@ -268,6 +297,35 @@ func (f *File) openRead() (fh *ReadFileHandle, err error) {
 	return fh, nil
 }
 
+// OpenOSFile opens the file for reading as an *os.File if the
+// underlying object supports it, eg if it is on the local backend.
+// This allows zero copy methods like sendfile to be used when serving
+// the file.
+//
+// It returns a nil *os.File and no error if this isn't possible, for
+// example if the file is being written or a bandwidth limit is set,
+// in which case use Open instead.  The caller must account the data
+// sent from the *os.File.
+func (f *File) OpenOSFile() (*os.File, error) {
+	f.mu.Lock()
+	o := f.o
+	nwriters := len(f.writers)
+	f.mu.Unlock()
+	// The cache may have data which hasn't been uploaded yet
+	if o == nil || nwriters > 0 || f.d.vfs.Opt.CacheMode >= CacheModeWrites {
+		return nil, nil
+	}
+	// Reading through the VFS is needed to limit the bandwidth
+	if accounting.LimitsBandwidth(o.Fs()) {
+		return nil, nil
+	}
+	opener, ok := o.(fs.OSFileOpener)
+	if !ok {
+		return nil, nil
+	}
+	return opener.OpenOSFile()
+}
+
 // openWrite open the file for write
 func (f *File) openWrite(flags int) (fh *WriteFileHandle, err error) {
 	if f.d.vfs.Opt.ReadOnly {
@@ -316,7 +374,7 @@ func (f *File) Remove() error {
 		return EROFS
 	}
 	if f.o != nil {
-		err := f.o.Remove()
+		err := f.o.Remove(context.TODO())
 		if err != nil {
 			fs.Errorf(f, "File.Remove file error: %v", err)
 			return err
@@ -5,6 +5,7 @@ import (
 	"os"
 	"testing"
 
+	"github.com/ncw/rclone/fs"
 	"github.com/ncw/rclone/fstest"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
@@ -106,6 +107,33 @@ func TestFileOpenRead(t *testing.T) {
 	require.NoError(t, fd.Close())
 }
 
+func TestFileOpenOSFile(t *testing.T) {
+	r := fstest.NewRun(t)
+	defer r.Finalise()
+	_, file, _ := fileCreate(t, r)
+	if _, ok := file.o.(fs.OSFileOpener); !ok {
+		t.Skip("remote can't open OS files")
+	}
+
+	fd, err := file.OpenOSFile()
+	require.NoError(t, err)
+	require.NotNil(t, fd)
+	contents, err := ioutil.ReadAll(fd)
+	require.NoError(t, err)
+	assert.Equal(t, "file1 contents", string(contents))
+	require.NoError(t, fd.Close())
+
+	// Not with a bandwidth limit as it wouldn't be applied
+	oldBwLimit := fs.Config.BwLimit
+	defer func() {
+		fs.Config.BwLimit = oldBwLimit
+	}()
+	require.NoError(t, fs.Config.BwLimit.Set("1M"))
+	fd, err = file.OpenOSFile()
+	require.NoError(t, err)
+	assert.Nil(t, fd)
+}
+
 func TestFileOpenWrite(t *testing.T) {
 	r := fstest.NewRun(t)
 	defer r.Finalise()
@@ -18,6 +18,19 @@ like this:
 
     kill -SIGHUP $(pidof rclone)
 
+### Free space
+
+The size and free space of the mount are read from the remote with
+its About call (see ` + "`rclone about`" + `) and cached for
+` + "`--dir-cache-time`" + `.  If the remote doesn't report them the mount
+shows a very large, nearly empty file system.
+
+Use ` + "`--vfs-used-is-size`" + ` to add up the sizes of the objects on the
+remote and report that as the used space instead, which is useful for
+remotes which don't report their usage or when only part of the
+remote is mounted.  This lists the whole mounted remote each time the
+figures expire, so can be slow and expensive on large remotes.
+
 ### File Caching
 
 **NB** File caching is **EXPERIMENTAL** - use with care!
@@ -34,9 +47,18 @@ may find that you need one or the other or both.
 
     --cache-dir string                   Directory rclone will use for caching.
     --vfs-cache-max-age duration         Max age of objects in the cache. (default 1h0m0s)
+    --vfs-cache-max-size int             Max total size of objects in the cache. (default off)
     --vfs-cache-mode string              Cache mode off|minimal|writes|full (default "off")
     --vfs-cache-poll-interval duration   Interval to poll the cache for stale objects. (default 1m0s)
 
+The cache is checked every ` + "`--vfs-cache-poll-interval`" + `.  Files which
+haven't been accessed for ` + "`--vfs-cache-max-age`" + ` are removed, then if the
+cache is bigger than ` + "`--vfs-cache-max-size`" + ` the least recently accessed
+files are removed until it isn't.  Files which are open are never
+removed, so the cache can grow bigger than ` + "`--vfs-cache-max-size`" + ` while
+they are in use, and as the sizes are only read when the cache is
+checked it may grow bigger in between checks.
+
 If run with ` + "`-vv`" + ` rclone will print the location of the file cache.  The
 files are stored in the user cache file area which is OS dependent but
 can be controlled with ` + "`--cache-dir`" + ` or setting the appropriate
@@ -85,7 +107,12 @@ In this mode files opened for read only are still read directly from
 the remote, write only and read/write files are buffered to disk
 first.
 
-This mode should support all normal file system operations.
+This mode should support all normal file system operations, including
+opening files with O_APPEND or O_TRUNC and the truncate then write or
+read, modify and write patterns used by editors, databases and office
+suites when saving files.  If a file is opened more than once at the
+same time the handles share the same cached copy and it is uploaded
+each time one of them is closed after writing.
 
 If an upload fails it will be retried up to --low-level-retries times.
 
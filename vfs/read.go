@@ -9,6 +9,7 @@ import (
 	"github.com/ncw/rclone/fs/accounting"
 	"github.com/ncw/rclone/fs/hash"
 	"github.com/pkg/errors"
+	"golang.org/x/net/context"
 )
 
 // ReadFileHandle is an open for read file handle on a File
@@ -62,11 +63,11 @@ func (fh *ReadFileHandle) openPending() (err error) {
 	if fh.opened {
 		return nil
 	}
-	r, err := fh.o.Open()
+	r, err := fh.o.Open(context.TODO())
 	if err != nil {
 		return err
 	}
-	fh.r = accounting.NewAccount(r, fh.o).WithBuffer() // account the transfer
+	fh.r = accounting.NewAccount(r, fh.o).WithLimits(fh.o.Fs(), nil).WithBuffer() // account the transfer
 	fh.opened = true
 	accounting.Stats.Transferring(fh.o.Remote())
 	return nil
@@ -121,7 +122,7 @@ func (fh *ReadFileHandle) seek(offset int64, reopen bool) (err error) {
 			fs.Debugf(fh.o, "ReadFileHandle.Read seek close old failed: %v", err)
 		}
 		// re-open with a seek
-		r, err = fh.o.Open(&fs.SeekOption{Offset: offset})
+		r, err = fh.o.Open(context.TODO(), &fs.SeekOption{Offset: offset})
 		if err != nil {
 			fs.Debugf(fh.o, "ReadFileHandle.Read seek failed: %v", err)
 			return err
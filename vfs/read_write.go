@@ -10,6 +10,7 @@ import (
 	"github.com/ncw/rclone/fs/log"
 	"github.com/ncw/rclone/fs/operations"
 	"github.com/pkg/errors"
+	"golang.org/x/net/context"
 )
 
 // RWFileHandle is a handle that can be open for read and write.
@@ -80,13 +81,20 @@ func (fh *RWFileHandle) openPending(truncate bool) (err error) {
 		return nil
 	}
 
-	cacheFileOpenFlags := fh.flags
+	// O_EXCL has been checked against the remote already and
+	// O_APPEND is done in Write since FUSE passes the offsets to
+	// WriteAt which os.File refuses for files opened with O_APPEND
+	cacheFileOpenFlags := fh.flags &^ (os.O_EXCL | os.O_APPEND)
 
-	// if not truncating the file, need to read it first
-	if fh.flags&os.O_TRUNC == 0 && !truncate {
+	// if not truncating the file, need to read it first unless
+	// another handle has it open in which case we share its copy
+	// so we don't overwrite any changes it has made
+	if fh.flags&os.O_TRUNC == 0 && !truncate && fh.file.rwOpen() {
+		fs.Debugf(fh.remote, "Sharing cached copy with open handles")
+	} else if fh.flags&os.O_TRUNC == 0 && !truncate {
 		// Fetch the file if it hasn't changed
 		// FIXME retries
-		err = operations.CopyFile(fh.d.vfs.cache.f, fh.d.vfs.f, fh.remote, fh.remote)
+		err = operations.CopyFile(context.TODO(), fh.d.vfs.cache.f, fh.d.vfs.f, fh.remote, fh.remote)
 		if err != nil {
 			// if the object wasn't found AND O_CREATE is set then...
 			cause := errors.Cause(err)
@@ -108,14 +116,13 @@ func (fh *RWFileHandle) openPending(truncate bool) (err error) {
 		// Set the size to 0 since we are truncating and flag we need to write it back
 		fh.file.setSize(0)
 		fh.writeCalled = true
-		if fh.flags&os.O_CREATE != 0 && fh.file.exists() {
-			// create and empty file if it exists on the source
-			cacheFileOpenFlags |= os.O_CREATE
-		}
+		// create an empty file as the file may not have been
+		// fetched into the cache
+		cacheFileOpenFlags |= os.O_CREATE
 		// Windows doesn't seem to deal well with O_TRUNC and
 		// certain access modes so so truncate the file if it
 		// exists in these cases.
-		if runtime.GOOS == "windows" && (fh.flags&accessModeMask == os.O_RDONLY || fh.flags|os.O_APPEND != 0) {
+		if runtime.GOOS == "windows" && (fh.flags&accessModeMask == os.O_RDONLY || fh.flags&os.O_APPEND != 0) {
 			cacheFileOpenFlags &^= os.O_TRUNC
 			_, err = os.Stat(fh.osPath)
 			if err == nil {
@@ -134,6 +141,7 @@ func (fh *RWFileHandle) openPending(truncate bool) (err error) {
 	}
 	fh.File = fd
 	fh.opened = true
+	fh.file.addRWOpen()
 	fh.d.addObject(fh.file) // make sure the directory has this object in it now
 	return nil
 }
@@ -204,6 +212,7 @@ func (fh *RWFileHandle) close() (err error) {
 
 	// Close the underlying file
 	err = fh.File.Close()
+	otherOpens := fh.file.delRWOpen()
 	if err != nil {
 		return err
 	}
@@ -223,12 +232,13 @@ func (fh *RWFileHandle) close() (err error) {
 		return nil
 	}
 
-	// Transfer the temp file to the remote
+	// Transfer the temp file to the remote leaving it in the
+	// cache if other handles are still using it
 	// FIXME retries
-	if fh.d.vfs.Opt.CacheMode < CacheModeFull {
-		err = operations.MoveFile(fh.d.vfs.f, fh.d.vfs.cache.f, fh.remote, fh.remote)
+	if fh.d.vfs.Opt.CacheMode < CacheModeFull && otherOpens == 0 {
+		err = operations.MoveFile(context.TODO(), fh.d.vfs.f, fh.d.vfs.cache.f, fh.remote, fh.remote)
 	} else {
-		err = operations.CopyFile(fh.d.vfs.f, fh.d.vfs.cache.f, fh.remote, fh.remote)
+		err = operations.CopyFile(context.TODO(), fh.d.vfs.f, fh.d.vfs.cache.f, fh.remote, fh.remote)
 	}
 	if err != nil {
 		err = errors.Wrap(err, "failed to transfer file from cache to remote")
@@ -237,7 +247,7 @@ func (fh *RWFileHandle) close() (err error) {
 	}
 
 	// FIXME get MoveFile to return this object
-	o, err := fh.d.vfs.f.NewObject(fh.remote)
+	o, err := fh.d.vfs.f.NewObject(context.TODO(), fh.remote)
 	if err != nil {
 		err = errors.Wrap(err, "failed to find object after transfer to remote")
 		fs.Errorf(fh.remote, "%v", err)
@@ -404,9 +414,24 @@ func (fh *RWFileHandle) writeFn(write func() error) (err error) {
 	return nil
 }
 
+// seekAppend moves to the end of the file if it was opened with
+// O_APPEND
+//
+// Must be called with fh.mu held
+func (fh *RWFileHandle) seekAppend() error {
+	if fh.flags&os.O_APPEND == 0 {
+		return nil
+	}
+	_, err := fh.File.Seek(0, 2) //io.SeekEnd
+	return err
+}
+
 // Write bytes to the file
 func (fh *RWFileHandle) Write(b []byte) (n int, err error) {
 	err = fh.writeFn(func() error {
+		if err = fh.seekAppend(); err != nil {
+			return err
+		}
 		n, err = fh.File.Write(b)
 		return err
 	})
@@ -425,6 +450,9 @@ func (fh *RWFileHandle) WriteAt(b []byte, off int64) (n int, err error) {
 // WriteString a string to the file
 func (fh *RWFileHandle) WriteString(s string) (n int, err error) {
 	err = fh.writeFn(func() error {
+		if err = fh.seekAppend(); err != nil {
+			return err
+		}
 		n, err = fh.File.WriteString(s)
 		return err
 	})
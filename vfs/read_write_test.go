@@ -440,6 +440,98 @@ func TestRWFileHandleReleaseWrite(t *testing.T) {
 	assert.True(t, fh.closed)
 }
 
+func TestRWFileHandleAppend(t *testing.T) {
+	r := fstest.NewRun(t)
+	defer r.Finalise()
+	vfs := New(r.Fremote, nil)
+	vfs.Opt.CacheMode = CacheModeWrites
+
+	file1 := r.WriteObject("file1", "hello", t1)
+	fstest.CheckItems(t, r.Fremote, file1)
+
+	h, err := vfs.OpenFile("file1", os.O_WRONLY|os.O_APPEND, 0777)
+	require.NoError(t, err)
+	fh, ok := h.(*RWFileHandle)
+	require.True(t, ok)
+
+	// Write appends to the end of the file
+	n, err := fh.Write([]byte(" world"))
+	assert.NoError(t, err)
+	assert.Equal(t, 6, n)
+
+	// WriteAt uses the offset passed in as FUSE does
+	n, err = fh.WriteAt([]byte("!"), 11)
+	assert.NoError(t, err)
+	assert.Equal(t, 1, n)
+
+	require.NoError(t, fh.Close())
+
+	file1 = fstest.NewItem("file1", "hello world!", t1)
+	fstest.CheckListingWithPrecision(t, r.Fremote, []fstest.Item{file1}, []string{}, fs.ModTimeNotSupported)
+}
+
+func TestRWFileHandleShared(t *testing.T) {
+	r := fstest.NewRun(t)
+	defer r.Finalise()
+	vfs := New(r.Fremote, nil)
+	vfs.Opt.CacheMode = CacheModeWrites
+
+	file1 := r.WriteObject("file1", "0123456789", t1)
+	fstest.CheckItems(t, r.Fremote, file1)
+
+	open := func() *RWFileHandle {
+		h, err := vfs.OpenFile("file1", os.O_RDWR, 0777)
+		require.NoError(t, err)
+		fh, ok := h.(*RWFileHandle)
+		require.True(t, ok)
+		return fh
+	}
+
+	// Modify the file through one handle
+	fh1 := open()
+	_, err := fh1.WriteAt([]byte("AB"), 0)
+	require.NoError(t, err)
+
+	// A second handle must see the changes not the remote
+	fh2 := open()
+	assert.Equal(t, "AB23", rwReadString(t, fh2, 4))
+	_, err = fh2.WriteAt([]byte("CD"), 8)
+	require.NoError(t, err)
+
+	// Closing the first handle uploads but leaves the cache
+	// file for the second
+	require.NoError(t, fh1.Close())
+	_, err = fh2.WriteAt([]byte("EF"), 4)
+	require.NoError(t, err)
+	require.NoError(t, fh2.Close())
+
+	file1 = fstest.NewItem("file1", "AB23EF67CD", t1)
+	fstest.CheckListingWithPrecision(t, r.Fremote, []fstest.Item{file1}, []string{}, fs.ModTimeNotSupported)
+}
+
+func TestRWFileHandleTruncateThenWrite(t *testing.T) {
+	r := fstest.NewRun(t)
+	defer r.Finalise()
+	vfs := New(r.Fremote, nil)
+	vfs.Opt.CacheMode = CacheModeWrites
+
+	file1 := r.WriteObject("file1", "old contents", t1)
+	fstest.CheckItems(t, r.Fremote, file1)
+
+	// Open without O_TRUNC then truncate as editors do
+	h, err := vfs.OpenFile("file1", os.O_WRONLY, 0777)
+	require.NoError(t, err)
+	fh, ok := h.(*RWFileHandle)
+	require.True(t, ok)
+	require.NoError(t, fh.Truncate(0))
+	_, err = fh.WriteAt([]byte("new"), 0)
+	require.NoError(t, err)
+	require.NoError(t, fh.Close())
+
+	file1 = fstest.NewItem("file1", "new", t1)
+	fstest.CheckListingWithPrecision(t, r.Fremote, []fstest.Item{file1}, []string{}, fs.ModTimeNotSupported)
+}
+
 func testRWFileHandleOpenTest(t *testing.T, vfs *VFS, test *openTest) {
 	fileName := "open-test-file"
 
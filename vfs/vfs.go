@@ -23,11 +23,13 @@ import (
 	"os"
 	"path"
 	"strings"
+	"sync"
 	"sync/atomic"
 	"time"
 
 	"github.com/ncw/rclone/fs"
 	"github.com/ncw/rclone/fs/log"
+	"github.com/ncw/rclone/fs/operations"
 	"golang.org/x/net/context" // switch to "context" when we stop supporting go1.6
 )
 
@@ -46,7 +48,9 @@ var DefaultOpt = Options{
 	FilePerms:         os.FileMode(0666),
 	CacheMode:         CacheModeOff,
 	CacheMaxAge:       3600 * time.Second,
+	CacheMaxSize:      -1,
 	CachePollInterval: 60 * time.Second,
+	UsedIsSize:        false,
 }
 
 // Node represents either a directory (*Dir) or a file (*File)
@@ -165,11 +169,17 @@ var (
 
 // VFS represents the top level filing system
 type VFS struct {
-	f      fs.Fs
-	root   *Dir
-	Opt    Options
-	cache  *cache
-	cancel context.CancelFunc
+	f         fs.Fs
+	root      *Dir
+	Opt       Options
+	cache     *cache
+	cancel    context.CancelFunc
+	usageMu   sync.Mutex
+	usageTime time.Time
+	usage     *fs.Usage
+
+	changeNotifyMu sync.Mutex
+	changeNotify   []func(relativePath string)
 }
 
 // Options is options for creating the vfs
@@ -187,7 +197,9 @@ type Options struct {
 	FilePerms         os.FileMode
 	CacheMode         CacheMode
 	CacheMaxAge       time.Duration
+	CacheMaxSize      fs.SizeSuffix
 	CachePollInterval time.Duration
+	UsedIsSize        bool // add up the sizes of the objects to find the used space
 }
 
 // New creates a new VFS and root directory.  If opt is nil, then
@@ -205,6 +217,12 @@ func New(f fs.Fs, opt *Options) *VFS {
 		vfs.Opt = DefaultOpt
 	}
 
+	// Don't allow any changes with --dry-run
+	if fs.Config.DryRun && !vfs.Opt.ReadOnly {
+		fs.Logf(f, "Making VFS read only as --dry-run is set")
+		vfs.Opt.ReadOnly = true
+	}
+
 	// Mask the permissions with the umask
 	vfs.Opt.DirPerms &= ^os.FileMode(vfs.Opt.Umask)
 	vfs.Opt.FilePerms &= ^os.FileMode(vfs.Opt.Umask)
@@ -218,7 +236,7 @@ func New(f fs.Fs, opt *Options) *VFS {
 	// Start polling if required
 	if vfs.Opt.PollInterval > 0 {
 		if do := vfs.f.Features().DirChangeNotify; do != nil {
-			do(vfs.root.ForgetPath, vfs.Opt.PollInterval)
+			do(vfs.forgetPath, vfs.Opt.PollInterval)
 		} else {
 			fs.Infof(f, "poll-interval is not supported by this remote")
 		}
@@ -236,6 +254,28 @@ func New(f fs.Fs, opt *Options) *VFS {
 	return vfs
 }
 
+// forgetPath is called by the remote when relativePath has changed
+func (vfs *VFS) forgetPath(relativePath string) {
+	vfs.root.ForgetPath(relativePath)
+	vfs.changeNotifyMu.Lock()
+	changeNotify := vfs.changeNotify
+	vfs.changeNotifyMu.Unlock()
+	for _, fn := range changeNotify {
+		fn(relativePath)
+	}
+}
+
+// AddChangeNotify adds fn to be called with the path relative to the
+// root whenever the remote says that path has changed.
+//
+// This is only called if --poll-interval is set and the remote
+// supports it.
+func (vfs *VFS) AddChangeNotify(fn func(relativePath string)) {
+	vfs.changeNotifyMu.Lock()
+	vfs.changeNotify = append(vfs.changeNotify, fn)
+	vfs.changeNotifyMu.Unlock()
+}
+
 // Shutdown stops any background go-routines
 func (vfs *VFS) Shutdown() {
 	if vfs.cancel != nil {
@@ -428,3 +468,60 @@ func (vfs *VFS) Rename(oldName, newName string) error {
 	}
 	return nil
 }
+
+// Statfs returns information about the filing system if known
+//
+// The values will be -1 if they aren't known.  Missing values are
+// worked out from the others where possible, and with UsedIsSize the
+// used space is found by adding up the sizes of the objects on the
+// remote rather than asking it.
+//
+// This information is cached for the DirCacheTime interval
+func (vfs *VFS) Statfs() (total, used, free int64) {
+	// defer log.Trace("/", "")("total=%d, used=%d, free=%d", &total, &used, &free)
+	vfs.usageMu.Lock()
+	defer vfs.usageMu.Unlock()
+	total, used, free = -1, -1, -1
+	doAbout := vfs.f.Features().About
+	if (doAbout != nil || vfs.Opt.UsedIsSize) && (vfs.usageTime.IsZero() || time.Since(vfs.usageTime) >= vfs.Opt.DirCacheTime) {
+		var err error
+		ctx := context.TODO()
+		if doAbout == nil {
+			vfs.usage = &fs.Usage{}
+		} else if vfs.usage, err = doAbout(ctx); err != nil {
+			fs.Errorf(vfs.f, "Statfs failed: %v", err)
+			vfs.usage = &fs.Usage{}
+		}
+		if vfs.Opt.UsedIsSize {
+			var size int64
+			_, size, err = operations.Count(ctx, vfs.f)
+			if err != nil {
+				fs.Errorf(vfs.f, "Statfs failed to count used space: %v", err)
+			} else {
+				vfs.usage.Used = &size
+			}
+		}
+		vfs.usageTime = time.Now()
+	}
+	if u := vfs.usage; u != nil {
+		if u.Total != nil {
+			total = *u.Total
+		}
+		if u.Free != nil {
+			free = *u.Free
+		}
+		if u.Used != nil {
+			used = *u.Used
+		}
+	}
+	if total < 0 && used >= 0 && free >= 0 {
+		total = used + free
+	}
+	if free < 0 && total >= 0 && used >= 0 {
+		free = total - used
+		if free < 0 {
+			free = 0
+		}
+	}
+	return
+}
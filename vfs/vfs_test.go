@@ -122,6 +122,20 @@ func TestVFSRoot(t *testing.T) {
 	assert.Equal(t, vfs.Opt.DirPerms.Perm(), root.Mode().Perm())
 }
 
+func TestVFSAddChangeNotify(t *testing.T) {
+	r := fstest.NewRun(t)
+	defer r.Finalise()
+	vfs := New(r.Fremote, nil)
+
+	var changed []string
+	vfs.AddChangeNotify(func(relativePath string) {
+		changed = append(changed, relativePath)
+	})
+	vfs.forgetPath("dir")
+	vfs.forgetPath("dir/file")
+	assert.Equal(t, []string{"dir", "dir/file"}, changed)
+}
+
 func TestVFSStat(t *testing.T) {
 	r := fstest.NewRun(t)
 	defer r.Finalise()
@@ -250,3 +264,18 @@ func TestVFSRename(t *testing.T) {
 	err = vfs.Rename("file0", "not found/file0")
 	assert.Equal(t, os.ErrNotExist, err)
 }
+
+func TestVFSStatfsUsedIsSize(t *testing.T) {
+	r := fstest.NewRun(t)
+	defer r.Finalise()
+	file1 := r.WriteObject("file1", "file1 contents", t1)
+	file2 := r.WriteObject("dir/file2", "file2 contents!", t2)
+	fstest.CheckItems(t, r.Fremote, file1, file2)
+
+	var opt = DefaultOpt
+	opt.UsedIsSize = true
+	vfs := New(r.Fremote, &opt)
+
+	_, used, _ := vfs.Statfs()
+	assert.Equal(t, file1.Size+file2.Size, used)
+}
@@ -8,6 +8,7 @@ import (
 
 	"github.com/ncw/rclone/fs"
 	"github.com/ncw/rclone/fs/operations"
+	"golang.org/x/net/context"
 )
 
 // WriteFileHandle is an open for write handle on a File
@@ -65,7 +66,7 @@ func (fh *WriteFileHandle) openPending() (err error) {
 	pipeReader, fh.pipeWriter = io.Pipe()
 	go func() {
 		// NB Rcat deals with Stats.Transferring etc
-		o, err := operations.Rcat(fh.file.d.f, fh.remote, pipeReader, time.Now())
+		o, err := operations.Rcat(context.TODO(), fh.file.d.f, fh.remote, pipeReader, time.Now())
 		if err != nil {
 			fs.Errorf(fh.remote, "WriteFileHandle.New Rcat failed: %v", err)
 		}
@@ -205,6 +206,35 @@ func (fh *WriteFileHandle) Close() error {
 	return fh.close()
 }
 
+// CloseWithError closes the file, cancelling the upload in progress
+// with err rather than completing it.  Use this when the data being
+// written is incomplete, eg because the client went away.
+//
+// A new file is removed from its directory as it was never uploaded.
+//
+// It returns err or an error from cancelling the upload.
+func (fh *WriteFileHandle) CloseWithError(err error) error {
+	fh.mu.Lock()
+	defer fh.mu.Unlock()
+	if fh.closed {
+		return ECLOSED
+	}
+	fh.closed = true
+	defer fh.file.delWriter(fh)
+	if !fh.opened {
+		return err
+	}
+	_ = fh.pipeWriter.CloseWithError(err)
+	rcatErr := <-fh.result
+	if !fh.file.exists() {
+		fh.file.d.delObject(fh.file.Name())
+	}
+	if rcatErr != nil {
+		return rcatErr
+	}
+	return err
+}
+
 // Flush is called on each close() of a file descriptor. So if a
 // filesystem wants to return write errors in close() and the file has
 // cached dirty data, this is a good place to write back data and
@@ -1,6 +1,7 @@
 package vfs
 
 import (
+	"errors"
 	"os"
 	"testing"
 
@@ -120,6 +121,33 @@ func TestWriteFileHandleMethods(t *testing.T) {
 	checkListing(t, root, []string{"file1,7,false"})
 }
 
+func TestWriteFileHandleCloseWithError(t *testing.T) {
+	r := fstest.NewRun(t)
+	defer r.Finalise()
+	vfs, fh := writeHandleCreate(t, r)
+
+	// Write some data then cancel the upload
+	_, err := fh.Write([]byte("hello"))
+	require.NoError(t, err)
+	errCancel := errors.New("cancelled")
+	err = fh.CloseWithError(errCancel)
+	assert.Error(t, err)
+
+	// Check double close
+	assert.Equal(t, ECLOSED, fh.CloseWithError(errCancel))
+	assert.Equal(t, ECLOSED, fh.Close())
+
+	// check the upload didn't happen
+	fstest.CheckListingWithPrecision(t, r.Fremote, []fstest.Item{}, []string{}, fs.ModTimeNotSupported)
+
+	// check the file isn't left in the directory
+	_, err = vfs.Stat("file1")
+	assert.Equal(t, ENOENT, err)
+	root, err := vfs.Root()
+	require.NoError(t, err)
+	checkListing(t, root, []string(nil))
+}
+
 func TestWriteFileHandleWriteAt(t *testing.T) {
 	r := fstest.NewRun(t)
 	defer r.Finalise()